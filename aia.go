@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	aiaFetchTimeout = 15 * time.Second // Espera máxima por cada certificado
+	aiaMaxDepth     = 4                // Intermedios que se buscan como máximo
+	aiaMaxSize      = 1 << 20          // Tamaño máximo de cada descarga
+)
+
+// AIACheck is the result of completing an incomplete chain through the
+// caIssuers URLs of its certificates (--check-aia)
+type AIACheck struct {
+	Fetched  []string // Intermedios obtenidos (label y URL)
+	Resolved bool     // La cadena se completó hasta un certificado sin emisor pendiente
+	Problem  string   // Motivo por el que no se pudo completar
+}
+
+// aiaChecker is an Assessor that, after each assessment, tries to fetch
+// the intermediates missing from incomplete chains
+type aiaChecker struct {
+	Assessor
+	client *http.Client
+}
+
+// withAIACheck wraps scanner so that its results include AIA checks
+func withAIACheck(scanner Assessor) Assessor {
+	return &aiaChecker{Assessor: scanner, client: &http.Client{Timeout: aiaFetchTimeout}}
+}
+
+// AssessContext runs the assessment and chases the AIA of every endpoint
+// whose chain is incomplete. Interrupted assessments are returned as is.
+func (c *aiaChecker) AssessContext(ctx context.Context, domain string) (*AssessmentResult, error) {
+	result, err := c.Assessor.AssessContext(ctx, domain)
+	if err != nil {
+		return result, err
+	}
+
+	checked := make(map[string]*AIACheck)
+	for i := range result.Endpoints {
+		endpoint := &result.Endpoints[i]
+		if endpoint.Details == nil || endpoint.Details.Chain == nil || endpoint.Details.Chain.Issues&chainIssueIncomplete == 0 {
+			continue
+		}
+		// Los endpoints suelen servir la misma cadena
+		key := endpoint.CertFingerprint
+		check, ok := checked[key]
+		if !ok {
+			check = chaseAIA(ctx, c.client, parseChainCerts(endpoint.Details.Chain))
+			checked[key] = check
+		}
+		endpoint.AIA = check
+	}
+	return result, nil
+}
+
+// chaseAIA follows the caIssuers URLs from the last certificate of the
+// served chain (starting at the leaf) until a self-signed certificate or
+// one without caIssuers, which is expected to be issued by a root
+func chaseAIA(ctx context.Context, client *http.Client, served []*x509.Certificate) *AIACheck {
+	check := &AIACheck{}
+	if len(served) == 0 || served[0] == nil {
+		check.Problem = tr("la cadena no incluye el PEM del certificado")
+		return check
+	}
+
+	// Último certificado alcanzable desde el del servidor con la cadena enviada
+	current := served[0]
+	for range served {
+		issuer := findIssuer(current, served)
+		if issuer == nil {
+			break
+		}
+		current = issuer
+	}
+
+	for depth := 0; ; depth++ {
+		// Sin caIssuers se asume que lo emitió una raíz, salvo en el
+		// último certificado enviado: ahí falta el intermedio
+		switch {
+		case current.CheckSignatureFrom(current) == nil, len(current.IssuingCertificateURL) == 0 && depth > 0:
+			check.Resolved = true
+			return check
+		case len(current.IssuingCertificateURL) == 0:
+			check.Problem = fmt.Sprintf(tr("%s no indica la URL de su emisor (caIssuers)"), current.Subject.CommonName)
+			return check
+		}
+		if depth == aiaMaxDepth {
+			check.Problem = fmt.Sprintf(tr("la cadena no termina después de %d intermedios"), aiaMaxDepth)
+			return check
+		}
+
+		var issuer *x509.Certificate
+		var problems []string
+		for _, url := range current.IssuingCertificateURL {
+			certs, err := fetchAIA(ctx, client, url)
+			if err == nil {
+				issuer = findIssuer(current, certs)
+				if issuer == nil {
+					err = fmt.Errorf(tr("no contiene el emisor de %s"), current.Subject.CommonName)
+				}
+			}
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %s", url, err))
+				continue
+			}
+			check.Fetched = append(check.Fetched, fmt.Sprintf("%s (%s)", certLabel(issuer.Subject.CommonName, issuer.Subject.Organization), url))
+			break
+		}
+		if issuer == nil {
+			check.Problem = fmt.Sprintf(tr("no se pudo obtener el emisor de %s: %s"), current.Subject.CommonName, strings.Join(problems, "; "))
+			return check
+		}
+		current = issuer
+	}
+}
+
+// fetchAIA downloads the certificates at a caIssuers URL, served as DER,
+// PEM or a PKCS#7 bundle (.p7c)
+func fetchAIA(ctx context.Context, client *http.Client, url string) ([]*x509.Certificate, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf(tr("URL inválida: %w"), err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf(tr("inalcanzable: %w"), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf(tr("respondió HTTP %d"), resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, aiaMaxSize))
+	if err != nil {
+		return nil, fmt.Errorf(tr("descarga incompleta: %w"), err)
+	}
+	return parseAIACerts(data)
+}
+
+// parseAIACerts parses DER, PEM or degenerate PKCS#7 certificates
+func parseAIACerts(data []byte) ([]*x509.Certificate, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		data = block.Bytes
+	}
+	if cert, err := x509.ParseCertificate(data); err == nil {
+		return []*x509.Certificate{cert}, nil
+	}
+
+	// PKCS#7 SignedData sin firmas, solo con certificados (RFC 2315)
+	var contentInfo struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue `asn1:"explicit,tag:0"`
+	}
+	var signedData struct {
+		Version          int
+		DigestAlgorithms asn1.RawValue
+		ContentInfo      asn1.RawValue
+		Certificates     asn1.RawValue `asn1:"tag:0"`
+	}
+	if _, err := asn1.Unmarshal(data, &contentInfo); err != nil {
+		return nil, errors.New(tr("no es un certificado DER, PEM ni PKCS#7"))
+	}
+	if _, err := asn1.Unmarshal(contentInfo.Content.Bytes, &signedData); err != nil {
+		return nil, fmt.Errorf(tr("PKCS#7 inválido: %w"), err)
+	}
+	certs, err := x509.ParseCertificates(signedData.Certificates.Bytes)
+	if err != nil || len(certs) == 0 {
+		return nil, errors.New(tr("PKCS#7 sin certificados válidos"))
+	}
+	return certs, nil
+}
+
+// displayAIA prints the result of completing an incomplete chain
+func displayAIA(check *AIACheck) {
+	switch {
+	case check.Resolved && len(check.Fetched) == 0:
+		fmt.Print(tr("Intermedios por AIA: la cadena enviada termina en una raíz\n"))
+	case check.Resolved:
+		fmt.Printf(tr("Intermedios por AIA: %s\n"), paint(colorYellow, tr("⚠️  se obtienen, pero solo los clientes que siguen AIA (navegadores) completan la cadena; curl, OpenSSL, Java y muchas apps fallan")))
+		for _, fetched := range check.Fetched {
+			fmt.Printf("  %s\n", fetched)
+		}
+	default:
+		fmt.Printf(tr("Intermedios por AIA: %s\n"), paint(colorRed, tr("❌ cadena incompleta y el AIA falla: ni los clientes que siguen AIA pueden validarla")))
+		fmt.Printf("  %s\n", paint(colorRed, check.Problem))
+	}
+}
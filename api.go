@@ -0,0 +1,552 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ssllabs-scanner/store"
+)
+
+const (
+	apiJobRetention = time.Hour // Tiempo que un trabajo terminado sigue disponible en GET /scan/{id}
+	apiMaxRunning   = 20        // Trabajos sin terminar; POST /scan responde 429 pasado el límite
+)
+
+// Estados de un trabajo de la API
+const (
+	jobRunning = "running" // Evaluación en curso (o esperando capacidad en la API)
+	jobDone    = "done"    // Evaluación completada
+	jobFailed  = "failed"  // La evaluación terminó con error
+)
+
+// scanJob is an assessment requested through POST /scan
+type scanJob struct {
+	ID         string
+	Domain     string
+	Status     string
+	Error      string
+	ErrorCode  string // Código estable del error (ver errorCode)
+	CreatedAt  time.Time
+	FinishedAt time.Time
+	Result     *AssessmentResult
+}
+
+// ScanAPI serves the HTTP API of serve --api: POST /scan starts an
+// assessment in the background, GET /scan/{id} reports its status and
+// result, GET /results/{domain} returns the latest result of a domain
+// from memory or, when there is one, the history, and GET /findings and
+// PATCH /findings[/{id}] list the findings of the history and change their
+// state or owner, one or many at once
+type ScanAPI struct {
+	ctx           context.Context // Contexto del servidor: cancela los trabajos al cerrarlo
+	scanner       *Scanner
+	exporter      *Exporter   // Resultados de los dominios monitoreados (opcional)
+	history       store.Store // Historial donde guardar y buscar evaluaciones (opcional)
+	config        *Config     // Etiquetas de los filtros de hallazgos (opcional)
+	notifications *Notifications
+	token         string // Bearer token requerido en cada petición
+
+	slots   chan struct{} // Semáforo de los trabajos sin terminar, con capacidad apiMaxRunning
+	mu      sync.Mutex
+	jobs    map[string]*scanJob
+	results map[string]*AssessmentResult // Último resultado de cada dominio evaluado por la API
+}
+
+// NewScanAPI creates the API on top of scanner. The assessments run until
+// they finish or ctx is cancelled.
+func NewScanAPI(ctx context.Context, scanner *Scanner) *ScanAPI {
+	return &ScanAPI{
+		ctx:     ctx,
+		scanner: scanner,
+		slots:   make(chan struct{}, apiMaxRunning),
+		jobs:    make(map[string]*scanJob),
+		results: make(map[string]*AssessmentResult),
+	}
+}
+
+// Register adds the API routes to mux
+func (a *ScanAPI) Register(mux *http.ServeMux) {
+	mux.Handle("POST /scan", a.authorize(a.handleScan))
+	mux.Handle("GET /scan/{id}", a.authorize(a.handleJob))
+	mux.Handle("GET /results/{domain}", a.authorize(a.handleResults))
+	mux.Handle("GET /findings", a.authorize(a.handleFindings))
+	mux.Handle("PATCH /findings", a.authorize(a.handleFindingsBulk))
+	mux.Handle("PATCH /findings/{id}", a.authorize(a.handleFindingState))
+}
+
+// authorize rejects requests without the configured bearer token
+func (a *ScanAPI) authorize(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeAPIError(w, http.StatusUnauthorized, "unauthorized", "token inválido o ausente")
+			return
+		}
+		next(w, r)
+	})
+}
+
+// handleScan implements POST /scan with a body like {"domain": "example.com"}
+func (a *ScanAPI) handleScan(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Domain string `json:"domain"`
+	}
+	if !decodeBody(w, r, &request) {
+		return
+	}
+	domain := strings.TrimSpace(request.Domain)
+	if err := validateDomain(domain); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_domain", fmt.Sprintf("%s: %s", domain, err))
+		return
+	}
+
+	job, ok := a.start(domain)
+	if !ok {
+		// Cada trabajo ocupa una goroutine hasta que SSL Labs termina: sin
+		// límite, un cliente podría acumular miles esperando capacidad
+		w.Header().Set("Retry-After", "60")
+		writeAPIError(w, http.StatusTooManyRequests, "too_many_jobs", fmt.Sprintf("hay %d evaluaciones en curso, el máximo de la API", apiMaxRunning))
+		return
+	}
+	w.Header().Set("Location", "/scan/"+job.ID)
+	writeJSON(w, http.StatusAccepted, a.jobResponse(job))
+}
+
+// handleJob implements GET /scan/{id}
+func (a *ScanAPI) handleJob(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	job, ok := a.jobs[r.PathValue("id")]
+	a.mu.Unlock()
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, "job_not_found", "trabajo desconocido o expirado")
+		return
+	}
+	writeJSON(w, http.StatusOK, a.jobResponse(job))
+}
+
+// handleResults implements GET /results/{domain}
+func (a *ScanAPI) handleResults(w http.ResponseWriter, r *http.Request) {
+	domain := r.PathValue("domain")
+	if result := a.latest(domain); result != nil {
+		writeJSON(w, http.StatusOK, newAPIResult(historyEntryFromResult(result), result.EndpointErrors))
+		return
+	}
+
+	if a.history != nil {
+		entries, err := a.history.List(domain, 1)
+		if err != nil {
+			slog.Error("no se pudo leer el historial", "domain", domain, "error", err)
+			writeAPIError(w, http.StatusInternalServerError, "history_error", "no se pudo leer el historial")
+			return
+		}
+		if len(entries) > 0 {
+			writeJSON(w, http.StatusOK, newAPIResult(entries[0], nil))
+			return
+		}
+	}
+	writeAPIError(w, http.StatusNotFound, "no_results", fmt.Sprintf("no hay resultados de %s", domain))
+}
+
+// apiFindingFilter is the filter of GET /findings (as query parameters) and
+// of PATCH /findings (as the "filter" object)
+type apiFindingFilter struct {
+	State    string `json:"state"`
+	Domain   string `json:"domain"`
+	Tag      string `json:"tag"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Owner    string `json:"owner"`
+}
+
+// findingFilter validates f and converts it to a FindingFilter, resolving
+// its tag with the configuration of serve
+func (a *ScanAPI) findingFilter(f apiFindingFilter) (store.FindingFilter, error) {
+	filter := store.FindingFilter{State: f.State, Domain: f.Domain, Rule: f.Rule, Severity: f.Severity, Owner: f.Owner}
+	if filter.State != "" {
+		if err := store.ValidateFindingState(filter.State); err != nil {
+			return filter, err
+		}
+	}
+	if filter.Severity != "" && !slices.Contains(store.FindingSeverities, filter.Severity) {
+		return filter, fmt.Errorf("severidad inválida %q: se espera %s", filter.Severity, strings.Join(store.FindingSeverities, ", "))
+	}
+	if f.Tag != "" {
+		domains, err := a.config.tagDomains(f.Tag)
+		if err != nil {
+			return filter, err
+		}
+		filter.Domains = domains
+	}
+	return filter, nil
+}
+
+// apiFindingChange is the change of PATCH /findings and /findings/{id}: a
+// state with its comment, an owner ("" unassigns), or both
+type apiFindingChange struct {
+	State   string  `json:"state"`
+	Comment string  `json:"comment"`
+	Owner   *string `json:"owner"`
+}
+
+// validate checks that the change has a valid state or an owner
+func (c apiFindingChange) validate() error {
+	if c.State == "" && c.Owner == nil {
+		return fmt.Errorf("se requiere state u owner")
+	}
+	if c.State != "" {
+		return store.ValidateFindingState(c.State)
+	}
+	return nil
+}
+
+// apply makes the change to the findings ids
+func (c apiFindingChange) apply(history store.Store, ids []int64) error {
+	if c.State != "" {
+		if err := history.SetFindingState(ids, c.State, c.Comment); err != nil {
+			return err
+		}
+	}
+	if c.Owner != nil {
+		return history.AssignFindings(ids, *c.Owner)
+	}
+	return nil
+}
+
+// handleFindings implements GET /findings, filtered by the state, domain,
+// tag, rule, severity and owner query parameters
+func (a *ScanAPI) handleFindings(w http.ResponseWriter, r *http.Request) {
+	if a.history == nil {
+		writeAPIError(w, http.StatusNotFound, "history_disabled", "el historial está deshabilitado")
+		return
+	}
+	query := r.URL.Query()
+	filter, err := a.findingFilter(apiFindingFilter{State: query.Get("state"), Domain: query.Get("domain"), Tag: query.Get("tag"),
+		Rule: query.Get("rule"), Severity: query.Get("severity"), Owner: query.Get("owner")})
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_filter", err.Error())
+		return
+	}
+	findings, err := a.history.Findings(filter)
+	if err != nil {
+		slog.Error("no se pudo leer el historial", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, "history_error", "no se pudo leer el historial")
+		return
+	}
+	writeJSON(w, http.StatusOK, newAPIFindings(findings))
+}
+
+// handleFindingState implements PATCH /findings/{id} with a body like
+// {"state": "acknowledged", "comment": "...", "owner": "..."}
+func (a *ScanAPI) handleFindingState(w http.ResponseWriter, r *http.Request) {
+	if a.history == nil {
+		writeAPIError(w, http.StatusNotFound, "history_disabled", "el historial está deshabilitado")
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeAPIError(w, http.StatusNotFound, "finding_not_found", "hallazgo desconocido")
+		return
+	}
+	var change apiFindingChange
+	if !decodeBody(w, r, &change) {
+		return
+	}
+	if err := change.validate(); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_change", err.Error())
+		return
+	}
+
+	err = change.apply(a.history, []int64{id})
+	if errors.Is(err, store.ErrFindingNotFound) {
+		writeAPIError(w, http.StatusNotFound, "finding_not_found", "hallazgo desconocido")
+		return
+	}
+	var findings []store.Finding
+	if err == nil {
+		findings, err = a.history.Findings(store.FindingFilter{ID: id})
+	}
+	if err != nil || len(findings) == 0 {
+		slog.Error("no se pudo actualizar el hallazgo", "finding", id, "error", err)
+		writeAPIError(w, http.StatusInternalServerError, "history_error", "no se pudo actualizar el hallazgo")
+		return
+	}
+	slog.Info("hallazgo actualizado por la API", "finding", id, "state", change.State)
+	writeJSON(w, http.StatusOK, newAPIFindings(findings)[0])
+}
+
+// handleFindingsBulk implements PATCH /findings, which changes every
+// finding that matches a filter, with a body like {"filter": {"tag":
+// "pagos"}, "state": "acknowledged", "comment": "..."}. Unless the filter
+// has a state, it skips the findings already in the new state and, except
+// to reopen them, the resolved ones (see selectFindings).
+func (a *ScanAPI) handleFindingsBulk(w http.ResponseWriter, r *http.Request) {
+	if a.history == nil {
+		writeAPIError(w, http.StatusNotFound, "history_disabled", "el historial está deshabilitado")
+		return
+	}
+	var request struct {
+		Filter apiFindingFilter `json:"filter"`
+		apiFindingChange
+	}
+	if !decodeBody(w, r, &request) {
+		return
+	}
+	if request.Filter == (apiFindingFilter{}) {
+		// Sin filtro cambiaría todos los hallazgos, casi seguro por error
+		writeAPIError(w, http.StatusBadRequest, "invalid_filter", "se requiere un filtro con al menos un campo")
+		return
+	}
+	filter, err := a.findingFilter(request.Filter)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_filter", err.Error())
+		return
+	}
+	if err := request.validate(); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_change", err.Error())
+		return
+	}
+
+	selected, err := selectFindings(a.history, filter, request.State)
+	if err == nil && len(selected) > 0 {
+		ids := make([]int64, len(selected))
+		for i, f := range selected {
+			ids[i] = f.ID
+		}
+		if err = request.apply(a.history, ids); err == nil {
+			// El cambio pudo sacarlos del filtro por estado o responsable
+			reload := filter
+			reload.State, reload.Owner = "", ""
+			selected, err = a.history.Findings(reload)
+			selected = slices.DeleteFunc(selected, func(f store.Finding) bool { return !slices.Contains(ids, f.ID) })
+		}
+	}
+	if err != nil {
+		slog.Error("no se pudieron actualizar los hallazgos", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, "history_error", "no se pudieron actualizar los hallazgos")
+		return
+	}
+	slog.Info("hallazgos actualizados por la API", "count", len(selected), "state", request.State)
+	writeJSON(w, http.StatusOK, map[string]any{"updated": len(selected), "findings": newAPIFindings(selected)})
+}
+
+// decodeBody decodes the JSON body of r into dst, rejecting unknown
+// fields. It writes the error response and returns false when it fails.
+func decodeBody(w http.ResponseWriter, r *http.Request, dst any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_body", fmt.Sprintf("cuerpo inválido: %s", err))
+		return false
+	}
+	return true
+}
+
+// start registers a job for domain and runs the assessment in the
+// background. It reports false, starting nothing, when apiMaxRunning jobs
+// are still running.
+func (a *ScanAPI) start(domain string) (*scanJob, bool) {
+	select {
+	case a.slots <- struct{}{}:
+	default:
+		return nil, false
+	}
+	id := make([]byte, 8)
+	rand.Read(id)
+	job := &scanJob{ID: hex.EncodeToString(id), Domain: domain, Status: jobRunning, CreatedAt: time.Now()}
+
+	a.mu.Lock()
+	a.prune(job.CreatedAt)
+	a.jobs[job.ID] = job
+	a.mu.Unlock()
+
+	go a.run(job)
+	return job, true
+}
+
+// run assesses the domain of a job and records the outcome. The result is
+// also saved in the history and notified, like in the monitoring loop.
+func (a *ScanAPI) run(job *scanJob) {
+	defer func() { <-a.slots }()
+	slog.Info("evaluación solicitada por la API", "domain", job.Domain, "job", job.ID)
+
+	result, err := a.scanner.AssessContext(a.ctx, job.Domain)
+	if err == nil {
+		recordAssessment(a.history, a.notifications, result)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	job.FinishedAt = time.Now()
+	if err != nil {
+		slog.Error("evaluación fallida", "domain", job.Domain, "job", job.ID, "error", err)
+		job.Status = jobFailed
+		job.Error = err.Error()
+		job.ErrorCode = errorCode(err)
+		return
+	}
+	job.Status = jobDone
+	job.Result = result
+	a.results[job.Domain] = result
+}
+
+// prune forgets the jobs finished more than apiJobRetention ago. Must be
+// called with mu held.
+func (a *ScanAPI) prune(now time.Time) {
+	for id, job := range a.jobs {
+		if !job.FinishedAt.IsZero() && now.Sub(job.FinishedAt) > apiJobRetention {
+			delete(a.jobs, id)
+		}
+	}
+}
+
+// latest returns the newest result of domain assessed by the API or by the
+// monitoring loop, or nil if there is none in memory
+func (a *ScanAPI) latest(domain string) *AssessmentResult {
+	a.mu.Lock()
+	result := a.results[domain]
+	a.mu.Unlock()
+
+	if a.exporter != nil {
+		if monitored := a.exporter.Latest(domain); monitored != nil && (result == nil || monitored.TestTime > result.TestTime) {
+			result = monitored
+		}
+	}
+	return result
+}
+
+// apiJob is the JSON representation of a job
+type apiJob struct {
+	ID         string     `json:"id"`
+	Domain     string     `json:"domain"`
+	Status     string     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	ErrorCode  string     `json:"errorCode,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Result     *apiResult `json:"result,omitempty"`
+}
+
+// apiResult is the JSON representation of an assessment result
+type apiResult struct {
+	Domain         string           `json:"domain"`
+	Grade          string           `json:"grade"`
+	ScannedAt      time.Time        `json:"scannedAt"`
+	Endpoints      []apiEndpoint    `json:"endpoints"`
+	EndpointErrors []apiEndpointErr `json:"endpointErrors,omitempty"`
+	Metadata       *store.Metadata  `json:"metadata,omitempty"`
+}
+
+// apiEndpoint is the JSON representation of an assessed endpoint
+type apiEndpoint struct {
+	IPAddress       string     `json:"ipAddress"`
+	Grade           string     `json:"grade"`
+	Protocols       []string   `json:"protocols"`
+	CertFingerprint string     `json:"certFingerprint,omitempty"`
+	CertIssuer      string     `json:"certIssuer,omitempty"`
+	CertNotAfter    *time.Time `json:"certNotAfter,omitempty"`
+	Vulnerabilities []string   `json:"vulnerabilities"`
+}
+
+// apiEndpointErr is the JSON representation of an endpoint that could not be assessed
+type apiEndpointErr struct {
+	IPAddress string `json:"ipAddress"`
+	Message   string `json:"message"`
+}
+
+// jobResponse converts a job to its JSON representation
+func (a *ScanAPI) jobResponse(job *scanJob) apiJob {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	response := apiJob{
+		ID:        job.ID,
+		Domain:    job.Domain,
+		Status:    job.Status,
+		Error:     job.Error,
+		ErrorCode: job.ErrorCode,
+		CreatedAt: job.CreatedAt.In(outputLocation),
+	}
+	if !job.FinishedAt.IsZero() {
+		finished := job.FinishedAt.In(outputLocation)
+		response.FinishedAt = &finished
+	}
+	if job.Result != nil {
+		result := newAPIResult(historyEntryFromResult(job.Result), job.Result.EndpointErrors)
+		response.Result = &result
+	}
+	return response
+}
+
+// newAPIResult converts a live or stored assessment to its JSON
+// representation. Times use the --tz zone.
+func newAPIResult(entry store.Entry, endpointErrors []EndpointError) apiResult {
+	result := apiResult{
+		Domain:    entry.Domain,
+		Grade:     entry.OverallGrade,
+		ScannedAt: entry.ScannedAt.In(outputLocation),
+		Endpoints: []apiEndpoint{},
+	}
+	for _, endpoint := range entry.Endpoints {
+		converted := apiEndpoint{
+			IPAddress:       endpoint.IPAddress,
+			Grade:           endpoint.Grade,
+			Protocols:       nonNil(endpoint.Protocols),
+			CertFingerprint: endpoint.CertFingerprint,
+			CertIssuer:      endpoint.CertIssuer,
+			Vulnerabilities: nonNil(endpoint.Vulnerabilities),
+		}
+		if endpoint.CertNotAfter > 0 {
+			notAfter := time.UnixMilli(endpoint.CertNotAfter).In(outputLocation)
+			converted.CertNotAfter = &notAfter
+		}
+		result.Endpoints = append(result.Endpoints, converted)
+	}
+	for _, endpointErr := range endpointErrors {
+		result.EndpointErrors = append(result.EndpointErrors, apiEndpointErr{IPAddress: endpointErr.IPAddress, Message: endpointErr.Message})
+	}
+	if entry.Metadata != nil {
+		metadata := *entry.Metadata
+		metadata.StartedAt = metadata.StartedAt.In(outputLocation)
+		metadata.FinishedAt = metadata.FinishedAt.In(outputLocation)
+		result.Metadata = &metadata
+	}
+	return result
+}
+
+// nonNil returns list, or an empty list instead of nil so it is encoded as []
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
+
+// writeJSON writes value as the JSON body of the response
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(value); err != nil {
+		slog.Warn("no se pudo escribir la respuesta", "error", err)
+	}
+}
+
+// writeAPIError writes an error response like {"error": "...", "code": "..."}:
+// the message is for people, the code is stable and in English for clients
+func writeAPIError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]string{"error": message, "code": code})
+}
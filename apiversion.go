@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Versiones soportadas de la API de SSL Labs
+const (
+	apiVersionAuto = 0 // Se elige según la configuración (ver resolveAPIVersion)
+	apiVersionV2   = 2
+	apiVersionV3   = 3
+	apiVersionV4   = 4
+)
+
+// parseAPIVersion parses the --api-version flag value ("2", "3", "4" or "auto")
+func parseAPIVersion(value string) (int, error) {
+	switch strings.TrimPrefix(strings.ToLower(strings.TrimSpace(value)), "v") {
+	case "auto", "":
+		return apiVersionAuto, nil
+	case "2":
+		return apiVersionV2, nil
+	case "3":
+		return apiVersionV3, nil
+	case "4":
+		return apiVersionV4, nil
+	default:
+		return 0, fmt.Errorf("versión de API inválida: %q (valores posibles: 2, 3, 4, auto)", value)
+	}
+}
+
+// resolveAPIVersion picks the API version to use when "auto" was requested:
+// v4 when a registered email is available, v2 otherwise
+func resolveAPIVersion(version int, email string) int {
+	if version != apiVersionAuto {
+		return version
+	}
+	if email != "" {
+		return apiVersionV4
+	}
+	return apiVersionV2
+}
+
+// apiBaseURL returns the base URL of the given API version
+func apiBaseURL(version int) string {
+	return fmt.Sprintf("%s/v%d", apiRootURL, version)
+}
+
+// parseAPIURL validates the --api-url value: the full base URL of a
+// compatible API including the version, e.g. https://api.dev.ssllabs.com/api/v4
+func parseAPIURL(value string) (string, error) {
+	apiURL, err := url.Parse(value)
+	if err != nil {
+		return "", fmt.Errorf("URL de la API inválida %q: %w", value, err)
+	}
+	if apiURL.Scheme != "http" && apiURL.Scheme != "https" {
+		return "", fmt.Errorf("URL de la API inválida %q: se espera http:// o https://", value)
+	}
+	if apiURL.Host == "" {
+		return "", fmt.Errorf("URL de la API sin host: %q", value)
+	}
+	if apiURL.RawQuery != "" || apiURL.Fragment != "" {
+		return "", fmt.Errorf("URL de la API inválida %q: no debe incluir query ni fragmento", value)
+	}
+	return strings.TrimSuffix(apiURL.String(), "/"), nil
+}
+
+// normalizeCerts maps the API v3/v4 certificate layout (certificates at host
+// level, referenced by ID from each endpoint's certChains) to the v2 layout
+// used by ProcessResults, where each endpoint carries its own leaf cert.
+// The leaves of the other chains (servers with RSA and ECDSA certificates)
+// go to AltCerts.
+func normalizeCerts(host *Host) {
+	certsByID := make(map[string]*Cert, len(host.Certs))
+	for i := range host.Certs {
+		cert := &host.Certs[i]
+		if cert.IssuerLabel == "" {
+			cert.IssuerLabel = issuerLabelFromSubject(cert.IssuerSubject)
+		}
+		certsByID[cert.ID] = cert
+	}
+
+	for i := range host.Endpoints {
+		details := host.Endpoints[i].Details
+		if details == nil || details.Cert != nil {
+			continue
+		}
+
+		// El primer certificado de cada cadena es uno del servidor: el de la
+		// primera es el principal. Las cadenas que solo se obtienen sin SNI
+		// no son las que ven los clientes.
+		seen := make(map[string]bool)
+		for _, chain := range details.CertChains {
+			if len(chain.CertIDs) == 0 || chain.NoSNI || seen[chain.CertIDs[0]] {
+				continue
+			}
+			cert, ok := certsByID[chain.CertIDs[0]]
+			if !ok {
+				continue
+			}
+			seen[cert.ID] = true
+			if details.Cert == nil {
+				details.Cert = cert
+				if details.Chain == nil {
+					details.Chain = chainFromCertIDs(chain, certsByID)
+				}
+				continue
+			}
+			details.AltCerts = append(details.AltCerts, ServedCert{Cert: cert, Chain: chainFromCertIDs(chain, certsByID)})
+		}
+	}
+}
+
+// chainFromCertIDs builds the v2 Chain of a v3/v4 certificate chain
+func chainFromCertIDs(certChain CertChain, certsByID map[string]*Cert) *Chain {
+	chain := &Chain{Issues: certChain.Issues, Trust: trustByStore(certChain.TrustPaths)}
+	for _, id := range certChain.CertIDs {
+		cert, ok := certsByID[id]
+		if !ok {
+			continue
+		}
+
+		// Los bits de Cert.Issues se traducen a los de ChainCert.Issues
+		issues := 0
+		if cert.Issues&certIssueNotBefore != 0 {
+			issues |= chainCertIssueNotYetValid
+		}
+		if cert.Issues&certIssueNotAfter != 0 {
+			issues |= chainCertIssueExpired
+		}
+		if cert.Issues&certIssueInsecureSignature != 0 {
+			issues |= chainCertIssueWeakSignature
+		}
+		if cert.Issues&certIssueBlacklisted != 0 {
+			issues |= chainCertIssueBlacklisted
+		}
+
+		chain.Certs = append(chain.Certs, ChainCert{
+			Subject:          cert.Subject,
+			Label:            commonNameFromSubject(cert.Subject),
+			NotBefore:        cert.NotBefore,
+			NotAfter:         cert.NotAfter,
+			IssuerSubject:    cert.IssuerSubject,
+			IssuerLabel:      cert.IssuerLabel,
+			SigAlg:           cert.SigAlg,
+			Issues:           issues,
+			KeyAlg:           cert.KeyAlg,
+			KeySize:          cert.KeySize,
+			KeyStrength:      cert.KeyStrength,
+			RevocationStatus: cert.RevocationStatus,
+			Raw:              cert.Raw,
+		})
+	}
+	return chain
+}
+
+// issuerLabelFromSubject extracts a user-friendly issuer name from a
+// distinguished name, preferring the organization (O) over the common name (CN)
+func issuerLabelFromSubject(subject string) string {
+	var organization, commonName string
+	for _, part := range strings.Split(subject, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch strings.ToUpper(key) {
+		case "O":
+			organization = value
+		case "CN":
+			commonName = value
+		}
+	}
+
+	if organization != "" {
+		return organization
+	}
+	return commonName
+}
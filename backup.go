@@ -0,0 +1,634 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"ssllabs-scanner/store"
+)
+
+// backupFormat is the version of the layout of a backup, recorded in its
+// manifest so that restore rejects archives it doesn't understand
+const backupFormat = 1
+
+// Nombres dentro del respaldo
+const (
+	backupManifestName  = "manifest.json"
+	backupHistoryName   = "history.db"
+	backupConfigName    = "config/config.yaml"
+	backupDataPrefix    = "data/"
+	backupIncludePrefix = "include/"
+)
+
+// Compresión del respaldo, según la extensión del archivo
+const (
+	compressionZstd = "zstd"
+	compressionGzip = "gzip"
+)
+
+// zstdMagic starts every zstd frame; restore uses it to tell a .tar.zst
+// from a .tar.gz without trusting the file name
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// backupCompression returns the compression of a backup named name
+func backupCompression(name string) (string, error) {
+	switch {
+	case strings.HasSuffix(name, ".tar.zst"), strings.HasSuffix(name, ".tzst"):
+		return compressionZstd, nil
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return compressionGzip, nil
+	}
+	return "", fmt.Errorf("el respaldo %s debe terminar en .tar.zst o .tar.gz", name)
+}
+
+// backupPaths are the local data of a monitoring setup: what backup
+// archives and where restore puts it back
+type backupPaths struct {
+	History  string   // Base SQLite del historial
+	Config   string   // Archivo de configuración
+	DataDir  string   // Directorio de datos: almacén de confianza y demás
+	Includes []string // Archivos o directorios adicionales (--include); se restauran en su ruta original
+}
+
+// backupManifest describes a backup: it is the last file of the archive
+type backupManifest struct {
+	Format        int          `json:"format"`
+	ToolVersion   string       `json:"toolVersion"`
+	CreatedAt     time.Time    `json:"createdAt"`
+	SchemaVersion int          `json:"schemaVersion,omitempty"` // Esquema del historial, 0 sin historial
+	Files         []backupFile `json:"files"`
+}
+
+// backupFile is one file of a backup
+type backupFile struct {
+	Name   string      `json:"name"`             // Ruta dentro del respaldo
+	Origin string      `json:"origin,omitempty"` // Ruta original de los --include, con ~ por el home
+	Mode   fs.FileMode `json:"mode"`
+	Size   int64       `json:"size"`
+	SHA256 string      `json:"sha256"`
+	path   string      // Dónde se lee al respaldar, o dónde se extrajo al restaurar
+}
+
+// collectBackup lists the files of paths that go in a backup. The history
+// is copied to a snapshot in tmpDir, so the backup is consistent even while
+// serve is writing to it.
+func collectBackup(paths backupPaths, tmpDir string) (*backupManifest, error) {
+	manifest := &backupManifest{Format: backupFormat, ToolVersion: toolVersion(), CreatedAt: time.Now().UTC()}
+	add := func(name, origin, path string) {
+		manifest.Files = append(manifest.Files, backupFile{Name: name, Origin: origin, path: path})
+	}
+
+	if exists, err := regularFileExists(paths.History); err != nil {
+		return nil, err
+	} else if exists {
+		snapshot := filepath.Join(tmpDir, backupHistoryName)
+		version, err := snapshotHistory(paths.History, snapshot)
+		if err != nil {
+			return nil, err
+		}
+		manifest.SchemaVersion = version
+		add(backupHistoryName, "", snapshot)
+	}
+	if exists, err := regularFileExists(paths.Config); err != nil {
+		return nil, err
+	} else if exists {
+		add(backupConfigName, "", paths.Config)
+	}
+
+	// Del directorio de datos, todo menos la base del historial en uso
+	// (ya está en la copia) y sus archivos auxiliares
+	live := []string{paths.History, paths.History + "-wal", paths.History + "-shm", paths.History + "-journal"}
+	err := walkFiles(paths.DataDir, func(path, rel string) {
+		if !slices.ContainsFunc(live, func(p string) bool { return sameFile(p, path) }) {
+			add(backupDataPrefix+rel, "", path)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, include := range paths.Includes {
+		abs, err := filepath.Abs(include)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(abs); err != nil {
+			return nil, fmt.Errorf("no se puede respaldar %s: %w", include, err)
+		}
+		prefix := backupIncludePrefix + strconv.Itoa(i+1) + "/"
+		err = walkFiles(abs, func(path, rel string) {
+			add(prefix+rel, homeRelative(path), path)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(manifest.Files) == 0 {
+		return nil, fmt.Errorf("no hay nada que respaldar: no existen %s ni %s", paths.History, paths.Config)
+	}
+	return manifest, nil
+}
+
+// snapshotHistory copies the history database at path to dst with VACUUM
+// INTO, which reads it in a single transaction, and returns its schema
+// version
+func snapshotHistory(path, dst string) (int, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return 0, fmt.Errorf("no se pudo abrir el historial: %w", err)
+	}
+	defer db.Close()
+	version, err := store.SchemaVersion(db)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := db.Exec(`VACUUM INTO ?`, dst); err != nil {
+		return 0, fmt.Errorf("no se pudo copiar el historial %s: %w", path, err)
+	}
+	return version, nil
+}
+
+// regularFileExists reports whether path is a regular file; a missing one
+// isn't an error
+func regularFileExists(path string) (bool, error) {
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return false, nil
+	case err != nil:
+		return false, err
+	case !info.Mode().IsRegular():
+		return false, fmt.Errorf("%s no es un archivo", path)
+	}
+	return true, nil
+}
+
+// walkFiles calls fn with each regular file under root (or root itself
+// when it is a file) and its slash-separated path relative to root. A
+// missing root has no files.
+func walkFiles(root string, fn func(path, rel string)) error {
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			rel = filepath.Base(path)
+		}
+		fn(path, filepath.ToSlash(rel))
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// sameFile reports whether a and b are the same existing file
+func sameFile(a, b string) bool {
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB)
+}
+
+// homeRelative returns path with the home directory replaced by ~, so
+// that restore puts the file in the home of the new machine
+func homeRelative(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	if rel, err := filepath.Rel(home, path); err == nil && filepath.IsLocal(rel) {
+		return "~/" + filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(path)
+}
+
+// expandHome reverses homeRelative
+func expandHome(origin string) (string, error) {
+	rest, ok := strings.CutPrefix(origin, "~/")
+	if !ok {
+		return filepath.FromSlash(origin), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, filepath.FromSlash(rest)), nil
+}
+
+// writeBackup writes the files of manifest, and then the manifest with
+// their sizes and checksums, as a tar archive with the given compression
+func writeBackup(w io.Writer, manifest *backupManifest, compression string) error {
+	var compressed io.WriteCloser = gzip.NewWriter(w)
+	if compression == compressionZstd {
+		zw, err := zstd.NewWriter(w)
+		if err != nil {
+			return err
+		}
+		compressed = zw
+	}
+	archive := tar.NewWriter(compressed)
+	for i := range manifest.Files {
+		if err := addBackupFile(archive, &manifest.Files[i]); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	header := &tar.Header{Name: backupManifestName, Mode: 0o644, Size: int64(len(data)), ModTime: manifest.CreatedAt}
+	if err := archive.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err := archive.Write(data); err != nil {
+		return err
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return compressed.Close()
+}
+
+// addBackupFile adds f to the archive, recording its mode, size and checksum
+func addBackupFile(archive *tar.Writer, f *backupFile) error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return fmt.Errorf("no se pudo respaldar %s: %w", f.path, err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("no se pudo respaldar %s: %w", f.path, err)
+	}
+	f.Mode, f.Size = info.Mode().Perm(), info.Size()
+
+	header := &tar.Header{Name: f.Name, Mode: int64(f.Mode), Size: f.Size, ModTime: info.ModTime()}
+	if err := archive.WriteHeader(header); err != nil {
+		return err
+	}
+	hash := sha256.New()
+	n, err := io.Copy(archive, io.TeeReader(io.LimitReader(file, f.Size), hash))
+	if err != nil {
+		return fmt.Errorf("no se pudo respaldar %s: %w", f.path, err)
+	}
+	if n != f.Size {
+		return fmt.Errorf("no se pudo respaldar %s: cambió durante la copia", f.path)
+	}
+	f.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return nil
+}
+
+// readBackup extracts the archive r, zstd or gzip, into dir and checks
+// every file against the manifest, so that nothing is restored from a
+// damaged or incomplete backup
+func readBackup(r io.Reader, dir string) (*backupManifest, error) {
+	buffered := bufio.NewReader(r)
+	var decompressed io.Reader
+	if magic, _ := buffered.Peek(len(zstdMagic)); bytes.Equal(magic, zstdMagic) {
+		zr, err := zstd.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("respaldo dañado: %w", err)
+		}
+		defer zr.Close()
+		decompressed = zr
+	} else {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("no es un respaldo de nebula (tar.zst o tar.gz): %w", err)
+		}
+		decompressed = gz
+	}
+	archive := tar.NewReader(decompressed)
+
+	var manifest *backupManifest
+	extracted := make(map[string]backupFile)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("respaldo dañado: %w", err)
+		}
+		name := header.Name
+		if header.Typeflag != tar.TypeReg || !filepath.IsLocal(filepath.FromSlash(name)) {
+			return nil, fmt.Errorf("respaldo inválido: entrada inesperada %q", name)
+		}
+		if _, ok := extracted[name]; ok || (name == backupManifestName && manifest != nil) {
+			return nil, fmt.Errorf("respaldo inválido: %s aparece dos veces", name)
+		}
+		if name == backupManifestName {
+			manifest = &backupManifest{}
+			if err := json.NewDecoder(io.LimitReader(archive, 1<<20)).Decode(manifest); err != nil {
+				return nil, fmt.Errorf("respaldo dañado: manifiesto inválido: %w", err)
+			}
+			continue
+		}
+		f, err := extractBackupFile(archive, filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, err
+		}
+		extracted[name] = f
+	}
+
+	switch {
+	case manifest == nil:
+		return nil, fmt.Errorf("no es un respaldo de nebula: falta %s", backupManifestName)
+	case manifest.Format != backupFormat:
+		return nil, fmt.Errorf("formato de respaldo %d no soportado: esta versión de nebula lee el %d", manifest.Format, backupFormat)
+	case manifest.SchemaVersion > store.LatestSchemaVersion():
+		return nil, fmt.Errorf("%w: el respaldo tiene el esquema %d y esta versión de nebula conoce hasta el %d", store.ErrNewerSchema, manifest.SchemaVersion, store.LatestSchemaVersion())
+	}
+	for i, f := range manifest.Files {
+		got, ok := extracted[f.Name]
+		switch {
+		case !ok:
+			return nil, fmt.Errorf("respaldo incompleto: falta %s", f.Name)
+		case got.Size != f.Size || got.SHA256 != f.SHA256:
+			return nil, fmt.Errorf("respaldo dañado: el checksum de %s no coincide", f.Name)
+		}
+		manifest.Files[i].path = got.path
+		delete(extracted, f.Name)
+	}
+	if len(extracted) > 0 {
+		names := slices.Sorted(maps.Keys(extracted))
+		return nil, fmt.Errorf("respaldo inválido: %s no figura en el manifiesto", strings.Join(names, ", "))
+	}
+	return manifest, nil
+}
+
+// extractBackupFile writes the current entry of archive to path
+func extractBackupFile(archive io.Reader, path string) (backupFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return backupFile{}, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return backupFile{}, err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	n, err := io.Copy(file, io.TeeReader(archive, hash))
+	if err != nil {
+		return backupFile{}, fmt.Errorf("respaldo dañado: %w", err)
+	}
+	return backupFile{Size: n, SHA256: hex.EncodeToString(hash.Sum(nil)), path: path}, nil
+}
+
+// restoreTarget returns where restore puts the file f of a backup. The
+// files of --include go back to their original path only inside the home
+// or the data directory, unless anyPath.
+func restoreTarget(f backupFile, paths backupPaths, anyPath bool) (string, error) {
+	switch {
+	case f.Name == backupHistoryName:
+		return paths.History, nil
+	case f.Name == backupConfigName:
+		return paths.Config, nil
+	case strings.HasPrefix(f.Name, backupDataPrefix):
+		return filepath.Join(paths.DataDir, filepath.FromSlash(strings.TrimPrefix(f.Name, backupDataPrefix))), nil
+	case strings.HasPrefix(f.Name, backupIncludePrefix) && f.Origin != "":
+		return includeTarget(f.Origin, paths.DataDir, anyPath)
+	}
+	return "", fmt.Errorf("respaldo inválido: no se sabe dónde restaurar %s", f.Name)
+}
+
+// includeTarget returns where an --include file recorded with origin is
+// restored. The path comes from the archive: without this check a crafted
+// backup could write anywhere the user can.
+func includeTarget(origin, dataDir string, anyPath bool) (string, error) {
+	if slices.Contains(strings.Split(origin, "/"), "..") {
+		return "", fmt.Errorf("respaldo inválido: la ruta %s tiene segmentos ..", origin)
+	}
+	target, err := expandHome(origin)
+	if err != nil {
+		return "", err
+	}
+	target = filepath.Clean(target)
+	if !filepath.IsAbs(target) {
+		return "", fmt.Errorf("respaldo inválido: la ruta %s no es absoluta", origin)
+	}
+	if anyPath || withinDir(dataDir, target) {
+		return target, nil
+	}
+	if home, err := os.UserHomeDir(); err == nil && withinDir(home, target) {
+		return target, nil
+	}
+	return "", fmt.Errorf("%s está fuera del home y del directorio de datos: usa --allow-any-path para restaurarlo ahí", target)
+}
+
+// withinDir reports whether path is dir or inside it
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && filepath.IsLocal(rel)
+}
+
+// restoreFile copies the extracted file f to target through a temporary
+// file and a rename, with its mode set before the rename so that a
+// configuration with secrets is never readable by others. Replacing the
+// history also removes its WAL files, which belong to the old database.
+func restoreFile(f backupFile, target string) error {
+	src, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".new-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), f.Mode.Perm()); err != nil {
+		return err
+	}
+	if f.Name == backupHistoryName {
+		for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+			if err := os.Remove(target + suffix); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+// runBackup implements the "backup" subcommand: it archives the history,
+// the configuration, the data directory and the paths of --include
+func runBackup(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	dsn := fs.String("history-db", defaultHistoryPath(), "base SQLite del historial a respaldar (ruta o sqlite:ruta)")
+	configPath := fs.String("config", defaultConfigPath(), "archivo de configuración a respaldar")
+	include := fs.String("include", "", "archivos o directorios adicionales a respaldar, separados por comas (ej: el directorio de --save con el archivo de informes)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s backup [--history-db archivo] [--config archivo] [--include rutas] <respaldo.tar.zst>\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("se requiere el archivo del respaldo")
+	}
+	output := fs.Arg(0)
+	compression, err := backupCompression(output)
+	if err != nil {
+		return err
+	}
+	historyPath, err := store.SQLitePath(*dsn)
+	if err != nil {
+		return err
+	}
+	paths := backupPaths{History: historyPath, Config: *configPath, DataDir: filepath.Dir(defaultHistoryPath()), Includes: splitList(*include)}
+
+	tmpDir, err := os.MkdirTemp("", "nebula-backup-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	manifest, err := collectBackup(paths, tmpDir)
+	if err != nil {
+		return err
+	}
+
+	// Se escribe al lado y se renombra al final: un respaldo a medias nunca
+	// queda con el nombre final. Incluye la configuración, que puede tener
+	// secretos, así que solo lo lee el dueño.
+	if err := os.MkdirAll(filepath.Dir(output), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(output), "."+filepath.Base(output)+".new-*")
+	if err != nil {
+		return fmt.Errorf("no se pudo escribir %s: %w", output, err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := writeBackup(tmp, manifest, compression); err != nil {
+		tmp.Close()
+		return fmt.Errorf("no se pudo escribir %s: %w", output, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("no se pudo escribir %s: %w", output, err)
+	}
+	if err := os.Rename(tmp.Name(), output); err != nil {
+		return fmt.Errorf("no se pudo escribir %s: %w", output, err)
+	}
+
+	for _, f := range manifest.Files {
+		fmt.Printf("  %-40s %s\n", f.Name, formatBytes(f.Size))
+	}
+	fmt.Printf("✅ Respaldo creado: %s (%d archivos", output, len(manifest.Files))
+	if manifest.SchemaVersion > 0 {
+		fmt.Printf(", historial con el esquema %d", manifest.SchemaVersion)
+	}
+	fmt.Println(")")
+	return nil
+}
+
+// runRestore implements the "restore" subcommand: it checks a backup and
+// puts its files back, without overwriting existing ones unless --force
+func runRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	dsn := fs.String("history-db", defaultHistoryPath(), "dónde restaurar la base SQLite del historial (ruta o sqlite:ruta)")
+	configPath := fs.String("config", defaultConfigPath(), "dónde restaurar el archivo de configuración")
+	force := fs.Bool("force", false, "reemplazar los archivos que ya existen")
+	dryRun := fs.Bool("dry-run", false, "verificar el respaldo y mostrar dónde se restauraría cada archivo, sin escribir nada")
+	anyPath := fs.Bool("allow-any-path", false, "restaurar los archivos de --include aunque su ruta original esté fuera del home y del directorio de datos")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s restore [--history-db archivo] [--config archivo] [--force] [--dry-run] [--allow-any-path] <respaldo.tar.zst>\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("se requiere el archivo del respaldo")
+	}
+	historyPath, err := store.SQLitePath(*dsn)
+	if err != nil {
+		return err
+	}
+	paths := backupPaths{History: historyPath, Config: *configPath, DataDir: filepath.Dir(defaultHistoryPath())}
+
+	file, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("no se pudo leer el respaldo: %w", err)
+	}
+	defer file.Close()
+	tmpDir, err := os.MkdirTemp("", "nebula-restore-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	manifest, err := readBackup(file, tmpDir)
+	if err != nil {
+		return err
+	}
+
+	// Todo se verifica antes de escribir el primer archivo
+	targets := make([]string, len(manifest.Files))
+	var existing []string
+	for i, f := range manifest.Files {
+		if targets[i], err = restoreTarget(f, paths, *anyPath); err != nil {
+			return err
+		}
+		if _, err := os.Stat(targets[i]); err == nil {
+			existing = append(existing, targets[i])
+		}
+	}
+	fmt.Printf("Respaldo de nebula %s, creado %s\n", manifest.ToolVersion, formatDateTime(manifest.CreatedAt))
+	for i, f := range manifest.Files {
+		fmt.Printf("  %-40s → %s\n", f.Name, targets[i])
+	}
+	if len(existing) > 0 && !*force {
+		return fmt.Errorf("ya existen %s: usa --force para reemplazarlos", strings.Join(existing, ", "))
+	}
+	if *dryRun {
+		fmt.Println("Respaldo verificado: no se escribió nada (--dry-run)")
+		return nil
+	}
+
+	for i, f := range manifest.Files {
+		if err := restoreFile(f, targets[i]); err != nil {
+			return fmt.Errorf("no se pudo restaurar %s: %w", targets[i], err)
+		}
+	}
+	fmt.Printf("✅ Respaldo restaurado: %d archivos\n", len(manifest.Files))
+	if manifest.SchemaVersion > 0 && manifest.SchemaVersion < store.LatestSchemaVersion() {
+		fmt.Printf("El historial tiene el esquema %d: se migra al %d la próxima vez que se abra (o con %s db migrate)\n",
+			manifest.SchemaVersion, store.LatestSchemaVersion(), os.Args[0])
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ssllabs-scanner/store"
+)
+
+// TestBackupRoundTrip backs up a history, a configuration, the data
+// directory and an included directory, and checks that the archive
+// restores the same files and that a tampered one is rejected
+func TestBackupRoundTrip(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	write := func(path, content string, mode os.FileMode) {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	paths := backupPaths{
+		History:  filepath.Join(home, "data", "history.db"),
+		Config:   filepath.Join(home, "config.yaml"),
+		DataDir:  filepath.Join(home, "data"),
+		Includes: []string{filepath.Join(home, "reports")},
+	}
+	history, err := store.OpenSQLite(paths.History)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := history.Save(storedAssessment(&AssessmentResult{Domain: "example.com", TestTime: 1717236900000, OverallGrade: "A"})); err != nil {
+		t.Fatal(err)
+	}
+	// Abierto mientras se respalda, como con serve
+	defer history.Close()
+	write(paths.Config, "domains: [example.com]\n", 0o600)
+	write(filepath.Join(paths.DataDir, "cacert.pem"), "bundle", 0o644)
+	write(filepath.Join(home, "reports", "example.com.json"), "{}", 0o644)
+
+	manifest, err := collectBackup(paths, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var archive bytes.Buffer
+	if err := writeBackup(&archive, manifest, compressionGzip); err != nil {
+		t.Fatal(err)
+	}
+	// El mismo respaldo en zstd se lee igual
+	var zstdArchive bytes.Buffer
+	if err := writeBackup(&zstdArchive, manifest, compressionZstd); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(zstdArchive.Bytes(), zstdMagic) {
+		t.Errorf("el respaldo zstd no empieza con el magic de zstd")
+	}
+	if fromZstd, err := readBackup(bytes.NewReader(zstdArchive.Bytes()), t.TempDir()); err != nil || len(fromZstd.Files) != len(manifest.Files) {
+		t.Errorf("respaldo zstd: %v", err)
+	}
+
+	restored, err := readBackup(bytes.NewReader(archive.Bytes()), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.SchemaVersion != store.LatestSchemaVersion() {
+		t.Errorf("esquema del respaldo %d, se esperaba %d", restored.SchemaVersion, store.LatestSchemaVersion())
+	}
+	target := backupPaths{History: filepath.Join(t.TempDir(), "history.db"), Config: filepath.Join(t.TempDir(), "config.yaml"), DataDir: t.TempDir()}
+	var names []string
+	for _, f := range restored.Files {
+		names = append(names, f.Name)
+		path, err := restoreTarget(f, target, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Name == "include/1/example.com.json" && path != filepath.Join(home, "reports", "example.com.json") {
+			t.Errorf("el --include se restaura en %s, se esperaba su ruta original", path)
+		}
+		if strings.HasPrefix(f.Name, backupIncludePrefix) {
+			continue
+		}
+		if err := restoreFile(f, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := strings.Join(names, " "), "history.db config/config.yaml data/cacert.pem include/1/example.com.json"; got != want {
+		t.Errorf("archivos del respaldo: %s, se esperaban %s", got, want)
+	}
+	if info, err := os.Stat(target.Config); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("configuración restaurada con %v, %v; se esperaba el modo 0600", info.Mode(), err)
+	}
+	reopened, err := store.Open(target.History)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if entries, err := reopened.List("example.com", -1); err != nil || len(entries) != 1 || entries[0].OverallGrade != "A" {
+		t.Errorf("historial restaurado: %+v, %v", entries, err)
+	}
+
+	// Un archivo cambiado después de respaldar no pasa la verificación
+	tampered := retarBackup(t, archive.Bytes(), func(name string, data []byte) []byte {
+		if name == "data/cacert.pem" {
+			return []byte("bundlf")
+		}
+		return data
+	})
+	if _, err := readBackup(bytes.NewReader(tampered), t.TempDir()); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("respaldo alterado: %v, se esperaba un error de checksum", err)
+	}
+}
+
+// TestBackupIncludeTargets checks that a backup can only restore its
+// --include files inside the home or the data directory, unless allowed
+func TestBackupIncludeTargets(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dataDir := t.TempDir()
+
+	for origin, want := range map[string]string{
+		"~/informes/a.json":                      filepath.Join(home, "informes", "a.json"),
+		filepath.ToSlash(dataDir) + "/extra.pem": filepath.Join(dataDir, "extra.pem"),
+		"/etc/cron.d/nebula":                     "",
+		"~/../../etc/passwd":                     "",
+		filepath.ToSlash(home) + "/a/../../x":    "",
+		"informes/relativo.json":                 "",
+	} {
+		got, err := includeTarget(origin, dataDir, false)
+		if want == "" && err == nil {
+			t.Errorf("includeTarget(%q) = %s, se esperaba un error", origin, got)
+		}
+		if want != "" && (err != nil || got != want) {
+			t.Errorf("includeTarget(%q) = %s, %v; se esperaba %s", origin, got, err, want)
+		}
+	}
+	if got, err := includeTarget("/etc/cron.d/nebula", dataDir, true); err != nil || got != filepath.FromSlash("/etc/cron.d/nebula") {
+		t.Errorf("con --allow-any-path: %s, %v", got, err)
+	}
+	if _, err := includeTarget("/srv/../etc/passwd", dataDir, true); err == nil {
+		t.Errorf("una ruta con .. se aceptó con --allow-any-path")
+	}
+}
+
+// retarBackup rewrites the archive of a backup passing each file through change
+func retarBackup(t *testing.T, archive []byte, change func(name string, data []byte) []byte) []byte {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := tar.NewReader(gz)
+	var out bytes.Buffer
+	gzOut := gzip.NewWriter(&out)
+	tw := tar.NewWriter(gzOut)
+	for {
+		header, err := in.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = change(header.Name, data)
+		header.Size = int64(len(data))
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write(data)
+	}
+	tw.Close()
+	gzOut.Close()
+	return out.Bytes()
+}
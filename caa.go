@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// caaFlagCritical marks a property that CAs must understand to issue
+const caaFlagCritical = 0x80
+
+// CAARecord is a CAA property of a domain (RFC 8659)
+type CAARecord struct {
+	Flags uint8
+	Tag   string // issue, issuewild, iodef...
+	Value string
+}
+
+// String formats the record as in a zone file
+func (r CAARecord) String() string {
+	return fmt.Sprintf("%d %s %q", r.Flags, r.Tag, r.Value)
+}
+
+// caaIssuers maps the organization of well-known CAs to the identifiers
+// they accept in the issue properties of CAA records
+var caaIssuers = []struct {
+	organization string
+	domains      []string
+}{
+	{"Let's Encrypt", []string{"letsencrypt.org"}},
+	{"DigiCert", []string{"digicert.com", "symantec.com", "thawte.com", "geotrust.com", "rapidssl.com", "digitalcertvalidation.com"}},
+	{"Sectigo", []string{"sectigo.com", "comodoca.com", "comodo.com", "usertrust.com", "trust-provider.com"}},
+	{"COMODO", []string{"sectigo.com", "comodoca.com", "comodo.com", "usertrust.com", "trust-provider.com"}},
+	{"ZeroSSL", []string{"sectigo.com", "zerossl.com"}},
+	{"GlobalSign", []string{"globalsign.com"}},
+	{"Google Trust Services", []string{"pki.goog"}},
+	{"Amazon", []string{"amazon.com", "amazontrust.com", "awstrust.com", "amazonaws.com"}},
+	{"GoDaddy", []string{"godaddy.com", "starfieldtech.com"}},
+	{"Starfield", []string{"godaddy.com", "starfieldtech.com"}},
+	{"Entrust", []string{"entrust.net"}},
+	{"Microsoft", []string{"microsoft.com"}},
+	{"Buypass", []string{"buypass.com", "buypass.no"}},
+	{"SSL Corporation", []string{"ssl.com"}},
+	{"Asseco", []string{"certum.pl"}},
+}
+
+// lookupCAA returns the CAA record set relevant to domain: that of the
+// closest ancestor (the domain itself included) that has one, and the
+// name where it was found (RFC 8659, section 3). Without records at any
+// level, any CA may issue.
+func lookupCAA(ctx context.Context, domain string) ([]CAARecord, string, error) {
+	name := strings.TrimSuffix(strings.ToLower(domain), ".")
+	// Los TLDs no publican CAA
+	for strings.Contains(name, ".") {
+		answers, err := dnsQuery(ctx, name, dnsTypeCAA)
+		if err != nil {
+			return nil, "", err
+		}
+		var records []CAARecord
+		for _, answer := range answers {
+			if answer.Type != dnsTypeCAA {
+				continue // Los CNAME que el resolver siguió
+			}
+			record, err := parseCAARecord(answer.Data)
+			if err != nil {
+				return nil, "", fmt.Errorf(tr("registro CAA inválido en %s: %w"), name, err)
+			}
+			records = append(records, record)
+		}
+		if len(records) > 0 {
+			return records, name, nil
+		}
+		_, name, _ = strings.Cut(name, ".")
+	}
+	return nil, "", nil
+}
+
+// parseCAARecord decodes the RDATA of a CAA record
+func parseCAARecord(data []byte) (CAARecord, error) {
+	if len(data) < 2 || len(data) < 2+int(data[1]) {
+		return CAARecord{}, errDNSShort
+	}
+	tagEnd := 2 + int(data[1])
+	return CAARecord{
+		Flags: data[0],
+		Tag:   strings.ToLower(string(data[2:tagEnd])),
+		Value: string(data[tagEnd:]),
+	}, nil
+}
+
+// caaIssuerName returns the CA that issued cert. The organization
+// identifies it better than the CN of the intermediate (e.g. R3).
+func caaIssuerName(cert *x509.Certificate) string {
+	if len(cert.Issuer.Organization) > 0 {
+		return cert.Issuer.Organization[0]
+	}
+	return cert.Issuer.CommonName
+}
+
+// caaIssuerDomains returns the CAA identifiers of the CA that issued cert,
+// or nil if it isn't a well-known CA
+func caaIssuerDomains(cert *x509.Certificate) []string {
+	names := append(slices.Clone(cert.Issuer.Organization), cert.Issuer.CommonName)
+	for _, issuer := range caaIssuers {
+		for _, name := range names {
+			if strings.Contains(strings.ToLower(name), strings.ToLower(issuer.organization)) {
+				return issuer.domains
+			}
+		}
+	}
+	return nil
+}
+
+// caaAuthorized returns the CA identifiers that records authorize to issue
+// cert: issuewild applies instead of issue when every name of cert is a
+// wildcard. An empty identifier (";") forbids issuance, so it isn't
+// returned. forbidden is a critical property unknown to CAs, which also
+// forbids issuance.
+func caaAuthorized(records []CAARecord, cert *x509.Certificate) (authorized []string, forbidden string) {
+	tag := "issue"
+	wildcard := len(cert.DNSNames) > 0
+	for _, name := range cert.DNSNames {
+		wildcard = wildcard && strings.HasPrefix(name, "*.")
+	}
+	if wildcard && slices.ContainsFunc(records, func(r CAARecord) bool { return r.Tag == "issuewild" }) {
+		tag = "issuewild"
+	}
+
+	for _, record := range records {
+		switch record.Tag {
+		case "issue", "issuewild", "iodef", "contactemail", "contactphone", "issuemail", "issuevmc":
+		default:
+			if record.Flags&caaFlagCritical != 0 {
+				forbidden = record.String()
+			}
+		}
+		if record.Tag != tag {
+			continue
+		}
+		// "ca.example; parámetros": solo importa el identificador
+		identifier, _, _ := strings.Cut(record.Value, ";")
+		if identifier = strings.ToLower(strings.TrimSpace(identifier)); identifier != "" && !slices.Contains(authorized, identifier) {
+			authorized = append(authorized, identifier)
+		}
+	}
+	return authorized, forbidden
+}
+
+// caaVerdict checks that records, found at owner, authorize the CA that
+// issued leaf. CAs that are not well known can't be matched to their CAA
+// identifier, so the note only lists the authorized ones.
+func caaVerdict(records []CAARecord, owner string, leaf *x509.Certificate) (problem, note string) {
+	authorized, forbidden := caaAuthorized(records, leaf)
+	issuer := caaIssuerName(leaf)
+	known := caaIssuerDomains(leaf)
+	switch {
+	case forbidden != "":
+		problem = fmt.Sprintf(tr("%s tiene una propiedad crítica desconocida (%s): ninguna CA puede emitir"), owner, forbidden)
+	case len(authorized) == 0:
+		problem = fmt.Sprintf(tr("los registros CAA de %s no autorizan a ninguna CA"), owner)
+	case known == nil:
+		note = fmt.Sprintf(tr("no se conoce el identificador CAA de %s; %s autoriza a %s"), issuer, owner, strings.Join(authorized, ", "))
+	case !slices.ContainsFunc(known, func(id string) bool { return slices.Contains(authorized, id) }):
+		problem = fmt.Sprintf(tr("%s no está autorizada: %s solo autoriza a %s"), issuer, owner, strings.Join(authorized, ", "))
+	default:
+		note = fmt.Sprintf(tr("%s autoriza a %s"), owner, strings.Join(authorized, ", "))
+	}
+	return problem, note
+}
+
+// CAACheck is the result of checking the CAA records of a domain against
+// the CAs of the certificates its endpoints serve (--caa)
+type CAACheck struct {
+	Owner   string // Nombre donde se encontraron los registros: el dominio o un ancestro
+	Records []CAARecord
+	Issuers []CAAIssuerCheck
+	Error   string // Motivo por el que no se pudieron consultar los registros
+}
+
+// CAAIssuerCheck is the verdict of the CAA records for one of the CAs of
+// the served certificates
+type CAAIssuerCheck struct {
+	Issuer    string
+	Endpoints []string
+	Problem   string // Motivo por el que la CA no está autorizada, vacío si lo está
+	Note      string
+	Suggested string // Identificador CAA de la CA, si es conocida
+}
+
+// Missing reports whether neither the domain nor its ancestors publish CAA
+// records, so any CA may issue for it
+func (c *CAACheck) Missing() bool {
+	return c.Error == "" && len(c.Records) == 0
+}
+
+// caaChecker is an Assessor that, after each assessment, checks that the
+// CAA records of the domain authorize the CAs of the served certificates
+type caaChecker struct {
+	Assessor
+}
+
+// withCAACheck wraps scanner so that its results include the CAA check
+func withCAACheck(scanner Assessor) Assessor {
+	return &caaChecker{Assessor: scanner}
+}
+
+// AssessContext runs the assessment and checks the CAA records. Interrupted
+// assessments are returned as is.
+func (c *caaChecker) AssessContext(ctx context.Context, domain string) (*AssessmentResult, error) {
+	result, err := c.Assessor.AssessContext(ctx, domain)
+	if err != nil {
+		return result, err
+	}
+	result.CAA = checkDomainCAA(ctx, domain, result)
+	return result, nil
+}
+
+// checkDomainCAA looks up the CAA records of domain and judges the leaf
+// certificate of each endpoint, grouping the endpoints with the same
+// verdict
+func checkDomainCAA(ctx context.Context, domain string, result *AssessmentResult) *CAACheck {
+	check := &CAACheck{}
+	records, owner, err := lookupCAA(ctx, domain)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	check.Records, check.Owner = records, owner
+
+	for _, endpoint := range result.Endpoints {
+		certs := servedCerts(endpoint.Details)
+		if len(certs) == 0 {
+			continue
+		}
+		verdict := CAAIssuerCheck{Issuer: caaIssuerName(certs[0])}
+		if known := caaIssuerDomains(certs[0]); len(known) > 0 {
+			verdict.Suggested = known[0]
+		}
+		if len(records) > 0 {
+			verdict.Problem, verdict.Note = caaVerdict(records, owner, certs[0])
+		}
+		i := slices.IndexFunc(check.Issuers, func(v CAAIssuerCheck) bool {
+			return v.Issuer == verdict.Issuer && v.Problem == verdict.Problem && v.Note == verdict.Note
+		})
+		if i < 0 {
+			check.Issuers = append(check.Issuers, verdict)
+			i = len(check.Issuers) - 1
+		}
+		check.Issuers[i].Endpoints = append(check.Issuers[i].Endpoints, endpoint.IPAddress)
+	}
+	return check
+}
+
+// displayCAA prints the CAA records of the domain and whether they
+// authorize the CA of each served certificate. Missing records are a
+// finding too: they let any CA issue for the domain.
+func displayCAA(domain string, check *CAACheck) {
+	fmt.Printf("=== CAA ===\n")
+	if check.Error != "" {
+		fmt.Printf("%s\n\n", paint(colorYellow, fmt.Sprintf(tr("⚠️  No se pudieron consultar los registros CAA: %s"), check.Error)))
+		return
+	}
+
+	if check.Missing() {
+		fmt.Printf("%s\n", paint(colorYellow, fmt.Sprintf(tr("⚠️  Sin registros CAA en %s ni en sus dominios padre: cualquier CA puede emitir certificados para el dominio"), domain)))
+		var suggested []string
+		for _, issuer := range check.Issuers {
+			if issuer.Suggested != "" && !slices.Contains(suggested, issuer.Suggested) {
+				suggested = append(suggested, issuer.Suggested)
+			}
+		}
+		for _, id := range suggested {
+			// Un registro en el dominio también cubre a sus subdominios
+			fmt.Printf(tr("   Para autorizar solo a la CA actual: %s. CAA 0 issue %q\n"), domain, id)
+		}
+	} else {
+		fmt.Printf(tr("Registros de %s:\n"), check.Owner)
+		for _, record := range check.Records {
+			fmt.Printf("  %s\n", record)
+		}
+	}
+
+	if len(check.Issuers) == 0 {
+		fmt.Println(tr("❔ Sin certificados con qué comparar (la evaluación no incluye la cadena)"))
+	}
+	for _, issuer := range check.Issuers {
+		label := fmt.Sprintf("%s (%s)", issuer.Issuer, strings.Join(issuer.Endpoints, ", "))
+		switch {
+		case check.Missing():
+			fmt.Printf(tr("  ❔ %s: sin restricciones\n"), label)
+		case issuer.Problem != "":
+			fmt.Printf("  %s\n", paint(colorRed, "❌ "+label+": "+issuer.Problem))
+		case issuer.Suggested == "":
+			// CA desconocida: no se puede comparar con su identificador
+			fmt.Printf("  ❔ %s: %s\n", label, issuer.Note)
+		default:
+			fmt.Printf("  %s\n", paint(colorGreen, "✅ "+label+": "+issuer.Note))
+		}
+	}
+	fmt.Println()
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// assessmentCapacity keeps the number of concurrent assessments started by
+// this client within the limit the API reports for the client IP in the
+// X-Max-Assessments and X-Current-Assessments headers. Assessments wait in
+// Acquire until a slot is free. It is safe for concurrent use.
+type assessmentCapacity struct {
+	mu        sync.Mutex
+	changed   chan struct{} // Se cierra (y se reemplaza) cuando cambia el estado
+	max       int           // Último X-Max-Assessments recibido (0 = desconocido)
+	current   int           // Último X-Current-Assessments recibido
+	updatedAt time.Time     // Cuándo se recibió current
+	active    int           // Evaluaciones iniciadas por este cliente y aún no terminadas
+	now       func() time.Time
+}
+
+// capacityStaleAfter is how long X-Current-Assessments is trusted. The
+// assessments of other processes finish without this client noticing, so
+// an old value is ignored instead of waiting for an update that may never
+// come.
+const capacityStaleAfter = 30 * time.Second
+
+// newAssessmentCapacity creates a coordinator with an unknown limit
+func newAssessmentCapacity() *assessmentCapacity {
+	return &assessmentCapacity{changed: make(chan struct{}), now: time.Now}
+}
+
+// Acquire blocks until a new assessment can be started. While the limit is
+// unknown (before the first response) only one assessment runs at a time.
+// It returns ErrInterrupted if ctx is done first.
+func (c *assessmentCapacity) Acquire(ctx context.Context) error {
+	for {
+		c.mu.Lock()
+		now := c.now()
+		if c.available(now) {
+			c.active++
+			c.mu.Unlock()
+			return nil
+		}
+		changed := c.changed
+		// Si lo que impide empezar es current, se vuelve a mirar cuando expire
+		wait := time.Duration(-1)
+		if !c.updatedAt.IsZero() && c.current > c.active {
+			wait = c.updatedAt.Add(capacityStaleAfter).Sub(now)
+		}
+		c.mu.Unlock()
+
+		if err := c.wait(ctx, changed, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// wait blocks until changed is closed, ctx is done or, when timeout isn't
+// negative, timeout elapses
+func (c *assessmentCapacity) wait(ctx context.Context, changed <-chan struct{}, timeout time.Duration) error {
+	var expire <-chan time.Time
+	if timeout >= 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expire = timer.C
+	}
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf(tr("%w: esperando capacidad para una evaluación nueva"), ErrInterrupted)
+	case <-changed:
+	case <-expire:
+	}
+	return nil
+}
+
+// available reports whether one more assessment fits at now. Must be
+// called with mu held.
+func (c *assessmentCapacity) available(now time.Time) bool {
+	if c.max <= 0 {
+		return c.active == 0
+	}
+	// El servidor cuenta también las evaluaciones de otros procesos con la misma IP
+	used := c.active
+	if now.Sub(c.updatedAt) < capacityStaleAfter && c.current > used {
+		used = c.current
+	}
+	return used < c.max
+}
+
+// notify wakes up the goroutines waiting in Acquire. Must be called with
+// mu held.
+func (c *assessmentCapacity) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// Release frees the slot of a finished assessment
+func (c *assessmentCapacity) Release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.active--
+	if c.current > 0 {
+		c.current--
+	}
+	c.notify()
+}
+
+// update records the limits reported in the headers of an API response
+func (c *assessmentCapacity) update(header http.Header) {
+	maxAssessments, errMax := strconv.Atoi(header.Get("X-Max-Assessments"))
+	current, errCurrent := strconv.Atoi(header.Get("X-Current-Assessments"))
+	if errMax != nil && errCurrent != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if errMax == nil {
+		c.max = maxAssessments
+	}
+	if errCurrent == nil {
+		c.current, c.updatedAt = current, c.now()
+	}
+	c.notify()
+}
+
+// Limits returns the last reported maximum and current number of assessments
+func (c *assessmentCapacity) Limits() (maxAssessments, current int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.max, c.current
+}
+
+// Middleware returns the middleware that reads the capacity headers of every response
+func (c *assessmentCapacity) Middleware() Middleware {
+	return Middleware{
+		AfterResponse: func(req *http.Request, resp *http.Response, err error, elapsed time.Duration) {
+			if err == nil {
+				c.update(resp.Header)
+			}
+		},
+	}
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cassetteVersion is the format version of the cassette files
+const cassetteVersion = 1
+
+// replayPollInterval is the wait between status polls when replaying:
+// the responses are already recorded, so there is nothing to wait for
+const replayPollInterval = 10 * time.Millisecond
+
+// cassetteHeaders are the response headers kept in a cassette: the ones
+// the client acts upon
+var cassetteHeaders = []string{"Content-Type", "Retry-After", "X-Max-Assessments", "X-Current-Assessments"}
+
+// cassetteOpaqueKeys are the JSON keys whose values identify the target
+// (certificate hashes and serials can be looked up in CT logs). They are
+// replaced by random values, consistent within the cassette.
+var cassetteOpaqueKeys = []string{"id", "certIds", "sha1Hash", "sha256Hash", "pinSha256", "serialNumber"}
+
+// Cassette is a recording of the interactions of the client with the API
+// (--record), which can be replayed instead of calling it (--replay).
+// Recordings are sanitized: see cassetteSanitizer.
+type Cassette struct {
+	Version      int           `json:"version"`
+	RecordedAt   time.Time     `json:"recordedAt"`
+	Tool         string        `json:"tool"`
+	APIVersion   int           `json:"apiVersion"`
+	Domains      []string      `json:"domains"` // Dominios sustitutos, los que hay que evaluar al reproducir
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a request to the API and its response
+type Interaction struct {
+	Method   string            `json:"method"`
+	Request  string            `json:"request"` // Endpoint y parámetros, ej: analyze?all=done&host=domain1.example
+	Status   int               `json:"status"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Body     json.RawMessage   `json:"body,omitempty"`     // Respuestas JSON
+	BodyText string            `json:"bodyText,omitempty"` // Respuestas que no son JSON
+}
+
+// cassetteRequest returns the key of a request in a cassette: the last
+// element of the path and the sorted query, without the base URL, so a
+// cassette replays against any --api-url
+func cassetteRequest(u *url.URL) string {
+	key := path.Base(u.Path)
+	if query := u.Query().Encode(); query != "" {
+		key += "?" + query
+	}
+	return key
+}
+
+// body returns the response body of the interaction
+func (i Interaction) body() []byte {
+	if len(i.Body) > 0 {
+		return i.Body
+	}
+	return []byte(i.BodyText)
+}
+
+// cassetteSanitizer replaces what identifies the assessed hosts before an
+// interaction is saved: domains and the names of their certificates
+// (also inside other strings, like subjects) become domainN.example, endpoint
+// addresses become documentation addresses (RFC 5737 and 3849), reverse
+// DNS names are dropped, certificate hashes and serials become random
+// values and the PEM of the certificates is removed. The registered email
+// is never saved, since request headers aren't.
+type cassetteSanitizer struct {
+	domains map[string]string // Dominio real → sustituto
+	ips     map[string]string // IP real → sustituta
+	opaque  map[string]string // Hash o serial real → aleatorio
+}
+
+// newCassetteSanitizer creates a sanitizer with no replacements yet
+func newCassetteSanitizer() *cassetteSanitizer {
+	return &cassetteSanitizer{domains: make(map[string]string), ips: make(map[string]string), opaque: make(map[string]string)}
+}
+
+// domain returns the replacement of a domain
+func (s *cassetteSanitizer) domain(real string) string {
+	real = strings.ToLower(real)
+	if _, ok := s.domains[real]; !ok {
+		s.domains[real] = fmt.Sprintf("domain%d.example", len(s.domains)+1)
+	}
+	return s.domains[real]
+}
+
+// ip returns the replacement of an endpoint address, of the same family
+func (s *cassetteSanitizer) ip(real string) string {
+	if _, ok := s.ips[real]; !ok {
+		n := len(s.ips) + 1
+		replacement := fmt.Sprintf("192.0.2.%d", n%254+1)
+		if addr, err := netip.ParseAddr(real); err == nil && addr.Is6() && !addr.Is4In6() {
+			replacement = fmt.Sprintf("2001:db8::%x", n)
+		}
+		s.ips[real] = replacement
+	}
+	return s.ips[real]
+}
+
+// opaqueValue returns the random replacement of a hash or serial, with
+// the same length so the fields still look like what they are
+func (s *cassetteSanitizer) opaqueValue(real string) string {
+	if _, ok := s.opaque[real]; !ok {
+		random := make([]byte, (len(real)+1)/2)
+		rand.Read(random)
+		s.opaque[real] = hex.EncodeToString(random)[:len(real)]
+	}
+	return s.opaque[real]
+}
+
+// text replaces the known domains and addresses inside a string. Longer
+// domains go first, so www.example.com isn't half replaced by example.com.
+func (s *cassetteSanitizer) text(value string) string {
+	reals := make([]string, 0, len(s.domains)+len(s.ips))
+	for real := range s.domains {
+		reals = append(reals, real)
+	}
+	for real := range s.ips {
+		reals = append(reals, real)
+	}
+	slices.SortFunc(reals, func(a, b string) int { return len(b) - len(a) })
+	for _, real := range reals {
+		replacement, ok := s.domains[real]
+		if !ok {
+			replacement = s.ips[real]
+		}
+		value = replaceFold(value, real, replacement)
+	}
+	return value
+}
+
+// replaceFold replaces every case-insensitive occurrence of old in s
+func replaceFold(s, old, replacement string) string {
+	lower, old := strings.ToLower(s), strings.ToLower(old)
+	var b strings.Builder
+	for {
+		i := strings.Index(lower, old)
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		b.WriteString(s[:i])
+		b.WriteString(replacement)
+		s, lower = s[i+len(old):], lower[i+len(old):]
+	}
+}
+
+// request sanitizes the URL of a request and returns its cassette key
+func (s *cassetteSanitizer) request(u *url.URL) string {
+	sanitized := *u
+	query := u.Query()
+	if host := query.Get("host"); host != "" {
+		query.Set("host", s.domain(host))
+	}
+	sanitized.RawQuery = query.Encode()
+	return cassetteRequest(&sanitized)
+}
+
+// body sanitizes a JSON response body. Bodies that aren't JSON only get
+// their domains and addresses replaced.
+func (s *cassetteSanitizer) body(data []byte) (json.RawMessage, string) {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, s.text(string(data))
+	}
+	// Primero se registran todos los dominios y direcciones, para
+	// reemplazarlos en cualquier texto sin importar el orden de las claves
+	s.collect("", value)
+	sanitized, err := json.Marshal(s.value("", value))
+	if err != nil {
+		return nil, s.text(string(data))
+	}
+	return sanitized, ""
+}
+
+// collect registers the domains and addresses that appear in value
+func (s *cassetteSanitizer) collect(key string, value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		for k, item := range v {
+			s.collect(k, item)
+		}
+	case []any:
+		for _, item := range v {
+			s.collect(key, item)
+		}
+	case string:
+		switch key {
+		case "host":
+			s.domain(v)
+		case "ipAddress":
+			s.ip(v)
+		case "commonNames", "altNames":
+			// Los nombres del certificado suelen incluir el dominio padre
+			// (example.com en el de www.example.com). Los de las CAs no son
+			// nombres DNS.
+			if name := strings.TrimPrefix(v, "*."); strings.Contains(name, ".") && !strings.ContainsAny(name, " '") {
+				s.domain(name)
+			}
+		}
+	}
+}
+
+// value returns the sanitized copy of a JSON value found under key
+func (s *cassetteSanitizer) value(key string, value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		sanitized := make(map[string]any, len(v))
+		for k, item := range v {
+			switch k {
+			case "raw", "serverName":
+				continue
+			}
+			sanitized[k] = s.value(k, item)
+		}
+		return sanitized
+	case []any:
+		sanitized := make([]any, len(v))
+		for i, item := range v {
+			sanitized[i] = s.value(key, item)
+		}
+		return sanitized
+	case string:
+		switch {
+		case key == "host":
+			return s.domain(v)
+		case key == "ipAddress":
+			return s.ip(v)
+		case slices.Contains(cassetteOpaqueKeys, key):
+			return s.opaqueValue(v)
+		}
+		return s.text(v)
+	}
+	return value
+}
+
+// cassetteRecorder is an http.RoundTripper that records every response
+// of the API to a cassette file. The file is rewritten after each
+// interaction, so an interrupted run still leaves a usable cassette.
+type cassetteRecorder struct {
+	base      http.RoundTripper
+	path      string
+	mu        sync.Mutex
+	cassette  Cassette
+	sanitizer *cassetteSanitizer
+}
+
+// newCassetteRecorder creates a recorder that sends the requests through
+// base (http.DefaultTransport if nil) and saves them to path
+func newCassetteRecorder(base http.RoundTripper, path string, apiVersion int) *cassetteRecorder {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &cassetteRecorder{
+		base: base,
+		path: path,
+		cassette: Cassette{
+			Version:    cassetteVersion,
+			RecordedAt: time.Now().UTC(),
+			Tool:       "nebula " + toolVersion(),
+			APIVersion: apiVersion,
+		},
+		sanitizer: newCassetteSanitizer(),
+	}
+}
+
+// RoundTrip sends the request and records its response. Connection errors
+// aren't recorded: a cassette replays what the API answered.
+func (r *cassetteRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := r.record(req, resp, body); err != nil {
+		slog.Warn("no se pudo guardar la cassette", "path", r.path, "error", err)
+	}
+	return resp, nil
+}
+
+// record sanitizes an interaction, appends it and saves the cassette
+func (r *cassetteRecorder) record(req *http.Request, resp *http.Response, body []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	interaction := Interaction{
+		Method:  req.Method,
+		Request: r.sanitizer.request(req.URL),
+		Status:  resp.StatusCode,
+	}
+	interaction.Body, interaction.BodyText = r.sanitizer.body(body)
+	for _, name := range cassetteHeaders {
+		if value := resp.Header.Get(name); value != "" {
+			if interaction.Headers == nil {
+				interaction.Headers = make(map[string]string)
+			}
+			interaction.Headers[name] = value
+		}
+	}
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+
+	// Solo los dominios evaluados, no los que aparecen en los certificados
+	if host := req.URL.Query().Get("host"); host != "" {
+		if replacement := r.sanitizer.domain(host); !slices.Contains(r.cassette.Domains, replacement) {
+			r.cassette.Domains = append(r.cassette.Domains, replacement)
+			slices.Sort(r.cassette.Domains)
+		}
+	}
+
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(r.path, append(data, '\n'))
+}
+
+// cassettePlayer is an http.RoundTripper that answers from a cassette
+// instead of the network. Repeated requests (the status polls) get the
+// recorded responses in order; once they run out, the last one again.
+type cassettePlayer struct {
+	mu       sync.Mutex
+	cassette *Cassette
+	byKey    map[string][]Interaction
+	next     map[string]int
+}
+
+// loadCassette reads a cassette file for --replay
+func loadCassette(path string) (*cassettePlayer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo leer la cassette: %w", err)
+	}
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("cassette inválida %s: %w", path, err)
+	}
+	if cassette.Version != cassetteVersion {
+		return nil, fmt.Errorf("cassette %s: versión %d no soportada (se espera %d)", path, cassette.Version, cassetteVersion)
+	}
+
+	player := &cassettePlayer{cassette: &cassette, byKey: make(map[string][]Interaction), next: make(map[string]int)}
+	for _, interaction := range cassette.Interactions {
+		key := interaction.Method + " " + interaction.Request
+		player.byKey[key] = append(player.byKey[key], interaction)
+	}
+	return player, nil
+}
+
+// RoundTrip answers the request with the next recorded response
+func (p *cassettePlayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := req.Method + " " + cassetteRequest(req.URL)
+	interactions := p.byKey[key]
+	if len(interactions) == 0 {
+		return nil, fmt.Errorf("la cassette no tiene la petición %s (dominios grabados: %s)", key, strings.Join(p.cassette.Domains, ", "))
+	}
+	i := min(p.next[key], len(interactions)-1)
+	p.next[key]++
+
+	interaction := interactions[i]
+	body := interaction.body()
+	header := make(http.Header)
+	for name, value := range interaction.Headers {
+		header.Set(name, value)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.Status, http.StatusText(interaction.Status)),
+		StatusCode:    interaction.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"ssllabs-scanner/store"
+)
+
+const (
+	anomalyBurstWindow = 7 * 24 * time.Hour // Ventana en la que se cuentan las emisiones
+	anomalyBurstCount  = 3                  // Certificados en la ventana que se consideran una ráfaga
+	anomalyMinHistory  = 3                  // Emisiones necesarias para conocer la cadencia de renovación
+)
+
+// seenCertLabel identifies the certificate in messages
+func seenCertLabel(c store.Cert) string {
+	return fmt.Sprintf(tr("%s (serie %s)"), shortFingerprint(c.Fingerprint), tr(orUnknown(c.Serial)))
+}
+
+// seenCerts returns the distinct server certificates of result, including
+// the additional ones of each endpoint, from the parsed PEM when available
+// and the API fields otherwise
+func seenCerts(result *AssessmentResult) []store.Cert {
+	var certs []store.Cert
+	for _, endpoint := range result.Endpoints {
+		for _, served := range allServedCerts(endpoint.Details) {
+			details := served.details()
+			fingerprint := certFingerprint(details)
+			if fingerprint == "" || slices.ContainsFunc(certs, func(c store.Cert) bool { return c.Fingerprint == fingerprint }) {
+				continue
+			}
+			cert := store.Cert{
+				Fingerprint: fingerprint,
+				Serial:      strings.ToLower(served.Cert.SerialNumber),
+				Issuer:      served.Cert.IssuerLabel,
+				NotBefore:   time.UnixMilli(served.Cert.NotBefore),
+				NotAfter:    time.UnixMilli(served.Cert.NotAfter),
+			}
+			if leaf := servedCerts(details); len(leaf) > 0 {
+				cert.Serial = leaf[0].SerialNumber.Text(16)
+				cert.Issuer = leaf[0].Issuer.CommonName
+				if len(leaf[0].Issuer.Organization) > 0 {
+					cert.Issuer = leaf[0].Issuer.Organization[0]
+				}
+				cert.NotBefore, cert.NotAfter = leaf[0].NotBefore, leaf[0].NotAfter
+			}
+			certs = append(certs, cert)
+		}
+	}
+	return certs
+}
+
+// checkIssuanceAnomalies compares the certificates of result with those
+// seen before for the domain. It must run before the result is saved.
+// Errors are logged, as with the rest of the history.
+func checkIssuanceAnomalies(history store.Store, result *AssessmentResult) []string {
+	known, err := history.Certs(result.Domain)
+	if err != nil {
+		slog.Warn("no se pudo leer el historial", "domain", result.Domain, "error", err)
+		return nil
+	}
+	return issuanceAnomalies(known, seenCerts(result))
+}
+
+// issuanceAnomalies flags the certificates of current that weren't seen
+// before and break the habits of the domain: a CA other than the usual
+// ones, a reissue well before the usual renewal cadence, a certificate
+// older than the latest known one, or a burst of issuances. It's a weak
+// but cheap compromise indicator; without history there's no baseline.
+func issuanceAnomalies(known, current []store.Cert) []string {
+	var fresh []store.Cert
+	for _, cert := range current {
+		if !slices.ContainsFunc(known, func(k store.Cert) bool { return k.Fingerprint == cert.Fingerprint }) {
+			fresh = append(fresh, cert)
+		}
+	}
+	if len(known) == 0 || len(fresh) == 0 {
+		return nil
+	}
+	slices.SortFunc(fresh, func(a, b store.Cert) int { return a.NotBefore.Compare(b.NotBefore) })
+
+	var issuers []string
+	for _, cert := range known {
+		if cert.Issuer != "" && !slices.Contains(issuers, cert.Issuer) {
+			issuers = append(issuers, cert.Issuer)
+		}
+	}
+	issuances := issuanceDates(known)
+	cadence := renewalCadence(issuances)
+	latest := issuances[len(issuances)-1]
+
+	var anomalies []string
+	for _, cert := range fresh {
+		if cert.Issuer != "" && len(issuers) > 0 && !slices.Contains(issuers, cert.Issuer) {
+			anomalies = append(anomalies, fmt.Sprintf(tr("%s emitido por %s; hasta ahora el dominio usaba %s"),
+				seenCertLabel(cert), cert.Issuer, strings.Join(issuers, ", ")))
+		}
+		gap := cert.NotBefore.Sub(latest)
+		switch {
+		case gap < -24*time.Hour:
+			anomalies = append(anomalies, fmt.Sprintf(tr("%s aparece por primera vez pero fue emitido el %s, antes que el último conocido (%s)"),
+				seenCertLabel(cert), formatDate(cert.NotBefore), formatDate(latest)))
+		case cadence > 0 && gap >= 24*time.Hour && gap < cadence/2:
+			anomalies = append(anomalies, fmt.Sprintf(tr("%s emitido %d días después del anterior; el dominio suele renovar cada %d días"),
+				seenCertLabel(cert), int(gap.Hours()/24), int(cadence.Hours()/24)))
+		}
+	}
+
+	// Emisiones alrededor del certificado nuevo más reciente, contando los conocidos
+	newest := fresh[len(fresh)-1]
+	var burst []string
+	for _, cert := range append(slices.Clone(known), fresh...) {
+		if distance := cert.NotBefore.Sub(newest.NotBefore); distance.Abs() <= anomalyBurstWindow {
+			burst = append(burst, shortFingerprint(cert.Fingerprint))
+		}
+	}
+	if len(burst) >= anomalyBurstCount {
+		anomalies = append(anomalies, fmt.Sprintf(tr("ráfaga de emisiones: %d certificados emitidos con menos de %d días de diferencia (%s)"),
+			len(burst), int(anomalyBurstWindow.Hours()/24), strings.Join(burst, ", ")))
+	}
+	return anomalies
+}
+
+// issuanceDates returns the notBefore of certs in order, merging those
+// issued within a day of each other (a renewal of several certificates,
+// like RSA and ECDSA, is a single issuance)
+func issuanceDates(certs []store.Cert) []time.Time {
+	var dates []time.Time
+	for _, cert := range certs {
+		dates = append(dates, cert.NotBefore)
+	}
+	slices.SortFunc(dates, func(a, b time.Time) int { return a.Compare(b) })
+
+	var merged []time.Time
+	for _, date := range dates {
+		if len(merged) > 0 && date.Sub(merged[len(merged)-1]) < 24*time.Hour {
+			continue
+		}
+		merged = append(merged, date)
+	}
+	return merged
+}
+
+// renewalCadence returns the median interval between issuances, or 0
+// when there are too few to know it
+func renewalCadence(issuances []time.Time) time.Duration {
+	if len(issuances) < anomalyMinHistory {
+		return 0
+	}
+	var intervals []time.Duration
+	for i := 1; i < len(issuances); i++ {
+		intervals = append(intervals, issuances[i].Sub(issuances[i-1]))
+	}
+	slices.Sort(intervals)
+	return intervals[len(intervals)/2]
+}
+
+// displayIssuanceAnomalies prints the anomalies found for a domain
+func displayIssuanceAnomalies(domain string, anomalies []string) {
+	fmt.Printf(tr("=== Anomalías de emisión (%s) ===\n"), domain)
+	for _, anomaly := range anomalies {
+		fmt.Printf("%s\n", paint(colorYellow, "⚠️  "+anomaly))
+	}
+	fmt.Println()
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"strings"
+)
+
+// Bits de Cert.Issues
+const (
+	certIssueNoTrust           = 1 << 0 // No hay cadena de confianza
+	certIssueNotBefore         = 1 << 1 // Aún no es válido
+	certIssueNotAfter          = 1 << 2 // Expirado
+	certIssueHostnameMismatch  = 1 << 3 // No coincide con el nombre del host
+	certIssueRevoked           = 1 << 4
+	certIssueBadCommonName     = 1 << 5
+	certIssueSelfSigned        = 1 << 6
+	certIssueBlacklisted       = 1 << 7
+	certIssueInsecureSignature = 1 << 8
+)
+
+// Bits de Chain.Issues
+const (
+	chainIssueIncomplete     = 1 << 1 // Faltan intermedios (se completó con fuentes externas)
+	chainIssueUnrelated      = 1 << 2 // Certificados no relacionados o duplicados
+	chainIssueIncorrectOrder = 1 << 3
+	chainIssueSelfSignedRoot = 1 << 4 // Incluye la raíz autofirmada
+	chainIssueUnvalidated    = 1 << 5 // No se pudo validar la cadena
+)
+
+// Bits de ChainCert.Issues
+const (
+	chainCertIssueNotYetValid   = 1 << 0
+	chainCertIssueExpired       = 1 << 1
+	chainCertIssueWeakKey       = 1 << 2
+	chainCertIssueWeakSignature = 1 << 3
+	chainCertIssueBlacklisted   = 1 << 4
+)
+
+// chainIssues returns the human-readable problems of the certificate and
+// chain served by an endpoint, explaining grades like T (no trust)
+func chainIssues(d *EndpointDetails) []string {
+	var issues []string
+
+	if d.Cert != nil {
+		leafIssues := []struct {
+			bit     int
+			message string
+		}{
+			{certIssueNoTrust, tr("Raíz no confiable: no hay cadena de confianza hasta una CA reconocida")},
+			{certIssueSelfSigned, tr("Certificado autofirmado")},
+			{certIssueNotBefore, tr("El certificado aún no es válido")},
+			{certIssueNotAfter, tr("El certificado expiró")},
+			{certIssueHostnameMismatch, tr("El certificado no coincide con el nombre del host")},
+			{certIssueRevoked, tr("El certificado fue revocado")},
+			{certIssueBadCommonName, tr("Common name inválido")},
+			{certIssueBlacklisted, tr("El certificado está en lista negra")},
+			{certIssueInsecureSignature, tr("El certificado usa una firma insegura")},
+		}
+		for _, issue := range leafIssues {
+			if d.Cert.Issues&issue.bit != 0 {
+				issues = append(issues, issue.message)
+			}
+		}
+	}
+
+	// Usos de la clave del certificado del servidor, desde su PEM
+	if certs := servedCerts(d); len(certs) > 0 {
+		for _, issue := range usageIssues(certs[0]) {
+			issues = append(issues, fmt.Sprintf(tr("Certificado: %s"), issue))
+		}
+	}
+
+	if d.Chain == nil {
+		return issues
+	}
+
+	chainFlags := []struct {
+		bit     int
+		message string
+	}{
+		{chainIssueIncomplete, tr("Cadena incompleta: el servidor no envía todos los certificados intermedios")},
+		{chainIssueUnrelated, tr("La cadena contiene certificados no relacionados o duplicados")},
+		{chainIssueIncorrectOrder, tr("Los certificados de la cadena están en orden incorrecto")},
+		{chainIssueSelfSignedRoot, tr("La cadena incluye el certificado raíz autofirmado (innecesario)")},
+		{chainIssueUnvalidated, tr("No se pudo validar la cadena enviada por el servidor")},
+	}
+	for _, issue := range chainFlags {
+		if d.Chain.Issues&issue.bit != 0 {
+			issues = append(issues, issue.message)
+		}
+	}
+
+	// Revisar los certificados intermedios (el primero es el del servidor)
+	for i, cert := range d.Chain.Certs {
+		if i == 0 {
+			continue
+		}
+
+		// Las firmas de las raíces autofirmadas no se verifican
+		isRoot := cert.Subject != "" && cert.Subject == cert.IssuerSubject
+		if !isRoot && isSHA1Signature(cert.SigAlg) {
+			issues = append(issues, fmt.Sprintf(tr("Intermedio firmado con SHA-1: %s"), cert.Label))
+		} else if cert.Issues&chainCertIssueWeakSignature != 0 {
+			issues = append(issues, fmt.Sprintf(tr("Intermedio con firma débil: %s"), cert.Label))
+		}
+		if cert.Issues&chainCertIssueExpired != 0 {
+			issues = append(issues, fmt.Sprintf(tr("Intermedio expirado: %s"), cert.Label))
+		}
+		if cert.Issues&chainCertIssueNotYetValid != 0 {
+			issues = append(issues, fmt.Sprintf(tr("Intermedio aún no válido: %s"), cert.Label))
+		}
+		if cert.Issues&chainCertIssueWeakKey != 0 {
+			issues = append(issues, fmt.Sprintf(tr("Intermedio con clave débil: %s"), cert.Label))
+		}
+		if cert.Issues&chainCertIssueBlacklisted != 0 {
+			issues = append(issues, fmt.Sprintf(tr("Intermedio en lista negra: %s"), cert.Label))
+		}
+	}
+
+	return issues
+}
+
+// isSHA1Signature reports whether a signature algorithm uses SHA-1
+func isSHA1Signature(sigAlg string) bool {
+	sigAlg = strings.ToUpper(strings.ReplaceAll(sigAlg, "-", ""))
+	return strings.Contains(sigAlg, "SHA1")
+}
+
+// commonNameFromSubject returns the CN of a distinguished name, or the
+// whole subject when it has no CN
+func commonNameFromSubject(subject string) string {
+	for _, part := range strings.Split(subject, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if ok && strings.EqualFold(key, "CN") {
+			return value
+		}
+	}
+	return subject
+}
+
+// displayLabel returns the label of the certificate, or the CN of its
+// subject when the API doesn't provide one
+func (c ChainCert) displayLabel() string {
+	if c.Label != "" {
+		return c.Label
+	}
+	return commonNameFromSubject(c.Subject)
+}
+
+// parseChainCerts parses the PEM of each certificate of the chain. The
+// result has the same indexes as chain.Certs, with nil for certificates
+// without a (valid) PEM.
+func parseChainCerts(chain *Chain) []*x509.Certificate {
+	certs := make([]*x509.Certificate, len(chain.Certs))
+	for i, chainCert := range chain.Certs {
+		block, _ := pem.Decode([]byte(chainCert.Raw))
+		if block == nil {
+			continue
+		}
+		certs[i], _ = x509.ParseCertificate(block.Bytes)
+	}
+	return certs
+}
+
+// servedCerts returns the parsed certificates served by an endpoint,
+// starting with the leaf, from the chain or, without it, the certificate
+func servedCerts(d *EndpointDetails) []*x509.Certificate {
+	if d == nil {
+		return nil
+	}
+	var certs []*x509.Certificate
+	if d.Chain != nil {
+		for _, cert := range parseChainCerts(d.Chain) {
+			if cert != nil {
+				certs = append(certs, cert)
+			}
+		}
+	}
+	if len(certs) == 0 && d.Cert != nil {
+		certs = parseChainCerts(&Chain{Certs: []ChainCert{{Raw: d.Cert.Raw}}})
+		if certs[0] == nil {
+			return nil
+		}
+	}
+	return certs
+}
+
+// findIssuer returns the certificate among candidates that signed cert,
+// other than cert itself
+func findIssuer(cert *x509.Certificate, candidates []*x509.Certificate) *x509.Certificate {
+	for _, candidate := range candidates {
+		if candidate == nil || candidate == cert || !bytes.Equal(cert.RawIssuer, candidate.RawSubject) {
+			continue
+		}
+		if cert.CheckSignatureFrom(candidate) == nil {
+			return candidate
+		}
+	}
+	return nil
+}
+
+// displayChain prints the certificates of the chain served by the endpoint (--details)
+func displayChain(chain *Chain) {
+	fmt.Printf(tr("Cadena de certificados (%d):\n"), len(chain.Certs))
+	for i, cert := range chain.Certs {
+		fmt.Printf("  %d. %s\n", i+1, cert.displayLabel())
+		fmt.Printf(tr("     Emisor: %s | Firma: %s | Clave: %s %d bits\n"), cert.IssuerLabel, cert.SigAlg, cert.KeyAlg, cert.KeySize)
+	}
+
+	if len(chain.Trust) > 0 {
+		fmt.Println(tr("Confianza por almacén:"))
+		for _, trust := range chain.Trust {
+			if trust.IsTrusted {
+				fmt.Printf("  %s\n", paint(colorGreen, "✅ "+trust.RootStore))
+				continue
+			}
+			line := "❌ " + trust.RootStore
+			if trust.TrustErrorMessage != "" {
+				line += ": " + trust.TrustErrorMessage
+			}
+			fmt.Printf("  %s\n", paint(colorRed, line))
+		}
+	}
+}
+
+// trustByStore summarizes the trust paths of a chain per root store: a
+// store trusts the chain if any path ends in one of its roots
+func trustByStore(paths []TrustPath) []Trust {
+	var stores []Trust
+	index := make(map[string]int)
+	for _, path := range paths {
+		for _, trust := range path.Trust {
+			i, ok := index[trust.RootStore]
+			if !ok {
+				index[trust.RootStore] = len(stores)
+				stores = append(stores, trust)
+				continue
+			}
+			if trust.IsTrusted && !stores[i].IsTrusted {
+				stores[i] = trust
+			}
+		}
+	}
+	return stores
+}
+
+// certFingerprint returns the SHA-256 fingerprint (hex) of the server
+// certificate, from the API hash (v3/v4) or the PEM of the chain (v2)
+func certFingerprint(d *EndpointDetails) string {
+	if d.Cert != nil && d.Cert.SHA256Hash != "" {
+		return strings.ToLower(d.Cert.SHA256Hash)
+	}
+
+	raw := ""
+	if d.Cert != nil {
+		raw = d.Cert.Raw
+	}
+	if raw == "" && d.Chain != nil && len(d.Chain.Certs) > 0 {
+		raw = d.Chain.Certs[0].Raw
+	}
+
+	block, _ := pem.Decode([]byte(raw))
+	if block == nil {
+		return ""
+	}
+	sum := sha256.Sum256(block.Bytes)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Códigos ANSI de la salida en color
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+)
+
+// outputColor enables ANSI colors on stdout (--color). It is set once at
+// startup, before any output.
+var outputColor = false
+
+// addColorFlag registers the --color flag on fs
+func addColorFlag(fs *flag.FlagSet) *string {
+	return fs.String("color", "auto", "colores en la salida: auto (solo en una terminal y sin NO_COLOR), always o never")
+}
+
+// setOutputColor parses the --color value and enables or disables colors.
+// With auto, colors are used when stdout is a terminal, NO_COLOR is not set
+// (https://no-color.org) and TERM is not dumb.
+func setOutputColor(mode string) error {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", "auto":
+		outputColor = isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+	case "always":
+		outputColor = true
+	case "never":
+		outputColor = false
+	default:
+		return fmt.Errorf("valor inválido de --color %q: se espera auto, always o never", mode)
+	}
+	return nil
+}
+
+// paint wraps text in the given ANSI color when colors are enabled
+func paint(color, text string) string {
+	if !outputColor || color == "" || text == "" {
+		return text
+	}
+	return color + text + colorReset
+}
+
+// gradeColor returns the color of a grade: green for A, yellow for B and
+// C, red for D to F, T and M. Unknown grades are not colored.
+func gradeColor(grade string) string {
+	switch strings.TrimRight(grade, "+-") {
+	case "A":
+		return colorGreen
+	case "B", "C":
+		return colorYellow
+	case "D", "E", "F", "T", "M":
+		return colorRed
+	default:
+		return ""
+	}
+}
+
+// paintGrade colors grade according to gradeColor
+func paintGrade(grade string) string {
+	return paint(gradeColor(grade), grade)
+}
+
+// expiryColor returns the color of an expiry status
+func expiryColor(status expiryStatus) string {
+	switch status {
+	case expiryCritical:
+		return colorRed
+	case expiryWarning:
+		return colorYellow
+	default:
+		return ""
+	}
+}
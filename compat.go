@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// strictCLIFlag rejects deprecated usage instead of warning about it. It
+// is read before dispatching the subcommand, so it applies to all of them.
+const strictCLIFlag = "strict-cli"
+
+// deprecatedFlags maps flags that were renamed to their replacement. The
+// old name keeps working with a warning (an error with --strict-cli).
+var deprecatedFlags = map[string]string{}
+
+// cliCompat collects the deprecated usages of a command line, to warn
+// about them or, with --strict-cli, reject them
+type cliCompat struct {
+	strict bool
+	notes  []string
+}
+
+// newCLICompat extracts --strict-cli from args (NEBULA_STRICT_CLI=1 has the
+// same effect) and returns the remaining arguments
+func newCLICompat(args []string) (*cliCompat, []string) {
+	c := &cliCompat{strict: envBool("NEBULA_STRICT_CLI")}
+
+	rest := make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != strictCLIFlag {
+			rest = append(rest, arg)
+			continue
+		}
+		c.strict = true
+		if hasValue {
+			c.strict, _ = strconv.ParseBool(value)
+		}
+	}
+	return c, rest
+}
+
+// deprecated records a deprecated usage
+func (c *cliCompat) deprecated(format string, args ...any) {
+	c.notes = append(c.notes, fmt.Sprintf(format, args...))
+}
+
+// rewriteFlags replaces the deprecated flags of args with their new names
+func (c *cliCompat) rewriteFlags(args []string) []string {
+	rewritten := make([]string, len(args))
+	for i, arg := range args {
+		rewritten[i] = arg
+		if arg == "--" {
+			copy(rewritten[i:], args[i:])
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		dashes := arg[:len(arg)-len(strings.TrimLeft(arg, "-"))]
+		name, value, hasValue := strings.Cut(arg[len(dashes):], "=")
+		replacement, ok := deprecatedFlags[name]
+		if !ok {
+			continue
+		}
+		c.deprecated("el flag --%s está obsoleto, usa --%s", name, replacement)
+		rewritten[i] = dashes + replacement
+		if hasValue {
+			rewritten[i] += "=" + value
+		}
+	}
+	return rewritten
+}
+
+// legacyInvocation records the original invocation without subcommand
+// (nebula <domain>), which now maps to the scan subcommand
+func (c *cliCompat) legacyInvocation() {
+	name := filepath.Base(os.Args[0])
+	c.deprecated("`%s <dominio>` está obsoleto, usa `%s scan <dominio>`", name, name)
+}
+
+// check prints a warning per deprecated usage to w, or returns an error
+// listing them with --strict-cli
+func (c *cliCompat) check(w io.Writer) error {
+	if len(c.notes) == 0 {
+		return nil
+	}
+	if c.strict {
+		return fmt.Errorf("uso obsoleto rechazado por --%s: %s", strictCLIFlag, strings.Join(c.notes, "; "))
+	}
+	for _, note := range c.notes {
+		fmt.Fprintf(w, "⚠️  Aviso: %s\n", note)
+	}
+	return nil
+}
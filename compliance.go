@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"ssllabs-scanner/store"
+)
+
+// complianceProfile is a built-in policy for a compliance standard
+// (--compliance)
+type complianceProfile struct {
+	Name   string
+	Policy Policy
+	Scope  string // Qué cubre el informe y qué no, para el auditor
+}
+
+// complianceProfiles are the profiles accepted by --compliance
+var complianceProfiles = map[string]complianceProfile{
+	"pci": {
+		Name: "PCI DSS v4.0",
+		Policy: Policy{
+			MinProtocol:       "1.2",
+			NoWeakCiphers:     true,
+			TrustedChain:      true,
+			NoVulnerabilities: true,
+		},
+		Scope: "Requisito 4.2.1 (criptografía robusta en la transmisión de datos de titulares de tarjeta): " +
+			"sin SSL ni TLS anterior a 1.2, sin cipher suites débiles (RC4, 3DES, NULL, export, anónimas o de menos de 128 bits), " +
+			"certificado confiable y vigente, y sin vulnerabilidades TLS conocidas. " +
+			"No reemplaza el escaneo trimestral de un ASV aprobado ni cubre el resto de los requisitos de PCI DSS.",
+	},
+}
+
+// loadComplianceProfile returns the profile named by --compliance
+func loadComplianceProfile(name string) (*complianceProfile, error) {
+	profile, ok := complianceProfiles[strings.ToLower(name)]
+	if !ok {
+		names := make([]string, 0, len(complianceProfiles))
+		for name := range complianceProfiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("perfil de cumplimiento desconocido %q: se espera %s", name, strings.Join(names, ", "))
+	}
+	return &profile, nil
+}
+
+// ComplianceReport collects the outcome of every domain of a run against
+// a compliance profile, for the final summary and the audit report
+type ComplianceReport struct {
+	Profile     *complianceProfile
+	GeneratedAt time.Time
+	Domains     []ComplianceDomain
+}
+
+// ComplianceDomain is the outcome of one domain
+type ComplianceDomain struct {
+	Domain   string
+	Grade    string
+	Metadata store.Metadata
+	Results  []RuleResult
+	Error    string // La evaluación falló: el dominio no cumple
+}
+
+// Passed reports whether the domain was assessed and meets every rule
+func (d ComplianceDomain) Passed() bool {
+	if d.Error != "" {
+		return false
+	}
+	for _, result := range d.Results {
+		if !result.Passed() {
+			return false
+		}
+	}
+	return true
+}
+
+// failedRules returns the names of the rules the domain doesn't meet
+func (d ComplianceDomain) failedRules() []string {
+	if d.Error != "" {
+		return []string{tr("evaluación fallida")}
+	}
+	var failed []string
+	for _, result := range d.Results {
+		if !result.Passed() {
+			failed = append(failed, result.Rule)
+		}
+	}
+	return failed
+}
+
+// newComplianceReport starts a report for profile
+func newComplianceReport(profile *complianceProfile) *ComplianceReport {
+	return &ComplianceReport{Profile: profile, GeneratedAt: time.Now()}
+}
+
+// Add evaluates result against the profile and records it
+func (r *ComplianceReport) Add(result *AssessmentResult) []RuleResult {
+	results := r.Profile.Policy.Evaluate(result)
+	r.Domains = append(r.Domains, ComplianceDomain{
+		Domain:   result.Domain,
+		Grade:    result.OverallGrade,
+		Metadata: result.Metadata,
+		Results:  results,
+	})
+	return results
+}
+
+// AddError records a domain whose assessment failed
+func (r *ComplianceReport) AddError(domain string, err error) {
+	r.Domains = append(r.Domains, ComplianceDomain{Domain: domain, Error: err.Error()})
+}
+
+// passedCount returns how many domains meet the profile
+func (r *ComplianceReport) passedCount() int {
+	passed := 0
+	for _, domain := range r.Domains {
+		if domain.Passed() {
+			passed++
+		}
+	}
+	return passed
+}
+
+// verdict returns the overall result in words
+func (r *ComplianceReport) verdict() string {
+	if r.passedCount() == len(r.Domains) {
+		return tr("CUMPLE")
+	}
+	return tr("NO CUMPLE")
+}
+
+// PrintSummary prints one line per domain, after all the assessments
+func (r *ComplianceReport) PrintSummary(w io.Writer) {
+	line := fmt.Sprintf(tr("=== Cumplimiento %s: %s (%d de %d dominios cumplen) ==="), r.Profile.Name, r.verdict(), r.passedCount(), len(r.Domains))
+	if r.passedCount() == len(r.Domains) {
+		fmt.Fprintln(w, paint(colorGreen, line))
+	} else {
+		fmt.Fprintln(w, paint(colorRed, line))
+	}
+	for _, domain := range r.Domains {
+		if domain.Passed() {
+			fmt.Fprintf(w, "  ✅ %s\n", domain.Domain)
+			continue
+		}
+		fmt.Fprintf(w, "  ❌ %s: %s\n", domain.Domain, strings.Join(domain.failedRules(), ", "))
+	}
+}
+
+// WriteMarkdown writes the audit report: verdict, scope, a table of the
+// domains and the detail of every rule per domain with its provenance
+func (r *ComplianceReport) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, tr("# Informe de cumplimiento: %s\n\n"), r.Profile.Name)
+	fmt.Fprintf(&b, tr("- **Resultado:** %s (%d de %d dominios cumplen)\n"), r.verdict(), r.passedCount(), len(r.Domains))
+	fmt.Fprintf(&b, tr("- **Generado:** %s por nebula %s\n"), formatMetadataTime(r.GeneratedAt), toolVersion())
+	fmt.Fprintf(&b, tr("- **Alcance:** %s\n\n"), tr(r.Profile.Scope))
+
+	b.WriteString(tr("| Dominio | Grade | Resultado | Reglas incumplidas |\n"))
+	b.WriteString(tr("|---------|-------|-----------|--------------------|\n"))
+	for _, domain := range r.Domains {
+		result := tr("Cumple")
+		if !domain.Passed() {
+			result = tr("**No cumple**")
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", domain.Domain, tr(orUnknown(domain.Grade)), result, strings.Join(domain.failedRules(), ", "))
+	}
+
+	for _, domain := range r.Domains {
+		fmt.Fprintf(&b, "\n## %s\n\n", domain.Domain)
+		if domain.Error != "" {
+			fmt.Fprintf(&b, tr("❌ La evaluación falló: %s\n"), domain.Error)
+			continue
+		}
+		fmt.Fprintf(&b, tr("Grade general: %s\n\n"), domain.Grade)
+		for _, line := range describeMetadata(domain.Metadata) {
+			fmt.Fprintf(&b, "- %s\n", line)
+		}
+		b.WriteString("\n")
+		for _, result := range domain.Results {
+			if result.Passed() {
+				fmt.Fprintf(&b, "- ✅ %s (`%s`)\n", result.Rule, result.ID)
+				continue
+			}
+			fmt.Fprintf(&b, "- ❌ %s (`%s`)\n", result.Rule, result.ID)
+			for _, failure := range result.Failures {
+				fmt.Fprintf(&b, "  - %s\n", failure)
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeComplianceReport writes the Markdown report of r to path
+func writeComplianceReport(path string, r *ComplianceReport) error {
+	var b strings.Builder
+	if err := r.WriteMarkdown(&b); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, []byte(b.String()))
+}
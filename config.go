@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the configuration file of scan, batch and serve (--config).
+// Its values are defaults: flags and environment variables take precedence.
+// The file is YAML only; other formats such as TOML are not supported.
+type Config struct {
+	Domains  []string            `yaml:"domains"`
+	Interval time.Duration       `yaml:"interval"` // Espera entre rondas de evaluación (serve)
+	Timeout  time.Duration       `yaml:"timeout"`  // Duración máxima de cada evaluación
+	MinGrade string              `yaml:"minGrade"` // Grade general mínimo aceptable (--min-grade)
+	Output   OutputConfig        `yaml:"output"`
+	Notify   NotifyConfig        `yaml:"notify"`
+	Tags     map[string][]string `yaml:"tags"` // Dominios de cada etiqueta, para filtrar hallazgos (--tag)
+}
+
+// OutputConfig holds the output options of the configuration file
+type OutputConfig struct {
+	Details bool   `yaml:"details"` // Salida detallada (--details)
+	Format  string `yaml:"format"`  // Formato del resumen final: text o json (--summary-format)
+	Color   string `yaml:"color"`   // auto, always o never (--color)
+	TZ      string `yaml:"tz"`      // Zona horaria de las fechas (--tz)
+}
+
+// NotifyConfig holds the notification targets of the configuration file
+type NotifyConfig struct {
+	Webhook    string         `yaml:"webhook"`              // URLs de notificación separadas por comas: http[s]://, slack:// o smtp[s]:// (vacío = sin notificaciones)
+	ExpiryDays int            `yaml:"expiryDays"`           // Avisar si el certificado expira en N días o menos
+	Batch      *int           `yaml:"batch,omitempty"`      // Resumir las rondas con alertas en N dominios o más (0 = nunca)
+	Cooldown   *time.Duration `yaml:"cooldown,omitempty"`   // Espera antes de volver a notificar un dominio a un destino (0 = sin espera)
+	MaxPerHour *int           `yaml:"maxPerHour,omitempty"` // Notificaciones por hora a cada destino (0 = sin límite)
+}
+
+// defaultConfigPath returns $XDG_CONFIG_HOME/nebula/config.yaml, falling
+// back to ~/.config/nebula/config.yaml
+func defaultConfigPath() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "config.yaml"
+	}
+	return filepath.Join(configDir, "nebula", "config.yaml")
+}
+
+// loadDefaultConfig loads the configuration at path or, when path is
+// empty, the default one if it exists. It returns nil when there is no
+// configuration to load.
+func loadDefaultConfig(path string) (*Config, error) {
+	if path == "" {
+		path = defaultConfigPath()
+		if _, err := os.Stat(path); err != nil {
+			return nil, nil
+		}
+	}
+	return LoadConfig(path)
+}
+
+// apply sets the flags of fs that were not passed on the command line to
+// the values of the configuration. Flags fs doesn't define are skipped, so
+// the same file serves scan, batch and serve; values that can also come
+// from an environment variable are only applied when it is not set.
+func (c *Config) apply(fs *flag.FlagSet) error {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	// notify.batch, cooldown y maxPerHour pueden ser 0 a propósito, para
+	// deshabilitarlos
+	batch, cooldown, maxPerHour := "", "", ""
+	if c.Notify.Batch != nil {
+		batch = strconv.Itoa(*c.Notify.Batch)
+	}
+	if c.Notify.Cooldown != nil {
+		cooldown = shortDuration(*c.Notify.Cooldown)
+	}
+	if c.Notify.MaxPerHour != nil {
+		maxPerHour = strconv.Itoa(*c.Notify.MaxPerHour)
+	}
+
+	values := []struct {
+		flag, env, value string
+		ok               bool
+	}{
+		{"interval", "", shortDuration(c.Interval), c.Interval > 0},
+		{"timeout", "", shortDuration(c.Timeout), c.Timeout > 0},
+		{"min-grade", "", c.MinGrade, c.MinGrade != ""},
+		{"details", "", "true", c.Output.Details},
+		{"summary-format", "", c.Output.Format, c.Output.Format != ""},
+		{"color", "", c.Output.Color, c.Output.Color != ""},
+		{"tz", "NEBULA_TZ", c.Output.TZ, c.Output.TZ != ""},
+		{"notify-webhook", "SSLLABS_WEBHOOK_URL", c.Notify.Webhook, c.Notify.Webhook != ""},
+		{"notify-expiry-days", "", strconv.Itoa(c.Notify.ExpiryDays), c.Notify.ExpiryDays > 0},
+		{"notify-batch", "", batch, c.Notify.Batch != nil},
+		{"notify-cooldown", "", cooldown, c.Notify.Cooldown != nil},
+		{"notify-max-per-hour", "", maxPerHour, c.Notify.MaxPerHour != nil},
+	}
+	for _, v := range values {
+		if !v.ok || set[v.flag] || fs.Lookup(v.flag) == nil || (v.env != "" && os.Getenv(v.env) != "") {
+			continue
+		}
+		if err := fs.Set(v.flag, v.value); err != nil {
+			return fmt.Errorf("configuración: valor inválido para --%s: %w", v.flag, err)
+		}
+	}
+	return nil
+}
+
+// LoadConfig reads and validates the configuration file at path. Unknown
+// keys are rejected so typos don't go unnoticed; deprecated keys are
+// accepted with a warning (see checkConfig).
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo leer la configuración: %w", err)
+	}
+
+	config, problems := checkConfig(data)
+	if errs := configErrors(problems); len(errs) > 0 {
+		messages := make([]string, len(errs))
+		for i, problem := range errs {
+			messages[i] = problem.String()
+		}
+		return nil, fmt.Errorf("configuración inválida %s: %s", path, strings.Join(messages, "; "))
+	}
+	for _, problem := range problems {
+		slog.Warn("opción de configuración obsoleta", "path", path, "line", problem.Line, "message", problem.Message)
+	}
+	return config, nil
+}
+
+// Validate checks the values of the configuration
+func (c *Config) Validate() error {
+	for _, domain := range c.Domains {
+		if err := validateDomain(domain); err != nil {
+			return fmt.Errorf("domains: %s: %w", domain, err)
+		}
+	}
+	if c.Interval < 0 {
+		return fmt.Errorf("interval no puede ser negativo")
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("timeout no puede ser negativo")
+	}
+	if err := validateMinGrade(c.MinGrade); err != nil {
+		return fmt.Errorf("minGrade: %w", err)
+	}
+	if c.Output.Format != "" {
+		if err := validateSummaryFormat(c.Output.Format); err != nil {
+			return fmt.Errorf("output.format: %w", err)
+		}
+	}
+	if c.Notify.Webhook != "" {
+		if err := validateNotifyTargets(c.Notify.Webhook); err != nil {
+			return fmt.Errorf("notify.webhook: %w", err)
+		}
+	}
+	if c.Notify.ExpiryDays < 0 {
+		return fmt.Errorf("notify.expiryDays no puede ser negativo")
+	}
+	if c.Notify.Batch != nil && *c.Notify.Batch < 0 {
+		return fmt.Errorf("notify.batch no puede ser negativo")
+	}
+	if c.Notify.Cooldown != nil && *c.Notify.Cooldown < 0 {
+		return fmt.Errorf("notify.cooldown no puede ser negativo")
+	}
+	if c.Notify.MaxPerHour != nil && *c.Notify.MaxPerHour < 0 {
+		return fmt.Errorf("notify.maxPerHour no puede ser negativo")
+	}
+	for tag, domains := range c.Tags {
+		if len(domains) == 0 {
+			return fmt.Errorf("tags.%s: la etiqueta no tiene dominios", tag)
+		}
+		for _, domain := range domains {
+			if err := validateDomain(domain); err != nil {
+				return fmt.Errorf("tags.%s: %s: %w", tag, domain, err)
+			}
+		}
+	}
+	return nil
+}
+
+// tagDomains returns the domains of a tag of the configuration, which may
+// be nil
+func (c *Config) tagDomains(tag string) ([]string, error) {
+	if c == nil || len(c.Tags[tag]) == 0 {
+		return nil, fmt.Errorf("etiqueta desconocida %q: se definen en tags del archivo de configuración", tag)
+	}
+	return c.Tags[tag], nil
+}
+
+// shortDuration formats d without trailing zero units (12h instead of 12h0m0s)
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// Marshal renders the configuration as YAML, with a comment per key
+func (c *Config) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("# Configuración de nebula (scan, batch y serve; los flags tienen prioridad)\n\n")
+
+	buf.WriteString("# Dominios a evaluar en cada ronda\n")
+	domains, err := yaml.Marshal(map[string][]string{"domains": c.Domains})
+	if err != nil {
+		return nil, err
+	}
+	buf.Write(domains)
+
+	fmt.Fprintf(&buf, "\n# Espera entre rondas de evaluación\ninterval: %s\n", shortDuration(c.Interval))
+
+	notify, err := yaml.Marshal(map[string]NotifyConfig{"notify": c.Notify})
+	if err != nil {
+		return nil, err
+	}
+	buf.WriteString("\n# Notificaciones ante bajas de grade, vulnerabilidades nuevas y certificados por expirar\n")
+	buf.Write(notify)
+
+	buf.WriteString(`
+# Opcional: duración máxima de cada evaluación, grade general mínimo
+# aceptable (scan termina con código 5 si algún dominio queda por debajo)
+# y opciones de salida
+# timeout: 10m
+# minGrade: B
+# output:
+#   details: false
+#   format: text
+#   color: auto
+#   tz: UTC
+
+# Opcional: etiquetas de dominios, para filtrar y asignar hallazgos en
+# bloque (findings ack --tag pagos)
+# tags:
+#   pagos: [pay.example.com, checkout.example.com]
+`)
+	return buf.Bytes(), nil
+}
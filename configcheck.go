@@ -0,0 +1,286 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// deprecatedConfigKeys maps the keys that are still accepted but will be
+// removed to what should be used instead. Keys use dotted paths such as
+// "notify.webhook".
+var deprecatedConfigKeys = map[string]string{}
+
+// ConfigProblem is an error or a deprecation warning found in a
+// configuration file
+type ConfigProblem struct {
+	Line       int // 0 si no se conoce
+	Message    string
+	Deprecated bool // Solo una advertencia: la configuración sigue siendo válida
+}
+
+// String formats the problem as "línea N: mensaje"
+func (p ConfigProblem) String() string {
+	if p.Line == 0 {
+		return p.Message
+	}
+	return fmt.Sprintf("línea %d: %s", p.Line, p.Message)
+}
+
+// yamlLine extracts the line number of a yaml syntax error
+var yamlLine = regexp.MustCompile(`line (\d+):`)
+
+// configChecker collects the problems found while walking the YAML tree
+type configChecker struct {
+	problems []ConfigProblem
+}
+
+// add records an error at the line of node
+func (c *configChecker) add(node *yaml.Node, format string, args ...any) {
+	c.problems = append(c.problems, ConfigProblem{Line: node.Line, Message: fmt.Sprintf(format, args...)})
+}
+
+// decode decodes a scalar or sequence node into dst, reporting a type
+// error at the node's line with the expected format
+func (c *configChecker) decode(node *yaml.Node, path, expected string, dst any) bool {
+	if err := node.Decode(dst); err != nil {
+		value := node.Value
+		if node.Kind != yaml.ScalarNode {
+			value = node.Tag
+		}
+		c.add(node, "%s: valor inválido %q (%s)", path, value, expected)
+		return false
+	}
+	return true
+}
+
+// mapping walks the keys of a mapping node, reporting deprecated ones
+// and calling field for each of them
+func (c *configChecker) mapping(node *yaml.Node, path string, field func(key, value *yaml.Node, keyPath string)) {
+	if node.Kind != yaml.MappingNode {
+		c.add(node, "%s: se esperaba un mapa de claves", strings.TrimPrefix(path, "."))
+		return
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		keyPath := strings.TrimPrefix(path+"."+key.Value, ".")
+		if seen[key.Value] {
+			c.add(key, "%s: clave repetida", keyPath)
+			continue
+		}
+		seen[key.Value] = true
+
+		if replacement, ok := deprecatedConfigKeys[keyPath]; ok {
+			c.problems = append(c.problems, ConfigProblem{
+				Line:       key.Line,
+				Message:    fmt.Sprintf("%s está obsoleta: %s", keyPath, replacement),
+				Deprecated: true,
+			})
+		}
+		field(key, value, keyPath)
+	}
+}
+
+// unknown reports a key that is not part of the configuration
+func (c *configChecker) unknown(key *yaml.Node, keyPath string) {
+	c.add(key, "clave desconocida %q", keyPath)
+}
+
+// checkConfig parses a configuration file and returns it along with every
+// problem found (unknown keys, invalid values and deprecated options),
+// each with its line. The returned configuration is only meaningful when
+// there are no errors.
+func checkConfig(data []byte) (*Config, []ConfigProblem) {
+	config := &Config{}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		problem := ConfigProblem{Message: strings.TrimPrefix(err.Error(), "yaml: ")}
+		if match := yamlLine.FindStringSubmatch(problem.Message); match != nil {
+			problem.Line, _ = strconv.Atoi(match[1])
+			problem.Message = strings.TrimSpace(strings.TrimPrefix(problem.Message, match[0]))
+		}
+		return config, []ConfigProblem{problem}
+	}
+	if len(doc.Content) == 0 {
+		return config, nil // Archivo vacío: todos los valores por defecto
+	}
+
+	c := &configChecker{}
+	c.mapping(doc.Content[0], "", func(key, value *yaml.Node, keyPath string) {
+		switch key.Value {
+		case "domains":
+			if !c.decode(value, keyPath, "se espera una lista de dominios", &config.Domains) {
+				return
+			}
+			for _, item := range value.Content {
+				if err := validateDomain(item.Value); err != nil {
+					c.add(item, "%s: %s: %s", keyPath, item.Value, err)
+				}
+			}
+		case "interval":
+			if c.decode(value, keyPath, "se espera una duración como 24h", &config.Interval) && config.Interval < 0 {
+				c.add(value, "%s no puede ser negativo", keyPath)
+			}
+		case "timeout":
+			if c.decode(value, keyPath, "se espera una duración como 10m", &config.Timeout) && config.Timeout < 0 {
+				c.add(value, "%s no puede ser negativo", keyPath)
+			}
+		case "minGrade":
+			if c.decode(value, keyPath, "se espera un grade como B", &config.MinGrade) {
+				if err := validateMinGrade(config.MinGrade); err != nil {
+					c.add(value, "%s: %s", keyPath, err)
+				}
+			}
+		case "output":
+			c.mapping(value, keyPath, func(key, value *yaml.Node, keyPath string) {
+				switch key.Value {
+				case "details":
+					c.decode(value, keyPath, "se espera true o false", &config.Output.Details)
+				case "format":
+					if c.decode(value, keyPath, "se espera text o json", &config.Output.Format) {
+						if err := validateSummaryFormat(config.Output.Format); err != nil {
+							c.add(value, "%s: %s", keyPath, err)
+						}
+					}
+				case "color":
+					if c.decode(value, keyPath, "se espera auto, always o never", &config.Output.Color) {
+						switch config.Output.Color {
+						case "auto", "always", "never":
+						default:
+							c.add(value, "%s: valor inválido %q (se espera auto, always o never)", keyPath, config.Output.Color)
+						}
+					}
+				case "tz":
+					if c.decode(value, keyPath, "se espera una zona horaria", &config.Output.TZ) {
+						if _, err := loadTimezone(config.Output.TZ); err != nil {
+							c.add(value, "%s: %s", keyPath, err)
+						}
+					}
+				default:
+					c.unknown(key, keyPath)
+				}
+			})
+		case "notify":
+			c.mapping(value, keyPath, func(key, value *yaml.Node, keyPath string) {
+				switch key.Value {
+				case "webhook":
+					if c.decode(value, keyPath, "se espera una URL", &config.Notify.Webhook) && config.Notify.Webhook != "" {
+						if err := validateNotifyTargets(config.Notify.Webhook); err != nil {
+							c.add(value, "%s: %s", keyPath, err)
+						}
+					}
+				case "expiryDays":
+					if c.decode(value, keyPath, "se espera un número de días", &config.Notify.ExpiryDays) && config.Notify.ExpiryDays < 0 {
+						c.add(value, "%s no puede ser negativo", keyPath)
+					}
+				case "batch":
+					if c.decode(value, keyPath, "se espera un número de dominios", &config.Notify.Batch) && config.Notify.Batch != nil && *config.Notify.Batch < 0 {
+						c.add(value, "%s no puede ser negativo", keyPath)
+					}
+				case "cooldown":
+					if c.decode(value, keyPath, "se espera una duración como 1h", &config.Notify.Cooldown) && config.Notify.Cooldown != nil && *config.Notify.Cooldown < 0 {
+						c.add(value, "%s no puede ser negativo", keyPath)
+					}
+				case "maxPerHour":
+					if c.decode(value, keyPath, "se espera un número de notificaciones", &config.Notify.MaxPerHour) && config.Notify.MaxPerHour != nil && *config.Notify.MaxPerHour < 0 {
+						c.add(value, "%s no puede ser negativo", keyPath)
+					}
+				default:
+					c.unknown(key, keyPath)
+				}
+			})
+		case "tags":
+			if !c.decode(value, keyPath, "se espera un mapa de etiquetas a listas de dominios", &config.Tags) {
+				return
+			}
+			for i := 0; i+1 < len(value.Content); i += 2 {
+				tag, domains := value.Content[i], value.Content[i+1]
+				if len(domains.Content) == 0 {
+					c.add(tag, "%s.%s: la etiqueta no tiene dominios", keyPath, tag.Value)
+				}
+				for _, item := range domains.Content {
+					if err := validateDomain(item.Value); err != nil {
+						c.add(item, "%s.%s: %s: %s", keyPath, tag.Value, item.Value, err)
+					}
+				}
+			}
+		default:
+			c.unknown(key, keyPath)
+		}
+	})
+	return config, c.problems
+}
+
+// configErrors returns the problems that make the configuration invalid
+func configErrors(problems []ConfigProblem) []ConfigProblem {
+	var errs []ConfigProblem
+	for _, problem := range problems {
+		if !problem.Deprecated {
+			errs = append(errs, problem)
+		}
+	}
+	return errs
+}
+
+// runConfig implements the "config" subcommand. "config validate" checks a
+// configuration file and exits non-zero if it has errors, so broken
+// configurations are caught in CI before a deploy.
+func runConfig(args []string) error {
+	if len(args) == 0 || args[0] != "validate" {
+		fmt.Fprintf(os.Stderr, "Usage: %s config validate [--strict] [archivo]\n", os.Args[0])
+		return errors.New("subcomando de config requerido: validate")
+	}
+
+	fs := flag.NewFlagSet("config validate", flag.ExitOnError)
+	strict := fs.Bool("strict", false, "fallar también si hay opciones obsoletas")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s config validate [--strict] [archivo]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Sin archivo se valida %s\n\n", defaultConfigPath())
+		fs.PrintDefaults()
+	}
+	fs.Parse(args[1:])
+
+	path := defaultConfigPath()
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("no se pudo leer la configuración: %w", err)
+	}
+
+	_, problems := checkConfig(data)
+	for _, problem := range problems {
+		prefix := "❌"
+		if problem.Deprecated {
+			prefix = "⚠️ "
+		}
+		if problem.Line > 0 {
+			fmt.Printf("%s %s:%d: %s\n", prefix, path, problem.Line, problem.Message)
+		} else {
+			fmt.Printf("%s %s: %s\n", prefix, path, problem.Message)
+		}
+	}
+
+	errs := configErrors(problems)
+	warnings := len(problems) - len(errs)
+	switch {
+	case len(errs) > 0:
+		return fmt.Errorf("%s: %d errores, %d advertencias", path, len(errs), warnings)
+	case *strict && warnings > 0:
+		return fmt.Errorf("%s: %d opciones obsoletas (--strict)", path, warnings)
+	}
+
+	fmt.Printf("✅ %s es válida\n", path)
+	return nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	crlFetchTimeout = 2 * time.Minute    // Espera máxima por cada descarga
+	crlMaxSize      = 64 << 20           // Tamaño máximo que se descarga
+	crlLargeSize    = 10 << 20           // Tamaño a partir del cual una CRL se considera enorme
+	crlMaxAge       = 7 * 24 * time.Hour // Antigüedad máxima de thisUpdate (Baseline Requirements)
+)
+
+// CRLCheck is the result of downloading one of the CRLs of the chain
+// (--check-crl)
+type CRLCheck struct {
+	Cert       string        // Certificado que la referencia (label de la cadena)
+	URL        string        // Punto de distribución
+	Latency    time.Duration // Tiempo de descarga
+	Size       int64         // Tamaño en bytes
+	Entries    int           // Certificados revocados que lista
+	ThisUpdate time.Time
+	NextUpdate time.Time // Cero si la CRL no la indica
+	Revoked    bool      // El certificado figura en la CRL
+	Problems   []string  // Motivos por los que la CRL se considera rota o degradada
+}
+
+// Broken reports whether the CRL is unreachable, invalid, stale or too
+// large for clients to download comfortably
+func (c CRLCheck) Broken() bool {
+	return len(c.Problems) > 0
+}
+
+// crlChecker is an Assessor that, after each assessment, downloads the
+// CRLs referenced by the chains served by the endpoints
+type crlChecker struct {
+	Assessor
+	client *http.Client
+}
+
+// withCRLCheck wraps scanner so that its results include CRL checks
+func withCRLCheck(scanner Assessor) Assessor {
+	return &crlChecker{Assessor: scanner, client: &http.Client{Timeout: crlFetchTimeout}}
+}
+
+// AssessContext runs the assessment and checks the CRLs of every endpoint.
+// Interrupted assessments are returned without checking.
+func (c *crlChecker) AssessContext(ctx context.Context, domain string) (*AssessmentResult, error) {
+	result, err := c.Assessor.AssessContext(ctx, domain)
+	if err != nil {
+		return result, err
+	}
+
+	// Una CRL puede pesar decenas de MB: cada una se descarga una vez por
+	// certificado aunque varios endpoints sirvan la misma cadena
+	checked := make(map[string]CRLCheck)
+	for i := range result.Endpoints {
+		endpoint := &result.Endpoints[i]
+		if endpoint.Details == nil || endpoint.Details.Chain == nil {
+			continue
+		}
+		chain := endpoint.Details.Chain
+		certs := parseChainCerts(chain)
+		for j, cert := range certs {
+			if cert == nil {
+				continue
+			}
+			for _, url := range cert.CRLDistributionPoints {
+				key := url + "|" + cert.SerialNumber.String()
+				check, ok := checked[key]
+				if !ok {
+					check = checkCRL(ctx, c.client, url, cert, findIssuer(cert, certs))
+					check.Cert = chain.Certs[j].displayLabel()
+					checked[key] = check
+				}
+				endpoint.CRL = append(endpoint.CRL, check)
+			}
+		}
+	}
+	return result, nil
+}
+
+// checkCRL downloads the CRL at url and checks its size, its signature
+// (when the issuer is in the chain) and its publication window, and
+// whether cert is listed
+func checkCRL(ctx context.Context, client *http.Client, url string, cert, issuer *x509.Certificate) CRLCheck {
+	check := CRLCheck{URL: url}
+	fail := func(format string, args ...any) CRLCheck {
+		check.Problems = append(check.Problems, fmt.Sprintf(format, args...))
+		return check
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fail(tr("URL inválida: %s"), err)
+	}
+	started := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return fail(tr("inalcanzable: %s"), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fail(tr("respondió HTTP %d"), resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, crlMaxSize+1))
+	check.Latency = time.Since(started)
+	check.Size = int64(len(data))
+	if err != nil {
+		return fail(tr("descarga incompleta: %s"), err)
+	}
+	if check.Size > crlMaxSize {
+		return fail(tr("enorme: más de %s, descarga abandonada"), formatBytes(crlMaxSize))
+	}
+	if check.Size >= crlLargeSize {
+		check.Problems = append(check.Problems, fmt.Sprintf(tr("enorme: %s (los clientes que la descargan pueden agotar su timeout)"), formatBytes(check.Size)))
+	}
+
+	// Las CRLs se publican en DER, pero algunas CAs las sirven en PEM
+	if block, _ := pem.Decode(data); block != nil && block.Type == "X509 CRL" {
+		data = block.Bytes
+	}
+	crl, err := x509.ParseRevocationList(data)
+	if err != nil {
+		return fail(tr("CRL inválida: %s"), err)
+	}
+	if issuer != nil {
+		if err := crl.CheckSignatureFrom(issuer); err != nil {
+			check.Problems = append(check.Problems, fmt.Sprintf(tr("firma inválida: %s"), err))
+		}
+	}
+
+	check.Entries = len(crl.RevokedCertificateEntries)
+	check.ThisUpdate, check.NextUpdate = crl.ThisUpdate, crl.NextUpdate
+	for _, entry := range crl.RevokedCertificateEntries {
+		if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+			check.Revoked = true
+		}
+	}
+
+	now := time.Now()
+	switch {
+	case !crl.NextUpdate.IsZero() && crl.NextUpdate.Before(now):
+		check.Problems = append(check.Problems, fmt.Sprintf(tr("vencida: nextUpdate %s"), formatDateTime(crl.NextUpdate)))
+	case now.Sub(crl.ThisUpdate) > crlMaxAge:
+		check.Problems = append(check.Problems, fmt.Sprintf(tr("publicación atrasada: thisUpdate %s (más de %d días)"), formatDateTime(crl.ThisUpdate), int(crlMaxAge.Hours()/24)))
+	}
+	return check
+}
+
+// formatBytes formats a size in bytes with binary units (KiB, MiB)
+func formatBytes(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", size)
+	}
+}
+
+// displayCRL prints the CRLs checked for an endpoint
+func displayCRL(checks []CRLCheck) {
+	fmt.Printf("CRLs:\n")
+	for _, check := range checks {
+		line := fmt.Sprintf("%s (%s)", check.URL, check.Cert)
+		if !check.ThisUpdate.IsZero() {
+			line += fmt.Sprintf(tr(": %s, %d entradas, en %s, publicada %s"), formatBytes(check.Size), check.Entries,
+				check.Latency.Round(time.Millisecond), formatDateTime(check.ThisUpdate))
+		}
+		switch {
+		case check.Revoked:
+			fmt.Printf("  %s\n", paint(colorRed, "❌ "+line+tr(": el certificado figura como revocado")))
+		case check.Broken():
+			fmt.Printf("  %s\n", paint(colorRed, "❌ "+line))
+		default:
+			fmt.Printf("  %s\n", paint(colorGreen, "✅ "+line))
+		}
+		for _, problem := range check.Problems {
+			fmt.Printf("     %s\n", paint(colorRed, problem))
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"ssllabs-scanner/store"
+)
+
+const (
+	ctSearchURL   = "https://crt.sh/" // Buscador de los logs de Certificate Transparency
+	ctTimeout     = time.Minute       // crt.sh suele tardar con dominios populares
+	ctMaxSize     = 32 << 20          // Tamaño máximo de la respuesta
+	ctMaxListed   = 20                // Certificados que se listan como máximo
+	ctTimeLayout  = "2006-01-02T15:04:05"
+	ctDefaultDays = 90
+)
+
+// CTLookup is the list of certificates recently issued for a domain
+// according to the Certificate Transparency logs, searched through crt.sh
+// (--ct)
+type CTLookup struct {
+	Days         int             // Antigüedad máxima de las emisiones listadas
+	Certificates []CTCertificate // Las más recientes primero
+	Error        string          // Motivo por el que no se pudo consultar crt.sh
+}
+
+// CTCertificate is a certificate logged for the domain
+type CTCertificate struct {
+	ID            int64  // ID de crt.sh, para verlo en https://crt.sh/?id=
+	Issuer        string // Organización de la CA emisora
+	Serial        string // Hexadecimal, como lo informa crt.sh
+	NotBefore     time.Time
+	Names         []string
+	Served        bool // Es uno de los certificados que sirven los endpoints
+	UnknownIssuer bool // Lo emitió una CA distinta de las de los certificados servidos
+}
+
+// ctFlags holds the flags of the CT lookup
+type ctFlags struct {
+	enabled *bool
+	days    *int
+}
+
+// addCTFlags registers the CT lookup flags on fs
+func addCTFlags(fs *flag.FlagSet) *ctFlags {
+	return &ctFlags{
+		enabled: fs.Bool("ct", false, "listar los certificados emitidos recientemente para el dominio según los logs de Certificate Transparency (consulta crt.sh)"),
+		days:    fs.Int("ct-days", ctDefaultDays, "con --ct, listar las emisiones de los últimos N días"),
+	}
+}
+
+// wrap returns scanner with the CT lookup when --ct was given
+func (f *ctFlags) wrap(scanner Assessor) (Assessor, error) {
+	if *f.days <= 0 {
+		return nil, fmt.Errorf("--ct-days debe ser mayor que 0")
+	}
+	if !*f.enabled {
+		return scanner, nil
+	}
+	return &ctChecker{Assessor: scanner, client: &http.Client{Timeout: ctTimeout}, url: ctSearchURL, days: *f.days}, nil
+}
+
+// ctChecker is an Assessor that, after each assessment, lists the
+// certificates recently logged for the domain
+type ctChecker struct {
+	Assessor
+	client *http.Client
+	url    string
+	days   int
+}
+
+// AssessContext runs the assessment and searches the CT logs. Interrupted
+// assessments are returned as is.
+func (c *ctChecker) AssessContext(ctx context.Context, domain string) (*AssessmentResult, error) {
+	result, err := c.Assessor.AssessContext(ctx, domain)
+	if err != nil {
+		return result, err
+	}
+
+	lookup := &CTLookup{Days: c.days}
+	certs, err := searchCTLogs(ctx, c.client, c.url, domain, time.Now().AddDate(0, 0, -c.days))
+	if err != nil {
+		lookup.Error = err.Error()
+	} else {
+		lookup.Certificates = markServedCerts(certs, seenCerts(result))
+	}
+	result.CT = lookup
+	return result, nil
+}
+
+// searchCTLogs returns the unexpired certificates matching q (a domain, or
+// a pattern like %.example.com) logged in crt.sh at searchURL with a
+// notBefore after since, the most recent first
+func searchCTLogs(ctx context.Context, client *http.Client, searchURL, q string, since time.Time) ([]CTCertificate, error) {
+	query := url.Values{"q": {q}, "output": {"json"}, "exclude": {"expired"}, "deduplicate": {"Y"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, searchURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "nebula/"+toolVersion())
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+		return nil, fmt.Errorf(tr("crt.sh respondió %d"), resp.StatusCode)
+	}
+
+	var entries []struct {
+		ID           int64  `json:"id"`
+		IssuerName   string `json:"issuer_name"`
+		CommonName   string `json:"common_name"`
+		NameValue    string `json:"name_value"` // Nombres del certificado, uno por línea
+		SerialNumber string `json:"serial_number"`
+		NotBefore    string `json:"not_before"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, ctMaxSize)).Decode(&entries); err != nil {
+		return nil, fmt.Errorf(tr("respuesta inválida de crt.sh: %w"), err)
+	}
+
+	var certs []CTCertificate
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		// crt.sh informa las fechas en UTC, sin zona
+		notBefore, err := time.Parse(ctTimeLayout, entry.NotBefore)
+		if err != nil || notBefore.Before(since) {
+			continue
+		}
+		// El precertificado y el certificado final comparten el número de serie
+		key := entry.IssuerName + "|" + normalizeSerial(entry.SerialNumber)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		names := strings.Fields(entry.NameValue)
+		if len(names) == 0 && entry.CommonName != "" {
+			names = []string{entry.CommonName}
+		}
+		certs = append(certs, CTCertificate{
+			ID:        entry.ID,
+			Issuer:    distinguishedNameOrg(entry.IssuerName),
+			Serial:    strings.ToLower(entry.SerialNumber),
+			NotBefore: notBefore,
+			Names:     names,
+		})
+	}
+	slices.SortStableFunc(certs, func(a, b CTCertificate) int { return b.NotBefore.Compare(a.NotBefore) })
+	return certs, nil
+}
+
+// normalizeSerial returns a serial number in lowercase hexadecimal without
+// leading zeros or separators, as big.Int.Text(16) renders it
+func normalizeSerial(serial string) string {
+	serial = strings.ToLower(strings.ReplaceAll(serial, ":", ""))
+	if trimmed := strings.TrimLeft(serial, "0"); trimmed != "" {
+		return trimmed
+	}
+	return serial
+}
+
+// distinguishedNameOrg returns the organization (O=) of a distinguished
+// name like "C=US, O=Let's Encrypt, CN=R3", or its CN when it has none
+func distinguishedNameOrg(dn string) string {
+	var commonName string
+	for _, part := range strings.Split(dn, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch strings.ToUpper(key) {
+		case "O":
+			return strings.Trim(value, `"`)
+		case "CN":
+			commonName = strings.Trim(value, `"`)
+		}
+	}
+	if commonName != "" {
+		return commonName
+	}
+	return dn
+}
+
+// markServedCerts flags the logged certificates that the endpoints serve,
+// and those issued by a CA other than the ones of the served certificates.
+// Without served certificates to compare with, no issuer is flagged.
+func markServedCerts(certs []CTCertificate, served []store.Cert) []CTCertificate {
+	issuers := make(map[string]bool)
+	for _, cert := range served {
+		if cert.Issuer != "" {
+			issuers[strings.ToLower(cert.Issuer)] = true
+		}
+	}
+	for i := range certs {
+		cert := &certs[i]
+		cert.Served = slices.ContainsFunc(served, func(s store.Cert) bool { return normalizeSerial(s.Serial) == normalizeSerial(cert.Serial) })
+		cert.UnknownIssuer = len(issuers) > 0 && !issuers[strings.ToLower(cert.Issuer)]
+	}
+	return certs
+}
+
+// displayCT prints the certificates recently logged for the domain, the
+// ones from an unexpected CA highlighted
+func displayCT(lookup *CTLookup) {
+	fmt.Printf("=== Certificate Transparency (crt.sh) ===\n")
+	if lookup.Error != "" {
+		fmt.Printf("%s\n\n", paint(colorYellow, tr("⚠️  No se pudo consultar crt.sh: ")+lookup.Error))
+		return
+	}
+	if len(lookup.Certificates) == 0 {
+		fmt.Printf(tr("Sin certificados emitidos en los últimos %d días\n\n"), lookup.Days)
+		return
+	}
+
+	unknown := 0
+	for _, cert := range lookup.Certificates {
+		if cert.UnknownIssuer {
+			unknown++
+		}
+	}
+	fmt.Printf(tr("%d certificados emitidos en los últimos %d días\n"), len(lookup.Certificates), lookup.Days)
+	for i, cert := range lookup.Certificates {
+		if i == ctMaxListed {
+			fmt.Printf(tr("  ... y %d más (ver https://crt.sh/)\n"), len(lookup.Certificates)-ctMaxListed)
+			break
+		}
+		line := fmt.Sprintf(tr("%s  %s  serie %s  %s"), formatDate(cert.NotBefore), cert.Issuer, cert.Serial, strings.Join(cert.Names, ", "))
+		switch {
+		case cert.UnknownIssuer:
+			fmt.Printf("  %s\n", paint(colorRed, fmt.Sprintf(tr("⚠️  %s (otra CA: https://crt.sh/?id=%d)"), line, cert.ID)))
+		case cert.Served:
+			fmt.Printf("  %s\n", paint(colorGreen, "✅ "+line+tr(" (servido)")))
+		default:
+			fmt.Printf("     %s\n", line)
+		}
+	}
+	if unknown > 0 {
+		fmt.Printf("%s\n", paint(colorRed, fmt.Sprintf(tr("⚠️  Emitidos por una CA distinta de la de los certificados servidos: %d (verificar que sean legítimos)"), unknown)))
+	}
+	fmt.Println()
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	cutoverDefaultWindow   = 30 * time.Minute
+	cutoverDefaultInterval = 30 * time.Second
+	// cutoverDefaultResolvers are the system resolver and public ones, whose
+	// caches expire at different times during a migration
+	cutoverDefaultResolvers = "system,1.1.1.1,8.8.8.8,9.9.9.9"
+)
+
+// cutoverResolver is the state of one resolver during the cutover
+type cutoverResolver struct {
+	Server    string // host:port
+	Label     string
+	Addrs     []netip.Addr // Última respuesta, ordenada
+	Error     string
+	Converged bool // Resuelve exactamente las IPs esperadas
+}
+
+// cutoverEndpoint is the state of one of the expected IPs
+type cutoverEndpoint struct {
+	Addr        netip.Addr
+	Grade       string
+	Fingerprint string
+	Error       string
+	Ready       bool // Sirve el certificado esperado con el grade mínimo
+}
+
+// cutoverVerifier follows a migration: until every resolver returns the
+// expected IPs and each of them serves the expected certificate with the
+// minimum grade, assessed locally
+type cutoverVerifier struct {
+	domain      string
+	resolvers   []*cutoverResolver
+	endpoints   []*cutoverEndpoint
+	minGrade    string // Vacío = sin mínimo
+	fingerprint string // Vacío = cualquier certificado
+	resolve     func(ctx context.Context, server, domain string) ([]netip.Addr, error)
+	assess      func(ctx context.Context, domain string, addr netip.Addr) (*AssessmentResult, error)
+}
+
+// resolveAddrs returns the A and AAAA records of domain according to
+// server, sorted. The resolver follows the CNAMEs.
+func resolveAddrs(ctx context.Context, server, domain string) ([]netip.Addr, error) {
+	var addrs []netip.Addr
+	for _, qtype := range []uint16{dnsTypeA, dnsTypeAAAA} {
+		records, err := dnsResolve(ctx, server, domain, qtype, false)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			if record.Type != qtype {
+				continue
+			}
+			if addr, ok := netip.AddrFromSlice(record.Data); ok {
+				addrs = append(addrs, addr.Unmap())
+			}
+		}
+	}
+	slices.SortFunc(addrs, compareAddrs)
+	return slices.Compact(addrs), nil
+}
+
+// expected returns the IPs the domain must resolve to, sorted
+func (v *cutoverVerifier) expected() []netip.Addr {
+	addrs := make([]netip.Addr, len(v.endpoints))
+	for i, endpoint := range v.endpoints {
+		addrs[i] = endpoint.Addr
+	}
+	return addrs
+}
+
+// graded reports whether grade satisfies the minimum grade. Without a
+// minimum any grade does; with one, a grade outside the scale doesn't.
+func (v *cutoverVerifier) graded(grade string) bool {
+	if v.minGrade == "" {
+		return true
+	}
+	_, known := gradeOrder[grade]
+	return known && !belowGrade(grade, v.minGrade)
+}
+
+// round queries every resolver and assesses the expected IPs that aren't
+// ready yet. It returns the resolvers and endpoints that converged in this
+// round, to report them once.
+func (v *cutoverVerifier) round(ctx context.Context) (resolvers []*cutoverResolver, endpoints []*cutoverEndpoint) {
+	expected := v.expected()
+	for _, resolver := range v.resolvers {
+		addrs, err := v.resolve(ctx, resolver.Server, v.domain)
+		resolver.Addrs, resolver.Error = addrs, ""
+		if err != nil {
+			resolver.Error = err.Error()
+		}
+		// Una caché vieja puede volver a aparecer: se reevalúa en cada ronda
+		converged := err == nil && slices.Equal(addrs, expected)
+		if converged && !resolver.Converged {
+			resolvers = append(resolvers, resolver)
+		}
+		resolver.Converged = converged
+	}
+
+	for _, endpoint := range v.endpoints {
+		if endpoint.Ready {
+			continue
+		}
+		endpoint.Grade, endpoint.Fingerprint, endpoint.Error = "", "", ""
+		result, err := v.assess(ctx, v.domain, endpoint.Addr)
+		switch {
+		case err != nil:
+			endpoint.Error = err.Error()
+			continue
+		case len(result.Endpoints) == 0 && len(result.EndpointErrors) > 0:
+			endpoint.Error = result.EndpointErrors[0].Message
+			continue
+		case len(result.Endpoints) == 0:
+			endpoint.Error = "sin resultado"
+			continue
+		}
+		endpoint.Grade = result.Endpoints[0].Grade
+		if details := result.Endpoints[0].Details; details != nil {
+			endpoint.Fingerprint = certFingerprint(details)
+		}
+		endpoint.Ready = v.graded(endpoint.Grade) && (v.fingerprint == "" || v.fingerprint == endpoint.Fingerprint)
+		if endpoint.Ready {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return resolvers, endpoints
+}
+
+// pending counts the resolvers and endpoints not converged yet
+func (v *cutoverVerifier) pending() (resolvers, endpoints int) {
+	for _, resolver := range v.resolvers {
+		if !resolver.Converged {
+			resolvers++
+		}
+	}
+	for _, endpoint := range v.endpoints {
+		if !endpoint.Ready {
+			endpoints++
+		}
+	}
+	return resolvers, endpoints
+}
+
+// run repeats the rounds every interval until everything converged or the
+// window is over, printing the progress to w. It fails with what is still
+// pending.
+func (v *cutoverVerifier) run(ctx context.Context, w io.Writer, window, interval time.Duration) error {
+	started := time.Now()
+	for attempt := 1; ; attempt++ {
+		resolvers, endpoints := v.round(ctx)
+		for _, endpoint := range endpoints {
+			fmt.Fprintf(w, "  %s\n", paint(colorGreen, fmt.Sprintf("✅ %s: grade %s, certificado %s", endpoint.Addr, endpoint.Grade, shortFingerprint(endpoint.Fingerprint))))
+		}
+		for _, resolver := range resolvers {
+			fmt.Fprintf(w, "  %s\n", paint(colorGreen, fmt.Sprintf("✅ %s resuelve las IPs nuevas", resolver.Label)))
+		}
+		pendingResolvers, pendingEndpoints := v.pending()
+		if pendingResolvers == 0 && pendingEndpoints == 0 {
+			fmt.Fprintf(w, "%s\n", paint(colorGreen, fmt.Sprintf("✅ Migración completa: %d resolvers y %d endpoints convergieron (rondas: %d, %s)",
+				len(v.resolvers), len(v.endpoints), attempt, time.Since(started).Round(time.Second))))
+			return nil
+		}
+		if time.Since(started)+interval > window {
+			break
+		}
+		fmt.Fprintf(w, "Ronda %d: resolvers %d/%d · endpoints %d/%d, reintentando en %s\n", attempt,
+			len(v.resolvers)-pendingResolvers, len(v.resolvers), len(v.endpoints)-pendingEndpoints, len(v.endpoints), interval)
+		if err := sleepContext(ctx, interval); err != nil {
+			return err
+		}
+	}
+
+	v.printPending(w)
+	pendingResolvers, pendingEndpoints := v.pending()
+	return fmt.Errorf("la migración no convergió en %s: %d resolvers y %d endpoints pendientes", window, pendingResolvers, pendingEndpoints)
+}
+
+// printPending lists why each resolver and endpoint hasn't converged
+func (v *cutoverVerifier) printPending(w io.Writer) {
+	for _, resolver := range v.resolvers {
+		switch {
+		case resolver.Converged:
+		case resolver.Error != "":
+			fmt.Fprintf(w, "  %s\n", paint(colorRed, fmt.Sprintf("❌ %s: %s", resolver.Label, resolver.Error)))
+		default:
+			fmt.Fprintf(w, "  %s\n", paint(colorRed, fmt.Sprintf("❌ %s todavía resuelve %s", resolver.Label, joinAddrs(resolver.Addrs))))
+		}
+	}
+	for _, endpoint := range v.endpoints {
+		var reasons []string
+		switch {
+		case endpoint.Ready:
+			continue
+		case endpoint.Error != "":
+			reasons = append(reasons, endpoint.Error)
+		default:
+			if !v.graded(endpoint.Grade) {
+				reasons = append(reasons, fmt.Sprintf("grade %s, se esperaba %s o mejor", cmp.Or(endpoint.Grade, "desconocido"), v.minGrade))
+			}
+			if v.fingerprint != "" && endpoint.Fingerprint != v.fingerprint {
+				reasons = append(reasons, "sirve otro certificado ("+shortFingerprint(endpoint.Fingerprint)+")")
+			}
+		}
+		fmt.Fprintf(w, "  %s\n", paint(colorRed, fmt.Sprintf("❌ %s: %s", endpoint.Addr, strings.Join(reasons, ", "))))
+	}
+}
+
+// joinAddrs renders a list of IPs, or a note when there is none
+func joinAddrs(addrs []netip.Addr) string {
+	if len(addrs) == 0 {
+		return "sin direcciones"
+	}
+	names := make([]string, len(addrs))
+	for i, addr := range addrs {
+		names[i] = addr.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// parseResolvers parses --resolvers: IPs, with an optional port, or
+// "system" for the resolver of /etc/resolv.conf
+func parseResolvers(list string) ([]*cutoverResolver, error) {
+	var resolvers []*cutoverResolver
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		resolver := &cutoverResolver{Label: entry}
+		switch {
+		case entry == "system":
+			resolver.Server = systemResolver()
+			resolver.Label = "resolver del sistema (" + resolver.Server + ")"
+		default:
+			if addr, err := netip.ParseAddr(entry); err == nil {
+				resolver.Server = net.JoinHostPort(addr.String(), "53")
+			} else if addrPort, err := netip.ParseAddrPort(entry); err == nil {
+				resolver.Server = addrPort.String()
+			} else {
+				return nil, fmt.Errorf("resolver inválido %q: se espera una IP, IP:puerto o system", entry)
+			}
+		}
+		if !slices.ContainsFunc(resolvers, func(r *cutoverResolver) bool { return r.Server == resolver.Server }) {
+			resolvers = append(resolvers, resolver)
+		}
+	}
+	return resolvers, nil
+}
+
+// runVerifyCutover implements the "verify-cutover" subcommand: during a
+// blue/green migration it follows the resolvers until all of them return
+// the new IPs, and assesses those IPs locally until they serve the
+// expected certificate with the minimum grade
+func runVerifyCutover(args []string) error {
+	fs := flag.NewFlagSet("verify-cutover", flag.ExitOnError)
+	expectIPs := fs.String("expect-ip", "", "IPs a las que debe apuntar el dominio al terminar la migración, separadas por comas (obligatorio)")
+	minGrade := fs.String("min-grade", "", "grade mínimo que debe obtener cada IP nueva en la evaluación local (ej: A)")
+	fingerprintFlag := fs.String("fingerprint", "", "huella SHA-256 del certificado que deben servir las IPs nuevas (vacío = cualquiera)")
+	resolverList := fs.String("resolvers", cutoverDefaultResolvers, "resolvers a consultar, separados por comas: IPs (con puerto opcional) o system")
+	window := fs.Duration("window", cutoverDefaultWindow, "tiempo durante el que se espera la convergencia")
+	interval := fs.Duration("interval", cutoverDefaultInterval, "espera entre rondas")
+	port := fs.Int("port", localPort, "puerto de los endpoints")
+	color := addColorFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s verify-cutover <domain> --expect-ip IP[,IP...] [--min-grade A] [--fingerprint huella] [--window 30m]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Ejemplo: %s verify-cutover example.com --expect-ip 203.0.113.7 --min-grade A\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+
+	// El dominio puede ir antes de los flags
+	var domain string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		domain, args = args[0], args[1:]
+	}
+	fs.Parse(args)
+	if domain == "" && fs.NArg() == 1 {
+		domain = fs.Arg(0)
+	}
+	if err := setOutputColor(*color); err != nil {
+		return err
+	}
+	if domain == "" || *expectIPs == "" {
+		fs.Usage()
+		return fmt.Errorf("se requieren un dominio y --expect-ip")
+	}
+	if err := validateDomain(domain); err != nil {
+		return err
+	}
+	if err := validateMinGrade(*minGrade); err != nil {
+		return err
+	}
+	if *window <= 0 || *interval <= 0 {
+		return fmt.Errorf("--window y --interval deben ser mayores que 0")
+	}
+
+	verifier := &cutoverVerifier{domain: domain, minGrade: *minGrade, resolve: resolveAddrs}
+	for _, value := range strings.Split(*expectIPs, ",") {
+		addr, err := netip.ParseAddr(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("--expect-ip inválida %q: se espera una IP", value)
+		}
+		if !slices.ContainsFunc(verifier.endpoints, func(e *cutoverEndpoint) bool { return e.Addr == addr.Unmap() }) {
+			verifier.endpoints = append(verifier.endpoints, &cutoverEndpoint{Addr: addr.Unmap()})
+		}
+	}
+	slices.SortFunc(verifier.endpoints, func(a, b *cutoverEndpoint) int { return compareAddrs(a.Addr, b.Addr) })
+	var err error
+	if verifier.resolvers, err = parseResolvers(*resolverList); err != nil {
+		return err
+	}
+	if *fingerprintFlag != "" {
+		if verifier.fingerprint, err = parseFingerprint(*fingerprintFlag); err != nil {
+			return err
+		}
+	}
+
+	trust, err := loadTrustStore(defaultTrustStorePath())
+	if err != nil {
+		return err
+	}
+	local := NewLocalScanner(trust, 0)
+	local.port = *port
+	verifier.assess = func(ctx context.Context, domain string, addr netip.Addr) (*AssessmentResult, error) {
+		// Solo la IP nueva, aunque el DNS todavía apunte a las viejas
+		scanner := *local
+		scanner.lookup = func(context.Context, string) ([]netip.Addr, error) { return []netip.Addr{addr}, nil }
+		return scanner.AssessContext(ctx, domain)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("Verificando la migración de %s a %s en %d resolvers\n", domain, joinAddrs(verifier.expected()), len(verifier.resolvers))
+	err = verifier.run(ctx, os.Stdout, *window, *interval)
+	if errors.Is(err, ErrInterrupted) {
+		return fmt.Errorf("verificación interrumpida")
+	}
+	return err
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Campos de un registro TLSA (RFC 6698, RFC 7218)
+const (
+	tlsaUsagePKIXTA = 0 // CA que debe estar en la cadena, además válida por PKIX
+	tlsaUsagePKIXEE = 1 // Certificado del servidor, además válido por PKIX
+	tlsaUsageDANETA = 2 // CA que debe estar en la cadena, sin PKIX
+	tlsaUsageDANEEE = 3 // Certificado del servidor, sin PKIX
+
+	tlsaSelectorCert = 0 // Certificado completo
+	tlsaSelectorSPKI = 1 // Solo la clave pública
+
+	tlsaMatchExact  = 0
+	tlsaMatchSHA256 = 1
+	tlsaMatchSHA512 = 2
+)
+
+// danePort is the port of the TLSA records looked up: the one SSL Labs
+// assesses
+const danePort = 443
+
+// tlsaUsageNames are the mnemonics of RFC 7218
+var tlsaUsageNames = map[uint8]string{
+	tlsaUsagePKIXTA: "PKIX-TA",
+	tlsaUsagePKIXEE: "PKIX-EE",
+	tlsaUsageDANETA: "DANE-TA",
+	tlsaUsageDANEEE: "DANE-EE",
+}
+
+// DANECheck is the result of checking the TLSA records of the domain
+// against the certificates its endpoints serve (--dane)
+type DANECheck struct {
+	Name      string // _443._tcp.<dominio>
+	Records   []TLSARecord
+	DNSSEC    *DNSSECCheck // Validación de los registros TLSA: sin DNSSEC los clientes DANE los ignoran
+	Endpoints []DANEEndpoint
+	Error     string // Motivo por el que no se pudieron consultar los registros
+}
+
+// TLSARecord is a TLSA record
+type TLSARecord struct {
+	Usage        uint8
+	Selector     uint8
+	MatchingType uint8
+	Data         string // Hexadecimal en minúsculas
+}
+
+// DANEEndpoint is the verdict of the TLSA records for one endpoint
+type DANEEndpoint struct {
+	IPAddress string
+	Match     *TLSARecord // Registro que coincide con la cadena servida, nil si ninguno
+	Unchecked bool        // La evaluación no trae la cadena del endpoint
+}
+
+// String renders the record as in a zone file, the data abbreviated
+func (r TLSARecord) String() string {
+	return fmt.Sprintf("%d %d %d %s", r.Usage, r.Selector, r.MatchingType, shortFingerprint(r.Data))
+}
+
+// supported reports whether the fields of the record are defined by RFC 6698
+func (r TLSARecord) supported() bool {
+	return r.Usage <= tlsaUsageDANEEE && r.Selector <= tlsaSelectorSPKI && r.MatchingType <= tlsaMatchSHA512
+}
+
+// matches reports whether the record designates cert
+func (r TLSARecord) matches(cert *x509.Certificate) bool {
+	data := cert.Raw
+	if r.Selector == tlsaSelectorSPKI {
+		data = cert.RawSubjectPublicKeyInfo
+	}
+	switch r.MatchingType {
+	case tlsaMatchSHA256:
+		sum := sha256.Sum256(data)
+		data = sum[:]
+	case tlsaMatchSHA512:
+		sum := sha512.Sum512(data)
+		data = sum[:]
+	}
+	return hex.EncodeToString(data) == r.Data
+}
+
+// matchChain returns the first record that designates the chain served by
+// an endpoint: the server certificate for the -EE usages, and one of the
+// CAs sent along with it for the -TA ones
+func matchChain(records []TLSARecord, chain []*x509.Certificate) *TLSARecord {
+	for i, record := range records {
+		if !record.supported() {
+			continue
+		}
+		candidates := chain[:1]
+		if record.Usage == tlsaUsagePKIXTA || record.Usage == tlsaUsageDANETA {
+			candidates = chain[1:]
+		}
+		if slices.ContainsFunc(candidates, record.matches) {
+			return &records[i]
+		}
+	}
+	return nil
+}
+
+// parseTLSA decodes the RDATA of a TLSA record
+func parseTLSA(data []byte) (TLSARecord, error) {
+	if len(data) < 4 {
+		return TLSARecord{}, errDNSShort
+	}
+	return TLSARecord{Usage: data[0], Selector: data[1], MatchingType: data[2], Data: hex.EncodeToString(data[3:])}, nil
+}
+
+// daneChecker is an Assessor that, after each assessment, checks the TLSA
+// records of the domain against the served certificates
+type daneChecker struct {
+	Assessor
+	dnssec *dnssecChecker // Valida la cadena de confianza de los registros TLSA
+}
+
+// withDANECheck wraps scanner so that its results include the DANE check
+func withDANECheck(scanner Assessor) Assessor {
+	return &daneChecker{Assessor: scanner, dnssec: &dnssecChecker{query: dnsQueryDNSSEC, anchors: dnssecRootAnchors}}
+}
+
+// AssessContext runs the assessment and checks the TLSA records.
+// Interrupted assessments are returned as is.
+func (c *daneChecker) AssessContext(ctx context.Context, domain string) (*AssessmentResult, error) {
+	result, err := c.Assessor.AssessContext(ctx, domain)
+	if err != nil {
+		return result, err
+	}
+	result.DANE = c.check(ctx, strings.ToLower(strings.TrimSuffix(domain, ".")), result, time.Now())
+	return result, nil
+}
+
+// check looks up the TLSA records of domain and, if it has any, validates
+// them with DNSSEC and matches them with the chains of each endpoint. An
+// endpoint with several certificates matches if any of its chains does.
+func (c *daneChecker) check(ctx context.Context, domain string, result *AssessmentResult, now time.Time) *DANECheck {
+	check := &DANECheck{Name: fmt.Sprintf("_%d._tcp.%s", danePort, domain)}
+	// El resolver sigue los CNAME: los TLSA pueden estar en otro nombre
+	records, err := c.dnssec.query(ctx, check.Name, dnsTypeTLSA)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	for _, record := range records {
+		if record.Type != dnsTypeTLSA {
+			continue
+		}
+		if tlsa, err := parseTLSA(record.Data); err == nil && !slices.Contains(check.Records, tlsa) {
+			check.Records = append(check.Records, tlsa)
+		}
+	}
+	if len(check.Records) == 0 {
+		return check
+	}
+
+	check.DNSSEC = c.dnssec.check(ctx, check.Name, []uint16{dnsTypeTLSA}, now)
+	for _, endpoint := range result.Endpoints {
+		verdict := DANEEndpoint{IPAddress: endpoint.IPAddress, Unchecked: true}
+		for _, served := range allServedCerts(endpoint.Details) {
+			chain := servedCerts(served.details())
+			if len(chain) == 0 {
+				continue
+			}
+			verdict.Unchecked = false
+			if verdict.Match = matchChain(check.Records, chain); verdict.Match != nil {
+				break
+			}
+		}
+		check.Endpoints = append(check.Endpoints, verdict)
+	}
+	return check
+}
+
+// displayDANE prints the TLSA records of the domain, whether DNSSEC
+// protects them and whether they match the certificate of each endpoint
+func displayDANE(check *DANECheck) {
+	fmt.Printf("=== DANE (TLSA) ===\n")
+	if check.Error != "" {
+		fmt.Printf("%s\n\n", paint(colorYellow, tr("⚠️  No se pudieron consultar los registros TLSA: ")+check.Error))
+		return
+	}
+	if len(check.Records) == 0 {
+		fmt.Printf(tr("Sin registros TLSA en %s: el dominio no usa DANE\n\n"), check.Name)
+		return
+	}
+
+	fmt.Printf(tr("Registros de %s:\n"), check.Name)
+	for _, record := range check.Records {
+		line := "  " + record.String()
+		switch {
+		case !record.supported():
+			line += paint(colorYellow, tr(" (no soportado, se ignora)"))
+		default:
+			line += " (" + tlsaUsageNames[record.Usage] + ")"
+		}
+		fmt.Println(line)
+	}
+
+	switch dnssec := check.DNSSEC; {
+	case dnssec.Error != "":
+		fmt.Printf("%s\n", paint(colorYellow, tr("⚠️  No se pudo validar DNSSEC: ")+dnssec.Error))
+	case dnssec.Status == dnssecSecure:
+		fmt.Printf("%s\n", paint(colorGreen, tr("✅ Registros firmados con DNSSEC")))
+	case dnssec.Status == dnssecInsecure:
+		fmt.Printf("%s\n", paint(colorYellow, fmt.Sprintf(tr("⚠️  Los registros no están protegidos por DNSSEC: los clientes DANE los ignoran (%s)"), dnssec.Problem)))
+	default:
+		fmt.Printf("%s\n", paint(colorRed, fmt.Sprintf(tr("❌ DNSSEC inválido: los clientes DANE no pueden conectar (%s)"), dnssec.Problem)))
+	}
+
+	pkix := false
+	for _, endpoint := range check.Endpoints {
+		switch {
+		case endpoint.Unchecked:
+			fmt.Printf(tr("  ❔ %s: la evaluación no trae la cadena de certificados\n"), endpoint.IPAddress)
+		case endpoint.Match != nil:
+			fmt.Printf("  %s\n", paint(colorGreen, fmt.Sprintf(tr("✅ %s: coincide con %s"), endpoint.IPAddress, endpoint.Match)))
+			pkix = pkix || endpoint.Match.Usage == tlsaUsagePKIXTA || endpoint.Match.Usage == tlsaUsagePKIXEE
+		default:
+			fmt.Printf("  %s\n", paint(colorRed, fmt.Sprintf(tr("❌ %s: ningún registro coincide con el certificado servido, los clientes DANE rechazan la conexión"), endpoint.IPAddress)))
+		}
+	}
+	if pkix {
+		fmt.Println(tr("  Los usos PKIX-TA y PKIX-EE exigen además una cadena válida para las CAs de confianza (ver la evaluación)"))
+	}
+	fmt.Println()
+}
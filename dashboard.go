@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"ssllabs-scanner/store"
+)
+
+// Configuración del dashboard de serve
+const (
+	dashboardHistoryLimit = 30 // Evaluaciones del historial en cada sparkline
+	dashboardRefresh      = 60 // Segundos entre recargas automáticas de la página
+	sparklineWidth        = 120
+	sparklineHeight       = 24
+)
+
+// dashboardExpiry colors the expiry countdown of the dashboard
+var dashboardExpiry = ExpiryThresholds{WarnDays: 30, CritDays: 7}
+
+// Dashboard serves an HTML page with the current state of the monitored
+// domains: grade, certificate expiry countdown, last scan and a sparkline
+// of the grade history, with its open findings when there is a history
+type Dashboard struct {
+	exporter *Exporter
+	history  store.Store // Fuente de las sparklines (opcional)
+}
+
+// dashboardRow is one monitored domain in the dashboard
+type dashboardRow struct {
+	Domain     string
+	Grade      string
+	GradeClass string // ok, warn o crit
+	Expiry     string
+	ExpiryCls  string // ok, warn o crit
+	LastScan   string
+	Error      string
+	Sparkline  template.HTML
+	Findings   string // Hallazgos abiertos y reconocidos
+	FindingCls string // ok, warn o crit
+}
+
+// dashboardPage is the data of the dashboard template
+type dashboardPage struct {
+	Refresh   int
+	Generated string
+	Rows      []dashboardRow
+	History   bool
+}
+
+// ServeHTTP implements the dashboard handler
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	page := dashboardPage{
+		Refresh:   dashboardRefresh,
+		Generated: formatDateTime(now),
+		History:   d.history != nil,
+	}
+	for _, domain := range d.exporter.domainNames() {
+		page.Rows = append(page.Rows, d.row(domain, now))
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := dashboardTemplate.Execute(w, page); err != nil {
+		slog.Warn("no se pudo generar el dashboard", "error", err)
+	}
+}
+
+// row builds the dashboard row of a monitored domain
+func (d *Dashboard) row(domain string, now time.Time) dashboardRow {
+	row := dashboardRow{Domain: domain, Grade: "—", Expiry: "—", LastScan: "pendiente"}
+
+	d.exporter.mu.RLock()
+	state := d.exporter.domains[domain]
+	result, err, lastScan := state.result, state.err, state.lastScan
+	d.exporter.mu.RUnlock()
+
+	if !lastScan.IsZero() {
+		row.LastScan = formatDateTime(lastScan)
+	}
+	if err != nil {
+		row.Error = err.Error()
+	}
+
+	if result != nil {
+		row.Grade = result.OverallGrade
+		row.GradeClass = gradeClass(result.OverallGrade)
+
+		// El certificado que expira primero entre todos los endpoints
+		soonest, found := 0, false
+		for _, endpoint := range result.Endpoints {
+			if endpoint.CertValidTo <= 0 {
+				continue
+			}
+			if days := daysUntil(endpoint.CertValidTo, now); !found || days < soonest {
+				soonest, found = days, true
+			}
+		}
+		if found {
+			row.Expiry = describeExpiry(soonest, ExpiryThresholds{})
+			row.ExpiryCls = [...]string{expiryOK: "ok", expiryWarning: "warn", expiryCritical: "crit"}[dashboardExpiry.Status(soonest)]
+		}
+	}
+
+	if d.history != nil {
+		entries, err := d.history.List(domain, dashboardHistoryLimit)
+		if err != nil {
+			slog.Warn("no se pudo leer el historial", "domain", domain, "error", err)
+		} else {
+			row.Sparkline = sparkline(entries)
+		}
+
+		findings, err := d.history.Findings(store.FindingFilter{Domain: domain})
+		if err != nil {
+			slog.Warn("no se pudo leer el historial", "domain", domain, "error", err)
+		} else {
+			row.Findings, row.FindingCls = findingsSummary(findings)
+		}
+	}
+	return row
+}
+
+// findingsSummary describes the open and acknowledged findings of a domain
+// for the dashboard, with the CSS class of the worst: crit if any is open,
+// warn if all are acknowledged
+func findingsSummary(findings []store.Finding) (string, string) {
+	counts := make(map[string]int)
+	for _, f := range findings {
+		counts[f.State]++
+	}
+	open, acknowledged := counts[store.FindingOpen], counts[store.FindingAcknowledged]
+	switch {
+	case open > 0 && acknowledged > 0:
+		return fmt.Sprintf("%d abiertos · %d reconocidos", open, acknowledged), "crit"
+	case open > 0:
+		return fmt.Sprintf("%d abiertos", open), "crit"
+	case acknowledged > 0:
+		return fmt.Sprintf("%d reconocidos", acknowledged), "warn"
+	}
+	return "Ninguno", "ok"
+}
+
+// gradeClass returns the CSS class of a grade: ok for A, warn for B to C
+// and crit for anything worse
+func gradeClass(grade string) string {
+	switch {
+	case compareGrades(grade, "A-") >= 0:
+		return "ok"
+	case compareGrades(grade, "C-") >= 0:
+		return "warn"
+	default:
+		return "crit"
+	}
+}
+
+// sparkline renders the grades of entries (newest first, as returned by
+// Store.List) as an inline SVG, oldest on the left
+func sparkline(entries []store.Entry) template.HTML {
+	var scores []int
+	var grades []string
+	for i := len(entries) - 1; i >= 0; i-- {
+		score, ok := gradeOrder[entries[i].OverallGrade]
+		if !ok {
+			continue
+		}
+		scores = append(scores, score)
+		grades = append(grades, entries[i].OverallGrade)
+	}
+	if len(scores) == 0 {
+		return ""
+	}
+
+	maxScore := gradeOrder["A+"]
+	points := make([]string, len(scores))
+	for i, score := range scores {
+		x := float64(sparklineWidth) / 2
+		if len(scores) > 1 {
+			x = float64(i) * float64(sparklineWidth-4) / float64(len(scores)-1)
+			x += 2
+		}
+		y := 2 + float64(maxScore-score)*float64(sparklineHeight-4)/float64(maxScore)
+		points[i] = fmt.Sprintf("%.1f,%.1f", x, y)
+	}
+	last := points[len(points)-1]
+	lastX, lastY, _ := strings.Cut(last, ",")
+
+	// Solo el título es texto; los puntos son números generados acá
+	return template.HTML(fmt.Sprintf(
+		`<svg width="%d" height="%d" viewBox="0 0 %d %d"><title>%s</title>`+
+			`<polyline fill="none" stroke="currentColor" stroke-width="1.5" points="%s"/>`+
+			`<circle cx="%s" cy="%s" r="2" fill="currentColor"/></svg>`,
+		sparklineWidth, sparklineHeight, sparklineWidth, sparklineHeight,
+		template.HTMLEscapeString(strings.Join(grades, " → ")), strings.Join(points, " "), lastX, lastY))
+}
+
+// dashboardTemplate is the HTML of the dashboard
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html lang="es">
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="{{.Refresh}}">
+<title>Nebula · Dominios monitoreados</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2rem; color: #222; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: .5rem .75rem; border-bottom: 1px solid #ddd; }
+th { font-size: .85rem; color: #666; }
+.grade { font-weight: bold; font-size: 1.2rem; }
+.ok { color: #1a7f37; }
+.warn { color: #b26b00; }
+.crit { color: #c62828; }
+.error { color: #c62828; font-size: .85rem; }
+footer { margin-top: 1rem; font-size: .8rem; color: #888; }
+</style>
+</head>
+<body>
+<h1>Dominios monitoreados</h1>
+<table>
+<tr><th>Dominio</th><th>Grade</th><th>Expiración del certificado</th><th>Última evaluación</th><th>Historial</th><th>Hallazgos</th></tr>
+{{- range .Rows}}
+<tr>
+<td>{{.Domain}}{{if .Error}}<div class="error">❌ {{.Error}}</div>{{end}}</td>
+<td class="grade {{.GradeClass}}">{{.Grade}}</td>
+<td class="{{.ExpiryCls}}">{{.Expiry}}</td>
+<td>{{.LastScan}}</td>
+<td class="{{.GradeClass}}">{{if .Sparkline}}{{.Sparkline}}{{else}}—{{end}}</td>
+<td class="{{.FindingCls}}">{{if .Findings}}{{.Findings}}{{else}}—{{end}}</td>
+</tr>
+{{- else}}
+<tr><td colspan="6">No hay dominios monitoreados</td></tr>
+{{- end}}
+</table>
+<footer>Generado {{.Generated}} · se actualiza cada {{.Refresh}}s{{if not .History}} · historial deshabilitado: sin sparklines ni hallazgos{{end}} · <a href="/metrics">/metrics</a></footer>
+</body>
+</html>
+`))
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	deployDefaultWindow   = 5 * time.Minute  // Tiempo que se reintenta hasta que todos sirvan el certificado
+	deployDefaultInterval = 10 * time.Second // Espera entre intentos
+)
+
+// deployEndpoint is the state of one address of a domain during the
+// verification
+type deployEndpoint struct {
+	Domain   string
+	Address  string // IP, o vacío si el dominio no resolvió
+	Served   string // Huella SHA-256 del certificado servido en el último intento
+	Error    string
+	Verified bool
+}
+
+// label identifies the endpoint in the output
+func (e *deployEndpoint) label() string {
+	if e.Address == "" {
+		return e.Domain
+	}
+	return e.Domain + " " + e.Address
+}
+
+// deployVerifier checks that every endpoint of the domains serves one of
+// the expected certificates, like after a renewal. It connects to the
+// endpoints directly, so it takes seconds instead of a full assessment.
+type deployVerifier struct {
+	domains      []string
+	fingerprints []string // Huellas SHA-256 aceptadas, en hexadecimal sin separadores
+	port         int
+	lookup       func(ctx context.Context, host string) ([]netip.Addr, error)
+	served       func(ctx context.Context, domain, address string) (string, error)
+	endpoints    map[string]*deployEndpoint
+}
+
+// newDeployVerifier creates a verifier that resolves with the system
+// resolver and connects with crypto/tls
+func newDeployVerifier(domains, fingerprints []string, port int) *deployVerifier {
+	return &deployVerifier{
+		domains:      domains,
+		fingerprints: fingerprints,
+		port:         port,
+		lookup: func(ctx context.Context, host string) ([]netip.Addr, error) {
+			return net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+		},
+		served:    servedFingerprint,
+		endpoints: make(map[string]*deployEndpoint),
+	}
+}
+
+// servedFingerprint returns the SHA-256 fingerprint of the certificate
+// that address serves for domain. The chain is not validated: the point is
+// which certificate is served, not whether it is trusted.
+func servedFingerprint(ctx context.Context, domain, address string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, localHandshakeTimeout)
+	defer cancel()
+
+	dialer := &tls.Dialer{Config: &tls.Config{ServerName: domain, InsecureSkipVerify: true}}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	sum := sha256.Sum256(conn.(*tls.Conn).ConnectionState().PeerCertificates[0].Raw)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// round resolves the domains again, so that addresses added during the
+// rollout are verified too, and checks the endpoints not verified yet. It
+// returns the endpoints verified in this round.
+func (v *deployVerifier) round(ctx context.Context) []*deployEndpoint {
+	var verified []*deployEndpoint
+	for _, domain := range v.domains {
+		addrs, err := v.lookup(ctx, domain)
+		if err != nil || len(addrs) == 0 {
+			// Sin resolver, los endpoints conocidos siguen pendientes
+			if !slices.ContainsFunc(v.list(), func(e *deployEndpoint) bool { return e.Domain == domain && e.Address != "" }) {
+				v.endpoints[domain] = &deployEndpoint{Domain: domain, Error: fmt.Sprintf("no se pudo resolver: %v", err)}
+			}
+			continue
+		}
+		delete(v.endpoints, domain)
+		for _, addr := range addrs {
+			key := domain + " " + addr.Unmap().String()
+			if v.endpoints[key] == nil {
+				v.endpoints[key] = &deployEndpoint{Domain: domain, Address: addr.Unmap().String()}
+			}
+		}
+	}
+
+	for _, endpoint := range v.list() {
+		if endpoint.Verified || endpoint.Address == "" {
+			continue
+		}
+		served, err := v.served(ctx, endpoint.Domain, net.JoinHostPort(endpoint.Address, strconv.Itoa(v.port)))
+		endpoint.Served, endpoint.Error = served, ""
+		if err != nil {
+			endpoint.Error = err.Error()
+			continue
+		}
+		if slices.Contains(v.fingerprints, served) {
+			endpoint.Verified = true
+			verified = append(verified, endpoint)
+		}
+	}
+	return verified
+}
+
+// list returns the endpoints in the order of the domains, by address
+func (v *deployVerifier) list() []*deployEndpoint {
+	endpoints := make([]*deployEndpoint, 0, len(v.endpoints))
+	for _, endpoint := range v.endpoints {
+		endpoints = append(endpoints, endpoint)
+	}
+	slices.SortFunc(endpoints, func(a, b *deployEndpoint) int {
+		if a.Domain != b.Domain {
+			return slices.Index(v.domains, a.Domain) - slices.Index(v.domains, b.Domain)
+		}
+		return strings.Compare(a.Address, b.Address)
+	})
+	return endpoints
+}
+
+// pending returns the endpoints that don't serve the new certificate yet
+func (v *deployVerifier) pending() []*deployEndpoint {
+	var pending []*deployEndpoint
+	for _, endpoint := range v.list() {
+		if !endpoint.Verified {
+			pending = append(pending, endpoint)
+		}
+	}
+	return pending
+}
+
+// run repeats the rounds every interval until every endpoint serves the
+// new certificate or the window is over, printing the progress to w. It
+// fails with the lagging endpoints.
+func (v *deployVerifier) run(ctx context.Context, w io.Writer, window, interval time.Duration) error {
+	started := time.Now()
+	for attempt := 1; ; attempt++ {
+		for _, endpoint := range v.round(ctx) {
+			fmt.Fprintf(w, "  %s\n", paint(colorGreen, "✅ "+endpoint.label()))
+		}
+		pending := v.pending()
+		if len(pending) == 0 {
+			fmt.Fprintf(w, "%s\n", paint(colorGreen, fmt.Sprintf("✅ Todos los endpoints sirven el certificado nuevo: %d (intentos: %d, %s)",
+				len(v.endpoints), attempt, time.Since(started).Round(time.Second))))
+			return nil
+		}
+		if time.Since(started)+interval > window {
+			break
+		}
+		fmt.Fprintf(w, "Intento %d: %d de %d endpoints sin el certificado nuevo, reintentando en %s\n", attempt, len(pending), len(v.endpoints), interval)
+		if err := sleepContext(ctx, interval); err != nil {
+			return err
+		}
+	}
+
+	pending := v.pending()
+	for _, endpoint := range pending {
+		reason := "sirve otro certificado (" + shortFingerprint(endpoint.Served) + ")"
+		if endpoint.Error != "" {
+			reason = endpoint.Error
+		}
+		fmt.Fprintf(w, "  %s\n", paint(colorRed, fmt.Sprintf("❌ %s: %s", endpoint.label(), reason)))
+	}
+	return fmt.Errorf("%d de %d endpoints no sirven el certificado nuevo después de %s", len(pending), len(v.endpoints), window)
+}
+
+// parseFingerprint normalizes a SHA-256 fingerprint given in hexadecimal,
+// with or without colons and with an optional sha256: prefix
+func parseFingerprint(value string) (string, error) {
+	value = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(value)), "sha256:")
+	value = strings.ReplaceAll(value, ":", "")
+	if decoded, err := hex.DecodeString(value); err != nil || len(decoded) != sha256.Size {
+		return "", fmt.Errorf("huella inválida %q: se espera el SHA-256 del certificado en hexadecimal", value)
+	}
+	return value, nil
+}
+
+// certFileFingerprint returns the SHA-256 fingerprint of the first
+// certificate of a PEM file, like the cert.pem of certbot
+func certFileFingerprint(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	for {
+		var block *pem.Block
+		if block, data = pem.Decode(data); block == nil {
+			return "", fmt.Errorf("%s no tiene certificados PEM", path)
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return "", fmt.Errorf("%s: certificado inválido: %w", path, err)
+		}
+		sum := sha256.Sum256(block.Bytes)
+		return hex.EncodeToString(sum[:]), nil
+	}
+}
+
+// runVerifyDeploy implements the "verify-deploy" subcommand: after a
+// renewal, it checks that every endpoint of the domains serves the new
+// certificate, retrying while it is rolled out. As a certbot deploy hook
+// it takes the domains and the certificate from RENEWED_DOMAINS and
+// RENEWED_LINEAGE.
+func runVerifyDeploy(args []string) error {
+	fs := flag.NewFlagSet("verify-deploy", flag.ExitOnError)
+	fingerprintList := fs.String("fingerprint", "", "huellas SHA-256 del certificado nuevo, separadas por comas (ej: la de un certificado RSA y la de uno ECDSA)")
+	certFile := fs.String("cert", "", "certificado nuevo en PEM, en lugar de --fingerprint (por defecto $RENEWED_LINEAGE/cert.pem de certbot)")
+	window := fs.Duration("window", deployDefaultWindow, "tiempo durante el que se reintenta hasta que todos los endpoints sirvan el certificado nuevo")
+	interval := fs.Duration("interval", deployDefaultInterval, "espera entre intentos")
+	port := fs.Int("port", localPort, "puerto de los endpoints")
+	color := addColorFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s verify-deploy [--fingerprint huella | --cert archivo] [--window 5m] <domain> [domain...]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Como deploy hook de certbot, sin argumentos: certbot renew --deploy-hook '%s verify-deploy'\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	// Los dominios pueden ir antes de los flags
+	var domains []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		domains, args = append(domains, args[0]), args[1:]
+	}
+	fs.Parse(args)
+	domains = append(domains, fs.Args()...)
+	if err := setOutputColor(*color); err != nil {
+		return err
+	}
+	if *window <= 0 || *interval <= 0 {
+		return fmt.Errorf("--window y --interval deben ser mayores que 0")
+	}
+
+	if len(domains) == 0 {
+		for _, domain := range strings.Fields(os.Getenv("RENEWED_DOMAINS")) {
+			// Un comodín no es un host al que conectarse
+			if strings.HasPrefix(domain, "*.") {
+				fmt.Fprintf(os.Stderr, "Se omite %s: los comodines no tienen endpoints propios\n", domain)
+				continue
+			}
+			domains = append(domains, domain)
+		}
+	}
+	if len(domains) == 0 {
+		fs.Usage()
+		return fmt.Errorf("se requiere al menos un dominio (o RENEWED_DOMAINS de certbot)")
+	}
+	for _, domain := range domains {
+		if err := validateDomain(domain); err != nil {
+			return fmt.Errorf("%s: %w", domain, err)
+		}
+	}
+
+	var fingerprints []string
+	switch {
+	case *fingerprintList != "" && *certFile != "":
+		return fmt.Errorf("--fingerprint y --cert son excluyentes")
+	case *fingerprintList != "":
+		for _, value := range strings.Split(*fingerprintList, ",") {
+			fingerprint, err := parseFingerprint(value)
+			if err != nil {
+				return err
+			}
+			fingerprints = append(fingerprints, fingerprint)
+		}
+	default:
+		path := *certFile
+		if lineage := os.Getenv("RENEWED_LINEAGE"); path == "" && lineage != "" {
+			path = filepath.Join(lineage, "cert.pem")
+		}
+		if path == "" {
+			return fmt.Errorf("se requiere --fingerprint o --cert (o RENEWED_LINEAGE de certbot)")
+		}
+		fingerprint, err := certFileFingerprint(path)
+		if err != nil {
+			return err
+		}
+		fingerprints = []string{fingerprint}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	short := make([]string, len(fingerprints))
+	for i, fingerprint := range fingerprints {
+		short[i] = shortFingerprint(fingerprint)
+	}
+	fmt.Printf("Verificando el certificado nuevo (SHA-256 %s) en %s\n", strings.Join(short, ", "), strings.Join(domains, ", "))
+	err := newDeployVerifier(domains, fingerprints, *port).run(ctx, os.Stdout, *window, *interval)
+	if errors.Is(err, ErrInterrupted) {
+		return fmt.Errorf("verificación interrumpida")
+	}
+	return err
+}
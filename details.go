@@ -0,0 +1,410 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Key represents the server key (API v2; in v3/v4 the key is part of Cert)
+type Key struct {
+	Size       int    `json:"size"`     // Tamaño en bits (ej: 2048 para RSA, 256 para EC)
+	Strength   int    `json:"strength"` // Tamaño equivalente en bits RSA
+	Alg        string `json:"alg"`      // RSA, DSA o EC
+	DebianFlaw bool   `json:"debianFlaw"`
+	Q          *int   `json:"q"` // 0 si la clave es insegura, null si es segura
+}
+
+// Chain represents the certificate chain sent by the server (API v2)
+type Chain struct {
+	Certs  []ChainCert `json:"certs"`
+	Issues int         `json:"issues"`          // Bits de problemas de la cadena
+	Trust  []Trust     `json:"trust,omitempty"` // Confianza por almacén (API v3/v4 y evaluación local)
+}
+
+// ChainCert represents a certificate of the chain (API v2)
+type ChainCert struct {
+	Subject          string `json:"subject"`
+	Label            string `json:"label"`
+	NotBefore        int64  `json:"notBefore"`
+	NotAfter         int64  `json:"notAfter"`
+	IssuerSubject    string `json:"issuerSubject"`
+	IssuerLabel      string `json:"issuerLabel"`
+	SigAlg           string `json:"sigAlg"`
+	Issues           int    `json:"issues"` // Bits de problemas del certificado
+	KeyAlg           string `json:"keyAlg"`
+	KeySize          int    `json:"keySize"`
+	KeyStrength      int    `json:"keyStrength"`
+	RevocationStatus int    `json:"revocationStatus"`
+	Raw              string `json:"raw,omitempty"` // Certificado en formato PEM
+}
+
+// ProtocolSuites contains the cipher suites supported for one protocol.
+// API v2 returns a single object for all protocols (Protocol == 0),
+// API v3/v4 return one object per protocol.
+type ProtocolSuites struct {
+	Protocol           int     `json:"protocol,omitempty"` // ID del protocolo (ej: 0x0303 para TLS 1.2)
+	List               []Suite `json:"list"`
+	Preference         *bool   `json:"preference"` // null si no se pudo determinar
+	ChaCha20Preference bool    `json:"chaCha20Preference,omitempty"`
+}
+
+// SuitesList holds the cipher suites of an endpoint in any API version
+type SuitesList []ProtocolSuites
+
+// UnmarshalJSON accepts both the v2 object and the v3/v4 array shapes
+func (s *SuitesList) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		var single ProtocolSuites
+		if err := json.Unmarshal(data, &single); err != nil {
+			return err
+		}
+		*s = SuitesList{single}
+		return nil
+	}
+
+	var list []ProtocolSuites
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*s = list
+	return nil
+}
+
+// Suite represents a single cipher suite
+type Suite struct {
+	ID             int    `json:"id"`
+	Name           string `json:"name"`             // ej: TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256
+	CipherStrength int    `json:"cipherStrength"`   // ej: 128
+	KxType         string `json:"kxType,omitempty"` // Tipo de intercambio de claves (API v3/v4)
+	KxStrength     int    `json:"kxStrength,omitempty"`
+	DHStrength     int    `json:"dhStrength,omitempty"`
+	ECDHBits       int    `json:"ecdhBits,omitempty"`
+	ECDHStrength   int    `json:"ecdhStrength,omitempty"`
+	NamedGroupName string `json:"namedGroupName,omitempty"` // ej: x25519 (API v3/v4)
+	Q              *int   `json:"q"`                        // 0 si es insegura, null si es segura
+
+	// No viene de la API: SHA-256 del certificado con el que se negoció la
+	// suite en la evaluación local
+	CertFingerprint string `json:"certFingerprint,omitempty"`
+}
+
+// HSTSPolicy represents the server's HSTS policy
+type HSTSPolicy struct {
+	LongMaxAge        int64  `json:"LONG_MAX_AGE"`
+	Header            string `json:"header"`
+	Status            string `json:"status"` // unknown, absent, present, invalid, disabled
+	Error             string `json:"error"`
+	MaxAge            *int64 `json:"maxAge"`
+	IncludeSubDomains bool   `json:"includeSubDomains"`
+	Preload           bool   `json:"preload"`
+}
+
+// HSTSPreload represents the preload status of the host in one source
+type HSTSPreload struct {
+	Source     string `json:"source"` // ej: Chrome, Firefox, Edge
+	Hostname   string `json:"hostname,omitempty"`
+	Status     string `json:"status"` // error, unknown, absent, present
+	Error      string `json:"error"`
+	SourceTime int64  `json:"sourceTime"`
+}
+
+// HPKPPolicy represents the server's HPKP policy
+type HPKPPolicy struct {
+	Status            string    `json:"status"` // unknown, absent, invalid, disabled, incomplete, valid
+	Header            string    `json:"header"`
+	Error             string    `json:"error"`
+	MaxAge            int64     `json:"maxAge"`
+	IncludeSubDomains bool      `json:"includeSubDomains"`
+	ReportURI         string    `json:"reportUri"`
+	Pins              []HPKPPin `json:"pins"`
+	MatchedPins       []HPKPPin `json:"matchedPins"`
+}
+
+// HPKPPin represents a single public key pin
+type HPKPPin struct {
+	HashFunction string `json:"hashFunction"`
+	Value        string `json:"value"`
+}
+
+// SimDetails holds the handshake simulations of common clients
+type SimDetails struct {
+	Results []Simulation `json:"results"`
+}
+
+// Simulation is the result of simulating the handshake of one client
+type Simulation struct {
+	Client         SimClient `json:"client"`
+	ErrorCode      int       `json:"errorCode"` // 0 si el handshake funciona
+	ErrorMessage   string    `json:"errorMessage,omitempty"`
+	Attempts       int       `json:"attempts"`
+	ProtocolID     int       `json:"protocolId"`
+	SuiteID        int       `json:"suiteId"`
+	SuiteName      string    `json:"suiteName"`
+	NamedGroupName string    `json:"namedGroupName,omitempty"`
+	KeyAlg         string    `json:"keyAlg,omitempty"`
+	KeySize        int       `json:"keySize,omitempty"`
+}
+
+// SimClient represents a simulated client
+type SimClient struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Platform    string `json:"platform,omitempty"`
+	Version     string `json:"version"`
+	IsReference bool   `json:"isReference"`
+}
+
+// vulnCheck is the result of one known TLS vulnerability test
+type vulnCheck struct {
+	ID         string // Identificador estable (ej: "heartbleed"), usado en métricas
+	Name       string
+	Status     string // Descripción legible del resultado
+	Vulnerable bool
+}
+
+// vulnerabilityChecks evaluates the vulnerability flags of an endpoint.
+// Local assessments don't test vulnerabilities, so they have none.
+func vulnerabilityChecks(d *EndpointDetails) []vulnCheck {
+	if d.Local {
+		return nil
+	}
+	return []vulnCheck{
+		boolCheck("beast", "BEAST", d.VulnBeast),
+		boolCheck("heartbleed", "Heartbleed", d.Heartbleed),
+		codeCheck("openssl_ccs", "OpenSSL CCS (CVE-2014-0224)", d.OpenSSLCCS, 3),
+		codeCheck("openssl_padding_oracle", "OpenSSL Padding Oracle (CVE-2016-2107)", d.OpenSSLLuckyMinus20, 2),
+		boolCheck("poodle", "POODLE (SSLv3)", d.Poodle),
+		codeCheck("poodle_tls", "POODLE (TLS)", d.PoodleTLS, 2),
+		boolCheck("freak", "FREAK", d.Freak),
+		boolCheck("logjam", "Logjam", d.Logjam),
+		boolCheck("drown", "DROWN", d.DrownVulnerable),
+		codeCheck("ticketbleed", "Ticketbleed", d.Ticketbleed, 2),
+		codeCheck("robot", "ROBOT", d.Bleichenbacher, 2, 3),
+		codeCheck("zombie_poodle", "Zombie POODLE", d.ZombiePoodle, 2, 3),
+		codeCheck("goldendoodle", "GOLDENDOODLE", d.GoldenDoodle, 4, 5),
+		codeCheck("zero_length_padding_oracle", "0-Length Padding Oracle", d.ZeroLengthPaddingOracle, 6, 7),
+		codeCheck("sleeping_poodle", "Sleeping POODLE", d.SleepingPoodle, 10, 11),
+	}
+}
+
+// vulnerableNames returns the names of the attacks the endpoint is vulnerable to
+func vulnerableNames(d *EndpointDetails) []string {
+	var names []string
+	for _, check := range vulnerabilityChecks(d) {
+		if check.Vulnerable {
+			names = append(names, check.Name)
+		}
+	}
+	return names
+}
+
+// boolCheck builds a vulnCheck from a boolean API flag
+func boolCheck(id, name string, vulnerable bool) vulnCheck {
+	if vulnerable {
+		return vulnCheck{ID: id, Name: name, Status: "VULNERABLE", Vulnerable: true}
+	}
+	return vulnCheck{ID: id, Name: name, Status: "No vulnerable"}
+}
+
+// codeCheck builds a vulnCheck from an integer API result, where negative
+// values mean the test failed, 0 means unknown and vulnerableCodes are
+// the values reported for vulnerable servers
+func codeCheck(id, name string, code int, vulnerableCodes ...int) vulnCheck {
+	for _, vulnerableCode := range vulnerableCodes {
+		if code == vulnerableCode {
+			return vulnCheck{ID: id, Name: name, Status: "VULNERABLE", Vulnerable: true}
+		}
+	}
+
+	switch {
+	case code < 0:
+		return vulnCheck{ID: id, Name: name, Status: "Prueba fallida"}
+	case code == 0:
+		return vulnCheck{ID: id, Name: name, Status: "Desconocido"}
+	default:
+		return vulnCheck{ID: id, Name: name, Status: "No vulnerable"}
+	}
+}
+
+// protocolName returns the name of a protocol ID (ej: 0x0303 -> "TLS 1.2")
+func protocolName(id int) string {
+	switch id {
+	case 0x0200:
+		return "SSL 2.0"
+	case 0x0300:
+		return "SSL 3.0"
+	case 0x0301:
+		return "TLS 1.0"
+	case 0x0302:
+		return "TLS 1.1"
+	case 0x0303:
+		return "TLS 1.2"
+	case 0x0304:
+		return "TLS 1.3"
+	default:
+		return fmt.Sprintf("0x%04x", id)
+	}
+}
+
+// keyExchange returns the key exchange family of a cipher suite
+func keyExchange(suite Suite) string {
+	if suite.KxType != "" {
+		return suite.KxType
+	}
+
+	name := strings.TrimPrefix(suite.Name, "TLS_")
+	switch {
+	case strings.HasPrefix(name, "ECDHE_"):
+		return "ECDHE"
+	case strings.HasPrefix(name, "DHE_"):
+		return "DHE"
+	case strings.HasPrefix(name, "ECDH_"):
+		return "ECDH"
+	case strings.HasPrefix(name, "DH_"):
+		return "DH"
+	case strings.HasPrefix(name, "RSA_"):
+		return "RSA"
+	case !strings.Contains(name, "_WITH_"):
+		// Las suites de TLS 1.3 no incluyen el intercambio de claves
+		return "TLS 1.3"
+	default:
+		return strings.SplitN(name, "_", 2)[0]
+	}
+}
+
+// yesNo renders a boolean in the output language
+func yesNo(value bool) string {
+	if value {
+		return tr("Sí")
+	}
+	return "No"
+}
+
+// displayDetails prints the detailed information of an endpoint (--details)
+func displayDetails(d *EndpointDetails) {
+	// Clave y certificado
+	switch {
+	case d.Key != nil:
+		fmt.Printf(tr("Clave: %s %d bits (equivalente RSA: %d bits)\n"), d.Key.Alg, d.Key.Size, d.Key.Strength)
+	case d.Cert != nil && d.Cert.KeyAlg != "":
+		fmt.Printf(tr("Clave: %s %d bits (equivalente RSA: %d bits)\n"), d.Cert.KeyAlg, d.Cert.KeySize, d.Cert.KeyStrength)
+	}
+	if d.Cert != nil {
+		if d.Cert.SigAlg != "" {
+			fmt.Printf(tr("Firma del certificado: %s\n"), d.Cert.SigAlg)
+		}
+		if len(d.Cert.AltNames) > 0 {
+			fmt.Printf(tr("Nombres alternativos: %s\n"), strings.Join(d.Cert.AltNames, ", "))
+		}
+		fmt.Printf(tr("SCT embebido: %s\n"), yesNo(d.Cert.SCT))
+	}
+	if certs := servedCerts(d); len(certs) > 0 {
+		displayKeyUsages(certs[0])
+	}
+
+	if d.Chain != nil && len(d.Chain.Certs) > 0 {
+		displayChain(d.Chain)
+	}
+	displayAltChains(d)
+
+	// Cipher suites por protocolo
+	for _, suites := range d.Suites {
+		fmt.Printf("Cipher suites")
+		if suites.Protocol != 0 {
+			fmt.Printf(" %s", protocolName(suites.Protocol))
+		}
+		if suites.Preference != nil {
+			fmt.Printf(tr(" (preferencia del servidor: %s)"), yesNo(*suites.Preference))
+		}
+		fmt.Println(":")
+
+		for _, suite := range suites.List {
+			insecure := ""
+			if suite.Q != nil && *suite.Q == 0 {
+				insecure = tr(" [INSEGURA]")
+			}
+			fmt.Printf("  %s (%d bits, %s)%s\n", suite.Name, suite.CipherStrength, keyExchange(suite), insecure)
+		}
+	}
+
+	// Características del protocolo
+	fmt.Printf("Forward Secrecy: %s\n", describeForwardSecrecy(d.ForwardSecrecy))
+	fmt.Printf("OCSP Stapling: %s\n", yesNo(d.OCSPStapling))
+	if d.SupportsALPN && d.ALPNProtocols != "" {
+		fmt.Printf("ALPN: %s\n", d.ALPNProtocols)
+	}
+	if d.Local {
+		// El resto no se prueba en la evaluación local
+		fmt.Println(tr("Reanudación de sesión, renegociación, HSTS/HPKP y vulnerabilidades: no evaluadas (evaluación local)"))
+		return
+	}
+	fmt.Printf(tr("Reanudación de sesión: %s\n"), describeSessionResumption(d.SessionResumption))
+	fmt.Printf("Session tickets: %s\n", yesNo(d.SessionTickets&1 != 0))
+	fmt.Printf(tr("Renegociación segura: %s\n"), yesNo(d.RenegSupport&2 != 0))
+	fmt.Printf(tr("SNI requerido: %s\n"), yesNo(d.SNIRequired))
+
+	// Políticas HTTP
+	fmt.Printf("HSTS: %s\n", describeHSTS(d.HSTSPolicy))
+	if d.HPKPPolicy != nil && d.HPKPPolicy.Status != "" {
+		fmt.Printf("HPKP: %s\n", d.HPKPPolicy.Status)
+	}
+
+	// Vulnerabilidades conocidas
+	fmt.Println(tr("Pruebas de vulnerabilidades:"))
+	for _, check := range vulnerabilityChecks(d) {
+		fmt.Printf("  %s: %s\n", check.Name, tr(check.Status))
+	}
+}
+
+// describeForwardSecrecy renders the forwardSecrecy bit field
+func describeForwardSecrecy(fs int) string {
+	switch {
+	case fs&4 != 0:
+		return tr("Sí, con todos los clientes simulados")
+	case fs&2 != 0:
+		return tr("Sí, con clientes modernos")
+	case fs&1 != 0:
+		return tr("Con algunos clientes")
+	default:
+		return "No"
+	}
+}
+
+// describeSessionResumption renders the sessionResumption value
+func describeSessionResumption(value int) string {
+	switch value {
+	case 0:
+		return tr("No habilitada")
+	case 1:
+		return tr("IDs de sesión asignados pero no aceptados")
+	case 2:
+		return tr("Habilitada")
+	default:
+		return fmt.Sprintf(tr("Desconocida (%d)"), value)
+	}
+}
+
+// describeHSTS renders the HSTS policy in one line
+func describeHSTS(policy *HSTSPolicy) string {
+	if policy == nil || policy.Status == "" {
+		return tr("desconocido")
+	}
+	if policy.Status != "present" {
+		return policy.Status
+	}
+
+	parts := []string{}
+	if policy.MaxAge != nil {
+		parts = append(parts, fmt.Sprintf("max-age=%d", *policy.MaxAge))
+	}
+	if policy.IncludeSubDomains {
+		parts = append(parts, "includeSubDomains")
+	}
+	if policy.Preload {
+		parts = append(parts, "preload")
+	}
+	return fmt.Sprintf("%s (%s)", policy.Status, strings.Join(parts, ", "))
+}
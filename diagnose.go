@@ -0,0 +1,546 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// legacyClient is the TLS configuration a client offers when it connects,
+// as in the handshake simulations of SSL Labs
+type legacyClient struct {
+	ID         string // Identificador para --client (ej: "chrome-49")
+	Name       string // Nombre, versión y plataforma como en las simulaciones de SSL Labs
+	Version    string
+	Platform   string
+	MinVersion uint16
+	MaxVersion uint16
+	Suites     []uint16 // IDs de las cipher suites en el orden del cliente, incluidas las que crypto/tls no implementa
+	Curves     []tls.CurveID
+	SNI        bool
+}
+
+// label returns the name of the client as SSL Labs shows it
+func (c legacyClient) label() string {
+	label := c.Name + " " + c.Version
+	if c.Platform != "" {
+		label += " / " + c.Platform
+	}
+	return label
+}
+
+// Cipher suites de los clientes antiguos que crypto/tls no implementa (DHE,
+// DSS, MD5 y ChaCha20 anterior al RFC 7905), con sus nombres IANA
+var legacySuiteNames = map[uint16]string{
+	0x0004: "TLS_RSA_WITH_RC4_128_MD5",
+	0x0013: "TLS_DHE_DSS_WITH_3DES_EDE_CBC_SHA",
+	0x0016: "TLS_DHE_RSA_WITH_3DES_EDE_CBC_SHA",
+	0x0032: "TLS_DHE_DSS_WITH_AES_128_CBC_SHA",
+	0x0033: "TLS_DHE_RSA_WITH_AES_128_CBC_SHA",
+	0x0038: "TLS_DHE_DSS_WITH_AES_256_CBC_SHA",
+	0x0039: "TLS_DHE_RSA_WITH_AES_256_CBC_SHA",
+	0x0040: "TLS_DHE_DSS_WITH_AES_128_CBC_SHA256",
+	0x0067: "TLS_DHE_RSA_WITH_AES_128_CBC_SHA256",
+	0x006a: "TLS_DHE_DSS_WITH_AES_256_CBC_SHA256",
+	0x009e: "TLS_DHE_RSA_WITH_AES_128_GCM_SHA256",
+	0x009f: "TLS_DHE_RSA_WITH_AES_256_GCM_SHA384",
+	0x003d: "TLS_RSA_WITH_AES_256_CBC_SHA256",
+	0x009d: "TLS_RSA_WITH_AES_256_GCM_SHA384",
+	0xc024: "TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA384",
+	0xc028: "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA384",
+	0xc02c: "TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+	0xc008: "TLS_ECDHE_ECDSA_WITH_3DES_EDE_CBC_SHA",
+	0xcc13: "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256 (draft)",
+	0xcc14: "TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256 (draft)",
+}
+
+// legacyClients are the clients that diagnose can reproduce, taken from the
+// handshakes that SSL Labs simulates. Only the offer of the client matters
+// here: protocol versions, cipher suites, curves and SNI.
+var legacyClients = []legacyClient{
+	{
+		ID: "android-2.3", Name: "Android", Version: "2.3.7",
+		MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS10,
+		Suites: []uint16{0x0004, 0x0005, 0x002f, 0x0033, 0x0032, 0x000a, 0x0016, 0x0013, 0x0035, 0x0039, 0x0038},
+	},
+	{
+		ID: "android-4.0", Name: "Android", Version: "4.0.4",
+		MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS10,
+		Suites: []uint16{0xc014, 0xc00a, 0x0039, 0x0038, 0x0035, 0xc013, 0xc009, 0x0033, 0x0032, 0x002f, 0xc012, 0xc008, 0x0016, 0x0013, 0x000a, 0xc011, 0xc007, 0x0005, 0x0004},
+		Curves: []tls.CurveID{tls.CurveP256, tls.CurveP384, tls.CurveP521},
+		SNI:    true,
+	},
+	{
+		ID: "android-4.4", Name: "Android", Version: "4.4.2",
+		MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS12,
+		Suites: []uint16{0xc02b, 0xc02f, 0x009e, 0xc00a, 0xc009, 0xc013, 0xc014, 0xc007, 0xc011, 0x0033, 0x0032, 0x0039, 0x009c, 0x002f, 0x0035, 0x000a, 0x0005, 0x0004},
+		Curves: []tls.CurveID{tls.CurveP256, tls.CurveP384, tls.CurveP521},
+		SNI:    true,
+	},
+	{
+		ID: "chrome-49", Name: "Chrome", Version: "49", Platform: "XP SP3",
+		MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS12,
+		Suites: []uint16{0xc02b, 0xc02f, 0xcc14, 0xcc13, 0xc00a, 0xc014, 0x0039, 0xc009, 0xc013, 0x0033, 0x009c, 0x0035, 0x002f, 0x000a},
+		Curves: []tls.CurveID{tls.CurveP256, tls.CurveP384},
+		SNI:    true,
+	},
+	{
+		ID: "ie-8-xp", Name: "IE", Version: "8", Platform: "XP",
+		MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS10,
+		Suites: []uint16{0x0004, 0x0005, 0x000a, 0x0013},
+	},
+	{
+		ID: "ie-11-win7", Name: "IE", Version: "11", Platform: "Win 7",
+		MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS12,
+		Suites: []uint16{0xc028, 0xc027, 0xc014, 0xc013, 0x009f, 0x009e, 0x009d, 0x009c, 0x003d, 0x003c, 0x0035, 0x002f,
+			0xc02c, 0xc02b, 0xc024, 0xc023, 0xc00a, 0xc009, 0x006a, 0x0040, 0x0038, 0x0032, 0x000a, 0x0013},
+		Curves: []tls.CurveID{tls.CurveP256, tls.CurveP384},
+		SNI:    true,
+	},
+	{
+		ID: "java-6", Name: "Java", Version: "6u45",
+		MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS10,
+		Suites: []uint16{0x0004, 0x0005, 0x002f, 0x0033, 0x0032, 0x000a, 0x0016, 0x0013},
+	},
+	{
+		ID: "java-7", Name: "Java", Version: "7u25",
+		MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS10,
+		Suites: []uint16{0xc009, 0xc013, 0x002f, 0x0033, 0x0032, 0xc008, 0xc012, 0x000a, 0x0016, 0x0013, 0xc007, 0xc011, 0x0005, 0x0004},
+		Curves: []tls.CurveID{tls.CurveP256, tls.CurveP384, tls.CurveP521},
+		SNI:    true,
+	},
+	{
+		ID: "openssl-0.9.8", Name: "OpenSSL", Version: "0.9.8y",
+		MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS10,
+		Suites: []uint16{0x0039, 0x0038, 0x0035, 0x0016, 0x0013, 0x000a, 0x0033, 0x0032, 0x002f, 0x0005, 0x0004},
+		SNI:    true,
+	},
+	{
+		ID: "safari-6-ios6", Name: "Safari", Version: "6", Platform: "iOS 6.0.1",
+		MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS12,
+		Suites: []uint16{0xc024, 0xc023, 0xc00a, 0xc009, 0xc008, 0xc028, 0xc027, 0xc014, 0xc013, 0xc012, 0x003d, 0x003c, 0x0035, 0x002f, 0x000a},
+		Curves: []tls.CurveID{tls.CurveP256, tls.CurveP384, tls.CurveP521},
+		SNI:    true,
+	},
+}
+
+// diagnoseCurves are the curves crypto/tls can offer, probed one by one
+var diagnoseCurves = []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384, tls.CurveP521}
+
+// findLegacyClient returns the client with the given --client ID
+func findLegacyClient(id string) (legacyClient, error) {
+	for _, client := range legacyClients {
+		if client.ID == strings.ToLower(id) {
+			return client, nil
+		}
+	}
+	ids := make([]string, 0, len(legacyClients))
+	for _, client := range legacyClients {
+		ids = append(ids, client.ID)
+	}
+	return legacyClient{}, fmt.Errorf("cliente desconocido %q: se espera %s", id, strings.Join(ids, ", "))
+}
+
+// suiteName returns the IANA name of a cipher suite ID
+func suiteName(id uint16) string {
+	if name, ok := legacySuiteNames[id]; ok {
+		return name
+	}
+	return tls.CipherSuiteName(id)
+}
+
+// testableSuites splits the suites of a client into those crypto/tls can
+// offer and those it can't
+func testableSuites(suites []uint16) (testable, untestable []uint16) {
+	known := append(tls.CipherSuites(), tls.InsecureCipherSuites()...)
+	for _, id := range suites {
+		if _, ok := legacySuiteNames[id]; !ok && slices.ContainsFunc(known, func(s *tls.CipherSuite) bool { return s.ID == id }) {
+			testable = append(testable, id)
+		} else {
+			untestable = append(untestable, id)
+		}
+	}
+	return testable, untestable
+}
+
+// clientHello is the offer of a diagnostic handshake
+type clientHello struct {
+	minVersion, maxVersion uint16
+	suites                 []uint16      // nil = todas las de crypto/tls
+	curves                 []tls.CurveID // nil = las de crypto/tls
+	sni                    bool
+}
+
+// diagnoser reproduces the handshake of a client against one address
+type diagnoser struct {
+	domain  string
+	address string
+}
+
+// handshake runs a TLS handshake with the offer of hello. Certificates
+// are not validated: only the negotiation matters.
+func (d *diagnoser) handshake(ctx context.Context, hello clientHello) (*tls.ConnectionState, error) {
+	ctx, cancel := context.WithTimeout(ctx, localHandshakeTimeout)
+	defer cancel()
+
+	suites := hello.suites
+	if suites == nil {
+		for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+			suites = append(suites, suite.ID)
+		}
+	}
+	config := &tls.Config{
+		MinVersion:         hello.minVersion,
+		MaxVersion:         hello.maxVersion,
+		CipherSuites:       suites,
+		CurvePreferences:   hello.curves,
+		InsecureSkipVerify: true,
+	}
+	// Sin ServerName crypto/tls no envía la extensión SNI
+	if hello.sni {
+		config.ServerName = d.domain
+	}
+	dialer := &tls.Dialer{Config: config}
+	conn, err := dialer.DialContext(ctx, "tcp", d.address)
+	if err != nil {
+		var netErr *net.OpError
+		if errors.As(err, &netErr) && netErr.Op == "dial" {
+			return nil, fmt.Errorf("no se pudo conectar a %s: %w", d.address, err)
+		}
+		return nil, &handshakeError{err}
+	}
+	defer conn.Close()
+	state := conn.(*tls.Conn).ConnectionState()
+	return &state, nil
+}
+
+// handshakeError is a handshake rejected by the server, as opposed to a
+// connection that couldn't be opened
+type handshakeError struct {
+	err error
+}
+
+func (e *handshakeError) Error() string { return e.err.Error() }
+func (e *handshakeError) Unwrap() error { return e.err }
+
+// Diagnosis is the outcome of reproducing the handshake of a client
+type Diagnosis struct {
+	Client      legacyClient
+	Address     string
+	Negotiated  *tls.ConnectionState // nil si el handshake falla
+	Cause       string               // Motivo del fallo
+	ClientOffer string               // Lo que ofrece el cliente en el punto del fallo
+	ServerOffer string               // Lo que acepta el servidor en ese punto
+	Notes       []string
+	Err         error // Error del handshake con la oferta completa del cliente
+}
+
+// diagnose reproduces the handshake of client and, when it fails, narrows
+// the mismatch down step by step: protocol version, SNI, cipher suites and
+// curves. Each step changes a single aspect of the offer of the client.
+func (d *diagnoser) diagnose(ctx context.Context, client legacyClient) (*Diagnosis, error) {
+	diagnosis := &Diagnosis{Client: client, Address: d.address}
+	testable, untestable := testableSuites(client.Suites)
+	if len(untestable) > 0 {
+		var names []string
+		for _, id := range untestable {
+			names = append(names, suiteName(id))
+		}
+		diagnosis.Notes = append(diagnosis.Notes, fmt.Sprintf("crypto/tls no implementa %d de las suites del cliente, que no se prueban: %s",
+			len(untestable), strings.Join(names, ", ")))
+	}
+	if len(testable) == 0 {
+		return nil, fmt.Errorf("no se puede reproducir %s: crypto/tls no implementa ninguna de sus cipher suites", client.label())
+	}
+
+	full := clientHello{minVersion: client.MinVersion, maxVersion: client.MaxVersion, suites: testable, curves: client.Curves, sni: client.SNI}
+	state, err := d.handshake(ctx, full)
+	var rejected *handshakeError
+	switch {
+	case err == nil:
+		diagnosis.Negotiated = state
+		if !client.SNI && state.PeerCertificates[0].VerifyHostname(d.domain) != nil {
+			diagnosis.Notes = append(diagnosis.Notes, fmt.Sprintf("Sin SNI el servidor envía un certificado de %s, que no es válido para %s: el cliente lo rechazará",
+				certLabel(state.PeerCertificates[0].Subject.CommonName, state.PeerCertificates[0].Subject.Organization), d.domain))
+		}
+		return diagnosis, nil
+	case !errors.As(err, &rejected):
+		return nil, err
+	}
+	diagnosis.Err = err
+
+	// 1. Versión del protocolo: la que acepta el servidor con cualquier suite
+	var clientVersions, serverVersions, shared []uint16
+	for _, version := range localProtocols {
+		if version >= client.MinVersion && version <= client.MaxVersion {
+			clientVersions = append(clientVersions, version)
+		}
+		if _, err := d.handshake(ctx, clientHello{minVersion: version, maxVersion: version, sni: true}); err == nil {
+			serverVersions = append(serverVersions, version)
+			if slices.Contains(clientVersions, version) {
+				shared = append(shared, version)
+			}
+		}
+	}
+	if len(shared) == 0 {
+		diagnosis.Cause = "Sin versión de protocolo en común"
+		diagnosis.ClientOffer = versionNames(clientVersions)
+		diagnosis.ServerOffer = versionNames(serverVersions)
+		return diagnosis, nil
+	}
+	best := shared[len(shared)-1]
+
+	// 2. SNI: el mismo handshake que funciona con SNI, sin enviarlo
+	if !client.SNI {
+		if _, err := d.handshake(ctx, clientHello{minVersion: best, maxVersion: best}); err != nil {
+			diagnosis.Cause = "El servidor requiere SNI"
+			diagnosis.ClientOffer = "handshake sin la extensión SNI"
+			diagnosis.ServerOffer = "solo completa el handshake con SNI"
+			return diagnosis, nil
+		}
+	}
+
+	// 3. Cipher suites: las del cliente que acepta el servidor, de a una
+	var accepted []uint16
+	for _, id := range testable {
+		if _, err := d.handshake(ctx, clientHello{minVersion: best, maxVersion: best, suites: []uint16{id}, sni: true}); err == nil {
+			accepted = append(accepted, id)
+		}
+	}
+	if len(accepted) == 0 {
+		var server []uint16
+		for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+			if !slices.Contains(suite.SupportedVersions, best) {
+				continue
+			}
+			if _, err := d.handshake(ctx, clientHello{minVersion: best, maxVersion: best, suites: []uint16{suite.ID}, sni: true}); err == nil {
+				server = append(server, suite.ID)
+			}
+		}
+		diagnosis.Cause = "Sin cipher suite en común en " + protocolName(int(best))
+		diagnosis.ClientOffer = suiteNames(testable)
+		diagnosis.ServerOffer = suiteNames(server)
+		if len(untestable) > 0 {
+			diagnosis.Notes = append(diagnosis.Notes, "Si el servidor acepta alguna de las suites que no se prueban (DHE, DSS...), el cliente podría conectar")
+		}
+		return diagnosis, nil
+	}
+
+	// 4. Curvas: las suites en común son ECDHE y el servidor no acepta
+	// ninguna de las curvas del cliente
+	if len(client.Curves) > 0 {
+		if _, err := d.handshake(ctx, clientHello{minVersion: best, maxVersion: best, suites: accepted, curves: client.Curves, sni: true}); err != nil {
+			var server []tls.CurveID
+			for _, curve := range diagnoseCurves {
+				if _, err := d.handshake(ctx, clientHello{minVersion: best, maxVersion: best, suites: accepted, curves: []tls.CurveID{curve}, sni: true}); err == nil {
+					server = append(server, curve)
+				}
+			}
+			diagnosis.Cause = "Sin curva elíptica en común"
+			diagnosis.ClientOffer = curveNames(client.Curves)
+			diagnosis.ServerOffer = curveNames(server)
+			return diagnosis, nil
+		}
+	}
+
+	diagnosis.Cause = "Ninguna diferencia de versión, SNI, suites o curvas explica el fallo (puede deberse a los algoritmos de firma o a extensiones que crypto/tls no reproduce)"
+	return diagnosis, nil
+}
+
+// versionNames renders protocol versions
+func versionNames(versions []uint16) string {
+	if len(versions) == 0 {
+		return "ninguna"
+	}
+	names := make([]string, len(versions))
+	for i, version := range versions {
+		names[i] = protocolName(int(version))
+	}
+	return strings.Join(names, ", ")
+}
+
+// suiteNames renders cipher suite IDs
+func suiteNames(suites []uint16) string {
+	if len(suites) == 0 {
+		return "ninguna"
+	}
+	names := make([]string, len(suites))
+	for i, id := range suites {
+		names[i] = suiteName(id)
+	}
+	return strings.Join(names, ", ")
+}
+
+// curveNames renders curve IDs
+func curveNames(curves []tls.CurveID) string {
+	if len(curves) == 0 {
+		return "ninguna"
+	}
+	names := make([]string, len(curves))
+	for i, curve := range curves {
+		names[i] = curve.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// displayDiagnosis prints the outcome of a diagnosis
+func displayDiagnosis(diagnosis *Diagnosis) {
+	client := diagnosis.Client
+	fmt.Printf("=== %s → %s ===\n", client.label(), diagnosis.Address)
+	curves := "sin ECC"
+	if len(client.Curves) > 0 {
+		curves = "curvas " + curveNames(client.Curves)
+	}
+	fmt.Printf("Cliente: %s a %s · %d cipher suites · %s · SNI: %s\n", protocolName(int(client.MinVersion)),
+		protocolName(int(client.MaxVersion)), len(client.Suites), curves, yesNo(client.SNI))
+
+	if state := diagnosis.Negotiated; state != nil {
+		leaf := state.PeerCertificates[0]
+		key := localKey(leaf)
+		line := fmt.Sprintf("✅ El cliente conecta: %s · %s", protocolName(int(state.Version)), tls.CipherSuiteName(state.CipherSuite))
+		if state.CurveID != 0 {
+			line += " · " + state.CurveID.String()
+		}
+		line += fmt.Sprintf(" · certificado %s (%s)", describeKey(key.Alg, key.Size), certLabel(leaf.Subject.CommonName, leaf.Subject.Organization))
+		fmt.Printf("%s\n", paint(colorGreen, line))
+	} else {
+		fmt.Printf("%s\n", paint(colorRed, "❌ El handshake falla: "+diagnosis.Err.Error()))
+		fmt.Printf("Causa: %s\n", diagnosis.Cause)
+		if diagnosis.ClientOffer != "" {
+			fmt.Printf("  Cliente ofrece:  %s\n", diagnosis.ClientOffer)
+			fmt.Printf("  Servidor acepta: %s\n", diagnosis.ServerOffer)
+		}
+	}
+	for _, note := range diagnosis.Notes {
+		fmt.Printf("%s\n", paint(colorYellow, "⚠️  "+note))
+	}
+}
+
+// findSimulation returns the SSL Labs simulation of client, if any
+func findSimulation(d *EndpointDetails, client legacyClient) *Simulation {
+	if d == nil || d.Sims == nil {
+		return nil
+	}
+	for i, sim := range d.Sims.Results {
+		if strings.EqualFold(sim.Client.Name, client.Name) && sim.Client.Version == client.Version &&
+			(client.Platform == "" || strings.EqualFold(sim.Client.Platform, client.Platform)) {
+			return &d.Sims.Results[i]
+		}
+	}
+	return nil
+}
+
+// displaySimulations prints the SSL Labs simulation of client for each
+// endpoint of result
+func displaySimulations(result *AssessmentResult, client legacyClient) {
+	fmt.Printf("Simulación de SSL Labs (%s):\n", client.label())
+	for _, endpoint := range result.Endpoints {
+		sim := findSimulation(endpoint.Details, client)
+		switch {
+		case sim == nil:
+			fmt.Printf("  %s: ❔ SSL Labs no simula este cliente\n", endpoint.IPAddress)
+		case sim.ErrorCode != 0:
+			fmt.Printf("  %s: %s\n", endpoint.IPAddress, paint(colorRed, "❌ "+orUnknown(sim.ErrorMessage)))
+		default:
+			fmt.Printf("  %s: %s\n", endpoint.IPAddress, paint(colorGreen, fmt.Sprintf("✅ %s · %s", protocolName(sim.ProtocolID), sim.SuiteName)))
+		}
+	}
+}
+
+// diagnoseAddress resolves domain and returns the address of its first IP
+func diagnoseAddress(ctx context.Context, domain string, port int) (string, error) {
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", domain)
+	if err != nil {
+		return "", fmt.Errorf("no se pudo resolver %s: %w", domain, err)
+	}
+	slices.SortFunc(addrs, compareAddrs)
+	return net.JoinHostPort(addrs[0].Unmap().String(), strconv.Itoa(port)), nil
+}
+
+// runDiagnose implements the "diagnose" subcommand: it reproduces the
+// handshake of a legacy client against a domain and explains why it fails
+func runDiagnose(args []string) error {
+	fs := flag.NewFlagSet("diagnose", flag.ExitOnError)
+	clientID := fs.String("client", "", "cliente a reproducir, ej: chrome-49 (--list muestra todos)")
+	list := fs.Bool("list", false, "listar los clientes disponibles")
+	port := fs.Int("port", localPort, "puerto del servidor")
+	address := fs.String("address", "", "IP del servidor a probar, en vez de la primera del dominio")
+	useAPI := fs.Bool("api", false, "mostrar también la simulación de SSL Labs del cliente (hace una evaluación; con --from-cache usa la última)")
+	apiFlags := addClientFlags(fs)
+	color := addColorFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s diagnose <domain> --client <cliente> [flags]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Ejemplo: %s diagnose example.com --client chrome-49\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+
+	// El dominio puede ir antes de los flags
+	var domain string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		domain, args = args[0], args[1:]
+	}
+	fs.Parse(args)
+	if domain == "" && fs.NArg() == 1 {
+		domain = fs.Arg(0)
+	}
+	if err := setOutputColor(*color); err != nil {
+		return err
+	}
+
+	if *list {
+		for _, client := range legacyClients {
+			fmt.Printf("%-15s %s\n", client.ID, client.label())
+		}
+		return nil
+	}
+	if domain == "" || *clientID == "" {
+		fs.Usage()
+		return fmt.Errorf("se requieren un dominio y --client")
+	}
+	if err := validateDomain(domain); err != nil {
+		return err
+	}
+	client, err := findLegacyClient(*clientID)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	target := net.JoinHostPort(*address, strconv.Itoa(*port))
+	if *address == "" {
+		if target, err = diagnoseAddress(ctx, domain, *port); err != nil {
+			return err
+		}
+	} else if _, err := netip.ParseAddr(*address); err != nil {
+		return fmt.Errorf("--address inválida %q: se espera una IP", *address)
+	}
+
+	diagnosis, err := (&diagnoser{domain: domain, address: target}).diagnose(ctx, client)
+	if err != nil {
+		return err
+	}
+	displayDiagnosis(diagnosis)
+
+	if *useAPI {
+		opts, err := apiFlags.options()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Consultando las simulaciones de SSL Labs para %s...\n", domain)
+		result, err := NewScanner(opts...).AssessContext(ctx, domain)
+		if err != nil {
+			return err
+		}
+		fmt.Println()
+		displaySimulations(result, client)
+	}
+	return nil
+}
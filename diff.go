@@ -0,0 +1,215 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"ssllabs-scanner/store"
+)
+
+// historyEntryFromResult converts an assessment result to the stored
+// representation, so fresh results and history entries can be compared
+func historyEntryFromResult(result *AssessmentResult) store.Entry {
+	entry := store.Entry{
+		Domain:       result.Domain,
+		ScannedAt:    time.UnixMilli(result.TestTime),
+		OverallGrade: result.OverallGrade,
+		Metadata:     &result.Metadata,
+	}
+	for _, endpoint := range result.Endpoints {
+		entry.Endpoints = append(entry.Endpoints, store.Endpoint{
+			IPAddress:       endpoint.IPAddress,
+			Grade:           endpoint.Grade,
+			Protocols:       endpoint.TLSProtocols,
+			CertFingerprint: endpoint.CertFingerprint,
+			CertIssuer:      endpoint.CertIssuer,
+			CertNotAfter:    endpoint.CertValidTo,
+			Vulnerabilities: endpoint.Vulnerabilities,
+		})
+	}
+	return entry
+}
+
+// loadAssessmentFile reads a saved /analyze API response (v2, v3 or v4)
+// and processes it like a live assessment
+func loadAssessmentFile(path string) (*AssessmentResult, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo leer %s: %w", path, err)
+	}
+	defer file.Close()
+
+	host, err := decodeHost(file)
+	if err != nil {
+		return nil, fmt.Errorf("error parseando %s: %w", path, err)
+	}
+	if len(host.Certs) > 0 {
+		normalizeCerts(host)
+	}
+
+	result, err := ProcessResults(host)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	result.Metadata.Source = sourceReplay
+	return result, nil
+}
+
+// describeChanges returns one line of text per change of diff
+func describeChanges(diff store.Diff) []string {
+	current := make(map[string]store.Endpoint, len(diff.Current.Endpoints))
+	for _, endpoint := range diff.Current.Endpoints {
+		current[endpoint.IPAddress] = endpoint
+	}
+
+	var lines []string
+	for _, change := range diff.Changes {
+		prefix := change.Endpoint + ": "
+		switch change.Kind {
+		case store.ChangeGrade:
+			movement := fmt.Sprintf("%s → %s (%s)", change.Previous, change.Current, gradeMovement(change.Previous, change.Current))
+			if change.Endpoint == "" {
+				lines = append(lines, "Grade General: "+movement)
+			} else {
+				lines = append(lines, prefix+"grade "+movement)
+			}
+		case store.ChangeEndpointAdded:
+			lines = append(lines, fmt.Sprintf("%sendpoint nuevo (grade %s)", prefix, change.Current))
+		case store.ChangeEndpointRemoved:
+			lines = append(lines, prefix+"endpoint eliminado")
+		case store.ChangeProtocolsAdded:
+			lines = append(lines, prefix+"protocolos agregados: "+strings.Join(change.Values, ", "))
+		case store.ChangeProtocolsRemoved:
+			lines = append(lines, prefix+"protocolos eliminados: "+strings.Join(change.Values, ", "))
+		case store.ChangeCert:
+			endpoint := current[change.Endpoint]
+			line := fmt.Sprintf("%snuevo certificado %s (emisor %s", prefix, shortFingerprint(change.Current), endpoint.CertIssuer)
+			if endpoint.CertNotAfter > 0 {
+				line += ", expira " + formatDate(time.UnixMilli(endpoint.CertNotAfter))
+			}
+			lines = append(lines, line+")")
+		case store.ChangeVulnerabilitiesAdded:
+			lines = append(lines, prefix+"nuevas vulnerabilidades: "+strings.Join(change.Values, ", "))
+		case store.ChangeVulnerabilitiesFixed:
+			lines = append(lines, prefix+"vulnerabilidades corregidas: "+strings.Join(change.Values, ", "))
+		}
+	}
+	return lines
+}
+
+// gradeMovement describes a grade change as an improvement or a regression
+func gradeMovement(previous, current string) string {
+	switch compareGrades(current, previous) {
+	case 1:
+		return "mejoró"
+	case -1:
+		return "empeoró"
+	default:
+		return "cambió"
+	}
+}
+
+// diffLists returns the values only present in current (added) and only
+// present in previous (removed), keeping their original order
+func diffLists(previous, current []string) (added, removed []string) {
+	inPrevious := make(map[string]bool, len(previous))
+	for _, value := range previous {
+		inPrevious[value] = true
+	}
+	inCurrent := make(map[string]bool, len(current))
+	for _, value := range current {
+		inCurrent[value] = true
+		if !inPrevious[value] {
+			added = append(added, value)
+		}
+	}
+	for _, value := range previous {
+		if !inCurrent[value] {
+			removed = append(removed, value)
+		}
+	}
+	return added, removed
+}
+
+// runDiff implements the "diff" subcommand. With one argument it compares
+// the two latest stored assessments of a domain; with two it compares two
+// saved API responses.
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	path := fs.String("history-db", defaultHistoryPath(), historyDBUsage)
+	tz := addTimezoneFlag(fs)
+	color := addColorFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s diff [--history-db archivo] <domain>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s diff <anterior.json> <actual.json>\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if err := setOutputTimezone(*tz); err != nil {
+		return err
+	}
+	if err := setOutputColor(*color); err != nil {
+		return err
+	}
+
+	var diff store.Diff
+	switch fs.NArg() {
+	case 1:
+		domain := strings.TrimSpace(fs.Arg(0))
+
+		history, err := store.Open(*path)
+		if err != nil {
+			return err
+		}
+		defer history.Close()
+
+		entries, err := history.List(domain, 2)
+		if err != nil {
+			return err
+		}
+		if len(entries) < 2 {
+			return fmt.Errorf("se necesitan al menos dos evaluaciones guardadas de %s (hay %d)", domain, len(entries))
+		}
+		if diff, err = history.Diff(entries[1].ID, entries[0].ID); err != nil {
+			return err
+		}
+	case 2:
+		var entries [2]store.Entry
+		for i := range entries {
+			result, err := loadAssessmentFile(fs.Arg(i))
+			if err != nil {
+				return err
+			}
+			entries[i] = historyEntryFromResult(result)
+		}
+		diff = store.Compare(entries[0], entries[1])
+	default:
+		fs.Usage()
+		return fmt.Errorf("se requiere un dominio o dos archivos JSON")
+	}
+
+	previous, current := diff.Previous, diff.Current
+	fmt.Printf("=== Cambios en %s ===\n", current.Domain)
+	fmt.Printf("Anterior: %s  Grade General: %s\n", formatDateTime(previous.ScannedAt), paintGrade(previous.OverallGrade))
+	fmt.Printf("Actual:   %s  Grade General: %s\n", formatDateTime(current.ScannedAt), paintGrade(current.OverallGrade))
+	if previous.Metadata != nil && current.Metadata != nil {
+		// Un cambio de criterios explica cambios de grade sin cambios en el servidor
+		fmt.Printf("Criterios: %s → %s · Motor: %s → %s\n",
+			orUnknown(previous.Metadata.CriteriaVersion), orUnknown(current.Metadata.CriteriaVersion),
+			orUnknown(previous.Metadata.EngineVersion), orUnknown(current.Metadata.EngineVersion))
+	}
+	fmt.Println()
+
+	if len(diff.Changes) == 0 {
+		fmt.Println("Sin cambios")
+		return nil
+	}
+	for _, change := range describeChanges(diff) {
+		fmt.Printf("  • %s\n", change)
+	}
+	return nil
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	dnsTimeout    = 5 * time.Second // Espera máxima por cada consulta
+	dnsMaxUDPSize = 4096            // Tamaño de respuesta anunciado con EDNS0
+	dnsResolvConf = "/etc/resolv.conf"
+)
+
+// Tipos y códigos de respuesta de DNS (RFC 1035, RFC 4034, RFC 6698, RFC 8659)
+const (
+	dnsTypeA      = 1
+	dnsTypeCNAME  = 5
+	dnsTypeAAAA   = 28
+	dnsTypeOPT    = 41
+	dnsTypeDS     = 43
+	dnsTypeRRSIG  = 46
+	dnsTypeDNSKEY = 48
+	dnsTypeTLSA   = 52
+	dnsTypeCAA    = 257
+	dnsClassIN    = 1
+
+	dnsRcodeSuccess  = 0
+	dnsRcodeNXDomain = 3
+)
+
+// dnsRecord is a resource record of the answer section of a response
+type dnsRecord struct {
+	Name string
+	Type uint16
+	TTL  uint32
+	Data []byte // RDATA sin interpretar
+}
+
+// systemResolver returns the address of the first nameserver of
+// /etc/resolv.conf, or the local one when there is none
+func systemResolver() string {
+	file, err := os.Open(dnsResolvConf)
+	if err == nil {
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) >= 2 && fields[0] == "nameserver" {
+				// JoinHostPort agrega los corchetes de las direcciones IPv6
+				return net.JoinHostPort(fields[1], "53")
+			}
+		}
+	}
+	return "127.0.0.1:53"
+}
+
+// dnsQuery asks the system resolver for the records of type qtype of name
+// and returns those of the answer section. net.Resolver only supports the
+// common types, so records like CAA are queried with this minimal client. A name that doesn't exist has
+// no records. The query goes over UDP and is retried over TCP when the
+// response is truncated.
+func dnsQuery(ctx context.Context, name string, qtype uint16) ([]dnsRecord, error) {
+	return dnsResolve(ctx, systemResolver(), name, qtype, false)
+}
+
+// dnsQueryDNSSEC is dnsQuery asking also for the RRSIG records (DO) and
+// without the validation of the resolver (CD), so that a bogus chain is
+// returned to be reported instead of failing with SERVFAIL
+func dnsQueryDNSSEC(ctx context.Context, name string, qtype uint16) ([]dnsRecord, error) {
+	return dnsResolve(ctx, systemResolver(), name, qtype, true)
+}
+
+// dnsResolve implements dnsQuery and dnsQueryDNSSEC against server
+// (host:port), which may also be a resolver other than the system one
+func dnsResolve(ctx context.Context, server, name string, qtype uint16, dnssec bool) ([]dnsRecord, error) {
+	ctx, cancel := context.WithTimeout(ctx, dnsTimeout)
+	defer cancel()
+
+	id := uint16(rand.Uint32())
+	query, err := buildDNSQuery(id, name, qtype, dnssec)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := dnsExchange(ctx, "udp", server, query)
+	if err == nil && len(response) >= 4 && response[2]&0x02 != 0 {
+		response, err = dnsExchange(ctx, "tcp", server, query)
+	}
+	if err != nil {
+		return nil, fmt.Errorf(tr("consulta DNS a %s: %w"), server, err)
+	}
+
+	rcode, records, err := parseDNSResponse(response, id)
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf(tr("respuesta DNS inválida de %s: %w"), server, err)
+	case rcode == dnsRcodeNXDomain:
+		return nil, nil
+	case rcode != dnsRcodeSuccess:
+		return nil, fmt.Errorf(tr("el resolver %s respondió %s para %s"), server, dnsRcodeName(rcode), name)
+	}
+	return records, nil
+}
+
+// dnsRcodeName returns the mnemonic of a response code
+func dnsRcodeName(rcode int) string {
+	names := map[int]string{1: "FORMERR", 2: "SERVFAIL", 3: "NXDOMAIN", 4: "NOTIMP", 5: "REFUSED"}
+	if name, ok := names[rcode]; ok {
+		return name
+	}
+	return fmt.Sprintf("RCODE %d", rcode)
+}
+
+// buildDNSQuery encodes a recursive query for name with an EDNS0 record
+// so the resolver can answer large record sets over UDP. With dnssec the
+// query sets the DO and CD bits. An empty name or "." is the root.
+func buildDNSQuery(id uint16, name string, qtype uint16, dnssec bool) ([]byte, error) {
+	msg := binary.BigEndian.AppendUint16(nil, id)
+	msg = append(msg, 0x01, 0x00) // RD: consulta recursiva
+	if dnssec {
+		msg[3] |= 0x10 // CD: sin validar en el resolver
+	}
+	msg = append(msg, 0, 1, 0, 0, 0, 0, 0, 1)
+
+	wire, err := dnsWireName(name)
+	if err != nil {
+		return nil, err
+	}
+	msg = append(msg, wire...)
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	msg = binary.BigEndian.AppendUint16(msg, dnsClassIN)
+
+	// OPT: nombre raíz, tipo, tamaño UDP, rcode extendido, versión y flags, sin datos
+	msg = append(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, dnsTypeOPT)
+	msg = binary.BigEndian.AppendUint16(msg, dnsMaxUDPSize)
+	msg = append(msg, 0, 0)
+	if dnssec {
+		msg = append(msg, 0x80, 0) // DO: incluir los RRSIG
+	} else {
+		msg = append(msg, 0, 0)
+	}
+	msg = append(msg, 0, 0)
+	return msg, nil
+}
+
+// dnsWireName encodes name as a sequence of labels, without compression.
+// An empty name or "." is the root.
+func dnsWireName(name string) ([]byte, error) {
+	name = strings.TrimSuffix(name, ".")
+	var wire []byte
+	if name != "" {
+		for _, label := range strings.Split(name, ".") {
+			if label == "" || len(label) > 63 {
+				return nil, fmt.Errorf(tr("nombre DNS inválido %q"), name)
+			}
+			wire = append(wire, byte(len(label)))
+			wire = append(wire, label...)
+		}
+	}
+	return append(wire, 0), nil
+}
+
+// dnsExchange sends query to server and returns the response. Over TCP
+// messages are prefixed with their length (RFC 1035, 4.2.2).
+func dnsExchange(ctx context.Context, network, server string, query []byte) ([]byte, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if network == "udp" {
+		if _, err := conn.Write(query); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, dnsMaxUDPSize)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+
+	if _, err := conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(query))), query...)); err != nil {
+		return nil, err
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	response := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// errDNSShort is returned when a response ends before its records
+var errDNSShort = localizedError("mensaje truncado")
+
+// parseDNSResponse returns the response code and the answer records of a
+// response to the query with id
+func parseDNSResponse(msg []byte, id uint16) (int, []dnsRecord, error) {
+	if len(msg) < 12 {
+		return 0, nil, errDNSShort
+	}
+	if binary.BigEndian.Uint16(msg) != id || msg[2]&0x80 == 0 {
+		return 0, nil, errors.New(tr("no corresponde a la consulta"))
+	}
+	rcode := int(msg[3] & 0x0f)
+	questions := int(binary.BigEndian.Uint16(msg[4:]))
+	answers := int(binary.BigEndian.Uint16(msg[6:]))
+
+	offset := 12
+	for range questions {
+		_, next, err := readDNSName(msg, offset)
+		if err != nil {
+			return 0, nil, err
+		}
+		offset = next + 4 // Tipo y clase
+	}
+
+	var records []dnsRecord
+	for range answers {
+		name, next, err := readDNSName(msg, offset)
+		if err != nil {
+			return 0, nil, err
+		}
+		if next+10 > len(msg) {
+			return 0, nil, errDNSShort
+		}
+		record := dnsRecord{
+			Name: name,
+			Type: binary.BigEndian.Uint16(msg[next:]),
+			TTL:  binary.BigEndian.Uint32(msg[next+4:]),
+		}
+		length := int(binary.BigEndian.Uint16(msg[next+8:]))
+		offset = next + 10 + length
+		if offset > len(msg) {
+			return 0, nil, errDNSShort
+		}
+		record.Data = msg[next+10 : offset]
+		if record.Type == dnsTypeCNAME {
+			// El nombre puede apuntar a otra parte del mensaje: se descomprime
+			// para que Data no dependa del resto
+			target, _, err := readDNSName(msg, next+10)
+			if err != nil {
+				return 0, nil, err
+			}
+			if record.Data, err = dnsWireName(target); err != nil {
+				return 0, nil, err
+			}
+		}
+		records = append(records, record)
+	}
+	return rcode, records, nil
+}
+
+// readDNSName reads the (possibly compressed) name at offset and returns
+// it with the offset that follows it
+func readDNSName(msg []byte, offset int) (string, int, error) {
+	var labels []string
+	next := -1
+	for jumps := 0; ; {
+		if offset >= len(msg) {
+			return "", 0, errDNSShort
+		}
+		length := int(msg[offset])
+		switch {
+		case length == 0:
+			if next < 0 {
+				next = offset + 1
+			}
+			return strings.Join(labels, "."), next, nil
+		case length&0xc0 == 0xc0:
+			// Puntero a un nombre anterior del mensaje (RFC 1035, 4.1.4)
+			if offset+1 >= len(msg) {
+				return "", 0, errDNSShort
+			}
+			if jumps++; jumps > 32 {
+				return "", 0, errors.New(tr("nombre comprimido con bucle"))
+			}
+			if next < 0 {
+				next = offset + 2
+			}
+			offset = int(binary.BigEndian.Uint16(msg[offset:]) & 0x3fff)
+		default:
+			if offset+1+length > len(msg) {
+				return "", 0, errDNSShort
+			}
+			labels = append(labels, string(msg[offset+1:offset+1+length]))
+			offset += 1 + length
+		}
+	}
+}
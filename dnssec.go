@@ -0,0 +1,663 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Estados de la cadena de confianza (RFC 4035, sección 4.3)
+const (
+	dnssecSecure   = "secure"   // Validada desde el ancla de confianza de la raíz
+	dnssecInsecure = "insecure" // El dominio no está firmado
+	dnssecBogus    = "bogus"    // Firmado, pero la cadena no valida
+)
+
+// dnskeyFlagZone marks the keys that sign the records of a zone
+const dnskeyFlagZone = 0x0100
+
+// dnssecRootAnchors are the DS records of the root KSKs published by IANA
+// in https://data.iana.org/root-anchors/root-anchors.xml (KSK-2017 and
+// KSK-2024)
+var dnssecRootAnchors = []dsRecord{
+	{KeyTag: 20326, Algorithm: 8, DigestType: 2, Digest: "e06d44b80b8f1d39a95c0b0d7c65d08458e880409bbc683457104237c7f8ec8d"},
+	{KeyTag: 38696, Algorithm: 8, DigestType: 2, Digest: "683d2d0acb8c9b712a1948b27f741219298d0a450d612c483af444a4c0fb2b16"},
+}
+
+// dnssecAlgorithms names the signing algorithms (RFC 8624)
+var dnssecAlgorithms = map[uint8]string{
+	5:  "RSASHA1",
+	7:  "RSASHA1-NSEC3-SHA1",
+	8:  "RSASHA256",
+	10: "RSASHA512",
+	13: "ECDSAP256SHA256",
+	14: "ECDSAP384SHA384",
+	15: "ED25519",
+	16: "ED448",
+}
+
+// dnssecDigests names the DS digest types
+var dnssecDigests = map[uint8]string{1: "SHA-1", 2: "SHA-256", 4: "SHA-384"}
+
+// DNSSECCheck is the DNSSEC chain of trust of a domain, from the root down
+// to its address records (--dnssec)
+type DNSSECCheck struct {
+	Status   string // secure, insecure o bogus; vacío si no se pudo verificar
+	Chain    []DNSSECLink
+	Problem  string   // Por qué el dominio no es secure
+	Warnings []string // Algoritmos obsoletos en la cadena
+	Error    string   // Motivo por el que no se pudo consultar el DNS
+}
+
+// DNSSECLink is a validated step of the chain: the keys of a zone,
+// authenticated by the DS of its parent, or the records of the domain
+type DNSSECLink struct {
+	Zone      string // Zona o dominio; "." es la raíz
+	Record    string // DNSKEY, o el tipo de los registros del dominio (A, AAAA, CNAME)
+	KeyTag    uint16 // Llave que firmó los registros
+	Algorithm string
+	Digest    string    // Tipo de digest del DS que autentica la llave (vacío en los registros del dominio)
+	Expires   time.Time // Vencimiento de la firma
+}
+
+// dsRecord is a DS record: the digest of a key of the child zone
+type dsRecord struct {
+	KeyTag     uint16
+	Algorithm  uint8
+	DigestType uint8
+	Digest     string // Hexadecimal en minúsculas
+}
+
+// dnskeyRecord is a DNSKEY record
+type dnskeyRecord struct {
+	Flags     uint16
+	Algorithm uint8
+	PublicKey []byte
+	rdata     []byte
+}
+
+// rrsigRecord is an RRSIG record: the signature of an RRset
+type rrsigRecord struct {
+	TypeCovered uint16
+	Algorithm   uint8
+	Labels      uint8
+	OriginalTTL uint32
+	Expiration  uint32
+	Inception   uint32
+	KeyTag      uint16
+	Signer      string
+	Signature   []byte
+}
+
+// dnssecChecker is an Assessor that, after each assessment, validates the
+// DNSSEC chain of trust of the domain
+type dnssecChecker struct {
+	Assessor
+	query   func(ctx context.Context, name string, qtype uint16) ([]dnsRecord, error)
+	anchors []dsRecord
+}
+
+// withDNSSECCheck wraps scanner so that its results include the DNSSEC
+// chain of trust of the domain
+func withDNSSECCheck(scanner Assessor) Assessor {
+	return &dnssecChecker{Assessor: scanner, query: dnsQueryDNSSEC, anchors: dnssecRootAnchors}
+}
+
+// AssessContext runs the assessment and validates the chain of trust.
+// Interrupted assessments are returned as is.
+func (c *dnssecChecker) AssessContext(ctx context.Context, domain string) (*AssessmentResult, error) {
+	result, err := c.Assessor.AssessContext(ctx, domain)
+	if err != nil {
+		return result, err
+	}
+	result.DNSSEC = c.check(ctx, strings.ToLower(strings.TrimSuffix(domain, ".")), []uint16{dnsTypeA, dnsTypeAAAA}, time.Now())
+	return result, nil
+}
+
+// check validates the chain of trust of domain: the keys of the root
+// against the anchors, then each delegation with a DS down to the zone of
+// the domain and, with its keys, the records of domain of the first of
+// qtypes it has. The resolver is asked without its own validation (CD),
+// so every signature is verified here. The absence of a DS is not proved
+// with NSEC/NSEC3.
+func (c *dnssecChecker) check(ctx context.Context, domain string, qtypes []uint16, now time.Time) *DNSSECCheck {
+	check := &DNSSECCheck{}
+	zone := ""
+	keys, link, err := c.zoneKeys(ctx, zone, "", c.anchors, now)
+	if errors.Is(err, errDNSLookup) {
+		check.Error = err.Error()
+		return check
+	}
+	if err != nil {
+		check.Status, check.Problem = dnssecBogus, fmt.Sprintf(tr("raíz: %s"), err)
+		return check
+	}
+	check.add(link)
+
+	labels := strings.Split(domain, ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		name := strings.Join(labels[i:], ".")
+		rrset, sigs, err := c.fetch(ctx, name, dnsTypeDS)
+		if err != nil {
+			check.Error = err.Error()
+			return check
+		}
+		if len(rrset) == 0 {
+			// Sin DS, name está dentro de la zona actual o es una delegación
+			// sin DNSSEC; una zona firmada sin DS en el padre es una isla
+			dnskeys, _, err := c.fetch(ctx, name, dnsTypeDNSKEY)
+			if err != nil {
+				check.Error = err.Error()
+				return check
+			}
+			if len(dnskeys) > 0 {
+				check.Status = dnssecInsecure
+				check.Problem = fmt.Sprintf(tr("%s publica DNSKEY pero %s no tiene su DS: la cadena de confianza se corta y los resolvers no validan el dominio"), name, dnsZoneLabel(zone))
+				return check
+			}
+			continue
+		}
+
+		if _, err := verifyRRset(name, rrset, sigs, zone, keys, now); err != nil {
+			check.Status, check.Problem = dnssecBogus, fmt.Sprintf(tr("DS de %s en %s: %s"), name, dnsZoneLabel(zone), err)
+			return check
+		}
+		var ds []dsRecord
+		for _, record := range rrset {
+			if parsed, err := parseDS(record.Data); err == nil {
+				ds = append(ds, parsed)
+			}
+		}
+		keys, link, err = c.zoneKeys(ctx, name, zone, ds, now)
+		if errors.Is(err, errDNSLookup) {
+			check.Error = err.Error()
+			return check
+		}
+		if err != nil {
+			check.Status, check.Problem = dnssecBogus, name+": "+err.Error()
+			return check
+		}
+		zone = name
+		check.add(link)
+	}
+
+	// Los registros del dominio, firmados por la última zona de la cadena
+	var rrset []dnsRecord
+	var sigs []rrsigRecord
+	for _, qtype := range qtypes {
+		if rrset, sigs, err = c.fetch(ctx, domain, qtype); err != nil {
+			check.Error = err.Error()
+			return check
+		}
+		if len(rrset) > 0 {
+			break
+		}
+	}
+	if len(rrset) == 0 {
+		names := make([]string, len(qtypes))
+		for i, qtype := range qtypes {
+			names[i] = dnsTypeName(qtype)
+		}
+		check.Error = fmt.Sprintf(tr("%s no tiene registros %s"), domain, strings.Join(names, tr(" ni ")))
+		return check
+	}
+	if len(sigs) == 0 && zone != domain {
+		check.Status = dnssecInsecure
+		check.Problem = fmt.Sprintf(tr("%s no usa DNSSEC: sus registros no están firmados y la cadena de confianza termina en %s"), domain, dnsZoneLabel(zone))
+		return check
+	}
+	sig, err := verifyRRset(domain, rrset, sigs, zone, keys, now)
+	if err != nil {
+		check.Status, check.Problem = dnssecBogus, fmt.Sprintf(tr("registros %s de %s: %s"), dnsTypeName(rrset[0].Type), domain, err)
+		return check
+	}
+	check.add(DNSSECLink{Zone: domain, Record: dnsTypeName(rrset[0].Type), KeyTag: sig.KeyTag,
+		Algorithm: dnssecAlgorithmName(sig.Algorithm), Expires: serialTime(sig.Expiration, now)})
+	check.Status = dnssecSecure
+	return check
+}
+
+// add appends a link to the chain, warning about obsolete algorithms
+func (c *DNSSECCheck) add(link DNSSECLink) {
+	c.Chain = append(c.Chain, link)
+	if strings.HasPrefix(link.Algorithm, "RSASHA1") {
+		c.Warnings = append(c.Warnings, fmt.Sprintf(tr("%s firma con %s, desaconsejado por RFC 8624"), link.Zone, link.Algorithm))
+	}
+	if link.Digest == "SHA-1" {
+		c.Warnings = append(c.Warnings, fmt.Sprintf(tr("el DS de %s usa SHA-1, desaconsejado por RFC 8624"), link.Zone))
+	}
+}
+
+// errDNSLookup marks the failures to query the resolver, as opposed to a
+// chain that doesn't validate
+var errDNSLookup = localizedError("no se pudo consultar el DNS")
+
+// fetch returns the RRset of type qtype of name and the RRSIGs that cover
+// it. A CNAME at name is returned instead, since a name with a CNAME has
+// no other records.
+func (c *dnssecChecker) fetch(ctx context.Context, name string, qtype uint16) ([]dnsRecord, []rrsigRecord, error) {
+	records, err := c.query(ctx, dnsZoneLabel(name), qtype)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", errDNSLookup, err)
+	}
+	owned := func(record dnsRecord) bool { return strings.EqualFold(dnsZoneLabel(record.Name), dnsZoneLabel(name)) }
+	covered := qtype
+	if slices.ContainsFunc(records, func(r dnsRecord) bool { return owned(r) && r.Type == dnsTypeCNAME }) {
+		covered = dnsTypeCNAME
+	}
+
+	var rrset []dnsRecord
+	var sigs []rrsigRecord
+	for _, record := range records {
+		if !owned(record) {
+			continue
+		}
+		switch record.Type {
+		case covered:
+			rrset = append(rrset, record)
+		case dnsTypeRRSIG:
+			if sig, err := parseRRSIG(record.Data); err == nil && sig.TypeCovered == covered {
+				sigs = append(sigs, sig)
+			}
+		}
+	}
+	return rrset, sigs, nil
+}
+
+// zoneKeys fetches the DNSKEY RRset of zone and authenticates it: one of
+// its keys must match a DS of the parent (or an anchor, for the root) and
+// sign the RRset. It returns the zone keys of the RRset.
+func (c *dnssecChecker) zoneKeys(ctx context.Context, zone, parent string, ds []dsRecord, now time.Time) ([]dnskeyRecord, DNSSECLink, error) {
+	rrset, sigs, err := c.fetch(ctx, zone, dnsTypeDNSKEY)
+	if err != nil {
+		return nil, DNSSECLink{}, err
+	}
+	// Las anclas de confianza hacen de DS de la raíz
+	source := fmt.Sprintf(tr("el DS de %s"), dnsZoneLabel(parent))
+	if zone == "" {
+		source = tr("las anclas de confianza de IANA")
+	}
+	if len(rrset) == 0 {
+		return nil, DNSSECLink{}, fmt.Errorf(tr("la zona no publica DNSKEY pese a %s"), source)
+	}
+
+	var keys, trusted []dnskeyRecord
+	var digest string
+	for _, record := range rrset {
+		key, err := parseDNSKEY(record.Data)
+		if err != nil || key.Flags&dnskeyFlagZone == 0 {
+			continue
+		}
+		keys = append(keys, key)
+		for _, d := range ds {
+			if d.KeyTag == key.keyTag() && d.Algorithm == key.Algorithm && key.digest(zone, d.DigestType) == d.Digest {
+				trusted = append(trusted, key)
+				digest = dnssecDigests[d.DigestType]
+				break
+			}
+		}
+	}
+	if len(trusted) == 0 {
+		return nil, DNSSECLink{}, fmt.Errorf(tr("ningún DNSKEY coincide con %s (¿rotación de llaves incompleta?)"), source)
+	}
+	// El RRset de DNSKEY debe estar firmado por una llave autenticada por el DS
+	sig, err := verifyRRset(zone, rrset, sigs, zone, trusted, now)
+	if err != nil {
+		return nil, DNSSECLink{}, fmt.Errorf("DNSKEY: %w", err)
+	}
+	link := DNSSECLink{Zone: dnsZoneLabel(zone), Record: "DNSKEY", KeyTag: sig.KeyTag, Algorithm: dnssecAlgorithmName(sig.Algorithm),
+		Digest: digest, Expires: serialTime(sig.Expiration, now)}
+	return keys, link, nil
+}
+
+// verifyRRset checks that one of sigs is a valid signature of rrset, the
+// records of owner, made by one of keys of zone. It returns that signature.
+func verifyRRset(owner string, rrset []dnsRecord, sigs []rrsigRecord, zone string, keys []dnskeyRecord, now time.Time) (*rrsigRecord, error) {
+	if len(sigs) == 0 {
+		return nil, errors.New(tr("sin firmas (RRSIG)"))
+	}
+	var lastErr error
+	for i := range sigs {
+		sig := &sigs[i]
+		if !strings.EqualFold(dnsZoneLabel(sig.Signer), dnsZoneLabel(zone)) {
+			lastErr = fmt.Errorf(tr("firmado por %s en lugar de %s"), dnsZoneLabel(sig.Signer), dnsZoneLabel(zone))
+			continue
+		}
+		// Aritmética de números de serie (RFC 1982): las fechas dan la vuelta en 2106
+		current := uint32(now.Unix())
+		if int32(current-sig.Inception) < 0 {
+			lastErr = fmt.Errorf(tr("la firma recién es válida desde %s"), formatDate(serialTime(sig.Inception, now)))
+			continue
+		}
+		if int32(sig.Expiration-current) < 0 {
+			lastErr = fmt.Errorf(tr("la firma venció el %s"), formatDate(serialTime(sig.Expiration, now)))
+			continue
+		}
+		for _, key := range keys {
+			if key.Algorithm != sig.Algorithm || key.keyTag() != sig.KeyTag {
+				continue
+			}
+			if err := key.verify(sig.signedData(owner, rrset), sig.Signature, sig.Algorithm); err != nil {
+				lastErr = err
+				continue
+			}
+			return sig, nil
+		}
+		if lastErr == nil {
+			lastErr = fmt.Errorf(tr("ninguna llave de %s con el tag %d"), dnsZoneLabel(zone), sig.KeyTag)
+		}
+	}
+	return nil, lastErr
+}
+
+// signedData returns the data the signature covers (RFC 4034, section
+// 3.1.8.1): the RRSIG fields without the signature followed by the records
+// in canonical form and order
+func (s *rrsigRecord) signedData(owner string, rrset []dnsRecord) []byte {
+	data := binary.BigEndian.AppendUint16(nil, s.TypeCovered)
+	data = append(data, s.Algorithm, s.Labels)
+	data = binary.BigEndian.AppendUint32(data, s.OriginalTTL)
+	data = binary.BigEndian.AppendUint32(data, s.Expiration)
+	data = binary.BigEndian.AppendUint32(data, s.Inception)
+	data = binary.BigEndian.AppendUint16(data, s.KeyTag)
+	signer, _ := dnsWireName(strings.ToLower(s.Signer))
+	data = append(data, signer...)
+
+	// Un registro generado por un comodín se firmó con el nombre *.padre
+	labels := strings.Split(strings.ToLower(strings.TrimSuffix(owner, ".")), ".")
+	if int(s.Labels) < len(labels) {
+		labels = append([]string{"*"}, labels[len(labels)-int(s.Labels):]...)
+	}
+	name, _ := dnsWireName(strings.Join(labels, "."))
+
+	rdatas := make([][]byte, len(rrset))
+	for i, record := range rrset {
+		rdatas[i] = record.Data
+		if record.Type == dnsTypeCNAME {
+			rdatas[i] = asciiLower(record.Data)
+		}
+	}
+	slices.SortFunc(rdatas, func(a, b []byte) int { return bytesCompare(a, b) })
+	rdatas = slices.CompactFunc(rdatas, func(a, b []byte) bool { return bytesCompare(a, b) == 0 })
+	for _, rdata := range rdatas {
+		data = append(data, name...)
+		data = binary.BigEndian.AppendUint16(data, s.TypeCovered)
+		data = binary.BigEndian.AppendUint16(data, dnsClassIN)
+		data = binary.BigEndian.AppendUint32(data, s.OriginalTTL)
+		data = binary.BigEndian.AppendUint16(data, uint16(len(rdata)))
+		data = append(data, rdata...)
+	}
+	return data
+}
+
+// verify checks a signature made with the key
+func (k dnskeyRecord) verify(data, signature []byte, algorithm uint8) error {
+	var hash crypto.Hash
+	switch algorithm {
+	case 5, 7:
+		hash = crypto.SHA1
+	case 8, 13:
+		hash = crypto.SHA256
+	case 14:
+		hash = crypto.SHA384
+	case 10:
+		hash = crypto.SHA512
+	case 15:
+		if len(k.PublicKey) != ed25519.PublicKeySize {
+			return errors.New(tr("llave Ed25519 inválida"))
+		}
+		if !ed25519.Verify(ed25519.PublicKey(k.PublicKey), data, signature) {
+			return errors.New(tr("firma inválida"))
+		}
+		return nil
+	default:
+		return fmt.Errorf(tr("algoritmo %s no soportado"), dnssecAlgorithmName(algorithm))
+	}
+	h := hash.New()
+	h.Write(data)
+	digest := h.Sum(nil)
+
+	switch algorithm {
+	case 13, 14:
+		curve, size := elliptic.P256(), 32
+		if algorithm == 14 {
+			curve, size = elliptic.P384(), 48
+		}
+		// La llave y la firma son los enteros concatenados, sin el prefijo 0x04 ni ASN.1
+		pub, err := ecdsa.ParseUncompressedPublicKey(curve, append([]byte{4}, k.PublicKey...))
+		if err != nil || len(signature) != 2*size {
+			return errors.New(tr("llave o firma ECDSA inválida"))
+		}
+		r, s := new(big.Int).SetBytes(signature[:size]), new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return errors.New(tr("firma inválida"))
+		}
+	default:
+		pub, err := parseDNSKEYRSA(k.PublicKey)
+		if err != nil {
+			return err
+		}
+		if err := rsa.VerifyPKCS1v15(pub, hash, digest, signature); err != nil {
+			return errors.New(tr("firma inválida"))
+		}
+	}
+	return nil
+}
+
+// parseDNSKEYRSA decodes an RSA public key of a DNSKEY (RFC 3110): the
+// length of the exponent in one byte, or in two after a zero, the
+// exponent and the modulus
+func parseDNSKEYRSA(key []byte) (*rsa.PublicKey, error) {
+	if len(key) < 3 {
+		return nil, errors.New(tr("llave RSA inválida"))
+	}
+	length, offset := int(key[0]), 1
+	if length == 0 {
+		length, offset = int(binary.BigEndian.Uint16(key[1:])), 3
+	}
+	if length == 0 || length > 4 || offset+length >= len(key) {
+		return nil, errors.New(tr("llave RSA inválida"))
+	}
+	exponent := new(big.Int).SetBytes(key[offset : offset+length])
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(key[offset+length:]), E: int(exponent.Int64())}, nil
+}
+
+// keyTag computes the tag that DS and RRSIG records use to refer to the
+// key (RFC 4034, appendix B)
+func (k dnskeyRecord) keyTag() uint16 {
+	var sum uint32
+	for i, b := range k.rdata {
+		if i%2 == 0 {
+			sum += uint32(b) << 8
+		} else {
+			sum += uint32(b)
+		}
+	}
+	sum += sum >> 16
+	return uint16(sum)
+}
+
+// digest returns the DS digest of the key of zone in hexadecimal, or ""
+// for an unknown digest type
+func (k dnskeyRecord) digest(zone string, digestType uint8) string {
+	name, err := dnsWireName(strings.ToLower(zone))
+	if err != nil {
+		return ""
+	}
+	data := append(name, k.rdata...)
+	switch digestType {
+	case 1:
+		sum := sha1.Sum(data)
+		return hex.EncodeToString(sum[:])
+	case 2:
+		sum := sha256.Sum256(data)
+		return hex.EncodeToString(sum[:])
+	case 4:
+		sum := sha512.Sum384(data)
+		return hex.EncodeToString(sum[:])
+	}
+	return ""
+}
+
+// parseDS decodes the RDATA of a DS record
+func parseDS(data []byte) (dsRecord, error) {
+	if len(data) < 5 {
+		return dsRecord{}, errDNSShort
+	}
+	return dsRecord{
+		KeyTag:     binary.BigEndian.Uint16(data),
+		Algorithm:  data[2],
+		DigestType: data[3],
+		Digest:     hex.EncodeToString(data[4:]),
+	}, nil
+}
+
+// parseDNSKEY decodes the RDATA of a DNSKEY record
+func parseDNSKEY(data []byte) (dnskeyRecord, error) {
+	if len(data) < 5 {
+		return dnskeyRecord{}, errDNSShort
+	}
+	return dnskeyRecord{
+		Flags:     binary.BigEndian.Uint16(data),
+		Algorithm: data[3],
+		PublicKey: data[4:],
+		rdata:     data,
+	}, nil
+}
+
+// parseRRSIG decodes the RDATA of an RRSIG record. The signer name is
+// never compressed (RFC 4034, section 3.1.7).
+func parseRRSIG(data []byte) (rrsigRecord, error) {
+	if len(data) < 19 {
+		return rrsigRecord{}, errDNSShort
+	}
+	signer, next, err := readDNSName(data, 18)
+	if err != nil {
+		return rrsigRecord{}, err
+	}
+	return rrsigRecord{
+		TypeCovered: binary.BigEndian.Uint16(data),
+		Algorithm:   data[2],
+		Labels:      data[3],
+		OriginalTTL: binary.BigEndian.Uint32(data[4:]),
+		Expiration:  binary.BigEndian.Uint32(data[8:]),
+		Inception:   binary.BigEndian.Uint32(data[12:]),
+		KeyTag:      binary.BigEndian.Uint16(data[16:]),
+		Signer:      signer,
+		Signature:   data[next:],
+	}, nil
+}
+
+// serialTime converts a timestamp of an RRSIG to the time closest to now,
+// since they wrap around every 136 years
+func serialTime(serial uint32, now time.Time) time.Time {
+	return now.Add(time.Duration(int32(serial-uint32(now.Unix()))) * time.Second)
+}
+
+// asciiLower lowercases the ASCII letters of a name in wire format
+func asciiLower(data []byte) []byte {
+	lower := make([]byte, len(data))
+	for i, b := range data {
+		if b >= 'A' && b <= 'Z' {
+			b += 'a' - 'A'
+		}
+		lower[i] = b
+	}
+	return lower
+}
+
+// bytesCompare orders two RDATAs as the canonical order of RFC 4034
+// (section 6.3): as unsigned bytes, the shorter first on a tie
+func bytesCompare(a, b []byte) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return int(a[i]) - int(b[i])
+		}
+	}
+	return len(a) - len(b)
+}
+
+// dnsZoneLabel renders a zone for the report: "." for the root
+func dnsZoneLabel(zone string) string {
+	if zone = strings.TrimSuffix(zone, "."); zone == "" {
+		return "."
+	}
+	return zone
+}
+
+// dnsTypeName returns the mnemonic of the record types of the chain
+func dnsTypeName(qtype uint16) string {
+	switch qtype {
+	case dnsTypeA:
+		return "A"
+	case dnsTypeAAAA:
+		return "AAAA"
+	case dnsTypeCNAME:
+		return "CNAME"
+	case dnsTypeDS:
+		return "DS"
+	case dnsTypeDNSKEY:
+		return "DNSKEY"
+	case dnsTypeTLSA:
+		return "TLSA"
+	}
+	return fmt.Sprintf("TYPE%d", qtype)
+}
+
+// dnssecAlgorithmName returns the mnemonic of a signing algorithm
+func dnssecAlgorithmName(algorithm uint8) string {
+	if name, ok := dnssecAlgorithms[algorithm]; ok {
+		return name
+	}
+	return fmt.Sprintf(tr("algoritmo %d"), algorithm)
+}
+
+// displayDNSSEC prints the chain of trust of the domain
+func displayDNSSEC(check *DNSSECCheck) {
+	fmt.Printf("=== DNSSEC ===\n")
+	if check.Error != "" {
+		fmt.Printf("%s\n\n", paint(colorYellow, tr("⚠️  No se pudo verificar: ")+check.Error))
+		return
+	}
+	switch check.Status {
+	case dnssecSecure:
+		fmt.Printf("%s\n", paint(colorGreen, tr("✅ Cadena de confianza válida desde la raíz")))
+	case dnssecInsecure:
+		fmt.Printf("%s\n", paint(colorYellow, tr("⚠️  Sin DNSSEC: ")+check.Problem))
+	default:
+		fmt.Printf("%s\n", paint(colorRed, tr("❌ Cadena de confianza inválida (los resolvers que validan no resuelven el dominio): ")+check.Problem))
+	}
+
+	width := 0
+	for _, link := range check.Chain {
+		width = max(width, len(link.Zone))
+	}
+	for _, link := range check.Chain {
+		origin := tr("llave de la zona")
+		switch {
+		case link.Zone == "." && link.Record == "DNSKEY":
+			origin = tr("ancla de confianza de IANA")
+		case link.Record == "DNSKEY":
+			origin = fmt.Sprintf(tr("DS %s del padre"), link.Digest)
+		}
+		fmt.Printf(tr("  %-*s  %-6s llave %d %s (%s), firma hasta %s\n"), width, link.Zone, link.Record, link.KeyTag, link.Algorithm, origin, formatDate(link.Expires))
+	}
+	for _, warning := range check.Warnings {
+		fmt.Printf("%s\n", paint(colorYellow, "⚠️  "+warning))
+	}
+	fmt.Println()
+}
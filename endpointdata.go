@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// EndpointData fetches the full result of a single endpoint with
+// /getEndpointData, without downloading the rest of the host
+func (c *HTTPClient) EndpointData(ctx context.Context, host, ip string) (*Endpoint, error) {
+	u, err := url.Parse(c.baseURL + endpointDataEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("URL base inválida %q: %w", c.baseURL, err)
+	}
+	u.RawQuery = url.Values{"host": {host}, "s": {ip}}.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf(tr("error creando petición: %w"), err)
+	}
+
+	body, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var endpoint Endpoint
+	if err := json.Unmarshal(body, &endpoint); err != nil {
+		return nil, fmt.Errorf(tr("error parseando respuesta JSON: %w"), err)
+	}
+	return &endpoint, nil
+}
+
+// missingDetails returns the indexes of the Ready endpoints of host that
+// came without details
+func missingDetails(host *Host) []int {
+	var missing []int
+	for i, endpoint := range host.Endpoints {
+		if endpoint.StatusMessage == endpointStatusReady && endpoint.Details == nil {
+			missing = append(missing, i)
+		}
+	}
+	return missing
+}
+
+// completeDetails re-polls, one by one, the Ready endpoints of host that
+// have no details yet and merges each answer into host, keeping the
+// endpoints that were already complete. An endpoint that still has no
+// details after the details timeout is left as is, so ProcessResults
+// reports it as missing data instead of holding the whole assessment.
+func completeDetails(ctx context.Context, client *HTTPClient, domain string, host *Host, opts PollOptions) (*Host, error) {
+	missing := missingDetails(host)
+	if len(missing) == 0 {
+		return host, nil
+	}
+
+	deadline := time.Now().Add(durationOr(opts.DetailsTimeout, defaultDetailsTimeout))
+	interval := durationOr(opts.InProgressInterval, defaultInProgressInterval)
+
+	for {
+		opts.Reporter.Progress(domain, fmt.Sprintf(tr("Esperando detalles de seguridad TLS... (%d de %d endpoints sin detalles)"),
+			len(missing), len(host.Endpoints)))
+
+		if err := sleepContext(ctx, interval); err != nil {
+			return nil, err
+		}
+
+		var pending []int
+		for _, i := range missing {
+			endpoint, err := client.EndpointData(ctx, domain, host.Endpoints[i].IPAddress)
+			if err != nil {
+				return nil, err
+			}
+			if endpoint.Details != nil {
+				host.Endpoints[i] = *endpoint
+			} else {
+				pending = append(pending, i)
+			}
+		}
+
+		// Las APIs v3/v4 referencian los certificados del host por ID
+		if client.apiVersion >= 3 {
+			normalizeCerts(host)
+		}
+
+		missing = pending
+		if len(missing) == 0 {
+			return host, nil
+		}
+		if time.Now().After(deadline) {
+			for _, i := range missing {
+				client.logger.Warn("endpoint sin detalles tras el timeout", "domain", domain, "endpoint", host.Endpoints[i].IPAddress)
+			}
+			return host, nil
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Mensajes de estado de un endpoint que no indican un error
+const (
+	endpointStatusReady      = "Ready"
+	endpointStatusInProgress = "In progress"
+	endpointStatusPending    = "Pending"
+)
+
+// EndpointError is an endpoint that SSL Labs could not assess, such as
+// "Unable to connect to the server"
+type EndpointError struct {
+	IPAddress string
+	Message   string // statusMessage del endpoint
+}
+
+// endpointFailure returns the error of an endpoint that finished without
+// being assessed, or nil if it is ready or still in progress. Once the
+// host is READY, an endpoint that is not Ready has failed even if its
+// message looks like progress.
+func endpointFailure(endpoint Endpoint, hostStatus string) *EndpointError {
+	switch endpoint.StatusMessage {
+	case endpointStatusReady:
+		return nil
+	case "", endpointStatusInProgress, endpointStatusPending:
+		if hostStatus != statusReady {
+			return nil
+		}
+	}
+
+	message := endpoint.StatusMessage
+	if message == "" {
+		message = tr("sin resultado")
+	}
+	return &EndpointError{IPAddress: endpoint.IPAddress, Message: message}
+}
+
+// HasEndpointErrors reports whether any endpoint of the host could not be assessed
+func (r *AssessmentResult) HasEndpointErrors() bool {
+	return len(r.EndpointErrors) > 0
+}
+
+// describeEndpointErrors joins the endpoint errors in a single line
+func describeEndpointErrors(errs []EndpointError) string {
+	parts := make([]string, 0, len(errs))
+	for _, e := range errs {
+		parts = append(parts, fmt.Sprintf("%s: %s", e.IPAddress, e.Message))
+	}
+	return strings.Join(parts, "; ")
+}
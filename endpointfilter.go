@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// Familias de direcciones de los endpoints
+const (
+	familyIPv4 = "IPv4"
+	familyIPv6 = "IPv6"
+)
+
+// addressFamily returns the family of an endpoint address, or "" when it
+// doesn't parse
+func addressFamily(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	switch {
+	case err != nil:
+		return ""
+	case addr.Unmap().Is4():
+		return familyIPv4
+	}
+	return familyIPv6
+}
+
+// endpointFilter selects the endpoints of a host by address family
+// (--only-ipv4, --only-ipv6) or address (--endpoint)
+type endpointFilter struct {
+	family string     // familyIPv4, familyIPv6 o "" para ambas
+	addr   netip.Addr // Inválida = cualquier dirección
+}
+
+// newEndpointFilter validates the filter flags. It returns nil when no
+// filter is given.
+func newEndpointFilter(onlyIPv4, onlyIPv6 bool, endpoint string) (*endpointFilter, error) {
+	if onlyIPv4 && onlyIPv6 {
+		return nil, fmt.Errorf("--only-ipv4 y --only-ipv6 no se pueden usar juntos")
+	}
+	f := &endpointFilter{}
+	switch {
+	case onlyIPv4:
+		f.family = familyIPv4
+	case onlyIPv6:
+		f.family = familyIPv6
+	}
+	if endpoint != "" {
+		addr, err := netip.ParseAddr(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("--endpoint inválido %q: se espera una dirección IPv4 o IPv6", endpoint)
+		}
+		f.addr = addr.Unmap()
+		if family := addressFamily(endpoint); f.family != "" && family != f.family {
+			return nil, fmt.Errorf("--endpoint %s es %s: no coincide con --only-%s", endpoint, family, strings.ToLower(f.family))
+		}
+	}
+	if f.family == "" && !f.addr.IsValid() {
+		return nil, nil
+	}
+	return f, nil
+}
+
+// String describes the filter for error messages
+func (f *endpointFilter) String() string {
+	if f.addr.IsValid() {
+		return "--endpoint " + f.addr.String()
+	}
+	return "--only-" + strings.ToLower(f.family)
+}
+
+// matches reports whether an endpoint address passes the filter
+func (f *endpointFilter) matches(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	if f.addr.IsValid() {
+		return addr.Unmap() == f.addr
+	}
+	return addressFamily(ip) == f.family
+}
+
+// filterLookup wraps the resolver of LocalScanner so only the matching
+// addresses are probed
+func (f *endpointFilter) filterLookup(lookup func(ctx context.Context, host string) ([]netip.Addr, error)) func(ctx context.Context, host string) ([]netip.Addr, error) {
+	return func(ctx context.Context, host string) ([]netip.Addr, error) {
+		addrs, err := lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		all := len(addrs)
+		addrs = slices.DeleteFunc(addrs, func(addr netip.Addr) bool { return !f.matches(addr.String()) })
+		if len(addrs) == 0 {
+			return nil, fmt.Errorf(tr("ninguna de las %d direcciones de %s coincide con %s"), all, host, f)
+		}
+		return addrs, nil
+	}
+}
+
+// endpointFilterer is an Assessor that keeps only the endpoints that pass
+// a filter and grades the host by them
+type endpointFilterer struct {
+	Assessor
+	filter *endpointFilter
+}
+
+// withEndpointFilter filters the endpoints of every assessment of scanner
+func withEndpointFilter(scanner Assessor, filter *endpointFilter) Assessor {
+	return &endpointFilterer{Assessor: scanner, filter: filter}
+}
+
+// AssessContext runs the assessment and drops the endpoints that don't
+// pass the filter. SSL Labs assesses every endpoint anyway: the filter
+// only changes what is displayed, graded, recorded and notified.
+func (f *endpointFilterer) AssessContext(ctx context.Context, domain string) (*AssessmentResult, error) {
+	result, err := f.Assessor.AssessContext(ctx, domain)
+	if result == nil {
+		return result, err
+	}
+	all := len(result.Endpoints) + len(result.EndpointErrors)
+	result.Endpoints = slices.DeleteFunc(result.Endpoints, func(e EndpointResult) bool { return !f.filter.matches(e.IPAddress) })
+	result.EndpointErrors = slices.DeleteFunc(result.EndpointErrors, func(e EndpointError) bool { return !f.filter.matches(e.IPAddress) })
+	if err == nil && len(result.Endpoints) == 0 {
+		if len(result.EndpointErrors) > 0 {
+			return nil, fmt.Errorf(tr("%s: %w: ningún endpoint de %s pudo evaluarse: %s"), domain, ErrAssessmentFailed, f.filter, describeEndpointErrors(result.EndpointErrors))
+		}
+		return nil, fmt.Errorf(tr("%s: ninguno de los %d endpoints coincide con %s"), domain, all, f.filter)
+	}
+
+	var grades []string
+	for _, endpoint := range result.Endpoints {
+		grades = append(grades, endpoint.Grade)
+	}
+	result.OverallGrade = findWorstGrade(grades)
+	return result, err
+}
+
+// familyGrades returns the worst grade and endpoint count of each address
+// family of result, in the order of the endpoints (IPv4 first). It returns
+// nil when all endpoints belong to the same family.
+func familyGrades(result *AssessmentResult) []familyGrade {
+	var families []familyGrade
+	for _, endpoint := range result.Endpoints {
+		family := addressFamily(endpoint.IPAddress)
+		i := slices.IndexFunc(families, func(g familyGrade) bool { return g.Family == family })
+		if i < 0 {
+			families = append(families, familyGrade{Family: family})
+			i = len(families) - 1
+		}
+		families[i].Endpoints++
+		families[i].grades = append(families[i].grades, endpoint.Grade)
+	}
+	if len(families) < 2 {
+		return nil
+	}
+	for i := range families {
+		families[i].Grade = findWorstGrade(families[i].grades)
+	}
+	return families
+}
+
+// familyGrade is the worst grade of the endpoints of one address family
+type familyGrade struct {
+	Family    string
+	Grade     string
+	Endpoints int
+	grades    []string
+}
+
+// displayFamilyHeader prints the address family of the endpoint at index i
+// when it starts a new group (only for hosts with both families)
+func displayFamilyHeader(endpoints []EndpointResult, i int, grouped bool) {
+	family := addressFamily(endpoints[i].IPAddress)
+	if !grouped || (i > 0 && addressFamily(endpoints[i-1].IPAddress) == family) {
+		return
+	}
+	count := 0
+	for _, endpoint := range endpoints {
+		if addressFamily(endpoint.IPAddress) == family {
+			count++
+		}
+	}
+	if family == "" {
+		family = tr("Otras direcciones")
+	}
+	fmt.Printf("##### %s (%s) #####\n\n", family, countEndpoints(count))
+}
+
+// countEndpoints renders a number of endpoints
+func countEndpoints(n int) string {
+	if n == 1 {
+		return tr("1 endpoint")
+	}
+	return fmt.Sprintf(tr("%d endpoints"), n)
+}
@@ -0,0 +1,61 @@
+package main
+
+import "errors"
+
+// Errores que devuelven el cliente y el scanner. Se comparan con errors.Is;
+// los errores HTTP de la API son *APIError (ver errors.As).
+var (
+	// ErrRateLimited: la API respondió 429 y se agotaron los reintentos
+	ErrRateLimited = localizedError("rate limit excedido")
+	// ErrServiceUnavailable: la API respondió 503 o 529 y se agotaron los reintentos
+	ErrServiceUnavailable = localizedError("servicio no disponible")
+	// ErrAssessmentFailed: SSL Labs terminó la evaluación con estado ERROR
+	// o ningún endpoint pudo evaluarse
+	ErrAssessmentFailed = localizedError("error en la evaluación")
+	// ErrTimeout: la evaluación no terminó dentro del timeout configurado
+	ErrTimeout = localizedError("timeout")
+	// ErrAtCapacity: /info indica que no se pueden iniciar evaluaciones nuevas
+	ErrAtCapacity = localizedError("SSL Labs no tiene capacidad para evaluaciones nuevas")
+)
+
+// errorCode returns a stable identifier of the kind of err for machine
+// formats, where the message (in the output language) isn't meant to be
+// parsed
+func errorCode(err error) string {
+	var apiErr *APIError
+	switch {
+	case errors.Is(err, ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, ErrServiceUnavailable):
+		return "service_unavailable"
+	case errors.Is(err, ErrAssessmentFailed):
+		return "assessment_failed"
+	case errors.Is(err, ErrTimeout):
+		return "timeout"
+	case errors.Is(err, ErrAtCapacity):
+		return "at_capacity"
+	case errors.Is(err, ErrInterrupted):
+		return "interrupted"
+	case errors.As(err, &apiErr):
+		return "api_error"
+	case isTransientError(err):
+		return "network"
+	}
+	return "error"
+}
+
+// errorHint suggests what to do about a failed assessment, or returns an
+// empty string when there is nothing useful to add
+func errorHint(err error) string {
+	switch {
+	case errors.Is(err, ErrRateLimited):
+		return tr("la API sigue limitando las peticiones: reintenta más tarde o aumenta --max-retries")
+	case errors.Is(err, ErrServiceUnavailable):
+		return tr("SSL Labs no está disponible en este momento: reintenta más tarde")
+	case errors.Is(err, ErrTimeout):
+		return tr("la evaluación puede tardar más en hosts con muchos endpoints: aumenta --timeout")
+	case isTransientError(err):
+		return tr("la conexión con la API falló en todos los reintentos: revisa la red o aumenta --retries")
+	}
+	return ""
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// expiryStatus classifies the days remaining on a certificate
+type expiryStatus int
+
+const (
+	expiryOK expiryStatus = iota
+	expiryWarning
+	expiryCritical
+)
+
+// ExpiryThresholds holds the --warn-expiry-days / --crit-expiry-days limits.
+// A zero threshold is disabled; expired certificates are always critical.
+type ExpiryThresholds struct {
+	WarnDays int
+	CritDays int
+}
+
+// Status returns the expiry status for the given days remaining
+func (t ExpiryThresholds) Status(days int) expiryStatus {
+	switch {
+	case days < 0:
+		return expiryCritical
+	case t.CritDays > 0 && days <= t.CritDays:
+		return expiryCritical
+	case t.WarnDays > 0 && days <= t.WarnDays:
+		return expiryWarning
+	default:
+		return expiryOK
+	}
+}
+
+// Validate checks that the thresholds are consistent
+func (t ExpiryThresholds) Validate() error {
+	if t.WarnDays < 0 || t.CritDays < 0 {
+		return fmt.Errorf("los umbrales de expiración no pueden ser negativos")
+	}
+	if t.WarnDays > 0 && t.CritDays > t.WarnDays {
+		return fmt.Errorf("--crit-expiry-days (%d) no puede ser mayor que --warn-expiry-days (%d)", t.CritDays, t.WarnDays)
+	}
+	return nil
+}
+
+// daysUntil returns the whole days from now until the timestamp (in
+// milliseconds, as returned by the API); negative when already past
+func daysUntil(timestampMs int64, now time.Time) int {
+	remaining := time.UnixMilli(timestampMs).Sub(now)
+	days := int(remaining / (24 * time.Hour))
+	if remaining < 0 && remaining%(24*time.Hour) != 0 {
+		days--
+	}
+	return days
+}
+
+// WorstExpiryStatus returns the most severe expiry status of the
+// certificates of all endpoints
+func (r *AssessmentResult) WorstExpiryStatus(t ExpiryThresholds) expiryStatus {
+	worst := expiryOK
+	for _, endpoint := range r.Endpoints {
+		if endpoint.CertValidTo <= 0 {
+			continue
+		}
+		if status := t.Status(endpoint.CertDaysRemaining); status > worst {
+			worst = status
+		}
+		for _, cert := range endpoint.OtherCerts {
+			if status := t.Status(cert.DaysRemaining); cert.ValidTo > 0 && status > worst {
+				worst = status
+			}
+		}
+	}
+	return worst
+}
+
+// describeExpiry renders the days remaining on a certificate
+func describeExpiry(days int, t ExpiryThresholds) string {
+	var text string
+	switch {
+	case days == -1:
+		text = tr("Expirado hace menos de un día")
+	case days < 0:
+		// daysUntil redondea hacia abajo: -2 significa que expiró hace entre 1 y 2 días
+		text = fmt.Sprintf(tr("Expirado hace %d días"), -days-1)
+	case days == 1:
+		text = tr("1 día")
+	default:
+		text = fmt.Sprintf(tr("%d días"), days)
+	}
+
+	switch t.Status(days) {
+	case expiryCritical:
+		return text + tr(" ❌ (crítico)")
+	case expiryWarning:
+		return text + tr(" ⚠️  (advertencia)")
+	default:
+		return text
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// apiUnavailable reports whether err means that SSL Labs can't be used
+// right now, as opposed to a failure of the assessment itself: rate
+// limits, lack of capacity or an API that can't be reached
+func apiUnavailable(err error) bool {
+	var apiErr *APIError
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	switch {
+	case errors.Is(err, ErrRateLimited), errors.Is(err, ErrServiceUnavailable), errors.Is(err, ErrAtCapacity):
+		return true
+	case errors.As(err, &apiErr):
+		return apiErr.StatusCode >= 500
+	case errors.As(err, &dnsErr), errors.As(err, &opErr):
+		// Sin red ni siquiera se resuelve el host de la API
+		return true
+	case errors.Is(err, syscall.ENETUNREACH), errors.Is(err, syscall.EHOSTUNREACH):
+		return true
+	}
+	return isTransientError(err)
+}
+
+// localFallback is an Assessor that assesses the domain locally, with a
+// direct TLS handshake, when SSL Labs is unavailable (--local). Once the
+// API failed, the rest of the run is assessed locally too, so each domain
+// doesn't wait for the retries again.
+type localFallback struct {
+	Assessor
+	local *LocalScanner
+
+	mu          sync.Mutex
+	unavailable error // Motivo por el que ya no se usa la API, nil mientras se usa
+}
+
+// withLocalFallback wraps scanner so that it falls back to local on API
+// failures
+func withLocalFallback(scanner Assessor, local *LocalScanner) *localFallback {
+	return &localFallback{Assessor: scanner, local: local}
+}
+
+// disable stops using the API for the rest of the run because of err
+func (f *localFallback) disable(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.unavailable == nil {
+		f.unavailable = err
+		fmt.Fprintf(os.Stderr, "%s\n", paint(colorYellow, fmt.Sprintf(tr("⚠️  SSL Labs no está disponible (%s): se evalúa localmente con --local"), err)))
+	}
+}
+
+// AssessContext assesses domain with the API, or locally when the API is
+// unavailable. The local result records why in its metadata.
+func (f *localFallback) AssessContext(ctx context.Context, domain string) (*AssessmentResult, error) {
+	f.mu.Lock()
+	unavailable := f.unavailable
+	f.mu.Unlock()
+	if unavailable == nil {
+		result, err := f.Assessor.AssessContext(ctx, domain)
+		if err == nil || !apiUnavailable(err) || errors.Is(err, ErrInterrupted) {
+			return result, err
+		}
+		f.disable(err)
+		unavailable = err
+	}
+
+	result, err := f.local.AssessContext(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+	result.Metadata.Fallback = errorCode(unavailable)
+	return result, nil
+}
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"ssllabs-scanner/store"
+)
+
+// localFindingRules are the rules a local assessment checks. It doesn't
+// test vulnerabilities, HSTS, SSL, DH or renegotiation, so it neither
+// confirms nor resolves the findings of those rules.
+var localFindingRules = map[string]bool{
+	"hostname_mismatch":  true,
+	"untrusted":          true,
+	"expired":            true,
+	"weak_signature":     true,
+	"no_tls12":           true,
+	"old_protocols":      true,
+	"weak_key":           true,
+	"rc4":                true,
+	"weak_ciphers":       true,
+	"no_forward_secrecy": true,
+	"no_aead":            true,
+}
+
+// judgedEndpoints returns what result detected on each endpoint, for the
+// store to open, keep or resolve the findings of its domain. Only the
+// endpoints with a grade and details are judged, and a local assessment
+// only on the rules it checks.
+func judgedEndpoints(result *AssessmentResult) []store.JudgedEndpoint {
+	var judged []store.JudgedEndpoint
+	for i := range result.Endpoints {
+		endpoint := &result.Endpoints[i]
+		if _, ok := gradeOrder[endpoint.Grade]; !ok || endpoint.Details == nil {
+			continue
+		}
+		j := store.JudgedEndpoint{IPAddress: endpoint.IPAddress}
+		if endpoint.Details.Local {
+			j.Rules = slices.Sorted(maps.Keys(localFindingRules))
+		}
+		for _, reason := range explainGrade(endpoint) {
+			// No son condiciones del servidor
+			if reason.ID == "unknown" || reason.ID == "hsts_unknown" {
+				continue
+			}
+			j.Detected = append(j.Detected, store.Detection{Rule: reason.ID, Detail: reason.Reason})
+		}
+		judged = append(judged, j)
+	}
+	return judged
+}
+
+// reopenedFindings returns the findings of a domain that the assessment
+// made at scannedAt detected again after they had been resolved
+func reopenedFindings(findings []store.Finding, scannedAt time.Time) []store.Finding {
+	var reopened []store.Finding
+	for _, f := range findings {
+		if f.State == store.FindingOpen && f.UpdatedAt.Equal(scannedAt) && !f.FirstSeen.Equal(scannedAt) {
+			reopened = append(reopened, f)
+		}
+	}
+	return reopened
+}
+
+// acknowledgedVulnerabilities returns, by endpoint, the names of the
+// vulnerabilities whose findings are acknowledged
+func acknowledgedVulnerabilities(findings []store.Finding) map[string][]string {
+	names := make(map[string]string)
+	for _, check := range vulnerabilityChecks(&EndpointDetails{}) {
+		names["vuln_"+check.ID] = check.Name
+	}
+	acknowledged := make(map[string][]string)
+	for _, f := range findings {
+		if name, ok := names[f.Rule]; ok && f.State == store.FindingAcknowledged {
+			acknowledged[f.IPAddress] = append(acknowledged[f.IPAddress], name)
+		}
+	}
+	return acknowledged
+}
+
+// runFindings implements the "findings" subcommand: "findings list" shows
+// the tracked findings, "findings show" the state changes of one,
+// "findings ack|resolve|reopen" change the state of some with a comment
+// and "findings assign" sets their owner. The changes take IDs or, for
+// many findings at once, the filters of list, which "findings export"
+// also takes to write the findings as CSV or JSON.
+func runFindings(args []string) error {
+	fs := flag.NewFlagSet("findings", flag.ExitOnError)
+	path := fs.String("history-db", defaultHistoryPath(), historyDBUsage)
+	configPath := fs.String("config", "", "archivo de configuración con las etiquetas de --tag (por defecto ~/.config/nebula/config.yaml si existe)")
+	state := fs.String("state", "", "solo los hallazgos en este estado: open, acknowledged o resolved")
+	domain := fs.String("domain", "", "solo los hallazgos de este dominio")
+	tag := fs.String("tag", "", "solo los hallazgos de los dominios de esta etiqueta (tags del archivo de configuración)")
+	rule := fs.String("rule", "", "solo los hallazgos de esta regla (ej: old_protocols)")
+	severity := fs.String("severity", "", "solo los hallazgos de esta severidad: critical, high, medium o low")
+	owner := fs.String("owner", "", "con list y los cambios en bloque, solo los hallazgos de este responsable; con assign, el responsable a asignar (vacío lo quita)")
+	jsonOutput := fs.Bool("json", false, "con list, imprimir los hallazgos en JSON")
+	format := fs.String("format", "csv", "con export, formato: csv o json")
+	output := fs.String("output", "", "con export, archivo a escribir (por defecto la salida estándar)")
+	comment := fs.String("comment", "", "con ack, resolve o reopen, motivo del cambio de estado")
+	dryRun := fs.Bool("dry-run", false, "con los cambios en bloque, mostrar los hallazgos que cambiarían sin cambiarlos")
+	tz := addTimezoneFlag(fs)
+	color := addColorFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s findings list [--state estado] [--domain dominio] [--tag etiqueta] [--rule regla] [--severity severidad] [--owner responsable] [--json]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s findings export [--state open] [--format csv|json] [--output archivo] [filtros]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s findings show <id>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s findings ack|resolve|reopen [--comment texto] <id>... | <filtros> [--dry-run]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s findings assign --owner responsable <id>... | <filtros> [--dry-run]\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if len(args) == 0 {
+		fs.Usage()
+		return fmt.Errorf("se requiere una acción: list, export, show, ack, resolve, reopen o assign")
+	}
+	action := args[0]
+	fs.Parse(args[1:])
+
+	targets := map[string]string{"ack": store.FindingAcknowledged, "resolve": store.FindingResolved, "reopen": store.FindingOpen, "assign": ""}
+	query := action == "list" || action == "export"
+	if _, ok := targets[action]; !ok && !query && action != "show" {
+		fs.Usage()
+		return fmt.Errorf("acción desconocida %q: se espera list, export, show, ack, resolve, reopen o assign", action)
+	}
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if action == "assign" && !set["owner"] {
+		return fmt.Errorf("assign requiere --owner")
+	}
+	if action == "export" && *format != "csv" && *format != "json" {
+		return fmt.Errorf("formato de exportación inválido %q: se espera csv o json", *format)
+	}
+	// export es para importar en otra herramienta lo que hay que resolver
+	if action == "export" && !set["state"] {
+		*state = store.FindingOpen
+	}
+
+	filter := store.FindingFilter{Domain: strings.TrimSpace(*domain), State: *state, Rule: *rule, Severity: *severity}
+	if action != "assign" {
+		filter.Owner = *owner
+	}
+	if filter.State != "" {
+		if err := store.ValidateFindingState(filter.State); err != nil {
+			return err
+		}
+	}
+	if filter.Severity != "" && !slices.Contains(store.FindingSeverities, filter.Severity) {
+		return fmt.Errorf("severidad inválida %q: se espera %s", filter.Severity, strings.Join(store.FindingSeverities, ", "))
+	}
+	if *tag != "" {
+		config, err := loadDefaultConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		if filter.Domains, err = config.tagDomains(*tag); err != nil {
+			return err
+		}
+	}
+	filtered := filter.Domain != "" || filter.Domains != nil || filter.State != "" || filter.Rule != "" ||
+		filter.Severity != "" || filter.Owner != ""
+
+	var ids []int64
+	for _, arg := range fs.Args() {
+		id, err := strconv.ParseInt(strings.TrimPrefix(arg, "#"), 10, 64)
+		if err != nil || id <= 0 {
+			return fmt.Errorf("ID de hallazgo inválido %q", arg)
+		}
+		ids = append(ids, id)
+	}
+	switch {
+	case query && len(ids) > 0:
+		fs.Usage()
+		return fmt.Errorf("%s no recibe IDs: se filtra con --state, --domain, --tag, --rule, --severity y --owner", action)
+	case action == "show" && len(ids) != 1:
+		fs.Usage()
+		return fmt.Errorf("se requiere el ID del hallazgo")
+	case !query && action != "show" && len(ids) > 0 && filtered:
+		return fmt.Errorf("los cambios reciben IDs o filtros, no ambos")
+	case !query && action != "show" && len(ids) == 0 && !filtered:
+		// Sin filtros cambiaría todos los hallazgos, casi seguro por error
+		fs.Usage()
+		return fmt.Errorf("se requieren los IDs de los hallazgos o al menos un filtro")
+	}
+
+	if err := setOutputTimezone(*tz); err != nil {
+		return err
+	}
+	if err := setOutputColor(*color); err != nil {
+		return err
+	}
+	history, err := store.Open(*path)
+	if err != nil {
+		return err
+	}
+	defer history.Close()
+
+	switch action {
+	case "list":
+		findings, err := history.Findings(filter)
+		if err != nil {
+			return err
+		}
+		if *jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(newAPIFindings(findings))
+		}
+		displayFindings(findings)
+		return nil
+	case "export":
+		findings, err := history.Findings(filter)
+		if err != nil {
+			return err
+		}
+		return exportFindings(findings, *format, *output)
+	case "show":
+		return showFinding(history, ids[0])
+	}
+
+	if len(ids) == 0 {
+		selected, err := selectFindings(history, filter, targets[action])
+		if err != nil {
+			return err
+		}
+		if *dryRun {
+			displayFindings(selected)
+			return nil
+		}
+		for _, f := range selected {
+			ids = append(ids, f.ID)
+		}
+		if len(ids) == 0 {
+			fmt.Println("Ningún hallazgo coincide con los filtros")
+			return nil
+		}
+	}
+
+	if action == "assign" {
+		if err := history.AssignFindings(ids, *owner); err != nil {
+			return err
+		}
+		if *owner == "" {
+			fmt.Printf("Hallazgos sin responsable: %d\n", len(ids))
+		} else {
+			fmt.Printf("Hallazgos asignados a %s: %d\n", *owner, len(ids))
+		}
+		return nil
+	}
+	if err := history.SetFindingState(ids, targets[action], *comment); err != nil {
+		return err
+	}
+	fmt.Printf("Hallazgos en %s: %d\n", targets[action], len(ids))
+	return nil
+}
+
+// selectFindings returns the findings that a change in bulk to target (a
+// state, or "" for an assignment) applies to. Unless filter has a state, it
+// skips the findings already in target and, except to reopen them, the
+// resolved ones.
+func selectFindings(history store.Store, filter store.FindingFilter, target string) ([]store.Finding, error) {
+	findings, err := history.Findings(filter)
+	if err != nil || filter.State != "" {
+		return findings, err
+	}
+	return slices.DeleteFunc(findings, func(f store.Finding) bool {
+		if target == store.FindingOpen {
+			return f.State != store.FindingResolved
+		}
+		return f.State == target || f.State == store.FindingResolved
+	}), nil
+}
+
+// findingsCSVHeader are the columns of findings export --format csv
+var findingsCSVHeader = []string{"id", "domain", "endpoint", "rule", "severity", "state", "detail", "first_seen", "last_seen", "owner"}
+
+// exportFindings writes findings as csv or json to path, or to stdout when
+// path is empty. Dates are RFC 3339 in the --tz zone, which every ticketing
+// and GRC tool can import.
+func exportFindings(findings []store.Finding, format, path string) error {
+	out := os.Stdout
+	if path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("no se pudo exportar los hallazgos: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	var err error
+	if format == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(newAPIFindings(findings))
+	} else {
+		w := csv.NewWriter(out)
+		w.Write(findingsCSVHeader)
+		for _, f := range findings {
+			w.Write([]string{
+				strconv.FormatInt(f.ID, 10), f.Domain, f.IPAddress, f.Rule, f.Severity, f.State, csvCell(f.Detail),
+				f.FirstSeen.In(outputLocation).Format(time.RFC3339), f.LastSeen.In(outputLocation).Format(time.RFC3339), csvCell(f.Owner),
+			})
+		}
+		w.Flush()
+		err = w.Error()
+	}
+	if err == nil && path != "" {
+		err = out.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("no se pudo exportar los hallazgos: %w", err)
+	}
+	if path != "" {
+		fmt.Fprintf(os.Stderr, "Hallazgos exportados a %s: %d\n", path, len(findings))
+	}
+	return nil
+}
+
+// csvCell neutralizes a text cell that a spreadsheet would run as a
+// formula (=, +, -, @), prefixing it with an apostrophe
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// findingStateColor is the color of each state in the findings list
+var findingStateColor = map[string]string{store.FindingOpen: colorRed, store.FindingAcknowledged: colorYellow, store.FindingResolved: colorGreen}
+
+// displayFindings prints a list of findings
+func displayFindings(findings []store.Finding) {
+	if len(findings) == 0 {
+		fmt.Println("No hay hallazgos")
+		return
+	}
+	fmt.Printf("=== Hallazgos (%d) ===\n\n", len(findings))
+	for _, f := range findings {
+		// Se alinea antes de colorear: los códigos ANSI no ocupan columnas
+		fmt.Printf("#%-5d %s %-8s  %s  %s  %s\n", f.ID, paint(findingStateColor[f.State], fmt.Sprintf("%-12s", f.State)),
+			f.Severity, f.Domain, f.IPAddress, f.Rule)
+		fmt.Printf("       %s\n", f.Detail)
+		fmt.Printf("       Visto desde %s hasta %s\n", formatDateTime(f.FirstSeen), formatDateTime(f.LastSeen))
+		if f.Owner != "" {
+			fmt.Printf("       Responsable: %s\n", f.Owner)
+		}
+		if f.Comment != "" {
+			fmt.Printf("       %s: %s (%s)\n", f.State, f.Comment, formatDateTime(f.UpdatedAt))
+		}
+		fmt.Println()
+	}
+}
+
+// showFinding prints a finding with its state changes
+func showFinding(history store.Store, id int64) error {
+	findings, err := history.Findings(store.FindingFilter{ID: id})
+	if err != nil {
+		return err
+	}
+	if len(findings) == 0 {
+		return fmt.Errorf("%w: #%d", store.ErrFindingNotFound, id)
+	}
+	displayFindings(findings)
+
+	events, err := history.FindingEvents(id)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Cambios de estado:")
+	for _, event := range events {
+		fmt.Printf("  %s  %s", formatDateTime(event.At), paint(findingStateColor[event.State], fmt.Sprintf("%-12s", event.State)))
+		if event.Comment != "" {
+			fmt.Printf("  %s", event.Comment)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// apiFinding is the JSON representation of a finding
+type apiFinding struct {
+	ID        int64     `json:"id"`
+	Domain    string    `json:"domain"`
+	IPAddress string    `json:"ipAddress"`
+	Rule      string    `json:"rule"`
+	Severity  string    `json:"severity"`
+	Detail    string    `json:"detail"`
+	State     string    `json:"state"`
+	Comment   string    `json:"comment,omitempty"`
+	Owner     string    `json:"owner,omitempty"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// newAPIFindings converts findings to their JSON representation. Times use
+// the --tz zone.
+func newAPIFindings(findings []store.Finding) []apiFinding {
+	converted := []apiFinding{}
+	for _, f := range findings {
+		converted = append(converted, apiFinding{
+			ID:        f.ID,
+			Domain:    f.Domain,
+			IPAddress: f.IPAddress,
+			Rule:      f.Rule,
+			Severity:  f.Severity,
+			Detail:    f.Detail,
+			State:     f.State,
+			Comment:   f.Comment,
+			Owner:     f.Owner,
+			FirstSeen: f.FirstSeen.In(outputLocation),
+			LastSeen:  f.LastSeen.In(outputLocation),
+			UpdatedAt: f.UpdatedAt.In(outputLocation),
+		})
+	}
+	return converted
+}
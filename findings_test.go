@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"ssllabs-scanner/store"
+)
+
+// TestStoreFindings follows the findings of an endpoint through detection,
+// acknowledgement, resolution and reopening in every backend
+func TestStoreFindings(t *testing.T) {
+	started := time.Date(2024, 6, 1, 10, 15, 0, 0, time.UTC)
+	assessment := func(at time.Time, local bool, protocols ...string) *AssessmentResult {
+		details := &EndpointDetails{Local: local, ForwardSecrecy: 4, RenegSupport: 2, FallbackSCSV: true}
+		for _, version := range protocols {
+			details.Protocols = append(details.Protocols, Protocol{Name: "TLS", Version: version})
+		}
+		return &AssessmentResult{Domain: "example.com", TestTime: at.UnixMilli(), OverallGrade: "B", Endpoints: []EndpointResult{
+			{IPAddress: "192.0.2.1", Grade: "B", Details: details},
+		}}
+	}
+
+	for _, dsn := range []string{store.PrefixSQLite + filepath.Join(t.TempDir(), "history.db"), store.PrefixMemory} {
+		history, err := store.Open(dsn)
+		if err != nil {
+			t.Fatalf("%s: %v", dsn, err)
+		}
+		defer history.Close()
+		states := func() map[string]string {
+			findings, err := history.Findings(store.FindingFilter{Domain: "example.com"})
+			if err != nil {
+				t.Fatalf("%s: %v", dsn, err)
+			}
+			got := make(map[string]string)
+			for _, f := range findings {
+				got[f.Rule] = f.State
+			}
+			return got
+		}
+
+		if err := history.Save(storedAssessment(assessment(started, false, "1.0", "1.2"))); err != nil {
+			t.Fatalf("%s: %v", dsn, err)
+		}
+		findings, _ := history.Findings(store.FindingFilter{State: store.FindingOpen})
+		if len(findings) != 2 || findings[0].Rule != "old_protocols" || findings[1].Rule != "no_hsts" {
+			t.Fatalf("%s: Findings() = %+v, se esperaban old_protocols y no_hsts, el más severo primero", dsn, findings)
+		}
+		oldProtocols, noHSTS := findings[0].ID, findings[1].ID
+
+		if err := history.AssignFindings([]int64{noHSTS}, "equipo-web"); err != nil {
+			t.Fatalf("%s: %v", dsn, err)
+		}
+		filters := map[string]store.FindingFilter{
+			"responsable":        {Owner: "equipo-web"},
+			"regla y severidad":  {Rule: "old_protocols", Severity: "medium"},
+			"dominios":           {Domains: []string{"other.example", "example.com"}, Severity: "low"},
+			"dominios sin datos": {Domains: []string{"other.example"}},
+		}
+		for name, want := range map[string]int{"responsable": 1, "regla y severidad": 1, "dominios": 1, "dominios sin datos": 0} {
+			if got, err := history.Findings(filters[name]); err != nil || len(got) != want {
+				t.Errorf("%s: Findings con filtro por %s: %d hallazgos, %v; se esperaban %d", dsn, name, len(got), err, want)
+			}
+		}
+
+		if err := history.SetFindingState([]int64{oldProtocols}, store.FindingAcknowledged, "se deshabilita en el próximo release"); err != nil {
+			t.Fatalf("%s: %v", dsn, err)
+		}
+		if err := history.SetFindingState([]int64{noHSTS, 9999}, store.FindingResolved, ""); !errors.Is(err, store.ErrFindingNotFound) {
+			t.Errorf("%s: SetFindingState con un ID desconocido: %v, se esperaba ErrFindingNotFound", dsn, err)
+		}
+		if got := states(); got["old_protocols"] != store.FindingAcknowledged || got["no_hsts"] != store.FindingOpen {
+			t.Fatalf("%s: estados %v después de reconocer old_protocols", dsn, got)
+		}
+
+		// Un reconocimiento en bloque sin estado en el filtro salta los ya reconocidos
+		if selected, err := selectFindings(history, store.FindingFilter{Domains: []string{"example.com"}}, store.FindingAcknowledged); err != nil || len(selected) != 1 || selected[0].ID != noHSTS {
+			t.Errorf("%s: selectFindings() = %+v, %v; se esperaba solo no_hsts", dsn, selected, err)
+		}
+
+		// La evaluación local no prueba HSTS: solo resuelve old_protocols
+		if err := history.Save(storedAssessment(assessment(started.Add(time.Hour), true, "1.2"))); err != nil {
+			t.Fatalf("%s: %v", dsn, err)
+		}
+		if got := states(); got["old_protocols"] != store.FindingResolved || got["no_hsts"] != store.FindingOpen {
+			t.Fatalf("%s: estados %v después de la evaluación local", dsn, got)
+		}
+
+		reopenedAt := started.Add(2 * time.Hour)
+		if err := history.Save(storedAssessment(assessment(reopenedAt, false, "1.0", "1.2"))); err != nil {
+			t.Fatalf("%s: %v", dsn, err)
+		}
+		findings, _ = history.Findings(store.FindingFilter{Domain: "example.com"})
+		if reopened := reopenedFindings(findings, reopenedAt); len(reopened) != 1 || reopened[0].ID != oldProtocols {
+			t.Errorf("%s: reopenedFindings() = %+v, se esperaba old_protocols", dsn, reopened)
+		}
+		for _, f := range findings {
+			if !f.FirstSeen.Equal(started) || !f.LastSeen.Equal(reopenedAt) {
+				t.Errorf("%s: %s visto de %s a %s", dsn, f.Rule, f.FirstSeen, f.LastSeen)
+			}
+		}
+
+		events, err := history.FindingEvents(oldProtocols)
+		if err != nil {
+			t.Fatalf("%s: %v", dsn, err)
+		}
+		var got []string
+		for _, event := range events {
+			got = append(got, event.State)
+		}
+		if want := []string{store.FindingOpen, store.FindingAcknowledged, store.FindingResolved, store.FindingOpen}; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: eventos %v, se esperaban %v", dsn, got, want)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clientFlags holds the flags shared by every command that talks to the API
+type clientFlags struct {
+	apiVersion  *string
+	email       *string
+	maxRetries  *int
+	retries     *int
+	retryDelay  *time.Duration
+	fromCache   *bool
+	maxAge      *time.Duration
+	startNew    *bool
+	noNew       *bool
+	interval    *time.Duration
+	inProgress  *time.Duration
+	timeout     *time.Duration
+	progressive *bool
+	proxy       *string
+	apiURL      *string
+	detailsWait *time.Duration
+	mismatch    *bool
+	publish     *bool
+	record      *string
+	replay      *string
+}
+
+// addClientFlags registers the API client flags on fs
+func addClientFlags(fs *flag.FlagSet) *clientFlags {
+	return &clientFlags{
+		apiVersion:  fs.String("api-version", "auto", "versión de la API de SSL Labs: 2, 3, 4 o auto"),
+		email:       fs.String("email", os.Getenv("SSLLABS_EMAIL"), "email registrado en SSL Labs (requerido en API v4, también SSLLABS_EMAIL)"),
+		fromCache:   fs.Bool("from-cache", false, "aceptar resultados en cache de SSL Labs en vez de iniciar una evaluación nueva"),
+		maxAge:      fs.Duration("max-age", 0, "antigüedad máxima del resultado en cache con --from-cache, ej: 24h (se redondea a horas)"),
+		startNew:    fs.Bool("new", true, "iniciar siempre una evaluación nueva (startNew=on)"),
+		noNew:       fs.Bool("no-new", false, "no forzar una evaluación nueva: seguir la que esté en curso o usar la última (equivale a --new=false)"),
+		interval:    fs.Duration("poll-interval", defaultPollInterval, "espera entre consultas de estado hasta que la evaluación está IN_PROGRESS"),
+		inProgress:  fs.Duration("poll-interval-inprogress", defaultInProgressInterval, "espera entre consultas de estado mientras la evaluación está IN_PROGRESS"),
+		timeout:     fs.Duration("timeout", defaultAssessmentTimeout, "duración máxima de cada evaluación"),
+		detailsWait: fs.Duration("details-timeout", defaultDetailsTimeout, "espera máxima por los detalles de un endpoint listo antes de mostrarlo sin datos"),
+		mismatch:    fs.Bool("ignore-mismatch", false, "evaluar aunque el certificado no coincida con el nombre del host (ignoreMismatch=on, útil en staging; no aplica a resultados en cache)"),
+		publish:     fs.Bool("publish", false, "publicar los resultados en los tableros públicos de SSL Labs (publish=on)"),
+		progressive: fs.Bool("progressive", false, "consultar con all=on y mostrar protocolos y certificados de cada endpoint a medida que llegan"),
+		apiURL:      fs.String("api-url", os.Getenv("SSLLABS_API_URL"), "URL base de una API compatible, incluida la versión, ej: https://api.dev.ssllabs.com/api/v4 (también SSLLABS_API_URL)"),
+		proxy:       fs.String("proxy", "", "proxy para llegar a la API, ej: socks5://host:1080 o http://host:3128 (por defecto se usan HTTP_PROXY/HTTPS_PROXY)"),
+		maxRetries:  fs.Int("max-retries", defaultMaxRetries, "reintentos ante respuestas 429/503/529 (respeta Retry-After, 0 = no reintentar)"),
+		retries:     fs.Int("retries", defaultNetworkRetries, "reintentos de cada consulta ante errores de red transitorios (conexión cortada, DNS, timeout) y respuestas 500/502/504 (0 = no reintentar)"),
+		retryDelay:  fs.Duration("retry-delay", defaultRetryDelay, "espera antes del primer reintento de --retries; se duplica en cada uno"),
+		record:      fs.String("record", "", "grabar las respuestas de la API en esta cassette (JSON), sin dominios, IPs ni certificados, para reproducirlas con --replay"),
+		replay:      fs.String("replay", "", "responder desde esta cassette grabada con --record en vez de consultar la API"),
+	}
+}
+
+// options returns the client options for the selected API version
+func (f *clientFlags) options() ([]Option, error) {
+	apiVersion, err := parseAPIVersion(*f.apiVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	// Al reproducir se usa la versión con la que se grabó, sin email
+	var player *cassettePlayer
+	if *f.replay != "" {
+		if *f.record != "" || *f.proxy != "" {
+			return nil, fmt.Errorf("--replay no se puede usar con --record ni --proxy: no hay peticiones reales")
+		}
+		player, err = loadCassette(*f.replay)
+		if err != nil {
+			return nil, err
+		}
+		apiVersion = player.cassette.APIVersion
+	}
+
+	apiVersion = resolveAPIVersion(apiVersion, *f.email)
+	if apiVersion == apiVersionV4 && *f.email == "" && player == nil {
+		return nil, fmt.Errorf("la API v4 requiere un email registrado (--email o SSLLABS_EMAIL). Registra tu email con: %s register --help", os.Args[0])
+	}
+
+	if *f.maxRetries < 0 {
+		return nil, fmt.Errorf("--max-retries no puede ser negativo")
+	}
+	if *f.retries < 0 {
+		return nil, fmt.Errorf("--retries no puede ser negativo")
+	}
+	if *f.retryDelay <= 0 {
+		return nil, fmt.Errorf("--retry-delay debe ser positivo")
+	}
+
+	if *f.interval <= 0 || *f.inProgress <= 0 {
+		return nil, fmt.Errorf("--poll-interval y --poll-interval-inprogress deben ser positivos")
+	}
+	if *f.timeout <= 0 {
+		return nil, fmt.Errorf("--timeout debe ser positivo")
+	}
+	if *f.detailsWait <= 0 {
+		return nil, fmt.Errorf("--details-timeout debe ser positivo")
+	}
+
+	if *f.maxAge < 0 {
+		return nil, fmt.Errorf("--max-age no puede ser negativo")
+	}
+	if *f.maxAge > 0 && !*f.fromCache {
+		return nil, fmt.Errorf("--max-age requiere --from-cache")
+	}
+
+	opts := []Option{
+		WithAPIVersion(apiVersion),
+		WithEmail(*f.email),
+		WithRetries(*f.maxRetries, defaultRetryBase, defaultRetryMax),
+		WithNetworkRetries(*f.retries, *f.retryDelay),
+		WithPollIntervals(*f.interval, *f.inProgress),
+		WithAssessmentTimeout(*f.timeout),
+		WithDetailsTimeout(*f.detailsWait),
+	}
+	if *f.fromCache {
+		opts = append(opts, WithFromCache(*f.maxAge))
+	}
+	if *f.apiURL != "" {
+		baseURL, err := parseAPIURL(*f.apiURL)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithBaseURL(baseURL))
+	}
+	var proxyURL *url.URL
+	if *f.proxy != "" {
+		proxyURL, err = parseProxyURL(*f.proxy)
+		if err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case player != nil:
+		// Las respuestas ya están grabadas: no hay nada que esperar
+		opts = append(opts, WithTransport(player), WithRateLimiter(nil), WithPollIntervals(replayPollInterval, replayPollInterval))
+	case *f.record != "":
+		// El proxy va debajo del grabador, que no es un *http.Transport
+		var base http.RoundTripper
+		if proxyURL != nil {
+			base = proxyTransport(nil, proxyURL)
+		}
+		opts = append(opts, WithTransport(newCassetteRecorder(base, *f.record, apiVersion)))
+	case proxyURL != nil:
+		opts = append(opts, WithProxy(proxyURL))
+	}
+	if *f.progressive {
+		opts = append(opts, WithProgressive())
+	}
+	if *f.mismatch {
+		opts = append(opts, WithIgnoreMismatch())
+	}
+	if *f.publish {
+		opts = append(opts, WithPublish())
+	}
+	if !*f.startNew || *f.noNew {
+		opts = append(opts, WithStartNew(false))
+	}
+	return opts, nil
+}
+
+// envBool reports whether the environment variable name is set to a true
+// value (1, true...)
+func envBool(name string) bool {
+	value, _ := strconv.ParseBool(os.Getenv(name))
+	return value
+}
+
+// collectDomains merges the positional domains with the ones read from
+// inputFile (when set) and validates all of them
+func collectDomains(args []string, inputFile string) ([]string, error) {
+	domains := append([]string{}, args...)
+	if inputFile != "" {
+		fileDomains, err := readDomainsFile(inputFile)
+		if err != nil {
+			return nil, err
+		}
+		domains = append(domains, fileDomains...)
+	}
+
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("dominio requerido")
+	}
+
+	// Validar todos los dominios antes de hacer llamadas a la API
+	for i, domain := range domains {
+		domain = strings.TrimSpace(domain)
+		if err := validateDomain(domain); err != nil {
+			return nil, fmt.Errorf("%s: %w", domain, err)
+		}
+		domains[i] = domain
+	}
+
+	return domains, nil
+}
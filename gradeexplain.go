@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// hstsMinMaxAge is the max-age that SSL Labs requires for A+ (180 días)
+const hstsMinMaxAge = 180 * 24 * 60 * 60
+
+// gradeReason is a condition that keeps an endpoint below A+, with the
+// change that removes it
+type gradeReason struct {
+	ID     string // Identificador estable de la condición (ej: "old_protocols")
+	Reason string
+	Fix    string
+}
+
+// vulnerabilityFixes are the remediations of the vulnerabilities of
+// vulnerabilityChecks, by ID
+var vulnerabilityFixes = map[string]string{
+	"beast":                      "Deshabilitar TLS 1.0 y priorizar las suites AEAD de TLS 1.2 o superior",
+	"heartbleed":                 "Actualizar OpenSSL a 1.0.1g o posterior y reemplazar la clave y el certificado, que pudieron filtrarse",
+	"openssl_ccs":                "Actualizar OpenSSL a 1.0.1h o posterior (CVE-2014-0224)",
+	"openssl_padding_oracle":     "Actualizar OpenSSL a 1.0.2h o posterior (CVE-2016-2107)",
+	"poodle":                     "Deshabilitar SSL 3.0",
+	"poodle_tls":                 "Actualizar el firmware del equipo que termina TLS (suele ser un balanceador) o quitar las suites CBC",
+	"freak":                      "Quitar las suites EXPORT",
+	"logjam":                     "Quitar las suites DHE_EXPORT y usar parámetros DH de 2048 bits o más",
+	"drown":                      "Deshabilitar SSL 2.0 en todos los servidores que comparten la clave o el certificado",
+	"ticketbleed":                "Actualizar el firmware de F5 BIG-IP (CVE-2016-9244) o deshabilitar los session tickets",
+	"robot":                      "Quitar las suites con intercambio de claves RSA (TLS_RSA_*) o actualizar el equipo que termina TLS",
+	"zombie_poodle":              "Actualizar el firmware del equipo que termina TLS o quitar las suites CBC",
+	"goldendoodle":               "Actualizar el firmware del equipo que termina TLS o quitar las suites CBC",
+	"zero_length_padding_oracle": "Actualizar el firmware del equipo que termina TLS o quitar las suites CBC",
+	"sleeping_poodle":            "Actualizar el firmware del equipo que termina TLS o quitar las suites CBC",
+}
+
+// explainGrade returns the conditions that keep the endpoint below A+,
+// mapping the penalties of the SSL Labs Server Rating Guide (and the caps
+// of localGrade) to concrete fixes. It returns nil for A+ or without
+// details.
+func explainGrade(e *EndpointResult) []gradeReason {
+	d := e.Details
+	if _, ok := gradeOrder[e.Grade]; !ok || e.Grade == "A+" || d == nil {
+		return nil
+	}
+	var reasons []gradeReason
+	add := func(id, reason, fix string) {
+		reasons = append(reasons, gradeReason{ID: id, Reason: reason, Fix: fix})
+	}
+
+	// Certificado: M y T
+	if d.Cert != nil {
+		issues := d.Cert.Issues
+		if issues&certIssueHostnameMismatch != 0 {
+			add("hostname_mismatch", tr("El certificado no incluye el nombre del host (grade M)"),
+				tr("Emitir un certificado con el nombre del host en el Subject Alternative Name"))
+		}
+		if issues&(certIssueNoTrust|certIssueSelfSigned) != 0 {
+			add("untrusted", tr("El certificado no es confiable: autofirmado, de una CA no reconocida o sin los intermedios (grade T)"),
+				tr("Usar un certificado de una CA pública y configurar el servidor para que envíe la cadena completa"))
+		}
+		if issues&(certIssueNotAfter|certIssueNotBefore) != 0 {
+			add("expired", tr("El certificado expiró o aún no es válido (grade T)"),
+				tr("Renovar el certificado y verificar el reloj del servidor"))
+		}
+		if issues&certIssueRevoked != 0 {
+			add("revoked", tr("El certificado fue revocado (grade T)"),
+				tr("Emitir un certificado nuevo con una clave nueva y desplegarlo"))
+		}
+		if issues&certIssueInsecureSignature != 0 {
+			add("weak_signature", tr("El certificado tiene una firma insegura, MD5 o SHA-1 (grade T)"),
+				tr("Re-emitir el certificado con SHA-256"))
+		}
+		if issues&certIssueBlacklisted != 0 || (d.Key != nil && d.Key.DebianFlaw) {
+			add("blacklisted_key", tr("La clave del certificado está en lista negra (grade F)"),
+				tr("Generar una clave nueva y re-emitir el certificado"))
+		}
+	}
+
+	// Vulnerabilidades: F
+	for _, check := range vulnerabilityChecks(d) {
+		if check.Vulnerable {
+			add("vuln_"+check.ID, fmt.Sprintf(tr("Vulnerable a %s (grade F)"), check.Name), tr(vulnerabilityFixes[check.ID]))
+		}
+	}
+	if d.RenegSupport&1 != 0 {
+		add("insecure_renegotiation", tr("Permite la renegociación insegura iniciada por el cliente (grade F)"),
+			tr("Actualizar la biblioteca TLS o deshabilitar la renegociación iniciada por el cliente"))
+	}
+
+	// Protocolos
+	var ssl, old []string
+	for _, protocol := range d.Protocols {
+		switch {
+		case protocol.Name == "SSL":
+			ssl = append(ssl, protocol.Name+" "+protocol.Version)
+		case protocol.Version == "1.0" || protocol.Version == "1.1":
+			old = append(old, protocol.Name+" "+protocol.Version)
+		}
+	}
+	if len(ssl) > 0 {
+		add("ssl", fmt.Sprintf(tr("Ofrece %s (grade F con SSL 2.0, C como máximo con SSL 3.0)"), strings.Join(ssl, ", ")),
+			tr("Deshabilitar SSL 2.0 y 3.0"))
+	}
+	if len(d.Protocols) > 0 && !supportsProtocol(d.Protocols, "1.2") && !supportsProtocol(d.Protocols, "1.3") {
+		add("no_tls12", tr("No ofrece TLS 1.2 ni 1.3 (grade C como máximo)"), tr("Habilitar TLS 1.2 y TLS 1.3"))
+	}
+	if len(old) > 0 {
+		add("old_protocols", fmt.Sprintf(tr("Ofrece %s (grade B como máximo)"), strings.Join(old, ", ")),
+			tr("Deshabilitar TLS 1.0 y 1.1; todos los clientes actuales soportan TLS 1.2"))
+	}
+
+	// Clave del servidor
+	switch alg, size := endpointKey(d); {
+	case alg == "RSA" && size > 0 && size < 2048:
+		add("weak_key", fmt.Sprintf(tr("Clave RSA de %d bits (grade B o peor)"), size),
+			tr("Re-emitir el certificado con una clave RSA de 2048 bits o más, o ECDSA P-256"))
+	case alg == "EC" && size > 0 && size < 256:
+		add("weak_key", fmt.Sprintf(tr("Clave EC de %d bits (grade B o peor)"), size),
+			tr("Re-emitir el certificado con una clave ECDSA P-256 o mayor"))
+	}
+
+	// Cipher suites e intercambio de claves
+	if d.SupportsRC4 {
+		add("rc4", tr("Acepta RC4 (grade B como máximo, C con TLS 1.1 o superior)"), tr("Quitar las suites RC4"))
+	}
+	var weak []string
+	minDH := 0
+	for _, suites := range d.Suites {
+		for _, suite := range suites.List {
+			if reason := weakSuite(suite); reason != "" && reason != "RC4" && !slices.Contains(weak, suite.Name) {
+				weak = append(weak, suite.Name)
+			}
+			if suite.DHStrength > 0 && (minDH == 0 || suite.DHStrength < minDH) {
+				minDH = suite.DHStrength
+			}
+		}
+	}
+	if len(weak) > 0 {
+		add("weak_ciphers", fmt.Sprintf(tr("Acepta cipher suites débiles: %s (grade C o peor)"), strings.Join(weak, ", ")),
+			tr("Quitar las suites NULL, EXPORT, anónimas, DES y 3DES"))
+	}
+	switch {
+	case minDH > 0 && minDH < 2048:
+		add("weak_dh", fmt.Sprintf(tr("Parámetros DH de %d bits (grade B como máximo)"), minDH),
+			tr("Generar parámetros DH de 2048 bits o más (openssl dhparam 2048) o quitar las suites DHE"))
+	case d.DHUsesKnownPrimes == 2:
+		add("weak_dh", tr("Parámetros DH con primos conocidos y débiles (grade B como máximo)"),
+			tr("Generar parámetros DH propios de 2048 bits o más (openssl dhparam 2048) o quitar las suites DHE"))
+	}
+	switch {
+	case d.ForwardSecrecy == 0:
+		add("no_forward_secrecy", tr("Sin Forward Secrecy (grade B como máximo)"), tr("Habilitar y priorizar las suites ECDHE"))
+	case d.ForwardSecrecy&4 == 0 && !d.Local:
+		add("partial_forward_secrecy", tr("Forward Secrecy solo con algunos clientes (grade A- como máximo)"),
+			tr("Priorizar las suites ECDHE sobre las de intercambio RSA para todos los clientes"))
+	}
+	if len(d.Suites) > 0 && !supportsAEAD(d.Suites) {
+		add("no_aead", tr("Sin suites AEAD (grade A- como máximo)"), tr("Habilitar las suites AES-GCM o ChaCha20-Poly1305"))
+	}
+	if d.CompressionMethods&1 != 0 {
+		add("compression", tr("Compresión TLS habilitada: vulnerable a CRIME (grade C como máximo)"), tr("Deshabilitar la compresión TLS"))
+	}
+
+	// Lo que solo prueba la API
+	tls12OrOlder := slices.ContainsFunc(d.Protocols, func(p Protocol) bool { return p.Name == "SSL" || p.Version != "1.3" })
+	if !d.Local && tls12OrOlder && d.RenegSupport&2 == 0 && d.RenegSupport&1 == 0 {
+		add("no_secure_renegotiation", tr("No soporta la renegociación segura (grade C como máximo)"),
+			tr("Actualizar la biblioteca TLS (RFC 5746)"))
+	}
+	if !d.Local && len(d.Protocols) > 1 && !d.FallbackSCSV {
+		add("no_fallback_scsv", tr("Sin TLS_FALLBACK_SCSV: no protege contra downgrades de protocolo (grade A- como máximo)"),
+			tr("Actualizar la biblioteca TLS; las versiones actuales lo soportan sin configuración"))
+	}
+
+	// A+ exige HSTS de larga duración
+	switch {
+	case d.Local:
+		add("hsts_unknown", tr("La evaluación local no verifica HSTS y nunca otorga A+"),
+			tr("Evaluar con la API de SSL Labs para confirmar el A+"))
+	case d.HSTSPolicy == nil || d.HSTSPolicy.Status != "present":
+		add("no_hsts", tr("Sin HSTS (necesario para A+)"),
+			tr("Agregar el header Strict-Transport-Security: max-age=31536000 a las respuestas HTTPS"))
+	case d.HSTSPolicy.MaxAge != nil && *d.HSTSPolicy.MaxAge < hstsMinMaxAge:
+		add("short_hsts", fmt.Sprintf(tr("HSTS con max-age=%d, menos de 180 días (necesario para A+)"), *d.HSTSPolicy.MaxAge),
+			tr("Subir el max-age de Strict-Transport-Security a 31536000 (un año)"))
+	}
+
+	if len(reasons) == 0 {
+		add("unknown", tr("Los datos de la evaluación no indican el motivo (SSL Labs también penaliza advertencias que la API no detalla)"),
+			tr("Revisar el informe completo en https://www.ssllabs.com/ssltest/"))
+	}
+	return reasons
+}
+
+// displayGradeReasons prints why the endpoint isn't A+ and how to fix it,
+// with the configuration snippets of server when one is given (--snippets)
+func displayGradeReasons(reasons []gradeReason, server string) {
+	fmt.Print(tr("Por qué no es A+:\n"))
+	var shown []string
+	for _, reason := range reasons {
+		fmt.Printf("  %s\n", paint(colorYellow, "⚠️  "+reason.Reason))
+		fmt.Printf("     → %s\n", reason.Fix)
+		if server == "" {
+			continue
+		}
+		if snippet := reasonSnippet(server, reason, &shown); snippet != "" {
+			displaySnippet(snippet)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	headerAuditTimeout   = 15 * time.Second // Espera máxima por la página
+	headerAuditRedirects = 5                // Redirecciones que se siguen como máximo
+)
+
+// HeaderAudit is the result of fetching https://<domain>/ and checking its
+// HTTP security headers (--audit-headers)
+type HeaderAudit struct {
+	URL        string // URL auditada, la final si hubo redirecciones
+	StatusCode int
+	Error      string // Motivo por el que no se pudo obtener la página
+	Findings   []HeaderFinding
+}
+
+// HeaderFinding is the outcome of the check of one security header
+type HeaderFinding struct {
+	Header  string
+	Value   string // Valor recibido, vacío si falta
+	Problem string // Motivo por el que no cumple, vacío si cumple
+	Note    string // Aclaración cuando cumple
+}
+
+// headerAuditor is an Assessor that, after each assessment, fetches the
+// home page of the domain and audits its security headers, which SSL Labs
+// only partly reports (HSTS)
+type headerAuditor struct {
+	Assessor
+	client *http.Client
+}
+
+// withHeaderAudit wraps scanner so that its results include the audit of
+// the security headers of the site
+func withHeaderAudit(scanner Assessor) Assessor {
+	client := &http.Client{
+		Timeout: headerAuditTimeout,
+		Transport: &http.Transport{
+			// La confianza del certificado ya la juzga la evaluación TLS: acá
+			// solo se leen headers, no se envía nada
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			Proxy:           http.ProxyFromEnvironment,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= headerAuditRedirects {
+				return fmt.Errorf(tr("más de %d redirecciones"), headerAuditRedirects)
+			}
+			return nil
+		},
+	}
+	return &headerAuditor{Assessor: scanner, client: client}
+}
+
+// AssessContext runs the assessment and audits the headers of the site.
+// Interrupted assessments are returned as is.
+func (a *headerAuditor) AssessContext(ctx context.Context, domain string) (*AssessmentResult, error) {
+	result, err := a.Assessor.AssessContext(ctx, domain)
+	if err != nil {
+		return result, err
+	}
+	result.Headers = auditHeaders(ctx, a.client, "https://"+domain+"/")
+	return result, nil
+}
+
+// auditHeaders fetches url and checks its security headers. HSTS is
+// checked on the first response, the one of the assessed host, and the
+// other headers on the page the redirects end at.
+func auditHeaders(ctx context.Context, client *http.Client, url string) *HeaderAudit {
+	audit := &HeaderAudit{URL: url}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		audit.Error = err.Error()
+		return audit
+	}
+	req.Header.Set("User-Agent", "nebula/"+toolVersion()+" (header audit)")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		audit.Error = err.Error()
+		return audit
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	resp.Body.Close()
+
+	audit.URL = resp.Request.URL.String()
+	audit.StatusCode = resp.StatusCode
+	// Cada petición de una redirección guarda la respuesta que la causó
+	first := resp
+	for first.Request.Response != nil {
+		first = first.Request.Response
+	}
+	audit.Findings = checkSecurityHeaders(resp.Header, first.Header.Get("Strict-Transport-Security"))
+	return audit
+}
+
+// checkSecurityHeaders checks the headers of a page, with hsts as the
+// Strict-Transport-Security value of the assessed host
+func checkSecurityHeaders(header http.Header, hsts string) []HeaderFinding {
+	csp := parseCSP(header.Get("Content-Security-Policy"))
+	return []HeaderFinding{
+		checkHSTSHeader(hsts),
+		checkCSPHeader(header, csp),
+		checkFrameOptions(header.Get("X-Frame-Options"), csp),
+		checkReferrerPolicy(header.Get("Referrer-Policy")),
+		checkContentTypeOptions(header.Get("X-Content-Type-Options")),
+	}
+}
+
+// checkHSTSHeader checks that HSTS is set for at least 180 days
+func checkHSTSHeader(value string) HeaderFinding {
+	finding := HeaderFinding{Header: "Strict-Transport-Security", Value: value}
+	if value == "" {
+		finding.Problem = tr("falta: los navegadores aceptan conexiones HTTP en la próxima visita")
+		return finding
+	}
+	maxAge := -1
+	var flags []string
+	for _, directive := range strings.Split(value, ";") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "max-age":
+			if n, err := strconv.Atoi(strings.Trim(arg, `"`)); err == nil {
+				maxAge = n
+			}
+		case "includesubdomains", "preload":
+			flags = append(flags, name)
+		}
+	}
+	switch {
+	case maxAge < 0:
+		finding.Problem = tr("sin max-age válido")
+	case maxAge == 0:
+		finding.Problem = tr("max-age=0 deshabilita HSTS")
+	case maxAge < hstsMinMaxAge:
+		finding.Problem = fmt.Sprintf(tr("max-age de %d días, se recomienda al menos 180"), maxAge/86400)
+	default:
+		finding.Note = fmt.Sprintf(tr("%d días"), maxAge/86400)
+		if len(flags) > 0 {
+			finding.Note += ", " + strings.Join(flags, ", ")
+		}
+	}
+	return finding
+}
+
+// parseCSP splits a Content-Security-Policy into its directives, keyed
+// by lowercase name. It returns nil when there is no policy.
+func parseCSP(value string) map[string][]string {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	directives := make(map[string][]string)
+	for _, directive := range strings.Split(value, ";") {
+		fields := strings.Fields(directive)
+		if len(fields) == 0 {
+			continue
+		}
+		name := strings.ToLower(fields[0])
+		// Solo cuenta la primera aparición de cada directiva (CSP3 §2.2.1)
+		if _, ok := directives[name]; !ok {
+			directives[name] = fields[1:]
+		}
+	}
+	return directives
+}
+
+// checkCSPHeader checks that there is an enforced policy and that its
+// scripts can't run from inline code, eval or any origin
+func checkCSPHeader(header http.Header, csp map[string][]string) HeaderFinding {
+	finding := HeaderFinding{Header: "Content-Security-Policy", Value: header.Get("Content-Security-Policy")}
+	if csp == nil {
+		finding.Problem = "falta"
+		if header.Get("Content-Security-Policy-Report-Only") != "" {
+			finding.Problem += tr(" (solo hay Content-Security-Policy-Report-Only, que no bloquea nada)")
+		}
+		return finding
+	}
+
+	sources, ok := csp["script-src"]
+	if !ok {
+		sources, ok = csp["default-src"]
+	}
+	if !ok {
+		finding.Problem = tr("no restringe los scripts: falta script-src y default-src")
+		return finding
+	}
+	// Con un nonce o un hash, los navegadores actuales ignoran 'unsafe-inline'
+	protected := false
+	for _, source := range sources {
+		source = strings.ToLower(source)
+		if strings.HasPrefix(source, "'nonce-") || strings.HasPrefix(source, "'sha") || source == "'strict-dynamic'" {
+			protected = true
+		}
+	}
+	var problems []string
+	for _, source := range sources {
+		switch strings.ToLower(source) {
+		case "'unsafe-inline'":
+			if !protected {
+				problems = append(problems, tr("permite scripts inline ('unsafe-inline')"))
+			}
+		case "'unsafe-eval'":
+			problems = append(problems, tr("permite eval ('unsafe-eval')"))
+		case "*", "http:", "https:", "data:":
+			problems = append(problems, fmt.Sprintf(tr("permite scripts de cualquier origen (%s)"), source))
+		}
+	}
+	finding.Problem = strings.Join(problems, "; ")
+	return finding
+}
+
+// checkFrameOptions checks the protection against clickjacking, given by
+// X-Frame-Options or by the frame-ancestors directive of the CSP
+func checkFrameOptions(value string, csp map[string][]string) HeaderFinding {
+	finding := HeaderFinding{Header: "X-Frame-Options", Value: value}
+	if _, ok := csp["frame-ancestors"]; ok {
+		finding.Note = tr("frame-ancestors de la CSP")
+		return finding
+	}
+	switch strings.ToUpper(strings.TrimSpace(value)) {
+	case "DENY", "SAMEORIGIN":
+	case "":
+		finding.Problem = tr("falta (y la CSP no define frame-ancestors): la página puede embeberse en otros sitios")
+	default:
+		finding.Problem = fmt.Sprintf(tr("valor inválido %q: se espera DENY o SAMEORIGIN"), value)
+	}
+	return finding
+}
+
+// checkReferrerPolicy checks that the full URL isn't sent to other sites
+func checkReferrerPolicy(value string) HeaderFinding {
+	finding := HeaderFinding{Header: "Referrer-Policy", Value: value}
+	// Con varios valores separados por comas vale el último que el navegador conozca
+	policies := strings.Split(value, ",")
+	switch policy := strings.ToLower(strings.TrimSpace(policies[len(policies)-1])); policy {
+	case "":
+		finding.Problem = tr("falta: se usa el valor por defecto del navegador")
+	case "unsafe-url", "no-referrer-when-downgrade":
+		finding.Problem = fmt.Sprintf(tr("%s envía la URL completa a otros sitios"), policy)
+	}
+	return finding
+}
+
+// checkContentTypeOptions checks that MIME sniffing is disabled
+func checkContentTypeOptions(value string) HeaderFinding {
+	finding := HeaderFinding{Header: "X-Content-Type-Options", Value: value}
+	if !strings.EqualFold(strings.TrimSpace(value), "nosniff") {
+		finding.Problem = tr("falta nosniff: el navegador puede interpretar respuestas con otro tipo MIME")
+	}
+	return finding
+}
+
+// displayHeaderAudit prints the security headers of the site
+func displayHeaderAudit(audit *HeaderAudit) {
+	fmt.Print(tr("=== Headers de Seguridad HTTP ===\n"))
+	if audit.Error != "" {
+		fmt.Printf("%s\n\n", paint(colorYellow, fmt.Sprintf(tr("⚠️  No se pudo obtener %s: %s"), audit.URL, audit.Error)))
+		return
+	}
+	fmt.Printf("%s (HTTP %d)\n", audit.URL, audit.StatusCode)
+	for _, finding := range audit.Findings {
+		if finding.Problem != "" {
+			fmt.Printf("  %s\n", paint(colorRed, "❌ "+finding.Header+": "+finding.Problem))
+			continue
+		}
+		line := "✅ " + finding.Header
+		if finding.Note != "" {
+			line += " (" + finding.Note + ")"
+		} else if finding.Value != "" {
+			line += ": " + finding.Value
+		}
+		fmt.Printf("  %s\n", paint(colorGreen, line))
+	}
+	fmt.Println()
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"ssllabs-scanner/store"
+)
+
+// defaultHistoryPath returns $XDG_DATA_HOME/nebula/history.db, falling
+// back to ~/.local/share/nebula/history.db
+func defaultHistoryPath() string {
+	dataDir := os.Getenv("XDG_DATA_HOME")
+	if dataDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "history.db"
+		}
+		dataDir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataDir, "nebula", "history.db")
+}
+
+// historyDBUsage is the help of the --history-db flags
+const historyDBUsage = "historial de evaluaciones: ruta de la base SQLite, sqlite:ruta o memory: (solo durante la ejecución)"
+
+// historyFlags holds the flags that control where assessments are stored
+type historyFlags struct {
+	path     *string
+	disabled *bool
+}
+
+// addHistoryFlags registers the history flags on fs
+func addHistoryFlags(fs *flag.FlagSet) *historyFlags {
+	return &historyFlags{
+		path:     fs.String("history-db", defaultHistoryPath(), historyDBUsage),
+		disabled: fs.Bool("no-history", false, "no guardar las evaluaciones en el historial"),
+	}
+}
+
+// open opens the history store, or returns nil when --no-history is set
+func (f *historyFlags) open() (store.Store, error) {
+	if *f.disabled {
+		return nil, nil
+	}
+	return store.Open(*f.path)
+}
+
+// storedAssessment converts an assessment result to what the store records:
+// its summary, the certificates it served and what it detected on each
+// endpoint
+func storedAssessment(result *AssessmentResult) store.Assessment {
+	a := store.Assessment{Entry: historyEntryFromResult(result), Certs: seenCerts(result), Judged: judgedEndpoints(result)}
+	if result.TestTime <= 0 {
+		a.ScannedAt = time.Time{}
+	}
+	return a
+}
+
+// runHistory implements the "history" subcommand
+func runHistory(args []string) error {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	path := fs.String("history-db", defaultHistoryPath(), historyDBUsage)
+	limit := fs.Int("limit", 20, "cantidad máxima de evaluaciones a mostrar")
+	tz := addTimezoneFlag(fs)
+	color := addColorFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s history [--limit N] [--history-db archivo] <domain>\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("dominio requerido")
+	}
+	domain := strings.TrimSpace(fs.Arg(0))
+
+	if err := setOutputTimezone(*tz); err != nil {
+		return err
+	}
+	if err := setOutputColor(*color); err != nil {
+		return err
+	}
+
+	history, err := store.Open(*path)
+	if err != nil {
+		return err
+	}
+	defer history.Close()
+
+	entries, err := history.List(domain, *limit)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		fmt.Printf("No hay evaluaciones guardadas para %s\n", domain)
+		return nil
+	}
+
+	fmt.Printf("=== Historial de %s (%d evaluaciones) ===\n\n", domain, len(entries))
+	for _, entry := range entries {
+		fmt.Printf("%s  Grade General: %s\n", formatDateTime(entry.ScannedAt), paintGrade(entry.OverallGrade))
+		if entry.Metadata != nil {
+			fmt.Printf("  Motor %s · criterios %s · fuente %s · nebula %s\n", orUnknown(entry.Metadata.EngineVersion),
+				orUnknown(entry.Metadata.CriteriaVersion), orUnknown(entry.Metadata.Source), orUnknown(entry.Metadata.ToolVersion))
+		}
+		for _, endpoint := range entry.Endpoints {
+			// Se alinea antes de colorear: los códigos ANSI no ocupan columnas
+			fmt.Printf("  %-40s %s %s\n", endpoint.IPAddress, paint(gradeColor(endpoint.Grade), fmt.Sprintf("%-3s", endpoint.Grade)),
+				strings.Join(endpoint.Protocols, ", "))
+			if endpoint.CertNotAfter > 0 {
+				fmt.Printf("  %-40s     Certificado: %s (expira %s)\n", "", shortFingerprint(endpoint.CertFingerprint),
+					formatDate(time.UnixMilli(endpoint.CertNotAfter)))
+			}
+			if len(endpoint.Vulnerabilities) > 0 {
+				fmt.Printf("  %-40s     Vulnerabilidades: %s\n", "", strings.Join(endpoint.Vulnerabilities, ", "))
+			}
+		}
+		fmt.Println()
+	}
+
+	certs, err := history.Certs(domain)
+	if err != nil {
+		return err
+	}
+	if len(certs) > 0 {
+		fmt.Printf("=== Certificados vistos (%d) ===\n\n", len(certs))
+		for _, cert := range certs {
+			fmt.Printf("  %s  serie %s  %s\n", shortFingerprint(cert.Fingerprint), orUnknown(cert.Serial), orUnknown(cert.Issuer))
+			fmt.Printf("  %-18s emitido %s · expira %s · visto por primera vez %s\n", "", formatDate(cert.NotBefore),
+				formatDate(cert.NotAfter), formatDate(cert.FirstSeen))
+		}
+		fmt.Println()
+	}
+
+	return nil
+}
+
+// shortFingerprint abbreviates a hex fingerprint for display
+func shortFingerprint(fingerprint string) string {
+	if fingerprint == "" {
+		return "desconocido"
+	}
+	if len(fingerprint) > 16 {
+		return fingerprint[:16] + "…"
+	}
+	return fingerprint
+}
+
+// splitList splits a comma-separated list, returning nil for empty values
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	hstsPreloadStatusURL = "https://hstspreload.org/api/v2/status" // API de la lista de preload publicada
+	hstsPreloadTimeout   = 15 * time.Second                        // Espera máxima por cada consulta
+	hstsPreloadMinMaxAge = 365 * 24 * 60 * 60                      // max-age que exige la lista (1 año)
+)
+
+// HSTSPreloadStatus is the status of a domain in the HSTS preload list
+// published by hstspreload.org, the one Chrome ships and the other
+// browsers derive theirs from (--hsts)
+type HSTSPreloadStatus struct {
+	Status  string // unknown, pending, preloaded, rejected...
+	Entry   string // Entrada de la lista que cubre al dominio, que puede ser un dominio padre
+	Message string // Motivo del rechazo, si lo hay
+	Error   string // Motivo por el que no se pudo consultar la lista
+}
+
+// Preloaded reports whether the domain, or a parent that covers it, is in
+// the preload list
+func (s *HSTSPreloadStatus) Preloaded() bool {
+	return s != nil && s.Status == "preloaded"
+}
+
+// hstsPreloadChecker is an Assessor that, after each assessment, looks up
+// the domain in the published HSTS preload list
+type hstsPreloadChecker struct {
+	Assessor
+	client *http.Client
+	url    string
+}
+
+// withHSTSPreload wraps scanner so that its results include the status of
+// the domain in the HSTS preload list
+func withHSTSPreload(scanner Assessor) Assessor {
+	return &hstsPreloadChecker{Assessor: scanner, client: &http.Client{Timeout: hstsPreloadTimeout}, url: hstsPreloadStatusURL}
+}
+
+// AssessContext runs the assessment and looks up the domain in the preload
+// list. Interrupted assessments are returned as is.
+func (c *hstsPreloadChecker) AssessContext(ctx context.Context, domain string) (*AssessmentResult, error) {
+	result, err := c.Assessor.AssessContext(ctx, domain)
+	if err != nil {
+		return result, err
+	}
+	result.HSTSPreload = c.lookup(ctx, domain)
+	return result, nil
+}
+
+// lookup returns the preload status of domain. A subdomain that isn't in
+// the list is looked up through its parents, since hstspreload.org only
+// accepts entries with includeSubDomains.
+func (c *hstsPreloadChecker) lookup(ctx context.Context, domain string) *HSTSPreloadStatus {
+	status, err := c.query(ctx, domain)
+	if err != nil {
+		return &HSTSPreloadStatus{Error: err.Error()}
+	}
+	if status.Preloaded() {
+		return status
+	}
+	// Los padres se consultan hasta el dominio de dos etiquetas (ej:
+	// example.com), sin llegar al TLD
+	for parent := domain; strings.Count(parent, ".") > 1; {
+		_, parent, _ = strings.Cut(parent, ".")
+		covering, err := c.query(ctx, parent)
+		if err != nil {
+			break
+		}
+		if covering.Preloaded() {
+			return covering
+		}
+	}
+	return status
+}
+
+// query fetches the status of one name from the preload list API
+func (c *hstsPreloadChecker) query(ctx context.Context, domain string) (*HSTSPreloadStatus, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+"?domain="+url.QueryEscape(domain), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "nebula/"+toolVersion())
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf(tr("código HTTP inesperado: %d"), resp.StatusCode)
+	}
+
+	var body struct {
+		Name            string `json:"name"`
+		Status          string `json:"status"`
+		Message         string `json:"message"`
+		PreloadedDomain string `json:"preloadedDomain"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return nil, fmt.Errorf(tr("respuesta inválida de la lista de preload: %w"), err)
+	}
+	status := &HSTSPreloadStatus{Status: body.Status, Message: body.Message, Entry: body.PreloadedDomain}
+	if status.Entry == "" && status.Preloaded() {
+		status.Entry = domain
+	}
+	return status, nil
+}
+
+// hstsPreloadMissing returns what policy lacks to be accepted in the
+// preload list: a max-age of at least a year, includeSubDomains and the
+// preload directive
+func hstsPreloadMissing(policy *HSTSPolicy) []string {
+	if policy == nil || policy.Status != "present" {
+		return []string{"el header HSTS"}
+	}
+	var missing []string
+	if policy.MaxAge == nil || *policy.MaxAge < hstsPreloadMinMaxAge {
+		missing = append(missing, tr("max-age de al menos 1 año"))
+	}
+	if !policy.IncludeSubDomains {
+		missing = append(missing, "includeSubDomains")
+	}
+	if !policy.Preload {
+		missing = append(missing, tr("la directiva preload"))
+	}
+	return missing
+}
+
+// describePreloadLookup renders the status of the domain in the list of
+// hstspreload.org
+func describePreloadLookup(domain string, status *HSTSPreloadStatus) string {
+	switch {
+	case status.Error != "":
+		return paint(colorYellow, tr("⚠️  No se pudo consultar: ")+status.Error)
+	case status.Preloaded() && status.Entry != "" && !strings.EqualFold(status.Entry, domain):
+		return paint(colorGreen, tr("✅ Incluido por la entrada de ")+status.Entry)
+	case status.Preloaded():
+		return paint(colorGreen, tr("✅ Incluido"))
+	case status.Status == "pending":
+		return paint(colorYellow, tr("⏳ Pendiente de inclusión"))
+	case status.Status == "rejected":
+		message := tr("❌ Rechazado")
+		if status.Message != "" {
+			message += ": " + status.Message
+		}
+		return paint(colorRed, message)
+	case status.Status == "unknown" || status.Status == "":
+		return tr("❌ No incluido")
+	default:
+		return status.Status
+	}
+}
+
+// describeBrowserPreloads renders the preload lists checked by SSL Labs,
+// e.g. "Chrome ✅, Edge ✅, Firefox ✅, IE ❌"
+func describeBrowserPreloads(preloads []HSTSPreload) string {
+	parts := make([]string, 0, len(preloads))
+	for _, preload := range preloads {
+		mark := "❔"
+		switch preload.Status {
+		case "present":
+			mark = "✅"
+		case "absent":
+			mark = "❌"
+		}
+		parts = append(parts, preload.Source+" "+mark)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// displayHSTS prints the HSTS policy of each endpoint, the preload lists
+// checked by SSL Labs and, with a lookup, the status in hstspreload.org,
+// warning when the policy and the list disagree
+func displayHSTS(result *AssessmentResult) {
+	fmt.Printf("=== HSTS ===\n")
+
+	// Los endpoints suelen enviar la misma política: se agrupan para no
+	// repetirla
+	var policy *HSTSPolicy
+	var preloads []HSTSPreload
+	var policies []string
+	byPolicy := make(map[string][]string)
+	for _, endpoint := range result.Endpoints {
+		d := endpoint.Details
+		if d == nil || d.Local {
+			continue
+		}
+		if policy == nil {
+			policy = d.HSTSPolicy
+		}
+		if preloads == nil {
+			preloads = d.HSTSPreloads
+		}
+		description := describeHSTS(d.HSTSPolicy)
+		if _, ok := byPolicy[description]; !ok {
+			policies = append(policies, description)
+		}
+		byPolicy[description] = append(byPolicy[description], endpoint.IPAddress)
+	}
+
+	switch len(policies) {
+	case 0:
+		fmt.Println(tr("Política: ❔ Sin datos"))
+	case 1:
+		fmt.Printf(tr("Política: %s\n"), policies[0])
+	default:
+		for _, description := range policies {
+			fmt.Printf(tr("Política en %s: %s\n"), strings.Join(byPolicy[description], ", "), description)
+		}
+		fmt.Printf("%s\n", paint(colorYellow, tr("⚠️  Los endpoints envían políticas distintas")))
+	}
+	if len(preloads) > 0 {
+		fmt.Printf(tr("Listas de preload de los navegadores (SSL Labs): %s\n"), describeBrowserPreloads(preloads))
+	}
+
+	status := result.HSTSPreload
+	if status == nil {
+		fmt.Println()
+		return
+	}
+	fmt.Printf(tr("Lista de preload (hstspreload.org): %s\n"), describePreloadLookup(result.Domain, status))
+	if status.Error == "" && policy != nil {
+		missing := hstsPreloadMissing(policy)
+		switch {
+		case status.Preloaded() && len(missing) > 0 && strings.EqualFold(status.Entry, result.Domain):
+			// La lista se revisa periódicamente y quita los dominios que dejan de cumplir
+			fmt.Printf("%s\n", paint(colorYellow, fmt.Sprintf(tr("⚠️  El header ya no cumple los requisitos de la lista (falta %s): el dominio puede ser eliminado"), strings.Join(missing, ", "))))
+		case !status.Preloaded() && status.Status != "pending" && policy.Preload && len(missing) == 0:
+			fmt.Printf("%s\n", paint(colorYellow, tr("⚠️  El header pide preload pero el dominio no está en la lista: enviarlo en https://hstspreload.org")))
+		case !status.Preloaded() && policy.Preload:
+			fmt.Printf("%s\n", paint(colorYellow, tr("⚠️  El header pide preload pero la lista exige además: ")+strings.Join(missing, ", ")))
+		}
+	}
+	fmt.Println()
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Info is the response of the /info endpoint: the engine version and the
+// assessment load of the calling client
+type Info struct {
+	EngineVersion        string   `json:"engineVersion"`
+	CriteriaVersion      string   `json:"criteriaVersion"`
+	MaxAssessments       int      `json:"maxAssessments"`       // Evaluaciones concurrentes permitidas
+	CurrentAssessments   int      `json:"currentAssessments"`   // Evaluaciones en curso
+	NewAssessmentCoolOff int64    `json:"newAssessmentCoolOff"` // Espera mínima entre evaluaciones nuevas (milisegundos)
+	Messages             []string `json:"messages"`             // Avisos del servicio
+}
+
+// Info fetches the engine version and the current assessment load
+func (c *HTTPClient) Info(ctx context.Context) (*Info, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+infoEndpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf(tr("error creando petición: %w"), err)
+	}
+
+	body, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var info Info
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf(tr("error parseando respuesta JSON: %w"), err)
+	}
+	return &info, nil
+}
+
+// AtCapacity reports whether the client can't start a new assessment
+// until one of the current ones finishes
+func (i *Info) AtCapacity() bool {
+	return i.MaxAssessments > 0 && i.CurrentAssessments >= i.MaxAssessments
+}
+
+// CoolOff returns the published wait between new assessments
+func (i *Info) CoolOff() time.Duration {
+	return time.Duration(i.NewAssessmentCoolOff) * time.Millisecond
+}
+
+// preflight calls /info before the first assessment and prints the service
+// status to w. With failIfBusy it returns ErrAtCapacity when no new
+// assessment can start; otherwise a failing /info is only logged, since
+// the assessments may still work.
+func preflight(ctx context.Context, client *HTTPClient, w io.Writer, failIfBusy bool) error {
+	info, err := client.Info(ctx)
+	if err != nil {
+		slog.Warn("no se pudo consultar /info", "error", err)
+		return nil
+	}
+
+	fmt.Fprintf(w, tr("SSL Labs: motor %s, criterios %s · evaluaciones en curso: %d de %d · cool-off: %v\n"),
+		info.EngineVersion, info.CriteriaVersion, info.CurrentAssessments, info.MaxAssessments, info.CoolOff())
+	for _, message := range info.Messages {
+		fmt.Fprintf(w, "ℹ️  %s\n", strings.TrimSpace(message))
+	}
+
+	if info.AtCapacity() {
+		if failIfBusy {
+			return fmt.Errorf(tr("%w (%d de %d evaluaciones en curso)"), ErrAtCapacity, info.CurrentAssessments, info.MaxAssessments)
+		}
+		fmt.Fprint(w, tr("⚠️  No hay capacidad para evaluaciones nuevas: la API responderá 429 hasta que termine alguna en curso\n"))
+	}
+	fmt.Fprintln(w)
+	return nil
+}
+
+// runInfo implements the "info" subcommand: it shows the status of the
+// SSL Labs service for this client without starting an assessment
+func runInfo(args []string) error {
+	fs := flag.NewFlagSet("info", flag.ExitOnError)
+	apiFlags := addClientFlags(fs)
+	format := fs.String("format", "text", "formato de salida: text o json")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s info [--format text|json]\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("formato inválido %q: se espera text o json", *format)
+	}
+	opts, err := apiFlags.options()
+	if err != nil {
+		return err
+	}
+
+	info, err := NewHTTPClient(opts...).Info(context.Background())
+	if err != nil {
+		return fmt.Errorf("no se pudo consultar /info: %w", err)
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info)
+	}
+	fmt.Printf("Motor:        %s\n", info.EngineVersion)
+	fmt.Printf("Criterios:    %s\n", info.CriteriaVersion)
+	fmt.Printf("Evaluaciones: %d de %d en curso\n", info.CurrentAssessments, info.MaxAssessments)
+	fmt.Printf("Cool-off:     %v\n", info.CoolOff())
+	if info.AtCapacity() {
+		fmt.Println("⚠️  No hay capacidad para evaluaciones nuevas")
+	}
+	for _, message := range info.Messages {
+		fmt.Printf("ℹ️  %s\n", strings.TrimSpace(message))
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// prompter asks questions on w and reads the answers from r, one per line
+type prompter struct {
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+// ask shows question with its default value and returns the answer after
+// checking it with validate, asking again while it is invalid. An empty
+// answer selects the default.
+func (p *prompter) ask(question, fallback string, validate func(string) error) (string, error) {
+	for {
+		if fallback != "" {
+			fmt.Fprintf(p.out, "%s [%s]: ", question, fallback)
+		} else {
+			fmt.Fprintf(p.out, "%s: ", question)
+		}
+
+		if !p.in.Scan() {
+			if err := p.in.Err(); err != nil {
+				return "", err
+			}
+			return "", errors.New("entrada terminada antes de completar la configuración")
+		}
+
+		answer := strings.TrimSpace(p.in.Text())
+		if answer == "" {
+			answer = fallback
+		}
+		if err := validate(answer); err != nil {
+			fmt.Fprintf(p.out, "  ❌ %s\n", err)
+			continue
+		}
+		return answer, nil
+	}
+}
+
+// splitDomains parses a comma or space separated list of domains
+func splitDomains(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
+
+// askConfig builds a configuration from the answers of the user
+func askConfig(p *prompter) (*Config, error) {
+	config := &Config{}
+
+	answer, err := p.ask("Dominios a monitorear (separados por coma)", "", func(value string) error {
+		domains := splitDomains(value)
+		if len(domains) == 0 {
+			return errors.New("se requiere al menos un dominio")
+		}
+		for _, domain := range domains {
+			if err := validateDomain(domain); err != nil {
+				return fmt.Errorf("%s: %w", domain, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	config.Domains = splitDomains(answer)
+
+	answer, err = p.ask("Intervalo entre rondas de evaluación", "24h", func(value string) error {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			return fmt.Errorf("duración inválida %q (ej: 24h, 12h, 30m)", value)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	config.Interval, _ = time.ParseDuration(answer)
+
+	config.Notify.Webhook, err = p.ask("URL para notificaciones: webhook, slack:// o smtp:// (vacío = sin notificaciones)", "", func(value string) error {
+		if value == "" {
+			return nil
+		}
+		return validateNotifyTargets(value)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Notify.Webhook != "" {
+		answer, err = p.ask("Notificar certificados que expiran en N días o menos", "14", func(value string) error {
+			days, err := strconv.Atoi(value)
+			if err != nil || days < 0 {
+				return fmt.Errorf("número de días inválido %q", value)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		config.Notify.ExpiryDays, _ = strconv.Atoi(answer)
+	}
+
+	return config, config.Validate()
+}
+
+// runInit implements the "init" subcommand: it asks for the domains, the
+// schedule and the notification targets and writes a starter configuration
+// file for serve --config
+func runInit(args []string) error {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	output := fs.String("output", defaultConfigPath(), "archivo de configuración a generar")
+	force := fs.Bool("force", false, "sobrescribir el archivo si ya existe")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s init [--output archivo] [--force]\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if _, err := os.Stat(*output); err == nil && !*force {
+		return fmt.Errorf("%s ya existe (usa --force para sobrescribirlo)", *output)
+	}
+
+	fmt.Printf("Configuración inicial de nebula (%s)\n\n", *output)
+	config, err := askConfig(&prompter{in: bufio.NewScanner(os.Stdin), out: os.Stdout})
+	if err != nil {
+		return err
+	}
+
+	data, err := config.Marshal()
+	if err != nil {
+		return fmt.Errorf("error generando la configuración: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(*output), 0o755); err != nil {
+		return fmt.Errorf("no se pudo crear el directorio de la configuración: %w", err)
+	}
+	// El webhook es un secreto: el archivo solo lo lee su dueño
+	if err := os.WriteFile(*output, data, 0o600); err != nil {
+		return fmt.Errorf("no se pudo escribir la configuración: %w", err)
+	}
+
+	fmt.Printf("\n✅ Configuración guardada en %s\n", *output)
+	fmt.Printf("Inicia el exporter con: %s serve --config %s\n", os.Args[0], *output)
+	return nil
+}
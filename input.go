@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// readDomainsFile reads a newline-separated list of domains from path.
+// When path is "-" the list is read from stdin.
+func readDomainsFile(path string) ([]string, error) {
+	if path == "-" {
+		return readDomains(os.Stdin)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo abrir el archivo de dominios: %w", err)
+	}
+	defer f.Close()
+
+	return readDomains(f)
+}
+
+// readDomains parses one domain per line, skipping blank lines and
+// comments (everything after a '#')
+func readDomains(r io.Reader) ([]string, error) {
+	var domains []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Eliminar comentarios al final de la línea
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		domains = append(domains, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error leyendo lista de dominios: %w", err)
+	}
+
+	return domains, nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// partialResultsTimeout bounds the request that fetches the endpoints
+// already assessed after an interruption
+const partialResultsTimeout = 15 * time.Second
+
+// ErrInterrupted is returned when an assessment is cancelled, usually by
+// SIGINT or SIGTERM or by cancelling the context
+var ErrInterrupted = localizedError("evaluación interrumpida")
+
+// sleepContext waits for d or until ctx is cancelled, in which case it
+// returns ErrInterrupted
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ErrInterrupted
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// issuanceMaxValidity is the longest validity accepted by the root programs
+// for certificates issued since September 2020 (Baseline Requirements 6.3.2)
+const issuanceMaxValidity = 398 * 24 * time.Hour
+
+// IssuanceFinding is the outcome of one of the requirements that browsers
+// impose on newly issued certificates (--issuance-hygiene)
+type IssuanceFinding struct {
+	Check   string
+	Problem string // Motivo por el que no se cumple, vacío si se cumple
+	Note    string // Aclaración cuando se cumple o no puede verificarse
+}
+
+// issuanceChecker is an Assessor that, after each assessment, checks the
+// served certificates against the requirements of the root programs, so
+// internal CAs that mimic public ones can be held to the same rules
+type issuanceChecker struct {
+	Assessor
+}
+
+// withIssuanceHygiene wraps scanner so that its results include the
+// issuance hygiene findings
+func withIssuanceHygiene(scanner Assessor) Assessor {
+	return &issuanceChecker{Assessor: scanner}
+}
+
+// AssessContext runs the assessment and checks the certificate of every
+// endpoint. Interrupted assessments are returned as is.
+func (c *issuanceChecker) AssessContext(ctx context.Context, domain string) (*AssessmentResult, error) {
+	result, err := c.Assessor.AssessContext(ctx, domain)
+	if err != nil {
+		return result, err
+	}
+
+	// Los registros CAA son del dominio: se consultan una sola vez
+	var caa *IssuanceFinding
+	checked := make(map[string][]IssuanceFinding)
+	for i := range result.Endpoints {
+		endpoint := &result.Endpoints[i]
+		certs := servedCerts(endpoint.Details)
+		if len(certs) == 0 {
+			continue
+		}
+		key := endpoint.CertFingerprint
+		findings, ok := checked[key]
+		if !ok {
+			findings = checkIssuance(certs, endpoint.Details)
+			if caa == nil {
+				finding := checkCAA(ctx, domain, certs[0])
+				caa = &finding
+			}
+			findings = append(findings, *caa)
+			checked[key] = findings
+		}
+		endpoint.Issuance = findings
+	}
+	return result, nil
+}
+
+// checkIssuance checks the leaf (certs[0]) and the intermediates served
+// with it against the requirements that need no network: Certificate
+// Transparency, validity period, SHA-1 signatures and key usages
+func checkIssuance(certs []*x509.Certificate, d *EndpointDetails) []IssuanceFinding {
+	leaf := certs[0]
+	var findings []IssuanceFinding
+
+	// SCTs embebidos, en la respuesta OCSP engrapada o en la extensión TLS
+	sct := IssuanceFinding{Check: "Certificate Transparency (SCT)"}
+	embedded := slices.ContainsFunc(leaf.Extensions, func(ext pkix.Extension) bool { return ext.Id.Equal(oidSCTList) })
+	switch {
+	case embedded:
+		sct.Note = tr("SCTs embebidos en el certificado")
+	case d.HasSCT&2 != 0:
+		sct.Note = tr("SCTs en la respuesta OCSP engrapada")
+	case d.HasSCT&4 != 0:
+		sct.Note = tr("SCTs en la extensión TLS")
+	default:
+		sct.Problem = tr("sin SCTs: Chrome y Safari rechazan los certificados públicos que no están registrados en Certificate Transparency")
+	}
+	findings = append(findings, sct)
+
+	// El período de validez incluye el último segundo (BR 1.6.1)
+	validity := IssuanceFinding{Check: tr("Validez de hasta 398 días")}
+	period := leaf.NotAfter.Sub(leaf.NotBefore) + time.Second
+	days := int(period.Hours() / 24)
+	switch {
+	case period > issuanceMaxValidity && period%(24*time.Hour) != 0:
+		validity.Problem = fmt.Sprintf(tr("validez de más de %d días: Chrome, Safari y Firefox rechazan los certificados de más de 398 días"), days)
+	case period > issuanceMaxValidity:
+		validity.Problem = fmt.Sprintf(tr("validez de %d días: Chrome, Safari y Firefox rechazan los certificados de más de 398 días"), days)
+	default:
+		validity.Note = fmt.Sprintf(tr("%d días"), days)
+	}
+	findings = append(findings, validity)
+
+	// Las firmas de las raíces autofirmadas no se verifican
+	sha1 := IssuanceFinding{Check: tr("Sin firmas SHA-1")}
+	var signedSHA1 []string
+	for _, cert := range certs {
+		if cert.CheckSignatureFrom(cert) != nil && isSHA1Signature(cert.SignatureAlgorithm.String()) {
+			signedSHA1 = append(signedSHA1, certLabel(cert.Subject.CommonName, cert.Subject.Organization))
+		}
+	}
+	if len(signedSHA1) > 0 {
+		sha1.Problem = fmt.Sprintf(tr("firmados con SHA-1: %s"), strings.Join(signedSHA1, ", "))
+	}
+	findings = append(findings, sha1)
+
+	usage := IssuanceFinding{Check: tr("Usos de la clave (EKU y Key Usage)")}
+	usage.Problem = strings.Join(usageIssues(leaf), "; ")
+	findings = append(findings, usage)
+	return findings
+}
+
+// checkCAA checks that the CAA records of domain authorize the CA that
+// issued leaf. CAs that are not well known can't be matched to their CAA
+// identifier, so the finding only lists the authorized ones.
+func checkCAA(ctx context.Context, domain string, leaf *x509.Certificate) IssuanceFinding {
+	finding := IssuanceFinding{Check: tr("Autorización CAA")}
+	records, owner, err := lookupCAA(ctx, domain)
+	if err != nil {
+		finding.Problem = fmt.Sprintf(tr("no se pudieron consultar los registros CAA: %s"), err)
+		return finding
+	}
+	if len(records) == 0 {
+		finding.Note = tr("sin registros CAA: cualquier CA puede emitir")
+		return finding
+	}
+	finding.Problem, finding.Note = caaVerdict(records, owner, leaf)
+	return finding
+}
+
+// displayIssuance prints the issuance hygiene findings of an endpoint
+func displayIssuance(findings []IssuanceFinding) {
+	fmt.Print(tr("Higiene de emisión:\n"))
+	for _, finding := range findings {
+		if finding.Problem != "" {
+			fmt.Printf("  %s\n", paint(colorRed, "❌ "+finding.Check+": "+finding.Problem))
+			continue
+		}
+		line := "✅ " + finding.Check
+		if finding.Note != "" {
+			line += " (" + finding.Note + ")"
+		}
+		fmt.Printf("  %s\n", paint(colorGreen, line))
+	}
+}
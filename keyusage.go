@@ -0,0 +1,133 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// keyUsageNames are the Key Usage bits with their RFC 5280 names, in
+// the order of the extension
+var keyUsageNames = []struct {
+	usage x509.KeyUsage
+	name  string
+}{
+	{x509.KeyUsageDigitalSignature, "digitalSignature"},
+	{x509.KeyUsageContentCommitment, "contentCommitment"},
+	{x509.KeyUsageKeyEncipherment, "keyEncipherment"},
+	{x509.KeyUsageDataEncipherment, "dataEncipherment"},
+	{x509.KeyUsageKeyAgreement, "keyAgreement"},
+	{x509.KeyUsageCertSign, "keyCertSign"},
+	{x509.KeyUsageCRLSign, "cRLSign"},
+	{x509.KeyUsageEncipherOnly, "encipherOnly"},
+	{x509.KeyUsageDecipherOnly, "decipherOnly"},
+}
+
+// extKeyUsageNames are the names of the extended key usages known to x509
+var extKeyUsageNames = map[x509.ExtKeyUsage]string{
+	x509.ExtKeyUsageAny:                            "anyExtendedKeyUsage",
+	x509.ExtKeyUsageServerAuth:                     "serverAuth",
+	x509.ExtKeyUsageClientAuth:                     "clientAuth",
+	x509.ExtKeyUsageCodeSigning:                    "codeSigning",
+	x509.ExtKeyUsageEmailProtection:                "emailProtection",
+	x509.ExtKeyUsageIPSECEndSystem:                 "ipsecEndSystem",
+	x509.ExtKeyUsageIPSECTunnel:                    "ipsecTunnel",
+	x509.ExtKeyUsageIPSECUser:                      "ipsecUser",
+	x509.ExtKeyUsageTimeStamping:                   "timeStamping",
+	x509.ExtKeyUsageOCSPSigning:                    "OCSPSigning",
+	x509.ExtKeyUsageMicrosoftServerGatedCrypto:     "msSGC",
+	x509.ExtKeyUsageNetscapeServerGatedCrypto:      "nsSGC",
+	x509.ExtKeyUsageMicrosoftCommercialCodeSigning: "msCodeCom",
+	x509.ExtKeyUsageMicrosoftKernelCodeSigning:     "msKernelCodeSigning",
+}
+
+// extKeyUsageName returns the name of an extended key usage
+func extKeyUsageName(usage x509.ExtKeyUsage) string {
+	if name, ok := extKeyUsageNames[usage]; ok {
+		return name
+	}
+	return fmt.Sprintf("EKU %d", usage)
+}
+
+// keyUsages returns the names of the Key Usage bits of cert
+func keyUsages(cert *x509.Certificate) []string {
+	var names []string
+	for _, bit := range keyUsageNames {
+		if cert.KeyUsage&bit.usage != 0 {
+			names = append(names, bit.name)
+		}
+	}
+	return names
+}
+
+// extKeyUsages returns the names of the extended key usages of cert, with
+// the OID of those unknown to x509
+func extKeyUsages(cert *x509.Certificate) []string {
+	var names []string
+	for _, usage := range cert.ExtKeyUsage {
+		names = append(names, extKeyUsageName(usage))
+	}
+	for _, oid := range cert.UnknownExtKeyUsage {
+		names = append(names, oid.String())
+	}
+	return names
+}
+
+// usageIssues returns the problems of the key usages of a server
+// certificate: serverAuth missing, usages beyond TLS (only clientAuth may
+// accompany serverAuth, BR 7.1.2.7.10), CA bits on a leaf, or no
+// digitalSignature, which TLS 1.3 and (EC)DHE need
+func usageIssues(leaf *x509.Certificate) []string {
+	var issues []string
+
+	var broad []string
+	for _, usage := range leaf.ExtKeyUsage {
+		if usage != x509.ExtKeyUsageServerAuth && usage != x509.ExtKeyUsageClientAuth {
+			broad = append(broad, extKeyUsageName(usage))
+		}
+	}
+	for _, oid := range leaf.UnknownExtKeyUsage {
+		broad = append(broad, oid.String())
+	}
+	switch {
+	case len(leaf.ExtKeyUsage) == 0 && len(leaf.UnknownExtKeyUsage) == 0:
+		issues = append(issues, tr("sin Extended Key Usage: los root programs exigen serverAuth en los certificados de servidor"))
+	case !slices.Contains(leaf.ExtKeyUsage, x509.ExtKeyUsageServerAuth) && !slices.Contains(leaf.ExtKeyUsage, x509.ExtKeyUsageAny):
+		issues = append(issues, tr("Extended Key Usage sin serverAuth: los clientes no aceptan el certificado para TLS"))
+	}
+	if len(broad) > 0 {
+		issues = append(issues, fmt.Sprintf(tr("Extended Key Usage demasiado amplio: %s"), strings.Join(broad, ", ")))
+	}
+
+	// Key Usage es opcional: sin la extensión no hay restricciones
+	if leaf.KeyUsage == 0 {
+		return issues
+	}
+	if leaf.KeyUsage&(x509.KeyUsageCertSign|x509.KeyUsageCRLSign) != 0 && !leaf.IsCA {
+		issues = append(issues, tr("Key Usage de CA (keyCertSign o cRLSign) en un certificado de servidor"))
+	}
+	if leaf.KeyUsage&x509.KeyUsageDigitalSignature == 0 {
+		if _, ok := leaf.PublicKey.(*ecdsa.PublicKey); ok || leaf.KeyUsage&x509.KeyUsageKeyEncipherment == 0 {
+			issues = append(issues, tr("Key Usage sin digitalSignature: la clave no sirve para ningún handshake TLS"))
+		} else {
+			issues = append(issues, tr("Key Usage sin digitalSignature: la clave solo sirve para intercambio RSA, no para TLS 1.3 ni (EC)DHE"))
+		}
+	}
+	return issues
+}
+
+// displayKeyUsages prints the key usages of the leaf (--details)
+func displayKeyUsages(leaf *x509.Certificate) {
+	if names := keyUsages(leaf); len(names) > 0 {
+		fmt.Printf("Key Usage: %s\n", strings.Join(names, ", "))
+	} else {
+		fmt.Print(tr("Key Usage: sin la extensión\n"))
+	}
+	if names := extKeyUsages(leaf); len(names) > 0 {
+		fmt.Printf("Extended Key Usage: %s\n", strings.Join(names, ", "))
+	} else {
+		fmt.Print(tr("Extended Key Usage: sin la extensión\n"))
+	}
+}
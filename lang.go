@@ -0,0 +1,601 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Idiomas de la salida para personas
+const (
+	langES = "es"
+	langEN = "en"
+)
+
+// outputLang is the language of the human-readable output (--lang). It is
+// set once at startup, before any output. Machine formats (JSON, metrics,
+// the final summary line) never depend on it: their keys, IDs, numbers
+// and dates are the same in every language.
+var outputLang = langES
+
+// addLangFlag registers the --lang flag on fs
+func addLangFlag(fs *flag.FlagSet) *string {
+	return fs.String("lang", os.Getenv("NEBULA_LANG"), "idioma de la salida para personas: es (por defecto) o en (también NEBULA_LANG); JSON, métricas y la línea de resumen no cambian")
+}
+
+// setOutputLang parses the --lang value and makes it the output language.
+// Regional variants such as en_US.UTF-8 are accepted.
+func setOutputLang(name string) error {
+	lang := strings.ToLower(strings.TrimSpace(name))
+	lang, _, _ = strings.Cut(lang, ".")
+	lang, _, _ = strings.Cut(strings.ReplaceAll(lang, "-", "_"), "_")
+	switch lang {
+	case "", langES:
+		outputLang = langES
+	case langEN:
+		outputLang = langEN
+	default:
+		return fmt.Errorf("idioma inválido %q: se espera es o en", name)
+	}
+	return nil
+}
+
+// tr returns a human-readable message in the output language. Messages
+// are written in Spanish in the code and looked up in the catalog of the
+// language; those without a translation are returned as is. Format
+// strings are translated before formatting: tr("%d días").
+func tr(message string) string {
+	if outputLang == langEN {
+		if translated, ok := messagesEN[message]; ok {
+			return translated
+		}
+	}
+	return message
+}
+
+// localizedError is an error whose message is translated when it is
+// displayed, for the sentinel errors, created before --lang is parsed.
+// Its values are comparable, so errors.Is still matches them.
+type localizedError string
+
+// Error returns the message in the output language
+func (e localizedError) Error() string {
+	return tr(string(e))
+}
+
+// messagesEN is the English catalog, keyed by the Spanish message. It
+// covers all the output of scan and batch: progress, results, detail
+// sections and assessment errors. Flag help, validation errors of the
+// command line and of the config and policy files, logs, notifications
+// and the other subcommands are only in Spanish.
+var messagesEN = map[string]string{
+	// scanDomain y DisplayResults
+	"SSL Labs Scanner - Verificando seguridad TLS de: %s\n\n": "SSL Labs Scanner - Checking TLS security of: %s\n\n",
+	"⚠️  Evaluación interrumpida: resultados parciales":       "⚠️  Assessment interrupted: partial results",
+	"\n=== Resultados de Seguridad TLS ===\n":                 "\n=== TLS Security Results ===\n",
+	"Dominio: %s\n":         "Domain: %s\n",
+	"Grade General: %s\n\n": "Overall Grade: %s\n\n",
+	"Protocolos TLS: ❔ Sin datos (la API no devolvió los protocolos del endpoint)\n": "TLS Protocols: ❔ No data (the API did not return the protocols of the endpoint)\n",
+	"❌ CRÍTICO: el servidor solo ofrece protocolos inseguros":                        "❌ CRITICAL: the server only offers insecure protocols",
+	"Protocolos TLS: %s\n":                                  "TLS Protocols: %s\n",
+	"Certificado Emisor: %s\n":                              "Certificate Issuer: %s\n",
+	"Certificado Válido: %s hasta %s (%s)\n":                "Certificate Valid: %s to %s (%s)\n",
+	"Días para expirar: %s\n":                               "Days to expiry: %s\n",
+	"Certificado Clave: %s\n":                               "Certificate Key: %s\n",
+	"Problemas de certificado/cadena:\n":                    "Certificate/chain issues:\n",
+	"Vulnerabilidades: ❔ Sin datos\n":                       "Vulnerabilities: ❔ No data\n",
+	"Vulnerabilidades: ❔ No evaluadas (evaluación local)\n": "Vulnerabilities: ❔ Not tested (local assessment)\n",
+	"Vulnerabilidades:\n":                                   "Vulnerabilities:\n",
+	"Vulnerabilidades: Ninguna detectada\n":                 "Vulnerabilities: None detected\n",
+	"=== Resumen ===\n":                                     "=== Summary ===\n",
+	"Grade General (peor de todos los endpoints): %s\n":     "Overall Grade (worst of all endpoints): %s\n",
+	"❌ %d de %d endpoints no pudieron evaluarse":            "❌ %d of %d endpoints could not be assessed",
+	"--- Endpoint %d: %s --- Grade: %s (resumido)\n":        "--- Endpoint %d: %s --- Grade: %s (summarized)\n",
+	"=== Metadatos ===\n":                                   "=== Metadata ===\n",
+	"Otras direcciones":                                     "Other addresses",
+	"1 endpoint":                                            "1 endpoint",
+	"%d endpoints":                                          "%d endpoints",
+
+	// Expiración
+	"Expirado hace menos de un día": "Expired less than a day ago",
+	"Expirado hace %d días":         "Expired %d days ago",
+	"1 día":                         "1 day",
+	"%d días":                       "%d days",
+	" ❌ (crítico)":                  " ❌ (critical)",
+	" ⚠️  (advertencia)":            " ⚠️  (warning)",
+
+	// Metadatos
+	"Motor: %s · Criterios: %s":                         "Engine: %s · Criteria: %s",
+	"Evaluación: %s → %s":                               "Assessment: %s → %s",
+	"Fuente: %s (fromCache=%s, publish=%s) · nebula %s": "Source: %s (fromCache=%s, publish=%s) · nebula %s",
+	"Almacén de confianza: %s":                          "Trust store: %s",
+	"Evaluación local en lugar de SSL Labs (%s)":        "Local assessment instead of SSL Labs (%s)",
+	"desconocido":                                       "unknown",
+
+	// Resumen de scan y batch
+	"=== %d dominios evaluados, %d con errores, %d con vulnerabilidades, %d con certificados por expirar ===\n": "=== %d domains assessed, %d with errors, %d with vulnerabilities, %d with expiring certificates ===\n",
+	"%s: grade %s por debajo del mínimo %s\n": "%s: grade %s below the minimum %s\n",
+	"Interrumpido\n":                    "Interrupted\n",
+	"Sugerencia: %s\n":                  "Hint: %s\n",
+	"Evaluación de %s guardada en %s\n": "Assessment of %s saved to %s\n",
+	"⚠️  SSL Labs no está disponible (%s): se evalúa localmente con --local": "⚠️  SSL Labs is unavailable (%s): assessing locally with --local",
+
+	// Progreso de la evaluación
+	"Resolviendo DNS...": "Resolving DNS...",
+	"Esperando detalles de seguridad TLS... (%d/%d endpoints con detalles completos)":  "Waiting for TLS security details... (%d/%d endpoints with complete details)",
+	"Esperando detalles de seguridad TLS... (%d endpoints listos, esperando detalles)": "Waiting for TLS security details... (%d endpoints ready, waiting for details)",
+	"Finalizando evaluación...":                                          "Finishing assessment...",
+	"Esperando que finalice la evaluación... (%d endpoints en progreso)": "Waiting for the assessment to finish... (%d endpoints in progress)",
+	"Evaluando seguridad TLS... (%d%%)":                                  "Assessing TLS security... (%d%%)",
+	"Evaluando seguridad TLS...":                                         "Assessing TLS security...",
+	"Evaluación completada.":                                             "Assessment complete.",
+	"Iniciando evaluación...":                                            "Starting assessment...",
+	"Estado desconocido de la API: %s":                                   "Unknown API status: %s",
+	", se sigue consultando...":                                          ", still polling...",
+	"sin estado":                                                         "no status",
+	" (desconocido)":                                                     " (unknown)",
+	"certificado de %s, válido hasta %s · %s":                            "certificate from %s, valid until %s · %s",
+	"protocolos %s":                                                      "protocols %s",
+
+	// Errores del cliente y de la evaluación
+	"error de la API (400): %s - %s":                                  "API error (400): %s - %s",
+	"error de invocación (400): parámetros inválidos":                 "invocation error (400): invalid parameters",
+	"rate limit excedido (429): por favor espera antes de reintentar": "rate limit exceeded (429): please wait before retrying",
+	"error interno del servidor (500): por favor intenta más tarde":   "internal server error (500): please try again later",
+	"servicio no disponible (503): por favor intenta más tarde":       "service unavailable (503): please try again later",
+	"servicio sobrecargado (529): por favor intenta más tarde":        "service overloaded (529): please try again later",
+	"código HTTP inesperado: %d":                                      "unexpected HTTP status: %d",
+	"%w: la evaluación tomó más de %v (último estado: %s)":            "%w: the assessment took longer than %v (last status: %s)",
+	"%w: ningún endpoint pudo evaluarse: %s":                          "%w: no endpoint could be assessed: %s",
+	"no hay endpoints listos. Status: %s":                             "no endpoints ready. Status: %s",
+	"no hay endpoints disponibles en la respuesta":                    "no endpoints available in the response",
+	"error creando petición: %w":                                      "error creating request: %w",
+	"error de conexión: %w":                                           "connection error: %w",
+	"error leyendo respuesta: %w":                                     "error reading response: %w",
+	"error parseando respuesta JSON: %w":                              "error parsing JSON response: %w",
+
+	// Detalles (--details)
+	"Sí": "Yes",
+	"Clave: %s %d bits (equivalente RSA: %d bits)\n": "Key: %s %d bits (RSA equivalent: %d bits)\n",
+	"Firma del certificado: %s\n":                    "Certificate signature: %s\n",
+	"Nombres alternativos: %s\n":                     "Alternative names: %s\n",
+	"SCT embebido: %s\n":                             "Embedded SCT: %s\n",
+	" (preferencia del servidor: %s)":                " (server preference: %s)",
+	" [INSEGURA]":                                    " [INSECURE]",
+	"Reanudación de sesión, renegociación, HSTS/HPKP y vulnerabilidades: no evaluadas (evaluación local)": "Session resumption, renegotiation, HSTS/HPKP and vulnerabilities: not tested (local assessment)",
+	"Reanudación de sesión: %s\n":               "Session resumption: %s\n",
+	"Renegociación segura: %s\n":                "Secure renegotiation: %s\n",
+	"SNI requerido: %s\n":                       "SNI required: %s\n",
+	"Pruebas de vulnerabilidades:":              "Vulnerability tests:",
+	"Sí, con todos los clientes simulados":      "Yes, with all simulated clients",
+	"Sí, con clientes modernos":                 "Yes, with modern clients",
+	"Con algunos clientes":                      "With some clients",
+	"No habilitada":                             "Not enabled",
+	"IDs de sesión asignados pero no aceptados": "Session IDs assigned but not accepted",
+	"Habilitada":                                "Enabled",
+	"Desconocida (%d)":                          "Unknown (%d)",
+	"Prueba fallida":                            "Test failed",
+	"No vulnerable":                             "Not vulnerable",
+	"Desconocido":                               "Unknown",
+
+	// Errores del scanner y sugerencias
+	"rate limit excedido":    "rate limit exceeded",
+	"servicio no disponible": "service unavailable",
+	"error en la evaluación": "assessment failed",
+	"timeout":                "timeout",
+	"SSL Labs no tiene capacidad para evaluaciones nuevas":                                    "SSL Labs has no capacity for new assessments",
+	"evaluación interrumpida":                                                                 "assessment interrupted",
+	"la API sigue limitando las peticiones: reintenta más tarde o aumenta --max-retries":      "the API keeps rate limiting the requests: retry later or increase --max-retries",
+	"SSL Labs no está disponible en este momento: reintenta más tarde":                        "SSL Labs is unavailable right now: retry later",
+	"la evaluación puede tardar más en hosts con muchos endpoints: aumenta --timeout":         "assessments can take longer on hosts with many endpoints: increase --timeout",
+	"la conexión con la API falló en todos los reintentos: revisa la red o aumenta --retries": "the connection to the API failed on every retry: check the network or increase --retries",
+
+	// Problemas de la cadena y usos de la clave
+	"Raíz no confiable: no hay cadena de confianza hasta una CA reconocida": "Untrusted root: no chain of trust up to a recognized CA",
+	"Certificado autofirmado":                           "Self-signed certificate",
+	"El certificado aún no es válido":                   "The certificate is not valid yet",
+	"El certificado expiró":                             "The certificate expired",
+	"El certificado no coincide con el nombre del host": "The certificate does not match the host name",
+	"El certificado fue revocado":                       "The certificate was revoked",
+	"Common name inválido":                              "Invalid common name",
+	"El certificado está en lista negra":                "The certificate is blacklisted",
+	"El certificado usa una firma insegura":             "The certificate uses an insecure signature",
+	"Certificado: %s":                                   "Certificate: %s",
+	"Cadena incompleta: el servidor no envía todos los certificados intermedios":                           "Incomplete chain: the server does not send all the intermediate certificates",
+	"La cadena contiene certificados no relacionados o duplicados":                                         "The chain contains unrelated or duplicate certificates",
+	"Los certificados de la cadena están en orden incorrecto":                                              "The certificates of the chain are in the wrong order",
+	"La cadena incluye el certificado raíz autofirmado (innecesario)":                                      "The chain includes the self-signed root certificate (unnecessary)",
+	"No se pudo validar la cadena enviada por el servidor":                                                 "The chain sent by the server could not be validated",
+	"Intermedio firmado con SHA-1: %s":                                                                     "Intermediate signed with SHA-1: %s",
+	"Intermedio con firma débil: %s":                                                                       "Intermediate with a weak signature: %s",
+	"Intermedio expirado: %s":                                                                              "Expired intermediate: %s",
+	"Intermedio aún no válido: %s":                                                                         "Intermediate not valid yet: %s",
+	"Intermedio con clave débil: %s":                                                                       "Intermediate with a weak key: %s",
+	"Intermedio en lista negra: %s":                                                                        "Blacklisted intermediate: %s",
+	"Cadena de certificados (%d):\n":                                                                       "Certificate chain (%d):\n",
+	"     Emisor: %s | Firma: %s | Clave: %s %d bits\n":                                                    "     Issuer: %s | Signature: %s | Key: %s %d bits\n",
+	"Confianza por almacén:":                                                                               "Trust per store:",
+	"sin Extended Key Usage: los root programs exigen serverAuth en los certificados de servidor":          "no Extended Key Usage: root programs require serverAuth in server certificates",
+	"Extended Key Usage sin serverAuth: los clientes no aceptan el certificado para TLS":                   "Extended Key Usage without serverAuth: clients don't accept the certificate for TLS",
+	"Extended Key Usage demasiado amplio: %s":                                                              "Extended Key Usage too broad: %s",
+	"Key Usage de CA (keyCertSign o cRLSign) en un certificado de servidor":                                "CA Key Usage (keyCertSign or cRLSign) in a server certificate",
+	"Key Usage sin digitalSignature: la clave no sirve para ningún handshake TLS":                          "Key Usage without digitalSignature: the key can't be used for any TLS handshake",
+	"Key Usage sin digitalSignature: la clave solo sirve para intercambio RSA, no para TLS 1.3 ni (EC)DHE": "Key Usage without digitalSignature: the key only works for RSA key exchange, not for TLS 1.3 or (EC)DHE",
+	"Key Usage: sin la extensión\n":                                                                        "Key Usage: no extension\n",
+	"Extended Key Usage: sin la extensión\n":                                                               "Extended Key Usage: no extension\n",
+
+	// Motivos del grade
+	"Por qué no es A+:\n": "Why it is not A+:\n",
+	"El certificado no incluye el nombre del host (grade M)":                                                 "The certificate does not include the host name (grade M)",
+	"Emitir un certificado con el nombre del host en el Subject Alternative Name":                            "Issue a certificate with the host name in the Subject Alternative Name",
+	"El certificado no es confiable: autofirmado, de una CA no reconocida o sin los intermedios (grade T)":   "The certificate is not trusted: self-signed, from an unrecognized CA or without the intermediates (grade T)",
+	"Usar un certificado de una CA pública y configurar el servidor para que envíe la cadena completa":       "Use a certificate from a public CA and configure the server to send the full chain",
+	"El certificado expiró o aún no es válido (grade T)":                                                     "The certificate expired or is not valid yet (grade T)",
+	"Renovar el certificado y verificar el reloj del servidor":                                               "Renew the certificate and check the server clock",
+	"El certificado fue revocado (grade T)":                                                                  "The certificate was revoked (grade T)",
+	"Emitir un certificado nuevo con una clave nueva y desplegarlo":                                          "Issue a new certificate with a new key and deploy it",
+	"El certificado tiene una firma insegura, MD5 o SHA-1 (grade T)":                                         "The certificate has an insecure signature, MD5 or SHA-1 (grade T)",
+	"Re-emitir el certificado con SHA-256":                                                                   "Reissue the certificate with SHA-256",
+	"La clave del certificado está en lista negra (grade F)":                                                 "The certificate key is blacklisted (grade F)",
+	"Generar una clave nueva y re-emitir el certificado":                                                     "Generate a new key and reissue the certificate",
+	"Vulnerable a %s (grade F)":                                                                              "Vulnerable to %s (grade F)",
+	"Deshabilitar TLS 1.0 y priorizar las suites AEAD de TLS 1.2 o superior":                                 "Disable TLS 1.0 and prioritize the AEAD suites of TLS 1.2 or later",
+	"Actualizar OpenSSL a 1.0.1g o posterior y reemplazar la clave y el certificado, que pudieron filtrarse": "Upgrade OpenSSL to 1.0.1g or later and replace the key and the certificate, which may have leaked",
+	"Actualizar OpenSSL a 1.0.1h o posterior (CVE-2014-0224)":                                                "Upgrade OpenSSL to 1.0.1h or later (CVE-2014-0224)",
+	"Actualizar OpenSSL a 1.0.2h o posterior (CVE-2016-2107)":                                                "Upgrade OpenSSL to 1.0.2h or later (CVE-2016-2107)",
+	"Deshabilitar SSL 3.0": "Disable SSL 3.0",
+	"Actualizar el firmware del equipo que termina TLS (suele ser un balanceador) o quitar las suites CBC": "Update the firmware of the device that terminates TLS (usually a load balancer) or remove the CBC suites",
+	"Quitar las suites EXPORT": "Remove the EXPORT suites",
+	"Quitar las suites DHE_EXPORT y usar parámetros DH de 2048 bits o más":                               "Remove the DHE_EXPORT suites and use DH parameters of 2048 bits or more",
+	"Deshabilitar SSL 2.0 en todos los servidores que comparten la clave o el certificado":               "Disable SSL 2.0 on every server that shares the key or the certificate",
+	"Actualizar el firmware de F5 BIG-IP (CVE-2016-9244) o deshabilitar los session tickets":             "Update the F5 BIG-IP firmware (CVE-2016-9244) or disable session tickets",
+	"Quitar las suites con intercambio de claves RSA (TLS_RSA_*) o actualizar el equipo que termina TLS": "Remove the suites with RSA key exchange (TLS_RSA_*) or update the device that terminates TLS",
+	"Actualizar el firmware del equipo que termina TLS o quitar las suites CBC":                          "Update the firmware of the device that terminates TLS or remove the CBC suites",
+	"Permite la renegociación insegura iniciada por el cliente (grade F)":                                "Allows insecure client-initiated renegotiation (grade F)",
+	"Actualizar la biblioteca TLS o deshabilitar la renegociación iniciada por el cliente":               "Update the TLS library or disable client-initiated renegotiation",
+	"Ofrece %s (grade F con SSL 2.0, C como máximo con SSL 3.0)":                                         "Offers %s (grade F with SSL 2.0, C at most with SSL 3.0)",
+	"Deshabilitar SSL 2.0 y 3.0":                                                                                     "Disable SSL 2.0 and 3.0",
+	"No ofrece TLS 1.2 ni 1.3 (grade C como máximo)":                                                                 "Offers neither TLS 1.2 nor 1.3 (grade C at most)",
+	"Habilitar TLS 1.2 y TLS 1.3":                                                                                    "Enable TLS 1.2 and TLS 1.3",
+	"Ofrece %s (grade B como máximo)":                                                                                "Offers %s (grade B at most)",
+	"Deshabilitar TLS 1.0 y 1.1; todos los clientes actuales soportan TLS 1.2":                                       "Disable TLS 1.0 and 1.1; every current client supports TLS 1.2",
+	"Clave RSA de %d bits (grade B o peor)":                                                                          "RSA key of %d bits (grade B or worse)",
+	"Re-emitir el certificado con una clave RSA de 2048 bits o más, o ECDSA P-256":                                   "Reissue the certificate with an RSA key of 2048 bits or more, or ECDSA P-256",
+	"Clave EC de %d bits (grade B o peor)":                                                                           "EC key of %d bits (grade B or worse)",
+	"Re-emitir el certificado con una clave ECDSA P-256 o mayor":                                                     "Reissue the certificate with an ECDSA P-256 key or larger",
+	"Acepta RC4 (grade B como máximo, C con TLS 1.1 o superior)":                                                     "Accepts RC4 (grade B at most, C with TLS 1.1 or later)",
+	"Quitar las suites RC4":                                                                                          "Remove the RC4 suites",
+	"Acepta cipher suites débiles: %s (grade C o peor)":                                                              "Accepts weak cipher suites: %s (grade C or worse)",
+	"Quitar las suites NULL, EXPORT, anónimas, DES y 3DES":                                                           "Remove the NULL, EXPORT, anonymous, DES and 3DES suites",
+	"Parámetros DH de %d bits (grade B como máximo)":                                                                 "DH parameters of %d bits (grade B at most)",
+	"Generar parámetros DH de 2048 bits o más (openssl dhparam 2048) o quitar las suites DHE":                        "Generate DH parameters of 2048 bits or more (openssl dhparam 2048) or remove the DHE suites",
+	"Parámetros DH con primos conocidos y débiles (grade B como máximo)":                                             "DH parameters with known weak primes (grade B at most)",
+	"Generar parámetros DH propios de 2048 bits o más (openssl dhparam 2048) o quitar las suites DHE":                "Generate your own DH parameters of 2048 bits or more (openssl dhparam 2048) or remove the DHE suites",
+	"Sin Forward Secrecy (grade B como máximo)":                                                                      "No Forward Secrecy (grade B at most)",
+	"Habilitar y priorizar las suites ECDHE":                                                                         "Enable and prioritize the ECDHE suites",
+	"Forward Secrecy solo con algunos clientes (grade A- como máximo)":                                               "Forward Secrecy only with some clients (grade A- at most)",
+	"Priorizar las suites ECDHE sobre las de intercambio RSA para todos los clientes":                                "Prioritize the ECDHE suites over RSA key exchange for every client",
+	"Sin suites AEAD (grade A- como máximo)":                                                                         "No AEAD suites (grade A- at most)",
+	"Habilitar las suites AES-GCM o ChaCha20-Poly1305":                                                               "Enable the AES-GCM or ChaCha20-Poly1305 suites",
+	"Compresión TLS habilitada: vulnerable a CRIME (grade C como máximo)":                                            "TLS compression enabled: vulnerable to CRIME (grade C at most)",
+	"Deshabilitar la compresión TLS":                                                                                 "Disable TLS compression",
+	"No soporta la renegociación segura (grade C como máximo)":                                                       "Does not support secure renegotiation (grade C at most)",
+	"Actualizar la biblioteca TLS (RFC 5746)":                                                                        "Update the TLS library (RFC 5746)",
+	"Sin TLS_FALLBACK_SCSV: no protege contra downgrades de protocolo (grade A- como máximo)":                        "No TLS_FALLBACK_SCSV: no protection against protocol downgrades (grade A- at most)",
+	"Actualizar la biblioteca TLS; las versiones actuales lo soportan sin configuración":                             "Update the TLS library; current versions support it without configuration",
+	"La evaluación local no verifica HSTS y nunca otorga A+":                                                         "The local assessment does not check HSTS and never awards A+",
+	"Evaluar con la API de SSL Labs para confirmar el A+":                                                            "Assess with the SSL Labs API to confirm the A+",
+	"Sin HSTS (necesario para A+)":                                                                                   "No HSTS (required for A+)",
+	"Agregar el header Strict-Transport-Security: max-age=31536000 a las respuestas HTTPS":                           "Add the Strict-Transport-Security: max-age=31536000 header to the HTTPS responses",
+	"HSTS con max-age=%d, menos de 180 días (necesario para A+)":                                                     "HSTS with max-age=%d, less than 180 days (required for A+)",
+	"Subir el max-age de Strict-Transport-Security a 31536000 (un año)":                                              "Raise the Strict-Transport-Security max-age to 31536000 (one year)",
+	"Los datos de la evaluación no indican el motivo (SSL Labs también penaliza advertencias que la API no detalla)": "The assessment data does not show the reason (SSL Labs also penalizes warnings that the API does not detail)",
+	"Revisar el informe completo en https://www.ssllabs.com/ssltest/":                                                "Check the full report at https://www.ssllabs.com/ssltest/",
+
+	// Simulación de clientes, certificados adicionales y anomalías de emisión
+	"Simulación de clientes: ❔ No evaluada (evaluación local)":             "Client simulation: ❔ Not tested (local assessment)",
+	"Simulación de clientes: ❔ Sin datos":                                  "Client simulation: ❔ No data",
+	"Simulación de clientes: %d de %d conectan\n":                          "Client simulation: %d of %d connect\n",
+	"el handshake falla":                                                   "the handshake fails",
+	"clave desconocida":                                                    "unknown key",
+	"Certificados adicionales (%d):\n":                                     "Additional certificates (%d):\n",
+	"  %s, emisor %s (%s)\n":                                               "  %s, issuer %s (%s)\n",
+	"     Válido: %s hasta %s (%s) | Días para expirar: %s\n":              "     Valid: %s to %s (%s) | Days to expiry: %s\n",
+	"Certificado adicional %d: %s\n":                                       "Additional certificate %d: %s\n",
+	"El certificado %s (%s) no se usa con ninguna de las suites aceptadas": "The certificate %s (%s) is not used with any of the accepted suites",
+	"Los clientes TLS 1.3 reciben el certificado RSA aunque hay uno ECDSA: revisar la preferencia de certificados del servidor": "TLS 1.3 clients get the RSA certificate although there is an ECDSA one: check the certificate preference of the server",
+	"Certificado por tipo de suite:\n":    "Certificate per suite type:\n",
+	"%s (según el algoritmo de la suite)": "%s (from the suite algorithm)",
+	"elegido por el servidor según los algoritmos de firma del cliente (la API no lo indica)": "chosen by the server from the signature algorithms of the client (the API does not say)",
+	"sin certificado (suite anónima)":                    "no certificate (anonymous suite)",
+	"%s (serie %s)":                                      "%s (serial %s)",
+	"%s emitido por %s; hasta ahora el dominio usaba %s": "%s issued by %s; until now the domain used %s",
+	"%s aparece por primera vez pero fue emitido el %s, antes que el último conocido (%s)":  "%s appears for the first time but was issued on %s, before the last known one (%s)",
+	"%s emitido %d días después del anterior; el dominio suele renovar cada %d días":        "%s issued %d days after the previous one; the domain usually renews every %d days",
+	"ráfaga de emisiones: %d certificados emitidos con menos de %d días de diferencia (%s)": "issuance burst: %d certificates issued less than %d days apart (%s)",
+	"=== Anomalías de emisión (%s) ===\n":                                                   "=== Issuance anomalies (%s) ===\n",
+	"sin resultado":                                                                         "no result",
+
+	// AIA, OCSP, CRLs e higiene de emisión
+	"Intermedios por AIA: la cadena enviada termina en una raíz\n": "AIA intermediates: the chain sent ends in a root\n",
+	"Intermedios por AIA: %s\n":                                    "AIA intermediates: %s\n",
+	"⚠️  se obtienen, pero solo los clientes que siguen AIA (navegadores) completan la cadena; curl, OpenSSL, Java y muchas apps fallan": "⚠️  they can be fetched, but only clients that follow AIA (browsers) complete the chain; curl, OpenSSL, Java and many apps fail",
+	"❌ cadena incompleta y el AIA falla: ni los clientes que siguen AIA pueden validarla":                                                "❌ incomplete chain and AIA fails: not even clients that follow AIA can validate it",
+	"la cadena no incluye el PEM del certificado":                                                                                        "the chain does not include the PEM of the certificate",
+	"%s no indica la URL de su emisor (caIssuers)":                                                                                       "%s does not give the URL of its issuer (caIssuers)",
+	"la cadena no termina después de %d intermedios":                                                                                     "the chain does not end after %d intermediates",
+	"no contiene el emisor de %s":                                                                                                        "does not contain the issuer of %s",
+	"no se pudo obtener el emisor de %s: %s":                                                                                             "could not fetch the issuer of %s: %s",
+	"no es un certificado DER, PEM ni PKCS#7":                                                                                            "not a DER, PEM or PKCS#7 certificate",
+	"PKCS#7 inválido: %w":                                                                                                                "invalid PKCS#7: %w",
+	"PKCS#7 sin certificados válidos":                                                                                                    "PKCS#7 without valid certificates",
+	"URL inválida: %w":                                                                                                                   "invalid URL: %w",
+	"URL inválida: %s":                                                                                                                   "invalid URL: %s",
+	"inalcanzable: %w":                                                                                                                   "unreachable: %w",
+	"inalcanzable: %s":                                                                                                                   "unreachable: %s",
+	"respondió HTTP %d":                                                                                                                  "answered HTTP %d",
+	"descarga incompleta: %w":                                                                                                            "incomplete download: %w",
+	"descarga incompleta: %s":                                                                                                            "incomplete download: %s",
+	"Responders OCSP:\n":                                                                                                                 "OCSP responders:\n",
+	": %s en %s":                                                                                                                         ": %s in %s",
+	", válida hasta %s":                                                                                                                  ", valid until %s",
+	"no se pudo armar la consulta: %s":                                                                                                   "could not build the request: %s",
+	"sin respuesta: %s":                                                                                                                  "no response: %s",
+	"respuesta incompleta: %s":                                                                                                           "incomplete response: %s",
+	"lento: %s (los clientes suelen abandonar a los pocos segundos)":                                                                     "slow: %s (clients usually give up after a few seconds)",
+	"el responder no conoce el certificado (unknown)":                                                                                    "the responder does not know the certificate (unknown)",
+	"thisUpdate en el futuro (%s)":                                                                                                       "thisUpdate in the future (%s)",
+	"respuesta vencida: nextUpdate %s":                                                                                                   "expired response: nextUpdate %s",
+	"clave pública del emisor inválida: %w":                                                                                              "invalid issuer public key: %w",
+	"respuesta OCSP ilegible":                                                                                                            "unreadable OCSP response",
+	"respuesta OCSP ilegible: %w":                                                                                                        "unreadable OCSP response: %w",
+	"estado %d":                                                                                                                          "status %d",
+	"el responder devolvió un error: %s":                                                                                                 "the responder returned an error: %s",
+	"tipo de respuesta no soportado: %s":                                                                                                 "unsupported response type: %s",
+	"la respuesta no incluye el certificado consultado":                                                                                  "the response does not include the requested certificate",
+	"algoritmo de firma no soportado: %s":                                                                                                "unsupported signature algorithm: %s",
+	"certificado del responder inválido: %w":                                                                                             "invalid responder certificate: %w",
+	"el certificado del responder no fue emitido por el emisor: %w":                                                                      "the responder certificate was not issued by the issuer: %w",
+	"el certificado del responder no tiene el uso OCSP Signing":                                                                          "the responder certificate lacks the OCSP Signing usage",
+	"firma inválida: %w":                                                                                                                 "invalid signature: %w",
+	"firma inválida: %s":                                                                                                                 "invalid signature: %s",
+	": %s, %d entradas, en %s, publicada %s":                                                                                             ": %s, %d entries, in %s, published %s",
+	": el certificado figura como revocado":                                                                                              ": the certificate is listed as revoked",
+	"enorme: más de %s, descarga abandonada":                                                                                             "huge: more than %s, download abandoned",
+	"enorme: %s (los clientes que la descargan pueden agotar su timeout)":                                                                "huge: %s (clients that download it may run out of time)",
+	"CRL inválida: %s":                                                                                                                   "invalid CRL: %s",
+	"vencida: nextUpdate %s":                                                                                                             "expired: nextUpdate %s",
+	"publicación atrasada: thisUpdate %s (más de %d días)":                                                                               "late publication: thisUpdate %s (more than %d days)",
+	"Higiene de emisión:\n":                                                                                                              "Issuance hygiene:\n",
+	"SCTs embebidos en el certificado":                                                                                                   "SCTs embedded in the certificate",
+	"SCTs en la respuesta OCSP engrapada":                                                                                                "SCTs in the stapled OCSP response",
+	"SCTs en la extensión TLS":                                                                                                           "SCTs in the TLS extension",
+	"sin SCTs: Chrome y Safari rechazan los certificados públicos que no están registrados en Certificate Transparency": "no SCTs: Chrome and Safari reject public certificates that are not logged in Certificate Transparency",
+	"Validez de hasta 398 días": "Validity of up to 398 days",
+	"validez de más de %d días: Chrome, Safari y Firefox rechazan los certificados de más de 398 días": "validity of more than %d days: Chrome, Safari and Firefox reject certificates of more than 398 days",
+	"validez de %d días: Chrome, Safari y Firefox rechazan los certificados de más de 398 días":        "validity of %d days: Chrome, Safari and Firefox reject certificates of more than 398 days",
+	"Sin firmas SHA-1":                               "No SHA-1 signatures",
+	"firmados con SHA-1: %s":                         "signed with SHA-1: %s",
+	"Usos de la clave (EKU y Key Usage)":             "Key usages (EKU and Key Usage)",
+	"Autorización CAA":                               "CAA authorization",
+	"no se pudieron consultar los registros CAA: %s": "could not query the CAA records: %s",
+	"sin registros CAA: cualquier CA puede emitir":   "no CAA records: any CA can issue",
+
+	// DNS y CAA
+	"consulta DNS a %s: %w":               "DNS query to %s: %w",
+	"respuesta DNS inválida de %s: %w":    "invalid DNS response from %s: %w",
+	"el resolver %s respondió %s para %s": "the resolver %s answered %s for %s",
+	"nombre DNS inválido %q":              "invalid DNS name %q",
+	"mensaje truncado":                    "truncated message",
+	"no corresponde a la consulta":        "does not match the query",
+	"nombre comprimido con bucle":         "compressed name with a loop",
+	"registro CAA inválido en %s: %w":     "invalid CAA record at %s: %w",
+	"%s tiene una propiedad crítica desconocida (%s): ninguna CA puede emitir": "%s has an unknown critical property (%s): no CA can issue",
+	"los registros CAA de %s no autorizan a ninguna CA":                        "the CAA records of %s authorize no CA",
+	"no se conoce el identificador CAA de %s; %s autoriza a %s":                "the CAA identifier of %s is not known; %s authorizes %s",
+	"%s no está autorizada: %s solo autoriza a %s":                             "%s is not authorized: %s only authorizes %s",
+	"%s autoriza a %s": "%s authorizes %s",
+	"⚠️  No se pudieron consultar los registros CAA: %s":                                                           "⚠️  Could not query the CAA records: %s",
+	"⚠️  Sin registros CAA en %s ni en sus dominios padre: cualquier CA puede emitir certificados para el dominio": "⚠️  No CAA records at %s or its parent domains: any CA can issue certificates for the domain",
+	"   Para autorizar solo a la CA actual: %s. CAA 0 issue %q\n":                                                  "   To authorize only the current CA: %s. CAA 0 issue %q\n",
+	"Registros de %s:\n": "Records of %s:\n",
+	"❔ Sin certificados con qué comparar (la evaluación no incluye la cadena)": "❔ No certificates to compare with (the assessment does not include the chain)",
+	"  ❔ %s: sin restricciones\n": "  ❔ %s: no restrictions\n",
+
+	// Políticas y cumplimiento
+	"Política (%s)":                            "Policy (%s)",
+	"Cumplimiento %s":                          "Compliance %s",
+	"Sin protocolos anteriores a TLS %s":       "No protocols older than TLS %s",
+	"Claves %s de al menos %d bits":            "%s keys of at least %d bits",
+	"Certificado vigente por al menos %d días": "Certificate valid for at least %d days",
+	"Sin vulnerabilidades conocidas":           "No known vulnerabilities",
+	"Sin cipher suites débiles":                "No weak cipher suites",
+	"Certificado confiable":                    "Trusted certificate",
+	"sin grade":                                "no grade",
+	"sin datos":                                "no data",
+	"sin datos de protocolos":                  "no protocol data",
+	"sin datos de la clave":                    "no key data",
+	"sin datos del certificado":                "no certificate data",
+	"sin datos de cipher suites":               "no cipher suite data",
+	"ofrece %s":                                "offers %s",
+	"acepta %s":                                "accepts %s",
+	"vulnerable a %s":                          "vulnerable to %s",
+	"clave %s de %d bits":                      "%s key of %d bits",
+	"certificado expirado":                     "expired certificate",
+	"expira en %d días":                        "expires in %d days",
+	"no evaluadas (evaluación local)":          "not tested (local assessment)",
+	"no evaluado (evaluación local)":           "not tested (local assessment)",
+	"sin Forward Secrecy":                      "no Forward Secrecy",
+	"problemas del certificado (issues=%d)":    "certificate issues (issues=%d)",
+	": no se pudo evaluar":                     ": could not be assessed",
+	"sin cifrado":                              "no encryption",
+	"sin autenticación":                        "no authentication",
+	"insegura":                                 "insecure",
+	"CUMPLE":                                   "COMPLIANT",
+	"NO CUMPLE":                                "NOT COMPLIANT",
+	"evaluación fallida":                       "assessment failed",
+	"=== Cumplimiento %s: %s (%d de %d dominios cumplen) ===": "=== Compliance %s: %s (%d of %d domains comply) ===",
+	"Informe de cumplimiento guardado en %s\n":                "Compliance report saved to %s\n",
+	"# Informe de cumplimiento: %s\n\n":                       "# Compliance report: %s\n\n",
+	"- **Resultado:** %s (%d de %d dominios cumplen)\n":       "- **Result:** %s (%d of %d domains comply)\n",
+	"- **Generado:** %s por nebula %s\n":                      "- **Generated:** %s by nebula %s\n",
+	"- **Alcance:** %s\n\n":                                   "- **Scope:** %s\n\n",
+	"Requisito 4.2.1 (criptografía robusta en la transmisión de datos de titulares de tarjeta): sin SSL ni TLS anterior a 1.2, sin cipher suites débiles (RC4, 3DES, NULL, export, anónimas o de menos de 128 bits), certificado confiable y vigente, y sin vulnerabilidades TLS conocidas. No reemplaza el escaneo trimestral de un ASV aprobado ni cubre el resto de los requisitos de PCI DSS.": "Requirement 4.2.1 (strong cryptography when transmitting cardholder data): no SSL or TLS older than 1.2, no weak cipher suites (RC4, 3DES, NULL, export, anonymous or under 128 bits), a trusted and valid certificate, and no known TLS vulnerabilities. It does not replace the quarterly scan of an approved ASV nor cover the rest of the PCI DSS requirements.",
+	"| Dominio | Grade | Resultado | Reglas incumplidas |\n": "| Domain | Grade | Result | Failed rules |\n",
+	"|---------|-------|-----------|--------------------|\n": "|--------|-------|--------|--------------|\n",
+	"Cumple":                      "Complies",
+	"**No cumple**":               "**Does not comply**",
+	"❌ La evaluación falló: %s\n": "❌ The assessment failed: %s\n",
+	"Grade general: %s\n\n":       "Overall grade: %s\n\n",
+
+	// Descubrimiento de subdominios y flota
+	"%s: %d hosts a evaluar según los logs de Certificate Transparency\n":     "%s: %d hosts to assess according to the Certificate Transparency logs\n",
+	"  %d sin registros DNS, descartados: %s\n":                               "  %d without DNS records, discarded: %s\n",
+	"  %d nombres comodín, no evaluables: sus hosts no figuran en los logs\n": "  %d wildcard names, not assessable: their hosts are not in the logs\n",
+	"⚠️  %d hosts más no se evalúan por --discover-max %d":                    "⚠️  %d more hosts are not assessed because of --discover-max %d",
+	"endpoints sin evaluar":                                     "endpoints not assessed",
+	"=== Flota de %s: %d hosts (%s) ===\n":                      "=== Fleet of %s: %d hosts (%s) ===\n",
+	"certificado expirado o por expirar":                        "certificate expired or expiring",
+	"certificado por expirar":                                   "certificate expiring",
+	"no se pudieron buscar los subdominios de %s en crt.sh: %w": "could not search the subdomains of %s in crt.sh: %w",
+
+	// Evaluación local, filtros de endpoints y estado del servicio
+	"%s: %w: ningún endpoint de %s pudo evaluarse: %s":                                                         "%s: %w: no endpoint of %s could be assessed: %s",
+	"%s: la evaluación no tiene una respuesta para guardar":                                                    "%s: the assessment has no response to save",
+	"%s: ninguno de los %d endpoints coincide con %s":                                                          "%s: none of the %d endpoints matches %s",
+	"ninguna de las %d direcciones de %s coincide con %s":                                                      "none of the %d addresses of %s matches %s",
+	"%w (%d de %d evaluaciones en curso)":                                                                      "%w (%d of %d assessments in progress)",
+	"SSL Labs: motor %s, criterios %s · evaluaciones en curso: %d de %d · cool-off: %v\n":                      "SSL Labs: engine %s, criteria %s · assessments in progress: %d of %d · cool-off: %v\n",
+	"⚠️  No hay capacidad para evaluaciones nuevas: la API responderá 429 hasta que termine alguna en curso\n": "⚠️  No capacity for new assessments: the API will answer 429 until one in progress finishes\n",
+	"error reintentando petición: %w":                                                                          "error retrying request: %w",
+	"Evaluando %s localmente...\n":                                                                             "Assessing %s locally...\n",
+	"%w: no se pudo resolver el dominio: %v":                                                                   "%w: could not resolve the domain: %v",
+	"No se pudo conectar al servidor":                                                                          "Unable to connect to the server",
+	"El servidor no completó ningún handshake TLS":                                                             "The server did not complete any TLS handshake",
+	"no hay cadena hasta una raíz de este bundle":                                                              "no chain up to a root of this bundle",
+	"certificado inválido en la cadena (%s)":                                                                   "invalid certificate in the chain (%s)",
+	"Respuesta de la API":                                                                                      "API response",
+	"Datos de la evaluación local":                                                                             "Local assessment data",
+	"❌ Error: no se pudo generar el JSON: ":                                                                    "❌ Error: could not generate the JSON: ",
+
+	// Resultado de cada evaluación
+	"%s: error procesando resultados: %w":                                      "%s: error processing results: %w",
+	"%w: esperando capacidad para una evaluación nueva":                        "%w: waiting for capacity for a new assessment",
+	"Esperando detalles de seguridad TLS... (%d de %d endpoints sin detalles)": "Waiting for TLS security details... (%d of %d endpoints without details)",
+	"✅ Evaluación completada":                                                  "✅ Assessment complete",
+	"clave inesperada %v":                                                      "unexpected key %v",
+	"se esperaba %q y se encontró %v":                                          "expected %q and found %v",
+	"se esperaba un arreglo y se encontró %v":                                  "expected an array and found %v",
+
+	// Comentarios de los fragmentos de configuración (--snippets)
+	"# Bloque server (o http)": "# server (or http) block",
+	"# Bloque server de HTTPS": "# HTTPS server block",
+	"# Frontend de HTTPS":      "# HTTPS frontend",
+	"# Sección global":         "# global section",
+	"# Sección global; openssl dhparam -out /etc/haproxy/dhparam.pem 2048": "# global section; openssl dhparam -out /etc/haproxy/dhparam.pem 2048",
+	"# VirtualHost *:443 (o configuración global de mod_ssl)":              "# VirtualHost *:443 (or global mod_ssl configuration)",
+	"# VirtualHost *:443, requiere mod_headers":                            "# VirtualHost *:443, requires mod_headers",
+	"# openssl dhparam -out /etc/nginx/dhparam.pem 2048":                   "# openssl dhparam -out /etc/nginx/dhparam.pem 2048",
+	"# openssl dhparam -out /etc/ssl/dhparam.pem 2048":                     "# openssl dhparam -out /etc/ssl/dhparam.pem 2048",
+
+	// Headers de seguridad HTTP (--audit-headers)
+	"=== Headers de Seguridad HTTP ===\n":                                                   "=== HTTP Security Headers ===\n",
+	"⚠️  No se pudo obtener %s: %s":                                                         "⚠️  Could not fetch %s: %s",
+	"más de %d redirecciones":                                                               "more than %d redirects",
+	"falta: los navegadores aceptan conexiones HTTP en la próxima visita":                   "missing: browsers accept HTTP connections on the next visit",
+	"sin max-age válido":                                                                    "no valid max-age",
+	"max-age=0 deshabilita HSTS":                                                            "max-age=0 disables HSTS",
+	"max-age de %d días, se recomienda al menos 180":                                        "max-age of %d days, at least 180 is recommended",
+	"no restringe los scripts: falta script-src y default-src":                              "does not restrict scripts: script-src and default-src are missing",
+	" (solo hay Content-Security-Policy-Report-Only, que no bloquea nada)":                  " (there is only Content-Security-Policy-Report-Only, which blocks nothing)",
+	"permite scripts inline ('unsafe-inline')":                                              "allows inline scripts ('unsafe-inline')",
+	"permite eval ('unsafe-eval')":                                                          "allows eval ('unsafe-eval')",
+	"permite scripts de cualquier origen (%s)":                                              "allows scripts from any origin (%s)",
+	"frame-ancestors de la CSP":                                                             "CSP frame-ancestors",
+	"falta (y la CSP no define frame-ancestors): la página puede embeberse en otros sitios": "missing (and the CSP does not define frame-ancestors): the page can be embedded in other sites",
+	"valor inválido %q: se espera DENY o SAMEORIGIN":                                        "invalid value %q: DENY or SAMEORIGIN expected",
+	"falta: se usa el valor por defecto del navegador":                                      "missing: the browser default is used",
+	"%s envía la URL completa a otros sitios":                                               "%s sends the full URL to other sites",
+	"falta nosniff: el navegador puede interpretar respuestas con otro tipo MIME":           "nosniff missing: the browser may interpret responses as another MIME type",
+
+	// HSTS y listas de preload
+	"Política: ❔ Sin datos":                                 "Policy: ❔ No data",
+	"Política: %s\n":                                        "Policy: %s\n",
+	"Política en %s: %s\n":                                  "Policy on %s: %s\n",
+	"⚠️  Los endpoints envían políticas distintas":          "⚠️  The endpoints send different policies",
+	"Listas de preload de los navegadores (SSL Labs): %s\n": "Browser preload lists (SSL Labs): %s\n",
+	"Lista de preload (hstspreload.org): %s\n":              "Preload list (hstspreload.org): %s\n",
+	"⚠️  No se pudo consultar: ":                            "⚠️  Could not query: ",
+	"✅ Incluido por la entrada de ":                         "✅ Included by the entry of ",
+	"✅ Incluido":                                            "✅ Included",
+	"⏳ Pendiente de inclusión":                              "⏳ Pending inclusion",
+	"❌ Rechazado":                                           "❌ Rejected",
+	"❌ No incluido":                                         "❌ Not included",
+	"max-age de al menos 1 año":                             "max-age of at least 1 year",
+	"la directiva preload":                                  "the preload directive",
+	"respuesta inválida de la lista de preload: %w":         "invalid response from the preload list: %w",
+	"⚠️  El header ya no cumple los requisitos de la lista (falta %s): el dominio puede ser eliminado":    "⚠️  The header no longer meets the requirements of the list (missing %s): the domain may be removed",
+	"⚠️  El header pide preload pero el dominio no está en la lista: enviarlo en https://hstspreload.org": "⚠️  The header asks for preload but the domain is not on the list: submit it at https://hstspreload.org",
+	"⚠️  El header pide preload pero la lista exige además: ":                                             "⚠️  The header asks for preload but the list also requires: ",
+
+	// Certificate Transparency (--ct)
+	"crt.sh respondió %d":                                  "crt.sh answered %d",
+	"respuesta inválida de crt.sh: %w":                     "invalid response from crt.sh: %w",
+	"⚠️  No se pudo consultar crt.sh: ":                    "⚠️  Could not query crt.sh: ",
+	"Sin certificados emitidos en los últimos %d días\n\n": "No certificates issued in the last %d days\n\n",
+	"%d certificados emitidos en los últimos %d días\n":    "%d certificates issued in the last %d days\n",
+	"  ... y %d más (ver https://crt.sh/)\n":               "  ... and %d more (see https://crt.sh/)\n",
+	"%s  %s  serie %s  %s":                                 "%s  %s  serial %s  %s",
+	"⚠️  %s (otra CA: https://crt.sh/?id=%d)":              "⚠️  %s (another CA: https://crt.sh/?id=%d)",
+	" (servido)": " (served)",
+	"⚠️  Emitidos por una CA distinta de la de los certificados servidos: %d (verificar que sean legítimos)": "⚠️  Issued by a CA other than that of the served certificates: %d (check that they are legitimate)",
+
+	// DNSSEC (--dnssec)
+	"⚠️  No se pudo verificar: ":                 "⚠️  Could not verify: ",
+	"✅ Cadena de confianza válida desde la raíz": "✅ Valid chain of trust from the root",
+	"⚠️  Sin DNSSEC: ":                           "⚠️  No DNSSEC: ",
+	"❌ Cadena de confianza inválida (los resolvers que validan no resuelven el dominio): ": "❌ Invalid chain of trust (validating resolvers do not resolve the domain): ",
+	"  %-*s  %-6s llave %d %s (%s), firma hasta %s\n":                                      "  %-*s  %-6s key %d %s (%s), signature until %s\n",
+	"llave de la zona":           "zone key",
+	"ancla de confianza de IANA": "IANA trust anchor",
+	"DS %s del padre":            "parent DS %s",
+	"raíz: %s":                   "root: %s",
+	"%s publica DNSKEY pero %s no tiene su DS: la cadena de confianza se corta y los resolvers no validan el dominio": "%s publishes DNSKEY but %s has no DS for it: the chain of trust is broken and resolvers do not validate the domain",
+	"DS de %s en %s: %s":       "DS of %s in %s: %s",
+	"%s no tiene registros %s": "%s has no %s records",
+	" ni ":                     " or ",
+	"%s no usa DNSSEC: sus registros no están firmados y la cadena de confianza termina en %s": "%s does not use DNSSEC: its records are not signed and the chain of trust ends at %s",
+	"registros %s de %s: %s":                                          "%s records of %s: %s",
+	"%s firma con %s, desaconsejado por RFC 8624":                     "%s signs with %s, discouraged by RFC 8624",
+	"el DS de %s usa SHA-1, desaconsejado por RFC 8624":               "the DS of %s uses SHA-1, discouraged by RFC 8624",
+	"no se pudo consultar el DNS":                                     "could not query the DNS",
+	"el DS de %s":                                                     "the DS of %s",
+	"las anclas de confianza de IANA":                                 "the IANA trust anchors",
+	"la zona no publica DNSKEY pese a %s":                             "the zone publishes no DNSKEY despite %s",
+	"ningún DNSKEY coincide con %s (¿rotación de llaves incompleta?)": "no DNSKEY matches %s (incomplete key rollover?)",
+	"sin firmas (RRSIG)":                                              "no signatures (RRSIG)",
+	"firmado por %s en lugar de %s":                                   "signed by %s instead of %s",
+	"la firma recién es válida desde %s":                              "the signature is only valid from %s",
+	"la firma venció el %s":                                           "the signature expired on %s",
+	"ninguna llave de %s con el tag %d":                               "no key of %s with tag %d",
+	"llave Ed25519 inválida":                                          "invalid Ed25519 key",
+	"firma inválida":                                                  "invalid signature",
+	"algoritmo %s no soportado":                                       "unsupported algorithm %s",
+	"llave o firma ECDSA inválida":                                    "invalid ECDSA key or signature",
+	"llave RSA inválida":                                              "invalid RSA key",
+	"algoritmo %d":                                                    "algorithm %d",
+
+	// DANE (--dane)
+	"⚠️  No se pudieron consultar los registros TLSA: ":                                                          "⚠️  Could not query the TLSA records: ",
+	"Sin registros TLSA en %s: el dominio no usa DANE\n\n":                                                       "No TLSA records at %s: the domain does not use DANE\n\n",
+	" (no soportado, se ignora)":                                                                                 " (unsupported, ignored)",
+	"⚠️  No se pudo validar DNSSEC: ":                                                                            "⚠️  Could not validate DNSSEC: ",
+	"✅ Registros firmados con DNSSEC":                                                                            "✅ Records signed with DNSSEC",
+	"⚠️  Los registros no están protegidos por DNSSEC: los clientes DANE los ignoran (%s)":                       "⚠️  The records are not protected by DNSSEC: DANE clients ignore them (%s)",
+	"❌ DNSSEC inválido: los clientes DANE no pueden conectar (%s)":                                               "❌ Invalid DNSSEC: DANE clients cannot connect (%s)",
+	"  ❔ %s: la evaluación no trae la cadena de certificados\n":                                                  "  ❔ %s: the assessment does not include the certificate chain\n",
+	"✅ %s: coincide con %s":                                                                                      "✅ %s: matches %s",
+	"❌ %s: ningún registro coincide con el certificado servido, los clientes DANE rechazan la conexión":          "❌ %s: no record matches the served certificate, DANE clients reject the connection",
+	"  Los usos PKIX-TA y PKIX-EE exigen además una cadena válida para las CAs de confianza (ver la evaluación)": "  The PKIX-TA and PKIX-EE usages also require a chain valid for the trusted CAs (see the assessment)",
+}
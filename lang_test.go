@@ -0,0 +1,90 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// formatVerb matches the fmt verbs of a message, with their flags and width
+var formatVerb = regexp.MustCompile(`%[-+# 0]*[0-9]*(\.[0-9]+)?[a-zA-Z%]`)
+
+// TestMessagesEN checks that every message given to tr or localizedError
+// in the code has an English translation, and that each translation keeps
+// the verbs of its message in the same order, so formatting doesn't break
+// in either language
+func TestMessagesEN(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(file, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if !ok || len(call.Args) != 1 {
+				return true
+			}
+			if fn, ok := call.Fun.(*ast.Ident); !ok || (fn.Name != "tr" && fn.Name != "localizedError") {
+				return true
+			}
+			lit, ok := call.Args[0].(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				return true
+			}
+			message, err := strconv.Unquote(lit.Value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := messagesEN[message]; !ok {
+				t.Errorf("%s: mensaje sin traducción al inglés: %q", fset.Position(lit.Pos()), message)
+			}
+			return true
+		})
+	}
+
+	// Los que se traducen desde una variable
+	dynamic := []string{"No vulnerable", "Prueba fallida", "Desconocido", localErrConnect, localErrTLS}
+	for _, fix := range vulnerabilityFixes {
+		dynamic = append(dynamic, fix)
+	}
+	for _, profile := range complianceProfiles {
+		dynamic = append(dynamic, profile.Scope)
+	}
+	for _, snippets := range configSnippets {
+		for _, snippet := range snippets {
+			for _, line := range strings.Split(snippet, "\n") {
+				if strings.HasPrefix(line, "#") {
+					dynamic = append(dynamic, line)
+				}
+			}
+		}
+	}
+	for _, message := range dynamic {
+		if _, ok := messagesEN[message]; !ok {
+			t.Errorf("mensaje sin traducción al inglés: %q", message)
+		}
+	}
+
+	for message, translated := range messagesEN {
+		if !slices.Equal(formatVerb.FindAllString(message, -1), formatVerb.FindAllString(translated, -1)) {
+			t.Errorf("la traducción de %q no tiene los mismos verbos: %q", message, translated)
+		}
+		if strings.HasSuffix(message, "\n") != strings.HasSuffix(translated, "\n") {
+			t.Errorf("la traducción de %q no termina igual: %q", message, translated)
+		}
+	}
+}
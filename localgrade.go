@@ -0,0 +1,140 @@
+package main
+
+import "strings"
+
+// localGrade grades an endpoint assessed locally, approximating the SSL
+// Labs Server Rating Guide: a weighted score of protocol support (30%),
+// key exchange (30%) and cipher strength (40%), capped by the conditions
+// SSL Labs penalizes. A+ is never awarded because it depends on HSTS,
+// which the local scanner doesn't check.
+func localGrade(d *EndpointDetails) string {
+	if d.Cert != nil {
+		switch {
+		case d.Cert.Issues&certIssueHostnameMismatch != 0:
+			return "M"
+		case d.Cert.Issues&(certIssueNoTrust|certIssueNotBefore|certIssueNotAfter|certIssueSelfSigned|certIssueInsecureSignature) != 0:
+			return "T"
+		}
+	}
+
+	score := 0.3*protocolScore(d.Protocols) + 0.3*keyExchangeScore(d.Key) + 0.4*cipherScore(d.Suites)
+	var grade string
+	switch {
+	case score >= 80:
+		grade = "A"
+	case score >= 65:
+		grade = "B"
+	case score >= 50:
+		grade = "C"
+	case score >= 35:
+		grade = "D"
+	case score >= 20:
+		grade = "E"
+	default:
+		grade = "F"
+	}
+
+	// Topes de la guía de SSL Labs
+	caps := []struct {
+		applies bool
+		grade   string
+	}{
+		{!supportsProtocol(d.Protocols, "1.2") && !supportsProtocol(d.Protocols, "1.3"), "C"},
+		{d.RC4WithModern, "C"},
+		{supportsProtocol(d.Protocols, "1.0") || supportsProtocol(d.Protocols, "1.1"), "B"},
+		{d.SupportsRC4, "B"},
+		{d.ForwardSecrecy == 0, "B"},
+		{!supportsAEAD(d.Suites), "A-"},
+	}
+	for _, c := range caps {
+		if c.applies && compareGrades(c.grade, grade) < 0 {
+			grade = c.grade
+		}
+	}
+	return grade
+}
+
+// protocolScore averages the scores of the best and worst protocol
+func protocolScore(protocols []Protocol) float64 {
+	scores := map[string]float64{"1.0": 90, "1.1": 95, "1.2": 100, "1.3": 100}
+	best, worst := 0.0, 100.0
+	for _, protocol := range protocols {
+		score := scores[protocol.Version]
+		best = max(best, score)
+		worst = min(worst, score)
+	}
+	if best == 0 {
+		return 0
+	}
+	return (best + worst) / 2
+}
+
+// keyExchangeScore scores the RSA-equivalent strength of the server key
+func keyExchangeScore(key *Key) float64 {
+	if key == nil {
+		return 0
+	}
+	switch {
+	case key.Strength < 512:
+		return 20
+	case key.Strength < 1024:
+		return 40
+	case key.Strength < 2048:
+		return 80
+	case key.Strength < 4096:
+		return 90
+	default:
+		return 100
+	}
+}
+
+// cipherScore averages the scores of the strongest and weakest cipher
+func cipherScore(list SuitesList) float64 {
+	strength := func(bits int) float64 {
+		switch {
+		case bits == 0:
+			return 0
+		case bits < 128:
+			return 20
+		case bits < 256:
+			return 80
+		default:
+			return 100
+		}
+	}
+	best, worst, found := 0.0, 100.0, false
+	for _, suites := range list {
+		for _, suite := range suites.List {
+			score := strength(suite.CipherStrength)
+			best = max(best, score)
+			worst = min(worst, score)
+			found = true
+		}
+	}
+	if !found {
+		return 0
+	}
+	return (best + worst) / 2
+}
+
+// supportsProtocol reports whether the TLS version is among protocols
+func supportsProtocol(protocols []Protocol, version string) bool {
+	for _, protocol := range protocols {
+		if protocol.Name == "TLS" && protocol.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// supportsAEAD reports whether any accepted suite uses an AEAD cipher
+func supportsAEAD(list SuitesList) bool {
+	for _, suites := range list {
+		for _, suite := range suites.List {
+			if strings.Contains(suite.Name, "GCM") || strings.Contains(suite.Name, "CHACHA20") {
+				return true
+			}
+		}
+	}
+	return false
+}
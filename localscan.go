@@ -0,0 +1,578 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"ssllabs-scanner/store"
+)
+
+// Configuración de la evaluación local
+const (
+	localPort             = 443
+	localHandshakeTimeout = 10 * time.Second
+	// localCriteriaVersion identifica la aproximación local de la guía de
+	// calificación de SSL Labs (ver localGrade)
+	localCriteriaVersion = "nebula-local-1"
+)
+
+// Mensajes de los endpoints que no pudieron evaluarse localmente
+const (
+	localErrConnect = "No se pudo conectar al servidor"
+	localErrTLS     = "El servidor no completó ningún handshake TLS"
+)
+
+// OIDs de extensiones de certificados que el paquete x509 no expone
+var (
+	oidSCTList    = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 2}
+	oidMustStaple = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 24}
+)
+
+// localProtocols are the protocol versions probed locally, oldest first.
+// crypto/tls can't speak SSL 2.0 or 3.0, so those are not tested.
+var localProtocols = []uint16{tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13}
+
+// LocalScanner assesses hosts without the SSL Labs API: it connects to
+// every address of the host with crypto/tls, probes protocols and cipher
+// suites, validates the certificate chain against a local trust store
+// (see TrustStore) and grades the result offline. The only connections it opens are to the
+// scanned hosts (and to the system resolver to find them).
+type LocalScanner struct {
+	roots      *x509.CertPool // nil = raíces del sistema
+	bundles    []TrustBundle  // Bundles que se informan por separado
+	trustStore string         // Versión del almacén de confianza, para los metadatos
+	timeout    time.Duration  // Duración máxima de cada evaluación
+	port       int
+	lookup     func(ctx context.Context, host string) ([]netip.Addr, error)
+}
+
+// NewLocalScanner creates a local scanner that trusts the CAs of trust
+// (the system roots when nil)
+func NewLocalScanner(trust *TrustStore, timeout time.Duration) *LocalScanner {
+	s := &LocalScanner{
+		timeout: durationOr(timeout, defaultAssessmentTimeout),
+		port:    localPort,
+		lookup: func(ctx context.Context, host string) ([]netip.Addr, error) {
+			return net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+		},
+	}
+	if trust != nil {
+		s.roots, s.bundles, s.trustStore = trust.Pool(), trust.Bundles(), trust.Version()
+	}
+	return s
+}
+
+// airGappedConflicts are the flags that need the SSL Labs API or another
+// internet service, so they are rejected with --air-gapped
+var airGappedConflicts = []string{
+	"api-version", "email", "api-url", "proxy", "max-retries", "from-cache", "max-age", "new", "no-new",
+	"poll-interval", "poll-interval-inprogress", "details-timeout", "ignore-mismatch", "publish",
+	"progressive", "fail-if-busy", "notify-webhook", "probe-ocsp", "check-crl", "check-aia",
+	"record", "replay", "hsts", "ct", "ct-days", "discover-subdomains", "discover-max", "local",
+}
+
+// checkAirGapped rejects the flags of fs that would open connections
+// other than to the scanned hosts. webhook is the effective webhook, which
+// may come from SSLLABS_WEBHOOK_URL.
+func checkAirGapped(fs *flag.FlagSet, webhook string) error {
+	var conflicts []string
+	fs.Visit(func(f *flag.Flag) {
+		if slices.Contains(airGappedConflicts, f.Name) {
+			conflicts = append(conflicts, "--"+f.Name)
+		}
+	})
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%s no se puede usar con --air-gapped: requiere la API de SSL Labs u otro servicio externo", strings.Join(conflicts, ", "))
+	}
+	if webhook != "" {
+		return fmt.Errorf("las notificaciones por webhook salen a internet: quita SSLLABS_WEBHOOK_URL para usar --air-gapped")
+	}
+	return nil
+}
+
+// AssessContext assesses every endpoint of domain and processes the results
+// like an API response, so they can be displayed, stored and compared the
+// same way
+func (s *LocalScanner) AssessContext(ctx context.Context, domain string) (*AssessmentResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	fmt.Fprintf(os.Stderr, tr("Evaluando %s localmente...\n"), domain)
+	host, err := s.assessHost(ctx, domain)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return nil, fmt.Errorf("%s: %w", domain, ErrInterrupted)
+		}
+		return nil, fmt.Errorf("%s: %w", domain, err)
+	}
+
+	result, err := ProcessResults(host)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", domain, err)
+	}
+	result.Metadata.Source = sourceLocal
+	result.Metadata.TrustStore = s.trustStore
+	return result, nil
+}
+
+// assessHost builds an API-like Host from local probes of every address of domain
+func (s *LocalScanner) assessHost(ctx context.Context, domain string) (*Host, error) {
+	started := time.Now()
+	addrs, err := s.lookup(ctx, domain)
+	if err != nil {
+		return nil, fmt.Errorf(tr("%w: no se pudo resolver el dominio: %v"), ErrAssessmentFailed, err)
+	}
+	slices.SortFunc(addrs, compareAddrs)
+	addrs = slices.Compact(addrs)
+
+	host := &Host{
+		Host:            domain,
+		Port:            s.port,
+		Protocol:        "http",
+		Status:          statusReady,
+		StartTime:       started.UnixMilli(),
+		EngineVersion:   "nebula " + toolVersion(),
+		CriteriaVersion: localCriteriaVersion,
+	}
+	for _, addr := range addrs {
+		if err := ctx.Err(); err != nil {
+			return nil, ErrTimeout
+		}
+		host.Endpoints = append(host.Endpoints, s.assessEndpoint(ctx, domain, addr))
+	}
+	if ctx.Err() != nil {
+		return nil, ErrTimeout
+	}
+	host.TestTime = time.Now().UnixMilli()
+	return host, nil
+}
+
+// assessEndpoint probes one address of domain
+func (s *LocalScanner) assessEndpoint(ctx context.Context, domain string, addr netip.Addr) Endpoint {
+	return s.probeEndpoint(ctx, domain, addr, true)
+}
+
+// probeEndpoint probes one address of domain. Without suites it skips the
+// handshake per cipher suite, the slowest part: the certificate and the
+// protocols are still described, but not what depends on the suites.
+func (s *LocalScanner) probeEndpoint(ctx context.Context, domain string, addr netip.Addr, suites bool) Endpoint {
+	started := time.Now()
+	endpoint := Endpoint{IPAddress: addr.String()}
+	address := net.JoinHostPort(addr.String(), strconv.Itoa(s.port))
+
+	// Protocolos: un handshake por versión
+	details := &EndpointDetails{HostStartTime: started.UnixMilli(), Local: true}
+	var state *tls.ConnectionState
+	connected := false
+	for _, version := range localProtocols {
+		cs, err := s.handshake(ctx, domain, address, version, nil)
+		var netErr *net.OpError
+		if errors.As(err, &netErr) && netErr.Op == "dial" {
+			continue
+		}
+		connected = true
+		if err != nil {
+			continue
+		}
+		details.Protocols = append(details.Protocols, localProtocol(version))
+		state = cs
+	}
+	if state == nil {
+		endpoint.StatusMessage = tr(localErrTLS)
+		if !connected {
+			endpoint.StatusMessage = tr(localErrConnect)
+		}
+		return endpoint
+	}
+
+	var chains [][]*x509.Certificate
+	if suites {
+		details.Suites, chains = s.probeSuites(ctx, domain, address, details.Protocols)
+	}
+	describeLocalConnection(details, state, domain, s.roots, s.bundles)
+	for _, chain := range chains {
+		if !chain[0].Equal(state.PeerCertificates[0]) {
+			details.AltCerts = append(details.AltCerts, localServedCert(chain, domain, s.roots, s.bundles))
+		}
+	}
+	endpoint.StatusMessage = endpointStatusReady
+	endpoint.Progress = 100
+	endpoint.Duration = int(time.Since(started).Milliseconds())
+	endpoint.Details = details
+	endpoint.Grade = localGrade(details)
+	return endpoint
+}
+
+// handshake runs a single TLS handshake with the given version and, when
+// not nil, cipher suites. Certificates are validated later, against the
+// local trust store.
+func (s *LocalScanner) handshake(ctx context.Context, domain, address string, version uint16, suites []uint16) (*tls.ConnectionState, error) {
+	ctx, cancel := context.WithTimeout(ctx, localHandshakeTimeout)
+	defer cancel()
+
+	dialer := &tls.Dialer{Config: &tls.Config{
+		ServerName:         domain,
+		MinVersion:         version,
+		MaxVersion:         version,
+		CipherSuites:       suites,
+		NextProtos:         []string{"h2", "http/1.1"},
+		InsecureSkipVerify: true,
+	}}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	state := conn.(*tls.Conn).ConnectionState()
+	return &state, nil
+}
+
+// probeSuites finds the cipher suites accepted for each supported
+// protocol, one handshake per suite. TLS 1.3 suites can't be chosen with
+// crypto/tls, so only the negotiated one is reported. It also returns the
+// distinct chains served: servers with RSA and ECDSA certificates choose
+// the one that matches the suite (ECDHE_RSA or ECDHE_ECDSA).
+func (s *LocalScanner) probeSuites(ctx context.Context, domain, address string, protocols []Protocol) (SuitesList, [][]*x509.Certificate) {
+	var list SuitesList
+	var chains [][]*x509.Certificate
+	addChain := func(cs *tls.ConnectionState) {
+		certs := cs.PeerCertificates
+		if len(certs) > 0 && !slices.ContainsFunc(chains, func(c []*x509.Certificate) bool { return c[0].Equal(certs[0]) }) {
+			chains = append(chains, certs)
+		}
+	}
+	candidates := append(tls.CipherSuites(), tls.InsecureCipherSuites()...)
+	for _, version := range localProtocols {
+		if !slices.ContainsFunc(protocols, func(p Protocol) bool { return p == localProtocol(version) }) {
+			continue
+		}
+		suites := ProtocolSuites{Protocol: int(version)}
+		if version == tls.VersionTLS13 {
+			if cs, err := s.handshake(ctx, domain, address, version, nil); err == nil {
+				suites.List = append(suites.List, localSuite(cs.CipherSuite, cs.CurveID, false, cs.PeerCertificates))
+				addChain(cs)
+			}
+			list = append(list, suites)
+			continue
+		}
+		for _, candidate := range candidates {
+			if !slices.Contains(candidate.SupportedVersions, version) {
+				continue
+			}
+			cs, err := s.handshake(ctx, domain, address, version, []uint16{candidate.ID})
+			if err != nil {
+				continue
+			}
+			suites.List = append(suites.List, localSuite(candidate.ID, cs.CurveID, candidate.Insecure, cs.PeerCertificates))
+			addChain(cs)
+		}
+		list = append(list, suites)
+	}
+	return list, chains
+}
+
+// localProtocol returns the API representation of a protocol version.
+// TLS 1.0 and 1.1 are marked insecure (q=0) like SSL Labs does.
+func localProtocol(version uint16) Protocol {
+	name := strings.TrimPrefix(protocolName(int(version)), "TLS ")
+	protocol := Protocol{Name: "TLS", Version: name}
+	if version < tls.VersionTLS12 {
+		insecure := 0
+		protocol.Q = &insecure
+	}
+	return protocol
+}
+
+// localSuite returns the API representation of a negotiated cipher suite,
+// with the certificate the server chose for it
+func localSuite(id uint16, curve tls.CurveID, insecure bool, certs []*x509.Certificate) Suite {
+	name := tls.CipherSuiteName(id)
+	suite := Suite{ID: int(id), Name: name, CipherStrength: cipherStrength(name)}
+	if len(certs) > 0 {
+		sum := sha256.Sum256(certs[0].Raw)
+		suite.CertFingerprint = hex.EncodeToString(sum[:])
+	}
+	if curve != 0 {
+		suite.NamedGroupName = strings.ToLower(curve.String())
+	}
+	if insecure {
+		q := 0
+		suite.Q = &q
+	}
+	return suite
+}
+
+// cipherStrength returns the key size in bits of the cipher of a suite
+func cipherStrength(name string) int {
+	switch {
+	case strings.Contains(name, "AES_256"), strings.Contains(name, "CHACHA20"):
+		return 256
+	case strings.Contains(name, "AES_128"), strings.Contains(name, "RC4_128"):
+		return 128
+	case strings.Contains(name, "3DES"):
+		return 112
+	default:
+		return 0
+	}
+}
+
+// describeLocalConnection fills the certificate, chain and connection
+// details of an endpoint from the handshake with its newest protocol. The
+// grade depends on roots; the chain is also validated against each bundle
+// to report trust per bundle.
+func describeLocalConnection(d *EndpointDetails, state *tls.ConnectionState, domain string, roots *x509.CertPool, bundles []TrustBundle) {
+	served := localServedCert(state.PeerCertificates, domain, roots, bundles)
+	d.Cert, d.Chain = served.Cert, served.Chain
+	d.Key = localKey(state.PeerCertificates[0])
+
+	d.OCSPStapling = len(state.OCSPResponse) > 0
+	if len(state.SignedCertificateTimestamps) > 0 {
+		d.HasSCT |= 4
+	}
+	if d.Cert.SCT {
+		d.HasSCT |= 1
+	}
+	if state.NegotiatedProtocol != "" {
+		d.SupportsALPN = true
+		d.ALPNProtocols = state.NegotiatedProtocol
+	}
+
+	// Forward Secrecy, RC4 y AEAD a partir de las suites aceptadas
+	total, forward := 0, 0
+	for _, suites := range d.Suites {
+		for _, suite := range suites.List {
+			total++
+			if kx := keyExchange(suite); kx == "ECDHE" || kx == "DHE" || kx == "TLS 1.3" {
+				forward++
+			}
+			if strings.Contains(suite.Name, "RC4") {
+				d.SupportsRC4 = true
+				if suites.Protocol >= tls.VersionTLS11 {
+					d.RC4WithModern = true
+				}
+			}
+		}
+	}
+	switch {
+	case total > 0 && forward == total:
+		d.ForwardSecrecy = 4
+	case forward > 0:
+		d.ForwardSecrecy = 2
+	}
+}
+
+// localServedCert returns the API representation of a served chain,
+// validated against roots and against each bundle
+func localServedCert(certs []*x509.Certificate, domain string, roots *x509.CertPool, bundles []TrustBundle) ServedCert {
+	now := time.Now()
+	key := localKey(certs[0])
+	cert := localCert(certs[0])
+	cert.Issues = verifyLocalChain(certs, domain, roots, now)
+	cert.KeyAlg, cert.KeySize, cert.KeyStrength = key.Alg, key.Size, key.Strength
+
+	chain := &Chain{Issues: localChainIssues(certs)}
+	for _, c := range certs {
+		chain.Certs = append(chain.Certs, localChainCert(c, now))
+	}
+	for _, bundle := range bundles {
+		trust := Trust{RootStore: bundle.Name, IsTrusted: true}
+		if err := verifyTrust(certs, bundle.Roots); err != nil {
+			trust.IsTrusted, trust.TrustErrorMessage = false, describeTrustError(err)
+		}
+		chain.Trust = append(chain.Trust, trust)
+	}
+	return ServedCert{Cert: cert, Chain: chain}
+}
+
+// localCert returns the API representation of the server certificate
+func localCert(cert *x509.Certificate) *Cert {
+	sum := sha256.Sum256(cert.Raw)
+	c := &Cert{
+		Subject:       cert.Subject.String(),
+		SerialNumber:  cert.SerialNumber.Text(16),
+		AltNames:      cert.DNSNames,
+		IssuerSubject: cert.Issuer.String(),
+		IssuerLabel:   certLabel(cert.Issuer.CommonName, cert.Issuer.Organization),
+		NotBefore:     cert.NotBefore.UnixMilli(),
+		NotAfter:      cert.NotAfter.UnixMilli(),
+		SigAlg:        cert.SignatureAlgorithm.String(),
+		CRLURIs:       cert.CRLDistributionPoints,
+		OCSPURIs:      cert.OCSPServer,
+		SHA256Hash:    hex.EncodeToString(sum[:]),
+		Raw:           string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})),
+	}
+	if cert.Subject.CommonName != "" {
+		c.CommonNames = []string{cert.Subject.CommonName}
+	}
+	if len(c.CRLURIs) > 0 {
+		c.RevocationInfo |= 1
+	}
+	if len(c.OCSPURIs) > 0 {
+		c.RevocationInfo |= 2
+	}
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(oidSCTList):
+			c.SCT = true
+		case ext.Id.Equal(oidMustStaple):
+			c.MustStaple = true
+		}
+	}
+	return c
+}
+
+// certLabel returns a short name for a certificate subject or issuer
+func certLabel(commonName string, organization []string) string {
+	if commonName != "" {
+		return commonName
+	}
+	if len(organization) > 0 {
+		return organization[0]
+	}
+	return "desconocido"
+}
+
+// verifyLocalChain validates the served chain against roots and returns
+// the Cert.Issues bits of the problems found
+func verifyLocalChain(certs []*x509.Certificate, domain string, roots *x509.CertPool, now time.Time) int {
+	leaf := certs[0]
+	issues := 0
+	if now.Before(leaf.NotBefore) {
+		issues |= certIssueNotBefore
+	}
+	if now.After(leaf.NotAfter) {
+		issues |= certIssueNotAfter
+	}
+	if leaf.VerifyHostname(domain) != nil {
+		issues |= certIssueHostnameMismatch
+	}
+	if isSHA1Signature(leaf.SignatureAlgorithm.String()) {
+		issues |= certIssueInsecureSignature
+	}
+	if verifyTrust(certs, roots) != nil {
+		issues |= certIssueNoTrust
+		// Un certificado autofirmado solo es un problema si no está en el
+		// almacén de confianza (por ejemplo, agregado con --ca-file)
+		if leaf.CheckSignatureFrom(leaf) == nil {
+			issues |= certIssueSelfSigned
+		}
+	}
+	return issues
+}
+
+// verifyTrust checks that the served chain leads to one of roots. Dates
+// and names are checked separately, so only the trust path matters here.
+func verifyTrust(certs []*x509.Certificate, roots *x509.CertPool) error {
+	leaf := certs[0]
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   leaf.NotBefore.Add(leaf.NotAfter.Sub(leaf.NotBefore) / 2),
+	})
+	return err
+}
+
+// describeTrustError explains why a chain is not trusted by a bundle
+func describeTrustError(err error) string {
+	var unknown x509.UnknownAuthorityError
+	var invalid x509.CertificateInvalidError
+	switch {
+	case errors.As(err, &unknown):
+		return tr("no hay cadena hasta una raíz de este bundle")
+	case errors.As(err, &invalid):
+		return fmt.Sprintf(tr("certificado inválido en la cadena (%s)"), invalid.Error())
+	default:
+		return err.Error()
+	}
+}
+
+// localChainIssues returns the Chain.Issues bits of the order of the served chain
+func localChainIssues(certs []*x509.Certificate) int {
+	issues := 0
+	for i := 0; i+1 < len(certs); i++ {
+		if certs[i].CheckSignatureFrom(certs[i+1]) != nil {
+			issues |= chainIssueIncorrectOrder
+			break
+		}
+	}
+	if last := certs[len(certs)-1]; len(certs) > 1 && last.CheckSignatureFrom(last) == nil {
+		issues |= chainIssueSelfSignedRoot
+	}
+	return issues
+}
+
+// localChainCert returns the API representation of a served certificate
+func localChainCert(cert *x509.Certificate, now time.Time) ChainCert {
+	key := localKey(cert)
+	c := ChainCert{
+		Subject:       cert.Subject.String(),
+		Label:         certLabel(cert.Subject.CommonName, cert.Subject.Organization),
+		NotBefore:     cert.NotBefore.UnixMilli(),
+		NotAfter:      cert.NotAfter.UnixMilli(),
+		IssuerSubject: cert.Issuer.String(),
+		IssuerLabel:   certLabel(cert.Issuer.CommonName, cert.Issuer.Organization),
+		SigAlg:        cert.SignatureAlgorithm.String(),
+		KeyAlg:        key.Alg,
+		KeySize:       key.Size,
+		KeyStrength:   key.Strength,
+		Raw:           string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})),
+	}
+	if now.Before(cert.NotBefore) {
+		c.Issues |= chainCertIssueNotYetValid
+	}
+	if now.After(cert.NotAfter) {
+		c.Issues |= chainCertIssueExpired
+	}
+	if key.Strength < 2048 {
+		c.Issues |= chainCertIssueWeakKey
+	}
+	return c
+}
+
+// localKey returns the public key of a certificate with its RSA-equivalent strength
+func localKey(cert *x509.Certificate) *Key {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		size := key.N.BitLen()
+		return &Key{Alg: "RSA", Size: size, Strength: size}
+	case *ecdsa.PublicKey:
+		size := key.Curve.Params().BitSize
+		// Equivalencias de NIST SP 800-57
+		strength := map[int]int{256: 3072, 384: 7680, 521: 15360}[size]
+		if strength == 0 {
+			strength = size * 12
+		}
+		return &Key{Alg: "EC", Size: size, Strength: strength}
+	case ed25519.PublicKey:
+		return &Key{Alg: "Ed25519", Size: 256, Strength: 3072}
+	default:
+		return &Key{Alg: cert.PublicKeyAlgorithm.String()}
+	}
+}
+
+// compareAddrs orders addresses like store.CompareIPs: IPv4 before IPv6
+func compareAddrs(a, b netip.Addr) int {
+	return store.CompareIPs(a.String(), b.String())
+}
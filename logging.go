@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// logFlags holds the flags that configure structured logging
+type logFlags struct {
+	level  *string
+	format *string
+}
+
+// addLogFlags registers the logging flags on fs
+func addLogFlags(fs *flag.FlagSet) *logFlags {
+	return &logFlags{
+		level:  fs.String("log-level", "warn", "nivel de log: debug, info, warn o error"),
+		format: fs.String("log-format", "text", "formato de los logs en stderr: text o json"),
+	}
+}
+
+// logger builds the logger writing to w and installs it as the slog default
+func (f *logFlags) logger(w io.Writer) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(*f.level)); err != nil {
+		return nil, fmt.Errorf("nivel de log inválido: %q (valores posibles: debug, info, warn, error)", *f.level)
+	}
+
+	handlerOpts := &slog.HandlerOptions{
+		Level: level,
+		// Registrar la hora en la zona de --tz
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.TimeKey && len(groups) == 0 {
+				attr.Value = slog.TimeValue(attr.Value.Time().In(outputLocation))
+			}
+			return attr
+		},
+	}
+	var handler slog.Handler
+	switch strings.ToLower(*f.format) {
+	case "text":
+		handler = slog.NewTextHandler(w, handlerOpts)
+	case "json":
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	default:
+		return nil, fmt.Errorf("formato de log inválido: %q (valores posibles: text, json)", *f.format)
+	}
+
+	logger := slog.New(handler)
+	slog.SetDefault(logger)
+	return logger, nil
+}
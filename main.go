@@ -1,22 +1,44 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
+
+	"ssllabs-scanner/store"
 )
 
 // API Constants
 const (
-	// SSL Labs API base URL
-	apiBaseURL = "https://api.ssllabs.com/api/v2"
+	// SSL Labs API root URL (la versión se agrega al final, ej: /v2)
+	apiRootURL = "https://api.ssllabs.com/api"
 	
 	// API Endpoints
-	analyzeEndpoint = "/analyze"
+	analyzeEndpoint      = "/analyze"
+	endpointDataEndpoint = "/getEndpointData"
+	infoEndpoint         = "/info"
+	statusCodesEndpoint  = "/getStatusCodes"
+	registerEndpoint     = "/register"
+)
+
+// Códigos de salida
+const (
+	exitError          = 1 // Error de uso, de red o de la evaluación
+	exitVulnerable     = 2 // Algún endpoint es vulnerable (--fail-on-vuln)
+	exitExpiryWarning  = 3 // Certificado dentro de --warn-expiry-days
+	exitExpiryCritical = 4 // Certificado dentro de --crit-expiry-days o expirado
+	exitBelowMinGrade  = 5 // Grade general por debajo de --min-grade
+	exitPolicyFailed   = 6 // Alguna regla de --policy no se cumple
+	exitInterrupted    = 130 // Interrumpido con SIGINT/SIGTERM (128 + SIGINT)
 )
 
 // Constantes para estados de evaluación
@@ -40,6 +62,7 @@ type Host struct {
 	EngineVersion  string     `json:"engineVersion"`
 	CriteriaVersion string    `json:"criteriaVersion"`
 	Endpoints      []Endpoint `json:"endpoints"`      // Lista de endpoints evaluados
+	Certs          []Cert     `json:"certs,omitempty"` // Certificados del host (solo API v3/v4)
 }
 
 // Endpoint represents information about a single endpoint (server)
@@ -59,8 +82,69 @@ type Endpoint struct {
 
 // EndpointDetails contains complete assessment information for an endpoint
 type EndpointDetails struct {
-	Protocols []Protocol `json:"protocols"`      // Protocolos TLS soportados
-	Cert      *Cert      `json:"cert,omitempty"` // Información del certificado
+	HostStartTime int64       `json:"hostStartTime"`
+	Protocols     []Protocol  `json:"protocols"`            // Protocolos TLS soportados
+	Cert          *Cert       `json:"cert,omitempty"`       // Información del certificado (API v2)
+	CertChains    []CertChain `json:"certChains,omitempty"` // Cadenas de certificados (API v3/v4)
+	Key           *Key        `json:"key,omitempty"`        // Clave del servidor (API v2)
+	Chain         *Chain      `json:"chain,omitempty"`      // Cadena enviada por el servidor (API v2)
+	Suites        SuitesList  `json:"suites,omitempty"`     // Cipher suites soportadas
+
+	ServerSignature    string `json:"serverSignature"`
+	SNIRequired        bool   `json:"sniRequired"`
+	HTTPStatusCode     int    `json:"httpStatusCode"`
+	RenegSupport       int    `json:"renegSupport"`       // Bits de soporte de renegociación
+	SessionResumption  int    `json:"sessionResumption"`  // 0: deshabilitada, 1: IDs sin reanudar, 2: habilitada
+	SessionTickets     int    `json:"sessionTickets"`     // bit 0: soportados
+	CompressionMethods int    `json:"compressionMethods"` // bit 0: DEFLATE
+	SupportsNPN        bool   `json:"supportsNpn"`
+	NPNProtocols       string `json:"npnProtocols"`
+	SupportsALPN       bool   `json:"supportsAlpn"`
+	ALPNProtocols      string `json:"alpnProtocols"`
+	OCSPStapling       bool   `json:"ocspStapling"`
+	HasSCT             int    `json:"hasSct"`         // Bits: certificado, OCSP, extensión TLS
+	ForwardSecrecy     int    `json:"forwardSecrecy"` // Bits de soporte de Forward Secrecy
+	SupportsRC4        bool   `json:"supportsRc4"`
+	RC4WithModern      bool   `json:"rc4WithModern"`
+	RC4Only            bool   `json:"rc4Only"`
+	FallbackSCSV       bool   `json:"fallbackScsv"`
+	DHUsesKnownPrimes  int    `json:"dhUsesKnownPrimes"` // 0: no, 1: sí, 2: sí y son débiles
+	DHYsReuse          bool   `json:"dhYsReuse"`
+
+	// Vulnerabilidades conocidas
+	VulnBeast               bool `json:"vulnBeast"`
+	Heartbleed              bool `json:"heartbleed"`
+	Heartbeat               bool `json:"heartbeat"`
+	OpenSSLCCS              int  `json:"openSslCcs"`          // CVE-2014-0224: 3 = vulnerable
+	OpenSSLLuckyMinus20     int  `json:"openSSLLuckyMinus20"` // CVE-2016-2107: 2 = vulnerable
+	Poodle                  bool `json:"poodle"`
+	PoodleTLS               int  `json:"poodleTls"` // 2 = vulnerable
+	Freak                   bool `json:"freak"`
+	Logjam                  bool `json:"logjam"`
+	DrownVulnerable         bool `json:"drownVulnerable"`
+	Ticketbleed             int  `json:"ticketbleed"`             // 2 = vulnerable
+	Bleichenbacher          int  `json:"bleichenbacher"`          // ROBOT: 2 o 3 = vulnerable
+	ZombiePoodle            int  `json:"zombiePoodle"`            // 2 o 3 = vulnerable
+	GoldenDoodle            int  `json:"goldenDoodle"`            // 4 o 5 = vulnerable
+	ZeroLengthPaddingOracle int  `json:"zeroLengthPaddingOracle"` // 6 o 7 = vulnerable
+	SleepingPoodle          int  `json:"sleepingPoodle"`          // 10 u 11 = vulnerable
+
+	// Políticas HTTP
+	HSTSPolicy   *HSTSPolicy   `json:"hstsPolicy,omitempty"`
+	HSTSPreloads []HSTSPreload `json:"hstsPreloads,omitempty"`
+	HPKPPolicy   *HPKPPolicy   `json:"hpkpPolicy,omitempty"`
+	HPKPRoPolicy *HPKPPolicy   `json:"hpkpRoPolicy,omitempty"`
+
+	// Simulaciones de handshake de clientes comunes
+	Sims *SimDetails `json:"sims,omitempty"`
+
+	// Certificados que el servidor envía además de Cert según las suites del
+	// cliente (ej: ECDSA además de RSA), cada uno con su cadena
+	AltCerts []ServedCert `json:"altCerts,omitempty"`
+
+	// No viene de la API: los details los generó el scanner local, que no
+	// prueba vulnerabilidades ni políticas HTTP
+	Local bool `json:"local,omitempty"`
 }
 
 // Protocol represents a TLS/SSL protocol version
@@ -72,9 +156,51 @@ type Protocol struct {
 
 // Cert represents certificate information
 type Cert struct {
-	IssuerLabel string `json:"issuerLabel"` // Nombre del emisor (ej: "Let's Encrypt")
-	NotBefore   int64  `json:"notBefore"`   // Timestamp: válido desde
-	NotAfter    int64  `json:"notAfter"`    // Timestamp: válido hasta
+	ID               string   `json:"id,omitempty"` // Identificador del certificado (API v3/v4)
+	Subject          string   `json:"subject"`
+	SerialNumber     string   `json:"serialNumber,omitempty"`
+	CommonNames      []string `json:"commonNames"`
+	AltNames         []string `json:"altNames"`
+	IssuerSubject    string   `json:"issuerSubject"`
+	IssuerLabel      string   `json:"issuerLabel"` // Nombre del emisor (ej: "Let's Encrypt"), solo API v2
+	NotBefore        int64    `json:"notBefore"`   // Timestamp: válido desde
+	NotAfter         int64    `json:"notAfter"`    // Timestamp: válido hasta
+	SigAlg           string   `json:"sigAlg"`
+	RevocationInfo   int      `json:"revocationInfo"` // bit 0: CRL, bit 1: OCSP
+	CRLURIs          []string `json:"crlURIs"`
+	OCSPURIs         []string `json:"ocspURIs"`
+	RevocationStatus int      `json:"revocationStatus"` // 1: revocado, 2: no revocado
+	ValidationType   string   `json:"validationType"`   // "E" para certificados EV
+	Issues           int      `json:"issues"`           // Bits de problemas del certificado
+	SCT              bool     `json:"sct"`              // Contiene SCT embebido
+	MustStaple       bool     `json:"mustStaple"`
+	SHA256Hash       string   `json:"sha256Hash,omitempty"`
+	KeyAlg           string   `json:"keyAlg,omitempty"` // Solo API v3/v4 (en v2 ver EndpointDetails.Key)
+	KeySize          int      `json:"keySize,omitempty"`
+	KeyStrength      int      `json:"keyStrength,omitempty"`
+	Raw              string   `json:"raw,omitempty"` // Certificado en formato PEM
+}
+
+// CertChain represents a certificate chain served by an endpoint (API v3/v4)
+type CertChain struct {
+	ID      string   `json:"id"`
+	CertIDs []string `json:"certIds"` // IDs de Host.Certs, el primero es el certificado del servidor
+	Issues  int      `json:"issues"`  // Bits de problemas de la cadena (igual que Chain.Issues)
+	NoSNI   bool     `json:"noSni"`   // Solo se obtiene sin SNI: no es la que ven los clientes
+	TrustPaths []TrustPath `json:"trustPaths,omitempty"`
+}
+
+// TrustPath is a path from a certificate chain to a trusted root (API v3/v4)
+type TrustPath struct {
+	CertIDs []string `json:"certIds"`
+	Trust   []Trust  `json:"trust"` // Resultado en cada almacén de confianza
+}
+
+// Trust is the result of validating a chain against one root store
+type Trust struct {
+	RootStore         string `json:"rootStore"` // Mozilla, Apple, Android, Java, Windows o un bundle local
+	IsTrusted         bool   `json:"isTrusted"`
+	TrustErrorMessage string `json:"trustErrorMessage,omitempty"`
 }
 
 // ErrorResponse represents an error response from the API
@@ -82,10 +208,13 @@ type ErrorResponse struct {
 	Errors []APIError `json:"errors"`
 }
 
-// APIError represents a single error from the API
+// APIError represents an error response from the API. For 400 responses
+// Field and Message hold the first error reported in the body. It matches
+// ErrRateLimited or ErrServiceUnavailable with errors.Is when applicable.
 type APIError struct {
-	Field   string `json:"field"`
-	Message string `json:"message"`
+	StatusCode int    `json:"-"`
+	Field      string `json:"field"`
+	Message    string `json:"message"`
 }
 
 // validateDomain performs basic validation on the domain input
@@ -105,133 +234,459 @@ func validateDomain(domain string) error {
 	return nil
 }
 
-// buildAnalyzeURL constructs the URL for the /analyze endpoint with the given parameters
-// Parameters:
-//   - host: domain to evaluate (required)
-//   - publish: "on" to publish results, "off" (default) to keep private
-//   - startNew: "on" to start new assessment (only on first call), omit on subsequent calls
-//   - all: "done" to get full information when ready
-func buildAnalyzeURL(host string, publish bool, startNew bool, allDone bool) string {
-	url := fmt.Sprintf("%s%s?host=%s", apiBaseURL, analyzeEndpoint, host)
+// AnalyzeParams holds the query parameters of the /analyze endpoint
+type AnalyzeParams struct {
+	Publish        bool   // publish=on publica los resultados en los boards de SSL Labs (por defecto off)
+	StartNew       bool   // startNew=on inicia una evaluación nueva (solo en la primera llamada)
+	FromCache      bool   // fromCache=on devuelve un resultado en cache si existe (incompatible con StartNew)
+	MaxAge         int    // maxAge: antigüedad máxima en horas del resultado en cache (solo con FromCache)
+	All            string // all: "on" o "done" para obtener toda la información (vacío = omitido)
+	IgnoreMismatch bool   // ignoreMismatch=on continúa aunque el certificado no coincida con el host
+}
+
+// buildAnalyzeURL constructs the URL for the /analyze endpoint with the given parameters.
+// baseURL is the API base URL including the version (see apiBaseURL). The query
+// is built with url.Values, so hosts with special characters are escaped.
+// A trailing slash in baseURL is ignored.
+func buildAnalyzeURL(baseURL string, host string, params AnalyzeParams) (string, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/") + analyzeEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("URL base inválida %q: %w", baseURL, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("URL base inválida %q: se espera una URL absoluta", baseURL)
+	}
 	
-	if publish {
-		url += "&publish=on"
+	query := url.Values{}
+	query.Set("host", host)
+	if params.Publish {
+		query.Set("publish", "on")
 	} else {
-		url += "&publish=off"
+		query.Set("publish", "off")
 	}
-	
-	if startNew {
-		url += "&startNew=on"
+	if params.StartNew {
+		query.Set("startNew", "on")
 	}
-	
-	if allDone {
-		url += "&all=done"
+	if params.FromCache {
+		query.Set("fromCache", "on")
+		if params.MaxAge > 0 {
+			query.Set("maxAge", strconv.Itoa(params.MaxAge))
+		}
+	}
+	if params.All != "" {
+		query.Set("all", params.All)
+	}
+	if params.IgnoreMismatch {
+		query.Set("ignoreMismatch", "on")
 	}
 	
-	return url
+	u.RawQuery = query.Encode()
+	return u.String(), nil
 }
 
-// HTTPClient wraps HTTP operations for SSL Labs API
+// HTTPClient wraps HTTP operations for SSL Labs API.
+//
+// An HTTPClient is safe for concurrent use by multiple goroutines: its
+// configuration is read-only after NewHTTPClient, URLs are built per call
+// and the rate limiter shared by all requests is internally synchronized.
+// A single instance should be shared so the rate limit applies globally.
 type HTTPClient struct {
-	client *http.Client
+	client     *http.Client
+	apiVersion int          // Versión de la API (apiVersionV2, apiVersionV3 o apiVersionV4)
+	baseURL    string       // URL base de la API para apiVersion
+	email      string       // Email registrado, enviado en el header "email" (requerido en v4)
+	retry      retryPolicy  // Reintentos ante 429/503/529
+	networkRetry retryPolicy // Reintentos de los GET ante errores de red y 500/502/504
+	logger     *slog.Logger // Logs estructurados (reintentos, peticiones)
+	capacity   *assessmentCapacity // Evaluaciones concurrentes permitidas (X-Max-Assessments)
+	statusCodes *statusCodeCache   // Mensajes de los códigos statusDetails (/getStatusCodes)
+	middleware []Middleware // Hooks de cada petición: email, rate limit, logging y los del usuario
 }
 
-// NewHTTPClient creates a new HTTP client with timeout
-func NewHTTPClient() *HTTPClient {
+// NewHTTPClient creates a new HTTP client configured with the given options.
+// Without options it uses API v2, a 30s timeout and one request per second.
+func NewHTTPClient(opts ...Option) *HTTPClient {
+	o := newOptions(opts)
+	
+	// Los mensajes de estado solo se guardan en disco para la API oficial
+	statusCodes := &statusCodeCache{}
+	baseURL := o.baseURL
+	if baseURL == "" {
+		baseURL = apiBaseURL(o.apiVersion)
+		statusCodes.path = defaultStatusCodesPath(o.apiVersion)
+	}
+	
+	// Middlewares internos primero: el rate limit espera antes de que los
+	// hooks del usuario vean la petición y el log mide solo la respuesta
+	var middleware []Middleware
+	if o.email != "" {
+		middleware = append(middleware, headerMiddleware("email", o.email))
+	}
+	if o.limiter != nil {
+		middleware = append(middleware, rateLimitMiddleware(o.limiter))
+	}
+	middleware = append(middleware, loggingMiddleware(o.logger))
+	capacity := newAssessmentCapacity()
+	middleware = append(middleware, capacity.Middleware())
+	middleware = append(middleware, o.middleware...)
+	
+	// Sin proxy explícito se usa http.DefaultTransport, que respeta
+	// HTTP_PROXY, HTTPS_PROXY y NO_PROXY
+	transport := o.transport
+	if o.proxy != nil {
+		transport = proxyTransport(transport, o.proxy)
+	}
+	
 	return &HTTPClient{
 		client: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   o.timeout,
+			Transport: transport,
 		},
+		apiVersion: o.apiVersion,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		email:      o.email,
+		retry:      o.retry,
+		networkRetry: o.networkRetry,
+		logger:     o.logger,
+		capacity:   capacity,
+		statusCodes: statusCodes,
+		middleware: middleware,
 	}
 }
 
 // Get performs a GET request to the SSL Labs API
 // Returns the response body and handles HTTP status codes
 func (c *HTTPClient) Get(url string) ([]byte, error) {
-	resp, err := c.client.Get(url)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("error de conexión: %w", err)
+		return nil, fmt.Errorf(tr("error creando petición: %w"), err)
+	}
+	
+	return c.do(req)
+}
+
+// do sends the request, retrying with backoff while the API answers
+// 429, 503 or 529 and, for GET requests, after transient network failures,
+// truncated bodies and 500, 502 or 504, and maps the HTTP status codes to errors
+func (c *HTTPClient) do(req *http.Request) ([]byte, error) {
+	var body []byte
+	err := c.doStream(req, func(r io.Reader) error {
+		var err error
+		body, err = io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf(tr("error leyendo respuesta: %w"), err)
+		}
+		return nil
+	})
+	return body, err
+}
+
+// doStream is like do, but hands the body of a successful response to
+// decode as it is read instead of buffering it
+func (c *HTTPClient) doStream(req *http.Request, decode func(io.Reader) error) error {
+	// Cada política cuenta sus propios reintentos
+	var throttled, failed int
+	for {
+		var wait time.Duration
+		resp, err := c.send(req)
+		if err != nil {
+			if !isTransientError(err) || !c.canRetryNetwork(req, failed) {
+				return err
+			}
+			wait = c.networkRetry.delay(failed, "", time.Now())
+			failed++
+			c.logger.Warn("error de red transitorio, reintentando", "error", err,
+				"wait", wait.Round(time.Millisecond), "attempt", failed, "max_retries", c.networkRetry.maxRetries)
+		} else if resp.StatusCode == http.StatusOK {
+			// Un cuerpo cortado a mitad de la lectura es un fallo de red más
+			err := decode(resp.Body)
+			resp.Body.Close()
+			if err == nil || !isTransientError(err) || !c.canRetryNetwork(req, failed) {
+				return err
+			}
+			wait = c.networkRetry.delay(failed, "", time.Now())
+			failed++
+			c.logger.Warn("respuesta incompleta, reintentando", "error", err,
+				"wait", wait.Round(time.Millisecond), "attempt", failed, "max_retries", c.networkRetry.maxRetries)
+		} else {
+			// Las respuestas de error son pequeñas: leerlas completas
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return fmt.Errorf(tr("error leyendo respuesta: %w"), err)
+			}
+			
+			switch {
+			case isRetryableStatus(resp.StatusCode) && throttled < c.retry.maxRetries:
+				wait = c.retry.delay(throttled, resp.Header.Get("Retry-After"), time.Now())
+				throttled++
+				c.logger.Warn("la API está limitando las peticiones, reintentando", "status", resp.StatusCode,
+					"wait", wait.Round(time.Second), "attempt", throttled, "max_retries", c.retry.maxRetries)
+			case isTransientStatus(resp.StatusCode) && c.canRetryNetwork(req, failed):
+				wait = c.networkRetry.delay(failed, "", time.Now())
+				failed++
+				c.logger.Warn("error transitorio del servidor, reintentando", "status", resp.StatusCode,
+					"wait", wait.Round(time.Millisecond), "attempt", failed, "max_retries", c.networkRetry.maxRetries)
+			default:
+				_, err := checkStatus(resp.StatusCode, body)
+				return err
+			}
+		}
+		
+		if err := sleepContext(req.Context(), wait); err != nil {
+			return err
+		}
+		req, err = rewindRequest(req)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// canRetryNetwork reports whether req can be retried after a transient
+// failure: only GET requests, which are idempotent, while the context is
+// alive and retries remain
+func (c *HTTPClient) canRetryNetwork(req *http.Request, failed int) bool {
+	return req.Method == http.MethodGet && req.Context().Err() == nil && failed < c.networkRetry.maxRetries
+}
+
+// send sends a single request through the middleware chain. The caller
+// must close the response body.
+func (c *HTTPClient) send(req *http.Request) (*http.Response, error) {
+	if err := runBefore(c.middleware, req); err != nil {
+		return nil, err
 	}
-	defer resp.Body.Close()
 	
-	body, err := io.ReadAll(resp.Body)
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	runAfter(c.middleware, req, resp, err, time.Since(start))
 	if err != nil {
-		return nil, fmt.Errorf("error leyendo respuesta: %w", err)
+		if errors.Is(err, context.Canceled) {
+			return nil, ErrInterrupted
+		}
+		return nil, fmt.Errorf(tr("error de conexión: %w"), err)
+	}
+	return resp, nil
+}
+
+// checkStatus maps the HTTP status code of a response to an *APIError
+func checkStatus(status int, body []byte) ([]byte, error) {
+	if status == http.StatusOK {
+		return body, nil
 	}
 	
+	apiErr := &APIError{StatusCode: status}
+	if status == http.StatusBadRequest {
+		// Intentar parsear error de la API
+		var resp ErrorResponse
+		if json.Unmarshal(body, &resp) == nil && len(resp.Errors) > 0 {
+			apiErr.Field = resp.Errors[0].Field
+			apiErr.Message = resp.Errors[0].Message
+		}
+	}
+	return nil, apiErr
+}
+
+// Error describes the HTTP status code of the response
+func (e *APIError) Error() string {
 	// Manejo de códigos HTTP esenciales
-	switch resp.StatusCode {
-	case http.StatusOK:
-		return body, nil
+	switch e.StatusCode {
 	case http.StatusBadRequest:
-		// Intentar parsear error de la API
-		var apiErr ErrorResponse
-		if json.Unmarshal(body, &apiErr) == nil && len(apiErr.Errors) > 0 {
-			return nil, fmt.Errorf("error de la API (400): %s - %s", 
-				apiErr.Errors[0].Field, apiErr.Errors[0].Message)
+		if e.Field != "" || e.Message != "" {
+			return fmt.Sprintf(tr("error de la API (400): %s - %s"), e.Field, e.Message)
 		}
-		return nil, fmt.Errorf("error de invocación (400): parámetros inválidos")
+		return tr("error de invocación (400): parámetros inválidos")
 	case http.StatusTooManyRequests:
-		return nil, fmt.Errorf("rate limit excedido (429): por favor espera antes de reintentar")
+		return tr("rate limit excedido (429): por favor espera antes de reintentar")
 	case http.StatusInternalServerError:
-		return nil, fmt.Errorf("error interno del servidor (500): por favor intenta más tarde")
+		return tr("error interno del servidor (500): por favor intenta más tarde")
 	case http.StatusServiceUnavailable:
-		return nil, fmt.Errorf("servicio no disponible (503): por favor intenta más tarde")
+		return tr("servicio no disponible (503): por favor intenta más tarde")
 	case 529: // Service overloaded
-		return nil, fmt.Errorf("servicio sobrecargado (529): por favor intenta más tarde")
+		return tr("servicio sobrecargado (529): por favor intenta más tarde")
 	default:
-		return nil, fmt.Errorf("código HTTP inesperado: %d", resp.StatusCode)
+		return fmt.Sprintf(tr("código HTTP inesperado: %d"), e.StatusCode)
 	}
 }
 
+// Unwrap returns the sentinel error matching the status code, if any
+func (e *APIError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusServiceUnavailable, 529:
+		return ErrServiceUnavailable
+	default:
+		return nil
+	}
+}
+
+// AssessmentLimits returns the maximum and current number of concurrent
+// assessments last reported by the API for this client (0 if unknown)
+func (c *HTTPClient) AssessmentLimits() (maxAssessments, current int) {
+	return c.capacity.Limits()
+}
+
 // Analyze initiates or checks the status of an SSL assessment
-func (c *HTTPClient) Analyze(host string, publish bool, startNew bool, allDone bool) (*Host, error) {
-	url := buildAnalyzeURL(host, publish, startNew, allDone)
+func (c *HTTPClient) Analyze(host string, params AnalyzeParams) (*Host, error) {
+	return c.AnalyzeContext(context.Background(), host, params)
+}
+
+// AnalyzeContext is like Analyze but the request is cancelled with ctx
+func (c *HTTPClient) AnalyzeContext(ctx context.Context, host string, params AnalyzeParams) (*Host, error) {
+	analyzeURL, err := buildAnalyzeURL(c.baseURL, host, params)
+	if err != nil {
+		return nil, err
+	}
 	
-	body, err := c.Get(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, analyzeURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf(tr("error creando petición: %w"), err)
+	}
+	
+	// Las respuestas con details pueden pesar varios MB: decodificarlas a medida que llegan
+	var hostResp *Host
+	err = c.doStream(req, func(r io.Reader) error {
+		var err error
+		hostResp, err = decodeHost(r)
+		if err != nil {
+			return fmt.Errorf(tr("error parseando respuesta JSON: %w"), err)
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 	
-	var hostResp Host
-	if err := json.Unmarshal(body, &hostResp); err != nil {
-		return nil, fmt.Errorf("error parseando respuesta JSON: %w", err)
+	// Las APIs v3/v4 devuelven los certificados a nivel de host
+	if c.apiVersion >= 3 {
+		normalizeCerts(hostResp)
 	}
 	
-	return &hostResp, nil
+	return hostResp, nil
+}
+
+// PollOptions controls how PollAssessment starts and follows an assessment
+type PollOptions struct {
+	Timeout            time.Duration // Duración máxima de la evaluación (0 = 10 minutos)
+	Interval           time.Duration // Espera entre consultas hasta IN_PROGRESS (0 = 5 segundos)
+	InProgressInterval time.Duration // Espera entre consultas en IN_PROGRESS (0 = 10 segundos)
+	FromCache          bool          // Usar un resultado en cache de SSL Labs en vez de forzar una evaluación nueva
+	MaxAge             time.Duration // Antigüedad máxima del resultado en cache (0 = la que decida la API)
+	Reuse              bool          // No enviar startNew: seguir la evaluación en curso o la última terminada
+	Reporter           *Reporter     // Dónde mostrar el progreso (nil = sin salida)
+	Progressive        bool          // Consultar con all=on y mostrar protocolos y certificados a medida que llegan
+	DetailsTimeout     time.Duration // Espera máxima por los details de un endpoint Ready (0 = 2 minutos)
+	IgnoreMismatch     bool          // Evaluar aunque el certificado no coincida con el host (ignoreMismatch=on)
+	Publish            bool          // Publicar el resultado en los tableros públicos de SSL Labs (publish=on)
+}
+
+// params returns the /analyze parameters for a call of the polling loop.
+// startNew is only sent on the first call and never together with fromCache.
+// In progressive mode the status polls use all=on.
+func (o PollOptions) params(first bool, all string) AnalyzeParams {
+	if all == "" && o.Progressive {
+		all = "on"
+	}
+	params := AnalyzeParams{All: all, IgnoreMismatch: o.IgnoreMismatch, Publish: o.Publish}
+	if o.FromCache {
+		params.FromCache = true
+		if o.MaxAge > 0 {
+			// maxAge se expresa en horas; redondear hacia arriba
+			params.MaxAge = int((o.MaxAge + time.Hour - 1) / time.Hour)
+		}
+	} else if first && !o.Reuse {
+		params.StartNew = true
+	}
+	return params
 }
 
 // PollAssessment performs polling until the assessment is complete
 // Uses variable polling intervals as recommended by SSL Labs:
 // - 5 seconds until status becomes IN_PROGRESS
 // - 10 seconds after IN_PROGRESS until completion
-func PollAssessment(client *HTTPClient, domain string, maxTimeout time.Duration) (*Host, error) {
+//
+// All polling state is local to the call, so several domains can be polled
+// concurrently with the same client. Progress lines are written with a
+// single call each, so they don't interleave mid-line.
+// Before starting a new assessment, it waits until the client has capacity
+// for it according to the X-Max-Assessments header. Ready endpoints
+// that arrive without details are re-polled one by one (see completeDetails). Cancelling ctx
+// stops the polling (also mid-sleep) with ErrInterrupted. A status this
+// version doesn't know is logged as a warning once each time it appears.
+func PollAssessment(ctx context.Context, client *HTTPClient, domain string, opts PollOptions) (*Host, error) {
+	// Esperar a que haya capacidad para una evaluación nueva (X-Max-Assessments);
+	// las consultas de resultados en cache no inician ninguna
+	params := opts.params(true, "")
+	if params.StartNew {
+		if err := client.capacity.Acquire(ctx); err != nil {
+			return nil, err
+		}
+		defer client.capacity.Release()
+	}
+	
 	startTime := time.Now()
+	maxTimeout := durationOr(opts.Timeout, defaultAssessmentTimeout)
+	interval := durationOr(opts.Interval, defaultPollInterval)
+	inProgressInterval := durationOr(opts.InProgressInterval, defaultInProgressInterval)
 	isFirstCall := true
 	
-	// Primera llamada con startNew=on
-	host, err := client.Analyze(domain, false, true, true)
+	// Primera llamada con startNew=on (o fromCache=on). Mientras la evaluación avanza se hacen
+	// consultas livianas (sin all): la respuesta completa con los details de
+	// cada endpoint se descarga una sola vez, cuando todos están listos.
+	host, err := client.AnalyzeContext(ctx, domain, params)
 	if err != nil {
 		return nil, err
 	}
 	
+	// Los estados desconocidos se siguen consultando, pero quedan en el log
+	// para detectar cambios de la API
+	warnedStatus := ""
+	warnUnknownStatus := func() {
+		if knownStatus(host.Status) || host.Status == warnedStatus {
+			return
+		}
+		warnedStatus = host.Status
+		client.logger.Warn("estado desconocido", "status", host.Status, "domain", domain, "message", host.StatusMessage)
+	}
+	
 	// Mostrar estado inicial
-	showProgress(host, isFirstCall)
+	warnUnknownStatus()
+	opts.Reporter.Progress(domain, withActivity(progressMessage(host, isFirstCall), client.currentActivity(ctx, host)))
 	isFirstCall = false
 	
+	// Con all=on los details llegan durante la evaluación: mostrar lo nuevo en cada consulta
+	seen := make(map[string]bool)
+	showFindings := func() {
+		if !opts.Progressive {
+			return
+		}
+		for _, finding := range partialFindings(host, seen) {
+			opts.Reporter.Printf("%s\n", finding)
+		}
+	}
+	showFindings()
+	
 	// Ciclo de polling
 	for {
 		// Verificar timeout
 		if time.Since(startTime) > maxTimeout {
-			return nil, fmt.Errorf("timeout: la evaluación tomó más de %v", maxTimeout)
+			return nil, fmt.Errorf(tr("%w: la evaluación tomó más de %v (último estado: %s)"), ErrTimeout, maxTimeout, describeStatus(host.Status))
 		}
 		
 		// Verificar si está completo o hay error
 		if host.Status == statusReady {
-			return host, nil
+			// Con all=on la respuesta READY ya trae los details disponibles
+			if !opts.Progressive {
+				host, err = client.AnalyzeContext(ctx, domain, opts.params(false, "done"))
+				if err != nil {
+					return nil, err
+				}
+			}
+			return completeDetails(ctx, client, domain, host, opts)
 		}
 		if host.Status == statusError {
-			return nil, fmt.Errorf("error en la evaluación: %s", host.StatusMessage)
+			return nil, fmt.Errorf("%w: %s", ErrAssessmentFailed, host.StatusMessage)
 		}
 		
 		// Verificar si todos los endpoints están listos (statusMessage == "Ready")
@@ -239,7 +694,6 @@ func PollAssessment(client *HTTPClient, domain string, maxTimeout time.Duration)
 		if len(host.Endpoints) > 0 {
 			endpointsWithProgress := 0
 			endpointsReady := 0
-			endpointsWithDetails := 0
 			
 			for _, endpoint := range host.Endpoints {
 				// Solo contar endpoints que han iniciado (progress >= 0)
@@ -248,71 +702,80 @@ func PollAssessment(client *HTTPClient, domain string, maxTimeout time.Duration)
 					
 					if endpoint.StatusMessage == "Ready" {
 						endpointsReady++
-						
-						if endpoint.Details != nil {
-							endpointsWithDetails++
-						}
 					}
 				}
 			}
 			
-			// Si todos los endpoints están Ready y todos tienen details, está completo
-			if endpointsWithProgress > 0 && 
-			   endpointsReady == endpointsWithProgress && 
-			   endpointsWithDetails == endpointsWithProgress {
-				return host, nil
-			}
-			
-			// Si todos están Ready pero algunos no tienen details, esperar un poco más
+			// Si todos están Ready, descargar los details una vez y pedir
+			// solo los de los endpoints que aún no los tengan
 			if endpointsWithProgress > 0 && endpointsReady == endpointsWithProgress {
-				// Si todos tienen details, retornar inmediatamente
-				if endpointsWithDetails == endpointsWithProgress {
-					return host, nil
-				}
-				// Si algunos tienen details, esperar un poco más y retornar
-				if endpointsWithDetails > 0 {
-					time.Sleep(10 * time.Second)
-					host, err = client.Analyze(domain, false, false, true)
-					if err != nil {
-						return nil, err
-					}
-					// Retornar (ProcessResults procesará solo los que tienen details)
-					return host, nil
+				full, err := client.AnalyzeContext(ctx, domain, opts.params(false, "done"))
+				if err != nil {
+					return nil, err
 				}
-				// Si ninguno tiene details, continuar con el polling normal
-				// (no retornar todavía, esperar a que lleguen los details)
+				return completeDetails(ctx, client, domain, full, opts)
 			}
 		}
 		
-		// Determinar intervalo de espera según el estado (polling variable)
+		// Determinar intervalo de espera según el estado (polling variable).
+		// Los estados desconocidos usan el intervalo por defecto.
 		var sleepDuration time.Duration
 		if host.Status == statusDNS {
-			sleepDuration = 5 * time.Second
+			sleepDuration = interval
 		} else if host.Status == statusInProgress {
-			sleepDuration = 10 * time.Second
+			sleepDuration = inProgressInterval
 		} else {
-			sleepDuration = 5 * time.Second
+			sleepDuration = interval
 		}
 		
 		// Esperar antes de la siguiente consulta
-		time.Sleep(sleepDuration)
+		if err := sleepContext(ctx, sleepDuration); err != nil {
+			return nil, err
+		}
 		
 		// Consultar estado nuevamente (SIN startNew, solo en la primera llamada)
-		host, err = client.Analyze(domain, false, false, true)
+		host, err = client.AnalyzeContext(ctx, domain, opts.params(false, ""))
 		if err != nil {
 			return nil, err
 		}
 		
 		// Mostrar progreso
-		showProgress(host, isFirstCall)
+		warnUnknownStatus()
+		opts.Reporter.Progress(domain, withActivity(progressMessage(host, isFirstCall), client.currentActivity(ctx, host)))
+		showFindings()
+	}
+}
+
+// knownStatus reports whether status is one of the statuses of the API
+// this version handles. A missing status counts as known: the first
+// response may not have one yet.
+func knownStatus(status string) bool {
+	switch status {
+	case statusDNS, statusInProgress, statusReady, statusError, "":
+		return true
 	}
+	return false
 }
 
-// showProgress displays progress information to the user
-func showProgress(host *Host, isFirstCall bool) {
+// describeStatus returns a status for messages, marking the ones this
+// version doesn't know
+func describeStatus(status string) string {
+	switch {
+	case status == "":
+		return tr("sin estado")
+	case knownStatus(status):
+		return status
+	default:
+		return status + tr(" (desconocido)")
+	}
+}
+
+// progressMessage describes the progress of an assessment for the Reporter.
+// It returns an empty string when there is nothing new to show.
+func progressMessage(host *Host, isFirstCall bool) string {
 	switch host.Status {
 	case statusDNS:
-		fmt.Println("Resolviendo DNS...")
+		return tr("Resolviendo DNS...")
 	case statusInProgress:
 		// Mostrar progreso si está disponible en los endpoints
 		if len(host.Endpoints) > 0 && host.Endpoints[0].Progress >= 0 {
@@ -338,35 +801,43 @@ func showProgress(host *Host, isFirstCall bool) {
 					if endpointsWithDetails < endpointsReady {
 						// Algunos endpoints están listos pero esperando detalles
 						if endpointsWithDetails > 0 {
-							fmt.Printf("Esperando detalles de seguridad TLS... (%d/%d endpoints con detalles completos)\n", 
+							return fmt.Sprintf(tr("Esperando detalles de seguridad TLS... (%d/%d endpoints con detalles completos)"), 
 								endpointsWithDetails, endpointsReady)
 						} else {
-							fmt.Printf("Esperando detalles de seguridad TLS... (%d endpoints listos, esperando detalles)\n", 
+							return fmt.Sprintf(tr("Esperando detalles de seguridad TLS... (%d endpoints listos, esperando detalles)"), 
 								endpointsReady)
 						}
 					} else {
 						// Todos los endpoints Ready tienen details
-						fmt.Println("Finalizando evaluación...")
+						return tr("Finalizando evaluación...")
 					}
 				} else {
 					// En 100% pero aún no todos están listos
-					fmt.Printf("Esperando que finalice la evaluación... (%d endpoints en progreso)\n", totalEndpoints)
+					return fmt.Sprintf(tr("Esperando que finalice la evaluación... (%d endpoints en progreso)"), totalEndpoints)
 				}
 			} else {
-				fmt.Printf("Evaluando seguridad TLS... (%d%%)\n", progress)
+				return fmt.Sprintf(tr("Evaluando seguridad TLS... (%d%%)"), progress)
 			}
 		} else {
-			fmt.Println("Evaluando seguridad TLS...")
+			return tr("Evaluando seguridad TLS...")
 		}
 	case statusReady:
-		fmt.Println("Evaluación completada.")
+		return tr("Evaluación completada.")
 	case statusError:
 		// El error se manejará en el polling
-	default:
+	case "":
 		if isFirstCall {
-			fmt.Println("Iniciando evaluación...")
+			return tr("Iniciando evaluación...")
 		}
+	default:
+		// Estado que esta versión no conoce: informarlo y seguir consultando
+		message := fmt.Sprintf(tr("Estado desconocido de la API: %s"), host.Status)
+		if host.StatusMessage != "" {
+			message += " (" + host.StatusMessage + ")"
+		}
+		return message + tr(", se sigue consultando...")
 	}
+	return ""
 }
 
 // AssessmentResult contiene la información procesada de seguridad TLS
@@ -374,6 +845,16 @@ type AssessmentResult struct {
 	Domain          string
 	Endpoints       []EndpointResult
 	OverallGrade    string // El peor grade si hay múltiples endpoints
+	TestTime        int64  // Timestamp de finalización de la evaluación (milisegundos)
+	EndpointErrors  []EndpointError // Endpoints que SSL Labs no pudo evaluar
+	Metadata        store.Metadata  // Procedencia del resultado (motor, criterios, fechas, fuente)
+	Host            *Host           // Respuesta de la API sin procesar (--raw)
+	Headers         *HeaderAudit    // Headers de seguridad HTTP del sitio (--audit-headers)
+	HSTSPreload     *HSTSPreloadStatus // Estado en la lista de preload de HSTS (--hsts)
+	CT              *CTLookup       // Certificados emitidos recientemente según los logs de CT (--ct)
+	CAA             *CAACheck       // Registros CAA y si autorizan a la CA de los certificados (--caa)
+	DNSSEC          *DNSSECCheck    // Cadena de confianza DNSSEC del dominio (--dnssec)
+	DANE            *DANECheck      // Registros TLSA y si coinciden con los certificados servidos (--dane)
 }
 
 // EndpointResult contiene la información de seguridad TLS de un endpoint
@@ -381,22 +862,44 @@ type EndpointResult struct {
 	IPAddress      string
 	Grade          string
 	TLSProtocols   []string
+	ProtocolStatus ProtocolStatus   // Distingue "sin protocolos seguros" de "sin datos"
 	CertIssuer     string
 	CertValidFrom  int64
 	CertValidTo    int64
+	CertDaysRemaining int           // Días hasta la expiración del certificado (negativo si expiró)
+	CertFingerprint string          // SHA-256 del certificado del servidor (hex)
+	OtherCerts     []CertSummary    // Certificados adicionales del servidor (ej: ECDSA además de RSA)
+	Vulnerabilities []string        // Ataques TLS conocidos a los que el endpoint es vulnerable
+	ChainIssues    []string         // Problemas del certificado y de la cadena de certificados
+	OCSP           []OCSPProbe      // Consultas a los responders OCSP de la cadena (--probe-ocsp)
+	CRL            []CRLCheck       // Descargas de las CRLs de la cadena (--check-crl)
+	AIA            *AIACheck        // Intermedios faltantes obtenidos por AIA (--check-aia)
+	Issuance       []IssuanceFinding // Requisitos de los root programs para certificados nuevos (--issuance-hygiene)
+	Details        *EndpointDetails // Información completa del endpoint (para --details)
+}
+
+// ProtocolStatus describe qué se sabe de los protocolos TLS de un endpoint
+type ProtocolStatus int
+
+const (
+	ProtocolsSecure     ProtocolStatus = iota // Al menos un protocolo seguro
+	ProtocolsNoneSecure                       // El servidor solo ofrece protocolos inseguros (crítico)
+	ProtocolsUnknown                          // La API no devolvió los protocolos (faltan datos)
+)
+
+// gradeOrder asigna un puntaje a cada grade (mayor es mejor)
+// Orden: A+ > A > A- > B+ > B > B- > C+ > C > C- > D+ > D > D- > E > F > T > M
+var gradeOrder = map[string]int{
+	"A+": 15, "A": 14, "A-": 13,
+	"B+": 12, "B": 11, "B-": 10,
+	"C+": 9, "C": 8, "C-": 7,
+	"D+": 6, "D": 5, "D-": 4,
+	"E": 3, "F": 2, "T": 1, "M": 0,
 }
 
 // compareGrades compara dos grades y retorna -1 si grade1 es peor, 0 si son iguales, 1 si grade1 es mejor
 // Orden: A+ > A > A- > B+ > B > B- > C+ > C > C- > D+ > D > D- > E > F > T > M
 func compareGrades(grade1, grade2 string) int {
-	gradeOrder := map[string]int{
-		"A+": 15, "A": 14, "A-": 13,
-		"B+": 12, "B": 11, "B-": 10,
-		"C+": 9, "C": 8, "C-": 7,
-		"D+": 6, "D": 5, "D-": 4,
-		"E": 3, "F": 2, "T": 1, "M": 0,
-	}
-	
 	score1, ok1 := gradeOrder[grade1]
 	score2, ok2 := gradeOrder[grade2]
 	
@@ -435,37 +938,64 @@ func findWorstGrade(grades []string) string {
 	return worst
 }
 
+// worstEndpoint returns the index of the endpoint that determines the
+// overall grade (the first one with that grade), or -1 when there is a
+// single endpoint or none has the overall grade
+func worstEndpoint(result *AssessmentResult) int {
+	if len(result.Endpoints) < 2 {
+		return -1
+	}
+	for i, endpoint := range result.Endpoints {
+		if endpoint.Grade == result.OverallGrade {
+			return i
+		}
+	}
+	return -1
+}
+
 // ProcessResults extrae y procesa la información de seguridad TLS del host
 func ProcessResults(host *Host) (*AssessmentResult, error) {
 	// No requerimos que el status sea READY porque podemos procesar endpoints
 	// que ya tienen statusMessage "Ready", incluso si el status general es IN_PROGRESS
 	
 	if len(host.Endpoints) == 0 {
-		return nil, fmt.Errorf("no hay endpoints disponibles en la respuesta")
+		return nil, errors.New(tr("no hay endpoints disponibles en la respuesta"))
 	}
 	
 	result := &AssessmentResult{
 		Domain:    host.Host,
 		Endpoints: []EndpointResult{},
+		TestTime:  host.TestTime,
+		Metadata:  metadataFromHost(host),
+		Host:      host,
 	}
 	
 	var allGrades []string
 	
 	// Procesar cada endpoint
 	for _, endpoint := range host.Endpoints {
-		// Solo procesar endpoints que estén listos
-		if endpoint.StatusMessage != "Ready" {
-			continue
-		}
-		
-		// Verificar que details esté presente
-		if endpoint.Details == nil {
+		// Solo procesar endpoints que estén listos; los que fallaron se
+		// informan como errores para no dar por sano un host a medias
+		if endpoint.StatusMessage != endpointStatusReady {
+			if failure := endpointFailure(endpoint, host.Status); failure != nil {
+				result.EndpointErrors = append(result.EndpointErrors, *failure)
+			}
 			continue
 		}
 		
 		endpointResult := EndpointResult{
 			IPAddress: endpoint.IPAddress,
 			Grade:     endpoint.Grade,
+			Details:   endpoint.Details,
+		}
+		
+		// Sin details solo se conoce el grade: se informa como falta de
+		// datos en vez de descartar el endpoint
+		if endpoint.Details == nil {
+			endpointResult.ProtocolStatus = ProtocolsUnknown
+			result.Endpoints = append(result.Endpoints, endpointResult)
+			allGrades = append(allGrades, endpoint.Grade)
+			continue
 		}
 		
 		// Extraer protocolos TLS (Q == nil significa seguro, Q == 0 significa inseguro)
@@ -475,110 +1005,342 @@ func ProcessResults(host *Host) (*AssessmentResult, error) {
 				endpointResult.TLSProtocols = append(endpointResult.TLSProtocols, protocolName)
 			}
 		}
+		switch {
+		case len(endpoint.Details.Protocols) == 0:
+			endpointResult.ProtocolStatus = ProtocolsUnknown
+		case len(endpointResult.TLSProtocols) == 0:
+			endpointResult.ProtocolStatus = ProtocolsNoneSecure
+		}
 		
 		// Extraer información del certificado
 		if endpoint.Details.Cert != nil {
 			endpointResult.CertIssuer = endpoint.Details.Cert.IssuerLabel
 			endpointResult.CertValidFrom = endpoint.Details.Cert.NotBefore
 			endpointResult.CertValidTo = endpoint.Details.Cert.NotAfter
+			endpointResult.CertDaysRemaining = daysUntil(endpoint.Details.Cert.NotAfter, time.Now())
 		}
+		endpointResult.CertFingerprint = certFingerprint(endpoint.Details)
+		endpointResult.OtherCerts = otherCerts(endpoint.Details, time.Now())
+		
+		// Extraer vulnerabilidades conocidas
+		endpointResult.Vulnerabilities = vulnerableNames(endpoint.Details)
+		
+		// Extraer problemas de la cadena de certificados
+		endpointResult.ChainIssues = chainIssues(endpoint.Details)
 		
 		result.Endpoints = append(result.Endpoints, endpointResult)
 		allGrades = append(allGrades, endpoint.Grade)
 	}
 	
 	if len(result.Endpoints) == 0 {
-		// Si no hay endpoints con details, puede que aún no estén listos
-		return nil, fmt.Errorf("no hay endpoints listos con información completa. Status: %s", host.Status)
+		if len(result.EndpointErrors) > 0 {
+			return nil, fmt.Errorf(tr("%w: ningún endpoint pudo evaluarse: %s"), ErrAssessmentFailed, describeEndpointErrors(result.EndpointErrors))
+		}
+		// Si no hay endpoints listos, puede que la evaluación aún no termine
+		return nil, fmt.Errorf(tr("no hay endpoints listos. Status: %s"), host.Status)
 	}
 	
 	// Calcular el peor grade (overall grade)
 	result.OverallGrade = findWorstGrade(allGrades)
 	
+	// El orden de los arrays de la API cambia entre consultas
+	sortResult(result)
+	
 	return result, nil
 }
 
+// HasVulnerabilities reports whether any endpoint is vulnerable to a known TLS attack
+func (r *AssessmentResult) HasVulnerabilities() bool {
+	for _, endpoint := range r.Endpoints {
+		if len(endpoint.Vulnerabilities) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// commands are the subcommands other than scan and batch, which share
+// runScan and have their own exit codes
+var commands = map[string]func([]string) error{
+	"serve":          runServe,
+	"history":        runHistory,
+	"db":             runDB,
+	"findings":       runFindings,
+	"recheck":        runRecheck,
+	"backup":         runBackup,
+	"restore":        runRestore,
+	"diff":           runDiff,
+	"info":           runInfo,
+	"version":        runVersion,
+	"register":       runRegister,
+	"init":           runInit,
+	"config":         runConfig,
+	"truststore":     runTrustStore,
+	"diagnose":       runDiagnose,
+	"verify-deploy":  runVerifyDeploy,
+	"verify-cutover": runVerifyCutover,
+	"selftest":       runSelftest,
+	"self-update":    runSelfUpdate,
+	"release-info":   runReleaseInfo,
+}
+
 func main() {
-	// Punto 3: Validación de entrada CLI
-	if len(os.Args) < 2 {
-		fmt.Fprintf(os.Stderr, "Error: dominio requerido\n")
-		fmt.Fprintf(os.Stderr, "Usage: %s <domain>\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "Ejemplo: %s google.com\n", os.Args[0])
-		os.Exit(1)
+	compat, args := newCLICompat(os.Args[1:])
+	args = compat.rewriteFlags(args)
+	if len(args) == 0 {
+		usage()
+		os.Exit(exitError)
 	}
 	
-	domain := strings.TrimSpace(os.Args[1])
-	
-	// Validar dominio
-	if err := validateDomain(domain); err != nil {
+	command := args[0]
+	switch command {
+	case "-h", "-help", "--help", "help":
+		usage()
+		return
+	case "scan", "batch":
+	default:
+		if _, ok := commands[command]; !ok {
+			// Invocación original sin subcomando: equivale a scan
+			compat.legacyInvocation()
+			command, args = "scan", append([]string{"scan"}, args...)
+		}
+	}
+	if err := compat.check(os.Stderr); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
-		fmt.Fprintf(os.Stderr, "Usage: %s <domain>\n", os.Args[0])
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 	
-	fmt.Printf("SSL Labs Scanner - Verificando seguridad TLS de: %s\n\n", domain)
-	
-	// Punto 4: Cliente HTTP
-	client := NewHTTPClient()
-	
-	// Punto 6: Lógica de polling
-	maxTimeout := 10 * time.Minute
-	host, err := PollAssessment(client, domain, maxTimeout)
-	if err != nil {
+	if command == "scan" || command == "batch" {
+		os.Exit(runScan(command, args[1:]))
+	}
+	if err := commands[command](args[1:]); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
+}
+
+// usage prints the list of subcommands to stderr
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <comando> [flags] [argumentos]\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "Comandos:\n")
+	fmt.Fprintf(os.Stderr, "  scan <domain>...            Evaluar dominios con SSL Labs (o localmente con --air-gapped)\n")
+	fmt.Fprintf(os.Stderr, "  batch <archivo>...          Evaluar los dominios de uno o más archivos, con un resumen al final\n")
+	fmt.Fprintf(os.Stderr, "  serve [domain...]           Exporter de Prometheus, dashboard y API HTTP\n")
+	fmt.Fprintf(os.Stderr, "  history <domain>            Historial de evaluaciones\n")
+	fmt.Fprintf(os.Stderr, "  db status|migrate           Versión del esquema del historial y sus migraciones\n")
+	fmt.Fprintf(os.Stderr, "  backup | restore <archivo>  Respaldar o restaurar el historial, la configuración y los datos locales\n")
+	fmt.Fprintf(os.Stderr, "  findings list|ack|export    Hallazgos del historial: estado, responsable y exportación\n")
+	fmt.Fprintf(os.Stderr, "  recheck <id>                Re-verificar localmente si un hallazgo sigue presente\n")
+	fmt.Fprintf(os.Stderr, "  diff <domain>               Cambios entre las dos últimas evaluaciones (o entre dos JSON)\n")
+	fmt.Fprintf(os.Stderr, "  info                        Estado de SSL Labs: motor, criterios y capacidad\n")
+	fmt.Fprintf(os.Stderr, "  version                     Versión de nebula\n")
+	fmt.Fprintf(os.Stderr, "  register                    Registro de email (API v4)\n")
+	fmt.Fprintf(os.Stderr, "  init | config validate      Configuración inicial y validación\n")
+	fmt.Fprintf(os.Stderr, "  truststore show|update      Almacén de confianza de --air-gapped\n")
+	fmt.Fprintf(os.Stderr, "  diagnose <domain> --client  Por qué falla el handshake de un cliente antiguo\n")
+	fmt.Fprintf(os.Stderr, "  verify-deploy <domain>...   Verificar que todos los endpoints sirvan un certificado recién renovado\n")
+	fmt.Fprintf(os.Stderr, "  verify-cutover <domain>     Seguir una migración hasta que resolvers y endpoints nuevos converjan\n")
+	fmt.Fprintf(os.Stderr, "  selftest [--chaos]          Prueba de punta a punta contra una API simulada, con fallos inyectados\n")
+	fmt.Fprintf(os.Stderr, "  self-update | release-info  Actualización y metadatos del binario\n\n")
+	fmt.Fprintf(os.Stderr, "Los flags de cada comando se ven con: %s <comando> -h\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "Con --%s (o NEBULA_STRICT_CLI=1) se rechaza el uso obsoleto, como %s <domain> sin scan\n", strictCLIFlag, os.Args[0])
+}
+
+// scanDomain runs a complete assessment for a single domain and displays the results.
+// If the assessment is interrupted, the endpoints already assessed are
+// displayed and returned along with ErrInterrupted.
+func scanDomain(ctx context.Context, scanner Assessor, domain string, opts DisplayOptions) (*AssessmentResult, error) {
+	fmt.Printf(tr("SSL Labs Scanner - Verificando seguridad TLS de: %s\n\n"), domain)
 	
-	// La evaluación está completa (status == READY)
-	fmt.Printf("\n✅ Evaluación completada\n")
-	
-	// Punto 7: Procesar resultados
-	result, err := ProcessResults(host)
+	result, err := scanner.AssessContext(ctx, domain)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error procesando resultados: %s\n", err)
-		os.Exit(1)
+		if result != nil {
+			fmt.Printf("\n%s\n", paint(colorYellow, tr("⚠️  Evaluación interrumpida: resultados parciales")))
+			DisplayResults(result, opts)
+		}
+		return result, err
 	}
 	
 	// Punto 8: Mostrar resultados
-	DisplayResults(result)
+	DisplayResults(result, opts)
+	return result, nil
+}
+
+// DisplayOptions controla qué información muestra DisplayResults
+type DisplayOptions struct {
+	Details   bool             // Mostrar la información detallada de cada endpoint
+	Expiry    ExpiryThresholds // Umbrales para resaltar certificados por expirar
+	Snippets  string           // Servidor para los snippets de configuración (--snippets), vacío = ninguno
+	Raw       bool             // Mostrar la respuesta de la API sin procesar (--raw)
+	WorstOnly bool             // Detallar solo el endpoint que determina el grade general (--worst-only)
+	Sims      bool             // Mostrar la simulación de handshake de clientes comunes (--sims)
 }
 
 // DisplayResults muestra los resultados de seguridad TLS de forma clara
-func DisplayResults(result *AssessmentResult) {
-	fmt.Printf("\n=== Resultados de Seguridad TLS ===\n")
-	fmt.Printf("Dominio: %s\n", result.Domain)
-	fmt.Printf("Grade General: %s\n\n", result.OverallGrade)
+func DisplayResults(result *AssessmentResult, opts DisplayOptions) {
+	fmt.Print(tr("\n=== Resultados de Seguridad TLS ===\n"))
+	fmt.Printf(tr("Dominio: %s\n"), result.Domain)
+	fmt.Printf(tr("Grade General: %s\n\n"), paintGrade(result.OverallGrade))
 	
-	// Mostrar información de cada endpoint
+	// Mostrar información de cada endpoint, agrupados por familia de
+	// direcciones si hay IPv4 e IPv6
+	families := familyGrades(result)
+	worst := -1
+	if opts.WorstOnly {
+		worst = worstEndpoint(result)
+	}
 	for i, endpoint := range result.Endpoints {
+		displayFamilyHeader(result.Endpoints, i, len(families) > 0)
+		// Con --worst-only los demás endpoints ocupan una línea, sin
+		// separación hasta el próximo bloque
+		if worst >= 0 && i != worst {
+			fmt.Printf(tr("--- Endpoint %d: %s --- Grade: %s (resumido)\n"), i+1, endpoint.IPAddress, paintGrade(endpoint.Grade))
+			if next := i + 1; next == len(result.Endpoints) || next == worst ||
+				addressFamily(result.Endpoints[next].IPAddress) != addressFamily(endpoint.IPAddress) {
+				fmt.Println()
+			}
+			continue
+		}
 		fmt.Printf("--- Endpoint %d: %s ---\n", i+1, endpoint.IPAddress)
-		fmt.Printf("Grade: %s\n", endpoint.Grade)
+		fmt.Printf("Grade: %s\n", paintGrade(endpoint.Grade))
 		
 		// Protocolos TLS
-		if len(endpoint.TLSProtocols) > 0 {
-			fmt.Printf("Protocolos TLS: %s\n", strings.Join(endpoint.TLSProtocols, ", "))
-		} else {
-			fmt.Printf("Protocolos TLS: No hay protocolos seguros disponibles\n")
+		switch endpoint.ProtocolStatus {
+		case ProtocolsUnknown:
+			fmt.Print(tr("Protocolos TLS: ❔ Sin datos (la API no devolvió los protocolos del endpoint)\n"))
+		case ProtocolsNoneSecure:
+			fmt.Printf(tr("Protocolos TLS: %s\n"), paint(colorRed, tr("❌ CRÍTICO: el servidor solo ofrece protocolos inseguros")))
+		default:
+			fmt.Printf(tr("Protocolos TLS: %s\n"), strings.Join(endpoint.TLSProtocols, ", "))
 		}
 		
 		// Información del certificado
 		if endpoint.CertIssuer != "" {
-			fmt.Printf("Certificado Emisor: %s\n", endpoint.CertIssuer)
+			fmt.Printf(tr("Certificado Emisor: %s\n"), endpoint.CertIssuer)
 		}
 		
 		if endpoint.CertValidFrom > 0 && endpoint.CertValidTo > 0 {
-			validFrom := time.Unix(endpoint.CertValidFrom/1000, 0)
-			validTo := time.Unix(endpoint.CertValidTo/1000, 0)
-			fmt.Printf("Certificado Válido: %s hasta %s\n", 
-				validFrom.Format("2006-01-02"), 
-				validTo.Format("2006-01-02"))
+			validFrom := time.UnixMilli(endpoint.CertValidFrom)
+			validTo := time.UnixMilli(endpoint.CertValidTo)
+			fmt.Printf(tr("Certificado Válido: %s hasta %s (%s)\n"), 
+				formatDate(validFrom), 
+				formatDate(validTo), zoneName(validTo))
+			fmt.Printf(tr("Días para expirar: %s\n"), paint(expiryColor(opts.Expiry.Status(endpoint.CertDaysRemaining)),
+				describeExpiry(endpoint.CertDaysRemaining, opts.Expiry)))
+		}
+		
+		// Servidores con varios certificados (ej: RSA y ECDSA): cada uno por separado
+		if len(endpoint.OtherCerts) > 0 {
+			fmt.Printf(tr("Certificado Clave: %s\n"), describeKey(endpointKey(endpoint.Details)))
+			displayOtherCerts(endpoint.OtherCerts, opts.Expiry)
+			displayCertMatrix(endpoint.Details)
+		}
+		
+		// Problemas de la cadena de certificados
+		if len(endpoint.ChainIssues) > 0 {
+			fmt.Print(tr("Problemas de certificado/cadena:\n"))
+			for _, issue := range endpoint.ChainIssues {
+				fmt.Printf("  %s\n", paint(colorYellow, "⚠️  "+issue))
+			}
+		}
+		
+		// Intermedios faltantes y salud de los responders OCSP y de las CRLs
+		if endpoint.AIA != nil {
+			displayAIA(endpoint.AIA)
+		}
+		if len(endpoint.OCSP) > 0 {
+			displayOCSP(endpoint.OCSP)
+		}
+		if len(endpoint.CRL) > 0 {
+			displayCRL(endpoint.CRL)
+		}
+		
+		// Requisitos de los navegadores para certificados nuevos
+		if len(endpoint.Issuance) > 0 {
+			displayIssuance(endpoint.Issuance)
+		}
+		
+		// Vulnerabilidades conocidas
+		if endpoint.Details == nil {
+			fmt.Print(tr("Vulnerabilidades: ❔ Sin datos\n"))
+		} else if endpoint.Details.Local {
+			fmt.Print(tr("Vulnerabilidades: ❔ No evaluadas (evaluación local)\n"))
+		} else if len(endpoint.Vulnerabilities) > 0 {
+			fmt.Print(tr("Vulnerabilidades:\n"))
+			for _, name := range endpoint.Vulnerabilities {
+				fmt.Printf("  %s\n", paint(colorRed, "⚠️  "+name))
+			}
+		} else {
+			fmt.Print(tr("Vulnerabilidades: Ninguna detectada\n"))
 		}
 		
+		// Motivos de un grade menor a A+ y cómo corregirlos
+		if reasons := explainGrade(&endpoint); len(reasons) > 0 {
+			displayGradeReasons(reasons, opts.Snippets)
+		}
+		
+		if opts.Sims {
+			displaySims(endpoint.Details)
+		}
+		
+		if opts.Details && endpoint.Details != nil {
+			displayDetails(endpoint.Details)
+		}
+		
+		fmt.Println()
+	}
+	
+	// Endpoints que no pudieron evaluarse
+	for i, endpointErr := range result.EndpointErrors {
+		fmt.Printf("--- Endpoint %d: %s ---\n", len(result.Endpoints)+i+1, endpointErr.IPAddress)
+		fmt.Printf("%s\n\n", paint(colorRed, "❌ Error: "+endpointErr.Message))
+	}
+	
+	if len(result.Endpoints)+len(result.EndpointErrors) > 1 {
+		fmt.Print(tr("=== Resumen ===\n"))
+		fmt.Printf(tr("Grade General (peor de todos los endpoints): %s\n"), paintGrade(result.OverallGrade))
+		for _, family := range families {
+			fmt.Printf("  %s: %s (%s)\n", family.Family, paintGrade(family.Grade), countEndpoints(family.Endpoints))
+		}
+		if result.HasEndpointErrors() {
+			fmt.Printf("%s\n", paint(colorRed, fmt.Sprintf(tr("❌ %d de %d endpoints no pudieron evaluarse"),
+				len(result.EndpointErrors), len(result.Endpoints)+len(result.EndpointErrors))))
+		}
 		fmt.Println()
 	}
 	
-	if len(result.Endpoints) > 1 {
-		fmt.Printf("=== Resumen ===\n")
-		fmt.Printf("Grade General (peor de todos los endpoints): %s\n", result.OverallGrade)
+	if result.CT != nil {
+		displayCT(result.CT)
+	}
+	
+	if result.CAA != nil {
+		displayCAA(result.Domain, result.CAA)
+	}
+	
+	if result.DNSSEC != nil {
+		displayDNSSEC(result.DNSSEC)
+	}
+	
+	if result.DANE != nil {
+		displayDANE(result.DANE)
+	}
+	
+	if result.HSTSPreload != nil {
+		displayHSTS(result)
+	}
+	
+	if result.Headers != nil {
+		displayHeaderAudit(result.Headers)
+	}
+	
+	// Procedencia del resultado, necesaria para auditorías
+	fmt.Print(tr("=== Metadatos ===\n"))
+	for _, line := range describeMetadata(result.Metadata) {
+		fmt.Println(line)
+	}
+	
+	if opts.Raw && result.Host != nil {
+		fmt.Println()
+		displayRawHost(result.Host, result.Metadata.Source)
 	}
 }
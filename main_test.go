@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBuildAnalyzeURL(t *testing.T) {
+	const base = "https://api.ssllabs.com/api/v3"
+	tests := []struct {
+		name    string
+		baseURL string
+		host    string
+		params  AnalyzeParams
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "defaults",
+			baseURL: base,
+			host:    "example.com",
+			want:    base + "/analyze?host=example.com&publish=off",
+		},
+		{
+			name:    "start new and publish",
+			baseURL: base,
+			host:    "example.com",
+			params:  AnalyzeParams{Publish: true, StartNew: true},
+			want:    base + "/analyze?host=example.com&publish=on&startNew=on",
+		},
+		{
+			name:    "ampersand in host",
+			baseURL: base,
+			host:    "example.com&startNew=on",
+			want:    base + "/analyze?host=example.com%26startNew%3Don&publish=off",
+		},
+		{
+			name:    "fragment in host",
+			baseURL: base,
+			host:    "example.com#frag",
+			want:    base + "/analyze?host=example.com%23frag&publish=off",
+		},
+		{
+			name:    "spaces in host",
+			baseURL: base,
+			host:    "exa mple.com",
+			want:    base + "/analyze?host=exa+mple.com&publish=off",
+		},
+		{
+			name:    "IDN host",
+			baseURL: base,
+			host:    "münchen.de",
+			want:    base + "/analyze?host=m%C3%BCnchen.de&publish=off",
+		},
+		{
+			name:    "from cache with max age",
+			baseURL: base,
+			host:    "example.com",
+			params:  AnalyzeParams{FromCache: true, MaxAge: 24},
+			want:    base + "/analyze?fromCache=on&host=example.com&maxAge=24&publish=off",
+		},
+		{
+			name:    "max age without from cache",
+			baseURL: base,
+			host:    "example.com",
+			params:  AnalyzeParams{MaxAge: 24},
+			want:    base + "/analyze?host=example.com&publish=off",
+		},
+		{
+			name:    "all and ignore mismatch",
+			baseURL: base,
+			host:    "example.com",
+			params:  AnalyzeParams{All: "done", IgnoreMismatch: true},
+			want:    base + "/analyze?all=done&host=example.com&ignoreMismatch=on&publish=off",
+		},
+		{
+			name:    "trailing slash in base URL",
+			baseURL: base + "/",
+			host:    "example.com",
+			want:    base + "/analyze?host=example.com&publish=off",
+		},
+		{
+			name:    "base URL with port and path",
+			baseURL: "http://127.0.0.1:8080/mirror/api/v4",
+			host:    "example.com",
+			want:    "http://127.0.0.1:8080/mirror/api/v4/analyze?host=example.com&publish=off",
+		},
+		{
+			name:    "unparsable base URL",
+			baseURL: "://api.ssllabs.com",
+			host:    "example.com",
+			wantErr: true,
+		},
+		{
+			name:    "relative base URL",
+			baseURL: "api.ssllabs.com/api/v3",
+			host:    "example.com",
+			wantErr: true,
+		},
+		{
+			name:    "empty base URL",
+			baseURL: "",
+			host:    "example.com",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildAnalyzeURL(tt.baseURL, tt.host, tt.params)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("buildAnalyzeURL(%q) = %q, se esperaba un error", tt.baseURL, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("buildAnalyzeURL(%q): %v", tt.baseURL, err)
+			}
+			if got != tt.want {
+				t.Errorf("buildAnalyzeURL() = %q, se esperaba %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPollAssessmentUnknownStatus checks that a status this version
+// doesn't know is logged once, while the polling goes on until READY
+func TestPollAssessmentUnknownStatus(t *testing.T) {
+	var mu sync.Mutex
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		polls++
+		host := &Host{Host: "example.com", Port: 443, Protocol: "http", Status: "QUEUED"}
+		if polls > 3 {
+			host.Status = statusReady
+			host.Endpoints = []Endpoint{{IPAddress: "192.0.2.1", StatusMessage: endpointStatusReady, Grade: "A", Progress: 100,
+				Details: &EndpointDetails{}}}
+		}
+		mu.Unlock()
+		json.NewEncoder(w).Encode(host)
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	client := NewHTTPClient(WithBaseURL(server.URL), WithRateLimiter(newRateLimiter(time.Millisecond)), WithRetries(0, 0, 0),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	opts := PollOptions{Interval: time.Millisecond, InProgressInterval: time.Millisecond, Timeout: time.Minute}
+	host, err := PollAssessment(context.Background(), client, "example.com", opts)
+	if err != nil || host.Status != statusReady {
+		t.Fatalf("PollAssessment: %v, %v", host, err)
+	}
+	if count := strings.Count(logs.String(), `msg="estado desconocido" status=QUEUED`); count != 1 {
+		t.Errorf("el estado desconocido se registró %d veces, se esperaba una:\n%s", count, logs.String())
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"ssllabs-scanner/store"
+)
+
+// Origen de los datos de una evaluación
+const (
+	sourceSSLLabs = "ssllabs" // Evaluación hecha por la API de SSL Labs
+	sourceLocal   = "local"   // Evaluación hecha localmente, sin la API
+	sourceReplay  = "replay"  // Respuesta de la API guardada y procesada de nuevo
+)
+
+// version is the tool version, set at build time with
+// -ldflags "-X main.version=v1.2.3"
+var version = "dev"
+
+// toolVersion returns the version of this program: the one set at build
+// time, or the module version when installed with go install
+func toolVersion() string {
+	if version != "dev" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return version
+}
+
+// metadataFromHost returns the metadata reported by the API for host.
+// Source and the request parameters are filled in by the caller.
+func metadataFromHost(host *Host) store.Metadata {
+	metadata := store.Metadata{
+		EngineVersion:   host.EngineVersion,
+		CriteriaVersion: host.CriteriaVersion,
+		ToolVersion:     toolVersion(),
+	}
+	if host.StartTime > 0 {
+		metadata.StartedAt = time.UnixMilli(host.StartTime)
+	}
+	if host.TestTime > 0 {
+		metadata.FinishedAt = time.UnixMilli(host.TestTime)
+	}
+	return metadata
+}
+
+// describeMetadata returns the metadata as lines for the text output
+func describeMetadata(m store.Metadata) []string {
+	lines := []string{
+		fmt.Sprintf(tr("Motor: %s · Criterios: %s"), tr(orUnknown(m.EngineVersion)), tr(orUnknown(m.CriteriaVersion))),
+	}
+	if !m.StartedAt.IsZero() || !m.FinishedAt.IsZero() {
+		lines = append(lines, fmt.Sprintf(tr("Evaluación: %s → %s"), formatMetadataTime(m.StartedAt), formatMetadataTime(m.FinishedAt)))
+	}
+	lines = append(lines, fmt.Sprintf(tr("Fuente: %s (fromCache=%s, publish=%s) · nebula %s"),
+		tr(orUnknown(m.Source)), onOff(m.FromCache), onOff(m.Publish), tr(orUnknown(m.ToolVersion))))
+	if m.TrustStore != "" {
+		lines = append(lines, fmt.Sprintf(tr("Almacén de confianza: %s"), m.TrustStore))
+	}
+	if m.Fallback != "" {
+		lines = append(lines, fmt.Sprintf(tr("Evaluación local en lugar de SSL Labs (%s)"), m.Fallback))
+	}
+	return lines
+}
+
+// formatMetadataTime formats a metadata timestamp, or "?" if unknown
+func formatMetadataTime(t time.Time) string {
+	if t.IsZero() {
+		return "?"
+	}
+	return t.In(outputLocation).Format("2006-01-02 15:04:05 MST")
+}
+
+// orUnknown returns value, or "desconocido" when it is empty
+func orUnknown(value string) string {
+	if value == "" {
+		return "desconocido"
+	}
+	return value
+}
+
+// onOff formats a boolean API parameter
+func onOff(value bool) string {
+	if value {
+		return "on"
+	}
+	return "off"
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Middleware is a pair of hooks run around every request sent by an
+// HTTPClient. Either hook may be nil.
+//
+// BeforeRequest runs before the request is sent and may modify it (for
+// example to add headers); returning an error aborts the request.
+// AfterResponse runs once the response headers arrive, or with err set if
+// the request failed. It must not read or close resp.Body.
+//
+// Middlewares run in the order they were added for BeforeRequest and in
+// reverse order for AfterResponse, so each one wraps the ones after it.
+// Hooks are called concurrently when the client is shared across goroutines.
+type Middleware struct {
+	BeforeRequest func(req *http.Request) error
+	AfterResponse func(req *http.Request, resp *http.Response, err error, elapsed time.Duration)
+}
+
+// runBefore runs the BeforeRequest hooks in order
+func runBefore(middleware []Middleware, req *http.Request) error {
+	for _, m := range middleware {
+		if m.BeforeRequest == nil {
+			continue
+		}
+		if err := m.BeforeRequest(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runAfter runs the AfterResponse hooks in reverse order
+func runAfter(middleware []Middleware, req *http.Request, resp *http.Response, err error, elapsed time.Duration) {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		if middleware[i].AfterResponse != nil {
+			middleware[i].AfterResponse(req, resp, err, elapsed)
+		}
+	}
+}
+
+// headerMiddleware sets a header on every request
+func headerMiddleware(name, value string) Middleware {
+	return Middleware{
+		BeforeRequest: func(req *http.Request) error {
+			req.Header.Set(name, value)
+			return nil
+		},
+	}
+}
+
+// rateLimitMiddleware waits for the limiter before every request
+func rateLimitMiddleware(limiter RateLimiter) Middleware {
+	return Middleware{
+		BeforeRequest: func(req *http.Request) error {
+			limiter.Wait()
+			return nil
+		},
+	}
+}
+
+// loggingMiddleware logs the method, URL, status and duration of every
+// request at debug level, and failed requests at warn level
+func loggingMiddleware(logger *slog.Logger) Middleware {
+	return Middleware{
+		AfterResponse: func(req *http.Request, resp *http.Response, err error, elapsed time.Duration) {
+			if err != nil {
+				logger.Warn("petición fallida", "method", req.Method, "url", req.URL.String(), "error", err)
+				return
+			}
+			logger.Debug("petición a la API", "method", req.Method, "url", req.URL.String(),
+				"status", resp.StatusCode, "duration", elapsed.Round(time.Millisecond))
+		},
+	}
+}
+
+// RequestMetrics counts the requests sent to the API by status code
+// ("error" for connection failures) and accumulates their duration.
+// It is safe for concurrent use.
+type RequestMetrics struct {
+	mu       sync.Mutex
+	counts   map[string]int
+	duration time.Duration
+}
+
+// NewRequestMetrics creates an empty set of request metrics
+func NewRequestMetrics() *RequestMetrics {
+	return &RequestMetrics{counts: make(map[string]int)}
+}
+
+// Middleware returns the middleware that feeds the metrics
+func (m *RequestMetrics) Middleware() Middleware {
+	return Middleware{
+		AfterResponse: func(req *http.Request, resp *http.Response, err error, elapsed time.Duration) {
+			code := "error"
+			if err == nil {
+				code = strconv.Itoa(resp.StatusCode)
+			}
+
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			m.counts[code]++
+			m.duration += elapsed
+		},
+	}
+}
+
+// WriteMetrics writes the request counters in the Prometheus text format
+func (m *RequestMetrics) WriteMetrics(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	codes := make([]string, 0, len(m.counts))
+	total := 0
+	for code, count := range m.counts {
+		codes = append(codes, code)
+		total += count
+	}
+	sort.Strings(codes)
+
+	writeTypedHeader(w, "ssllabs_api_requests_total", "Requests sent to the SSL Labs API by HTTP status code", "counter")
+	for _, code := range codes {
+		fmt.Fprintf(w, "ssllabs_api_requests_total{code=%s} %d\n", promLabel(code), m.counts[code])
+	}
+
+	writeTypedHeader(w, "ssllabs_api_request_duration_seconds_total", "Total time spent in requests to the SSL Labs API", "counter")
+	fmt.Fprintf(w, "ssllabs_api_request_duration_seconds_total %g\n", m.duration.Seconds())
+}
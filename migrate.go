@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+
+	"ssllabs-scanner/store"
+)
+
+// runDB implements the "db" subcommand: "db status" shows the schema
+// version of the history and its pending migrations, and "db migrate"
+// applies them, or reverts them with --to
+func runDB(args []string) error {
+	fs := flag.NewFlagSet("db", flag.ExitOnError)
+	dsn := fs.String("history-db", defaultHistoryPath(), "base SQLite del historial (ruta o sqlite:ruta)")
+	to := fs.Int("to", -1, "con migrate, versión del esquema a la que llevar la base; menor que la actual revierte migraciones y borra sus datos (por defecto la última)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s db status | migrate [--to versión] [--history-db archivo]\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if len(args) == 0 {
+		fs.Usage()
+		return fmt.Errorf("se requiere una acción: status o migrate")
+	}
+	action := args[0]
+	fs.Parse(args[1:])
+	if action != "status" && action != "migrate" {
+		fs.Usage()
+		return fmt.Errorf("acción desconocida %q: se espera status o migrate", action)
+	}
+
+	dbPath, err := store.SQLitePath(*dsn)
+	if err != nil {
+		return err
+	}
+	// status no crea la base: solo informa
+	if _, err := os.Stat(dbPath); action == "status" && err != nil {
+		return fmt.Errorf("no se pudo leer el historial: %w", err)
+	}
+	db, err := store.OpenDB(dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if action == "migrate" {
+		target := *to
+		if target < 0 {
+			target = store.LatestSchemaVersion()
+		}
+		ran, err := store.Migrate(db, target)
+		for _, migration := range ran {
+			if migration.Version > target {
+				fmt.Printf("⬇️  %04d_%s revertida\n", migration.Version, migration.Name)
+			} else {
+				fmt.Printf("⬆️  %04d_%s aplicada\n", migration.Version, migration.Name)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if len(ran) == 0 {
+			fmt.Printf("El historial %s ya está en el esquema %d\n", dbPath, target)
+		}
+		return nil
+	}
+
+	version, err := store.SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	applied, err := store.AppliedMigrations(db)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Historial: %s\n", dbPath)
+	fmt.Printf("Esquema: %d (esta versión de nebula usa el %d)\n\n", version, store.LatestSchemaVersion())
+	for _, migration := range store.Migrations() {
+		if at, ok := applied[migration.Version]; ok {
+			fmt.Printf("  ✅ %04d_%s  aplicada %s\n", migration.Version, migration.Name, formatDateTime(at))
+		} else {
+			fmt.Printf("  ⏳ %04d_%s  pendiente\n", migration.Version, migration.Name)
+		}
+	}
+	// Migraciones de una versión más nueva, que esta no conoce
+	var unknown []int
+	for v := range applied {
+		if v > store.LatestSchemaVersion() {
+			unknown = append(unknown, v)
+		}
+	}
+	slices.Sort(unknown)
+	for _, v := range unknown {
+		fmt.Printf("  ⚠️  %04d  aplicada por una versión más nueva de nebula\n", v)
+	}
+	return nil
+}
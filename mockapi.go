@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Dominios que conoce la API simulada, uno por camino del scanner
+const (
+	mockDomainOK      = "ok.selftest.invalid"      // Evaluación completa con dos endpoints
+	mockDomainPartial = "partial.selftest.invalid" // Un endpoint no se pudo evaluar
+	mockDomainSlow    = "slow.selftest.invalid"    // Un endpoint nunca termina
+	mockDomainError   = "error.selftest.invalid"   // La evaluación termina con ERROR
+)
+
+// mockPolls is how many status polls an assessment stays IN_PROGRESS
+// after startNew
+const mockPolls = 2
+
+// Fallos que puede inyectar la API simulada
+const (
+	faultThrottled  = "429"       // Rate limit
+	faultOverloaded = "529"       // Servicio sobrecargado
+	faultTruncated  = "truncated" // JSON cortado a la mitad
+	faultStall      = "stall"     // Respuesta demorada más que el timeout del cliente
+	faultFlapping   = "flapping"  // Racha de 503 antes de volver a responder
+)
+
+// mockFlapStreak is how many consecutive 503 a flapping fault answers
+const mockFlapStreak = 2
+
+// ChaosConfig controls the faults injected by MockAPI. A zero Rate
+// disables them.
+type ChaosConfig struct {
+	Rate  float64       // Probabilidad de inyectar un fallo en cada petición
+	Stall time.Duration // Demora de las respuestas con faultStall
+	Seed  uint64        // Semilla de la secuencia de fallos, para repetir una corrida
+}
+
+// MockAPI is an in-memory implementation of the /analyze endpoint of the
+// SSL Labs API v2, with canned assessments for the mockDomain* hosts and
+// optional fault injection. It backs the selftest subcommand. It is safe
+// for concurrent use.
+type MockAPI struct {
+	mu       sync.Mutex
+	chaos    ChaosConfig
+	rand     *rand.Rand
+	polls    map[string]int // Consultas de cada dominio desde el último startNew
+	flapping int            // 503 que faltan de la racha actual
+	faults   map[string]int // Fallos inyectados por tipo
+	requests int
+}
+
+// NewMockAPI creates a mock API injecting the faults of chaos
+func NewMockAPI(chaos ChaosConfig) *MockAPI {
+	return &MockAPI{
+		chaos:  chaos,
+		rand:   rand.New(rand.NewPCG(chaos.Seed, chaos.Seed)),
+		polls:  make(map[string]int),
+		faults: make(map[string]int),
+	}
+}
+
+// ServeHTTP implements the API. Faults are drawn before the request is
+// handled, so a retried request sees the assessment where it was.
+func (m *MockAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fault := m.nextFault()
+	switch fault {
+	case faultThrottled:
+		http.Error(w, `{"errors":[{"message":"Too many requests"}]}`, http.StatusTooManyRequests)
+		return
+	case faultOverloaded:
+		http.Error(w, `{"errors":[{"message":"Service overloaded"}]}`, 529)
+		return
+	case faultFlapping:
+		http.Error(w, `{"errors":[{"message":"Service unavailable"}]}`, http.StatusServiceUnavailable)
+		return
+	case faultStall:
+		select {
+		case <-time.After(m.chaos.Stall):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if !strings.HasSuffix(r.URL.Path, analyzeEndpoint) {
+		http.Error(w, `{"errors":[{"message":"Not found"}]}`, http.StatusNotFound)
+		return
+	}
+	query := r.URL.Query()
+	host := m.analyze(query.Get("host"), query.Get("startNew") == "on", query.Get("all") == "done")
+	body, err := json.Marshal(host)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if fault == faultTruncated {
+		body = body[:len(body)/2]
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// nextFault draws the fault of the next request ("" for none)
+func (m *MockAPI) nextFault() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests++
+	fault := ""
+	switch {
+	case m.flapping > 0:
+		m.flapping--
+		fault = faultFlapping
+	case m.chaos.Rate > 0 && m.rand.Float64() < m.chaos.Rate:
+		faults := []string{faultThrottled, faultOverloaded, faultTruncated, faultFlapping}
+		if m.chaos.Stall > 0 {
+			faults = append(faults, faultStall)
+		}
+		fault = faults[m.rand.IntN(len(faults))]
+		if fault == faultFlapping {
+			m.flapping = mockFlapStreak - 1
+		}
+	}
+	if fault != "" {
+		m.faults[fault]++
+	}
+	return fault
+}
+
+// analyze advances the assessment of domain and returns its state
+func (m *MockAPI) analyze(domain string, startNew, details bool) *Host {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if startNew {
+		m.polls[domain] = 0
+	}
+	polls := m.polls[domain]
+	m.polls[domain]++
+
+	now := time.Now()
+	host := &Host{Host: domain, Port: 443, Protocol: "http", Status: statusInProgress, StartTime: now.UnixMilli(), EngineVersion: "2.3.1", CriteriaVersion: "2009q"}
+	switch domain {
+	case mockDomainOK:
+		ready := polls >= mockPolls
+		host.Endpoints = []Endpoint{mockEndpoint("192.0.2.10", "A+", ready, details), mockEndpoint("192.0.2.11", "A", ready, details)}
+		if ready {
+			host.Status = statusReady
+		}
+	case mockDomainPartial:
+		failed := Endpoint{IPAddress: "192.0.2.21", StatusMessage: "Unable to connect to the server", Progress: 100}
+		host.Endpoints = []Endpoint{mockEndpoint("192.0.2.20", "A", true, details), failed}
+		host.Status = statusReady
+	case mockDomainSlow:
+		host.Endpoints = []Endpoint{mockEndpoint("192.0.2.30", "B", true, details), mockEndpoint("192.0.2.31", "", false, false)}
+	default:
+		host.Status = statusError
+		host.StatusMessage = "Unable to resolve domain name"
+	}
+	if host.Status == statusReady {
+		host.TestTime = now.UnixMilli()
+	}
+	return host
+}
+
+// mockEndpoint returns an endpoint of a canned assessment, in progress or
+// ready with grade
+func mockEndpoint(ip, grade string, ready, details bool) Endpoint {
+	if !ready {
+		return Endpoint{IPAddress: ip, StatusMessage: endpointStatusInProgress, Progress: 50, ETA: 60}
+	}
+	endpoint := Endpoint{IPAddress: ip, StatusMessage: endpointStatusReady, Grade: grade, Progress: 100}
+	if details {
+		now := time.Now()
+		endpoint.Details = &EndpointDetails{
+			Protocols: []Protocol{{Name: "TLS", Version: "1.2"}, {Name: "TLS", Version: "1.3"}},
+			Cert: &Cert{
+				Subject:       "CN=selftest.invalid",
+				CommonNames:   []string{"selftest.invalid"},
+				IssuerSubject: "CN=Nebula Selftest CA",
+				IssuerLabel:   "Nebula Selftest CA",
+				NotBefore:     now.AddDate(0, 0, -30).UnixMilli(),
+				NotAfter:      now.AddDate(0, 0, 60).UnixMilli(),
+			},
+		}
+	}
+	return endpoint
+}
+
+// describeFaults renders the faults injected so far, e.g. "429=2 stall=1 (31 peticiones)"
+func (m *MockAPI) describeFaults() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kinds := make([]string, 0, len(m.faults))
+	for kind := range m.faults {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	parts := make([]string, 0, len(kinds)+1)
+	for _, kind := range kinds {
+		parts = append(parts, fmt.Sprintf("%s=%d", kind, m.faults[kind]))
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "ninguno")
+	}
+	return fmt.Sprintf("%s (%d peticiones)", strings.Join(parts, " "), m.requests)
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ServedCert is a certificate served by an endpoint with its chain.
+// Servers with RSA and ECDSA keys serve one or the other depending on the
+// cipher suites and signature algorithms offered by the client.
+type ServedCert struct {
+	Cert  *Cert  `json:"cert"`
+	Chain *Chain `json:"chain,omitempty"`
+}
+
+// details returns endpoint details with only the certificate and chain of
+// s, so the checks of the main certificate apply to it too
+func (s ServedCert) details() *EndpointDetails {
+	return &EndpointDetails{Cert: s.Cert, Chain: s.Chain}
+}
+
+// CertSummary is a certificate served by an endpoint besides the main one
+type CertSummary struct {
+	Key           string // Algoritmo y tamaño de la clave (ej: "EC 256 bits")
+	Issuer        string
+	ValidFrom     int64
+	ValidTo       int64
+	DaysRemaining int      // Días hasta la expiración (negativo si expiró)
+	Fingerprint   string   // SHA-256 del certificado (hex)
+	Issues        []string // Problemas del certificado y de su cadena
+}
+
+// otherCerts summarizes the certificates of d other than the main one
+func otherCerts(d *EndpointDetails, now time.Time) []CertSummary {
+	var certs []CertSummary
+	for _, served := range d.AltCerts {
+		if served.Cert == nil {
+			continue
+		}
+		details := served.details()
+		certs = append(certs, CertSummary{
+			Key:           describeKey(served.Cert.KeyAlg, served.Cert.KeySize),
+			Issuer:        served.Cert.IssuerLabel,
+			ValidFrom:     served.Cert.NotBefore,
+			ValidTo:       served.Cert.NotAfter,
+			DaysRemaining: daysUntil(served.Cert.NotAfter, now),
+			Fingerprint:   certFingerprint(details),
+			Issues:        chainIssues(details),
+		})
+	}
+	return certs
+}
+
+// allServedCerts returns the main certificate of d and the additional ones
+func allServedCerts(d *EndpointDetails) []ServedCert {
+	if d == nil || d.Cert == nil {
+		return nil
+	}
+	return append([]ServedCert{{Cert: d.Cert, Chain: d.Chain}}, d.AltCerts...)
+}
+
+// describeKey renders the algorithm and size of a key
+func describeKey(alg string, size int) string {
+	switch {
+	case alg == "":
+		return tr("clave desconocida")
+	case size == 0:
+		return alg
+	}
+	return fmt.Sprintf("%s %d bits", alg, size)
+}
+
+// displayOtherCerts prints the additional certificates of an endpoint,
+// each with its expiry and problems, like the main one
+func displayOtherCerts(certs []CertSummary, t ExpiryThresholds) {
+	fmt.Printf(tr("Certificados adicionales (%d):\n"), len(certs))
+	for _, cert := range certs {
+		fmt.Printf(tr("  %s, emisor %s (%s)\n"), cert.Key, tr(orUnknown(cert.Issuer)), shortFingerprint(cert.Fingerprint))
+		if cert.ValidFrom > 0 && cert.ValidTo > 0 {
+			validTo := time.UnixMilli(cert.ValidTo)
+			fmt.Printf(tr("     Válido: %s hasta %s (%s) | Días para expirar: %s\n"),
+				formatDate(time.UnixMilli(cert.ValidFrom)), formatDate(validTo), zoneName(validTo),
+				paint(expiryColor(t.Status(cert.DaysRemaining)), describeExpiry(cert.DaysRemaining, t)))
+		}
+		for _, issue := range cert.Issues {
+			fmt.Printf("     %s\n", paint(colorYellow, "⚠️  "+issue))
+		}
+	}
+}
+
+// displayAltChains prints the key usages and chain of each additional
+// certificate (--details)
+func displayAltChains(d *EndpointDetails) {
+	for i, served := range d.AltCerts {
+		if served.Cert == nil {
+			continue
+		}
+		fmt.Printf(tr("Certificado adicional %d: %s\n"), i+1, describeKey(served.Cert.KeyAlg, served.Cert.KeySize))
+		if certs := servedCerts(served.details()); len(certs) > 0 {
+			displayKeyUsages(certs[0])
+		}
+		if served.Chain != nil && len(served.Chain.Certs) > 0 {
+			displayChain(served.Chain)
+		}
+	}
+}
+
+// servedKey is the key of a certificate served by an endpoint
+type servedKey struct {
+	fingerprint string
+	alg         string // RSA, EC, DSA...
+	label       string // ej: "EC 256 bits"
+}
+
+// servedKeys returns the key of each certificate of d, the main one first
+func servedKeys(d *EndpointDetails) []servedKey {
+	var keys []servedKey
+	for i, served := range allServedCerts(d) {
+		alg, size := served.Cert.KeyAlg, served.Cert.KeySize
+		if i == 0 {
+			alg, size = endpointKey(d)
+		}
+		keys = append(keys, servedKey{fingerprint: certFingerprint(served.details()), alg: alg, label: describeKey(alg, size)})
+	}
+	return keys
+}
+
+// certUse is the certificate served with a family of cipher suites
+type certUse struct {
+	Family    string   // Intercambio de claves y autenticación (ej: "ECDHE_ECDSA") o "TLS 1.3"
+	Protocols []string // Protocolos en los que se aceptan suites de la familia
+	Certs     []int    // Índices en servedKeys de los certificados servidos
+	Inferred  bool     // Deducido del algoritmo de autenticación, sin observarlo
+}
+
+// suiteFamily returns the key exchange and authentication of a suite (ej:
+// "ECDHE_ECDSA"), or "TLS 1.3" for the suites that don't include them
+func suiteFamily(name string) string {
+	name = strings.TrimPrefix(strings.TrimPrefix(name, "TLS_"), "SSL_")
+	family, _, ok := strings.Cut(name, "_WITH_")
+	if !ok {
+		return "TLS 1.3"
+	}
+	return family
+}
+
+// suiteKeyAlg returns the algorithm of the certificate key that a family
+// of suites authenticates with, or "" when the suite doesn't determine it
+// (TLS 1.3, where the server chooses by the signature algorithms of the
+// client, and the anonymous suites)
+func suiteKeyAlg(family string) string {
+	switch {
+	case strings.HasSuffix(family, "ECDSA"):
+		return "EC"
+	case strings.HasSuffix(family, "RSA"):
+		return "RSA"
+	case strings.HasSuffix(family, "DSS"):
+		return "DSA"
+	}
+	return ""
+}
+
+// certMatrix returns which certificate the server uses with each family of
+// the accepted cipher suites. The local assessment records the certificate
+// of each handshake; with the API it's deduced from the authentication
+// algorithm of the suite.
+func certMatrix(d *EndpointDetails, keys []servedKey) []certUse {
+	var uses []certUse
+	for _, suites := range d.Suites {
+		for _, suite := range suites.List {
+			family := suiteFamily(suite.Name)
+			i := slices.IndexFunc(uses, func(u certUse) bool { return u.Family == family })
+			if i < 0 {
+				uses = append(uses, certUse{Family: family})
+				i = len(uses) - 1
+			}
+			use := &uses[i]
+			if protocol := protocolName(suites.Protocol); suites.Protocol != 0 && !slices.Contains(use.Protocols, protocol) {
+				use.Protocols = append(use.Protocols, protocol)
+			}
+
+			var matches []int
+			if suite.CertFingerprint != "" {
+				matches = append(matches, slices.IndexFunc(keys, func(k servedKey) bool { return k.fingerprint == suite.CertFingerprint }))
+			} else if alg := suiteKeyAlg(family); alg != "" {
+				use.Inferred = true
+				for j, key := range keys {
+					if key.alg == alg {
+						matches = append(matches, j)
+					}
+				}
+			}
+			for _, j := range matches {
+				if j >= 0 && !slices.Contains(use.Certs, j) {
+					use.Certs = append(use.Certs, j)
+				}
+			}
+		}
+	}
+	return uses
+}
+
+// certMatrixWarnings flags the certificates that no suite uses and modern
+// clients that get the RSA certificate while an ECDSA one is available
+func certMatrixWarnings(uses []certUse, keys []servedKey) []string {
+	var warnings []string
+	hasEC := slices.ContainsFunc(keys, func(k servedKey) bool { return k.alg == "EC" })
+	for i, key := range keys {
+		if !slices.ContainsFunc(uses, func(u certUse) bool { return slices.Contains(u.Certs, i) }) {
+			warnings = append(warnings, fmt.Sprintf(tr("El certificado %s (%s) no se usa con ninguna de las suites aceptadas"),
+				key.label, shortFingerprint(key.fingerprint)))
+		}
+	}
+	for _, use := range uses {
+		if use.Family == "TLS 1.3" && !use.Inferred && hasEC && len(use.Certs) > 0 &&
+			!slices.ContainsFunc(use.Certs, func(i int) bool { return keys[i].alg == "EC" }) {
+			warnings = append(warnings, tr("Los clientes TLS 1.3 reciben el certificado RSA aunque hay uno ECDSA: revisar la preferencia de certificados del servidor"))
+		}
+	}
+	return warnings
+}
+
+// displayCertMatrix prints which certificate is served with each family of
+// cipher suites, for endpoints with several certificates
+func displayCertMatrix(d *EndpointDetails) {
+	keys := servedKeys(d)
+	uses := certMatrix(d, keys)
+	if len(uses) == 0 {
+		return
+	}
+	fmt.Print(tr("Certificado por tipo de suite:\n"))
+	for _, use := range uses {
+		family := use.Family
+		if len(use.Protocols) > 0 && use.Family != "TLS 1.3" {
+			family += " (" + strings.Join(use.Protocols, ", ") + ")"
+		}
+		var labels []string
+		for _, i := range use.Certs {
+			labels = append(labels, keys[i].label)
+		}
+		var served string
+		switch {
+		case len(labels) > 0 && use.Inferred:
+			served = fmt.Sprintf(tr("%s (según el algoritmo de la suite)"), strings.Join(labels, ", "))
+		case len(labels) > 0:
+			served = strings.Join(labels, ", ")
+		case use.Family == "TLS 1.3":
+			served = tr("elegido por el servidor según los algoritmos de firma del cliente (la API no lo indica)")
+		case strings.Contains(use.Family, "anon"):
+			served = tr("sin certificado (suite anónima)")
+		default:
+			served = tr("desconocido")
+		}
+		fmt.Printf("  %s: %s\n", family, served)
+	}
+	for _, warning := range certMatrixWarnings(uses, keys) {
+		fmt.Printf("  %s\n", paint(colorYellow, "⚠️  "+warning))
+	}
+}
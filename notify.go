@@ -0,0 +1,478 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"ssllabs-scanner/store"
+)
+
+// notifyTimeout is the maximum time to deliver the alerts of one
+// assessment, or the summary of a run, through every notifier
+const notifyTimeout = time.Minute
+
+// defaultNotifyBatch is how many domains with alerts in a run make their
+// notifications go out as a single summary
+const defaultNotifyBatch = 5
+
+// Notifier is a notification channel: it delivers the alerts raised by an
+// assessment. Notifiers are created from a URL whose scheme selects the
+// provider (see RegisterNotifier); the built-in ones are in notifiers.go.
+// Notify must honor ctx and is only called when there are alerts.
+type Notifier interface {
+	Notify(ctx context.Context, event NotificationEvent) error
+}
+
+// NotificationEvent is what a Notifier delivers: the alerts raised by an
+// assessment of a domain, with the grade and the metadata of the
+// assessment. When a run raises alerts in many domains, a single event
+// summarizes it: Batch holds the event of each domain and the other
+// fields are empty.
+type NotificationEvent struct {
+	Domain   string
+	Grade    string
+	Alerts   []Alert
+	Metadata store.Metadata    // Fechas en la zona de --tz
+	Result   *AssessmentResult // Evaluación completa, para las plantillas
+	Batch    []NotificationEvent
+	Message  string // Mensaje generado por la plantilla del destino; vacío = formato por defecto
+}
+
+// Tipos de alerta, estables para filtrar o agrupar en las plantillas
+const (
+	alertGradeDrop          = "grade_drop"
+	alertEndpointError      = "endpoint_error"
+	alertInsecureProtocols  = "insecure_protocols"
+	alertNewVulnerabilities = "new_vulnerabilities"
+	alertCertExpired        = "cert_expired"
+	alertCertExpiring       = "cert_expiring"
+	alertFindingReopened    = "finding_reopened"
+)
+
+// Alert is one of the changes of an assessment worth notifying
+type Alert struct {
+	Kind     string // Uno de los alert*, ej: grade_drop
+	Endpoint string // IP del endpoint, vacío si es del dominio
+	Message  string
+}
+
+// String renders the alert with its endpoint, e.g. "192.0.2.1: nuevas
+// vulnerabilidades: Heartbleed"
+func (a Alert) String() string {
+	if a.Endpoint == "" {
+		return a.Message
+	}
+	return a.Endpoint + ": " + a.Message
+}
+
+// alertLines renders each alert with String
+func alertLines(alerts []Alert) []string {
+	lines := make([]string, len(alerts))
+	for i, alert := range alerts {
+		lines[i] = alert.String()
+	}
+	return lines
+}
+
+// Title returns the one-line summary of the event, e.g. "SSL Labs:
+// example.com (grade B)" or "SSL Labs: 12 dominios con alertas"
+func (e NotificationEvent) Title() string {
+	if len(e.Batch) > 0 {
+		return fmt.Sprintf("SSL Labs: %d dominios con alertas", len(e.Batch))
+	}
+	return fmt.Sprintf("SSL Labs: %s (grade %s)", e.Domain, e.Grade)
+}
+
+// Lines returns the body of the event: one line per alert, or per domain
+// in a summary, with its first alert and how many more it has
+func (e NotificationEvent) Lines() []string {
+	if len(e.Batch) == 0 {
+		return alertLines(e.Alerts)
+	}
+	lines := make([]string, len(e.Batch))
+	for i, domain := range e.Batch {
+		lines[i] = fmt.Sprintf("%s (grade %s): %s", domain.Domain, domain.Grade, domain.Alerts[0])
+		if more := len(domain.Alerts) - 1; more > 0 {
+			lines[i] += fmt.Sprintf(" (+%d más)", more)
+		}
+	}
+	return lines
+}
+
+// Text returns the event as Slack mrkdwn: the title in bold and one
+// bullet per line
+func (e NotificationEvent) Text() string {
+	title := "*SSL Labs: " + e.Domain + "* (grade " + e.Grade + ")"
+	if len(e.Batch) > 0 {
+		title = "*" + e.Title() + "*"
+	}
+	return title + "\n• " + strings.Join(e.Lines(), "\n• ")
+}
+
+// NotifierFactory creates the notifier of a target URL. It validates the
+// URL, so configuration errors are reported before the first assessment.
+type NotifierFactory func(target *url.URL) (Notifier, error)
+
+// notifierProviders maps the URL schemes to the providers that handle them
+var notifierProviders = map[string]NotifierFactory{
+	"http":  newWebhookNotifier,
+	"https": newWebhookNotifier,
+	"slack": newSlackNotifier,
+	"smtp":  newEmailNotifier,
+	"smtps": newEmailNotifier,
+}
+
+// RegisterNotifier makes a provider available for the URLs with scheme,
+// replacing the one registered before. Providers compiled in by downstream
+// users call it from an init function of their own file.
+func RegisterNotifier(scheme string, factory NotifierFactory) {
+	notifierProviders[strings.ToLower(scheme)] = factory
+}
+
+// newNotifier creates the notifier of one target URL
+func newNotifier(target string) (Notifier, error) {
+	// Sin mostrar la URL completa: suele incluir un token o una contraseña
+	targetURL, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("URL de notificación inválida: %w", redactURLError(err))
+	}
+	factory, ok := notifierProviders[strings.ToLower(targetURL.Scheme)]
+	if !ok {
+		return nil, fmt.Errorf("URL de notificación inválida: se espera %s", notifierSchemes())
+	}
+	// El fragmento nunca se envía: ahí van las opciones de nebula para el destino
+	tmpl, err := notifyTemplateOption(targetURL.Fragment)
+	if err != nil {
+		return nil, fmt.Errorf("URL de notificación %s://: %w", targetURL.Scheme, err)
+	}
+	targetURL.Fragment, targetURL.RawFragment = "", ""
+	notifier, err := factory(targetURL)
+	if err != nil {
+		return nil, fmt.Errorf("URL de notificación %s://: %w", targetURL.Scheme, err)
+	}
+	if tmpl != nil {
+		notifier = &templatedNotifier{Notifier: notifier, template: tmpl}
+	}
+	return notifier, nil
+}
+
+// redactURLError drops the URL from an error of url.Parse or net/http
+func redactURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+// notifierSchemes lists the registered schemes, e.g. "http://, https://
+// o slack://"
+func notifierSchemes() string {
+	schemes := make([]string, 0, len(notifierProviders))
+	for scheme := range notifierProviders {
+		schemes = append(schemes, scheme+"://")
+	}
+	sort.Strings(schemes)
+	if len(schemes) == 1 {
+		return schemes[0]
+	}
+	return strings.Join(schemes[:len(schemes)-1], ", ") + " o " + schemes[len(schemes)-1]
+}
+
+// splitNotifyTargets splits a comma-separated list of notification URLs
+func splitNotifyTargets(value string) []string {
+	var targets []string
+	for _, target := range strings.Split(value, ",") {
+		if target = strings.TrimSpace(target); target != "" {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// validateNotifyTargets checks a comma-separated list of notification URLs
+func validateNotifyTargets(value string) error {
+	for _, target := range splitNotifyTargets(value) {
+		if _, err := newNotifier(target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// namedNotifier is a notifier with the scheme of its URL, to tell which
+// channel failed without logging secrets, and the limits of its target
+type namedNotifier struct {
+	Notifier
+	scheme  string
+	limiter *notifyLimiter // nil = sin límites
+}
+
+// Notifications raises the alerts of each assessment and delivers them
+// through every configured notifier
+type Notifications struct {
+	notifiers  []namedNotifier
+	expiryDays int                // Avisar si el certificado expira en N días o menos (0 = deshabilitado)
+	batchSize  int                // Dominios con alertas a partir de los cuales una ronda se resume (0 = nunca)
+	batch      *notificationBatch // Eventos retenidos hasta el fin de la ronda (ver Batched)
+	metrics    *NotificationMetrics
+}
+
+// notificationBatch holds the events of a run until Flush
+type notificationBatch struct {
+	events []NotificationEvent
+}
+
+// NewNotifications creates the notifications for a comma-separated list
+// of target URLs
+func NewNotifications(targets string, expiryDays int) (*Notifications, error) {
+	n := &Notifications{expiryDays: expiryDays, metrics: newNotificationMetrics()}
+	for _, target := range splitNotifyTargets(targets) {
+		notifier, err := newNotifier(target)
+		if err != nil {
+			return nil, err
+		}
+		scheme, _, _ := strings.Cut(target, ":")
+		n.notifiers = append(n.notifiers, namedNotifier{Notifier: notifier, scheme: strings.ToLower(scheme)})
+	}
+	return n, nil
+}
+
+// setLimits gives each target a cooldown per domain and kind of alert and
+// a maximum of notifications per hour (zero disables each one)
+func (n *Notifications) setLimits(cooldown time.Duration, maxPerHour int) {
+	if cooldown <= 0 && maxPerHour <= 0 {
+		return
+	}
+	for i := range n.notifiers {
+		n.notifiers[i].limiter = newNotifyLimiter(cooldown, maxPerHour)
+	}
+}
+
+// Events returns the alerts raised by an assessment compared to the
+// previous one of the same domain (nil if there is no previous assessment),
+// given the findings of the domain once the assessment is saved
+func (n *Notifications) Events(previous *store.Entry, result *AssessmentResult, findings []store.Finding, now time.Time) []Alert {
+	current := historyEntryFromResult(result)
+	var events []Alert
+
+	if previous != nil && compareGrades(current.OverallGrade, previous.OverallGrade) < 0 {
+		events = append(events, Alert{Kind: alertGradeDrop, Message: fmt.Sprintf("El grade bajó de %s a %s", previous.OverallGrade, current.OverallGrade)})
+	}
+
+	previousVulns := make(map[string][]string)
+	if previous != nil {
+		for _, endpoint := range previous.Endpoints {
+			previousVulns[endpoint.IPAddress] = endpoint.Vulnerabilities
+		}
+	}
+
+	for _, endpointErr := range result.EndpointErrors {
+		events = append(events, Alert{Kind: alertEndpointError, Endpoint: endpointErr.IPAddress, Message: fmt.Sprintf("no se pudo evaluar (%s)", endpointErr.Message)})
+	}
+
+	for _, endpoint := range result.Endpoints {
+		if endpoint.ProtocolStatus == ProtocolsNoneSecure {
+			events = append(events, Alert{Kind: alertInsecureProtocols, Endpoint: endpoint.IPAddress, Message: "CRÍTICO: el servidor solo ofrece protocolos inseguros"})
+		}
+	}
+
+	// Las vulnerabilidades reconocidas no vuelven a alertar, aunque la
+	// evaluación anterior (ej: una local) no las haya probado
+	acknowledged := acknowledgedVulnerabilities(findings)
+	for _, endpoint := range current.Endpoints {
+		added, _ := diffLists(previousVulns[endpoint.IPAddress], endpoint.Vulnerabilities)
+		added = slices.DeleteFunc(added, func(name string) bool { return slices.Contains(acknowledged[endpoint.IPAddress], name) })
+		if len(added) > 0 {
+			events = append(events, Alert{Kind: alertNewVulnerabilities, Endpoint: endpoint.IPAddress, Message: "nuevas vulnerabilidades: " + strings.Join(added, ", ")})
+		}
+
+		if n.expiryDays > 0 && endpoint.CertNotAfter > 0 {
+			days := daysUntil(endpoint.CertNotAfter, now)
+			switch {
+			case days < 0:
+				events = append(events, Alert{Kind: alertCertExpired, Endpoint: endpoint.IPAddress,
+					Message: "el certificado expiró el " + formatDate(time.UnixMilli(endpoint.CertNotAfter))})
+			case days <= n.expiryDays:
+				events = append(events, Alert{Kind: alertCertExpiring, Endpoint: endpoint.IPAddress, Message: "el certificado expira en " + describeExpiry(days, ExpiryThresholds{})})
+			}
+		}
+	}
+
+	for _, f := range reopenedFindings(findings, time.UnixMilli(result.TestTime)) {
+		events = append(events, Alert{Kind: alertFindingReopened, Endpoint: f.IPAddress,
+			Message: fmt.Sprintf("volvió a detectarse el hallazgo resuelto #%d: %s", f.ID, f.Detail)})
+	}
+
+	return events
+}
+
+// Batched returns notifications for a run (a scan or batch, or a round of
+// serve) that hold the events until Flush, so that a run with alerts in
+// many domains sends a single summary per target. Without a batch size it
+// returns n, which delivers right away. It accepts a nil n.
+func (n *Notifications) Batched() *Notifications {
+	if n == nil || n.batchSize == 0 {
+		return n
+	}
+	batched := *n
+	batched.batch = &notificationBatch{}
+	return &batched
+}
+
+// Notify delivers the alerts of an assessment through every notifier,
+// along with its metadata, or holds them until Flush if n is Batched. It
+// does nothing when there are no alerts. A failing notifier doesn't stop
+// the others.
+func (n *Notifications) Notify(ctx context.Context, result *AssessmentResult, alerts []Alert) error {
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	// Fechas en RFC 3339 con el offset de --tz
+	metadata := result.Metadata
+	metadata.StartedAt = metadata.StartedAt.In(outputLocation)
+	metadata.FinishedAt = metadata.FinishedAt.In(outputLocation)
+	event := NotificationEvent{Domain: result.Domain, Grade: result.OverallGrade, Alerts: alerts, Metadata: metadata, Result: result}
+	if n.batch != nil {
+		n.batch.events = append(n.batch.events, event)
+		return nil
+	}
+	return n.deliver(ctx, event)
+}
+
+// Flush delivers the events held by Batched notifications: a summary per
+// target if they are from batchSize domains or more, each one otherwise.
+func (n *Notifications) Flush(ctx context.Context) error {
+	if n == nil || n.batch == nil || len(n.batch.events) == 0 {
+		return nil
+	}
+	events := n.batch.events
+	n.batch.events = nil
+	if len(events) >= n.batchSize {
+		return n.deliver(ctx, NotificationEvent{Batch: events})
+	}
+	var errs []error
+	for _, event := range events {
+		if err := n.deliver(ctx, event); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", event.Domain, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// deliver sends an event through every notifier, within the limits of
+// each target
+func (n *Notifications) deliver(ctx context.Context, event NotificationEvent) error {
+	now := time.Now()
+	var errs []error
+	for _, notifier := range n.notifiers {
+		admitted, ok := n.limit(notifier, event, now)
+		if !ok {
+			continue
+		}
+		if err := notifier.Notify(ctx, admitted); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", notifier.scheme, err))
+			continue
+		}
+		n.metrics.countSent(notifier.scheme)
+	}
+	return errors.Join(errs...)
+}
+
+// recordAssessment stores a result in the history and sends the
+// notifications it raises. Both history and notifications are optional.
+// Errors are logged as warnings so they never abort a scan.
+func recordAssessment(history store.Store, notifications *Notifications, result *AssessmentResult) {
+	var previous *store.Entry
+	var findings []store.Finding
+	if history != nil {
+		entries, err := history.List(result.Domain, 1)
+		if err != nil {
+			slog.Warn("no se pudo leer el historial", "domain", result.Domain, "error", err)
+		} else if len(entries) > 0 {
+			previous = &entries[0]
+		}
+
+		if err := history.Save(storedAssessment(result)); err != nil {
+			slog.Warn("no se pudo guardar en el historial", "domain", result.Domain, "error", err)
+		} else if findings, err = history.Findings(store.FindingFilter{Domain: result.Domain}); err != nil {
+			slog.Warn("no se pudo leer el historial", "domain", result.Domain, "error", err)
+		}
+	}
+
+	if notifications != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		defer cancel()
+		alerts := notifications.Events(previous, result, findings, time.Now())
+		if err := notifications.Notify(ctx, result, alerts); err != nil {
+			slog.Warn("no se pudo enviar la notificación", "domain", result.Domain, "error", err)
+		}
+	}
+}
+
+// flushNotifications delivers the notifications held during a run.
+// Errors are logged as warnings, like in recordAssessment.
+func flushNotifications(notifications *Notifications) {
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	if err := notifications.Flush(ctx); err != nil {
+		slog.Warn("no se pudo enviar la notificación", "error", err)
+	}
+}
+
+// notifyFlags holds the flags that configure notifications
+type notifyFlags struct {
+	webhook    *string
+	expiryDays *int
+	batch      *int
+	cooldown   *time.Duration
+	maxPerHour *int
+}
+
+// addNotifyFlags registers the notification flags on fs
+func addNotifyFlags(fs *flag.FlagSet) *notifyFlags {
+	return &notifyFlags{
+		webhook:    fs.String("notify-webhook", os.Getenv("SSLLABS_WEBHOOK_URL"), "URLs separadas por comas donde notificar bajas de grade, vulnerabilidades nuevas y certificados por expirar: webhook (http[s]://), Slack (slack://) o email (smtp[s]://)"),
+		expiryDays: fs.Int("notify-expiry-days", 14, "notificar si un certificado expira en N días o menos (0 = deshabilitado)"),
+		batch:      fs.Int("notify-batch", defaultNotifyBatch, "si una ronda genera alertas en N dominios o más, enviar un solo resumen por destino al terminarla (0 = notificar cada dominio al momento)"),
+		cooldown:   fs.Duration("notify-cooldown", defaultNotifyCooldown, "no volver a notificar una misma alerta de un dominio a un destino antes de este tiempo (0 = deshabilitado)"),
+		maxPerHour: fs.Int("notify-max-per-hour", defaultNotifyMaxPerHour, "enviar como máximo N notificaciones por hora a cada destino, descartando las demás (0 = sin límite)"),
+	}
+}
+
+// notifications returns the configured notifications, or nil when no
+// target was given
+func (f *notifyFlags) notifications() (*Notifications, error) {
+	if len(splitNotifyTargets(*f.webhook)) == 0 {
+		return nil, nil
+	}
+	if *f.expiryDays < 0 {
+		return nil, fmt.Errorf("--notify-expiry-days no puede ser negativo")
+	}
+	if *f.batch < 0 {
+		return nil, fmt.Errorf("--notify-batch no puede ser negativo")
+	}
+	if *f.cooldown < 0 {
+		return nil, fmt.Errorf("--notify-cooldown no puede ser negativo")
+	}
+	if *f.maxPerHour < 0 {
+		return nil, fmt.Errorf("--notify-max-per-hour no puede ser negativo")
+	}
+	notifications, err := NewNotifications(*f.webhook, *f.expiryDays)
+	if err != nil {
+		return nil, fmt.Errorf("--notify-webhook: %w", err)
+	}
+	notifications.batchSize = *f.batch
+	notifications.setLimits(*f.cooldown, *f.maxPerHour)
+	return notifications, nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultNotifyCooldown   = time.Hour // Espera antes de volver a notificar una misma alerta de un dominio a un destino
+	defaultNotifyMaxPerHour = 30        // Notificaciones por hora a un mismo destino
+	notifyLimitWindow       = time.Hour // Ventana de --notify-max-per-hour
+)
+
+// Motivos por los que se descarta una notificación, como label de las métricas
+const (
+	notifyDropCooldown  = "cooldown"
+	notifyDropRateLimit = "rate_limit"
+)
+
+// notifyLimiter holds the limits of one notification target: a cooldown
+// per domain and kind of alert, so a flapping endpoint notifies once
+// instead of on every assessment while a different problem of the same
+// domain still gets through, and a maximum per hour, so a misconfigured
+// policy can't flood the channel. It is safe for concurrent use: the assessments
+// started through the API of serve notify from their own goroutines.
+type notifyLimiter struct {
+	mu         sync.Mutex
+	cooldown   time.Duration
+	maxPerHour int
+	notified   map[[2]string]time.Time // Última notificación de cada dominio y tipo de alerta
+	sent       []time.Time             // Envíos de la última hora, el más antiguo primero
+}
+
+// newNotifyLimiter creates a limiter; zero disables each limit
+func newNotifyLimiter(cooldown time.Duration, maxPerHour int) *notifyLimiter {
+	return &notifyLimiter{cooldown: cooldown, maxPerHour: maxPerHour, notified: make(map[[2]string]time.Time)}
+}
+
+// cooling reports whether an alert of kind was notified for domain less
+// than the cooldown ago
+func (l *notifyLimiter) cooling(domain, kind string, now time.Time) bool {
+	last, ok := l.notified[[2]string{domain, kind}]
+	return ok && l.cooldown > 0 && now.Sub(last) < l.cooldown
+}
+
+// take reserves a send in the window of the maximum per hour, and reports
+// false when it is exhausted
+func (l *notifyLimiter) take(now time.Time) bool {
+	if l.maxPerHour <= 0 {
+		return true
+	}
+	expired := 0
+	for expired < len(l.sent) && now.Sub(l.sent[expired]) >= notifyLimitWindow {
+		expired++
+	}
+	l.sent = l.sent[expired:]
+	if len(l.sent) >= l.maxPerHour {
+		return false
+	}
+	l.sent = append(l.sent, now)
+	return true
+}
+
+// admit applies the limits to event: the alerts in their cooldown are
+// dropped, and so are the domains left without alerts (from a summary, the
+// others are still sent), and the whole event past the maximum per hour. It returns what is left to send, if
+// anything, and the dropped domains by reason.
+func (l *notifyLimiter) admit(event NotificationEvent, now time.Time) (NotificationEvent, bool, map[string][]string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	dropped := make(map[string][]string)
+	domains := event.Batch
+	if len(domains) == 0 {
+		domains = []NotificationEvent{event}
+	}
+	var kept []NotificationEvent
+	for _, domain := range domains {
+		var alerts []Alert
+		for _, alert := range domain.Alerts {
+			if !l.cooling(domain.Domain, alert.Kind, now) {
+				alerts = append(alerts, alert)
+			}
+		}
+		if len(alerts) == 0 {
+			dropped[notifyDropCooldown] = append(dropped[notifyDropCooldown], domain.Domain)
+			continue
+		}
+		domain.Alerts = alerts
+		kept = append(kept, domain)
+	}
+	if len(kept) == 0 {
+		return event, false, dropped
+	}
+	if !l.take(now) {
+		for _, domain := range kept {
+			dropped[notifyDropRateLimit] = append(dropped[notifyDropRateLimit], domain.Domain)
+		}
+		return event, false, dropped
+	}
+
+	for _, domain := range kept {
+		for _, alert := range domain.Alerts {
+			l.notified[[2]string{domain.Domain, alert.Kind}] = now
+		}
+	}
+	// Un resumen con un solo dominio se envía como su notificación normal
+	if len(kept) == 1 {
+		return kept[0], true, dropped
+	}
+	event.Batch = kept
+	return event, true, dropped
+}
+
+// NotificationMetrics counts the notifications sent and dropped by the
+// limits, by channel (the scheme of the target, which has no secrets). It
+// is safe for concurrent use.
+type NotificationMetrics struct {
+	mu      sync.Mutex
+	sent    map[string]int
+	dropped map[[2]string]int // Canal y motivo
+}
+
+// newNotificationMetrics creates an empty set of notification metrics
+func newNotificationMetrics() *NotificationMetrics {
+	return &NotificationMetrics{sent: make(map[string]int), dropped: make(map[[2]string]int)}
+}
+
+// countSent records a notification delivered to channel
+func (m *NotificationMetrics) countSent(channel string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent[channel]++
+}
+
+// countDropped records the notifications of count domains dropped for
+// channel
+func (m *NotificationMetrics) countDropped(channel, reason string, count int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dropped[[2]string{channel, reason}] += count
+}
+
+// WriteMetrics writes the notification counters in the Prometheus text
+// format
+func (m *NotificationMetrics) WriteMetrics(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	channels := make([]string, 0, len(m.sent))
+	for channel := range m.sent {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	writeTypedHeader(w, "ssllabs_notifications_sent_total", "Notifications sent by channel, summaries counting once", "counter")
+	for _, channel := range channels {
+		fmt.Fprintf(w, "ssllabs_notifications_sent_total{channel=%s} %d\n", promLabel(channel), m.sent[channel])
+	}
+
+	keys := make([][2]string, 0, len(m.dropped))
+	for key := range m.dropped {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	writeTypedHeader(w, "ssllabs_notifications_dropped_total", "Domain notifications dropped by the cooldown or the hourly limit of the channel", "counter")
+	for _, key := range keys {
+		fmt.Fprintf(w, "ssllabs_notifications_dropped_total{channel=%s,reason=%s} %d\n", promLabel(key[0]), promLabel(key[1]), m.dropped[key])
+	}
+}
+
+// limit applies the limits of the target of notifier to event, logging and
+// counting what is dropped. It reports false when nothing is left to send.
+func (n *Notifications) limit(notifier namedNotifier, event NotificationEvent, now time.Time) (NotificationEvent, bool) {
+	if notifier.limiter == nil {
+		return event, true
+	}
+	event, ok, dropped := notifier.limiter.admit(event, now)
+	for _, reason := range []string{notifyDropCooldown, notifyDropRateLimit} {
+		if domains := dropped[reason]; len(domains) > 0 {
+			n.metrics.countDropped(notifier.scheme, reason, len(domains))
+			slog.Warn("notificación descartada por los límites del destino", "channel", notifier.scheme, "reason", reason, "domains", domains)
+		}
+	}
+	return event, ok
+}
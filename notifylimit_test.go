@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestNotifyCooldownPerKind checks that the cooldown of a domain only holds
+// back the kinds of alert already notified, not a new problem of the same
+// domain
+func TestNotifyCooldownPerKind(t *testing.T) {
+	limiter := newNotifyLimiter(time.Hour, 0)
+	now := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	event := func(kinds ...string) NotificationEvent {
+		e := NotificationEvent{Domain: "example.com"}
+		for _, kind := range kinds {
+			e.Alerts = append(e.Alerts, Alert{Kind: kind, Message: kind})
+		}
+		return e
+	}
+
+	if _, ok, _ := limiter.admit(event(alertGradeDrop), now); !ok {
+		t.Fatalf("la primera alerta del dominio se descartó")
+	}
+	// La misma alerta dentro del cooldown se descarta
+	if _, ok, dropped := limiter.admit(event(alertGradeDrop), now.Add(time.Minute)); ok || len(dropped[notifyDropCooldown]) != 1 {
+		t.Errorf("grade_drop repetido: enviado=%v, descartados=%v", ok, dropped)
+	}
+	// Otra alerta del mismo dominio se envía, sin la que está en espera
+	admitted, ok, _ := limiter.admit(event(alertGradeDrop, alertCertExpiring), now.Add(2*time.Minute))
+	if !ok || len(admitted.Alerts) != 1 || admitted.Alerts[0].Kind != alertCertExpiring {
+		t.Errorf("cert_expiring tras grade_drop: enviado=%v, alertas=%+v", ok, admitted.Alerts)
+	}
+	// Pasado el cooldown, la primera vuelve a notificarse
+	if _, ok, _ := limiter.admit(event(alertGradeDrop), now.Add(time.Hour)); !ok {
+		t.Errorf("grade_drop se descartó pasado el cooldown")
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// notifyTemplateFuncs are the functions available to notification
+// templates besides the built-in ones of text/template
+var notifyTemplateFuncs = template.FuncMap{
+	// json renders a value as JSON, to embed strings in JSON payloads
+	// with the right escaping
+	"json": func(value any) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	// date renders a time or a timestamp in milliseconds like the report
+	"date": func(value any) (string, error) {
+		switch v := value.(type) {
+		case time.Time:
+			return formatDate(v), nil
+		case int64:
+			return formatDate(time.UnixMilli(v)), nil
+		default:
+			return "", fmt.Errorf("date: se espera una fecha, se recibió %T", value)
+		}
+	},
+}
+
+// notifyTemplateOption parses the options in the fragment of a target URL
+// (#template=path) and returns the template it names, or nil without one
+func notifyTemplateOption(fragment string) (*template.Template, error) {
+	if fragment == "" {
+		return nil, nil
+	}
+	options, err := url.ParseQuery(fragment)
+	if err != nil {
+		return nil, fmt.Errorf("opciones inválidas después de #: %w", err)
+	}
+	for name := range options {
+		if name != "template" {
+			return nil, fmt.Errorf("opción desconocida #%s (se espera #template=archivo)", name)
+		}
+	}
+	return loadNotifyTemplate(options.Get("template"))
+}
+
+// loadNotifyTemplate parses the notification template in path
+func loadNotifyTemplate(path string) (*template.Template, error) {
+	if path == "" {
+		return nil, fmt.Errorf("falta el archivo de #template=")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo leer la plantilla: %w", err)
+	}
+	tmpl, err := template.New(filepath.Base(path)).Funcs(notifyTemplateFuncs).Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("plantilla inválida: %w", err)
+	}
+	return tmpl, nil
+}
+
+// templatedNotifier renders the message of each event with the template
+// of its target before handing it to the notifier
+type templatedNotifier struct {
+	Notifier
+	template *template.Template
+}
+
+// Notify renders the message and delivers the event. If the template
+// fails, the event is delivered in the default format so the alerts
+// aren't lost, and the error is returned to be logged.
+func (n *templatedNotifier) Notify(ctx context.Context, event NotificationEvent) error {
+	var message strings.Builder
+	renderErr := n.template.Execute(&message, event)
+	if renderErr == nil {
+		event.Message = message.String()
+	} else {
+		renderErr = fmt.Errorf("la plantilla falló, se envió el formato por defecto: %w", renderErr)
+	}
+	if err := n.Notifier.Notify(ctx, event); err != nil {
+		return err
+	}
+	return renderErr
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"slices"
+	"time"
+)
+
+const (
+	ocspProbeTimeout = 10 * time.Second // Espera máxima por cada responder
+	ocspSlowLatency  = 2 * time.Second  // Latencia a partir de la cual un responder se considera lento
+	ocspClockSkew    = 5 * time.Minute  // Tolerancia para thisUpdate en el futuro
+	ocspMaxResponse  = 1 << 20          // Tamaño máximo de una respuesta
+)
+
+// Estado del certificado según el responder
+const (
+	ocspGood    = "good"
+	ocspRevoked = "revoked"
+	ocspUnknown = "unknown"
+)
+
+var (
+	oidSHA1      = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidOCSPBasic = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+)
+
+// ocspSignatureAlgorithms maps the signature OIDs OCSP responders use in
+// practice to their x509 algorithm (RSA-PSS is not supported)
+var ocspSignatureAlgorithms = []struct {
+	oid       asn1.ObjectIdentifier
+	algorithm x509.SignatureAlgorithm
+}{
+	{asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 5}, x509.SHA1WithRSA},
+	{asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}, x509.SHA256WithRSA},
+	{asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 12}, x509.SHA384WithRSA},
+	{asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 13}, x509.SHA512WithRSA},
+	{asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 1}, x509.ECDSAWithSHA1},
+	{asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}, x509.ECDSAWithSHA256},
+	{asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}, x509.ECDSAWithSHA384},
+	{asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}, x509.ECDSAWithSHA512},
+	{asn1.ObjectIdentifier{1, 3, 101, 112}, x509.PureEd25519},
+}
+
+// ocspResponseStatuses describes the OCSPResponseStatus values (RFC 6960)
+var ocspResponseStatuses = map[asn1.Enumerated]string{
+	1: "malformedRequest",
+	2: "internalError",
+	3: "tryLater",
+	5: "sigRequired",
+	6: "unauthorized",
+}
+
+// Estructuras ASN.1 de RFC 6960
+type ocspCertID struct {
+	HashAlgorithm  pkix.AlgorithmIdentifier
+	IssuerNameHash []byte
+	IssuerKeyHash  []byte
+	SerialNumber   *big.Int
+}
+
+type ocspRequest struct {
+	TBSRequest ocspTBSRequest
+}
+
+type ocspTBSRequest struct {
+	RequestList []ocspSingleRequest
+}
+
+type ocspSingleRequest struct {
+	CertID ocspCertID
+}
+
+type ocspResponse struct {
+	Status        asn1.Enumerated
+	ResponseBytes struct {
+		ResponseType asn1.ObjectIdentifier
+		Response     []byte
+	} `asn1:"explicit,tag:0,optional"`
+}
+
+type ocspBasicResponse struct {
+	TBSResponseData    ocspResponseData
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+	Certificates       []asn1.RawValue `asn1:"explicit,tag:0,optional"`
+}
+
+type ocspResponseData struct {
+	Raw                asn1.RawContent
+	Version            int `asn1:"optional,default:0,explicit,tag:0"`
+	ResponderID        asn1.RawValue
+	ProducedAt         time.Time `asn1:"generalized"`
+	Responses          []ocspSingleResponse
+	ResponseExtensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+type ocspSingleResponse struct {
+	CertID     ocspCertID
+	Good       asn1.Flag        `asn1:"tag:0,optional"`
+	Revoked    asn1.RawValue    `asn1:"tag:1,optional"`
+	Unknown    asn1.Flag        `asn1:"tag:2,optional"`
+	ThisUpdate time.Time        `asn1:"generalized"`
+	NextUpdate time.Time        `asn1:"generalized,explicit,tag:0,optional"`
+	Extensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+// OCSPProbe is the result of querying the OCSP responder of a certificate
+// of the chain (--probe-ocsp)
+type OCSPProbe struct {
+	Cert       string        // Certificado consultado (label de la cadena)
+	URL        string        // URL del responder
+	Latency    time.Duration // Tiempo hasta recibir la respuesta completa
+	Status     string        // good, revoked o unknown (vacío si no hubo respuesta válida)
+	ThisUpdate time.Time
+	NextUpdate time.Time // Cero si el responder no la indica
+	Problems   []string  // Motivos por los que el responder se considera roto o degradado
+}
+
+// Broken reports whether the responder failed or answered something a
+// client performing revocation checks can't rely on
+func (p OCSPProbe) Broken() bool {
+	return len(p.Problems) > 0
+}
+
+// ocspProber is an Assessor that, after each assessment, probes the OCSP
+// responders of the chains served by the endpoints
+type ocspProber struct {
+	Assessor
+	client *http.Client
+}
+
+// withOCSPProbe wraps scanner so that its results include OCSP probes
+func withOCSPProbe(scanner Assessor) Assessor {
+	return &ocspProber{Assessor: scanner, client: &http.Client{Timeout: ocspProbeTimeout}}
+}
+
+// AssessContext runs the assessment and probes the responders of every
+// endpoint. Interrupted assessments are returned without probing.
+func (p *ocspProber) AssessContext(ctx context.Context, domain string) (*AssessmentResult, error) {
+	result, err := p.Assessor.AssessContext(ctx, domain)
+	if err != nil {
+		return result, err
+	}
+
+	// Los endpoints suelen servir la misma cadena: cada responder se
+	// consulta una vez por certificado
+	probed := make(map[string]OCSPProbe)
+	for i := range result.Endpoints {
+		endpoint := &result.Endpoints[i]
+		if endpoint.Details == nil || endpoint.Details.Chain == nil {
+			continue
+		}
+		for _, target := range ocspTargets(endpoint.Details.Chain) {
+			key := target.url + "|" + target.cert.SerialNumber.String()
+			probe, ok := probed[key]
+			if !ok {
+				probe = probeOCSP(ctx, p.client, target)
+				probed[key] = probe
+			}
+			endpoint.OCSP = append(endpoint.OCSP, probe)
+		}
+	}
+	return result, nil
+}
+
+// ocspTarget is a certificate to check with one of its OCSP responders
+type ocspTarget struct {
+	label  string
+	url    string
+	cert   *x509.Certificate
+	issuer *x509.Certificate
+}
+
+// ocspTargets returns the certificates of the chain with an OCSP URL whose
+// issuer is also in the chain, which the request needs
+func ocspTargets(chain *Chain) []ocspTarget {
+	certs := parseChainCerts(chain)
+	var targets []ocspTarget
+	for i, cert := range certs {
+		if cert == nil || len(cert.OCSPServer) == 0 {
+			continue
+		}
+		issuer := findIssuer(cert, certs)
+		if issuer == nil {
+			continue
+		}
+		for _, url := range cert.OCSPServer {
+			targets = append(targets, ocspTarget{label: chain.Certs[i].displayLabel(), url: url, cert: cert, issuer: issuer})
+		}
+	}
+	return targets
+}
+
+// probeOCSP queries the responder of target and checks the answer: the
+// response status, the signature (by the issuer or a responder delegated
+// by it), the certificate status and the thisUpdate/nextUpdate window
+func probeOCSP(ctx context.Context, client *http.Client, target ocspTarget) OCSPProbe {
+	probe := OCSPProbe{Cert: target.label, URL: target.url}
+	fail := func(format string, args ...any) OCSPProbe {
+		probe.Problems = append(probe.Problems, fmt.Sprintf(format, args...))
+		return probe
+	}
+
+	certID, err := newOCSPCertID(target.cert, target.issuer)
+	if err != nil {
+		return fail(tr("no se pudo armar la consulta: %s"), err)
+	}
+	request := ocspRequest{TBSRequest: ocspTBSRequest{RequestList: []ocspSingleRequest{{CertID: certID}}}}
+	body, err := asn1.Marshal(request)
+	if err != nil {
+		return fail(tr("no se pudo armar la consulta: %s"), err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.url, bytes.NewReader(body))
+	if err != nil {
+		return fail(tr("URL inválida: %s"), err)
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+	req.Header.Set("Accept", "application/ocsp-response")
+
+	started := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return fail(tr("sin respuesta: %s"), err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, ocspMaxResponse))
+	probe.Latency = time.Since(started)
+	if err != nil {
+		return fail(tr("respuesta incompleta: %s"), err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fail(tr("respondió HTTP %d"), resp.StatusCode)
+	}
+	if probe.Latency >= ocspSlowLatency {
+		probe.Problems = append(probe.Problems, fmt.Sprintf(tr("lento: %s (los clientes suelen abandonar a los pocos segundos)"), probe.Latency.Round(time.Millisecond)))
+	}
+
+	single, err := parseOCSPResponse(data, certID, target.issuer)
+	if err != nil {
+		return fail("%s", err)
+	}
+
+	probe.ThisUpdate, probe.NextUpdate = single.ThisUpdate, single.NextUpdate
+	switch {
+	case bool(single.Good):
+		probe.Status = ocspGood
+	case bool(single.Unknown):
+		probe.Status = ocspUnknown
+		probe.Problems = append(probe.Problems, tr("el responder no conoce el certificado (unknown)"))
+	default:
+		probe.Status = ocspRevoked
+	}
+
+	now := time.Now()
+	if single.ThisUpdate.After(now.Add(ocspClockSkew)) {
+		probe.Problems = append(probe.Problems, fmt.Sprintf(tr("thisUpdate en el futuro (%s)"), formatDateTime(single.ThisUpdate)))
+	}
+	if !single.NextUpdate.IsZero() && single.NextUpdate.Before(now) {
+		probe.Problems = append(probe.Problems, fmt.Sprintf(tr("respuesta vencida: nextUpdate %s"), formatDateTime(single.NextUpdate)))
+	}
+	return probe
+}
+
+// newOCSPCertID identifies cert in requests and responses, with SHA-1
+// hashes of the issuer name and key as most responders expect
+func newOCSPCertID(cert, issuer *x509.Certificate) (ocspCertID, error) {
+	var spki struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(issuer.RawSubjectPublicKeyInfo, &spki); err != nil {
+		return ocspCertID{}, fmt.Errorf(tr("clave pública del emisor inválida: %w"), err)
+	}
+	nameHash := sha1.Sum(issuer.RawSubject)
+	keyHash := sha1.Sum(spki.PublicKey.RightAlign())
+	return ocspCertID{
+		HashAlgorithm:  pkix.AlgorithmIdentifier{Algorithm: oidSHA1, Parameters: asn1.NullRawValue},
+		IssuerNameHash: nameHash[:],
+		IssuerKeyHash:  keyHash[:],
+		SerialNumber:   cert.SerialNumber,
+	}, nil
+}
+
+// parseOCSPResponse decodes a response, verifies its signature and
+// returns the single response for certID
+func parseOCSPResponse(data []byte, certID ocspCertID, issuer *x509.Certificate) (*ocspSingleResponse, error) {
+	var response ocspResponse
+	if rest, err := asn1.Unmarshal(data, &response); err != nil || len(rest) > 0 {
+		return nil, errors.New(tr("respuesta OCSP ilegible"))
+	}
+	if response.Status != 0 {
+		name, ok := ocspResponseStatuses[response.Status]
+		if !ok {
+			name = fmt.Sprintf(tr("estado %d"), response.Status)
+		}
+		return nil, fmt.Errorf(tr("el responder devolvió un error: %s"), name)
+	}
+	if !response.ResponseBytes.ResponseType.Equal(oidOCSPBasic) {
+		return nil, fmt.Errorf(tr("tipo de respuesta no soportado: %s"), response.ResponseBytes.ResponseType)
+	}
+
+	var basic ocspBasicResponse
+	if _, err := asn1.Unmarshal(response.ResponseBytes.Response, &basic); err != nil {
+		return nil, fmt.Errorf(tr("respuesta OCSP ilegible: %w"), err)
+	}
+	if err := verifyOCSPSignature(&basic, issuer); err != nil {
+		return nil, err
+	}
+
+	for i, single := range basic.TBSResponseData.Responses {
+		if single.CertID.SerialNumber.Cmp(certID.SerialNumber) == 0 &&
+			bytes.Equal(single.CertID.IssuerKeyHash, certID.IssuerKeyHash) {
+			return &basic.TBSResponseData.Responses[i], nil
+		}
+	}
+	return nil, errors.New(tr("la respuesta no incluye el certificado consultado"))
+}
+
+// verifyOCSPSignature checks that the response is signed by the issuer or
+// by a certificate it delegated OCSP signing to
+func verifyOCSPSignature(basic *ocspBasicResponse, issuer *x509.Certificate) error {
+	algorithm := x509.UnknownSignatureAlgorithm
+	for _, known := range ocspSignatureAlgorithms {
+		if known.oid.Equal(basic.SignatureAlgorithm.Algorithm) {
+			algorithm = known.algorithm
+		}
+	}
+	if algorithm == x509.UnknownSignatureAlgorithm {
+		return fmt.Errorf(tr("algoritmo de firma no soportado: %s"), basic.SignatureAlgorithm.Algorithm)
+	}
+
+	signer := issuer
+	if len(basic.Certificates) > 0 {
+		delegated, err := x509.ParseCertificate(basic.Certificates[0].FullBytes)
+		if err != nil {
+			return fmt.Errorf(tr("certificado del responder inválido: %w"), err)
+		}
+		if !bytes.Equal(delegated.Raw, issuer.Raw) {
+			if err := delegated.CheckSignatureFrom(issuer); err != nil {
+				return fmt.Errorf(tr("el certificado del responder no fue emitido por el emisor: %w"), err)
+			}
+			if !slices.Contains(delegated.ExtKeyUsage, x509.ExtKeyUsageOCSPSigning) {
+				return errors.New(tr("el certificado del responder no tiene el uso OCSP Signing"))
+			}
+			signer = delegated
+		}
+	}
+
+	if err := signer.CheckSignature(algorithm, basic.TBSResponseData.Raw, basic.Signature.RightAlign()); err != nil {
+		return fmt.Errorf(tr("firma inválida: %w"), err)
+	}
+	return nil
+}
+
+// displayOCSP prints the OCSP responders probed for an endpoint
+func displayOCSP(probes []OCSPProbe) {
+	fmt.Print(tr("Responders OCSP:\n"))
+	for _, probe := range probes {
+		line := fmt.Sprintf("%s (%s)", probe.URL, probe.Cert)
+		if probe.Status != "" {
+			line += fmt.Sprintf(tr(": %s en %s"), probe.Status, probe.Latency.Round(time.Millisecond))
+			if !probe.NextUpdate.IsZero() {
+				line += fmt.Sprintf(tr(", válida hasta %s"), formatDateTime(probe.NextUpdate))
+			}
+		}
+		switch {
+		case probe.Broken():
+			fmt.Printf("  %s\n", paint(colorRed, "❌ "+line))
+			for _, problem := range probe.Problems {
+				fmt.Printf("     %s\n", paint(colorRed, problem))
+			}
+		case probe.Status == ocspRevoked:
+			fmt.Printf("  %s\n", paint(colorRed, "❌ "+line))
+		default:
+			fmt.Printf("  %s\n", paint(colorGreen, "✅ "+line))
+		}
+	}
+}
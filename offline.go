@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// offlineConflicts are the flags that assess or record the domain, which
+// make no sense when displaying a saved assessment with --offline
+var offlineConflicts = append(slices.Clone(airGappedConflicts), "air-gapped", "ca-file", "input")
+
+// savedAssessor is an Assessor that returns an assessment saved with
+// --save instead of assessing the domain again (--offline)
+type savedAssessor struct {
+	result *AssessmentResult
+}
+
+// AssessContext returns the saved assessment
+func (s savedAssessor) AssessContext(ctx context.Context, domain string) (*AssessmentResult, error) {
+	return s.result, nil
+}
+
+// loadOffline reads the assessment of --offline, rejecting the flags and
+// domains that would assess a host
+func loadOffline(fs *flag.FlagSet, path string) (*AssessmentResult, error) {
+	var conflicts []string
+	fs.Visit(func(f *flag.Flag) {
+		if slices.Contains(offlineConflicts, f.Name) {
+			conflicts = append(conflicts, "--"+f.Name)
+		}
+	})
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("%s no se puede usar con --offline: la evaluación ya está hecha", strings.Join(conflicts, ", "))
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("--offline no recibe dominios: el dominio es el de la evaluación guardada")
+	}
+	return loadAssessmentFile(path)
+}
+
+// validateSavePath checks that --save can hold the assessments of count
+// domains: a single file holds one, a directory one file per domain
+func validateSavePath(path string, count int) error {
+	if path == "" || count <= 1 || isSaveDir(path) {
+		return nil
+	}
+	return fmt.Errorf("--save %s guarda una sola evaluación: para %d dominios usa un directorio (terminado en /)", path, count)
+}
+
+// isSaveDir reports whether --save names a directory, existing or ending in a separator
+func isSaveDir(path string) bool {
+	if strings.HasSuffix(path, "/") || strings.HasSuffix(path, string(filepath.Separator)) {
+		return true
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// saveAssessment writes the API response of result (its Host) as JSON to
+// path, or to <domain>.json inside it when path is a directory. The file
+// is read back with --offline or diff.
+func saveAssessment(path string, result *AssessmentResult) (string, error) {
+	if result.Host == nil {
+		return "", fmt.Errorf(tr("%s: la evaluación no tiene una respuesta para guardar"), result.Domain)
+	}
+	if isSaveDir(path) {
+		path = filepath.Join(path, result.Domain+".json")
+	}
+	data, err := json.MarshalIndent(result.Host, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", result.Domain, err)
+	}
+	return path, writeFileAtomic(path, append(data, '\n'))
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// Valores por defecto de la configuración
+const (
+	defaultHTTPTimeout        = 30 * time.Second
+	defaultAssessmentTimeout  = 10 * time.Minute
+	defaultPollInterval       = 5 * time.Second
+	defaultInProgressInterval = 10 * time.Second
+	defaultDetailsTimeout     = 2 * time.Minute
+)
+
+// RateLimiter spaces the requests sent to the API. Implementations must be
+// safe for concurrent use, since one client is shared across goroutines.
+type RateLimiter interface {
+	Wait()
+}
+
+// Option configures an HTTPClient or a Scanner. Options that don't apply
+// to the object being built are ignored, so the same list can be passed
+// to NewHTTPClient and NewScanner.
+type Option func(*options)
+
+// options holds the configuration collected from the Option list
+type options struct {
+	apiVersion        int
+	email             string
+	baseURL           string // Vacío: se deriva de apiVersion
+	timeout           time.Duration
+	transport         http.RoundTripper
+	proxy             *url.URL
+	limiter           RateLimiter
+	logger            *slog.Logger
+	middleware        []Middleware
+	retry             retryPolicy
+	networkRetry      retryPolicy
+	client            *HTTPClient // Cliente existente para NewScanner
+	assessmentTimeout time.Duration
+	pollInterval      time.Duration
+	inProgressPoll    time.Duration
+	fromCache         bool
+	maxAge            time.Duration
+	reuse             bool
+	reporter          *Reporter
+	progressive       bool
+	detailsTimeout    time.Duration
+	ignoreMismatch    bool
+	publish           bool
+}
+
+// durationOr returns d, or fallback when d is not positive
+func durationOr(d, fallback time.Duration) time.Duration {
+	if d <= 0 {
+		return fallback
+	}
+	return d
+}
+
+// newOptions applies opts over the defaults
+func newOptions(opts []Option) *options {
+	o := &options{
+		apiVersion:        apiVersionV2,
+		timeout:           defaultHTTPTimeout,
+		limiter:           newRateLimiter(defaultRequestInterval),
+		logger:            slog.New(slog.DiscardHandler),
+		reporter:          NewReporter(os.Stderr),
+		retry:             retryPolicy{maxRetries: defaultMaxRetries, baseDelay: defaultRetryBase, maxDelay: defaultRetryMax},
+		networkRetry:      retryPolicy{maxRetries: defaultNetworkRetries, baseDelay: defaultRetryDelay, maxDelay: defaultRetryMax},
+		assessmentTimeout: defaultAssessmentTimeout,
+		pollInterval:      defaultPollInterval,
+		inProgressPoll:    defaultInProgressInterval,
+		detailsTimeout:    defaultDetailsTimeout,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithAPIVersion selects the API version (apiVersionV2 by default)
+func WithAPIVersion(version int) Option {
+	return func(o *options) {
+		o.apiVersion = version
+	}
+}
+
+// WithEmail sets the registered email sent in the "email" header (required by API v4)
+func WithEmail(email string) Option {
+	return func(o *options) {
+		o.email = email
+	}
+}
+
+// WithBaseURL overrides the API base URL derived from the API version
+func WithBaseURL(baseURL string) Option {
+	return func(o *options) {
+		o.baseURL = baseURL
+	}
+}
+
+// WithTimeout sets the timeout of each HTTP request (30s by default)
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithTransport sets the http.RoundTripper used for the requests
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *options) {
+		o.transport = transport
+	}
+}
+
+// WithProxy sends every request through the given proxy (http, https,
+// socks5 or socks5h), overriding the HTTP_PROXY/HTTPS_PROXY environment
+// variables. It has no effect if WithTransport sets a RoundTripper that is
+// not an *http.Transport.
+func WithProxy(proxyURL *url.URL) Option {
+	return func(o *options) {
+		o.proxy = proxyURL
+	}
+}
+
+// WithRateLimiter replaces the default limiter (one request per second).
+// A nil limiter disables rate limiting.
+func WithRateLimiter(limiter RateLimiter) Option {
+	return func(o *options) {
+		o.limiter = limiter
+	}
+}
+
+// WithLogger sets the structured logger for requests, retries and
+// assessments (discarded by default)
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithRetries sets how many times a request rejected with 429, 503 or 529
+// is retried (3 by default, 0 disables retries). The wait honors the
+// Retry-After header or else backs off exponentially from baseDelay up to
+// maxDelay, with jitter.
+func WithRetries(maxRetries int, baseDelay, maxDelay time.Duration) Option {
+	return func(o *options) {
+		o.retry = retryPolicy{maxRetries: maxRetries, baseDelay: baseDelay, maxDelay: maxDelay}
+	}
+}
+
+// WithNetworkRetries sets how many times a GET request is retried after a
+// transient failure: a connection reset or refused, a timeout, a temporary
+// DNS error or a 500, 502 or 504 response (2 by default, 0 disables
+// retries). The wait starts at delay and doubles on each retry, with
+// jitter. Other methods are never retried, since they may not be idempotent.
+func WithNetworkRetries(retries int, delay time.Duration) Option {
+	return func(o *options) {
+		o.networkRetry = retryPolicy{maxRetries: retries, baseDelay: delay, maxDelay: defaultRetryMax}
+	}
+}
+
+// WithMiddleware adds request hooks to the client, run after the internal
+// ones (registration header, rate limiting and logging). It can be given
+// several times; middlewares run in the order they were added.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(o *options) {
+		o.middleware = append(o.middleware, middleware...)
+	}
+}
+
+// WithMetrics counts every request of the client in metrics
+func WithMetrics(metrics *RequestMetrics) Option {
+	return WithMiddleware(metrics.Middleware())
+}
+
+// WithClient makes NewScanner use an existing client instead of building one
+func WithClient(client *HTTPClient) Option {
+	return func(o *options) {
+		o.client = client
+	}
+}
+
+// WithPollIntervals sets the wait between status polls until the
+// assessment is IN_PROGRESS (5s by default) and while it is (10s by default)
+func WithPollIntervals(interval, inProgress time.Duration) Option {
+	return func(o *options) {
+		o.pollInterval = interval
+		o.inProgressPoll = inProgress
+	}
+}
+
+// WithFromCache makes the scanner accept a cached SSL Labs result instead
+// of starting a new assessment. maxAge limits how old the cached result may
+// be (rounded up to hours); 0 leaves it to the API.
+func WithFromCache(maxAge time.Duration) Option {
+	return func(o *options) {
+		o.fromCache = true
+		o.maxAge = maxAge
+	}
+}
+
+// WithStartNew controls whether the scanner forces a new assessment
+// (startNew=on, the default). With false it attaches to an assessment in
+// progress, or returns the latest finished one, which saves API quota.
+func WithStartNew(startNew bool) Option {
+	return func(o *options) {
+		o.reuse = !startNew
+	}
+}
+
+// WithReporter sets where the scanner shows assessment progress (stderr by
+// default, so stdout only carries results). Scanners running concurrently should share one Reporter; nil
+// disables progress output.
+func WithReporter(reporter *Reporter) Option {
+	return func(o *options) {
+		o.reporter = reporter
+	}
+}
+
+// WithProgressive makes the scanner poll with all=on and report each
+// endpoint's certificate, protocols and grade as soon as the API returns
+// them, before the assessment is READY
+func WithProgressive() Option {
+	return func(o *options) {
+		o.progressive = true
+	}
+}
+
+// WithAssessmentTimeout sets the maximum duration of an assessment (10m by default)
+func WithAssessmentTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.assessmentTimeout = timeout
+	}
+}
+
+// WithDetailsTimeout sets how long to keep re-polling a Ready endpoint that
+// has no details yet (2m by default). After that it is reported as missing data.
+func WithDetailsTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.detailsTimeout = timeout
+	}
+}
+
+// WithIgnoreMismatch sends ignoreMismatch=on so the assessment proceeds
+// even when the certificate doesn't match the hostname, e.g. on staging
+// hosts. The API ignores it when it returns a cached report.
+func WithIgnoreMismatch() Option {
+	return func(o *options) {
+		o.ignoreMismatch = true
+	}
+}
+
+// WithPublish sends publish=on so the results appear on the public SSL
+// Labs boards. Results are private (publish=off) by default.
+func WithPublish() Option {
+	return func(o *options) {
+		o.publish = true
+	}
+}
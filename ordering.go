@@ -0,0 +1,21 @@
+package main
+
+import (
+	"slices"
+
+	"ssllabs-scanner/store"
+)
+
+// sortResult orders endpoints and endpoint errors by IP address, so the
+// output, history and diffs don't depend on the order of the API arrays
+func sortResult(result *AssessmentResult) {
+	slices.SortStableFunc(result.Endpoints, func(a, b EndpointResult) int {
+		return store.CompareIPs(a.IPAddress, b.IPAddress)
+	})
+	slices.SortStableFunc(result.EndpointErrors, func(a, b EndpointError) int {
+		return store.CompareIPs(a.IPAddress, b.IPAddress)
+	})
+	for i := range result.Endpoints {
+		slices.Sort(result.Endpoints[i].TLSProtocols)
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Policy is a set of declarative TLS requirements that assessments are
+// checked against (--policy). Zero values disable a rule.
+type Policy struct {
+	MinGrade              string `yaml:"minGrade"`              // Grade mínimo de cada endpoint, ej: A
+	MinProtocol           string `yaml:"minProtocol"`           // Versión mínima de TLS ofrecida, ej: 1.2
+	MinRSAKeySize         int    `yaml:"minRSAKeySize"`         // Tamaño mínimo de las claves RSA
+	MinECKeySize          int    `yaml:"minECKeySize"`          // Tamaño mínimo de las claves EC
+	MinExpiryDays         int    `yaml:"minExpiryDays"`         // Días mínimos de vigencia del certificado
+	NoVulnerabilities     bool   `yaml:"noVulnerabilities"`     // Ninguna vulnerabilidad conocida
+	NoWeakCiphers         bool   `yaml:"noWeakCiphers"`         // Ninguna cipher suite débil (ver weakSuite)
+	RequireForwardSecrecy bool   `yaml:"requireForwardSecrecy"` // Forward Secrecy con algún cliente
+	RequireHSTS           bool   `yaml:"requireHSTS"`           // Header HSTS presente
+	TrustedChain          bool   `yaml:"trustedChain"`          // Sin problemas de confianza en el certificado
+}
+
+// tlsVersions are the versions accepted in minProtocol, oldest first
+var tlsVersions = []string{"1.0", "1.1", "1.2", "1.3"}
+
+// LoadPolicy reads and validates the policy file at path. Unknown keys
+// are rejected so a typo doesn't silently disable a rule.
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo leer la política: %w", err)
+	}
+
+	var policy Policy
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&policy); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("política inválida %s: %w", path, err)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("política inválida %s: %w", path, err)
+	}
+	if len(policy.rules()) == 0 {
+		return nil, fmt.Errorf("política inválida %s: no define ninguna regla", path)
+	}
+	return &policy, nil
+}
+
+// Validate checks the values of the policy
+func (p *Policy) Validate() error {
+	if err := validateMinGrade(p.MinGrade); err != nil {
+		return fmt.Errorf("minGrade: %w", err)
+	}
+	p.MinProtocol = strings.TrimPrefix(strings.TrimSpace(p.MinProtocol), "TLS ")
+	if p.MinProtocol != "" && !slices.Contains(tlsVersions, p.MinProtocol) {
+		return fmt.Errorf("minProtocol: versión inválida %q: se espera 1.0, 1.1, 1.2 o 1.3", p.MinProtocol)
+	}
+	if p.MinRSAKeySize < 0 || p.MinECKeySize < 0 || p.MinExpiryDays < 0 {
+		return fmt.Errorf("los tamaños de clave y los días no pueden ser negativos")
+	}
+	return nil
+}
+
+// policyRule is one requirement of a policy. check returns why an
+// endpoint doesn't meet it, or "" if it does.
+type policyRule struct {
+	id    string // Clave de la regla en el archivo de la política, ej: minGrade
+	name  string
+	check func(endpoint *EndpointResult) string
+}
+
+// RuleResult is the outcome of one policy rule for a domain
+type RuleResult struct {
+	ID       string // Identificador estable de la regla (la clave en la política)
+	Rule     string
+	Failures []string // Un motivo por endpoint que no cumple la regla
+}
+
+// Passed reports whether every endpoint meets the rule
+func (r RuleResult) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// rules returns the enabled rules of the policy. A rule that lacks the
+// data to be checked (no details, local assessment) is not met.
+func (p *Policy) rules() []policyRule {
+	var rules []policyRule
+	if p.MinGrade != "" {
+		rules = append(rules, policyRule{"minGrade", "Grade >= " + p.MinGrade, func(e *EndpointResult) string {
+			if _, ok := gradeOrder[e.Grade]; !ok {
+				return tr("sin grade")
+			}
+			if compareGrades(e.Grade, p.MinGrade) < 0 {
+				return "grade " + e.Grade
+			}
+			return ""
+		}})
+	}
+	if p.MinProtocol != "" {
+		rules = append(rules, policyRule{"minProtocol", fmt.Sprintf(tr("Sin protocolos anteriores a TLS %s"), p.MinProtocol), func(e *EndpointResult) string {
+			if e.Details == nil || len(e.Details.Protocols) == 0 {
+				return tr("sin datos de protocolos")
+			}
+			var old []string
+			for _, protocol := range e.Details.Protocols {
+				if protocol.Name != "TLS" || protocol.Version < p.MinProtocol {
+					old = append(old, protocol.Name+" "+protocol.Version)
+				}
+			}
+			if len(old) > 0 {
+				return fmt.Sprintf(tr("ofrece %s"), strings.Join(old, ", "))
+			}
+			return ""
+		}})
+	}
+	keyRule := func(alg string, minSize int) policyRule {
+		return policyRule{"min" + alg + "KeySize", fmt.Sprintf(tr("Claves %s de al menos %d bits"), alg, minSize), func(e *EndpointResult) string {
+			keyAlg, size := endpointKey(e.Details)
+			switch {
+			case keyAlg == "":
+				return tr("sin datos de la clave")
+			case keyAlg == alg && size < minSize:
+				return fmt.Sprintf(tr("clave %s de %d bits"), keyAlg, size)
+			}
+			return ""
+		}}
+	}
+	if p.MinRSAKeySize > 0 {
+		rules = append(rules, keyRule("RSA", p.MinRSAKeySize))
+	}
+	if p.MinECKeySize > 0 {
+		rules = append(rules, keyRule("EC", p.MinECKeySize))
+	}
+	if p.MinExpiryDays > 0 {
+		rules = append(rules, policyRule{"minExpiryDays", fmt.Sprintf(tr("Certificado vigente por al menos %d días"), p.MinExpiryDays), func(e *EndpointResult) string {
+			switch {
+			case e.CertValidTo == 0:
+				return tr("sin datos del certificado")
+			case e.CertDaysRemaining < 0:
+				return tr("certificado expirado")
+			case e.CertDaysRemaining < p.MinExpiryDays:
+				return fmt.Sprintf(tr("expira en %d días"), e.CertDaysRemaining)
+			}
+			return ""
+		}})
+	}
+	if p.NoVulnerabilities {
+		rules = append(rules, policyRule{"noVulnerabilities", tr("Sin vulnerabilidades conocidas"), func(e *EndpointResult) string {
+			switch {
+			case e.Details == nil:
+				return tr("sin datos")
+			case e.Details.Local:
+				return tr("no evaluadas (evaluación local)")
+			case len(e.Vulnerabilities) > 0:
+				return fmt.Sprintf(tr("vulnerable a %s"), strings.Join(e.Vulnerabilities, ", "))
+			}
+			return ""
+		}})
+	}
+	if p.NoWeakCiphers {
+		rules = append(rules, policyRule{"noWeakCiphers", tr("Sin cipher suites débiles"), func(e *EndpointResult) string {
+			if e.Details == nil || len(e.Details.Suites) == 0 {
+				return tr("sin datos de cipher suites")
+			}
+			var weak []string
+			for _, suites := range e.Details.Suites {
+				for _, suite := range suites.List {
+					if reason := weakSuite(suite); reason != "" && !slices.Contains(weak, suite.Name+" ("+reason+")") {
+						weak = append(weak, suite.Name+" ("+reason+")")
+					}
+				}
+			}
+			if len(weak) > 0 {
+				return fmt.Sprintf(tr("acepta %s"), strings.Join(weak, ", "))
+			}
+			return ""
+		}})
+	}
+	if p.RequireForwardSecrecy {
+		rules = append(rules, policyRule{"requireForwardSecrecy", "Forward Secrecy", func(e *EndpointResult) string {
+			switch {
+			case e.Details == nil:
+				return tr("sin datos")
+			case e.Details.ForwardSecrecy == 0:
+				return tr("sin Forward Secrecy")
+			}
+			return ""
+		}})
+	}
+	if p.RequireHSTS {
+		rules = append(rules, policyRule{"requireHSTS", "HSTS", func(e *EndpointResult) string {
+			switch {
+			case e.Details == nil:
+				return tr("sin datos")
+			case e.Details.Local:
+				return tr("no evaluado (evaluación local)")
+			case e.Details.HSTSPolicy == nil || e.Details.HSTSPolicy.Status != "present":
+				return "HSTS " + describeHSTS(e.Details.HSTSPolicy)
+			}
+			return ""
+		}})
+	}
+	if p.TrustedChain {
+		rules = append(rules, policyRule{"trustedChain", tr("Certificado confiable"), func(e *EndpointResult) string {
+			switch {
+			case e.Details == nil || e.Details.Cert == nil:
+				return tr("sin datos del certificado")
+			case e.Details.Cert.Issues != 0:
+				if issues := chainIssues(&EndpointDetails{Cert: e.Details.Cert}); len(issues) > 0 {
+					return strings.Join(issues, "; ")
+				}
+				return fmt.Sprintf(tr("problemas del certificado (issues=%d)"), e.Details.Cert.Issues)
+			}
+			return ""
+		}})
+	}
+	return rules
+}
+
+// Evaluate checks every endpoint of result against the policy
+func (p *Policy) Evaluate(result *AssessmentResult) []RuleResult {
+	var results []RuleResult
+	for _, rule := range p.rules() {
+		outcome := RuleResult{ID: rule.id, Rule: rule.name}
+		for i := range result.Endpoints {
+			endpoint := &result.Endpoints[i]
+			if failure := rule.check(endpoint); failure != "" {
+				outcome.Failures = append(outcome.Failures, endpoint.IPAddress+": "+failure)
+			}
+		}
+		for _, endpointErr := range result.EndpointErrors {
+			outcome.Failures = append(outcome.Failures, endpointErr.IPAddress+tr(": no se pudo evaluar"))
+		}
+		results = append(results, outcome)
+	}
+	return results
+}
+
+// weakSuite returns why a cipher suite is weak, or "" if it isn't: SSL
+// Labs marks it insecure, it uses RC4, NULL, export-grade, anonymous or
+// single DES cryptography, or it offers less than 128 bits (3DES)
+func weakSuite(suite Suite) string {
+	name := strings.ToUpper(suite.Name)
+	switch {
+	case strings.Contains(name, "NULL"):
+		return tr("sin cifrado")
+	case strings.Contains(name, "EXPORT"):
+		return "export"
+	case strings.Contains(name, "_ANON_"):
+		return tr("sin autenticación")
+	case strings.Contains(name, "RC4"):
+		return "RC4"
+	case suite.Q != nil && *suite.Q == 0:
+		return tr("insegura")
+	case suite.CipherStrength > 0 && suite.CipherStrength < 128:
+		return fmt.Sprintf("%d bits", suite.CipherStrength)
+	}
+	return ""
+}
+
+// endpointKey returns the algorithm and size of the server key, from
+// details.key (API v2 and local assessments) or the certificate (v3/v4)
+func endpointKey(d *EndpointDetails) (string, int) {
+	switch {
+	case d == nil:
+		return "", 0
+	case d.Key != nil:
+		return d.Key.Alg, d.Key.Size
+	case d.Cert != nil:
+		return d.Cert.KeyAlg, d.Cert.KeySize
+	}
+	return "", 0
+}
+
+// displayPolicy prints the outcome of each rule under title and returns
+// whether all of them passed
+func displayPolicy(title string, results []RuleResult) bool {
+	passed := true
+	fmt.Printf("=== %s ===\n", title)
+	for _, result := range results {
+		if result.Passed() {
+			fmt.Printf("%s\n", paint(colorGreen, "✅ "+result.Rule))
+			continue
+		}
+		passed = false
+		fmt.Printf("%s\n", paint(colorRed, "❌ "+result.Rule))
+		for _, failure := range result.Failures {
+			fmt.Printf("   %s\n", failure)
+		}
+	}
+	fmt.Println()
+	return passed
+}
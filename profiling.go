@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"path/filepath"
+	"runtime"
+	runtimepprof "runtime/pprof"
+	"time"
+)
+
+// profilingFlags holds the profiling flags of the long-running modes
+type profilingFlags struct {
+	listen           *string
+	snapshotDir      *string
+	snapshotInterval *time.Duration
+}
+
+// addProfilingFlags registers the profiling flags on fs
+func addProfilingFlags(fs *flag.FlagSet) *profilingFlags {
+	return &profilingFlags{
+		listen:           fs.String("pprof", "", "dirección donde exponer net/http/pprof, ej: localhost:6060 (vacío = deshabilitado)"),
+		snapshotDir:      fs.String("heap-snapshot-dir", "", "directorio donde guardar snapshots periódicos del heap (vacío = deshabilitado)"),
+		snapshotInterval: fs.Duration("heap-snapshot-interval", time.Hour, "intervalo entre snapshots del heap"),
+	}
+}
+
+// start launches the pprof server and the heap snapshot loop when enabled
+func (f *profilingFlags) start() error {
+	if *f.snapshotDir != "" {
+		if *f.snapshotInterval <= 0 {
+			return fmt.Errorf("--heap-snapshot-interval debe ser positivo")
+		}
+		if err := os.MkdirAll(*f.snapshotDir, 0o755); err != nil {
+			return fmt.Errorf("no se pudo crear %s: %w", *f.snapshotDir, err)
+		}
+		go heapSnapshotLoop(*f.snapshotDir, *f.snapshotInterval)
+	}
+
+	if *f.listen != "" {
+		go func() {
+			fmt.Printf("Exponiendo pprof en %s/debug/pprof/\n", *f.listen)
+			if err := http.ListenAndServe(*f.listen, pprofMux()); err != nil {
+				slog.Warn("servidor pprof detenido", "error", err)
+			}
+		}()
+	}
+	return nil
+}
+
+// pprofMux returns a mux with the net/http/pprof handlers. They are
+// registered explicitly so they are never exposed on the metrics listener.
+func pprofMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// heapSnapshotLoop writes a heap profile to dir every interval
+func heapSnapshotLoop(dir string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		if err := writeHeapSnapshot(dir, now); err != nil {
+			slog.Warn("no se pudo guardar el snapshot del heap", "error", err)
+		}
+	}
+}
+
+// writeHeapSnapshot writes the current heap profile to dir/heap-<timestamp>.pprof
+func writeHeapSnapshot(dir string, now time.Time) error {
+	path := filepath.Join(dir, "heap-"+now.Format("20060102-150405")+".pprof")
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("no se pudo crear el snapshot del heap: %w", err)
+	}
+	defer file.Close()
+
+	// Un GC antes del snapshot refleja la memoria viva y no la basura pendiente
+	runtime.GC()
+	if err := runtimepprof.WriteHeapProfile(file); err != nil {
+		return fmt.Errorf("no se pudo escribir el snapshot del heap: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// partialFindings returns the endpoint information that arrived since the
+// previous poll of an all=on assessment: the certificate, the supported
+// protocols and the grade are each reported once per endpoint, as soon as
+// the API includes them. seen keeps track of what was already reported.
+func partialFindings(host *Host, seen map[string]bool) []string {
+	var findings []string
+	report := func(endpoint Endpoint, kind, text string) {
+		key := endpoint.IPAddress + "/" + kind
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		findings = append(findings, fmt.Sprintf("🔎 %s: %s", endpoint.IPAddress, text))
+	}
+
+	for _, endpoint := range host.Endpoints {
+		details := endpoint.Details
+		if details == nil {
+			continue
+		}
+
+		if cert := details.Cert; cert != nil && cert.NotAfter > 0 {
+			report(endpoint, "cert", fmt.Sprintf(tr("certificado de %s, válido hasta %s · %s"), cert.IssuerLabel,
+				formatDate(time.UnixMilli(cert.NotAfter)), describeExpiry(daysUntil(cert.NotAfter, time.Now()), ExpiryThresholds{})))
+		}
+
+		if len(details.Protocols) > 0 {
+			var protocols []string
+			for _, protocol := range details.Protocols {
+				protocols = append(protocols, protocol.Name+" "+protocol.Version)
+			}
+			report(endpoint, "protocols", fmt.Sprintf(tr("protocolos %s"), strings.Join(protocols, ", ")))
+		}
+
+		if endpoint.Grade != "" {
+			report(endpoint, "grade", "grade "+endpoint.Grade)
+		}
+	}
+
+	return findings
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// parseProxyURL validates the --proxy value. Supported schemes are http,
+// https, socks5 and socks5h (DNS resolved by the proxy).
+func parseProxyURL(value string) (*url.URL, error) {
+	proxyURL, err := url.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("URL de proxy inválida %q: %w", value, err)
+	}
+
+	switch strings.ToLower(proxyURL.Scheme) {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("esquema de proxy no soportado %q (valores posibles: http, https, socks5, socks5h)", proxyURL.Scheme)
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("URL de proxy sin host: %q", value)
+	}
+	return proxyURL, nil
+}
+
+// proxyTransport returns a copy of base that sends every request through
+// proxyURL. base defaults to http.DefaultTransport; a custom RoundTripper
+// that is not an *http.Transport is returned unchanged.
+func proxyTransport(base http.RoundTripper, proxyURL *url.URL) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	transport, ok := base.(*http.Transport)
+	if !ok {
+		return base
+	}
+
+	transport = transport.Clone()
+	transport.Proxy = http.ProxyURL(proxyURL)
+	return transport
+}
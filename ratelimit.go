@@ -0,0 +1,42 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultRequestInterval is the minimum time between two API requests
+// made through the same HTTPClient
+const defaultRequestInterval = 1 * time.Second
+
+// rateLimiter spaces requests at least interval apart. It is safe for
+// concurrent use: each caller reserves the next free slot under the
+// mutex and then sleeps outside of it.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time // Primer instante libre para la siguiente petición
+}
+
+// newRateLimiter creates a limiter allowing one request per interval
+func newRateLimiter(interval time.Duration) *rateLimiter {
+	return &rateLimiter{interval: interval}
+}
+
+// Wait blocks until the caller is allowed to send a request
+func (l *rateLimiter) Wait() {
+	if l == nil || l.interval <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	time.Sleep(slot.Sub(now))
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// capacityAPI is an /analyze endpoint that reports X-Max-Assessments and
+// X-Current-Assessments and records the requests, to check what a shared
+// HTTPClient sends from several goroutines
+type capacityAPI struct {
+	max int
+
+	mu         sync.Mutex
+	polls      map[string]int  // Consultas de cada dominio desde su startNew
+	running    map[string]bool // Evaluaciones iniciadas y aún no terminadas
+	finished   map[string]bool // Evaluaciones terminadas, que se siguen respondiendo READY
+	peak       int             // Máximo de evaluaciones simultáneas
+	violations []string        // startNew recibidos sin capacidad
+	arrivals   []time.Time
+}
+
+func newCapacityAPI(max int) *capacityAPI {
+	return &capacityAPI{max: max, polls: make(map[string]int), running: make(map[string]bool), finished: make(map[string]bool)}
+}
+
+func (a *capacityAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	domain := query.Get("host")
+
+	a.mu.Lock()
+	a.arrivals = append(a.arrivals, time.Now())
+	host := &Host{Host: domain, Port: 443, Protocol: "http", Status: statusInProgress}
+	switch {
+	case query.Get("startNew") == "on":
+		if len(a.running) >= a.max {
+			a.violations = append(a.violations, fmt.Sprintf("%s con %d evaluaciones en curso", domain, len(a.running)))
+		}
+		a.running[domain] = true
+		a.peak = max(a.peak, len(a.running))
+		a.polls[domain] = 0
+		delete(a.finished, domain)
+	case a.running[domain]:
+		a.polls[domain]++
+		if a.polls[domain] >= 2 {
+			// La evaluación termina con esta respuesta
+			delete(a.running, domain)
+			a.finished[domain] = true
+		}
+	}
+	if a.finished[domain] {
+		host.Status = statusReady
+		host.Endpoints = []Endpoint{{
+			IPAddress:     "192.0.2.1",
+			StatusMessage: endpointStatusReady,
+			Grade:         "A",
+			Progress:      100,
+			Details:       &EndpointDetails{Protocols: []Protocol{{Name: "TLS", Version: "1.3"}}},
+		}}
+	}
+	w.Header().Set("X-Max-Assessments", strconv.Itoa(a.max))
+	w.Header().Set("X-Current-Assessments", strconv.Itoa(len(a.running)))
+	a.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(host)
+}
+
+// TestHTTPClientConcurrentUse sends assessments and plain Analyze calls
+// from several goroutines through one HTTPClient. Run with -race.
+func TestHTTPClientConcurrentUse(t *testing.T) {
+	const (
+		maxAssessments = 2
+		interval       = 20 * time.Millisecond
+	)
+	api := newCapacityAPI(maxAssessments)
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	client := NewHTTPClient(WithBaseURL(server.URL), WithRateLimiter(newRateLimiter(interval)), WithRetries(0, 0, 0))
+	opts := PollOptions{Interval: time.Millisecond, InProgressInterval: time.Millisecond, Timeout: time.Minute}
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			domain := fmt.Sprintf("scan%d.example", i)
+			host, err := PollAssessment(ctx, client, domain, opts)
+			if err == nil && host.Status != statusReady {
+				err = fmt.Errorf("%s: estado %s", domain, host.Status)
+			}
+			if err != nil {
+				errs <- err
+			}
+		}()
+	}
+	for i := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Consultas sin startNew: no ocupan capacidad
+			if _, err := client.AnalyzeContext(ctx, fmt.Sprintf("status%d.example", i), AnalyzeParams{}); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if len(api.violations) > 0 {
+		t.Errorf("startNew sin capacidad: %v", api.violations)
+	}
+	if api.peak > maxAssessments {
+		t.Errorf("%d evaluaciones simultáneas, el máximo es %d", api.peak, maxAssessments)
+	}
+	if len(api.running) > 0 {
+		t.Errorf("evaluaciones sin terminar: %v", api.running)
+	}
+
+	// El rate limiter es compartido: ninguna petición sale antes de su turno
+	arrivals := slices.Clone(api.arrivals)
+	slices.SortFunc(arrivals, func(a, b time.Time) int { return a.Compare(b) })
+	if total := arrivals[len(arrivals)-1].Sub(arrivals[0]); total < time.Duration(len(arrivals)-1)*interval*3/4 {
+		t.Errorf("%d peticiones en %s: el rate limit de %s no se respetó", len(arrivals), total, interval)
+	}
+
+	client.capacity.mu.Lock()
+	active := client.capacity.active
+	client.capacity.mu.Unlock()
+	if active != 0 {
+		t.Errorf("quedaron %d evaluaciones activas en el cliente", active)
+	}
+	if maxReported, current := client.AssessmentLimits(); maxReported != maxAssessments || current != 0 {
+		t.Errorf("AssessmentLimits() = %d, %d; se esperaba %d, 0", maxReported, current, maxAssessments)
+	}
+}
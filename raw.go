@@ -0,0 +1,22 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// displayRawHost prints the Host of an assessment as JSON, before
+// ProcessResults summarizes it (--raw). Local assessments have no API
+// response, so the Host built by LocalScanner is printed instead.
+func displayRawHost(host *Host, source string) {
+	title := tr("Respuesta de la API")
+	if source == sourceLocal {
+		title = tr("Datos de la evaluación local")
+	}
+	data, err := json.MarshalIndent(host, "", "  ")
+	if err != nil {
+		fmt.Printf("%s\n", paint(colorRed, tr("❌ Error: no se pudo generar el JSON: ")+err.Error()))
+		return
+	}
+	fmt.Printf("=== %s ===\n%s\n", title, data)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/netip"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"ssllabs-scanner/store"
+)
+
+// suiteFindingRules are the rules that depend on the accepted cipher
+// suites. Only these need the handshake per suite when re-checked.
+var suiteFindingRules = map[string]bool{"rc4": true, "weak_ciphers": true, "no_forward_secrecy": true, "no_aead": true}
+
+// recheck probes the endpoint of f, and nothing else, and reports whether
+// its rule is still detected and with what detail. It fails, without
+// judging the finding, if the endpoint can't be assessed.
+func (s *LocalScanner) recheck(ctx context.Context, f store.Finding) (bool, string, error) {
+	addr, err := netip.ParseAddr(f.IPAddress)
+	if err != nil {
+		return false, "", fmt.Errorf("el hallazgo #%d tiene una dirección inválida %q", f.ID, f.IPAddress)
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	endpoint := s.probeEndpoint(ctx, f.Domain, addr, suiteFindingRules[f.Rule])
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		return false, "", fmt.Errorf("%s: %w", f.Domain, ErrInterrupted)
+	case ctx.Err() != nil:
+		return false, "", fmt.Errorf("%s: %w", f.Domain, ErrTimeout)
+	case endpoint.Details == nil:
+		return false, "", fmt.Errorf("no se pudo re-verificar %s [%s]: %s", f.Domain, f.IPAddress, endpoint.StatusMessage)
+	}
+	result := EndpointResult{IPAddress: endpoint.IPAddress, Grade: endpoint.Grade, Details: endpoint.Details}
+	for _, reason := range explainGrade(&result) {
+		if reason.ID == f.Rule {
+			return true, reason.Reason, nil
+		}
+	}
+	return false, "", nil
+}
+
+// runRecheck implements the "recheck" subcommand: it confirms whether a
+// finding is still present by probing locally only its endpoint, with only
+// the handshakes its rule needs, and records the result in the history
+func runRecheck(args []string) error {
+	fs := flag.NewFlagSet("recheck", flag.ExitOnError)
+	path := fs.String("history-db", defaultHistoryPath(), historyDBUsage)
+	caFile := fs.String("ca-file", "", "bundle PEM de CAs adicionales en las que confiar (el mismo que se usó al evaluar con --local)")
+	timeout := fs.Duration("timeout", defaultAssessmentTimeout, "duración máxima de la prueba")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s recheck [--history-db archivo] [--ca-file bundle.pem] [--timeout duración] <id>\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("se requiere el ID de un hallazgo")
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(fs.Arg(0), "#"), 10, 64)
+	if err != nil || id <= 0 {
+		return fmt.Errorf("ID de hallazgo inválido %q", fs.Arg(0))
+	}
+
+	history, err := store.Open(*path)
+	if err != nil {
+		return err
+	}
+	defer history.Close()
+	findings, err := history.Findings(store.FindingFilter{ID: id})
+	if err != nil {
+		return err
+	}
+	if len(findings) == 0 {
+		return fmt.Errorf("%w: #%d", store.ErrFindingNotFound, id)
+	}
+	f := findings[0]
+	// Lo que la evaluación local no prueba (HSTS, vulnerabilidades...) solo
+	// lo confirma SSL Labs
+	if !localFindingRules[f.Rule] {
+		return fmt.Errorf("la regla %s no se puede comprobar con una prueba local: vuelve a evaluar el dominio con %s scan %s", f.Rule, os.Args[0], f.Domain)
+	}
+
+	trust, err := loadTrustStore(defaultTrustStorePath())
+	if err == nil && *caFile != "" {
+		err = trust.AddFile(*caFile)
+	}
+	if err != nil {
+		return err
+	}
+	local := NewLocalScanner(trust, *timeout)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	fmt.Fprintf(os.Stderr, "Re-verificando #%d (%s) en %s [%s]...\n", f.ID, f.Rule, f.Domain, f.IPAddress)
+	detected, detail, err := local.recheck(ctx, f)
+	if err != nil {
+		return err
+	}
+	updated, err := history.RecheckFinding(f.ID, detected, detail, time.UnixMilli(time.Now().UnixMilli()))
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case !detected && f.State == store.FindingResolved:
+		fmt.Printf("✅ #%d %s: sigue sin detectarse\n", f.ID, f.Rule)
+	case !detected:
+		fmt.Printf("✅ #%d %s: ya no se detecta, pasa a %s\n", f.ID, f.Rule, updated.State)
+	case f.State == store.FindingResolved:
+		fmt.Printf("⚠️  #%d %s: volvió a detectarse, pasa a %s: %s\n", f.ID, f.Rule, updated.State, detail)
+	default:
+		fmt.Printf("⚠️  #%d %s: sigue presente (%s): %s\n", f.ID, f.Rule, updated.State, detail)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// RegisterRequest is the body of the API v4 /register endpoint
+type RegisterRequest struct {
+	FirstName    string `json:"firstName"`
+	LastName     string `json:"lastName"`
+	Email        string `json:"email"`
+	Organization string `json:"organization"`
+}
+
+// RegisterResponse is the response of the API v4 /register endpoint
+type RegisterResponse struct {
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+// Register registers an organization email with the SSL Labs API (v4 only).
+// The registered email must then be sent in every request (see --email).
+func (c *HTTPClient) Register(reg RegisterRequest) (*RegisterResponse, error) {
+	payload, err := json.Marshal(reg)
+	if err != nil {
+		return nil, fmt.Errorf("error generando petición de registro: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.baseURL+registerEndpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("error creando petición: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	body, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp RegisterResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("error parseando respuesta JSON: %w", err)
+	}
+
+	return &resp, nil
+}
+
+// runRegister implements the "register" subcommand
+func runRegister(args []string) error {
+	fs := flag.NewFlagSet("register", flag.ExitOnError)
+	firstName := fs.String("first-name", "", "nombre")
+	lastName := fs.String("last-name", "", "apellido")
+	email := fs.String("email", os.Getenv("SSLLABS_EMAIL"), "email de la organización (no se aceptan proveedores gratuitos como gmail)")
+	organization := fs.String("organization", "", "nombre de la organización")
+	apiURL := fs.String("api-url", os.Getenv("SSLLABS_API_URL"), "URL base de una API v4 compatible (también SSLLABS_API_URL)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s register --first-name <nombre> --last-name <apellido> --email <email> --organization <organización>\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	reg := RegisterRequest{
+		FirstName:    *firstName,
+		LastName:     *lastName,
+		Email:        *email,
+		Organization: *organization,
+	}
+	if reg.FirstName == "" || reg.LastName == "" || reg.Email == "" || reg.Organization == "" {
+		fs.Usage()
+		return fmt.Errorf("todos los campos de registro son requeridos")
+	}
+
+	// El registro solo existe en la API v4
+	opts := []Option{WithAPIVersion(apiVersionV4)}
+	if *apiURL != "" {
+		baseURL, err := parseAPIURL(*apiURL)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, WithBaseURL(baseURL))
+	}
+	client := NewHTTPClient(opts...)
+	resp, err := client.Register(reg)
+	if err != nil {
+		return fmt.Errorf("error registrando email: %w", err)
+	}
+
+	fmt.Printf("Registro %s: %s\n", resp.Status, resp.Message)
+	fmt.Printf("Usa --email %s (o SSLLABS_EMAIL) para evaluar con la API v4\n", reg.Email)
+	return nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/debug"
+)
+
+// ReleaseInfo describes the running binary for packaging automation
+// (Homebrew, Scoop) and for checking that a deployed binary matches its
+// release
+type ReleaseInfo struct {
+	Version   string `json:"version"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	Asset     string `json:"asset"`  // Nombre del binario en la release (ver self-update)
+	SHA256    string `json:"sha256"` // Checksum del ejecutable en ejecución
+	GoVersion string `json:"goVersion"`
+	Module    string `json:"module,omitempty"`
+	Revision  string `json:"revision,omitempty"` // Commit de git del build
+	BuildTime string `json:"buildTime,omitempty"`
+	Modified  bool   `json:"modified"` // El build incluía cambios sin commitear
+}
+
+// currentReleaseInfo collects the release information of the running
+// binary from its embedded build info
+func currentReleaseInfo() (*ReleaseInfo, error) {
+	info := &ReleaseInfo{
+		Version:   toolVersion(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		Asset:     releaseAssetName(),
+		GoVersion: runtime.Version(),
+	}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		info.Module = build.Main.Path
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				info.Revision = setting.Value
+			case "vcs.time":
+				info.BuildTime = setting.Value
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+
+	sum, err := executableChecksum()
+	if err != nil {
+		return nil, err
+	}
+	info.SHA256 = sum
+	return info, nil
+}
+
+// executableChecksum returns the SHA-256 of the running binary
+func executableChecksum() (string, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("no se pudo ubicar el binario actual: %w", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("no se pudo leer el binario actual: %w", err)
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", fmt.Errorf("no se pudo leer el binario actual: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// runReleaseInfo implements the "release-info" subcommand
+func runReleaseInfo(args []string) error {
+	fs := flag.NewFlagSet("release-info", flag.ExitOnError)
+	format := fs.String("format", "text", "formato de salida: text o json")
+	checksums := fs.String("checksums", "", "checksums.txt de la release esperada: falla si el binario no coincide")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s release-info [--format text|json] [--checksums checksums.txt]\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("formato inválido %q: se espera text o json", *format)
+	}
+
+	info, err := currentReleaseInfo()
+	if err != nil {
+		return err
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(info); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("Versión:    %s\n", info.Version)
+		fmt.Printf("Plataforma: %s/%s (%s)\n", info.OS, info.Arch, info.Asset)
+		fmt.Printf("SHA-256:    %s\n", info.SHA256)
+		fmt.Printf("Go:         %s\n", info.GoVersion)
+		if info.Revision != "" {
+			modified := ""
+			if info.Modified {
+				modified = " (con cambios sin commitear)"
+			}
+			fmt.Printf("Commit:     %s%s\n", info.Revision, modified)
+		}
+		if info.BuildTime != "" {
+			fmt.Printf("Fecha:      %s\n", info.BuildTime)
+		}
+	}
+
+	if *checksums == "" {
+		return nil
+	}
+	sums, err := os.ReadFile(*checksums)
+	if err != nil {
+		return fmt.Errorf("no se pudo leer %s: %w", *checksums, err)
+	}
+	expected, err := findChecksum(sums, *checksums, info.Asset)
+	if err != nil {
+		return err
+	}
+	if expected != info.SHA256 {
+		return fmt.Errorf("el binario no coincide con la release: %s espera %s", *checksums, expected)
+	}
+	fmt.Fprintf(os.Stderr, "✅ El binario coincide con %s\n", *checksums)
+	return nil
+}
+
+// runVersion implements the "version" subcommand
+func runVersion(args []string) error {
+	fs := flag.NewFlagSet("version", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s version\n\nPara los metadatos completos del binario: %s release-info\n", os.Args[0], os.Args[0])
+	}
+	fs.Parse(args)
+
+	fmt.Printf("nebula %s (%s, %s/%s)\n", toolVersion(), runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	return nil
+}
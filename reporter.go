@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// defaultRedrawInterval is the minimum time between two redraws of the
+// progress block on a terminal
+const defaultRedrawInterval = 200 * time.Millisecond
+
+// Reporter renders assessment progress. It owns the output so concurrent
+// assessments can report safely: on a terminal it keeps one live line per
+// domain and redraws them in place (at most once per redraw interval);
+// otherwise it writes a line each time a domain's status changes.
+type Reporter struct {
+	mu             sync.Mutex
+	w              io.Writer
+	tty            bool
+	redrawInterval time.Duration
+	active         []string          // Dominios en curso, en orden de inicio
+	status         map[string]string // Último mensaje de cada dominio en curso
+	drawn          int               // Líneas del bloque dibujado en la terminal
+	concurrent     bool              // Hubo más de un dominio en curso a la vez
+	lastDraw       time.Time
+}
+
+// NewReporter creates a reporter writing to w. Live redraws are only used
+// when w is a terminal.
+func NewReporter(w io.Writer) *Reporter {
+	return &Reporter{
+		w:              w,
+		tty:            isTerminal(w),
+		redrawInterval: defaultRedrawInterval,
+		status:         make(map[string]string),
+	}
+}
+
+// isTerminal reports whether w is a character device such as a terminal
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Progress records the current status message of a domain. Empty messages
+// are ignored.
+func (r *Reporter) Progress(domain, message string) {
+	if r == nil || message == "" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	previous, ok := r.status[domain]
+	if !ok {
+		r.active = append(r.active, domain)
+		if len(r.active) > 1 {
+			r.concurrent = true
+		}
+	}
+	r.status[domain] = message
+
+	if !r.tty {
+		if message != previous {
+			fmt.Fprintln(r.w, r.label(domain)+message)
+		}
+		return
+	}
+
+	if time.Since(r.lastDraw) >= r.redrawInterval {
+		r.redraw()
+	}
+}
+
+// Done removes a domain from the live block, printing its final message
+func (r *Reporter) Done(domain, message string) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	label := r.label(domain)
+	delete(r.status, domain)
+	for i, name := range r.active {
+		if name == domain {
+			r.active = append(r.active[:i], r.active[i+1:]...)
+			break
+		}
+	}
+
+	r.clear()
+	if message != "" {
+		fmt.Fprintln(r.w, label+message)
+	}
+	r.redraw()
+}
+
+// Printf writes a message above the live block
+func (r *Reporter) Printf(format string, args ...any) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.clear()
+	fmt.Fprintf(r.w, format, args...)
+	r.redraw()
+}
+
+// label prefixes messages with the domain once several domains have been
+// in progress at the same time. Must be called with mu held.
+func (r *Reporter) label(domain string) string {
+	if r.concurrent {
+		return domain + ": "
+	}
+	return ""
+}
+
+// clear erases the live block from the terminal. Must be called with mu held.
+func (r *Reporter) clear() {
+	if !r.tty || r.drawn == 0 {
+		return
+	}
+	// Subir al inicio del bloque y borrar hasta el final de la pantalla
+	fmt.Fprintf(r.w, "\033[%dA\r\033[J", r.drawn)
+	r.drawn = 0
+}
+
+// redraw draws one line per domain in progress. Must be called with mu held.
+func (r *Reporter) redraw() {
+	if !r.tty {
+		return
+	}
+	r.clear()
+	for _, domain := range r.active {
+		fmt.Fprintf(r.w, "%s%s\n", r.label(domain), r.status[domain])
+	}
+	r.drawn = len(r.active)
+	r.lastDraw = time.Now()
+}
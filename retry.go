@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Valores por defecto de los reintentos ante 429/503/529
+const (
+	defaultMaxRetries = 3
+	defaultRetryBase  = 5 * time.Second
+	defaultRetryMax   = 2 * time.Minute
+)
+
+// Valores por defecto de los reintentos ante errores de red y 5xx
+const (
+	defaultNetworkRetries = 2
+	defaultRetryDelay     = 2 * time.Second
+)
+
+// retryPolicy controls how requests rejected with 429, 503 or 529 are retried
+type retryPolicy struct {
+	maxRetries int           // Reintentos después del primer intento (0 = no reintentar)
+	baseDelay  time.Duration // Espera antes del primer reintento
+	maxDelay   time.Duration // Tope del backoff exponencial (no aplica a Retry-After)
+}
+
+// isRetryableStatus reports whether a status code means the API is
+// throttling or temporarily unavailable
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable, 529:
+		return true
+	}
+	return false
+}
+
+// isTransientStatus reports whether a status code is a server error that
+// may not repeat, such as a 502 from a proxy in front of the API. 503 and
+// 529 are left to isRetryableStatus.
+func isTransientStatus(status int) bool {
+	switch status {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isTransientError reports whether a connection error may not repeat:
+// connections reset or refused, timeouts and temporary DNS failures
+func isTransientError(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTemporary || dnsErr.IsTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	for _, target := range []error{syscall.ECONNRESET, syscall.ECONNREFUSED, syscall.ECONNABORTED, syscall.EPIPE, io.EOF, io.ErrUnexpectedEOF} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// delay returns how long to wait before retry number attempt (0-based).
+// A valid Retry-After header is honored as is; otherwise the delay grows
+// exponentially from baseDelay up to maxDelay, with jitter so concurrent
+// clients don't retry in lockstep.
+func (p retryPolicy) delay(attempt int, retryAfter string, now time.Time) time.Duration {
+	if wait, ok := parseRetryAfter(retryAfter, now); ok {
+		return wait
+	}
+
+	backoff := p.baseDelay
+	for i := 0; i < attempt && backoff < p.maxDelay; i++ {
+		backoff *= 2
+	}
+	if backoff > p.maxDelay {
+		backoff = p.maxDelay
+	}
+	if backoff <= 0 {
+		return 0
+	}
+
+	// Jitter: entre la mitad y el total del backoff
+	half := backoff / 2
+	return half + rand.N(half+1)
+}
+
+// parseRetryAfter parses a Retry-After header, given either in seconds or
+// as an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		wait := date.Sub(now)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+// rewindRequest prepares a request to be sent again, restoring its body
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	retry := req.Clone(req.Context())
+	if req.Body != nil && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf(tr("error reintentando petición: %w"), err)
+		}
+		retry.Body = body
+	}
+	return retry, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// RunSummary counts the outcome of the domains of a scan or batch run.
+// It's printed as the last line of the output so wrapper scripts don't
+// need to tally the results themselves.
+type RunSummary struct {
+	Scanned      int     `json:"scanned"`      // Dominios evaluados, con o sin error
+	Passed       int     `json:"passed"`       // Sin ninguna condición que cambie el código de salida
+	FailedPolicy int     `json:"failedPolicy"` // No cumplen --policy o --compliance
+	Errored      int     `json:"errored"`      // La evaluación (o --save) falló
+	Partial      int     `json:"partial"`      // Algún endpoint no pudo evaluarse
+	Duration     float64 `json:"durationSeconds"`
+	Interrupted  bool    `json:"interrupted"`
+	ExitCode     int     `json:"exitCode"`
+}
+
+// validateSummaryFormat checks the value of --summary-format
+func validateSummaryFormat(format string) error {
+	if format != "text" && format != "json" {
+		return fmt.Errorf("formato de resumen inválido %q: se espera text o json", format)
+	}
+	return nil
+}
+
+// finish records the duration and exit code of the run
+func (s *RunSummary) finish(start time.Time, exitCode int) {
+	s.Duration = time.Since(start).Seconds()
+	s.ExitCode = exitCode
+}
+
+// Print writes the summary as a single line: key=value pairs for text, an
+// object for json
+func (s *RunSummary) Print(w io.Writer, format string) error {
+	if format == "json" {
+		return json.NewEncoder(w).Encode(s)
+	}
+	_, err := fmt.Fprintf(w, "summary: scanned=%d passed=%d failed_policy=%d errored=%d partial=%d duration=%s interrupted=%t exit=%d\n",
+		s.Scanned, s.Passed, s.FailedPolicy, s.Errored, s.Partial,
+		(time.Duration(s.Duration * float64(time.Second))).Round(time.Second), s.Interrupted, s.ExitCode)
+	return err
+}
@@ -0,0 +1,544 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"ssllabs-scanner/store"
+)
+
+// runScan implements the "scan" and "batch" subcommands: scan assesses the
+// domains given as arguments (or with --input), batch those listed in the
+// files given as arguments. It returns the exit code.
+func runScan(name string, args []string) int {
+	batch := name == "batch"
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	var inputFile *string
+	if !batch {
+		inputFile = fs.String("input", "", "archivo con un dominio por línea (\"-\" para leer de stdin)")
+	}
+	apiFlags := addClientFlags(fs)
+	historyOpts := addHistoryFlags(fs)
+	notifyOpts := addNotifyFlags(fs)
+	ctOpts := addCTFlags(fs)
+	discoverOpts := addDiscoverFlags(fs)
+	logOpts := addLogFlags(fs)
+	tz := addTimezoneFlag(fs)
+	color := addColorFlag(fs)
+	lang := addLangFlag(fs)
+	details := fs.Bool("details", false, "mostrar información detallada (cipher suites, vulnerabilidades, HSTS, OCSP, etc.)")
+	savePath := fs.String("save", "", "guardar la respuesta de cada evaluación como JSON en este archivo (o en <dominio>.json dentro de un directorio) para verla después con --offline")
+	offline := fs.String("offline", "", "mostrar una evaluación guardada con --save (o una respuesta de /analyze) sin consultar la API ni evaluar el dominio")
+	sims := fs.Bool("sims", false, "mostrar la simulación de handshake de SSL Labs: qué clientes (Android, Java, IE, Safari...) conectan y con qué protocolo y cipher suite")
+	worstOnly := fs.Bool("worst-only", false, "en hosts con varios endpoints, mostrar el detalle solo del que determina el grade general y una línea por cada uno de los demás")
+	raw := fs.Bool("raw", false, "mostrar también la respuesta completa de la API (Host) como JSON indentado, sin procesar")
+	failOnVuln := fs.Bool("fail-on-vuln", false, fmt.Sprintf("terminar con código %d si algún endpoint es vulnerable a un ataque TLS conocido", exitVulnerable))
+	warnExpiryDays := fs.Int("warn-expiry-days", 0, fmt.Sprintf("terminar con código %d si algún certificado expira en N días o menos (0 = deshabilitado)", exitExpiryWarning))
+	noInfo := fs.Bool("no-info", false, "no consultar /info antes de empezar (versión del motor y evaluaciones en curso)")
+	failIfBusy := fs.Bool("fail-if-busy", false, "terminar con error si /info indica que no hay capacidad para evaluaciones nuevas")
+	airGapped := fs.Bool("air-gapped", envBool("NEBULA_AIR_GAPPED"), "evaluar localmente, sin la API de SSL Labs ni otras conexiones salientes salvo a los hosts evaluados (también NEBULA_AIR_GAPPED=1)")
+	localFlag := fs.Bool("local", false, "si SSL Labs no está disponible (rate limit, sin capacidad o inalcanzable), evaluar localmente con un handshake TLS directo")
+	probeOCSP := fs.Bool("probe-ocsp", false, "consultar los responders OCSP de la cadena (latencia, firma, thisUpdate/nextUpdate) y señalar los que fallan")
+	checkCRL := fs.Bool("check-crl", false, "descargar las CRLs de la cadena y señalar las inalcanzables, enormes o con publicación atrasada")
+	checkAIA := fs.Bool("check-aia", false, "si la cadena está incompleta, intentar obtener los intermedios por AIA (caIssuers) y señalar si falla")
+	caa := fs.Bool("caa", false, "consultar los registros CAA del dominio y verificar que autoricen a la CA de los certificados servidos, señalando su ausencia")
+	dnssec := fs.Bool("dnssec", false, "validar la cadena de confianza DNSSEC del dominio (DS, DNSKEY y RRSIG) desde la raíz hasta sus registros A/AAAA")
+	dane := fs.Bool("dane", false, "consultar los registros TLSA de _443._tcp.<dominio> (DANE) y verificar que coincidan con los certificados servidos y estén firmados con DNSSEC")
+	issuanceHygiene := fs.Bool("issuance-hygiene", false, "verificar los requisitos de los navegadores para certificados nuevos (SCT, validez de hasta 398 días, sin SHA-1, EKU, CAA)")
+	hsts := fs.Bool("hsts", false, "mostrar la política HSTS de cada endpoint y si el dominio está en la lista de preload publicada (consulta hstspreload.org)")
+	auditHeaders := fs.Bool("audit-headers", false, "obtener https://<dominio>/ y auditar sus headers de seguridad (HSTS, CSP, X-Frame-Options, Referrer-Policy, X-Content-Type-Options)")
+	snippets := fs.String("snippets", "", "mostrar snippets de configuración listos para pegar que corrigen los motivos del grade (nginx, apache o haproxy)")
+	onlyIPv4 := fs.Bool("only-ipv4", false, "considerar solo los endpoints IPv4 del dominio")
+	onlyIPv6 := fs.Bool("only-ipv6", false, "considerar solo los endpoints IPv6 del dominio")
+	endpointIP := fs.String("endpoint", "", "considerar solo el endpoint con esta IP")
+	caFile := fs.String("ca-file", "", "bundle PEM de CAs adicionales en las que confiar en la evaluación local (--air-gapped o --local)")
+	critExpiryDays := fs.Int("crit-expiry-days", 0, fmt.Sprintf("terminar con código %d si algún certificado expira en N días o menos (0 = deshabilitado)", exitExpiryCritical))
+	minGrade := fs.String("min-grade", "", fmt.Sprintf("terminar con código %d si el grade general de algún dominio es peor, ej: B", exitBelowMinGrade))
+	policyPath := fs.String("policy", "", fmt.Sprintf("archivo YAML con requisitos TLS a verificar; termina con código %d si alguno no se cumple", exitPolicyFailed))
+	complianceName := fs.String("compliance", "", fmt.Sprintf("verificar un estándar de cumplimiento (pci); termina con código %d si algún dominio no cumple", exitPolicyFailed))
+	complianceReport := fs.String("compliance-report", "", "con --compliance, escribir el informe para auditoría en este archivo (Markdown)")
+	summaryFormat := fs.String("summary-format", "text", "formato de la línea de resumen final: text (clave=valor) o json")
+	configPath := fs.String("config", "", "archivo de configuración YAML (por defecto ~/.config/nebula/config.yaml si existe; los flags tienen prioridad)")
+	fs.Usage = func() { scanUsage(fs, batch) }
+	fs.Parse(args)
+
+	config, err := loadDefaultConfig(*configPath)
+	if err == nil && config != nil {
+		err = config.apply(fs)
+	}
+	if err == nil {
+		err = validateMinGrade(*minGrade)
+	}
+	if err == nil {
+		err = validateSnippetServer(*snippets)
+	}
+	if err == nil {
+		err = validateSummaryFormat(*summaryFormat)
+	}
+	var filter *endpointFilter
+	if err == nil {
+		filter, err = newEndpointFilter(*onlyIPv4, *onlyIPv6, *endpointIP)
+	}
+	var discoverer *subdomainDiscoverer
+	if err == nil {
+		discoverer, err = discoverOpts.discoverer()
+	}
+	if err == nil && discoverer != nil && *offline != "" {
+		err = fmt.Errorf("--discover-subdomains no se puede usar con --offline")
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return exitError
+	}
+
+	var policy *Policy
+	if *policyPath != "" {
+		if policy, err = LoadPolicy(*policyPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			return exitError
+		}
+	}
+
+	var compliance *ComplianceReport
+	if *complianceName != "" {
+		profile, err := loadComplianceProfile(*complianceName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			return exitError
+		}
+		compliance = newComplianceReport(profile)
+	} else if *complianceReport != "" {
+		fmt.Fprintf(os.Stderr, "Error: --compliance-report requiere --compliance\n")
+		return exitError
+	}
+
+	expiry := ExpiryThresholds{
+		WarnDays: *warnExpiryDays,
+		CritDays: *critExpiryDays,
+	}
+	if err := expiry.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return exitError
+	}
+
+	// Punto 3: Validación de entrada CLI. batch recibe archivos en lugar de
+	// dominios; sin dominios ni archivos se usan los de la configuración.
+	var domains []string
+	var saved *AssessmentResult
+	switch {
+	case *offline != "":
+		if saved, err = loadOffline(fs, *offline); err == nil {
+			domains = []string{saved.Domain}
+		}
+	case fs.NArg() == 0 && (batch || *inputFile == "") && config != nil && len(config.Domains) > 0:
+		domains, err = collectDomains(config.Domains, "")
+	case batch:
+		domains, err = collectBatchDomains(fs.Args())
+	default:
+		domains, err = collectDomains(fs.Args(), *inputFile)
+	}
+	if err == nil {
+		err = validateSavePath(*savePath, len(domains))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		fs.Usage()
+		return exitError
+	}
+
+	// Antes del logger, que también usa la zona horaria
+	if err := setOutputTimezone(*tz); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return exitError
+	}
+	if err := setOutputColor(*color); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return exitError
+	}
+	if err := setOutputLang(*lang); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return exitError
+	}
+
+	// Logs estructurados en stderr; los resultados van a stdout
+	logger, err := logOpts.logger(os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return exitError
+	}
+
+	// Punto 4: Cliente HTTP
+	clientOpts, err := apiFlags.options()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return exitError
+	}
+	clientOpts = append(clientOpts, WithLogger(logger))
+
+	notifications, err := notifyOpts.notifications()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return exitError
+	}
+	// Toda la ejecución es una ronda: las alertas salen juntas al final
+	notifications = notifications.Batched()
+
+	// Una evaluación guardada ya se registró y notificó cuando se hizo
+	var history store.Store
+	if saved == nil {
+		history, err = historyOpts.open()
+	} else {
+		notifications = nil
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return exitError
+	}
+	opts := DisplayOptions{
+		Details:   *details,
+		Expiry:    expiry,
+		Snippets:  *snippets,
+		Raw:       *raw,
+		WorstOnly: *worstOnly,
+		Sims:      *sims,
+	}
+
+	// Sin --air-gapped se evalúa con la API; con --air-gapped, localmente;
+	// con --offline no se evalúa: se muestra la evaluación guardada
+	var scanner Assessor
+	var apiClient *HTTPClient
+	var fallback *localFallback
+	newLocal := func() (*LocalScanner, error) {
+		trust, err := loadTrustStore(defaultTrustStorePath())
+		if err == nil && *caFile != "" {
+			err = trust.AddFile(*caFile)
+		}
+		if err != nil {
+			return nil, err
+		}
+		local := NewLocalScanner(trust, *apiFlags.timeout)
+		if filter != nil {
+			// Las direcciones filtradas ni siquiera se prueban
+			local.lookup = filter.filterLookup(local.lookup)
+		}
+		return local, nil
+	}
+	if saved != nil {
+		scanner = savedAssessor{result: saved}
+	} else if *airGapped {
+		if err := checkAirGapped(fs, *notifyOpts.webhook); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			return exitError
+		}
+		if scanner, err = newLocal(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			return exitError
+		}
+	} else {
+		if *caFile != "" && !*localFlag {
+			fmt.Fprintf(os.Stderr, "Error: --ca-file requiere --air-gapped o --local\n")
+			return exitError
+		}
+		apiScanner := NewScanner(clientOpts...)
+		scanner, apiClient = apiScanner, apiScanner.Client()
+		if *probeOCSP {
+			scanner = withOCSPProbe(scanner)
+		}
+		if *checkCRL {
+			scanner = withCRLCheck(scanner)
+		}
+		if *checkAIA {
+			scanner = withAIACheck(scanner)
+		}
+		if *localFlag {
+			local, err := newLocal()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				return exitError
+			}
+			fallback = withLocalFallback(scanner, local)
+			scanner = fallback
+		}
+	}
+	if filter != nil {
+		scanner = withEndpointFilter(scanner, filter)
+	}
+	// No necesitan la API: solo consultan al resolver del sistema
+	if *issuanceHygiene {
+		scanner = withIssuanceHygiene(scanner)
+	}
+	if *caa {
+		scanner = withCAACheck(scanner)
+	}
+	if *dnssec {
+		scanner = withDNSSECCheck(scanner)
+	}
+	if *dane {
+		scanner = withDANECheck(scanner)
+	}
+	if *hsts {
+		scanner = withHSTSPreload(scanner)
+	}
+	if scanner, err = ctOpts.wrap(scanner); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return exitError
+	}
+	// Solo se conecta al sitio evaluado: también vale con --air-gapped
+	if *auditHeaders {
+		scanner = withHeaderAudit(scanner)
+	}
+
+	// SIGINT/SIGTERM cancelan la evaluación en curso en lugar de matar el proceso
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if !*noInfo && apiClient != nil {
+		err := preflight(ctx, apiClient, os.Stderr, *failIfBusy)
+		switch {
+		case err != nil && fallback != nil && apiUnavailable(err):
+			// Con --local, sin capacidad no se termina: se evalúa localmente
+			fallback.disable(err)
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			return exitError
+		}
+	}
+
+	// Los dominios dados pasan a ser los apex de la flota a evaluar
+	var fleet *FleetReport
+	if discoverer != nil {
+		apexes := domains
+		domains, err = discoverer.expand(ctx, os.Stdout, apexes)
+		if err == nil {
+			err = validateSavePath(*savePath, len(domains))
+		}
+		if ctx.Err() != nil {
+			fmt.Fprint(os.Stderr, tr("Interrumpido\n"))
+			return exitInterrupted
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			return exitError
+		}
+		fmt.Println()
+		fleet = newFleetReport(apexes, expiry)
+	}
+
+	start := time.Now()
+	var summary RunSummary
+	interrupted := false
+	failed := 0
+	vulnerable := 0
+	expiringWarn := 0
+	expiringCrit := 0
+	belowMinGrade := 0
+	policyFailed := 0
+	for _, domain := range domains {
+		if ctx.Err() != nil {
+			interrupted = true
+			break
+		}
+		result, err := scanDomain(ctx, scanner, domain, opts)
+		if errors.Is(err, ErrInterrupted) {
+			interrupted = true
+			break
+		}
+		summary.Scanned++
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			if hint := errorHint(err); hint != "" {
+				fmt.Fprintf(os.Stderr, tr("Sugerencia: %s\n"), hint)
+			}
+			if compliance != nil {
+				compliance.AddError(domain, err)
+			}
+			if fleet != nil {
+				fleet.AddError(domain, err)
+			}
+			failed++
+			summary.Errored++
+			continue
+		}
+		// Antes de guardar: los certificados de esta evaluación aún no están en el historial
+		var anomalies []string
+		if history != nil {
+			anomalies = checkIssuanceAnomalies(history, result)
+		}
+		recordAssessment(history, notifications, result)
+		// Alguna condición de este dominio cambia el código de salida
+		flagged := false
+		if *savePath != "" {
+			path, err := saveAssessment(*savePath, result)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				failed++
+				summary.Errored++
+				flagged = true
+			} else {
+				fmt.Fprintf(os.Stderr, tr("Evaluación de %s guardada en %s\n"), result.Domain, path)
+			}
+		}
+		if len(anomalies) > 0 {
+			displayIssuanceAnomalies(domain, anomalies)
+		}
+		if fleet != nil {
+			fleet.Add(result)
+		}
+		policyOK := policy == nil || displayPolicy(fmt.Sprintf(tr("Política (%s)"), *policyPath), policy.Evaluate(result))
+		if !policyOK {
+			policyFailed++
+		}
+		if compliance != nil && !displayPolicy(fmt.Sprintf(tr("Cumplimiento %s"), compliance.Profile.Name), compliance.Add(result)) {
+			policyFailed++
+			policyOK = false
+		}
+		if !policyOK {
+			summary.FailedPolicy++
+			flagged = true
+		}
+		if result.HasEndpointErrors() {
+			// Un host con endpoints sin evaluar no se da por sano
+			failed++
+			summary.Partial++
+			flagged = true
+		}
+		if result.HasVulnerabilities() {
+			vulnerable++
+			flagged = flagged || *failOnVuln
+		}
+		if belowGrade(result.OverallGrade, *minGrade) {
+			fmt.Fprintf(os.Stderr, tr("%s: grade %s por debajo del mínimo %s\n"), result.Domain, result.OverallGrade, *minGrade)
+			belowMinGrade++
+			flagged = true
+		}
+		switch result.WorstExpiryStatus(expiry) {
+		case expiryCritical:
+			expiringCrit++
+			flagged = flagged || expiry.WarnDays > 0 || expiry.CritDays > 0
+		case expiryWarning:
+			expiringWarn++
+			flagged = true
+		}
+		if !flagged {
+			summary.Passed++
+		}
+	}
+
+	// También con una interrupción: las alertas de lo ya evaluado no se pierden
+	flushNotifications(notifications)
+
+	if len(domains) > 1 || batch {
+		fmt.Printf(tr("=== %d dominios evaluados, %d con errores, %d con vulnerabilidades, %d con certificados por expirar ===\n"),
+			summary.Scanned, failed, vulnerable, expiringWarn+expiringCrit)
+	}
+
+	// Con una interrupción muestra los hosts ya evaluados
+	if fleet != nil {
+		fleet.Print(os.Stdout)
+	}
+
+	if compliance != nil && !interrupted {
+		compliance.PrintSummary(os.Stdout)
+		if *complianceReport != "" {
+			if err := writeComplianceReport(*complianceReport, compliance); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				return exitError
+			}
+			fmt.Fprintf(os.Stderr, tr("Informe de cumplimiento guardado en %s\n"), *complianceReport)
+		}
+	}
+
+	if history != nil {
+		history.Close()
+	}
+
+	code := scanExitCode(interrupted, failed, expiringCrit, vulnerable, belowMinGrade, policyFailed, expiringWarn, expiry, *failOnVuln)
+	if interrupted {
+		fmt.Fprint(os.Stderr, tr("Interrumpido\n"))
+	}
+	summary.Interrupted = interrupted
+	summary.finish(start, code)
+	if err := summary.Print(os.Stdout, *summaryFormat); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+	}
+	return code
+}
+
+// scanExitCode returns the exit code of a scan or batch run from the
+// number of domains with each condition. Priority: interruption, errors,
+// critical expiry, vulnerabilities, minimum grade, policy or compliance
+// and expiry warning.
+func scanExitCode(interrupted bool, failed, expiringCrit, vulnerable, belowMinGrade, policyFailed, expiringWarn int,
+	expiry ExpiryThresholds, failOnVuln bool) int {
+	switch {
+	case interrupted:
+		return exitInterrupted
+	case failed > 0:
+		return exitError
+	case (expiry.WarnDays > 0 || expiry.CritDays > 0) && expiringCrit > 0:
+		return exitExpiryCritical
+	case failOnVuln && vulnerable > 0:
+		return exitVulnerable
+	case belowMinGrade > 0:
+		return exitBelowMinGrade
+	case policyFailed > 0:
+		return exitPolicyFailed
+	case expiringWarn > 0:
+		return exitExpiryWarning
+	}
+	return 0
+}
+
+// validateMinGrade checks a --min-grade value (empty disables the check)
+func validateMinGrade(grade string) error {
+	if _, ok := gradeOrder[grade]; grade != "" && !ok {
+		return fmt.Errorf("grade mínimo inválido %q: se espera A+, A, A-, B... F, T o M", grade)
+	}
+	return nil
+}
+
+// belowGrade reports whether grade is worse than minimum. Results without
+// a known grade are left to the error checks.
+func belowGrade(grade, minimum string) bool {
+	if _, ok := gradeOrder[grade]; !ok || minimum == "" {
+		return false
+	}
+	return compareGrades(grade, minimum) < 0
+}
+
+// collectBatchDomains reads and validates the domains of the files given
+// to batch ("-" reads stdin)
+func collectBatchDomains(paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("se requiere al menos un archivo con dominios")
+	}
+	var domains []string
+	for _, path := range paths {
+		fileDomains, err := readDomainsFile(path)
+		if err != nil {
+			return nil, err
+		}
+		domains = append(domains, fileDomains...)
+	}
+	return collectDomains(domains, "")
+}
+
+// scanUsage prints the help of scan or batch to stderr
+func scanUsage(fs *flag.FlagSet, batch bool) {
+	if batch {
+		fmt.Fprintf(os.Stderr, "Usage: %s batch [flags] <archivo> [archivo...]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Evalúa los dominios de cada archivo (uno por línea, \"-\" para stdin) y muestra un resumen al final.\n")
+		fmt.Fprintf(os.Stderr, "Ejemplo: %s batch dominios.txt\n\n", os.Args[0])
+	} else {
+		fmt.Fprintf(os.Stderr, "Usage: %s scan [flags] <domain> [domain...]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Ejemplo: %s scan google.com\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Ejemplo: %s scan --input domains.txt\n\n", os.Args[0])
+	}
+	fs.PrintDefaults()
+}
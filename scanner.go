@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// Assessor runs complete assessments of a domain: Scanner through the SSL
+// Labs API, LocalScanner directly against the host
+type Assessor interface {
+	AssessContext(ctx context.Context, domain string) (*AssessmentResult, error)
+}
+
+// Scanner runs complete assessments (polling and processing) on top of an
+// HTTPClient. Like the client, it is safe for concurrent use.
+type Scanner struct {
+	client *HTTPClient
+	poll   PollOptions
+	logger *slog.Logger
+}
+
+// NewScanner creates a scanner. Unless WithClient is given, a new
+// HTTPClient is built from the same options.
+func NewScanner(opts ...Option) *Scanner {
+	o := newOptions(opts)
+
+	client := o.client
+	if client == nil {
+		client = NewHTTPClient(opts...)
+	}
+
+	return &Scanner{
+		client: client,
+		poll: PollOptions{
+			Timeout:            o.assessmentTimeout,
+			Interval:           o.pollInterval,
+			InProgressInterval: o.inProgressPoll,
+			FromCache:          o.fromCache,
+			MaxAge:             o.maxAge,
+			Reuse:              o.reuse,
+			Reporter:           o.reporter,
+			Progressive:        o.progressive,
+			DetailsTimeout:     o.detailsTimeout,
+			IgnoreMismatch:     o.ignoreMismatch,
+			Publish:            o.publish,
+		},
+		logger: o.logger,
+	}
+}
+
+// Client returns the HTTP client used by the scanner
+func (s *Scanner) Client() *HTTPClient {
+	return s.client
+}
+
+// Assess polls the assessment of a domain until it completes and processes the results
+func (s *Scanner) Assess(domain string) (*AssessmentResult, error) {
+	return s.AssessContext(context.Background(), domain)
+}
+
+// AssessContext is like Assess but can be cancelled with ctx. When
+// cancelled, it returns the endpoints that were already assessed (nil if
+// none) along with ErrInterrupted.
+func (s *Scanner) AssessContext(ctx context.Context, domain string) (*AssessmentResult, error) {
+	// Punto 6: Lógica de polling
+	host, err := PollAssessment(ctx, s.client, domain, s.poll)
+	if err != nil {
+		s.poll.Reporter.Done(domain, "")
+	}
+	if errors.Is(err, ErrInterrupted) {
+		return s.partialResults(domain), fmt.Errorf("%s: %w", domain, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", domain, err)
+	}
+
+	// La evaluación está completa (status == READY)
+	s.poll.Reporter.Done(domain, tr("✅ Evaluación completada"))
+
+	// Punto 7: Procesar resultados
+	result, err := ProcessResults(host)
+	if err != nil {
+		return nil, fmt.Errorf(tr("%s: error procesando resultados: %w"), domain, err)
+	}
+	s.setMetadata(result)
+
+	s.logger.Info("evaluación completada", "domain", domain, "grade", result.OverallGrade,
+		"endpoints", len(result.Endpoints))
+	return result, nil
+}
+
+// partialResults fetches the details of the endpoints already assessed
+// after an interruption. It returns nil if there are none.
+func (s *Scanner) partialResults(domain string) *AssessmentResult {
+	// El contexto original ya fue cancelado: usar uno nuevo y acotado
+	ctx, cancel := context.WithTimeout(context.Background(), partialResultsTimeout)
+	defer cancel()
+
+	host, err := s.client.AnalyzeContext(ctx, domain, s.poll.params(false, "done"))
+	if err != nil {
+		return nil
+	}
+	result, err := ProcessResults(host)
+	if err != nil {
+		return nil
+	}
+	s.setMetadata(result)
+	return result
+}
+
+// setMetadata records the source and the request parameters of a result
+// assessed by the API
+func (s *Scanner) setMetadata(result *AssessmentResult) {
+	params := s.poll.params(false, "done")
+	result.Metadata.Source = sourceSSLLabs
+	result.Metadata.FromCache = params.FromCache
+	result.Metadata.Publish = params.Publish
+}
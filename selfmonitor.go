@@ -0,0 +1,260 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime"
+	"runtime/metrics"
+	"sync"
+	"time"
+)
+
+// Métricas del runtime que vigila el automonitoreo
+const (
+	metricGoroutines = "/sched/goroutines:goroutines"
+	metricRunnable   = "/sched/goroutines/runnable:goroutines"
+	metricHeapLive   = "/gc/heap/live:bytes"
+)
+
+// Crecimientos mínimos para sospechar una fuga: con pocos recursos el
+// factor solo no alcanza (de 10 a 20 goroutines no es una fuga)
+const (
+	minGoroutineGrowth = 100
+	minHeapGrowth      = 64 << 20
+)
+
+// runnableQueueFactor is how many runnable goroutines per P make the
+// scheduler queue count as saturated
+const runnableQueueFactor = 4
+
+// selfMonitorFlags holds the self-monitoring flags of serve
+type selfMonitorFlags struct {
+	interval *time.Duration
+	window   *int
+	factor   *float64
+}
+
+// addSelfMonitorFlags registers the self-monitoring flags on fs
+func addSelfMonitorFlags(fs *flag.FlagSet) *selfMonitorFlags {
+	return &selfMonitorFlags{
+		interval: fs.Duration("self-monitor-interval", time.Minute, "intervalo entre muestras de goroutines, heap y cola del planificador (0 = deshabilitado)"),
+		window:   fs.Int("self-monitor-window", 15, "cantidad de muestras de la ventana: se compara el mínimo de la ventana más reciente con el de la primera"),
+		factor:   fs.Float64("leak-factor", 2, "crecimiento respecto de la primera ventana a partir del cual se sospecha una fuga"),
+	}
+}
+
+// monitor validates the flags and returns the self-monitor, or nil when
+// it is disabled
+func (f *selfMonitorFlags) monitor() (*SelfMonitor, error) {
+	switch {
+	case *f.interval < 0:
+		return nil, fmt.Errorf("--self-monitor-interval no puede ser negativo")
+	case *f.interval == 0:
+		return nil, nil
+	case *f.window < 2:
+		return nil, fmt.Errorf("--self-monitor-window debe ser al menos 2")
+	case *f.factor <= 1:
+		return nil, fmt.Errorf("--leak-factor debe ser mayor que 1")
+	}
+	return NewSelfMonitor(*f.interval, *f.window, *f.factor), nil
+}
+
+// runtimeSample is a reading of the resources of the process
+type runtimeSample struct {
+	Goroutines int64
+	HeapBytes  int64
+	Runnable   int64 // -1 si el runtime no expone la cola del planificador
+	MaxProcs   int64
+}
+
+// readRuntimeSample reads the current resources from runtime/metrics
+func readRuntimeSample() runtimeSample {
+	samples := []metrics.Sample{{Name: metricGoroutines}, {Name: metricHeapLive}, {Name: metricRunnable}}
+	metrics.Read(samples)
+	sample := runtimeSample{
+		Goroutines: int64(runtime.NumGoroutine()),
+		Runnable:   -1,
+		MaxProcs:   int64(runtime.GOMAXPROCS(0)),
+	}
+	if samples[0].Value.Kind() == metrics.KindUint64 {
+		sample.Goroutines = int64(samples[0].Value.Uint64())
+	}
+	if samples[1].Value.Kind() == metrics.KindUint64 {
+		sample.HeapBytes = int64(samples[1].Value.Uint64())
+	}
+	if samples[2].Value.Kind() == metrics.KindUint64 {
+		sample.Runnable = int64(samples[2].Value.Uint64())
+	}
+	return sample
+}
+
+// leakTracker detects the sustained growth of one resource. It compares
+// the minimum of the latest window of samples with the minimum of the
+// first one: the minimum ignores the peaks of a round of assessments, so
+// only memory or goroutines that are never released make it grow.
+type leakTracker struct {
+	resource  string
+	minGrowth int64
+	window    []int64 // Últimas muestras, la más reciente al final
+	baseline  int64   // Mínimo de la primera ventana, 0 mientras se llena
+	warned    int64   // Mínimo de la ventana en la última advertencia
+	suspected bool
+}
+
+// add records a sample. It reports whether a new warning is due: the
+// first time a leak is suspected and every time it grows by factor again.
+func (t *leakTracker) add(value int64, size int, factor float64) bool {
+	t.window = append(t.window, value)
+	if len(t.window) > size {
+		t.window = t.window[1:]
+	}
+	if len(t.window) < size {
+		return false
+	}
+	current := minOf(t.window)
+	if t.baseline == 0 {
+		t.baseline = max(current, 1)
+		return false
+	}
+
+	t.suspected = float64(current) > float64(t.baseline)*factor && current-t.baseline >= t.minGrowth
+	if !t.suspected {
+		t.warned = 0
+		return false
+	}
+	if t.warned == 0 || float64(current) > float64(t.warned)*factor {
+		t.warned = current
+		return true
+	}
+	return false
+}
+
+// current returns the minimum of the latest window
+func (t *leakTracker) current() int64 {
+	return minOf(t.window)
+}
+
+// minOf returns the smallest of values (0 if empty)
+func minOf(values []int64) int64 {
+	if len(values) == 0 {
+		return 0
+	}
+	smallest := values[0]
+	for _, value := range values[1:] {
+		smallest = min(smallest, value)
+	}
+	return smallest
+}
+
+// SelfMonitor periodically samples the goroutines, live heap and
+// scheduler run queue of the process, logs a warning when they grow
+// without bound and exposes them as metrics, so slow leaks show up long
+// before they take down a deployment that runs for months. It is safe
+// for concurrent use.
+type SelfMonitor struct {
+	mu         sync.Mutex
+	interval   time.Duration
+	size       int
+	factor     float64
+	latest     runtimeSample
+	goroutines leakTracker
+	heap       leakTracker
+	queue      []int64 // Últimas muestras de la cola del planificador
+	saturated  bool
+}
+
+// NewSelfMonitor creates a monitor that takes a sample every interval and
+// compares windows of size samples
+func NewSelfMonitor(interval time.Duration, size int, factor float64) *SelfMonitor {
+	return &SelfMonitor{
+		interval:   interval,
+		size:       size,
+		factor:     factor,
+		goroutines: leakTracker{resource: "goroutines", minGrowth: minGoroutineGrowth},
+		heap:       leakTracker{resource: "heap", minGrowth: minHeapGrowth},
+	}
+}
+
+// Run samples the process every interval, forever
+func (m *SelfMonitor) Run() {
+	m.record(readRuntimeSample())
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		m.record(readRuntimeSample())
+	}
+}
+
+// record adds a sample and logs the warnings it triggers
+func (m *SelfMonitor) record(sample runtimeSample) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.latest = sample
+	slog.Debug("automonitoreo", "goroutines", sample.Goroutines, "heap_bytes", sample.HeapBytes, "runnable", sample.Runnable)
+
+	if m.goroutines.add(sample.Goroutines, m.size, m.factor) {
+		slog.Warn("posible fuga de goroutines: el mínimo de la última ventana no deja de crecer",
+			"goroutines", m.goroutines.current(), "inicial", m.goroutines.baseline, "ventana", m.window())
+	}
+	if m.heap.add(sample.HeapBytes, m.size, m.factor) {
+		slog.Warn("posible fuga de memoria: el heap vivo no vuelve a su tamaño inicial",
+			"heap", formatBytes(m.heap.current()), "inicial", formatBytes(m.heap.baseline), "ventana", m.window())
+	}
+
+	if sample.Runnable < 0 {
+		return
+	}
+	m.queue = append(m.queue, sample.Runnable)
+	if len(m.queue) > m.size {
+		m.queue = m.queue[1:]
+	}
+	saturated := len(m.queue) == m.size && minOf(m.queue) > sample.MaxProcs*runnableQueueFactor
+	if saturated && !m.saturated {
+		slog.Warn("cola del planificador saturada durante toda la ventana: el proceso no da abasto",
+			"runnable", minOf(m.queue), "gomaxprocs", sample.MaxProcs, "ventana", m.window())
+	}
+	m.saturated = saturated
+}
+
+// window returns the time covered by a window of samples
+func (m *SelfMonitor) window() time.Duration {
+	return m.interval * time.Duration(m.size)
+}
+
+// WriteMetrics writes the latest sample and the leak indicators in the
+// Prometheus text format
+func (m *SelfMonitor) WriteMetrics(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	writeHeader(w, "nebula_goroutines", "Goroutines of the process at the latest self-monitoring sample")
+	fmt.Fprintf(w, "nebula_goroutines %d\n", m.latest.Goroutines)
+
+	writeHeader(w, "nebula_heap_live_bytes", "Live heap after the latest garbage collection")
+	fmt.Fprintf(w, "nebula_heap_live_bytes %d\n", m.latest.HeapBytes)
+
+	if m.latest.Runnable >= 0 {
+		writeHeader(w, "nebula_sched_runnable_goroutines", "Goroutines waiting in the scheduler run queue")
+		fmt.Fprintf(w, "nebula_sched_runnable_goroutines %d\n", m.latest.Runnable)
+	}
+
+	writeHeader(w, "nebula_leak_suspected", "1 if the minimum of the latest window grew past --leak-factor times the first one")
+	for _, tracker := range []*leakTracker{&m.goroutines, &m.heap} {
+		fmt.Fprintf(w, "nebula_leak_suspected{resource=%s} %d\n", promLabel(tracker.resource), boolToInt(tracker.suspected))
+	}
+
+	writeHeader(w, "nebula_sched_saturated", "1 if the run queue exceeded 4 goroutines per P during the whole latest window")
+	fmt.Fprintf(w, "nebula_sched_saturated %d\n", boolToInt(m.saturated))
+}
+
+// boolToInt renders a boolean as a 0/1 gauge value
+func boolToInt(value bool) int {
+	if value {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Tiempos de selftest: mucho más cortos que los de la API real para que
+// la corrida dure segundos
+const (
+	selftestRequestTimeout = time.Second
+	selftestPollInterval   = 20 * time.Millisecond
+	selftestRetryDelay     = 10 * time.Millisecond
+	selftestInterruptAfter = 300 * time.Millisecond
+)
+
+// selftestRetries is how many retries the selftest client allows per
+// request and policy. With --chaos the faults are random: enough retries
+// make a run fail only when the scanner mishandles a fault.
+const selftestRetries = 8
+
+// selftestScenario is an end-to-end run of the scanner against one of the
+// canned assessments of MockAPI
+type selftestScenario struct {
+	name      string
+	domain    string
+	interrupt bool // Cancelar la evaluación como con Ctrl+C
+	check     func(result *AssessmentResult, err error) error
+}
+
+// selftestScenarios covers the complete, partial, interrupted and failed
+// assessment paths
+var selftestScenarios = []selftestScenario{
+	{
+		name:   "evaluación completa",
+		domain: mockDomainOK,
+		check: func(result *AssessmentResult, err error) error {
+			switch {
+			case err != nil:
+				return err
+			case result.OverallGrade != "A" || len(result.Endpoints) != 2:
+				return fmt.Errorf("se esperaba grade A con 2 endpoints, se obtuvo %s con %d", result.OverallGrade, len(result.Endpoints))
+			case result.Endpoints[0].Details == nil:
+				return fmt.Errorf("los endpoints llegaron sin details")
+			}
+			return nil
+		},
+	},
+	{
+		name:   "endpoint que no se pudo evaluar",
+		domain: mockDomainPartial,
+		check: func(result *AssessmentResult, err error) error {
+			switch {
+			case err != nil:
+				return err
+			case len(result.Endpoints) != 1 || len(result.EndpointErrors) != 1:
+				return fmt.Errorf("se esperaba 1 endpoint evaluado y 1 con error, se obtuvieron %d y %d", len(result.Endpoints), len(result.EndpointErrors))
+			}
+			return nil
+		},
+	},
+	{
+		name:      "interrupción con resultados parciales",
+		domain:    mockDomainSlow,
+		interrupt: true,
+		check: func(result *AssessmentResult, err error) error {
+			switch {
+			case !errors.Is(err, ErrInterrupted):
+				return fmt.Errorf("se esperaba una interrupción, se obtuvo: %v", err)
+			case result == nil || len(result.Endpoints) != 1:
+				return fmt.Errorf("se esperaba el endpoint ya evaluado como resultado parcial")
+			}
+			return nil
+		},
+	},
+	{
+		name:   "evaluación fallida",
+		domain: mockDomainError,
+		check: func(result *AssessmentResult, err error) error {
+			if !errors.Is(err, ErrAssessmentFailed) {
+				return fmt.Errorf("se esperaba ErrAssessmentFailed, se obtuvo: %v", err)
+			}
+			return nil
+		},
+	},
+}
+
+// run assesses the scenario domain with scanner and checks the outcome
+func (s selftestScenario) run(scanner Assessor) (string, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if s.interrupt {
+		// Igual que SIGINT: cancelar, no vencer un plazo
+		timer := time.AfterFunc(selftestInterruptAfter, cancel)
+		defer timer.Stop()
+	}
+
+	result, err := scanner.AssessContext(ctx, s.domain)
+	if err := s.check(result, err); err != nil {
+		return "", err
+	}
+	if result == nil {
+		return errorCode(err), nil
+	}
+	return fmt.Sprintf("grade %s, %s", result.OverallGrade, countEndpoints(len(result.Endpoints))), nil
+}
+
+// runSelftest implements the "selftest" subcommand: it runs the scanner
+// end to end against an embedded mock of the SSL Labs API, optionally
+// injecting faults (--chaos) to exercise retries, backoff and partial
+// results without touching the real API
+func runSelftest(args []string) error {
+	fs := flag.NewFlagSet("selftest", flag.ExitOnError)
+	chaos := fs.Bool("chaos", false, "inyectar fallos aleatorios: 429, 529, JSON truncado, respuestas demoradas y rachas de 503")
+	rate := fs.Float64("fault-rate", 0.3, "probabilidad de inyectar un fallo en cada petición (con --chaos)")
+	stall := fs.Duration("stall", 2*time.Second, "demora de las respuestas demoradas, mayor que el timeout de 1s del cliente (con --chaos; 0 = sin demoras)")
+	seed := fs.Uint64("seed", 0, "semilla de la secuencia de fallos, para repetir una corrida (0 = aleatoria)")
+	logOpts := addLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s selftest [--chaos] [--fault-rate 0.3] [--stall 2s] [--seed n]\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *rate < 0 || *rate >= 1 {
+		return fmt.Errorf("--fault-rate debe estar entre 0 y 1")
+	}
+	if *stall < 0 {
+		return fmt.Errorf("--stall no puede ser negativo")
+	}
+	logger, err := logOpts.logger(os.Stderr)
+	if err != nil {
+		return err
+	}
+
+	config := ChaosConfig{}
+	if *chaos {
+		config = ChaosConfig{Rate: *rate, Stall: *stall, Seed: *seed}
+		if config.Seed == 0 {
+			config.Seed = uint64(time.Now().UnixNano())
+		}
+	}
+	mock := NewMockAPI(config)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("no se pudo iniciar la API simulada: %w", err)
+	}
+	server := &http.Server{Handler: mock}
+	go server.Serve(listener)
+	defer server.Close()
+
+	baseURL := fmt.Sprintf("http://%s/api/v2", listener.Addr())
+	scanner := NewScanner(
+		WithBaseURL(baseURL),
+		WithTimeout(selftestRequestTimeout),
+		WithRateLimiter(nil),
+		WithRetries(selftestRetries, selftestRetryDelay, 10*selftestRetryDelay),
+		WithNetworkRetries(selftestRetries, selftestRetryDelay),
+		WithPollIntervals(selftestPollInterval, selftestPollInterval),
+		WithReporter(nil),
+		WithLogger(logger),
+	)
+
+	fmt.Printf("Autodiagnóstico contra la API simulada en %s\n", baseURL)
+	if *chaos {
+		fmt.Printf("Caos: fallo en el %.0f%% de las peticiones, demoras de %v, semilla %d\n", config.Rate*100, config.Stall, config.Seed)
+	}
+	fmt.Println()
+
+	failed := 0
+	for _, scenario := range selftestScenarios {
+		start := time.Now()
+		outcome, err := scenario.run(scanner)
+		elapsed := time.Since(start).Round(time.Millisecond)
+		if err != nil {
+			failed++
+			fmt.Printf("❌ %s (%s): %s [%v]\n", scenario.name, scenario.domain, err, elapsed)
+			continue
+		}
+		fmt.Printf("✅ %s (%s): %s [%v]\n", scenario.name, scenario.domain, outcome, elapsed)
+	}
+
+	fmt.Printf("\nFallos inyectados: %s\n", mock.describeFaults())
+	if failed > 0 {
+		hint := ""
+		if *chaos {
+			hint = fmt.Sprintf(" (repetir con --seed %d)", config.Seed)
+		}
+		return fmt.Errorf("%d de %d escenarios fallaron%s", failed, len(selftestScenarios), hint)
+	}
+	fmt.Printf("%d de %d escenarios correctos\n", len(selftestScenarios), len(selftestScenarios))
+	return nil
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultReleasesURL is the GitHub API endpoint of the latest release
+const defaultReleasesURL = "https://api.github.com/repos/ElDanissito/Nebula-Challenge/releases/latest"
+
+// Archivos de cada release además de los binarios
+const (
+	checksumsAsset = "checksums.txt"    // sha256sum de cada binario
+	manifestAsset  = "manifest.txt"     // "version <tag>" seguido de checksums.txt: lo que se firma
+	signatureAsset = "manifest.txt.sig" // Firma ed25519 de manifest.txt, en base64
+)
+
+// Tamaño máximo de lo que se descarga, para que un servidor no agote la memoria
+const (
+	maxReleaseMetadataSize = 1 << 20   // Respuesta de la API, checksums, manifiesto y firma
+	maxReleaseBinarySize   = 256 << 20 // Binario
+)
+
+// updatePublicKey is the base64 ed25519 key that signs manifest.txt, set
+// at build time with -ldflags "-X main.updatePublicKey=...". When empty,
+// self-update refuses to install unless --allow-unsigned is given, and
+// never from a mirror: the checksums come from the same place as the
+// binary, so they only catch corrupted downloads.
+var updatePublicKey = ""
+
+// Release is the part of a GitHub release used by self-update
+type Release struct {
+	TagName string         `json:"tag_name"`
+	HTMLURL string         `json:"html_url"`
+	Assets  []ReleaseAsset `json:"assets"`
+}
+
+// ReleaseAsset is a file attached to a GitHub release
+type ReleaseAsset struct {
+	Name        string `json:"name"`
+	DownloadURL string `json:"browser_download_url"`
+}
+
+// asset returns the asset with the given name, or nil
+func (r *Release) asset(name string) *ReleaseAsset {
+	for i := range r.Assets {
+		if r.Assets[i].Name == name {
+			return &r.Assets[i]
+		}
+	}
+	return nil
+}
+
+// releaseAssetName returns the name of the binary for this platform, like
+// nebula_linux_amd64 or nebula_windows_amd64.exe
+func releaseAssetName() string {
+	name := fmt.Sprintf("nebula_%s_%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// updater downloads and verifies releases
+type updater struct {
+	client      *http.Client
+	releasesURL string
+	publicKey   ed25519.PublicKey // nil = sin verificación de firma
+}
+
+// latest returns the latest published release
+func (u *updater) latest() (*Release, error) {
+	data, err := u.get(u.releasesURL, maxReleaseMetadataSize)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo consultar la última versión: %w", err)
+	}
+	var release Release
+	if err := json.Unmarshal(data, &release); err != nil {
+		return nil, fmt.Errorf("error parseando respuesta JSON: %w", err)
+	}
+	if release.TagName == "" {
+		return nil, fmt.Errorf("la respuesta no indica la versión (tag_name)")
+	}
+	return &release, nil
+}
+
+// download fetches the binary of this platform from release and checks it
+// against the signed manifest.txt with a public key, or else checksums.txt
+func (u *updater) download(release *Release) ([]byte, error) {
+	name := releaseAssetName()
+	binary := release.asset(name)
+	if binary == nil {
+		return nil, fmt.Errorf("la versión %s no incluye un binario para %s/%s (%s)", release.TagName, runtime.GOOS, runtime.GOARCH, name)
+	}
+	sumsName := checksumsAsset
+	if u.publicKey != nil {
+		sumsName = manifestAsset
+	}
+	checksums := release.asset(sumsName)
+	if checksums == nil {
+		return nil, fmt.Errorf("la versión %s no incluye %s: no se puede verificar el binario", release.TagName, sumsName)
+	}
+
+	sums, err := u.get(checksums.DownloadURL, maxReleaseMetadataSize)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo descargar %s: %w", sumsName, err)
+	}
+	if u.publicKey != nil {
+		if err := u.verifySignature(release, sums); err != nil {
+			return nil, err
+		}
+	}
+	expected, err := findChecksum(sums, sumsName, name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := u.get(binary.DownloadURL, maxReleaseBinarySize)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo descargar %s: %w", name, err)
+	}
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return nil, fmt.Errorf("checksum inválido de %s: se esperaba %s y se obtuvo %s", name, expected, actual)
+	}
+	return data, nil
+}
+
+// verifySignature checks the ed25519 signature of manifest and that it is
+// the manifest of release. The tag is signed along with the checksums so
+// that an older signed release can't be served as a newer one.
+func (u *updater) verifySignature(release *Release, manifest []byte) error {
+	asset := release.asset(signatureAsset)
+	if asset == nil {
+		return fmt.Errorf("la versión %s no incluye %s y este binario exige releases firmadas", release.TagName, signatureAsset)
+	}
+	encoded, err := u.get(asset.DownloadURL, maxReleaseMetadataSize)
+	if err != nil {
+		return fmt.Errorf("no se pudo descargar %s: %w", signatureAsset, err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil {
+		return fmt.Errorf("firma inválida en %s: %w", signatureAsset, err)
+	}
+	if !ed25519.Verify(u.publicKey, manifest, signature) {
+		return fmt.Errorf("la firma de %s no es válida", manifestAsset)
+	}
+	first, _, _ := bytes.Cut(manifest, []byte("\n"))
+	tag, ok := strings.CutPrefix(strings.TrimSpace(string(first)), "version ")
+	if !ok {
+		return fmt.Errorf("%s no empieza con la versión (\"version <tag>\")", manifestAsset)
+	}
+	if tag != release.TagName {
+		return fmt.Errorf("%s está firmado para la versión %s, no para %s", manifestAsset, tag, release.TagName)
+	}
+	return nil
+}
+
+// get downloads url and returns its body, failing if it exceeds limit bytes
+func (u *updater) get(url string, limit int64) ([]byte, error) {
+	resp, err := u.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s respondió %d", url, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%s supera el tamaño máximo de %d bytes", url, limit)
+	}
+	return data, nil
+}
+
+// findChecksum returns the SHA-256 of name in a sha256sum-style file, or
+// the lines of manifest.txt after its version
+func findChecksum(sums []byte, file, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// sha256sum marca los archivos binarios con "*"
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("%s no incluye el checksum de %s", file, name)
+}
+
+// compareVersions compares two versions like v1.4.0, returning -1, 0 or 1.
+// Pre-release suffixes (-rc1) are ignored.
+func compareVersions(a, b string) int {
+	partsA, partsB := versionParts(a), versionParts(b)
+	for i := range max(len(partsA), len(partsB)) {
+		var x, y int
+		if i < len(partsA) {
+			x = partsA[i]
+		}
+		if i < len(partsB) {
+			y = partsB[i]
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionParts returns the numeric components of a version
+func versionParts(version string) []int {
+	version = strings.TrimPrefix(version, "v")
+	version, _, _ = strings.Cut(version, "-")
+	var parts []int
+	for _, field := range strings.Split(version, ".") {
+		n, _ := strconv.Atoi(field)
+		parts = append(parts, n)
+	}
+	return parts
+}
+
+// replaceExecutable writes data over the running binary. The new file is
+// written next to it and renamed, so an interrupted update never leaves a
+// half-written binary behind.
+func replaceExecutable(data []byte) (string, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("no se pudo ubicar el binario actual: %w", err)
+	}
+	if path, err = filepath.EvalSymlinks(path); err != nil {
+		return "", fmt.Errorf("no se pudo ubicar el binario actual: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".new-*")
+	if err != nil {
+		return "", fmt.Errorf("no se puede escribir junto al binario (¿faltan permisos?): %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("error escribiendo el binario nuevo: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("error escribiendo el binario nuevo: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return "", err
+	}
+
+	// Windows no permite reemplazar un ejecutable en uso, pero sí renombrarlo
+	if runtime.GOOS == "windows" {
+		old := path + ".old"
+		os.Remove(old)
+		if err := os.Rename(path, old); err != nil {
+			return "", fmt.Errorf("no se pudo mover el binario actual: %w", err)
+		}
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("no se pudo reemplazar el binario: %w", err)
+	}
+	return path, nil
+}
+
+// runSelfUpdate implements the "self-update" subcommand
+func runSelfUpdate(args []string) error {
+	fs := flag.NewFlagSet("self-update", flag.ExitOnError)
+	checkOnly := fs.Bool("check-only", false, "solo informar si hay una versión nueva, sin instalarla")
+	force := fs.Bool("force", false, "instalar la última versión aunque no sea más nueva (o el binario sea de desarrollo)")
+	releasesURL := fs.String("releases-url", os.Getenv("NEBULA_RELEASES_URL"), "URL de la última release en la API de GitHub o un mirror compatible (también NEBULA_RELEASES_URL); para instalar desde un mirror el binario debe tener la clave de firma")
+	allowUnsigned := fs.Bool("allow-unsigned", false, "instalar desde las releases de GitHub aunque este binario no tenga la clave de firma (solo se comprueba el checksum publicado junto al binario)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s self-update [--check-only] [--force] [--allow-unsigned]\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	u := &updater{
+		client:      &http.Client{Timeout: 5 * time.Minute},
+		releasesURL: defaultReleasesURL,
+	}
+	mirror := *releasesURL != "" && *releasesURL != defaultReleasesURL
+	if mirror {
+		u.releasesURL = *releasesURL
+	}
+	if updatePublicKey != "" {
+		key, err := base64.StdEncoding.DecodeString(updatePublicKey)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return fmt.Errorf("clave pública de actualización inválida en el binario")
+		}
+		u.publicKey = key
+	}
+	// Sin firma, un mirror podría servir cualquier binario con su propio checksum
+	if u.publicKey == nil && !*checkOnly {
+		switch {
+		case mirror:
+			return fmt.Errorf("este binario no tiene la clave de firma de las releases (main.updatePublicKey): no se puede instalar desde %s", u.releasesURL)
+		case !*allowUnsigned:
+			return fmt.Errorf("este binario no tiene la clave de firma de las releases (main.updatePublicKey): usa --allow-unsigned para instalar solo con el checksum de GitHub, o --check-only")
+		}
+	}
+
+	release, err := u.latest()
+	if err != nil {
+		return err
+	}
+
+	current := toolVersion()
+	dev := current == "dev"
+	newer := dev || compareVersions(release.TagName, current) > 0
+	switch {
+	case dev:
+		fmt.Printf("Versión actual: desarrollo · última versión: %s\n", release.TagName)
+	case newer:
+		fmt.Printf("Hay una versión nueva: %s (actual: %s)\n", release.TagName, current)
+	default:
+		fmt.Printf("nebula %s es la última versión\n", current)
+	}
+	if release.HTMLURL != "" && newer {
+		fmt.Printf("Notas: %s\n", release.HTMLURL)
+	}
+
+	if *checkOnly || (!newer && !*force) {
+		return nil
+	}
+	if dev && !*force {
+		return fmt.Errorf("este binario es de desarrollo (sin -ldflags \"-X main.version=...\"): usa --force para reemplazarlo")
+	}
+
+	data, err := u.download(release)
+	if err != nil {
+		return err
+	}
+	path, err := replaceExecutable(data)
+	if err != nil {
+		return err
+	}
+	if u.publicKey != nil {
+		fmt.Printf("✅ %s actualizado a %s (firma ed25519 verificada)\n", path, release.TagName)
+	} else {
+		fmt.Printf("⚠️  %s actualizado a %s sin verificar la firma: solo se comprobó el checksum publicado por GitHub junto al binario\n", path, release.TagName)
+	}
+	return nil
+}
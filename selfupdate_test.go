@@ -0,0 +1,61 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestSelfUpdateSignedManifest checks that a binary is only installed when
+// the signed manifest names the tag of the release being installed
+func TestSelfUpdateSignedManifest(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary := []byte("nebula nuevo")
+	sum := sha256.Sum256(binary)
+	sign := func(tag string) (string, string) {
+		manifest := fmt.Sprintf("version %s\n%s  %s\n", tag, hex.EncodeToString(sum[:]), releaseAssetName())
+		return manifest, base64.StdEncoding.EncodeToString(ed25519.Sign(private, []byte(manifest)))
+	}
+
+	files := map[string]string{"/" + releaseAssetName(): string(binary)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(data))
+	}))
+	defer server.Close()
+	release := &Release{TagName: "v2.0.0", Assets: []ReleaseAsset{
+		{Name: releaseAssetName(), DownloadURL: server.URL + "/" + releaseAssetName()},
+		{Name: manifestAsset, DownloadURL: server.URL + "/" + manifestAsset},
+		{Name: signatureAsset, DownloadURL: server.URL + "/" + signatureAsset},
+	}}
+	u := &updater{client: server.Client(), publicKey: public}
+
+	files["/"+manifestAsset], files["/"+signatureAsset] = sign("v2.0.0")
+	if data, err := u.download(release); err != nil || string(data) != string(binary) {
+		t.Errorf("manifiesto firmado para v2.0.0: %q, %v", data, err)
+	}
+
+	// Una release vieja firmada, servida como si fuera la nueva
+	files["/"+manifestAsset], files["/"+signatureAsset] = sign("v1.0.0")
+	if _, err := u.download(release); err == nil || !strings.Contains(err.Error(), "v1.0.0") {
+		t.Errorf("manifiesto firmado para v1.0.0 servido como v2.0.0: %v, se esperaba un error", err)
+	}
+
+	// Una respuesta más grande que el límite no se lee entera
+	if _, err := u.get(server.URL+"/"+releaseAssetName(), int64(len(binary)-1)); err == nil {
+		t.Errorf("se aceptó una respuesta de %d bytes con un límite de %d", len(binary), len(binary)-1)
+	}
+}
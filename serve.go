@@ -0,0 +1,467 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"ssllabs-scanner/store"
+)
+
+// serveShutdownTimeout bounds how long serve waits for the open requests
+// after SIGINT or SIGTERM
+const serveShutdownTimeout = 10 * time.Second
+
+// domainState holds the latest assessment of a monitored domain
+type domainState struct {
+	result   *AssessmentResult // Último resultado exitoso
+	err      error             // Error de la última evaluación, nil si fue exitosa
+	lastScan time.Time
+	duration time.Duration
+}
+
+// Exporter keeps the latest assessment of each domain and renders
+// them in the Prometheus text exposition format
+type Exporter struct {
+	mu            sync.RWMutex
+	domains       map[string]*domainState
+	history       store.Store     // Historial donde guardar cada evaluación (opcional)
+	notifications *Notifications  // Notificaciones de cambios (opcional)
+	requests      *RequestMetrics // Peticiones a la API (opcional)
+	monitor       *SelfMonitor    // Automonitoreo del proceso (opcional)
+}
+
+// NewExporter creates an exporter for the given domains
+func NewExporter(domains []string) *Exporter {
+	e := &Exporter{domains: make(map[string]*domainState, len(domains))}
+	for _, domain := range domains {
+		e.domains[domain] = &domainState{}
+	}
+	return e
+}
+
+// Record stores the outcome of an assessment. On error the previous
+// result is kept so the gauges don't disappear on transient failures.
+func (e *Exporter) Record(domain string, result *AssessmentResult, err error, started time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	state, ok := e.domains[domain]
+	if !ok {
+		state = &domainState{}
+		e.domains[domain] = state
+	}
+	if result != nil {
+		state.result = result
+	}
+	state.err = err
+	state.lastScan = time.Now()
+	state.duration = time.Since(started)
+}
+
+// ScanLoop assesses every domain sequentially, then waits interval and starts over
+func (e *Exporter) ScanLoop(scanner *Scanner, interval time.Duration) {
+	for {
+		// Las alertas de una ronda salen juntas al terminarla
+		round := e.notifications.Batched()
+		for _, domain := range e.domainNames() {
+			started := time.Now()
+			result, err := scanner.Assess(domain)
+			if err != nil {
+				slog.Error("evaluación fallida", "domain", domain, "error", err)
+			} else {
+				recordAssessment(e.history, round, result)
+			}
+			e.Record(domain, result, err, started)
+		}
+		flushNotifications(round)
+
+		time.Sleep(interval)
+	}
+}
+
+// Latest returns the latest successful result of a monitored domain, or
+// nil if it has none yet
+func (e *Exporter) Latest(domain string) *AssessmentResult {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if state, ok := e.domains[domain]; ok {
+		return state.result
+	}
+	return nil
+}
+
+// Monitored returns the name of the monitored domain matching domain,
+// ignoring case, and whether there is one
+func (e *Exporter) Monitored(domain string) (string, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	for name := range e.domains {
+		if strings.EqualFold(name, domain) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// domainNames returns the monitored domains sorted alphabetically
+func (e *Exporter) domainNames() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	names := make([]string, 0, len(e.domains))
+	for name := range e.domains {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ServeHTTP implements the /metrics handler
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	e.WriteMetrics(w, time.Now())
+}
+
+// WriteMetrics writes all gauges in the Prometheus text format
+func (e *Exporter) WriteMetrics(w io.Writer, now time.Time) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	names := make([]string, 0, len(e.domains))
+	for name := range e.domains {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	writeHeader(w, "ssllabs_scan_success", "1 if the last assessment of the domain succeeded")
+	for _, name := range names {
+		state := e.domains[name]
+		if state.lastScan.IsZero() {
+			continue
+		}
+		success := 1
+		if state.err != nil {
+			success = 0
+		}
+		fmt.Fprintf(w, "ssllabs_scan_success{domain=%s} %d\n", promLabel(name), success)
+	}
+
+	writeHeader(w, "ssllabs_last_scan_timestamp_seconds", "Time of the last assessment of the domain (Unix)")
+	for _, name := range names {
+		state := e.domains[name]
+		if state.lastScan.IsZero() {
+			continue
+		}
+		fmt.Fprintf(w, "ssllabs_last_scan_timestamp_seconds{domain=%s} %d\n", promLabel(name), state.lastScan.Unix())
+	}
+
+	writeHeader(w, "ssllabs_scan_duration_seconds", "Duration of the last assessment of the domain")
+	for _, name := range names {
+		state := e.domains[name]
+		if state.lastScan.IsZero() {
+			continue
+		}
+		fmt.Fprintf(w, "ssllabs_scan_duration_seconds{domain=%s} %g\n", promLabel(name), state.duration.Seconds())
+	}
+
+	writeHeader(w, "ssllabs_scan_info", "Provenance of the last successful assessment of the domain (always 1)")
+	for _, name := range names {
+		state := e.domains[name]
+		if state.result == nil {
+			continue
+		}
+		m := state.result.Metadata
+		fmt.Fprintf(w, "ssllabs_scan_info{domain=%s,engine_version=%s,criteria_version=%s,tool_version=%s,source=%s,from_cache=%s,publish=%s} 1\n",
+			promLabel(name), promLabel(m.EngineVersion), promLabel(m.CriteriaVersion), promLabel(m.ToolVersion),
+			promLabel(m.Source), promLabel(onOff(m.FromCache)), promLabel(onOff(m.Publish)))
+	}
+
+	writeHeader(w, "ssllabs_grade", "Overall grade of the domain (15 = A+, 14 = A, ..., 2 = F, 1 = T, 0 = M)")
+	for _, name := range names {
+		state := e.domains[name]
+		if state.result == nil {
+			continue
+		}
+		if score, ok := gradeOrder[state.result.OverallGrade]; ok {
+			fmt.Fprintf(w, "ssllabs_grade{domain=%s,grade=%s} %d\n", promLabel(name), promLabel(state.result.OverallGrade), score)
+		}
+	}
+
+	writeHeader(w, "ssllabs_endpoint_grade", "Grade of each endpoint (same scale as ssllabs_grade)")
+	for _, name := range names {
+		state := e.domains[name]
+		if state.result == nil {
+			continue
+		}
+		for _, endpoint := range state.result.Endpoints {
+			if score, ok := gradeOrder[endpoint.Grade]; ok {
+				fmt.Fprintf(w, "ssllabs_endpoint_grade{domain=%s,endpoint=%s,grade=%s} %d\n",
+					promLabel(name), promLabel(endpoint.IPAddress), promLabel(endpoint.Grade), score)
+			}
+		}
+	}
+
+	writeHeader(w, "ssllabs_endpoint_error", "1 if SSL Labs could not assess the endpoint")
+	for _, name := range names {
+		state := e.domains[name]
+		if state.result == nil {
+			continue
+		}
+		for _, endpointErr := range state.result.EndpointErrors {
+			fmt.Fprintf(w, "ssllabs_endpoint_error{domain=%s,endpoint=%s,message=%s} 1\n",
+				promLabel(name), promLabel(endpointErr.IPAddress), promLabel(endpointErr.Message))
+		}
+	}
+
+	writeHeader(w, "ssllabs_protocols_status", "TLS protocols of the endpoint: 0 = secure protocols offered, 1 = only insecure ones (critical), 2 = no data")
+	for _, name := range names {
+		state := e.domains[name]
+		if state.result == nil {
+			continue
+		}
+		for _, endpoint := range state.result.Endpoints {
+			fmt.Fprintf(w, "ssllabs_protocols_status{domain=%s,endpoint=%s} %d\n",
+				promLabel(name), promLabel(endpoint.IPAddress), endpoint.ProtocolStatus)
+		}
+	}
+
+	writeHeader(w, "ssllabs_cert_expiry_seconds", "Seconds until the certificate expires (negative if expired)")
+	for _, name := range names {
+		state := e.domains[name]
+		if state.result == nil {
+			continue
+		}
+		for _, endpoint := range state.result.Endpoints {
+			if endpoint.CertValidTo <= 0 {
+				continue
+			}
+			seconds := time.UnixMilli(endpoint.CertValidTo).Sub(now).Seconds()
+			fmt.Fprintf(w, "ssllabs_cert_expiry_seconds{domain=%s,endpoint=%s} %.0f\n",
+				promLabel(name), promLabel(endpoint.IPAddress), seconds)
+		}
+	}
+
+	writeHeader(w, "ssllabs_vulnerable", "1 if the endpoint is vulnerable to the given attack")
+	for _, name := range names {
+		state := e.domains[name]
+		if state.result == nil {
+			continue
+		}
+		for _, endpoint := range state.result.Endpoints {
+			if endpoint.Details == nil {
+				continue
+			}
+			for _, check := range vulnerabilityChecks(endpoint.Details) {
+				value := 0
+				if check.Vulnerable {
+					value = 1
+				}
+				fmt.Fprintf(w, "ssllabs_vulnerable{domain=%s,endpoint=%s,vuln=%s} %d\n",
+					promLabel(name), promLabel(endpoint.IPAddress), promLabel(check.ID), value)
+			}
+		}
+	}
+
+	writeHeader(w, "ssllabs_sim_failures", "Simulated clients (browsers, Android, Java...) that can't complete a handshake with the endpoint")
+	for _, name := range names {
+		state := e.domains[name]
+		if state.result == nil {
+			continue
+		}
+		for _, endpoint := range state.result.Endpoints {
+			if endpoint.Details == nil || endpoint.Details.Sims == nil {
+				continue
+			}
+			fmt.Fprintf(w, "ssllabs_sim_failures{domain=%s,endpoint=%s} %d\n",
+				promLabel(name), promLabel(endpoint.IPAddress), simFailures(endpoint.Details.Sims))
+		}
+	}
+
+	if e.requests != nil {
+		e.requests.WriteMetrics(w)
+	}
+	if e.notifications != nil {
+		e.notifications.metrics.WriteMetrics(w)
+	}
+	if e.monitor != nil {
+		e.monitor.WriteMetrics(w)
+	}
+}
+
+// writeHeader writes the HELP and TYPE lines of a gauge
+func writeHeader(w io.Writer, name, help string) {
+	writeTypedHeader(w, name, help, "gauge")
+}
+
+// writeTypedHeader writes the HELP and TYPE lines of a metric of the given type
+func writeTypedHeader(w io.Writer, name, help, metricType string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, metricType)
+}
+
+// promLabel quotes a label value escaping backslashes, quotes and newlines
+func promLabel(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// runServe implements the "serve" subcommand: a Prometheus exporter that
+// periodically assesses the given domains
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := fs.String("listen", ":9115", "dirección donde exponer /metrics")
+	interval := fs.Duration("interval", 24*time.Hour, "tiempo de espera entre rondas de evaluación")
+	inputFile := fs.String("input", "", "archivo con un dominio por línea (\"-\" para leer de stdin)")
+	configPath := fs.String("config", "", "archivo de configuración YAML (por defecto ~/.config/nebula/config.yaml si existe; los flags tienen prioridad)")
+	enableAPI := fs.Bool("api", false, "exponer la API HTTP (POST /scan, GET /scan/{id}, GET /results/{domain})")
+	apiToken := fs.String("api-token", os.Getenv("NEBULA_API_TOKEN"), "bearer token requerido por la API, obligatorio con --api")
+	triggerOpts := addTriggerFlags(fs)
+	apiFlags := addClientFlags(fs)
+	historyOpts := addHistoryFlags(fs)
+	notifyOpts := addNotifyFlags(fs)
+	profiling := addProfilingFlags(fs)
+	selfMonitor := addSelfMonitorFlags(fs)
+	logOpts := addLogFlags(fs)
+	tz := addTimezoneFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s serve [--listen :9115] [--interval 24h] [--input archivo] [--config archivo] [--api] <domain> [domain...]\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	domainArgs := fs.Args()
+	config, err := loadDefaultConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if config != nil {
+		// Los valores del archivo solo se usan si el flag no se pasó
+		if err := config.apply(fs); err != nil {
+			return err
+		}
+		domainArgs = append(domainArgs, config.Domains...)
+	}
+
+	// Con --api los dominios son opcionales: se evalúan a pedido
+	var domains []string
+	if !*enableAPI || len(domainArgs) > 0 || *inputFile != "" {
+		domains, err = collectDomains(domainArgs, *inputFile)
+		if err != nil {
+			fs.Usage()
+			return err
+		}
+	}
+
+	if err := setOutputTimezone(*tz); err != nil {
+		return err
+	}
+
+	logger, err := logOpts.logger(os.Stderr)
+	if err != nil {
+		return err
+	}
+
+	triggerSources, err := triggerOpts.sources()
+	if err != nil {
+		return err
+	}
+	// Sin token cualquiera con acceso a la dirección podría iniciar evaluaciones
+	// con el email y la cuota de este cliente
+	if *enableAPI && *apiToken == "" {
+		return fmt.Errorf("--api requiere --api-token (o NEBULA_API_TOKEN)")
+	}
+	// Los webhooks reevalúan dominios monitoreados: sin dominios no tienen sentido
+	if triggerSources != nil && len(domains) == 0 {
+		return fmt.Errorf("--trigger-secrets requiere dominios monitoreados")
+	}
+
+	clientOpts, err := apiFlags.options()
+	if err != nil {
+		return err
+	}
+	clientOpts = append(clientOpts, WithLogger(logger))
+
+	notifications, err := notifyOpts.notifications()
+	if err != nil {
+		return err
+	}
+
+	history, err := historyOpts.open()
+	if err != nil {
+		return err
+	}
+
+	monitor, err := selfMonitor.monitor()
+	if err != nil {
+		return err
+	}
+
+	if err := profiling.start(); err != nil {
+		return err
+	}
+
+	exporter := NewExporter(domains)
+	exporter.history = history
+	exporter.notifications = notifications
+	exporter.requests = NewRequestMetrics()
+	exporter.monitor = monitor
+	if monitor != nil {
+		go monitor.Run()
+	}
+	clientOpts = append(clientOpts, WithMetrics(exporter.requests))
+	scanner := NewScanner(clientOpts...)
+	if len(domains) > 0 {
+		go exporter.ScanLoop(scanner, *interval)
+	}
+
+	// SIGINT/SIGTERM cierra el servidor y cancela las reevaluaciones en curso
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", exporter)
+	mux.Handle("GET /{$}", &Dashboard{exporter: exporter, history: history})
+
+	fmt.Printf("Exponiendo métricas de %d dominios en %s/metrics (dashboard en %s/)\n", len(domains), *listen, *listen)
+	if triggerSources != nil {
+		NewTriggerReceiver(ctx, exporter, scanner, triggerSources).Register(mux)
+		fmt.Printf("Webhooks de reevaluación habilitados en %s/hooks/{fuente} (%d fuentes)\n", *listen, len(triggerSources))
+	}
+	if *enableAPI {
+		api := NewScanAPI(ctx, scanner)
+		api.exporter = exporter
+		api.history = history
+		api.config = config
+		api.notifications = notifications
+		api.token = *apiToken
+		api.Register(mux)
+		fmt.Printf("API HTTP habilitada en %s (POST /scan, GET /scan/{id}, GET /results/{domain})\n", *listen)
+	}
+
+	server := &http.Server{Addr: *listen, Handler: mux, BaseContext: func(net.Listener) context.Context { return ctx }}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"cmp"
+	"crypto/tls"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// label renders a simulated client as SSL Labs does, e.g. "Android 4.4.2"
+// or "IE 11 / Win 7 (R)" for the reference clients
+func (c SimClient) label() string {
+	label := strings.TrimSpace(c.Name + " " + c.Version)
+	if c.Platform != "" {
+		label += " / " + c.Platform
+	}
+	if c.IsReference {
+		label += " (R)"
+	}
+	return label
+}
+
+// simFailures counts the simulated clients that can't connect
+func simFailures(sims *SimDetails) int {
+	failures := 0
+	for _, sim := range sims.Results {
+		if sim.ErrorCode != 0 {
+			failures++
+		}
+	}
+	return failures
+}
+
+// simSuiteName returns the name of the suite negotiated in sim. API v2 only
+// returns its ID, which is looked up in the suites of the endpoint.
+func simSuiteName(d *EndpointDetails, sim Simulation) string {
+	if sim.SuiteName != "" {
+		return sim.SuiteName
+	}
+	for _, suites := range d.Suites {
+		for _, suite := range suites.List {
+			if suite.ID == sim.SuiteID {
+				return suite.Name
+			}
+		}
+	}
+	return tls.CipherSuiteName(uint16(sim.SuiteID))
+}
+
+// displaySims prints the handshake simulations of an endpoint: first the
+// clients that can't connect, then the protocol and suite each of the
+// others negotiates
+func displaySims(d *EndpointDetails) {
+	if d != nil && d.Local {
+		fmt.Println(tr("Simulación de clientes: ❔ No evaluada (evaluación local)"))
+		return
+	}
+	if d == nil || d.Sims == nil || len(d.Sims.Results) == 0 {
+		fmt.Println(tr("Simulación de clientes: ❔ Sin datos"))
+		return
+	}
+
+	results := slices.Clone(d.Sims.Results)
+	// Los que fallan primero; el resto en el orden de SSL Labs
+	slices.SortStableFunc(results, func(a, b Simulation) int {
+		return cmp.Compare(min(b.ErrorCode, 1), min(a.ErrorCode, 1))
+	})
+
+	failures := simFailures(d.Sims)
+	fmt.Printf(tr("Simulación de clientes: %d de %d conectan\n"), len(results)-failures, len(results))
+	for _, sim := range results {
+		if sim.ErrorCode != 0 {
+			message := sim.ErrorMessage
+			if message == "" {
+				message = tr("el handshake falla")
+			}
+			fmt.Printf("  %s\n", paint(colorRed, fmt.Sprintf("❌ %s: %s", sim.Client.label(), message)))
+			continue
+		}
+		negotiated := []string{protocolName(sim.ProtocolID), simSuiteName(d, sim)}
+		if sim.NamedGroupName != "" {
+			negotiated = append(negotiated, sim.NamedGroupName)
+		}
+		fmt.Printf("  ✅ %s: %s\n", sim.Client.label(), strings.Join(negotiated, " · "))
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Cipher suites de TLS 1.2 de la configuración "intermediate" de Mozilla,
+// sin DHE: todas con Forward Secrecy y AEAD
+const (
+	snippetCiphers   = "ECDHE-ECDSA-AES128-GCM-SHA256:ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES256-GCM-SHA384:ECDHE-RSA-AES256-GCM-SHA384:ECDHE-ECDSA-CHACHA20-POLY1305:ECDHE-RSA-CHACHA20-POLY1305"
+	snippetTLS13     = "TLS_AES_128_GCM_SHA256:TLS_AES_256_GCM_SHA384:TLS_CHACHA20_POLY1305_SHA256"
+	snippetHSTSValue = "max-age=31536000"
+)
+
+// Tipos de cambio de configuración, compartidos por varios motivos del grade
+const (
+	snippetProtocols = "protocols"
+	snippetCipherSet = "ciphers"
+	snippetDHParams  = "dhparams"
+	snippetHSTS      = "hsts"
+)
+
+// snippetKinds maps the IDs of gradeReason to the change that fixes them
+var snippetKinds = map[string]string{
+	"ssl":                     snippetProtocols,
+	"no_tls12":                snippetProtocols,
+	"old_protocols":           snippetProtocols,
+	"vuln_poodle":             snippetProtocols,
+	"vuln_beast":              snippetProtocols,
+	"rc4":                     snippetCipherSet,
+	"weak_ciphers":            snippetCipherSet,
+	"no_forward_secrecy":      snippetCipherSet,
+	"partial_forward_secrecy": snippetCipherSet,
+	"no_aead":                 snippetCipherSet,
+	"vuln_freak":              snippetCipherSet,
+	"vuln_robot":              snippetCipherSet,
+	"vuln_poodle_tls":         snippetCipherSet,
+	"vuln_zombie_poodle":      snippetCipherSet,
+	"vuln_goldendoodle":       snippetCipherSet,
+	"weak_dh":                 snippetDHParams,
+	"vuln_logjam":             snippetDHParams,
+	"no_hsts":                 snippetHSTS,
+	"short_hsts":              snippetHSTS,
+}
+
+// configSnippets are the ready-to-paste snippets of each server, by kind
+var configSnippets = map[string]map[string]string{
+	"nginx": {
+		snippetProtocols: "# Bloque server (o http)\nssl_protocols TLSv1.2 TLSv1.3;",
+		snippetCipherSet: "# Bloque server (o http)\nssl_protocols TLSv1.2 TLSv1.3;\nssl_ciphers " + snippetCiphers + ";\nssl_prefer_server_ciphers off;",
+		snippetDHParams:  "# openssl dhparam -out /etc/nginx/dhparam.pem 2048\nssl_dhparam /etc/nginx/dhparam.pem;",
+		snippetHSTS:      "# Bloque server de HTTPS\nadd_header Strict-Transport-Security \"" + snippetHSTSValue + "\" always;",
+	},
+	"apache": {
+		snippetProtocols: "# VirtualHost *:443 (o configuración global de mod_ssl)\nSSLProtocol -all +TLSv1.2 +TLSv1.3",
+		snippetCipherSet: "# VirtualHost *:443 (o configuración global de mod_ssl)\nSSLProtocol -all +TLSv1.2 +TLSv1.3\nSSLCipherSuite " + snippetCiphers + "\nSSLHonorCipherOrder off",
+		snippetDHParams:  "# openssl dhparam -out /etc/ssl/dhparam.pem 2048\nSSLOpenSSLConfCmd DHParameters \"/etc/ssl/dhparam.pem\"",
+		snippetHSTS:      "# VirtualHost *:443, requiere mod_headers\nHeader always set Strict-Transport-Security \"" + snippetHSTSValue + "\"",
+	},
+	"haproxy": {
+		snippetProtocols: "# Sección global\nssl-default-bind-options ssl-min-ver TLSv1.2",
+		snippetCipherSet: "# Sección global\nssl-default-bind-options ssl-min-ver TLSv1.2\nssl-default-bind-ciphers " + snippetCiphers + "\nssl-default-bind-ciphersuites " + snippetTLS13,
+		snippetDHParams:  "# Sección global; openssl dhparam -out /etc/haproxy/dhparam.pem 2048\nssl-dh-param-file /etc/haproxy/dhparam.pem",
+		snippetHSTS:      "# Frontend de HTTPS\nhttp-response set-header Strict-Transport-Security \"" + snippetHSTSValue + "\"",
+	},
+}
+
+// validateSnippetServer checks the value of --snippets
+func validateSnippetServer(server string) error {
+	if _, ok := configSnippets[server]; server == "" || ok {
+		return nil
+	}
+	servers := make([]string, 0, len(configSnippets))
+	for name := range configSnippets {
+		servers = append(servers, name)
+	}
+	sort.Strings(servers)
+	return fmt.Errorf("servidor inválido para --snippets %q: se espera %s", server, strings.Join(servers, ", "))
+}
+
+// reasonSnippet returns the snippet of server that fixes reason, unless a
+// snippet of the same kind was already printed (listed in shown)
+func reasonSnippet(server string, reason gradeReason, shown *[]string) string {
+	kind, ok := snippetKinds[reason.ID]
+	if !ok || slices.Contains(*shown, kind) {
+		return ""
+	}
+	*shown = append(*shown, kind)
+	return configSnippets[server][kind]
+}
+
+// displaySnippet prints a configuration snippet under a grade reason, with
+// its comments in the output language
+func displaySnippet(snippet string) {
+	for _, line := range strings.Split(snippet, "\n") {
+		if strings.HasPrefix(line, "#") {
+			line = tr(line)
+		}
+		fmt.Printf("       %s\n", line)
+	}
+}
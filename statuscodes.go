@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// statusCodesCacheTTL is how long the translations saved on disk are
+// reused before fetching /getStatusCodes again
+const statusCodesCacheTTL = 7 * 24 * time.Hour
+
+// statusCodesResponse is the response of the /getStatusCodes endpoint
+type statusCodesResponse struct {
+	StatusDetails map[string]string `json:"statusDetails"`
+}
+
+// StatusCodes fetches the human-readable messages of the statusDetails
+// codes reported by endpoints in progress
+func (c *HTTPClient) StatusCodes(ctx context.Context) (map[string]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+statusCodesEndpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf(tr("error creando petición: %w"), err)
+	}
+
+	body, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp statusCodesResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf(tr("error parseando respuesta JSON: %w"), err)
+	}
+	return resp.StatusDetails, nil
+}
+
+// statusCodeCache translates statusDetails codes. The mapping is loaded
+// once per client, from the file at path when it is recent enough or
+// from the API otherwise. An empty path keeps it in memory only.
+type statusCodeCache struct {
+	mu     sync.Mutex
+	path   string
+	loaded bool
+	codes  map[string]string
+}
+
+// defaultStatusCodesPath returns the cache file for the given API version
+// under the user cache directory, or "" if there is none
+func defaultStatusCodesPath(apiVersion int) string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(cacheDir, "nebula", fmt.Sprintf("status-codes-v%d.json", apiVersion))
+}
+
+// translate returns the message of code, or code itself if it is unknown
+// or the mapping could not be loaded
+func (s *statusCodeCache) translate(ctx context.Context, client *HTTPClient, code string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.loaded {
+		s.loaded = true
+		s.codes = s.load(ctx, client)
+	}
+	if message, ok := s.codes[code]; ok && message != "" {
+		return message
+	}
+	return code
+}
+
+// load reads the cache file or fetches the mapping from the API, saving it
+// for the next runs. Failures are logged: the raw codes are still useful.
+func (s *statusCodeCache) load(ctx context.Context, client *HTTPClient) map[string]string {
+	if s.path != "" {
+		if info, err := os.Stat(s.path); err == nil && time.Since(info.ModTime()) < statusCodesCacheTTL {
+			if data, err := os.ReadFile(s.path); err == nil {
+				var codes map[string]string
+				if json.Unmarshal(data, &codes) == nil {
+					return codes
+				}
+			}
+		}
+	}
+
+	codes, err := client.StatusCodes(ctx)
+	if err != nil {
+		client.logger.Warn("no se pudieron obtener los mensajes de estado", "error", err)
+		return nil
+	}
+
+	if s.path != "" {
+		data, err := json.Marshal(codes)
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(s.path), 0o755)
+		}
+		if err == nil {
+			err = os.WriteFile(s.path, data, 0o644)
+		}
+		if err != nil {
+			client.logger.Debug("no se pudo guardar la cache de mensajes de estado", "path", s.path, "error", err)
+		}
+	}
+	return codes
+}
+
+// currentActivity describes what SSL Labs is testing on the first endpoint
+// in progress, translating its statusDetails code. It returns "" when
+// there is nothing to show.
+func (c *HTTPClient) currentActivity(ctx context.Context, host *Host) string {
+	if host.Status != statusInProgress {
+		return ""
+	}
+	for _, endpoint := range host.Endpoints {
+		if endpoint.StatusMessage != endpointStatusReady && endpoint.StatusDetails != "" {
+			return c.statusCodes.translate(ctx, c, endpoint.StatusDetails)
+		}
+	}
+	return ""
+}
+
+// withActivity appends the current activity to a progress message
+func withActivity(message, activity string) string {
+	if message == "" || activity == "" {
+		return message
+	}
+	return message + " · " + activity
+}
@@ -0,0 +1,107 @@
+package store
+
+// Tipos de cambio entre dos evaluaciones
+const (
+	ChangeGrade                = "grade"                 // Cambió el grade general (sin Endpoint) o el de un endpoint
+	ChangeEndpointAdded        = "endpoint_added"        // Endpoint nuevo
+	ChangeEndpointRemoved      = "endpoint_removed"      // El endpoint ya no existe
+	ChangeProtocolsAdded       = "protocols_added"       // Protocolos nuevos
+	ChangeProtocolsRemoved     = "protocols_removed"     // Protocolos que ya no se ofrecen
+	ChangeCert                 = "cert"                  // El endpoint sirve otro certificado
+	ChangeVulnerabilitiesAdded = "vulnerabilities_added" // Vulnerabilidades nuevas
+	ChangeVulnerabilitiesFixed = "vulnerabilities_fixed" // Vulnerabilidades corregidas
+)
+
+// Diff is what changed between two assessments of a domain
+type Diff struct {
+	Previous Entry
+	Current  Entry
+	Changes  []Change // Vacío si no cambió nada relevante
+}
+
+// Change is one difference between two assessments. Previous and Current
+// hold the grades of ChangeGrade and the certificate fingerprints of
+// ChangeCert; the rest of the current endpoint is in Diff.Current.
+type Change struct {
+	Kind     string   // Uno de los Change*
+	Endpoint string   // IP del endpoint, vacío para el grade general
+	Previous string   // Valor anterior
+	Current  string   // Valor nuevo
+	Values   []string // Protocolos o vulnerabilidades agregados o quitados
+}
+
+// Compare describes what changed from previous to current, two
+// assessments of the same domain. They don't need to be stored, so it
+// also compares fresh results.
+func Compare(previous, current Entry) Diff {
+	diff := Diff{Previous: previous, Current: current}
+	if previous.OverallGrade != current.OverallGrade {
+		diff.Changes = append(diff.Changes, Change{Kind: ChangeGrade, Previous: previous.OverallGrade, Current: current.OverallGrade})
+	}
+
+	previousByIP := make(map[string]Endpoint, len(previous.Endpoints))
+	for _, endpoint := range previous.Endpoints {
+		previousByIP[endpoint.IPAddress] = endpoint
+	}
+	for _, endpoint := range current.Endpoints {
+		old, ok := previousByIP[endpoint.IPAddress]
+		if !ok {
+			diff.Changes = append(diff.Changes, Change{Kind: ChangeEndpointAdded, Endpoint: endpoint.IPAddress, Current: endpoint.Grade})
+			continue
+		}
+		delete(previousByIP, endpoint.IPAddress)
+		diff.Changes = append(diff.Changes, endpointChanges(old, endpoint)...)
+	}
+
+	// Los endpoints que quedan en el mapa ya no existen
+	for _, endpoint := range previous.Endpoints {
+		if _, ok := previousByIP[endpoint.IPAddress]; ok {
+			diff.Changes = append(diff.Changes, Change{Kind: ChangeEndpointRemoved, Endpoint: endpoint.IPAddress, Previous: endpoint.Grade})
+		}
+	}
+	return diff
+}
+
+// endpointChanges describes the changes of a single endpoint
+func endpointChanges(previous, current Endpoint) []Change {
+	var changes []Change
+	add := func(kind, from, to string, values []string) {
+		changes = append(changes, Change{Kind: kind, Endpoint: current.IPAddress, Previous: from, Current: to, Values: values})
+	}
+
+	if previous.Grade != current.Grade {
+		add(ChangeGrade, previous.Grade, current.Grade, nil)
+	}
+	if added := missingFrom(previous.Protocols, current.Protocols); len(added) > 0 {
+		add(ChangeProtocolsAdded, "", "", added)
+	}
+	if removed := missingFrom(current.Protocols, previous.Protocols); len(removed) > 0 {
+		add(ChangeProtocolsRemoved, "", "", removed)
+	}
+	if previous.CertFingerprint != current.CertFingerprint {
+		add(ChangeCert, previous.CertFingerprint, current.CertFingerprint, nil)
+	}
+	if added := missingFrom(previous.Vulnerabilities, current.Vulnerabilities); len(added) > 0 {
+		add(ChangeVulnerabilitiesAdded, "", "", added)
+	}
+	if fixed := missingFrom(current.Vulnerabilities, previous.Vulnerabilities); len(fixed) > 0 {
+		add(ChangeVulnerabilitiesFixed, "", "", fixed)
+	}
+	return changes
+}
+
+// missingFrom returns the values of list that aren't in other, keeping
+// their order
+func missingFrom(other, list []string) []string {
+	in := make(map[string]bool, len(other))
+	for _, value := range other {
+		in[value] = true
+	}
+	var missing []string
+	for _, value := range list {
+		if !in[value] {
+			missing = append(missing, value)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,244 @@
+package store
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Estados de un hallazgo
+const (
+	FindingOpen         = "open"         // Detectado y sin revisar
+	FindingAcknowledged = "acknowledged" // Reconocido: se sabe y se acepta por ahora
+	FindingResolved     = "resolved"     // Ya no aparece en las evaluaciones, o resuelto a mano
+)
+
+// FindingStates are the valid states, in the order they are listed
+var FindingStates = []string{FindingOpen, FindingAcknowledged, FindingResolved}
+
+// FindingSeverities are the severities of a finding, most severe first
+var FindingSeverities = []string{"critical", "high", "medium", "low"}
+
+// ruleSeverity is the severity of each rule by the grade it caps; vuln_*
+// rules are all critical
+var ruleSeverity = map[string]string{
+	"hostname_mismatch":       "critical",
+	"untrusted":               "critical",
+	"expired":                 "critical",
+	"revoked":                 "critical",
+	"weak_signature":          "critical",
+	"blacklisted_key":         "critical",
+	"insecure_renegotiation":  "critical",
+	"ssl":                     "critical",
+	"no_tls12":                "high",
+	"rc4":                     "high",
+	"weak_ciphers":            "high",
+	"compression":             "high",
+	"no_secure_renegotiation": "high",
+	"old_protocols":           "medium",
+	"weak_key":                "medium",
+	"weak_dh":                 "medium",
+	"no_forward_secrecy":      "medium",
+	"partial_forward_secrecy": "low",
+	"no_aead":                 "low",
+	"no_fallback_scsv":        "low",
+	"no_hsts":                 "low",
+	"short_hsts":              "low",
+}
+
+// Finding is a condition that keeps an endpoint of a domain below A+,
+// tracked across assessments. It opens when an assessment detects it,
+// resolves itself when one of the endpoint no longer does and reopens if
+// it comes back; acknowledging it stops its alerts.
+type Finding struct {
+	ID        int64
+	Domain    string
+	IPAddress string
+	Rule      string // ID de la condición (ej: "old_protocols")
+	Severity  string
+	Detail    string // Descripción de la última evaluación que lo detectó
+	State     string
+	Comment   string // Comentario del último cambio de estado
+	Owner     string // Responsable asignado con findings assign, vacío si no tiene
+	FirstSeen time.Time
+	LastSeen  time.Time
+	UpdatedAt time.Time // Último cambio de estado
+}
+
+// FindingEvent is a state change of a finding
+type FindingEvent struct {
+	FindingID int64
+	At        time.Time
+	State     string // Estado al que pasó
+	Comment   string
+}
+
+// FindingFilter selects findings; empty fields match everything
+type FindingFilter struct {
+	ID       int64
+	Domain   string
+	Domains  []string // Los dominios de una etiqueta (--tag); nil no filtra
+	State    string
+	Rule     string
+	Severity string
+	Owner    string
+}
+
+// ErrFindingNotFound is returned for a finding ID that isn't in the store
+var ErrFindingNotFound = errors.New("hallazgo desconocido")
+
+// matches reports whether f passes the filter
+func (filter FindingFilter) matches(f Finding) bool {
+	return (filter.ID == 0 || f.ID == filter.ID) &&
+		(filter.Domain == "" || f.Domain == filter.Domain) &&
+		(filter.Domains == nil || slices.Contains(filter.Domains, f.Domain)) &&
+		(filter.State == "" || f.State == filter.State) &&
+		(filter.Rule == "" || f.Rule == filter.Rule) &&
+		(filter.Severity == "" || f.Severity == filter.Severity) &&
+		(filter.Owner == "" || f.Owner == filter.Owner)
+}
+
+// where returns the SQL condition of the filter, with its arguments
+func (filter FindingFilter) where() (string, []any) {
+	conditions := []string{"1 = 1"}
+	var args []any
+	if filter.ID != 0 {
+		conditions = append(conditions, "id = ?")
+		args = append(args, filter.ID)
+	}
+	if filter.Domain != "" {
+		conditions = append(conditions, "domain = ?")
+		args = append(args, filter.Domain)
+	}
+	if filter.Domains != nil {
+		conditions = append(conditions, "domain IN ("+strings.TrimSuffix(strings.Repeat("?, ", len(filter.Domains)), ", ")+")")
+		for _, domain := range filter.Domains {
+			args = append(args, domain)
+		}
+	}
+	for _, column := range []struct{ name, value string }{
+		{"state", filter.State}, {"rule", filter.Rule}, {"severity", filter.Severity}, {"owner", filter.Owner},
+	} {
+		if column.value != "" {
+			conditions = append(conditions, column.name+" = ?")
+			args = append(args, column.value)
+		}
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// Severity returns the severity of a rule
+func Severity(rule string) string {
+	if strings.HasPrefix(rule, "vuln_") {
+		return "critical"
+	}
+	return cmp.Or(ruleSeverity[rule], "medium")
+}
+
+// sortFindings orders findings most severe first, then by domain, endpoint
+// and rule
+func sortFindings(findings []Finding) {
+	slices.SortFunc(findings, func(a, b Finding) int {
+		return cmp.Or(
+			cmp.Compare(slices.Index(FindingSeverities, a.Severity), slices.Index(FindingSeverities, b.Severity)),
+			cmp.Compare(a.Domain, b.Domain),
+			cmp.Compare(a.IPAddress, b.IPAddress),
+			cmp.Compare(a.Rule, b.Rule),
+		)
+	})
+}
+
+// ValidateFindingState checks that state is one of FindingStates
+func ValidateFindingState(state string) error {
+	if !slices.Contains(FindingStates, state) {
+		return fmt.Errorf("estado de hallazgo inválido %q: se espera %s", state, strings.Join(FindingStates, ", "))
+	}
+	return nil
+}
+
+// findingKey identifies a finding within a domain
+type findingKey struct {
+	IPAddress string
+	Rule      string
+}
+
+// findingChange is a change that an assessment makes to a finding: the
+// finding with its new data (ID 0 if it is new) and, when its state
+// changes, the event to record
+type findingChange struct {
+	Finding Finding
+	Event   *FindingEvent
+}
+
+// reconcileFindings compares the conditions that a detects with the known
+// findings of its domain, and returns the findings to insert or update.
+// Only the judged endpoints of a are compared, and only on the rules their
+// assessment checks: a finding of an endpoint missing from a (filtered
+// out, without details, or gone) keeps its state.
+func reconcileFindings(a Assessment, known []Finding, at time.Time) []findingChange {
+	byKey := make(map[findingKey]Finding)
+	for _, f := range known {
+		byKey[findingKey{f.IPAddress, f.Rule}] = f
+	}
+
+	var changes []findingChange
+	detected := make(map[findingKey]bool)
+	judged := make(map[string]JudgedEndpoint)
+	for _, endpoint := range a.Judged {
+		judged[endpoint.IPAddress] = endpoint
+		for _, detection := range endpoint.Detected {
+			key := findingKey{endpoint.IPAddress, detection.Rule}
+			detected[key] = true
+			f, ok := byKey[key]
+			change := findingChange{}
+			switch {
+			case !ok:
+				f = Finding{Domain: a.Domain, IPAddress: endpoint.IPAddress, Rule: detection.Rule, State: FindingOpen,
+					Comment: "detectado", FirstSeen: at, UpdatedAt: at}
+				change.Event = &FindingEvent{At: at, State: FindingOpen, Comment: f.Comment}
+			case f.State == FindingResolved:
+				f.State, f.Comment, f.UpdatedAt = FindingOpen, "volvió a detectarse", at
+				change.Event = &FindingEvent{FindingID: f.ID, At: at, State: FindingOpen, Comment: f.Comment}
+			}
+			f.Severity, f.Detail, f.LastSeen = Severity(detection.Rule), detection.Detail, at
+			change.Finding = f
+			changes = append(changes, change)
+		}
+	}
+
+	for _, f := range known {
+		endpoint, ok := judged[f.IPAddress]
+		if f.State == FindingResolved || !ok || detected[findingKey{f.IPAddress, f.Rule}] {
+			continue
+		}
+		if endpoint.Rules != nil && !slices.Contains(endpoint.Rules, f.Rule) {
+			continue
+		}
+		f.State, f.Comment, f.UpdatedAt = FindingResolved, "ya no se detecta", at
+		changes = append(changes, findingChange{Finding: f, Event: &FindingEvent{FindingID: f.ID, At: at, State: FindingResolved, Comment: f.Comment}})
+	}
+	return changes
+}
+
+// recheckedFinding applies the result of a re-check to f the way
+// reconcileFindings applies an assessment: a detected finding is seen again
+// (and reopened if it was resolved), one no longer detected is resolved.
+// It returns the event to record, nil when the state doesn't change.
+func recheckedFinding(f Finding, detected bool, detail string, at time.Time) (Finding, *FindingEvent) {
+	if detected {
+		f.Detail, f.LastSeen = detail, at
+	}
+	switch {
+	case detected && f.State == FindingResolved:
+		f.State, f.Comment = FindingOpen, "volvió a detectarse al re-verificarlo"
+	case !detected && f.State != FindingResolved:
+		f.State, f.Comment = FindingResolved, "ya no se detecta al re-verificarlo"
+	default:
+		return f, nil
+	}
+	f.UpdatedAt = at
+	return f, &FindingEvent{FindingID: f.ID, At: at, State: f.State, Comment: f.Comment}
+}
@@ -0,0 +1,225 @@
+package store
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Memory is a Store that keeps the assessments in memory, lost when
+// the process exits. It is safe for concurrent use.
+type Memory struct {
+	mu      sync.Mutex
+	lastID  int64
+	entries map[string][]Entry // Evaluaciones de cada dominio, en el orden en que se guardaron
+	certs   map[string][]Cert  // Certificados vistos de cada dominio
+
+	lastFindingID int64
+	findings      []Finding // Hallazgos de todos los dominios, en el orden en que se detectaron
+	events        []FindingEvent
+}
+
+// NewMemory creates an empty Memory store
+func NewMemory() *Memory {
+	return &Memory{entries: make(map[string][]Entry), certs: make(map[string][]Cert)}
+}
+
+// Save records an assessment
+func (s *Memory) Save(a Assessment) error {
+	// Se guarda con la precisión de la base SQLite, para que ambos backends
+	// devuelvan lo mismo
+	scannedAt := a.scannedAt()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastID++
+	// Solo los metadatos que guarda SQLite
+	var m Metadata
+	if a.Metadata != nil {
+		m = *a.Metadata
+	}
+	metadata := Metadata{EngineVersion: m.EngineVersion, CriteriaVersion: m.CriteriaVersion,
+		StartedAt: timeFromMilli(unixMilliOrZero(m.StartedAt)), FinishedAt: timeFromMilli(unixMilliOrZero(m.FinishedAt)),
+		ToolVersion: m.ToolVersion, FromCache: m.FromCache, Publish: m.Publish, Source: m.Source}
+	entry := Entry{ID: s.lastID, Domain: a.Domain, ScannedAt: scannedAt, OverallGrade: a.OverallGrade, Metadata: &metadata}
+	for _, endpoint := range a.Endpoints {
+		endpoint.Protocols = slices.Clone(endpoint.Protocols)
+		endpoint.Vulnerabilities = slices.Clone(endpoint.Vulnerabilities)
+		entry.Endpoints = append(entry.Endpoints, endpoint)
+	}
+	sortEndpoints(entry.Endpoints)
+	s.entries[a.Domain] = append(s.entries[a.Domain], entry)
+
+	// Cada certificado se registra la primera vez que se ve
+	for _, cert := range a.Certs {
+		known := slices.ContainsFunc(s.certs[a.Domain], func(c Cert) bool { return c.Fingerprint == cert.Fingerprint })
+		if !known {
+			cert.FirstSeen = scannedAt
+			s.certs[a.Domain] = append(s.certs[a.Domain], cert)
+		}
+	}
+
+	var known []Finding
+	for _, f := range s.findings {
+		if f.Domain == a.Domain {
+			known = append(known, f)
+		}
+	}
+	for _, change := range reconcileFindings(a, known, scannedAt) {
+		f := change.Finding
+		if f.ID == 0 {
+			s.lastFindingID++
+			f.ID = s.lastFindingID
+			s.findings = append(s.findings, f)
+		} else {
+			s.findings[s.findingIndex(f.ID)] = f
+		}
+		if change.Event != nil {
+			event := *change.Event
+			event.FindingID = f.ID
+			s.events = append(s.events, event)
+		}
+	}
+	return nil
+}
+
+// findingIndex returns the position of the finding id in s.findings, or -1.
+// Must be called with mu held.
+func (s *Memory) findingIndex(id int64) int {
+	return slices.IndexFunc(s.findings, func(f Finding) bool { return f.ID == id })
+}
+
+// Query returns the assessments that match q, newest first
+func (s *Memory) Query(q Query) ([]Entry, error) {
+	s.mu.Lock()
+	var entries []Entry
+	for _, domainEntries := range s.entries {
+		for _, entry := range domainEntries {
+			if q.matches(entry) {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	slices.SortFunc(entries, func(a, b Entry) int {
+		return cmp.Or(b.ScannedAt.Compare(a.ScannedAt), cmp.Compare(b.ID, a.ID))
+	})
+	if q.Limit > 0 && len(entries) > q.Limit {
+		entries = entries[:q.Limit]
+	}
+	return entries, nil
+}
+
+// List returns the latest assessments of domain, newest first
+func (s *Memory) List(domain string, limit int) ([]Entry, error) {
+	return listEntries(s, domain, limit)
+}
+
+// Diff describes what changed from the assessment previousID to currentID
+func (s *Memory) Diff(previousID, currentID int64) (Diff, error) {
+	return diffStored(s, previousID, currentID)
+}
+
+// Certs returns the certificates seen for domain, oldest issued first
+func (s *Memory) Certs(domain string) ([]Cert, error) {
+	s.mu.Lock()
+	certs := slices.Clone(s.certs[domain])
+	s.mu.Unlock()
+
+	slices.SortFunc(certs, func(a, b Cert) int {
+		return cmp.Or(a.NotBefore.Compare(b.NotBefore), cmp.Compare(a.Fingerprint, b.Fingerprint))
+	})
+	return certs, nil
+}
+
+// Findings returns the findings that match filter, most severe first
+func (s *Memory) Findings(filter FindingFilter) ([]Finding, error) {
+	s.mu.Lock()
+	var findings []Finding
+	for _, f := range s.findings {
+		if filter.matches(f) {
+			findings = append(findings, f)
+		}
+	}
+	s.mu.Unlock()
+
+	sortFindings(findings)
+	return findings, nil
+}
+
+// FindingEvents returns the state changes of a finding in the order they
+// were recorded
+func (s *Memory) FindingEvents(id int64) ([]FindingEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var events []FindingEvent
+	for _, event := range s.events {
+		if event.FindingID == id {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// SetFindingState moves the findings ids to state, recording comment. It
+// changes none if one of them doesn't exist.
+func (s *Memory) SetFindingState(ids []int64, state, comment string) error {
+	if err := ValidateFindingState(state); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		if s.findingIndex(id) < 0 {
+			return fmt.Errorf("%w: #%d", ErrFindingNotFound, id)
+		}
+	}
+	now := time.UnixMilli(time.Now().UnixMilli())
+	for _, id := range ids {
+		f := &s.findings[s.findingIndex(id)]
+		f.State, f.Comment, f.UpdatedAt = state, comment, now
+		s.events = append(s.events, FindingEvent{FindingID: id, At: now, State: state, Comment: comment})
+	}
+	return nil
+}
+
+// AssignFindings sets the owner of the findings ids; an empty owner
+// unassigns them. It changes none if one of them doesn't exist.
+func (s *Memory) AssignFindings(ids []int64, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		if s.findingIndex(id) < 0 {
+			return fmt.Errorf("%w: #%d", ErrFindingNotFound, id)
+		}
+	}
+	for _, id := range ids {
+		s.findings[s.findingIndex(id)].Owner = owner
+	}
+	return nil
+}
+
+// RecheckFinding records whether the finding id was still detected when
+// re-checked at at and returns it updated
+func (s *Memory) RecheckFinding(id int64, detected bool, detail string, at time.Time) (Finding, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.findingIndex(id)
+	if i < 0 {
+		return Finding{}, fmt.Errorf("%w: #%d", ErrFindingNotFound, id)
+	}
+	f, event := recheckedFinding(s.findings[i], detected, detail, at)
+	s.findings[i] = f
+	if event != nil {
+		s.events = append(s.events, *event)
+	}
+	return f, nil
+}
+
+// Close does nothing: the assessments are lost with the process
+func (s *Memory) Close() error {
+	return nil
+}
@@ -0,0 +1,206 @@
+package store
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// migrationFiles are the migrations of the history schema, named
+// <versión>_<nombre>.up.sql and <versión>_<nombre>.down.sql
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationName matches the name of a migration file
+var migrationName = regexp.MustCompile(`^([0-9]+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// schemaMigrationsTable records the migrations applied to a history database
+const schemaMigrationsTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version    INTEGER PRIMARY KEY,
+	name       TEXT    NOT NULL,
+	applied_at INTEGER NOT NULL -- Unix, en milisegundos
+);`
+
+// Migration is one change of the history schema, with the SQL that
+// applies it and the one that reverts it
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// migrations are the embedded migrations, oldest first
+var migrations = mustLoadMigrations(migrationFiles)
+
+// Migrations returns the migrations of the schema this build writes,
+// oldest first
+func Migrations() []Migration {
+	return slices.Clone(migrations)
+}
+
+// mustLoadMigrations parses the migrations of fsys. They are part of the
+// binary, so a malformed one is a bug and panics.
+func mustLoadMigrations(fsys fs.FS) []Migration {
+	names, err := fs.Glob(fsys, "migrations/*.sql")
+	if err != nil {
+		panic(err)
+	}
+	byVersion := make(map[int]*Migration)
+	for _, name := range names {
+		match := migrationName.FindStringSubmatch(path.Base(name))
+		if match == nil {
+			panic(fmt.Sprintf("nombre de migración inválido: %s", name))
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			panic(err)
+		}
+		version, _ := strconv.Atoi(match[1])
+		migration := byVersion[version]
+		if migration == nil {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if match[3] == "up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	var loaded []Migration
+	for version := 1; version <= len(byVersion); version++ {
+		migration := byVersion[version]
+		if migration == nil || migration.Up == "" || migration.Down == "" {
+			panic(fmt.Sprintf("falta la migración %d o uno de sus archivos up/down", version))
+		}
+		loaded = append(loaded, *migration)
+	}
+	return loaded
+}
+
+// LatestSchemaVersion is the schema version this build writes
+func LatestSchemaVersion() int {
+	return len(migrations)
+}
+
+// SchemaVersion returns the version of the schema of db, 0 for a database
+// without migrations
+func SchemaVersion(db *sql.DB) (int, error) {
+	if _, err := db.Exec(schemaMigrationsTable); err != nil {
+		return 0, fmt.Errorf("no se pudo leer la versión del historial: %w", err)
+	}
+	var version int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("no se pudo leer la versión del historial: %w", err)
+	}
+	return version, nil
+}
+
+// AppliedMigrations returns when each applied migration of db was applied
+func AppliedMigrations(db *sql.DB) (map[int]time.Time, error) {
+	rows, err := db.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo leer la versión del historial: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt int64
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("no se pudo leer la versión del historial: %w", err)
+		}
+		applied[version] = time.UnixMilli(appliedAt)
+	}
+	return applied, rows.Err()
+}
+
+// Migrate brings the schema of db to target, applying the up migrations
+// after its version or the down ones until it. Each migration runs in its
+// own transaction, so a failure leaves the database at the last one that
+// completed. It returns the migrations it ran.
+func Migrate(db *sql.DB, target int) ([]Migration, error) {
+	if target < 0 || target > LatestSchemaVersion() {
+		return nil, fmt.Errorf("versión de esquema inválida %d: se espera de 0 a %d", target, LatestSchemaVersion())
+	}
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	if current > LatestSchemaVersion() {
+		return nil, fmt.Errorf("%w: la base tiene el esquema %d y esta versión de nebula conoce hasta el %d", ErrNewerSchema, current, LatestSchemaVersion())
+	}
+
+	var ran []Migration
+	for ; current < target; current++ {
+		migration := migrations[current]
+		if err := runMigration(db, migration, migration.Up, true); err != nil {
+			return ran, err
+		}
+		ran = append(ran, migration)
+	}
+	for ; current > target; current-- {
+		migration := migrations[current-1]
+		if err := runMigration(db, migration, migration.Down, false); err != nil {
+			return ran, err
+		}
+		ran = append(ran, migration)
+	}
+	return ran, nil
+}
+
+// ErrNewerSchema means the history was written by a newer nebula: older
+// code could misread it, so it isn't opened
+var ErrNewerSchema = errors.New("el historial es de una versión más nueva de nebula")
+
+// runMigration applies (up) or reverts one migration in a transaction
+func runMigration(db *sql.DB, migration Migration, statement string, up bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("migración %d (%s): %w", migration.Version, migration.Name, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(statement); err != nil {
+		return fmt.Errorf("migración %d (%s): %w", migration.Version, migration.Name, err)
+	}
+	if up {
+		_, err = tx.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+			migration.Version, migration.Name, time.Now().UnixMilli())
+	} else {
+		_, err = tx.Exec(`DELETE FROM schema_migrations WHERE version = ?`, migration.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("migración %d (%s): %w", migration.Version, migration.Name, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("migración %d (%s): %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+// migrateOnOpen brings a history database opened by OpenSQLite to the
+// latest schema, logging the migrations it applies
+func migrateOnOpen(db *sql.DB, dbPath string) error {
+	ran, err := Migrate(db, LatestSchemaVersion())
+	for _, migration := range ran {
+		slog.Info("historial migrado", "path", dbPath, "version", migration.Version, "migration", migration.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("no se pudo migrar el historial %s: %w", dbPath, err)
+	}
+	return nil
+}
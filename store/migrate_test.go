@@ -0,0 +1,54 @@
+package store
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// TestMigrateHistory checks that a database created before the migrations
+// keeps its assessments when migrated, that every migration can be
+// reverted and applied again, and that a newer schema is not opened
+func TestMigrateHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+
+	// Una base de antes de las migraciones: el esquema inicial sin schema_migrations
+	db, err := OpenDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(migrations[0].Up); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO scans (domain, scanned_at, overall_grade) VALUES ('example.com', 1717236900000, 'A')`); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	history, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := history.List("example.com", 10)
+	if err != nil || len(entries) != 1 || entries[0].OverallGrade != "A" {
+		t.Fatalf("List() = %+v, %v; se esperaba la evaluación guardada antes de migrar", entries, err)
+	}
+	if version, err := SchemaVersion(history.db); err != nil || version != LatestSchemaVersion() {
+		t.Fatalf("SchemaVersion() = %d, %v; se esperaba %d", version, err, LatestSchemaVersion())
+	}
+
+	if _, err := Migrate(history.db, 0); err != nil {
+		t.Fatalf("revertir todas las migraciones: %v", err)
+	}
+	if ran, err := Migrate(history.db, LatestSchemaVersion()); err != nil || len(ran) != LatestSchemaVersion() {
+		t.Fatalf("aplicar de nuevo las migraciones: %d aplicadas, %v", len(ran), err)
+	}
+
+	if _, err := history.db.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, 'futura', 0)`, LatestSchemaVersion()+1); err != nil {
+		t.Fatal(err)
+	}
+	history.Close()
+	if _, err := OpenSQLite(path); !errors.Is(err, ErrNewerSchema) {
+		t.Errorf("OpenSQLite con un esquema más nuevo: %v, se esperaba ErrNewerSchema", err)
+	}
+}
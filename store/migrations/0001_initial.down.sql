@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS domain_certs;
+DROP TABLE IF EXISTS scan_metadata;
+DROP TABLE IF EXISTS scan_endpoints;
+DROP TABLE IF EXISTS scans;
@@ -0,0 +1,45 @@
+-- Esquema inicial del historial: evaluaciones, endpoints, metadatos y
+-- certificados vistos. Las bases creadas antes de las migraciones ya lo
+-- tienen, así que todo usa IF NOT EXISTS.
+CREATE TABLE IF NOT EXISTS scans (
+	id            INTEGER PRIMARY KEY AUTOINCREMENT,
+	domain        TEXT    NOT NULL,
+	scanned_at    INTEGER NOT NULL, -- Unix, en milisegundos
+	overall_grade TEXT    NOT NULL
+);
+CREATE INDEX IF NOT EXISTS scans_domain_idx ON scans (domain, scanned_at);
+
+CREATE TABLE IF NOT EXISTS scan_endpoints (
+	scan_id          INTEGER NOT NULL REFERENCES scans (id) ON DELETE CASCADE,
+	ip_address       TEXT    NOT NULL,
+	grade            TEXT    NOT NULL,
+	protocols        TEXT    NOT NULL, -- Separados por coma
+	cert_fingerprint TEXT    NOT NULL, -- SHA-256 del certificado del servidor
+	cert_issuer      TEXT    NOT NULL,
+	cert_not_after   INTEGER NOT NULL, -- Unix, en milisegundos
+	vulnerabilities  TEXT    NOT NULL  -- Separadas por coma
+);
+CREATE INDEX IF NOT EXISTS scan_endpoints_scan_idx ON scan_endpoints (scan_id);
+
+CREATE TABLE IF NOT EXISTS scan_metadata (
+	scan_id          INTEGER PRIMARY KEY REFERENCES scans (id) ON DELETE CASCADE,
+	engine_version   TEXT    NOT NULL,
+	criteria_version TEXT    NOT NULL,
+	started_at       INTEGER NOT NULL, -- Unix, en milisegundos (0 = desconocido)
+	finished_at      INTEGER NOT NULL, -- Unix, en milisegundos (0 = desconocido)
+	tool_version     TEXT    NOT NULL,
+	from_cache       INTEGER NOT NULL,
+	publish          INTEGER NOT NULL,
+	source           TEXT    NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS domain_certs (
+	domain      TEXT    NOT NULL,
+	fingerprint TEXT    NOT NULL, -- SHA-256 del certificado del servidor
+	serial      TEXT    NOT NULL, -- Hexadecimal
+	issuer      TEXT    NOT NULL, -- Organización de la CA emisora
+	not_before  INTEGER NOT NULL, -- Unix, en milisegundos
+	not_after   INTEGER NOT NULL, -- Unix, en milisegundos
+	first_seen  INTEGER NOT NULL, -- Unix, en milisegundos
+	PRIMARY KEY (domain, fingerprint)
+);
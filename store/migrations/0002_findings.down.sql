@@ -0,0 +1,2 @@
+DROP TABLE finding_events;
+DROP TABLE findings;
@@ -0,0 +1,26 @@
+-- Hallazgos: cada condición que deja un endpoint debajo de A+ (ver
+-- explainGrade), seguida entre evaluaciones con su estado
+CREATE TABLE findings (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	domain     TEXT    NOT NULL,
+	ip_address TEXT    NOT NULL,
+	rule       TEXT    NOT NULL, -- ID de la condición (ej: old_protocols)
+	severity   TEXT    NOT NULL, -- critical, high, medium o low
+	detail     TEXT    NOT NULL, -- Descripción de la última evaluación que lo detectó
+	state      TEXT    NOT NULL, -- open, acknowledged o resolved
+	comment    TEXT    NOT NULL, -- Comentario del último cambio de estado
+	first_seen INTEGER NOT NULL, -- Unix, en milisegundos
+	last_seen  INTEGER NOT NULL, -- Unix, en milisegundos
+	updated_at INTEGER NOT NULL, -- Último cambio de estado, Unix en milisegundos
+	UNIQUE (domain, ip_address, rule)
+);
+CREATE INDEX findings_state_idx ON findings (state, domain);
+
+-- Cambios de estado de cada hallazgo
+CREATE TABLE finding_events (
+	finding_id INTEGER NOT NULL REFERENCES findings (id) ON DELETE CASCADE,
+	at         INTEGER NOT NULL, -- Unix, en milisegundos
+	state      TEXT    NOT NULL, -- Estado al que pasó
+	comment    TEXT    NOT NULL
+);
+CREATE INDEX finding_events_finding_idx ON finding_events (finding_id, at);
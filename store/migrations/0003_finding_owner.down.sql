@@ -0,0 +1 @@
+ALTER TABLE findings DROP COLUMN owner;
@@ -0,0 +1,2 @@
+-- Responsable de cada hallazgo (findings assign)
+ALTER TABLE findings ADD COLUMN owner TEXT NOT NULL DEFAULT '';
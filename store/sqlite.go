@@ -0,0 +1,468 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLite is the Store of the assessments in a local SQLite database
+type SQLite struct {
+	db *sql.DB
+}
+
+// OpenSQLite opens (creating it if needed) the history database at path
+// and applies the schema migrations it is missing
+func OpenSQLite(path string) (*SQLite, error) {
+	db, err := OpenDB(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := migrateOnOpen(db, path); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLite{db: db}, nil
+}
+
+// OpenDB opens the SQLite database at path as is, without migrating it,
+// for the tools that manage its schema (see Migrate)
+func OpenDB(path string) (*sql.DB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("no se pudo crear el directorio del historial: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo abrir el historial: %w", err)
+	}
+
+	// SQLite admite un solo escritor; serializar el acceso evita errores SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+// Close closes the database
+func (h *SQLite) Close() error {
+	return h.db.Close()
+}
+
+// Save stores an assessment
+func (h *SQLite) Save(a Assessment) error {
+	scannedAt := a.scannedAt()
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error guardando historial: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`INSERT INTO scans (domain, scanned_at, overall_grade) VALUES (?, ?, ?)`,
+		a.Domain, scannedAt.UnixMilli(), a.OverallGrade)
+	if err != nil {
+		return fmt.Errorf("error guardando historial: %w", err)
+	}
+	scanID, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error guardando historial: %w", err)
+	}
+
+	var metadata Metadata
+	if a.Metadata != nil {
+		metadata = *a.Metadata
+	}
+	_, err = tx.Exec(`INSERT INTO scan_metadata
+		(scan_id, engine_version, criteria_version, started_at, finished_at, tool_version, from_cache, publish, source)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		scanID, metadata.EngineVersion, metadata.CriteriaVersion, unixMilliOrZero(metadata.StartedAt),
+		unixMilliOrZero(metadata.FinishedAt), metadata.ToolVersion, metadata.FromCache, metadata.Publish, metadata.Source)
+	if err != nil {
+		return fmt.Errorf("error guardando historial: %w", err)
+	}
+
+	for _, endpoint := range a.Endpoints {
+		_, err := tx.Exec(`INSERT INTO scan_endpoints
+			(scan_id, ip_address, grade, protocols, cert_fingerprint, cert_issuer, cert_not_after, vulnerabilities)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			scanID, endpoint.IPAddress, endpoint.Grade, strings.Join(endpoint.Protocols, ","),
+			endpoint.CertFingerprint, endpoint.CertIssuer, endpoint.CertNotAfter,
+			strings.Join(endpoint.Vulnerabilities, ","))
+		if err != nil {
+			return fmt.Errorf("error guardando historial: %w", err)
+		}
+	}
+
+	// Cada certificado se registra la primera vez que se ve
+	for _, cert := range a.Certs {
+		_, err := tx.Exec(`INSERT OR IGNORE INTO domain_certs
+			(domain, fingerprint, serial, issuer, not_before, not_after, first_seen)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			a.Domain, cert.Fingerprint, cert.Serial, cert.Issuer, cert.NotBefore.UnixMilli(),
+			cert.NotAfter.UnixMilli(), scannedAt.UnixMilli())
+		if err != nil {
+			return fmt.Errorf("error guardando historial: %w", err)
+		}
+	}
+
+	if err := saveFindings(tx, a, scannedAt); err != nil {
+		return fmt.Errorf("error guardando historial: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error guardando historial: %w", err)
+	}
+	return nil
+}
+
+// Certs returns the certificates seen for a domain, oldest issued first
+func (h *SQLite) Certs(domain string) ([]Cert, error) {
+	rows, err := h.db.Query(`SELECT fingerprint, serial, issuer, not_before, not_after, first_seen
+		FROM domain_certs WHERE domain = ? ORDER BY not_before, fingerprint`, domain)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando historial: %w", err)
+	}
+	defer rows.Close()
+
+	var certs []Cert
+	for rows.Next() {
+		var cert Cert
+		var notBefore, notAfter, firstSeen int64
+		if err := rows.Scan(&cert.Fingerprint, &cert.Serial, &cert.Issuer, &notBefore, &notAfter, &firstSeen); err != nil {
+			return nil, fmt.Errorf("error consultando historial: %w", err)
+		}
+		cert.NotBefore, cert.NotAfter, cert.FirstSeen = time.UnixMilli(notBefore), time.UnixMilli(notAfter), time.UnixMilli(firstSeen)
+		certs = append(certs, cert)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error consultando historial: %w", err)
+	}
+	return certs, nil
+}
+
+// List returns the latest assessments of a domain, newest first
+func (h *SQLite) List(domain string, limit int) ([]Entry, error) {
+	return listEntries(h, domain, limit)
+}
+
+// Diff describes what changed from the assessment previousID to currentID
+func (h *SQLite) Diff(previousID, currentID int64) (Diff, error) {
+	return diffStored(h, previousID, currentID)
+}
+
+// Query returns the assessments that match q, newest first
+func (h *SQLite) Query(q Query) ([]Entry, error) {
+	where, args := q.where()
+	limit := q.Limit
+	if limit <= 0 {
+		limit = -1 // Sin límite en SQLite
+	}
+	rows, err := h.db.Query(`SELECT s.id, s.domain, s.scanned_at, s.overall_grade,
+			m.engine_version, m.criteria_version, m.started_at, m.finished_at, m.tool_version, m.from_cache, m.publish, m.source
+		FROM scans s LEFT JOIN scan_metadata m ON m.scan_id = s.id
+		WHERE `+where+` ORDER BY s.scanned_at DESC, s.id DESC LIMIT ?`, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando historial: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var entry Entry
+		var scannedAt int64
+		var engine, criteria, tool, source sql.NullString
+		var startedAt, finishedAt sql.NullInt64
+		var fromCache, publish sql.NullBool
+		if err := rows.Scan(&entry.ID, &entry.Domain, &scannedAt, &entry.OverallGrade,
+			&engine, &criteria, &startedAt, &finishedAt, &tool, &fromCache, &publish, &source); err != nil {
+			return nil, fmt.Errorf("error consultando historial: %w", err)
+		}
+		entry.ScannedAt = time.UnixMilli(scannedAt)
+		if source.Valid {
+			entry.Metadata = &Metadata{
+				EngineVersion:   engine.String,
+				CriteriaVersion: criteria.String,
+				StartedAt:       timeFromMilli(startedAt.Int64),
+				FinishedAt:      timeFromMilli(finishedAt.Int64),
+				ToolVersion:     tool.String,
+				FromCache:       fromCache.Bool,
+				Publish:         publish.Bool,
+				Source:          source.String,
+			}
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error consultando historial: %w", err)
+	}
+
+	for i := range entries {
+		endpoints, err := h.endpoints(entries[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		entries[i].Endpoints = endpoints
+	}
+
+	return entries, nil
+}
+
+// endpoints returns the stored endpoints of a scan
+func (h *SQLite) endpoints(scanID int64) ([]Endpoint, error) {
+	rows, err := h.db.Query(`SELECT ip_address, grade, protocols, cert_fingerprint, cert_issuer, cert_not_after, vulnerabilities
+		FROM scan_endpoints WHERE scan_id = ? ORDER BY rowid`, scanID)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando historial: %w", err)
+	}
+	defer rows.Close()
+
+	var endpoints []Endpoint
+	for rows.Next() {
+		var endpoint Endpoint
+		var protocols, vulnerabilities string
+		if err := rows.Scan(&endpoint.IPAddress, &endpoint.Grade, &protocols, &endpoint.CertFingerprint,
+			&endpoint.CertIssuer, &endpoint.CertNotAfter, &vulnerabilities); err != nil {
+			return nil, fmt.Errorf("error consultando historial: %w", err)
+		}
+		endpoint.Protocols = splitColumn(protocols)
+		endpoint.Vulnerabilities = splitColumn(vulnerabilities)
+		endpoints = append(endpoints, endpoint)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error consultando historial: %w", err)
+	}
+	sortEndpoints(endpoints)
+	return endpoints, nil
+}
+
+// sqlQuerier is what the findings queries need from a *sql.DB or a *sql.Tx
+type sqlQuerier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// Findings returns the findings that match filter, most severe first
+func (h *SQLite) Findings(filter FindingFilter) ([]Finding, error) {
+	findings, err := queryFindings(h.db, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando historial: %w", err)
+	}
+	sortFindings(findings)
+	return findings, nil
+}
+
+// queryFindings reads the findings that match filter through q
+func queryFindings(q sqlQuerier, filter FindingFilter) ([]Finding, error) {
+	where, args := filter.where()
+	rows, err := q.Query(`SELECT id, domain, ip_address, rule, severity, detail, state, comment, owner, first_seen, last_seen, updated_at
+		FROM findings WHERE `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var findings []Finding
+	for rows.Next() {
+		var f Finding
+		var firstSeen, lastSeen, updatedAt int64
+		if err := rows.Scan(&f.ID, &f.Domain, &f.IPAddress, &f.Rule, &f.Severity, &f.Detail, &f.State, &f.Comment, &f.Owner,
+			&firstSeen, &lastSeen, &updatedAt); err != nil {
+			return nil, err
+		}
+		f.FirstSeen, f.LastSeen, f.UpdatedAt = time.UnixMilli(firstSeen), time.UnixMilli(lastSeen), time.UnixMilli(updatedAt)
+		findings = append(findings, f)
+	}
+	return findings, rows.Err()
+}
+
+// saveFindings updates the findings of the domain of a with what the
+// assessment made at scannedAt detected
+func saveFindings(tx *sql.Tx, a Assessment, scannedAt time.Time) error {
+	known, err := queryFindings(tx, FindingFilter{Domain: a.Domain})
+	if err != nil {
+		return err
+	}
+	for _, change := range reconcileFindings(a, known, scannedAt) {
+		f := change.Finding
+		if f.ID == 0 {
+			res, err := tx.Exec(`INSERT INTO findings
+				(domain, ip_address, rule, severity, detail, state, comment, first_seen, last_seen, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				f.Domain, f.IPAddress, f.Rule, f.Severity, f.Detail, f.State, f.Comment,
+				f.FirstSeen.UnixMilli(), f.LastSeen.UnixMilli(), f.UpdatedAt.UnixMilli())
+			if err != nil {
+				return err
+			}
+			if f.ID, err = res.LastInsertId(); err != nil {
+				return err
+			}
+		} else {
+			_, err := tx.Exec(`UPDATE findings SET severity = ?, detail = ?, state = ?, comment = ?, last_seen = ?, updated_at = ?
+				WHERE id = ?`, f.Severity, f.Detail, f.State, f.Comment, f.LastSeen.UnixMilli(), f.UpdatedAt.UnixMilli(), f.ID)
+			if err != nil {
+				return err
+			}
+		}
+		if change.Event != nil {
+			if err := insertFindingEvent(tx, f.ID, *change.Event); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// insertFindingEvent records a state change of the finding id
+func insertFindingEvent(tx *sql.Tx, id int64, event FindingEvent) error {
+	_, err := tx.Exec(`INSERT INTO finding_events (finding_id, at, state, comment) VALUES (?, ?, ?, ?)`,
+		id, event.At.UnixMilli(), event.State, event.Comment)
+	return err
+}
+
+// FindingEvents returns the state changes of a finding in the order they
+// were recorded
+func (h *SQLite) FindingEvents(id int64) ([]FindingEvent, error) {
+	rows, err := h.db.Query(`SELECT at, state, comment FROM finding_events WHERE finding_id = ? ORDER BY rowid`, id)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando historial: %w", err)
+	}
+	defer rows.Close()
+
+	var events []FindingEvent
+	for rows.Next() {
+		event := FindingEvent{FindingID: id}
+		var at int64
+		if err := rows.Scan(&at, &event.State, &event.Comment); err != nil {
+			return nil, fmt.Errorf("error consultando historial: %w", err)
+		}
+		event.At = time.UnixMilli(at)
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error consultando historial: %w", err)
+	}
+	return events, nil
+}
+
+// SetFindingState moves the findings ids to state, recording comment. It
+// changes none if one of them doesn't exist.
+func (h *SQLite) SetFindingState(ids []int64, state, comment string) error {
+	if err := ValidateFindingState(state); err != nil {
+		return err
+	}
+	tx, err := h.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error guardando historial: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.UnixMilli(time.Now().UnixMilli())
+	for _, id := range ids {
+		res, err := tx.Exec(`UPDATE findings SET state = ?, comment = ?, updated_at = ? WHERE id = ?`, state, comment, now.UnixMilli(), id)
+		if err != nil {
+			return fmt.Errorf("error guardando historial: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("error guardando historial: %w", err)
+		}
+		if n == 0 {
+			return fmt.Errorf("%w: #%d", ErrFindingNotFound, id)
+		}
+		if err := insertFindingEvent(tx, id, FindingEvent{At: now, State: state, Comment: comment}); err != nil {
+			return fmt.Errorf("error guardando historial: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error guardando historial: %w", err)
+	}
+	return nil
+}
+
+// AssignFindings sets the owner of the findings ids; an empty owner
+// unassigns them. It changes none if one of them doesn't exist.
+func (h *SQLite) AssignFindings(ids []int64, owner string) error {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error guardando historial: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, id := range ids {
+		res, err := tx.Exec(`UPDATE findings SET owner = ? WHERE id = ?`, owner, id)
+		if err != nil {
+			return fmt.Errorf("error guardando historial: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("error guardando historial: %w", err)
+		}
+		if n == 0 {
+			return fmt.Errorf("%w: #%d", ErrFindingNotFound, id)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error guardando historial: %w", err)
+	}
+	return nil
+}
+
+// RecheckFinding records whether the finding id was still detected when
+// re-checked at at and returns it updated
+func (h *SQLite) RecheckFinding(id int64, detected bool, detail string, at time.Time) (Finding, error) {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return Finding{}, fmt.Errorf("error guardando historial: %w", err)
+	}
+	defer tx.Rollback()
+
+	findings, err := queryFindings(tx, FindingFilter{ID: id})
+	if err != nil {
+		return Finding{}, fmt.Errorf("error consultando historial: %w", err)
+	}
+	if len(findings) == 0 {
+		return Finding{}, fmt.Errorf("%w: #%d", ErrFindingNotFound, id)
+	}
+	f, event := recheckedFinding(findings[0], detected, detail, at)
+	_, err = tx.Exec(`UPDATE findings SET detail = ?, state = ?, comment = ?, last_seen = ?, updated_at = ? WHERE id = ?`,
+		f.Detail, f.State, f.Comment, f.LastSeen.UnixMilli(), f.UpdatedAt.UnixMilli(), id)
+	if err != nil {
+		return Finding{}, fmt.Errorf("error guardando historial: %w", err)
+	}
+	if event != nil {
+		if err := insertFindingEvent(tx, id, *event); err != nil {
+			return Finding{}, fmt.Errorf("error guardando historial: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return Finding{}, fmt.Errorf("error guardando historial: %w", err)
+	}
+	return f, nil
+}
+
+// unixMilliOrZero returns t in Unix milliseconds, or 0 for the zero time
+func unixMilliOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+// timeFromMilli is the inverse of unixMilliOrZero
+func timeFromMilli(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
+// splitColumn splits a comma-separated column, returning nil for empty values
+func splitColumn(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
@@ -0,0 +1,287 @@
+// Package store is the history of nebula: the assessments of each domain,
+// the certificates seen for it and the findings they raise. The nebula
+// CLI keeps it in the SQLite database of --history-db, and other Go
+// programs can import this package to query the same database without the
+// CLI or its HTTP API.
+package store
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Prefijos del DSN de Open que eligen el backend; sin prefijo es la ruta
+// de una base SQLite
+const (
+	PrefixSQLite = "sqlite:"
+	PrefixMemory = "memory:"
+)
+
+// Store records the assessments of each domain, the certificates seen for
+// it and the findings they raise. SQLite, the database, is the only
+// persistent implementation, and Memory keeps everything in memory for
+// tests or runs that shouldn't leave files behind. Save records, Query and
+// Findings read across domains and Diff compares two assessments.
+// Implementations are safe for concurrent use.
+type Store interface {
+	// Save records an assessment
+	Save(a Assessment) error
+	// Query returns the assessments that match q, newest first
+	Query(q Query) ([]Entry, error)
+	// List returns the latest assessments of domain, newest first; a
+	// negative limit returns them all
+	List(domain string, limit int) ([]Entry, error)
+	// Diff describes what changed from the assessment previousID to
+	// currentID, failing with ErrEntryNotFound if one doesn't exist
+	Diff(previousID, currentID int64) (Diff, error)
+	// Certs returns the certificates seen for domain, oldest issued first
+	Certs(domain string) ([]Cert, error)
+	// Findings returns the findings that match filter, most severe first
+	Findings(filter FindingFilter) ([]Finding, error)
+	// FindingEvents returns the state changes of a finding in the order
+	// they were recorded (an assessment is dated when it ran, which can be
+	// before a change made by hand)
+	FindingEvents(id int64) ([]FindingEvent, error)
+	// SetFindingState moves the findings ids to state, recording comment.
+	// It changes none, failing with ErrFindingNotFound, if one of them
+	// doesn't exist.
+	SetFindingState(ids []int64, state, comment string) error
+	// AssignFindings sets the owner of the findings ids, or unassigns them
+	// with an empty owner. Like SetFindingState, it changes all or none.
+	AssignFindings(ids []int64, owner string) error
+	// RecheckFinding records whether the finding id was still detected when
+	// re-checked at at and returns it updated: a detected finding is seen
+	// again (and reopened if it was resolved), one no longer detected is
+	// resolved
+	RecheckFinding(id int64, detected bool, detail string, at time.Time) (Finding, error)
+	// Close releases the store
+	Close() error
+}
+
+// Entry is one stored assessment of a domain
+type Entry struct {
+	ID           int64
+	Domain       string
+	ScannedAt    time.Time
+	OverallGrade string
+	Endpoints    []Endpoint // Ordenados por IP (ver CompareIPs)
+	Metadata     *Metadata  // nil en evaluaciones guardadas antes de registrar metadatos
+}
+
+// Endpoint is the stored summary of one endpoint of an assessment
+type Endpoint struct {
+	IPAddress       string
+	Grade           string
+	Protocols       []string
+	CertFingerprint string
+	CertIssuer      string
+	CertNotAfter    int64 // Timestamp en milisegundos
+	Vulnerabilities []string
+}
+
+// Metadata records the provenance of an assessment: which engine and
+// grading criteria produced it, when, with which parameters and from
+// which source. TrustStore and Fallback describe a run and are not stored.
+type Metadata struct {
+	EngineVersion   string    `json:"engineVersion"`
+	CriteriaVersion string    `json:"criteriaVersion"`
+	StartedAt       time.Time `json:"startedAt"`
+	FinishedAt      time.Time `json:"finishedAt"`
+	ToolVersion     string    `json:"toolVersion"`
+	FromCache       bool      `json:"fromCache"`            // Se pidió fromCache=on
+	Publish         bool      `json:"publish"`              // Se pidió publish=on
+	Source          string    `json:"source"`               // ssllabs, local o replay
+	TrustStore      string    `json:"trustStore,omitempty"` // Almacén de confianza de las evaluaciones locales
+	Fallback        string    `json:"fallback,omitempty"`   // Con --local, por qué no se usó la API (rate_limited, network...)
+}
+
+// Cert is a server certificate seen for a domain, stored to follow its
+// issuance over time
+type Cert struct {
+	Fingerprint string
+	Serial      string // Hexadecimal
+	Issuer      string // Organización de la CA emisora
+	NotBefore   time.Time
+	NotAfter    time.Time
+	FirstSeen   time.Time // Cero si aún no está en el historial
+}
+
+// Assessment is what Save records: the assessment of a domain, the
+// certificates it served and what it detected on each endpoint
+type Assessment struct {
+	Entry                   // El ID se ignora; sin ScannedAt se usa el momento de Save
+	Certs  []Cert           // Certificados servidos, sin repetir
+	Judged []JudgedEndpoint // Endpoints evaluados con detalles, los únicos que cambian hallazgos
+}
+
+// JudgedEndpoint is what an assessment detected on one endpoint. Its
+// findings not detected again are resolved, except those of rules the
+// assessment didn't check.
+type JudgedEndpoint struct {
+	IPAddress string
+	Rules     []string // Reglas que comprobó la evaluación; nil = todas
+	Detected  []Detection
+}
+
+// Detection is a condition detected on an endpoint, which opens or keeps
+// open its finding
+type Detection struct {
+	Rule   string // ID de la condición (ej: "old_protocols")
+	Detail string
+}
+
+// Query selects assessments; empty fields match everything
+type Query struct {
+	ID      int64
+	Domain  string
+	Domains []string  // nil no filtra
+	Since   time.Time // Evaluadas en este momento o después
+	Until   time.Time // Evaluadas antes de este momento
+	Limit   int       // Cantidad máxima, las más nuevas; 0 no limita
+}
+
+// matches reports whether entry passes the query, ignoring Limit
+func (q Query) matches(entry Entry) bool {
+	return (q.ID == 0 || entry.ID == q.ID) &&
+		(q.Domain == "" || entry.Domain == q.Domain) &&
+		(q.Domains == nil || slices.Contains(q.Domains, entry.Domain)) &&
+		(q.Since.IsZero() || !entry.ScannedAt.Before(q.Since)) &&
+		(q.Until.IsZero() || entry.ScannedAt.Before(q.Until))
+}
+
+// where returns the SQL condition of the query over the scans table (s),
+// with its arguments
+func (q Query) where() (string, []any) {
+	conditions := []string{"1 = 1"}
+	var args []any
+	if q.ID != 0 {
+		conditions = append(conditions, "s.id = ?")
+		args = append(args, q.ID)
+	}
+	if q.Domain != "" {
+		conditions = append(conditions, "s.domain = ?")
+		args = append(args, q.Domain)
+	}
+	if q.Domains != nil {
+		conditions = append(conditions, "s.domain IN ("+strings.TrimSuffix(strings.Repeat("?, ", len(q.Domains)), ", ")+")")
+		for _, domain := range q.Domains {
+			args = append(args, domain)
+		}
+	}
+	if !q.Since.IsZero() {
+		conditions = append(conditions, "s.scanned_at >= ?")
+		args = append(args, q.Since.UnixMilli())
+	}
+	if !q.Until.IsZero() {
+		conditions = append(conditions, "s.scanned_at < ?")
+		args = append(args, q.Until.UnixMilli())
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// listEntries implements List with Query, where a limit of 0 doesn't limit
+func listEntries(s Store, domain string, limit int) ([]Entry, error) {
+	switch {
+	case limit == 0:
+		return nil, nil
+	case limit < 0:
+		limit = 0
+	}
+	return s.Query(Query{Domain: domain, Limit: limit})
+}
+
+// ErrEntryNotFound is returned for an assessment ID that isn't in the store
+var ErrEntryNotFound = errors.New("evaluación desconocida")
+
+// diffStored compares the assessments previousID and currentID of s
+func diffStored(s Store, previousID, currentID int64) (Diff, error) {
+	var entries [2]Entry
+	for i, id := range []int64{previousID, currentID} {
+		found, err := s.Query(Query{ID: id})
+		if err != nil {
+			return Diff{}, err
+		}
+		if len(found) == 0 {
+			return Diff{}, fmt.Errorf("%w: #%d", ErrEntryNotFound, id)
+		}
+		entries[i] = found[0]
+	}
+	return Compare(entries[0], entries[1]), nil
+}
+
+// Open opens the store of dsn: a path to a SQLite database, optionally
+// prefixed with "sqlite:", or "memory:" for a Memory store. SQLite is the
+// only persistent backend; any other scheme is rejected.
+func Open(dsn string) (Store, error) {
+	if dsn == PrefixMemory {
+		return NewMemory(), nil
+	}
+	path, err := SQLitePath(dsn)
+	if err != nil {
+		return nil, err
+	}
+	db, err := OpenSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// SQLitePath returns the path of the SQLite database of dsn, or an error
+// when dsn selects another backend
+func SQLitePath(dsn string) (string, error) {
+	switch {
+	case dsn == PrefixMemory:
+		return "", fmt.Errorf("el historial %s no es una base SQLite", dsn)
+	case strings.HasPrefix(dsn, PrefixSQLite):
+		dsn = strings.TrimPrefix(dsn, PrefixSQLite)
+	case strings.Contains(dsn, "://"):
+		scheme, _, _ := strings.Cut(dsn, "://")
+		return "", fmt.Errorf("backend de historial no soportado %q: se espera una ruta de SQLite, sqlite:ruta o memory:", scheme)
+	}
+	if dsn == "" {
+		return "", fmt.Errorf("falta la ruta de la base SQLite del historial")
+	}
+	return dsn, nil
+}
+
+// CompareIPs orders IP addresses numerically, IPv4 before IPv6. Values
+// that don't parse as addresses go last, in lexical order. It is the
+// order of Entry.Endpoints.
+func CompareIPs(a, b string) int {
+	addrA, errA := netip.ParseAddr(a)
+	addrB, errB := netip.ParseAddr(b)
+	switch {
+	case errA == nil && errB == nil:
+		return addrA.Compare(addrB)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	default:
+		return cmp.Compare(a, b)
+	}
+}
+
+// sortEndpoints orders stored endpoints by IP address. Scans saved before
+// results were sorted keep the order of the API response.
+func sortEndpoints(endpoints []Endpoint) {
+	slices.SortStableFunc(endpoints, func(a, b Endpoint) int {
+		return CompareIPs(a.IPAddress, b.IPAddress)
+	})
+}
+
+// scannedAt returns when a was made, or now if it doesn't say, with the
+// millisecond precision of the SQLite database
+func (a Assessment) scannedAt() time.Time {
+	at := a.ScannedAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+	return time.UnixMilli(at.UnixMilli())
+}
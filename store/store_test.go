@@ -0,0 +1,99 @@
+package store
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestStores saves the same assessments in every backend and checks that
+// they all return them the same way
+func TestStores(t *testing.T) {
+	started := time.Date(2024, 6, 1, 10, 15, 0, 123456789, time.UTC)
+	assessments := []Assessment{
+		{Entry: Entry{Domain: "example.com", ScannedAt: started, OverallGrade: "B", Endpoints: []Endpoint{
+			{IPAddress: "2001:db8::1", Grade: "B", Protocols: []string{"TLS 1.1", "TLS 1.2"}, CertFingerprint: "ab12", CertIssuer: "Example CA", CertNotAfter: 1735689600000},
+			{IPAddress: "192.0.2.1", Grade: "B", Protocols: []string{"TLS 1.2"}, Vulnerabilities: []string{"beast"}},
+		}, Metadata: &Metadata{EngineVersion: "2.3.0", StartedAt: started, Source: "ssllabs", Fallback: "network"}}},
+		{Entry: Entry{Domain: "example.com", ScannedAt: started.Add(time.Hour), OverallGrade: "A", Endpoints: []Endpoint{
+			{IPAddress: "192.0.2.1", Grade: "A", Protocols: []string{"TLS 1.2", "TLS 1.3"}},
+		}, Metadata: &Metadata{Source: "local", FromCache: true}}},
+		{Entry: Entry{Domain: "other.example", ScannedAt: started, OverallGrade: "A+"}},
+	}
+
+	stores := map[string]string{
+		"sqlite": PrefixSQLite + filepath.Join(t.TempDir(), "history.db"),
+		"memory": PrefixMemory,
+	}
+	got := make(map[string][]Entry)
+	for name, dsn := range stores {
+		s, err := Open(dsn)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		defer s.Close()
+		for _, a := range assessments {
+			if err := s.Save(a); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+		}
+
+		entries, err := s.List("example.com", 10)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(entries) != 2 || entries[0].OverallGrade != "A" || entries[1].OverallGrade != "B" {
+			t.Fatalf("%s: List() = %+v, se esperaban las 2 evaluaciones de la más nueva a la más vieja", name, entries)
+		}
+		if ips := []string{entries[1].Endpoints[0].IPAddress, entries[1].Endpoints[1].IPAddress}; ips[0] != "192.0.2.1" {
+			t.Errorf("%s: endpoints en el orden %v, se esperaba IPv4 primero", name, ips)
+		}
+		if limited, _ := s.List("example.com", 1); len(limited) != 1 {
+			t.Errorf("%s: List con límite 1 devolvió %d evaluaciones", name, len(limited))
+		}
+		if none, _ := s.List("missing.example", 10); len(none) != 0 {
+			t.Errorf("%s: List de un dominio sin evaluaciones devolvió %d", name, len(none))
+		}
+		if all, err := s.Query(Query{}); err != nil || len(all) != 3 {
+			t.Errorf("%s: Query sin filtros devolvió %d evaluaciones, %v", name, len(all), err)
+		}
+		if since, _ := s.Query(Query{Domains: []string{"example.com", "other.example"}, Since: started.Add(time.Minute)}); len(since) != 1 || since[0].OverallGrade != "A" {
+			t.Errorf("%s: Query desde una fecha = %+v, se esperaba solo la segunda de example.com", name, since)
+		}
+		if until, _ := s.Query(Query{Until: started.Add(time.Minute), Limit: 1}); len(until) != 1 {
+			t.Errorf("%s: Query hasta una fecha con límite 1 devolvió %d evaluaciones", name, len(until))
+		}
+
+		diff, err := s.Diff(entries[1].ID, entries[0].ID)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if diff.Previous.OverallGrade != "B" || diff.Current.OverallGrade != "A" || len(diff.Changes) == 0 {
+			t.Errorf("%s: Diff() = %+v, se esperaban los cambios de B a A", name, diff)
+		}
+		if _, err := s.Diff(entries[1].ID, 9999); !errors.Is(err, ErrEntryNotFound) {
+			t.Errorf("%s: Diff con un ID desconocido: %v, se esperaba ErrEntryNotFound", name, err)
+		}
+		// Los IDs dependen del backend
+		for i := range entries {
+			entries[i].ID = 0
+		}
+		got[name] = entries
+	}
+	if !reflect.DeepEqual(got["sqlite"], got["memory"]) {
+		t.Errorf("los backends no coinciden:\nsqlite: %+v\nmemory: %+v", got["sqlite"], got["memory"])
+	}
+}
+
+// TestOpenRejectsUnknownBackends checks that a DSN of a backend that
+// isn't built in fails instead of being taken as a file name
+func TestOpenRejectsUnknownBackends(t *testing.T) {
+	for _, dsn := range []string{"postgres://db.example/nebula", "sqlite:"} {
+		if s, err := Open(dsn); err == nil {
+			s.Close()
+			t.Errorf("Open(%q) no falló", dsn)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// decodeHost decodes an /analyze response as it is read. The endpoints and
+// host-level certificates, which make up almost all of a large response,
+// are decoded one at a time, so neither the raw body nor more than one
+// endpoint's JSON is held in memory at once.
+func decodeHost(r io.Reader) (*Host, error) {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+
+	var host Host
+	// Campos pequeños del host, se decodifican juntos al final
+	fields := make(map[string]json.RawMessage)
+
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return nil, fmt.Errorf(tr("clave inesperada %v"), token)
+		}
+
+		// encoding/json compara los nombres de campo sin distinguir mayúsculas
+		switch strings.ToLower(key) {
+		case "endpoints":
+			err = decodeArray(dec, func() error {
+				var endpoint Endpoint
+				if err := dec.Decode(&endpoint); err != nil {
+					return err
+				}
+				host.Endpoints = append(host.Endpoints, endpoint)
+				return nil
+			})
+		case "certs":
+			err = decodeArray(dec, func() error {
+				var cert Cert
+				if err := dec.Decode(&cert); err != nil {
+					return err
+				}
+				host.Certs = append(host.Certs, cert)
+				return nil
+			})
+		default:
+			var raw json.RawMessage
+			err = dec.Decode(&raw)
+			fields[key] = raw
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := expectDelim(dec, '}'); err != nil {
+		return nil, err
+	}
+
+	rest, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(rest, &host); err != nil {
+		return nil, err
+	}
+	return &host, nil
+}
+
+// decodeArray calls decodeElement for each element of the JSON array at
+// the decoder's position. A null value is treated as an empty array.
+func decodeArray(dec *json.Decoder, decodeElement func() error) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return nil
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf(tr("se esperaba un arreglo y se encontró %v"), token)
+	}
+
+	for dec.More() {
+		if err := decodeElement(); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, ']')
+}
+
+// expectDelim reads the next token and checks that it is the given delimiter
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != want {
+		return fmt.Errorf(tr("se esperaba %q y se encontró %v"), want, token)
+	}
+	return nil
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	discoverDefaultMax  = 50              // Hosts que se evalúan como máximo por dominio
+	discoverResolveTime = 5 * time.Second // Espera máxima por cada resolución
+	discoverResolvers   = 8               // Resoluciones en paralelo
+)
+
+// discoverFlags holds the flags of the subdomain discovery
+type discoverFlags struct {
+	enabled *bool
+	max     *int
+}
+
+// addDiscoverFlags registers the subdomain discovery flags on fs
+func addDiscoverFlags(fs *flag.FlagSet) *discoverFlags {
+	return &discoverFlags{
+		enabled: fs.Bool("discover-subdomains", false, "descubrir los subdominios de cada dominio en los logs de Certificate Transparency (crt.sh) y evaluarlos todos, con un informe de la flota al final"),
+		max:     fs.Int("discover-max", discoverDefaultMax, "con --discover-subdomains, evaluar como máximo N hosts por dominio"),
+	}
+}
+
+// discoverer returns the subdomain discoverer when --discover-subdomains
+// was given, or nil
+func (f *discoverFlags) discoverer() (*subdomainDiscoverer, error) {
+	if *f.max <= 0 {
+		return nil, fmt.Errorf("--discover-max debe ser mayor que 0")
+	}
+	if !*f.enabled {
+		return nil, nil
+	}
+	return &subdomainDiscoverer{
+		client: &http.Client{Timeout: ctTimeout},
+		url:    ctSearchURL,
+		max:    *f.max,
+		lookup: net.DefaultResolver.LookupHost,
+	}, nil
+}
+
+// subdomainDiscoverer turns apex domains into the hosts named by their
+// certificates in the CT logs
+type subdomainDiscoverer struct {
+	client *http.Client
+	url    string
+	max    int
+	lookup func(ctx context.Context, host string) ([]string, error)
+}
+
+// Discovery is the outcome of the discovery of one apex domain
+type Discovery struct {
+	Apex       string
+	Hosts      []string // A evaluar: el dominio primero y luego sus subdominios
+	Unresolved []string // Nombrados en los certificados pero sin registros DNS
+	Wildcards  int      // Nombres comodín (*.example.com), que no se pueden evaluar
+	Skipped    int      // Hosts que superaron --discover-max
+}
+
+// discover searches the CT logs for the subdomains of apex and keeps the
+// ones that still resolve, up to the maximum. The apex itself is always
+// assessed, even when the logs have nothing.
+func (d *subdomainDiscoverer) discover(ctx context.Context, apex string) (*Discovery, error) {
+	certs, err := searchCTLogs(ctx, d.client, d.url, "%."+apex, time.Time{})
+	if err != nil {
+		return nil, fmt.Errorf(tr("no se pudieron buscar los subdominios de %s en crt.sh: %w"), apex, err)
+	}
+
+	discovery := &Discovery{Apex: apex}
+	seen := map[string]bool{apex: true}
+	var candidates []string
+	for _, cert := range certs {
+		for _, name := range cert.Names {
+			name = strings.TrimSuffix(strings.ToLower(name), ".")
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			switch {
+			case strings.HasPrefix(name, "*."):
+				discovery.Wildcards++
+			case strings.HasSuffix(name, "."+apex) && isHostname(name):
+				candidates = append(candidates, name)
+			}
+		}
+	}
+	slices.Sort(candidates)
+
+	resolved := d.resolve(ctx, candidates)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	discovery.Hosts = []string{apex}
+	for _, host := range candidates {
+		switch {
+		case !resolved[host]:
+			discovery.Unresolved = append(discovery.Unresolved, host)
+		case len(discovery.Hosts) < d.max:
+			discovery.Hosts = append(discovery.Hosts, host)
+		default:
+			discovery.Skipped++
+		}
+	}
+	return discovery, nil
+}
+
+// resolve returns which of hosts have DNS records. Certificates outlive
+// the hosts they were issued for, and SSL Labs would fail on each of them.
+func (d *subdomainDiscoverer) resolve(ctx context.Context, hosts []string) map[string]bool {
+	resolved := make(map[string]bool, len(hosts))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, discoverResolvers)
+	for _, host := range hosts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			lookupCtx, cancel := context.WithTimeout(ctx, discoverResolveTime)
+			defer cancel()
+			addrs, err := d.lookup(lookupCtx, host)
+			mu.Lock()
+			resolved[host] = err == nil && len(addrs) > 0
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return resolved
+}
+
+// isHostname reports whether name only has the characters of a DNS host
+// name. crt.sh also lists the email addresses and IPs of the certificates.
+func isHostname(name string) bool {
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '.' {
+			return false
+		}
+	}
+	return strings.Contains(name, ".") && !strings.Contains(name, "..")
+}
+
+// expand discovers the hosts of each apex domain and returns them all, in
+// order and without repetitions, printing to w what was found
+func (d *subdomainDiscoverer) expand(ctx context.Context, w io.Writer, apexes []string) ([]string, error) {
+	var hosts []string
+	seen := make(map[string]bool)
+	for _, apex := range apexes {
+		discovery, err := d.discover(ctx, strings.ToLower(apex))
+		if err != nil {
+			return nil, err
+		}
+		discovery.print(w)
+		for _, host := range discovery.Hosts {
+			if !seen[host] {
+				seen[host] = true
+				hosts = append(hosts, host)
+			}
+		}
+	}
+	return hosts, nil
+}
+
+// print summarizes the discovery: how many hosts are assessed and why the
+// other names are left out
+func (d *Discovery) print(w io.Writer) {
+	fmt.Fprintf(w, tr("%s: %d hosts a evaluar según los logs de Certificate Transparency\n"), d.Apex, len(d.Hosts))
+	if len(d.Unresolved) > 0 {
+		fmt.Fprintf(w, tr("  %d sin registros DNS, descartados: %s\n"), len(d.Unresolved), strings.Join(d.Unresolved, ", "))
+	}
+	if d.Wildcards > 0 {
+		fmt.Fprintf(w, tr("  %d nombres comodín, no evaluables: sus hosts no figuran en los logs\n"), d.Wildcards)
+	}
+	if d.Skipped > 0 {
+		fmt.Fprintf(w, "  %s\n", paint(colorYellow, fmt.Sprintf(tr("⚠️  %d hosts más no se evalúan por --discover-max %d"), d.Skipped, len(d.Hosts))))
+	}
+}
+
+// FleetHost is the outcome of one host of the fleet
+type FleetHost struct {
+	Host       string
+	Grade      string
+	Vulnerable bool
+	Expiry     expiryStatus
+	Error      string
+}
+
+// FleetReport gathers the assessments of the discovered hosts into a
+// single report, printed after all of them
+type FleetReport struct {
+	Apexes []string
+	Hosts  []FleetHost
+	expiry ExpiryThresholds
+}
+
+// newFleetReport starts the report of the hosts discovered from apexes
+func newFleetReport(apexes []string, expiry ExpiryThresholds) *FleetReport {
+	return &FleetReport{Apexes: apexes, expiry: expiry}
+}
+
+// Add records an assessed host
+func (r *FleetReport) Add(result *AssessmentResult) {
+	host := FleetHost{
+		Host:       result.Domain,
+		Grade:      result.OverallGrade,
+		Vulnerable: result.HasVulnerabilities(),
+		Expiry:     result.WorstExpiryStatus(r.expiry),
+	}
+	if result.HasEndpointErrors() {
+		host.Error = tr("endpoints sin evaluar")
+	}
+	r.Hosts = append(r.Hosts, host)
+}
+
+// AddError records a host whose assessment failed
+func (r *FleetReport) AddError(domain string, err error) {
+	r.Hosts = append(r.Hosts, FleetHost{Host: domain, Error: err.Error()})
+}
+
+// Print writes the grade distribution of the fleet and one line per host,
+// the worst first
+func (r *FleetReport) Print(w io.Writer) {
+	hosts := slices.Clone(r.Hosts)
+	slices.SortStableFunc(hosts, func(a, b FleetHost) int {
+		// Sin grade (fallidos) primero, luego de peor a mejor
+		_, okA := gradeOrder[a.Grade]
+		_, okB := gradeOrder[b.Grade]
+		switch {
+		case okA != okB && !okA:
+			return -1
+		case okA != okB:
+			return 1
+		case okA:
+			return compareGrades(a.Grade, b.Grade)
+		}
+		return 0
+	})
+
+	counts := make(map[string]int)
+	var grades []string
+	for _, host := range hosts {
+		grade := host.Grade
+		if _, ok := gradeOrder[grade]; !ok {
+			grade = tr("sin grade")
+		}
+		if counts[grade] == 0 {
+			grades = append(grades, grade)
+		}
+		counts[grade]++
+	}
+	distribution := make([]string, len(grades))
+	for i, grade := range grades {
+		distribution[i] = fmt.Sprintf("%s: %d", grade, counts[grade])
+	}
+
+	fmt.Fprintf(w, tr("=== Flota de %s: %d hosts (%s) ===\n"), strings.Join(r.Apexes, ", "), len(hosts), strings.Join(distribution, ", "))
+	for _, host := range hosts {
+		var notes []string
+		if host.Error != "" {
+			notes = append(notes, paint(colorRed, host.Error))
+		}
+		if host.Vulnerable {
+			notes = append(notes, paint(colorRed, "vulnerable"))
+		}
+		switch host.Expiry {
+		case expiryCritical:
+			notes = append(notes, paint(colorRed, tr("certificado expirado o por expirar")))
+		case expiryWarning:
+			notes = append(notes, paint(colorYellow, tr("certificado por expirar")))
+		}
+		grade := host.Grade
+		if grade == "" {
+			grade = "-"
+		}
+		line := fmt.Sprintf("  %s %s", paint(gradeColor(grade), fmt.Sprintf("%-3s", grade)), host.Host)
+		if len(notes) > 0 {
+			line += "  " + strings.Join(notes, ", ")
+		}
+		fmt.Fprintln(w, line)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	_ "time/tzdata" // Zonas IANA también en contenedores sin /usr/share/zoneinfo
+)
+
+// outputLocation is the time zone used to render timestamps (--tz). It is
+// set once at startup, before any output.
+var outputLocation = time.Local
+
+// addTimezoneFlag registers the --tz flag on fs
+func addTimezoneFlag(fs *flag.FlagSet) *string {
+	return fs.String("tz", os.Getenv("NEBULA_TZ"), "zona horaria de las fechas: local (por defecto), UTC o un nombre IANA como America/Argentina/Buenos_Aires (también NEBULA_TZ)")
+}
+
+// setOutputTimezone parses the --tz value and makes it the output time zone
+func setOutputTimezone(name string) error {
+	location, err := loadTimezone(name)
+	if err != nil {
+		return err
+	}
+	outputLocation = location
+	return nil
+}
+
+// loadTimezone parses a --tz value: local, UTC or an IANA name
+func loadTimezone(name string) (*time.Location, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "local":
+		return time.Local, nil
+	case "utc":
+		return time.UTC, nil
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("zona horaria inválida %q: %w", name, err)
+	}
+	return location, nil
+}
+
+// formatDate renders the date of t in the output time zone
+func formatDate(t time.Time) string {
+	return t.In(outputLocation).Format("2006-01-02")
+}
+
+// formatDateTime renders t in the output time zone, naming the zone
+func formatDateTime(t time.Time) string {
+	return t.In(outputLocation).Format("2006-01-02 15:04 MST")
+}
+
+// zoneName returns the abbreviation of the output time zone at t, to
+// label dates rendered without a time
+func zoneName(t time.Time) string {
+	return t.In(outputLocation).Format("MST")
+}
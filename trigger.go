@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	triggerDefaultMaxPerHour = 10        // Reevaluaciones por hora de cada fuente
+	triggerWindow            = time.Hour // Ventana de --trigger-max-per-hour
+	triggerMaxBody           = 1 << 16
+	triggerMaxSkew           = 5 * time.Minute // Antigüedad máxima de un webhook, y adelanto máximo de su reloj
+
+	triggerSignatureHeader = "X-Nebula-Signature" // HMAC-SHA256 del timestamp y el cuerpo, como sha256=<hex>
+	triggerTimestampHeader = "X-Nebula-Timestamp" // Segundos Unix en que se firmó el webhook
+)
+
+// triggerFlags holds the flags of the webhook receiver of serve
+type triggerFlags struct {
+	secrets    *string
+	maxPerHour *int
+}
+
+// addTriggerFlags registers the webhook receiver flags on fs
+func addTriggerFlags(fs *flag.FlagSet) *triggerFlags {
+	return &triggerFlags{
+		secrets:    fs.String("trigger-secrets", os.Getenv("NEBULA_TRIGGER_SECRETS"), "fuentes de webhooks que pueden pedir la reevaluación de un dominio monitoreado, como fuente=secreto separadas por comas; habilita POST /hooks/{fuente}"),
+		maxPerHour: fs.Int("trigger-max-per-hour", triggerDefaultMaxPerHour, "reevaluaciones por hora que puede pedir cada fuente de webhooks (0 = sin límite)"),
+	}
+}
+
+// sources parses --trigger-secrets into the sources of webhooks, or nil
+// when it is empty
+func (f *triggerFlags) sources() (map[string]*triggerSource, error) {
+	if *f.maxPerHour < 0 {
+		return nil, fmt.Errorf("--trigger-max-per-hour no puede ser negativo")
+	}
+	if strings.TrimSpace(*f.secrets) == "" {
+		return nil, nil
+	}
+	sources := make(map[string]*triggerSource)
+	for _, entry := range strings.Split(*f.secrets, ",") {
+		name, secret, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || secret == "" {
+			return nil, fmt.Errorf("--trigger-secrets: se espera fuente=secreto")
+		}
+		if !isSourceName(name) {
+			return nil, fmt.Errorf("--trigger-secrets: nombre de fuente inválido %q (letras, dígitos, - y _)", name)
+		}
+		if sources[name] != nil {
+			return nil, fmt.Errorf("--trigger-secrets: fuente repetida %q", name)
+		}
+		sources[name] = &triggerSource{secret: []byte(secret), maxPerHour: *f.maxPerHour}
+	}
+	return sources, nil
+}
+
+// isSourceName reports whether name can be used in the path of a source
+func isSourceName(name string) bool {
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// triggerSource is a sender of webhooks, like a deployment pipeline, with
+// its own secret and rate limit
+type triggerSource struct {
+	secret     []byte
+	maxPerHour int
+	requested  []time.Time          // Reevaluaciones de la última hora, la más antigua primero
+	delivered  map[string]time.Time // Firmas de los webhooks aceptados, con su timestamp
+}
+
+// Motivos por los que se rechaza un webhook
+var (
+	errTriggerSignature = errors.New("firma HMAC-SHA256 inválida o ausente")
+	errTriggerTimestamp = fmt.Errorf("timestamp ausente o con más de %s de diferencia", triggerMaxSkew)
+	errTriggerReplayed  = errors.New("webhook repetido")
+)
+
+// verify checks that the request carries the HMAC-SHA256 of its timestamp
+// and body with the secret of the source, that the timestamp is within
+// triggerMaxSkew of now and that the webhook wasn't accepted before. The
+// signature identifies each delivery: it is remembered until the
+// timestamp leaves the window, so a captured webhook can't be replayed.
+// Must be called with the mutex of the receiver held.
+func (s *triggerSource) verify(header http.Header, body []byte, now time.Time) error {
+	timestamp := header.Get(triggerTimestampHeader)
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	signature, ok := strings.CutPrefix(header.Get(triggerSignatureHeader), "sha256=")
+	if got, err := hex.DecodeString(signature); !ok || err != nil || !hmac.Equal(got, mac.Sum(nil)) {
+		return errTriggerSignature
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errTriggerTimestamp
+	}
+	signed := time.Unix(seconds, 0)
+	if now.Sub(signed) > triggerMaxSkew || signed.Sub(now) > triggerMaxSkew {
+		return errTriggerTimestamp
+	}
+
+	for delivery, at := range s.delivered {
+		if now.Sub(at) > triggerMaxSkew {
+			delete(s.delivered, delivery)
+		}
+	}
+	if _, ok := s.delivered[signature]; ok {
+		return errTriggerReplayed
+	}
+	if s.delivered == nil {
+		s.delivered = make(map[string]time.Time)
+	}
+	s.delivered[signature] = signed
+	return nil
+}
+
+// take reserves count re-scans in the window of the source. When they
+// don't fit it reserves nothing and returns how long until they do.
+func (s *triggerSource) take(count int, now time.Time) (time.Duration, bool) {
+	if s.maxPerHour == 0 {
+		return 0, true
+	}
+	expired := 0
+	for expired < len(s.requested) && now.Sub(s.requested[expired]) >= triggerWindow {
+		expired++
+	}
+	s.requested = s.requested[expired:]
+	if count > s.maxPerHour {
+		return triggerWindow, false
+	}
+	if excess := len(s.requested) + count - s.maxPerHour; excess > 0 {
+		return s.requested[excess-1].Add(triggerWindow).Sub(now), false
+	}
+	for range count {
+		s.requested = append(s.requested, now)
+	}
+	return 0, true
+}
+
+// TriggerReceiver serves POST /hooks/{source}: signed webhooks from
+// deployment pipelines or certificate managers that re-assess a monitored
+// domain right away instead of at the next round. The results are
+// recorded like the ones of the monitoring loop.
+type TriggerReceiver struct {
+	ctx      context.Context // Contexto del servidor: cancela las reevaluaciones al cerrarlo
+	exporter *Exporter
+	scanner  *Scanner
+
+	mu      sync.Mutex
+	sources map[string]*triggerSource
+	running map[string]bool // Dominios con una reevaluación en curso
+}
+
+// NewTriggerReceiver creates the receiver of the webhooks of sources. The
+// re-scans run until they finish or ctx is cancelled.
+func NewTriggerReceiver(ctx context.Context, exporter *Exporter, scanner *Scanner, sources map[string]*triggerSource) *TriggerReceiver {
+	return &TriggerReceiver{ctx: ctx, exporter: exporter, scanner: scanner, sources: sources, running: make(map[string]bool)}
+}
+
+// Register adds the webhook route to mux
+func (t *TriggerReceiver) Register(mux *http.ServeMux) {
+	mux.HandleFunc("POST /hooks/{source}", t.handle)
+}
+
+// apiTrigger is the JSON representation of a re-scan requested by a webhook
+type apiTrigger struct {
+	Domain string `json:"domain"`
+	Status string `json:"status"` // scheduled, o running si ya había una en curso
+}
+
+// handle implements POST /hooks/{source} with a body like
+// {"domain": "example.com"} or {"domains": ["a.example.com", ...]}
+func (t *TriggerReceiver) handle(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("source")
+	source, ok := t.sources[name]
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, "unknown_source", "fuente de webhooks desconocida")
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, triggerMaxBody))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_body", fmt.Sprintf("cuerpo inválido: %s", err))
+		return
+	}
+	// La firma se verifica antes de mirar el contenido
+	t.mu.Lock()
+	err = source.verify(r.Header, body, time.Now())
+	t.mu.Unlock()
+	if err != nil {
+		slog.Warn("webhook rechazado", "source", name, "remote", r.RemoteAddr, "error", err)
+		switch err {
+		case errTriggerReplayed:
+			writeAPIError(w, http.StatusConflict, "replayed", err.Error())
+		case errTriggerTimestamp:
+			writeAPIError(w, http.StatusUnauthorized, "invalid_timestamp", err.Error())
+		default:
+			writeAPIError(w, http.StatusUnauthorized, "invalid_signature", err.Error())
+		}
+		return
+	}
+
+	var request struct {
+		Domain  string   `json:"domain"`
+		Domains []string `json:"domains"`
+	}
+	// Se ignoran los campos desconocidos: los emisores suelen mandar más datos del evento
+	if err := json.Unmarshal(body, &request); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_body", fmt.Sprintf("cuerpo inválido: %s", err))
+		return
+	}
+	var domains []string
+	seen := make(map[string]bool)
+	for _, domain := range append(request.Domains, request.Domain) {
+		domain = strings.TrimSuffix(strings.TrimSpace(domain), ".")
+		if domain == "" {
+			continue
+		}
+		// Solo los dominios monitoreados: un webhook no agrega dominios
+		monitored, ok := t.exporter.Monitored(domain)
+		if !ok {
+			writeAPIError(w, http.StatusNotFound, "domain_not_monitored", fmt.Sprintf("%s no es un dominio monitoreado", domain))
+			return
+		}
+		if !seen[monitored] {
+			seen[monitored] = true
+			domains = append(domains, monitored)
+		}
+	}
+	if len(domains) == 0 {
+		writeAPIError(w, http.StatusBadRequest, "missing_domain", "falta el dominio (domain o domains)")
+		return
+	}
+
+	t.mu.Lock()
+	wait, ok := source.take(len(domains), time.Now())
+	t.mu.Unlock()
+	if !ok {
+		slog.Warn("webhook descartado por el límite de la fuente", "source", name, "domains", domains)
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		writeAPIError(w, http.StatusTooManyRequests, "rate_limited", fmt.Sprintf("la fuente %s superó las %d reevaluaciones por hora", name, source.maxPerHour))
+		return
+	}
+
+	response := make([]apiTrigger, 0, len(domains))
+	for _, domain := range domains {
+		status := "running"
+		if t.start(name, domain) {
+			status = "scheduled"
+		}
+		response = append(response, apiTrigger{Domain: domain, Status: status})
+	}
+	writeJSON(w, http.StatusAccepted, map[string]any{"source": name, "domains": response})
+}
+
+// start re-assesses domain in the background, unless it already is. It
+// reports whether a re-scan was started.
+func (t *TriggerReceiver) start(source, domain string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.running[domain] {
+		return false
+	}
+	t.running[domain] = true
+	go t.rescan(source, domain)
+	return true
+}
+
+// rescan assesses domain and records the outcome in the exporter, the
+// history and the notifications
+func (t *TriggerReceiver) rescan(source, domain string) {
+	defer func() {
+		t.mu.Lock()
+		delete(t.running, domain)
+		t.mu.Unlock()
+	}()
+	slog.Info("reevaluación solicitada por webhook", "source", source, "domain", domain)
+
+	started := time.Now()
+	result, err := t.scanner.AssessContext(t.ctx, domain)
+	if err != nil {
+		slog.Error("evaluación fallida", "domain", domain, "source", source, "error", err)
+	} else {
+		recordAssessment(t.exporter.history, t.exporter.notifications, result)
+	}
+	t.exporter.Record(domain, result, err, started)
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	_ "embed"
+	"encoding/hex"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// embeddedTrustStore is the Mozilla CA bundle shipped with the binary, in
+// the format of curl's cacert.pem
+//
+//go:embed truststore/cacert.pem
+var embeddedTrustStore []byte
+
+// defaultTrustStoreURL is curl's extraction of the Mozilla CA bundle. A
+// SHA-256 of the file is published at the same URL plus ".sha256".
+const defaultTrustStoreURL = "https://curl.se/ca/cacert.pem"
+
+// trustStoreDatePrefixes precede the date of the Mozilla data in the
+// header of cacert.pem (the wording changed between curl versions)
+var trustStoreDatePrefixes = []string{
+	"## Certificate data from Mozilla as of:",
+	"## Certificate data from Mozilla last updated on:",
+}
+
+// Origen del almacén de confianza
+const trustStoreEmbedded = "embebido"
+
+// TrustStore is the set of root CAs trusted by local assessments: the
+// Mozilla bundle (embedded or updated with "truststore update") plus the
+// bundles of --ca-file
+type TrustStore struct {
+	Certs  []*x509.Certificate
+	Date   time.Time     // Fecha de los datos de Mozilla (cero si el bundle no la indica)
+	Source string        // "embebido" o ruta del bundle actualizado
+	Extra  []*TrustStore // Bundles adicionales (--ca-file)
+}
+
+// TrustBundle is a named set of roots that a chain is validated against
+// separately, to report trust per bundle
+type TrustBundle struct {
+	Name  string
+	Roots *x509.CertPool
+}
+
+// defaultTrustStorePath returns $XDG_DATA_HOME/nebula/cacert.pem, falling
+// back to ~/.local/share/nebula/cacert.pem, where "truststore update"
+// saves the bundle
+func defaultTrustStorePath() string {
+	return filepath.Join(filepath.Dir(defaultHistoryPath()), "cacert.pem")
+}
+
+// loadTrustStore returns the bundle saved at path by "truststore update"
+// or, if there is none or it is older, the embedded one
+func loadTrustStore(path string) (*TrustStore, error) {
+	embedded, err := parseTrustStore(embeddedTrustStore, trustStoreEmbedded)
+	if err != nil {
+		return nil, fmt.Errorf("almacén de confianza embebido inválido: %w", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return embedded, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo leer el almacén de confianza: %w", err)
+	}
+	updated, err := parseTrustStore(data, path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w (bórralo o vuelve a ejecutar truststore update)", path, err)
+	}
+	if updated.Date.Before(embedded.Date) {
+		return embedded, nil
+	}
+	return updated, nil
+}
+
+// parseTrustStore parses a cacert.pem-style bundle
+func parseTrustStore(data []byte, source string) (*TrustStore, error) {
+	store := &TrustStore{Source: source}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() && store.Date.IsZero() {
+		for _, prefix := range trustStoreDatePrefixes {
+			date, ok := strings.CutPrefix(scanner.Text(), prefix)
+			if !ok {
+				continue
+			}
+			parsed, err := time.Parse("Mon Jan _2 15:04:05 2006 MST", strings.TrimSpace(date))
+			if err != nil {
+				return nil, fmt.Errorf("fecha inválida en el encabezado: %w", err)
+			}
+			store.Date = parsed
+		}
+	}
+
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("certificado inválido: %w", err)
+		}
+		store.Certs = append(store.Certs, cert)
+	}
+	if len(store.Certs) == 0 {
+		return nil, fmt.Errorf("no contiene certificados PEM")
+	}
+	return store, nil
+}
+
+// AddFile adds the PEM bundle at path (--ca-file)
+func (t *TrustStore) AddFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("no se pudo leer el bundle de CAs: %w", err)
+	}
+	extra, err := parseTrustStore(data, path)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	t.Extra = append(t.Extra, extra)
+	return nil
+}
+
+// Pool returns the Mozilla certificates plus those of the additional
+// bundles, the roots that decide the grade of local assessments
+func (t *TrustStore) Pool() *x509.CertPool {
+	pool := certPool(t.Certs)
+	for _, extra := range t.Extra {
+		for _, cert := range extra.Certs {
+			pool.AddCert(cert)
+		}
+	}
+	return pool
+}
+
+// Bundles returns each bundle on its own, for the per-bundle trust report:
+// Mozilla, the system roots (when available) and every --ca-file
+func (t *TrustStore) Bundles() []TrustBundle {
+	bundles := []TrustBundle{{Name: "Mozilla", Roots: certPool(t.Certs)}}
+	if system, err := x509.SystemCertPool(); err == nil {
+		bundles = append(bundles, TrustBundle{Name: "Sistema", Roots: system})
+	}
+	for _, extra := range t.Extra {
+		bundles = append(bundles, TrustBundle{Name: filepath.Base(extra.Source), Roots: certPool(extra.Certs)})
+	}
+	return bundles
+}
+
+// certPool returns a pool with certs
+func certPool(certs []*x509.Certificate) *x509.CertPool {
+	pool := x509.NewCertPool()
+	for _, cert := range certs {
+		pool.AddCert(cert)
+	}
+	return pool
+}
+
+// Version describes the store for reports, like
+// "Mozilla 2023-03-11 (142 CAs, embebido)"
+func (t *TrustStore) Version() string {
+	date := "sin fecha"
+	if !t.Date.IsZero() {
+		date = t.Date.Format("2006-01-02")
+	}
+	version := fmt.Sprintf("Mozilla %s (%d CAs, %s)", date, len(t.Certs), t.Source)
+	for _, extra := range t.Extra {
+		version += fmt.Sprintf(" + %s (%d CAs)", filepath.Base(extra.Source), len(extra.Certs))
+	}
+	return version
+}
+
+// runTrustStore implements the "truststore" subcommand
+func runTrustStore(args []string) error {
+	fs := flag.NewFlagSet("truststore", flag.ExitOnError)
+	storePath := fs.String("path", defaultTrustStorePath(), "archivo del almacén de confianza actualizado")
+	url := fs.String("url", defaultTrustStoreURL, "URL del bundle de Mozilla en formato cacert.pem (con su SHA-256 en <url>.sha256)")
+	force := fs.Bool("force", false, "instalar el bundle descargado aunque sea más antiguo que el actual")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s truststore show | update [--url url] [--force]\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if len(args) == 0 {
+		fs.Usage()
+		return fmt.Errorf("se requiere una acción: show o update")
+	}
+	action := args[0]
+	fs.Parse(args[1:])
+
+	current, err := loadTrustStore(*storePath)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "show":
+		fmt.Printf("Almacén de confianza: %s\n", current.Version())
+		if current.Source == trustStoreEmbedded {
+			fmt.Printf("Para actualizarlo: %s truststore update\n", os.Args[0])
+		}
+		return nil
+	case "update":
+	default:
+		fs.Usage()
+		return fmt.Errorf("acción desconocida %q: se espera show o update", action)
+	}
+
+	client := &http.Client{Timeout: 2 * time.Minute}
+	data, err := downloadTrustStore(client, *url)
+	if err != nil {
+		return err
+	}
+	updated, err := parseTrustStore(data, *storePath)
+	if err != nil {
+		return fmt.Errorf("bundle descargado inválido: %w", err)
+	}
+	if updated.Date.IsZero() {
+		return fmt.Errorf("el bundle descargado no indica la fecha de los datos de Mozilla")
+	}
+	if !updated.Date.After(current.Date) && !*force {
+		fmt.Printf("El almacén de confianza ya está actualizado: %s\n", current.Version())
+		return nil
+	}
+
+	if err := writeFileAtomic(*storePath, data); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Almacén de confianza actualizado: %s → %s\n", current.Version(), updated.Version())
+	return nil
+}
+
+// downloadTrustStore downloads the bundle at url and checks it against
+// the SHA-256 published at url + ".sha256"
+func downloadTrustStore(client *http.Client, url string) ([]byte, error) {
+	get := func(target string) ([]byte, error) {
+		resp, err := client.Get(target)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%s respondió %d", target, resp.StatusCode)
+		}
+		return io.ReadAll(resp.Body)
+	}
+
+	sums, err := get(url + ".sha256")
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo descargar el checksum del bundle: %w", err)
+	}
+	fields := strings.Fields(string(sums))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("checksum vacío en %s.sha256", url)
+	}
+	expected := strings.ToLower(fields[0])
+	if len(fields) > 1 && strings.TrimPrefix(fields[1], "*") != path.Base(url) {
+		return nil, fmt.Errorf("%s.sha256 corresponde a %s, no a %s", url, fields[1], path.Base(url))
+	}
+
+	data, err := get(url)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo descargar el bundle: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return nil, fmt.Errorf("checksum inválido del bundle: se esperaba %s y se obtuvo %s", expected, actual)
+	}
+	return data, nil
+}
+
+// writeFileAtomic writes data to path through a temporary file and a
+// rename, so readers never see a half-written file
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("no se pudo crear el directorio de %s: %w", path, err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".new-*")
+	if err != nil {
+		return fmt.Errorf("no se pudo escribir %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("no se pudo escribir %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("no se pudo escribir %s: %w", path, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("no se pudo escribir %s: %w", path, err)
+	}
+	return nil
+}